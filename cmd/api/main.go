@@ -12,6 +12,8 @@ import (
 
 	"advanced-user-api/internal/config"
 	"advanced-user-api/internal/handler"
+	"advanced-user-api/internal/pkg/fieldcrypt"
+	"advanced-user-api/internal/pkg/mailer"
 	"advanced-user-api/internal/repository"
 	"advanced-user-api/internal/service"
 
@@ -23,13 +25,24 @@ import (
 // ================================================================
 
 func main() {
-	fmt.Println("=== 🚀 Advanced User Management API ===\n")
+	fmt.Print("=== 🚀 Advanced User Management API ===\n\n")
 
 	// === ШАГ 1: ЗАГРУЗКА КОНФИГУРАЦИИ ===
 	// Загружаем настройки из .env и environment variables
 	cfg := config.Load()
 	log.Println("✅ Конфигурация загружена")
 
+	// Шифрование полей настраивается до подключения к БД:
+	// уже миграция и первые запросы читают зашифрованные значения
+	fieldEncryptor, err := service.NewFieldEncryptor(cfg)
+	if err != nil {
+		log.Fatal("❌ Ошибка загрузки ключей шифрования:", err)
+	}
+	fieldcrypt.SetActive(fieldEncryptor)
+	if fieldEncryptor != nil {
+		log.Printf("✅ Шифрование персональных данных включено (поля: %s)", cfg.FieldEncryptionFields)
+	}
+
 	// === ШАГ 2: ПОДКЛЮЧЕНИЕ К БД ===
 	// InitDB() подключается к PostgreSQL и выполняет Auto Migration
 	// GORM автоматически создаст таблицу users на основе struct
@@ -50,16 +63,171 @@ func main() {
 	
 	// 3.1: Repository (работа с БД)
 	userRepo := repository.NewUserRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	magicLinkRepo := repository.NewMagicLinkRepository(db)
+	credentialRepo := repository.NewCredentialRepository(db)
+	challengeRepo := repository.NewWebAuthnChallengeRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	orgRepo := repository.NewOrganizationRepository(db)
+	invitationRepo := repository.NewInvitationRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
+	userStatusRepo := repository.NewUserStatusRepository(db)
+	dataExportRepo := repository.NewDataExportRepository(db)
+	erasureRepo := repository.NewErasureRepository(db)
+	userImportRepo := repository.NewUserImportRepository(db)
+	attributeRepo := repository.NewAttributeRepository(db)
 	
-	// 3.2: Services (бизнес-логика)
-	authService := service.NewAuthService(userRepo, cfg)
-	userService := service.NewUserService(userRepo)
+	// 3.2: Отправка писем (MAIL_DRIVER=smtp - реальная отправка, иначе - в лог)
+	mail := mailer.NewLogMailer()
+	if cfg.MailDriver == "smtp" {
+		mail = mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		})
+	}
 	
-	// 3.3: Handlers (HTTP обработчики)
+	// 3.3: Services (бизнес-логика)
+	// Один TokenIssuer на все способы входа - каждый вход открывает сеанс
+	auditService := service.NewAuditService(auditRepo)
+	tokenIssuer := service.NewTokenIssuer(cfg, sessionRepo)
+	attributeService := service.NewAttributeService(attributeRepo, auditService)
+	// Роли групп: администратор через группу - администратор и для имперсонации
+	groupService := service.NewGroupService(groupRepo, userRepo, auditService)
+	authService := service.NewAuthService(userRepo, cfg,
+		service.WithRoleProvider(groupService),
+		service.WithAuditService(auditService),
+		service.WithAttributeService(attributeService),
+		service.WithMailer(mail),
+		service.WithMagicLinkRepository(magicLinkRepo),
+		service.WithTokenIssuer(tokenIssuer),
+		service.WithSessionRepository(sessionRepo),
+	)
+	avatarStorage, err := service.NewAvatarStorage(cfg)
+	if err != nil {
+		log.Fatal("❌ Ошибка настройки хранилища аватаров:", err)
+	}
+	profileService := service.NewProfileService(userRepo, avatarStorage, auditService, cfg)
+	dataExportService := service.NewDataExportService(dataExportRepo, userRepo, auditService, cfg)
+	userService := service.NewUserService(userRepo,
+		service.WithUserAuditService(auditService),
+		service.WithDeletedUserPurge(time.Duration(cfg.UserPurgeAfterDays)*24*time.Hour),
+		service.WithAvatarRemover(profileService),
+		service.WithArchiveRemover(dataExportService),
+		service.WithUserAttributeService(attributeService),
+	)
+	auditLogService := service.NewAuditLogService(auditRepo, cfg)
+	sessionService := service.NewSessionService(sessionRepo)
+	impersonationService := service.NewImpersonationService(userRepo, tokenIssuer, auditService, cfg,
+		service.WithImpersonationRoles(groupService),
+	)
+	orgService := service.NewOrganizationService(orgRepo, auditService)
+	invitationService := service.NewInvitationService(invitationRepo, orgRepo, userRepo, authService, tokenIssuer, mail, auditService, cfg)
+	accountStatusService := service.NewAccountStatusService(userRepo, userStatusRepo, sessionRepo, auditService)
+	erasureService := service.NewErasureService(erasureRepo, userRepo, authService, profileService, auditService, cfg)
+	userImportService := service.NewUserImportService(userImportRepo, userRepo, attributeService, invitationService, auditService, cfg,
+		service.WithUserImportSessions(sessionRepo),
+	)
+	userExportService := service.NewUserExportService(userRepo, attributeService, auditService, cfg)
+	
+	// Ответы на POST с Idempotency-Key (IDEMPOTENCY_STORAGE=off - без сохранения)
+	idempotencyRepo, err := repository.NewIdempotencyStorage(cfg, db)
+	if err != nil {
+		log.Fatal("❌ Ошибка настройки хранилища ключей идемпотентности:", err)
+	}
+	var idempotencyService service.IdempotencyService
+	if idempotencyRepo != nil {
+		idempotencyService = service.NewIdempotencyService(idempotencyRepo, cfg)
+	}
+	
+	// 3.4: Handlers (HTTP обработчики)
 	authHandler := handler.NewAuthHandler(authService, userService)
-	userHandler := handler.NewUserHandler(userService)
+	userHandler := handler.NewUserHandler(userService, attributeService)
+	sessionHandler := handler.NewSessionHandler(sessionService)
+	adminHandler := handler.NewAdminHandler(impersonationService, auditService, auditLogService, accountStatusService)
+	orgHandler := handler.NewOrganizationHandler(orgService, invitationService)
+	groupHandler := handler.NewGroupHandler(groupService)
+	dataExportHandler := handler.NewDataExportHandler(dataExportService)
+	erasureHandler := handler.NewErasureHandler(erasureService)
+	profileHandler := handler.NewProfileHandler(profileService)
+	attributeHandler := handler.NewAttributeHandler(attributeService)
+	userImportHandler := handler.NewUserImportHandler(userImportService)
+	userExportHandler := handler.NewUserExportHandler(userExportService, attributeService)
+	
+	// 3.5: Правила доступа ABAC (только если задан POLICY_FILES)
+	var policyHandler *handler.PolicyHandler
+	var authzService service.AuthorizationService
+	if cfg.PolicyFiles != "" {
+		authzService, err = service.NewAuthorizationService(userRepo, groupRepo, cfg)
+		if err != nil {
+			log.Fatal("❌ Ошибка загрузки правил доступа:", err)
+		}
+		policyHandler = handler.NewPolicyHandler(authzService)
+		log.Printf("✅ Правила доступа загружены: %d (режим %s)", len(authzService.Policies()), cfg.PolicyMode)
+	}
+	
+	// 3.6: Passkeys (только если LOGIN_METHODS содержит "passkey")
+	var passkeyHandler *handler.PasskeyHandler
+	if service.LoginMethodEnabled(cfg, service.LoginMethodPasskey) {
+		passkeyService, err := service.NewPasskeyService(userRepo, credentialRepo, challengeRepo, tokenIssuer, auditService, cfg)
+		if err != nil {
+			log.Fatal("❌ Ошибка настройки passkeys:", err)
+		}
+		passkeyHandler = handler.NewPasskeyHandler(passkeyService)
+		log.Printf("✅ Passkeys включены (RP ID: %s)", cfg.WebAuthnRPID)
+	}
 	
 	log.Println("✅ Все слои приложения инициализированы")
+	if cfg.LDAPEnabled {
+		log.Printf("✅ LDAP аутентификация включена (%s)", cfg.LDAPURL)
+	}
+	log.Printf("✅ Способы входа: %s", cfg.LoginMethods)
+
+	// 3.7: Срок хранения журнала аудита - очистка раз в сутки (AUDIT_RETENTION_DAYS=0 - не удалять)
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	defer stopRetention()
+	go auditLogService.RunRetention(retentionCtx, 24*time.Hour)
+
+	// 3.8: Правила доступа перечитываются при изменении файлов (POLICY_RELOAD_INTERVAL)
+	if authzService != nil {
+		go authzService.RunReload(retentionCtx)
+	}
+
+	// 3.9: Снятие блокировок аккаунтов с истёкшим сроком (ACCOUNT_STATUS_CHECK_INTERVAL)
+	statusInterval, err := time.ParseDuration(cfg.AccountStatusCheckInterval)
+	if err != nil {
+		statusInterval = time.Minute
+	}
+	go accountStatusService.RunExpiry(retentionCtx, statusInterval)
+
+	// 3.10: Окончательное удаление аккаунтов после срока хранения (USER_PURGE_AFTER_DAYS=0 - не удалять)
+	go userService.RunPurge(retentionCtx, time.Hour)
+
+	// 3.11: Сборка архивов выгрузки данных (сразу после запроса, плюс проверка раз в минуту)
+	go dataExportService.RunWorker(retentionCtx, time.Minute)
+
+	// 3.12: Стирание данных по запросам с истёкшим периодом отмены (ERASURE_COOLING_OFF_DAYS)
+	go erasureService.RunErasure(retentionCtx, time.Hour)
+
+	// 3.13: Перешифрование после смены ключа или списка полей (FIELD_ENCRYPTION_REENCRYPT_INTERVAL)
+	if fieldEncryptor != nil {
+		reencryptInterval, err := time.ParseDuration(cfg.FieldEncryptionReencryptInterval)
+		if err != nil {
+			reencryptInterval = time.Hour
+		}
+		fieldEncryptionService := service.NewFieldEncryptionService(repository.NewFieldEncryptionRepository(db, fieldEncryptor))
+		go fieldEncryptionService.RunReencryption(retentionCtx, reencryptInterval)
+	}
+
+	// 3.14: Удаление устаревших ключей идемпотентности (IDEMPOTENCY_TTL)
+	if idempotencyService != nil {
+		go idempotencyService.RunCleanup(retentionCtx, time.Hour)
+	}
+
+	// 3.15: Импорт пользователей из загруженных файлов (сразу после загрузки, плюс проверка раз в минуту)
+	go userImportService.RunWorker(retentionCtx, time.Minute)
 
 	// === ШАГ 4: НАСТРОЙКА GIN ===
 	// Устанавливаем режим Gin (debug, release, test)
@@ -75,7 +243,22 @@ func main() {
 
 	// === ШАГ 5: РЕГИСТРАЦИЯ МАРШРУТОВ ===
 	// Настраиваем все HTTP endpoints
-	handler.SetupRoutes(router, authHandler, userHandler, cfg)
+	handler.SetupRoutes(router, handler.Handlers{
+		Auth:         authHandler,
+		User:         userHandler,
+		Passkey:      passkeyHandler,
+		Session:      sessionHandler,
+		Admin:        adminHandler,
+		Organization: orgHandler,
+		Group:        groupHandler,
+		Policy:       policyHandler,
+		DataExport:   dataExportHandler,
+		Erasure:      erasureHandler,
+		Profile:      profileHandler,
+		Attribute:    attributeHandler,
+		UserImport:   userImportHandler,
+		UserExport:   userExportHandler,
+	}, idempotencyService, cfg)
 	log.Println("✅ Маршруты зарегистрированы")
 
 	// === ШАГ 6: СОЗДАНИЕ HTTP СЕРВЕРА ===
@@ -99,14 +282,88 @@ func main() {
 		fmt.Println("   PUBLIC (без токена):")
 		fmt.Println("     POST   /api/v1/auth/register  - Регистрация")
 		fmt.Println("     POST   /api/v1/auth/login     - Вход")
+		fmt.Println("     POST   /api/v1/auth/magic-link        - Ссылка для входа")
+		fmt.Println("     POST   /api/v1/auth/magic-link/verify - Вход по ссылке")
+		fmt.Println("     POST   /api/v1/auth/passkeys/signup/{begin,finish} - Регистрация с passkey")
+		fmt.Println("     POST   /api/v1/auth/passkeys/login/{begin,finish}  - Вход по passkey")
+		fmt.Println("     POST   /api/v1/invitations/register - Принять приглашение с регистрацией")
+		fmt.Println("     POST   /api/v1/invitations/decline  - Отклонить приглашение")
+		fmt.Println("     GET    /api/v1/exports/:id/download - Скачать выгрузку данных (подписанная ссылка)")
+		fmt.Println("     GET    /api/v1/avatars/:id/:size  - Файл аватара")
 		fmt.Println("     GET    /health                - Health check")
 		fmt.Println("\n   PROTECTED (требуют JWT токен):")
 		fmt.Println("     GET    /api/v1/auth/me        - Текущий пользователь")
-		fmt.Println("     GET    /api/v1/users          - Список пользователей")
+		fmt.Println("     POST   /api/v1/auth/password/change - Смена пароля")
+		fmt.Println("     GET    /api/v1/auth/sessions  - Мои сеансы")
+		fmt.Println("     DELETE /api/v1/auth/sessions  - Завершить остальные сеансы")
+		fmt.Println("     DELETE /api/v1/auth/sessions/:id - Завершить сеанс")
+		fmt.Println("     GET    /api/v1/auth/passkeys  - Мои passkeys")
+		fmt.Println("     POST   /api/v1/auth/passkeys/register/{begin,finish} - Добавить passkey")
+		fmt.Println("     PATCH  /api/v1/auth/passkeys/:id - Переименовать passkey")
+		fmt.Println("     DELETE /api/v1/auth/passkeys/:id - Удалить passkey")
+		fmt.Println("     GET    /api/v1/organizations/current - Текущая организация")
+		fmt.Println("     GET    /api/v1/organizations/current/members - Участники организации")
+		fmt.Println("     PUT    /api/v1/organizations/current/members/:userId - Сменить роль участника")
+		fmt.Println("     DELETE /api/v1/organizations/current/members/:userId - Исключить участника")
+		fmt.Println("     POST   /api/v1/organizations/current/invitations - Пригласить по email")
+		fmt.Println("     GET    /api/v1/organizations/current/invitations - Ожидающие приглашения")
+		fmt.Println("     POST   /api/v1/organizations/current/invitations/:id/resend - Отправить повторно")
+		fmt.Println("     DELETE /api/v1/organizations/current/invitations/:id - Отозвать приглашение")
+		fmt.Println("     POST   /api/v1/invitations/accept - Принять приглашение своим аккаунтом")
+		fmt.Println("     GET    /api/v1/groups         - Группы пользователей")
+		fmt.Println("     GET    /api/v1/groups/:id     - Группа и её роли")
+		fmt.Println("     GET    /api/v1/groups/:id/members - Участники группы")
+		fmt.Println("     GET    /api/v1/users          - Список пользователей (?group=ID - участники группы, ?attr.key=value - по атрибутам)")
 		fmt.Println("     GET    /api/v1/users/:id      - Получить пользователя")
-		fmt.Println("     PUT    /api/v1/users/:id      - Обновить пользователя")
+		fmt.Println("     PUT    /api/v1/users/:id      - Заменить данные пользователя")
+		fmt.Println("     PATCH  /api/v1/users/:id      - Частично изменить (merge-patch / json-patch)")
 		fmt.Println("     DELETE /api/v1/users/:id      - Удалить пользователя")
-		fmt.Println("\n💡 Нажмите Ctrl+C для остановки\n")
+		fmt.Println("     GET    /api/v1/users/:id/profile - Профиль пользователя")
+		fmt.Println("     GET    /api/v1/users/me/profile  - Мой профиль")
+		fmt.Println("     PUT    /api/v1/users/me/profile  - Изменить профиль")
+		fmt.Println("     POST   /api/v1/users/me/avatar   - Загрузить аватар")
+		fmt.Println("     DELETE /api/v1/users/me/avatar   - Удалить аватар")
+		fmt.Println("     POST   /api/v1/users/me/export - Выгрузить свои данные (GDPR)")
+		fmt.Println("     GET    /api/v1/users/me/exports/:id - Статус выгрузки и ссылка на архив")
+		fmt.Println("     DELETE /api/v1/users/me       - Удалить свой аккаунт со стиранием данных")
+		fmt.Println("     GET    /api/v1/users/me/erasure - Состояние запроса на удаление")
+		fmt.Println("     DELETE /api/v1/users/me/erasure - Отменить удаление аккаунта")
+		fmt.Println("\n   ADMIN (роль admin):")
+		fmt.Println("     POST   /api/v1/organizations  - Создать организацию")
+		fmt.Println("     POST   /api/v1/admin/users/:id/impersonate - Войти от имени пользователя")
+		fmt.Println("     POST   /api/v1/admin/users/:id/suspend - Временно заблокировать")
+		fmt.Println("     POST   /api/v1/admin/users/:id/ban - Заблокировать")
+		fmt.Println("     POST   /api/v1/admin/users/:id/reactivate - Активировать / разблокировать")
+		fmt.Println("     GET    /api/v1/admin/users/:id/status-history - История статусов")
+		fmt.Println("     POST   /api/v1/admin/users/:id/export - Выгрузить данные пользователя")
+		fmt.Println("     GET    /api/v1/admin/exports/:id - Статус выгрузки")
+		fmt.Println("     POST   /api/v1/admin/users/import - Импорт пользователей (CSV / NDJSON)")
+		fmt.Println("     GET    /api/v1/admin/imports/:id - Прогресс и ошибки импорта")
+		fmt.Println("     GET    /api/v1/admin/users/export - Выгрузка пользователей (CSV / NDJSON / Parquet)")
+		fmt.Println("     POST   /api/v1/admin/users/:id/erasure - Стереть данные пользователя")
+		fmt.Println("     GET    /api/v1/admin/users/:id/erasure - Запрос на стирание пользователя")
+		fmt.Println("     DELETE /api/v1/admin/users/:id/erasure - Отменить стирание")
+		fmt.Println("     GET    /api/v1/admin/erasures - Запросы на стирание")
+		fmt.Println("     GET    /api/v1/admin/audit-events - Журнал событий")
+		fmt.Println("     GET    /api/v1/admin/audit-events/verify - Проверка цепочки журнала")
+		fmt.Println("     GET    /api/v1/admin/policies - Правила доступа")
+		fmt.Println("     POST   /api/v1/admin/policies/reload - Перечитать правила")
+		fmt.Println("     POST   /api/v1/admin/policies/explain - Объяснить решение правил")
+		fmt.Println("     GET    /api/v1/admin/deleted-users - Удалённые пользователи")
+		fmt.Println("     POST   /api/v1/admin/deleted-users/:id/restore - Восстановить пользователя")
+		fmt.Println("     DELETE /api/v1/admin/deleted-users/:id - Удалить окончательно")
+		fmt.Println("     GET    /api/v1/admin/attributes - Схемы атрибутов пользователей")
+		fmt.Println("     POST   /api/v1/admin/attributes - Описать атрибут")
+		fmt.Println("     PUT    /api/v1/admin/attributes/:key - Изменить схему атрибута")
+		fmt.Println("     DELETE /api/v1/admin/attributes/:key - Удалить атрибут со значениями")
+		fmt.Println("     POST   /api/v1/groups         - Создать группу")
+		fmt.Println("     PUT    /api/v1/groups/:id     - Изменить группу")
+		fmt.Println("     DELETE /api/v1/groups/:id     - Удалить группу")
+		fmt.Println("     PUT    /api/v1/groups/:id/members/:userId - Добавить в группу")
+		fmt.Println("     DELETE /api/v1/groups/:id/members/:userId - Убрать из группы")
+		fmt.Println("     POST   /api/v1/groups/:id/roles - Выдать роль группе")
+		fmt.Println("     DELETE /api/v1/groups/:id/roles/:role - Отозвать роль у группы")
+		fmt.Print("\n💡 Нажмите Ctrl+C для остановки\n\n")
 		
 		// ListenAndServe() - запускает HTTP сервер
 		// Блокирующая функция - работает до ошибки или остановки
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/fieldcrypt"
+	"advanced-user-api/internal/pkg/mailer"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+)
+
+// ================================================================
+// IMPORT - Массовый импорт пользователей из командной строки
+// ================================================================
+// Те же проверки и пачки, что и POST /api/v1/admin/users/import,
+// но задача выполняется сразу, а отчёт печатается в консоль:
+//
+//	go run ./cmd/import -file users.csv -org acme -dry-run
+//	go run ./cmd/import -file users.ndjson -upsert -invite
+//
+// Код выхода: 0 - все строки импортированы, 1 - файл не обработан,
+// 2 - есть ошибки строк
+
+func main() {
+	file := flag.String("file", "", "файл пользователей (.csv или .ndjson)")
+	format := flag.String("format", "", "формат файла: csv или ndjson (по умолчанию - по расширению)")
+	org := flag.String("org", "", "slug организации (по умолчанию TENANT_DEFAULT)")
+	dryRun := flag.Bool("dry-run", false, "только проверить строки, ничего не записывать")
+	upsert := flag.Bool("upsert", false, "обновлять пользователей с существующим email")
+	invite := flag.Bool("invite", false, "строкам без пароля отправлять приглашение в организацию")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+		if *format == "jsonl" {
+			*format = domain.UserImportFormatNDJSON
+		}
+	}
+
+	// === ШАГ 1: КОНФИГУРАЦИЯ И БД ===
+	cfg := config.Load()
+	fieldEncryptor, err := service.NewFieldEncryptor(cfg)
+	if err != nil {
+		log.Fatal("❌ Ошибка загрузки ключей шифрования:", err)
+	}
+	fieldcrypt.SetActive(fieldEncryptor)
+
+	db, err := repository.InitDB(cfg)
+	if err != nil {
+		log.Fatal("❌ Ошибка подключения к БД:", err)
+	}
+	defer repository.CloseDB(db)
+
+	// === ШАГ 2: ОРГАНИЗАЦИЯ ===
+	orgRepo := repository.NewOrganizationRepository(db)
+	slug := *org
+	if slug == "" {
+		slug = cfg.TenantDefault
+	}
+	organization, err := orgRepo.FindBySlug(strings.ToLower(slug))
+	if err != nil {
+		log.Fatalf("❌ Организация %q не найдена", slug)
+	}
+
+	// === ШАГ 3: СЕРВИСЫ ===
+	mail := mailer.NewLogMailer()
+	if cfg.MailDriver == "smtp" {
+		mail = mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		})
+	}
+	userRepo := repository.NewUserRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	auditService := service.NewAuditService(repository.NewAuditRepository(db))
+	tokenIssuer := service.NewTokenIssuer(cfg, sessionRepo)
+	attributeService := service.NewAttributeService(repository.NewAttributeRepository(db), auditService)
+	authService := service.NewAuthService(userRepo, cfg,
+		service.WithAuditService(auditService),
+		service.WithAttributeService(attributeService),
+		service.WithMailer(mail),
+		service.WithTokenIssuer(tokenIssuer),
+		service.WithSessionRepository(sessionRepo),
+	)
+	invitationService := service.NewInvitationService(repository.NewInvitationRepository(db), orgRepo, userRepo, authService, tokenIssuer, mail, auditService, cfg)
+	importService := service.NewUserImportService(repository.NewUserImportRepository(db), userRepo, attributeService, invitationService, auditService, cfg,
+		service.WithUserImportSessions(sessionRepo),
+	)
+
+	// === ШАГ 4: ИМПОРТ ===
+	data, err := os.Open(*file)
+	if err != nil {
+		log.Fatal("❌ Не удалось открыть файл:", err)
+	}
+	defer data.Close()
+
+	job, err := importService.ForTenant(organization.ID).Import(0, &domain.CreateUserImportRequest{
+		Format: *format,
+		DryRun: *dryRun,
+		Upsert: *upsert,
+		Invite: *invite,
+	}, data)
+	if err != nil {
+		log.Fatal("❌ Ошибка импорта:", err)
+	}
+
+	// === ШАГ 5: ОТЧЁТ ===
+	printReport(job, organization)
+	switch {
+	case job.Status == domain.UserImportStatusFailed:
+		os.Exit(1)
+	case job.Failed > 0:
+		os.Exit(2)
+	}
+}
+
+// printReport - счётчики, изменения ролей и ошибки строк
+func printReport(job *domain.UserImport, organization *domain.Organization) {
+	mode := ""
+	if job.DryRun {
+		mode = " (dry-run: ничего не записано)"
+	}
+	fmt.Printf("\n📦 Импорт %d в %s%s: %s\n", job.ID, organization.Slug, mode, job.Status)
+	if job.Status == domain.UserImportStatusFailed {
+		fmt.Printf("❌ %s\n", job.Error)
+		return
+	}
+
+	fmt.Printf("   строк:       %d\n", job.Total)
+	fmt.Printf("   создано:     %d\n", job.Created)
+	fmt.Printf("   обновлено:   %d\n", job.Updated)
+	fmt.Printf("   приглашено:  %d\n", job.Invited)
+	fmt.Printf("   с ошибками:  %d\n", job.Failed)
+
+	for _, change := range job.RoleChanges {
+		fmt.Printf("   🔑 строка %d %s: роль %s → %s\n", change.Row, change.Email, change.From, change.To)
+	}
+
+	for _, rowError := range job.Errors {
+		fmt.Printf("   ⚠️  строка %d %s: %s\n", rowError.Row, rowError.Email, strings.Join(rowError.Errors, "; "))
+	}
+	if hidden := job.Failed - len(job.Errors); hidden > 0 {
+		fmt.Printf("   ... и ещё %d строк с ошибками\n", hidden)
+	}
+}
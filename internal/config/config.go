@@ -66,6 +66,306 @@ type Config struct {
 	
 	// LogLevel - уровень логирования ("debug", "info", "warn", "error")
 	LogLevel string `mapstructure:"LOG_LEVEL"`
+
+	// === LOGIN METHODS ===
+	// Какие способы входа включены в этой инсталляции (через запятую):
+	//   "password"   - email + пароль (POST /auth/login)
+	//   "magic_link" - ссылка для входа по email (POST /auth/magic-link)
+	//   "passkey"    - WebAuthn passkeys (POST /auth/passkeys/...)
+	// Пусто - только "password"
+	LoginMethods string `mapstructure:"LOGIN_METHODS"`
+	
+	// MagicLinkURL - адрес страницы фронтенда, куда ведёт ссылка из письма
+	// К адресу добавляется "?token=..."; страница отправляет токен на /auth/magic-link/verify
+	MagicLinkURL string `mapstructure:"MAGIC_LINK_URL"`
+	
+	// MagicLinkTTL - время жизни ссылки (например, "15m")
+	MagicLinkTTL string `mapstructure:"MAGIC_LINK_TTL"`
+	
+	// MagicLinkRateLimit / MagicLinkRateWindow - сколько ссылок можно запросить
+	// на один email за окно времени (например, 3 за "15m")
+	MagicLinkRateLimit  int    `mapstructure:"MAGIC_LINK_RATE_LIMIT"`
+	MagicLinkRateWindow string `mapstructure:"MAGIC_LINK_RATE_WINDOW"`
+	
+	// === WEBAUTHN (PASSKEYS) ===
+	// Используются, если LOGIN_METHODS содержит "passkey"
+	
+	// WebAuthnRPID - идентификатор сайта (домен без схемы и порта, например "example.com")
+	// Passkey привязан к этому домену: сменить его позже нельзя без перерегистрации ключей
+	WebAuthnRPID string `mapstructure:"WEBAUTHN_RP_ID"`
+	
+	// WebAuthnRPName - название сервиса, которое браузер показывает пользователю
+	WebAuthnRPName string `mapstructure:"WEBAUTHN_RP_NAME"`
+	
+	// WebAuthnRPOrigins - разрешённые origin фронтенда через запятую
+	// (например, "https://app.example.com,https://example.com")
+	WebAuthnRPOrigins string `mapstructure:"WEBAUTHN_RP_ORIGINS"`
+	
+	// WebAuthnAttestation - запрашивать ли аттестацию ("none" или "direct")
+	// Принимаются форматы "none" и "packed"
+	WebAuthnAttestation string `mapstructure:"WEBAUTHN_ATTESTATION"`
+	
+	// WebAuthnTimeout - сколько действует challenge регистрации/входа (например, "5m")
+	WebAuthnTimeout string `mapstructure:"WEBAUTHN_TIMEOUT"`
+
+	// === IMPERSONATION SETTINGS ===
+	// Вход администратора от имени пользователя (POST /admin/users/:id/impersonate)
+	
+	// ImpersonationTTL - срок жизни токена имперсонации (например, "15m")
+	// Короткий: токен нельзя продлить, для продолжения нужен новый
+	ImpersonationTTL string `mapstructure:"IMPERSONATION_TTL"`
+
+	// === AUDIT SETTINGS ===
+	// Журнал событий безопасности (таблица audit_events)
+	
+	// AuditRetentionDays - сколько дней хранить события (0 - хранить всегда)
+	// Старые события удаляются фоновой задачей раз в сутки
+	AuditRetentionDays int `mapstructure:"AUDIT_RETENTION_DAYS"`
+
+	// === TENANT SETTINGS ===
+	// Организация запроса (см. internal/middleware/tenant.go)
+	
+	// TenantHeader - заголовок с slug организации (например, "X-Organization")
+	TenantHeader string `mapstructure:"TENANT_HEADER"`
+	
+	// TenantBaseDomain - базовый домен для поддоменов организаций
+	// "api.example.com" → acme.api.example.com = организация acme (пусто - выключено)
+	TenantBaseDomain string `mapstructure:"TENANT_BASE_DOMAIN"`
+	
+	// TenantDefault - slug организации, если она не указана в запросе
+	TenantDefault string `mapstructure:"TENANT_DEFAULT"`
+	
+	// InvitationURL - страница фронтенда, куда ведёт ссылка из приглашения
+	// К адресу добавляется ?token=... (см. POST /invitations/accept)
+	InvitationURL string `mapstructure:"INVITATION_URL"`
+	
+	// InvitationTTL - срок действия приглашения (например, "168h")
+	// Повторная отправка выдаёт новую ссылку и продлевает срок
+	InvitationTTL string `mapstructure:"INVITATION_TTL"`
+
+	// === POLICY SETTINGS ===
+	// Правила доступа на атрибутах (ABAC, см. internal/pkg/policy)
+	
+	// PolicyFiles - файлы или каталоги с правилами через запятую (пусто - ABAC выключен)
+	PolicyFiles string `mapstructure:"POLICY_FILES"`
+	
+	// PolicyMode - enforce (применять решения) или dry_run (только записывать в журнал)
+	PolicyMode string `mapstructure:"POLICY_MODE"`
+	
+	// PolicyDecisionLog - какие решения писать в лог: off, deny, all
+	PolicyDecisionLog string `mapstructure:"POLICY_DECISION_LOG"`
+	
+	// PolicyReloadInterval - как часто проверять изменения файлов ("30s", "0" - не проверять)
+	PolicyReloadInterval string `mapstructure:"POLICY_RELOAD_INTERVAL"`
+	
+	// PolicyTimezone - часовой пояс для context.hour/weekday ("Europe/Moscow")
+	PolicyTimezone string `mapstructure:"POLICY_TIMEZONE"`
+
+	// === ACCOUNT STATUS SETTINGS ===
+	// Статусы аккаунтов: pending, active, suspended, banned
+	
+	// RegistrationApproval - новые аккаунты ждут активации администратором (статус pending)
+	RegistrationApproval bool `mapstructure:"REGISTRATION_APPROVAL"`
+	
+	// AccountStatusCheckInterval - как часто снимать блокировки с истёкшим сроком ("1m", "0" - не снимать)
+	// Истёкшая блокировка не мешает входу и раньше - задача обновляет статус и историю
+	AccountStatusCheckInterval string `mapstructure:"ACCOUNT_STATUS_CHECK_INTERVAL"`
+
+	// UserPurgeAfterDays - через сколько дней удалённый аккаунт стирается окончательно
+	// До этого администратор может его восстановить (0 - не стирать автоматически)
+	UserPurgeAfterDays int `mapstructure:"USER_PURGE_AFTER_DAYS"`
+
+	// RequireIfMatch - PUT, PATCH и DELETE /users/:id без заголовка If-Match
+	// отклоняются (428): клиент обязан показать, какую версию он изменяет
+	RequireIfMatch bool `mapstructure:"REQUIRE_IF_MATCH"`
+
+	// === IDEMPOTENCY SETTINGS ===
+	// POST запросы с заголовком Idempotency-Key: повтор получает первый ответ
+
+	// IdempotencyStorage - где хранить ответы: "database", "memory" (один экземпляр API) или "off"
+	IdempotencyStorage string `mapstructure:"IDEMPOTENCY_STORAGE"`
+
+	// IdempotencyTTL - сколько хранится ответ и ключ нельзя использовать для другого запроса ("24h")
+	IdempotencyTTL string `mapstructure:"IDEMPOTENCY_TTL"`
+
+	// IdempotencyLockTTL - срок аренды ключа выполняющимся запросом ("1m"), продлевается, пока запрос выполняется
+	// Если процесс упал, не сохранив ответ, ключ освобождается через этот срок, а не через IdempotencyTTL
+	IdempotencyLockTTL string `mapstructure:"IDEMPOTENCY_LOCK_TTL"`
+
+	// IdempotencyMaxBodySize - наибольший размер тела запроса, читаемого в память для отпечатка,
+	// и сохраняемого ответа в байтах. Запрос больше хешируется по ходу чтения handler'ом,
+	// ответ больше - отдаётся, но не сохраняется
+	IdempotencyMaxBodySize int64 `mapstructure:"IDEMPOTENCY_MAX_BODY_SIZE"`
+
+	// === USER IMPORT SETTINGS ===
+	// Массовый импорт пользователей из CSV и NDJSON (POST /admin/users/import, cmd/import)
+
+	// UserImportDir - каталог для загруженных файлов до их обработки
+	UserImportDir string `mapstructure:"USER_IMPORT_DIR"`
+
+	// UserImportMaxSize - наибольший размер файла в байтах
+	UserImportMaxSize int64 `mapstructure:"USER_IMPORT_MAX_SIZE"`
+
+	// UserImportBatchSize - сколько строк записывается в одной транзакции
+	UserImportBatchSize int `mapstructure:"USER_IMPORT_BATCH_SIZE"`
+
+	// === USER EXPORT SETTINGS ===
+	// Потоковая выгрузка пользователей для аналитики (GET /admin/users/export)
+
+	// UserExportBatchSize - сколько пользователей читается из БД одним запросом
+	UserExportBatchSize int `mapstructure:"USER_EXPORT_BATCH_SIZE"`
+
+	// UserExportRowGroupSize - строк в группе файла Parquet (группа целиком в памяти)
+	UserExportRowGroupSize int `mapstructure:"USER_EXPORT_ROW_GROUP_SIZE"`
+
+	// UserExportCSVRaw - не экранировать в CSV значения, похожие на формулы
+	// (=, +, -, @, tab, CR в начале); по умолчанию к ним добавляется '
+	UserExportCSVRaw bool `mapstructure:"USER_EXPORT_CSV_RAW"`
+
+	// === DATA EXPORT SETTINGS ===
+	// Выгрузка персональных данных пользователя (GDPR)
+
+	// DataExportDir - каталог для готовых архивов
+	DataExportDir string `mapstructure:"DATA_EXPORT_DIR"`
+
+	// DataExportURL - внешний адрес endpoint скачивания (к нему добавляется /:id/download)
+	DataExportURL string `mapstructure:"DATA_EXPORT_URL"`
+
+	// DataExportTTL - сколько действует ссылка на скачивание ("24h"), затем архив удаляется
+	DataExportTTL string `mapstructure:"DATA_EXPORT_TTL"`
+
+	// ErasureCoolingOffDays - через сколько дней выполняется запрос на стирание данных
+	// До этого пользователь или администратор может отменить запрос (0 - стирать сразу)
+	ErasureCoolingOffDays int `mapstructure:"ERASURE_COOLING_OFF_DAYS"`
+
+	// === AVATAR SETTINGS ===
+	// Аватары пользователей (POST /users/me/avatar)
+
+	// AvatarStorage - где хранить файлы: "local" (каталог AVATAR_DIR)
+	AvatarStorage string `mapstructure:"AVATAR_STORAGE"`
+
+	// AvatarDir - каталог для AVATAR_STORAGE=local
+	AvatarDir string `mapstructure:"AVATAR_DIR"`
+
+	// AvatarURL - внешний адрес endpoint аватаров (к нему добавляется /:id/:size)
+	AvatarURL string `mapstructure:"AVATAR_URL"`
+
+	// AvatarMaxSize - наибольший размер загружаемого файла в байтах
+	AvatarMaxSize int64 `mapstructure:"AVATAR_MAX_SIZE"`
+
+	// === FIELD ENCRYPTION SETTINGS ===
+	// Шифрование персональных данных в БД (см. internal/pkg/fieldcrypt)
+
+	// FieldEncryptionKeyFile - JSON файл с ключами (пусто - шифрование выключено)
+	FieldEncryptionKeyFile string `mapstructure:"FIELD_ENCRYPTION_KEY_FILE"`
+
+	// FieldEncryptionFields - какие колонки users шифровать ("email,name,profile_phone")
+	FieldEncryptionFields string `mapstructure:"FIELD_ENCRYPTION_FIELDS"`
+
+	// FieldEncryptionReencryptInterval - как часто перешифровывать записи после
+	// смены ключа или списка полей ("1h", "0" - только при запуске)
+	FieldEncryptionReencryptInterval string `mapstructure:"FIELD_ENCRYPTION_REENCRYPT_INTERVAL"`
+
+	// === PASSWORD HASHING SETTINGS ===
+	// Настройки хеширования паролей (см. internal/pkg/password/hasher.go)
+	// При изменении настроек старые хеши продолжают работать и
+	// автоматически пересчитываются при следующем успешном входе
+	
+	// PasswordAlgorithm - алгоритм для новых хешей ("argon2id" или "bcrypt")
+	PasswordAlgorithm string `mapstructure:"PASSWORD_ALGORITHM"`
+	
+	// Argon2id: память (KiB), количество проходов и потоков
+	PasswordArgon2Memory      uint32 `mapstructure:"PASSWORD_ARGON2_MEMORY"`
+	PasswordArgon2Iterations  uint32 `mapstructure:"PASSWORD_ARGON2_ITERATIONS"`
+	PasswordArgon2Parallelism uint8  `mapstructure:"PASSWORD_ARGON2_PARALLELISM"`
+	
+	// PasswordBcryptCost - сложность bcrypt (10 = bcrypt.DefaultCost)
+	PasswordBcryptCost int `mapstructure:"PASSWORD_BCRYPT_COST"`
+	
+	// PasswordPepper - секрет сервера, подмешиваемый к паролю (HMAC-SHA256)
+	// Пустой - pepper не используется
+	// ВАЖНО: если потерять pepper, пароли с ним проверить будет невозможно!
+	PasswordPepper string `mapstructure:"PASSWORD_PEPPER"`
+
+	// === PASSWORD POLICY SETTINGS ===
+	// Правила для новых паролей (регистрация, смена, сброс)
+	
+	// Минимальная и максимальная длина пароля (в символах)
+	PasswordMinLength int `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength int `mapstructure:"PASSWORD_MAX_LENGTH"`
+	
+	// Обязательные классы символов
+	PasswordRequireLower  bool `mapstructure:"PASSWORD_REQUIRE_LOWER"`
+	PasswordRequireUpper  bool `mapstructure:"PASSWORD_REQUIRE_UPPER"`
+	PasswordRequireDigit  bool `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol bool `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	
+	// PasswordMinScore - минимальная оценка стойкости 0..4 (0 - не проверять)
+	PasswordMinScore int `mapstructure:"PASSWORD_MIN_SCORE"`
+	
+	// PasswordBannedWords - запрещённые слова через запятую (название компании и т.п.)
+	PasswordBannedWords string `mapstructure:"PASSWORD_BANNED_WORDS"`
+	
+	// PasswordBreachedCorpus - путь к базе утёкших паролей (SHA-1, формат Pwned Passwords)
+	// Файл "SHA1:COUNT" или каталог range-файлов "<PREFIX>.txt". Пусто - не проверять
+	PasswordBreachedCorpus string `mapstructure:"PASSWORD_BREACHED_CORPUS"`
+
+	// === LDAP SETTINGS ===
+	// Альтернативный источник учётных данных (Active Directory / OpenLDAP)
+	// Если LDAP включён, Login сначала проверяет локальный пароль,
+	// а затем выполняет search-and-bind в каталоге
+	
+	// LDAPEnabled - включить проверку учётных данных через LDAP
+	LDAPEnabled bool `mapstructure:"LDAP_ENABLED"`
+	
+	// LDAPURL - адрес сервера (например, "ldap://dc.example.com:389" или "ldaps://...:636")
+	LDAPURL string `mapstructure:"LDAP_URL"`
+	
+	// LDAPBindDN / LDAPBindPassword - сервисная учётная запись для поиска пользователей
+	// Если LDAPBindDN пустой - поиск выполняется анонимно
+	LDAPBindDN       string `mapstructure:"LDAP_BIND_DN"`
+	LDAPBindPassword string `mapstructure:"LDAP_BIND_PASSWORD"`
+	
+	// LDAPBaseDN - корень поиска пользователей (например, "ou=people,dc=example,dc=com")
+	LDAPBaseDN string `mapstructure:"LDAP_BASE_DN"`
+	
+	// LDAPUserFilter - фильтр поиска, %s заменяется на экранированный email
+	LDAPUserFilter string `mapstructure:"LDAP_USER_FILTER"`
+	
+	// Атрибуты каталога, из которых берутся email, имя и группы пользователя
+	LDAPEmailAttribute string `mapstructure:"LDAP_EMAIL_ATTRIBUTE"`
+	LDAPNameAttribute  string `mapstructure:"LDAP_NAME_ATTRIBUTE"`
+	LDAPGroupAttribute string `mapstructure:"LDAP_GROUP_ATTRIBUTE"`
+	
+	// LDAPGroupRoles - соответствие групп ролям в формате "groupDN=role;groupDN=role"
+	// Разделитель между DN и ролью - последний "=" в паре
+	// Порядок важен: побеждает первая совпавшая группа
+	LDAPGroupRoles string `mapstructure:"LDAP_GROUP_ROLES"`
+	
+	// LDAPDefaultRole - роль, если ни одна группа не совпала
+	LDAPDefaultRole string `mapstructure:"LDAP_DEFAULT_ROLE"`
+	
+	// LDAPStartTLS - выполнить StartTLS после подключения по ldap://
+	LDAPStartTLS bool `mapstructure:"LDAP_START_TLS"`
+	
+	// LDAPTimeout - таймаут сетевых операций с каталогом (например, "5s")
+	LDAPTimeout string `mapstructure:"LDAP_TIMEOUT"`
+
+	// === MAIL SETTINGS ===
+	// Отправка уведомлений пользователям (смена пароля и т.п.)
+	
+	// MailDriver - способ отправки: "log" (только в лог, для разработки) или "smtp"
+	MailDriver string `mapstructure:"MAIL_DRIVER"`
+	
+	// MailFrom - адрес отправителя (например, "Advanced User API <no-reply@example.com>")
+	MailFrom string `mapstructure:"MAIL_FROM"`
+	
+	// Параметры SMTP сервера (используются при MAIL_DRIVER=smtp)
+	// Если SMTPUsername пустой - отправка без аутентификации
+	SMTPHost     string `mapstructure:"SMTP_HOST"`
+	SMTPPort     string `mapstructure:"SMTP_PORT"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
 }
 
 // ================================================================
@@ -108,6 +408,121 @@ func Load() *Config {
 	
 	// Logging defaults
 	viper.SetDefault("LOG_LEVEL", "debug")
+	
+	// Login methods defaults (magic link выключен)
+	viper.SetDefault("LOGIN_METHODS", "password")
+	viper.SetDefault("MAGIC_LINK_URL", "http://localhost:3000/auth/magic-link")
+	viper.SetDefault("MAGIC_LINK_TTL", "15m")
+	viper.SetDefault("MAGIC_LINK_RATE_LIMIT", 3)
+	viper.SetDefault("MAGIC_LINK_RATE_WINDOW", "15m")
+	
+	// WebAuthn defaults (для локальной разработки)
+	viper.SetDefault("WEBAUTHN_RP_ID", "localhost")
+	viper.SetDefault("WEBAUTHN_RP_NAME", "Advanced User API")
+	viper.SetDefault("WEBAUTHN_RP_ORIGINS", "http://localhost:3000")
+	viper.SetDefault("WEBAUTHN_ATTESTATION", "none")
+	viper.SetDefault("WEBAUTHN_TIMEOUT", "5m")
+	
+	// Impersonation defaults
+	viper.SetDefault("IMPERSONATION_TTL", "15m")
+	
+	// Audit defaults
+	viper.SetDefault("AUDIT_RETENTION_DAYS", 365)
+	
+	// Tenant defaults
+	viper.SetDefault("TENANT_HEADER", "X-Organization")
+	viper.SetDefault("TENANT_BASE_DOMAIN", "")
+	viper.SetDefault("TENANT_DEFAULT", "default")
+	viper.SetDefault("INVITATION_URL", "http://localhost:3000/invitations/accept")
+	viper.SetDefault("INVITATION_TTL", "168h")
+	
+	// Policy defaults (ABAC выключен)
+	viper.SetDefault("POLICY_FILES", "")
+	viper.SetDefault("POLICY_MODE", "enforce")
+	viper.SetDefault("POLICY_DECISION_LOG", "deny")
+	viper.SetDefault("POLICY_RELOAD_INTERVAL", "30s")
+	viper.SetDefault("POLICY_TIMEZONE", "UTC")
+	
+	// Account status defaults (регистрация без подтверждения)
+	viper.SetDefault("REGISTRATION_APPROVAL", false)
+	viper.SetDefault("ACCOUNT_STATUS_CHECK_INTERVAL", "1m")
+	viper.SetDefault("USER_PURGE_AFTER_DAYS", 30)
+	viper.SetDefault("REQUIRE_IF_MATCH", false)
+
+	// Idempotency defaults (ответы в БД, сутки, тела до 1 МБ)
+	viper.SetDefault("IDEMPOTENCY_STORAGE", "database")
+	viper.SetDefault("IDEMPOTENCY_TTL", "24h")
+	viper.SetDefault("IDEMPOTENCY_LOCK_TTL", "1m")
+	viper.SetDefault("IDEMPOTENCY_MAX_BODY_SIZE", 1024*1024)
+
+	// User import defaults (файлы до 20 МБ, по 500 строк в транзакции)
+	viper.SetDefault("USER_IMPORT_DIR", "./data/imports")
+	viper.SetDefault("USER_IMPORT_MAX_SIZE", 20*1024*1024)
+	viper.SetDefault("USER_IMPORT_BATCH_SIZE", 500)
+
+	// User export defaults (по 1000 пользователей на запрос, группы Parquet по 10000 строк)
+	viper.SetDefault("USER_EXPORT_BATCH_SIZE", 1000)
+	viper.SetDefault("USER_EXPORT_ROW_GROUP_SIZE", 10000)
+	viper.SetDefault("USER_EXPORT_CSV_RAW", false)
+
+	// Data export defaults (ссылка на архив действует сутки)
+	viper.SetDefault("DATA_EXPORT_DIR", "./data/exports")
+	viper.SetDefault("DATA_EXPORT_URL", "http://localhost:8080/api/v1/exports")
+	viper.SetDefault("DATA_EXPORT_TTL", "24h")
+	viper.SetDefault("ERASURE_COOLING_OFF_DAYS", 14)
+
+	// Avatar defaults (файлы в локальном каталоге, до 5 МБ)
+	viper.SetDefault("AVATAR_STORAGE", "local")
+	viper.SetDefault("AVATAR_DIR", "./data/avatars")
+	viper.SetDefault("AVATAR_URL", "http://localhost:8080/api/v1/avatars")
+	viper.SetDefault("AVATAR_MAX_SIZE", 5*1024*1024)
+
+	// Field encryption defaults (выключено, пока не задан файл ключей)
+	viper.SetDefault("FIELD_ENCRYPTION_KEY_FILE", "")
+	viper.SetDefault("FIELD_ENCRYPTION_FIELDS", "email,name,profile_phone")
+	viper.SetDefault("FIELD_ENCRYPTION_REENCRYPT_INTERVAL", "1h")
+	
+	// Password hashing defaults (OWASP рекомендации для argon2id)
+	viper.SetDefault("PASSWORD_ALGORITHM", "argon2id")
+	viper.SetDefault("PASSWORD_ARGON2_MEMORY", 65536)
+	viper.SetDefault("PASSWORD_ARGON2_ITERATIONS", 3)
+	viper.SetDefault("PASSWORD_ARGON2_PARALLELISM", 2)
+	viper.SetDefault("PASSWORD_BCRYPT_COST", 10)
+	viper.SetDefault("PASSWORD_PEPPER", "")
+	
+	// Password policy defaults
+	viper.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	viper.SetDefault("PASSWORD_MAX_LENGTH", 128)
+	viper.SetDefault("PASSWORD_REQUIRE_LOWER", false)
+	viper.SetDefault("PASSWORD_REQUIRE_UPPER", false)
+	viper.SetDefault("PASSWORD_REQUIRE_DIGIT", false)
+	viper.SetDefault("PASSWORD_REQUIRE_SYMBOL", false)
+	viper.SetDefault("PASSWORD_MIN_SCORE", 2)
+	viper.SetDefault("PASSWORD_BANNED_WORDS", "")
+	viper.SetDefault("PASSWORD_BREACHED_CORPUS", "")
+	
+	// LDAP defaults (по умолчанию выключен)
+	viper.SetDefault("LDAP_ENABLED", false)
+	viper.SetDefault("LDAP_URL", "ldap://localhost:389")
+	viper.SetDefault("LDAP_BIND_DN", "")
+	viper.SetDefault("LDAP_BIND_PASSWORD", "")
+	viper.SetDefault("LDAP_BASE_DN", "")
+	viper.SetDefault("LDAP_USER_FILTER", "(&(objectClass=person)(mail=%s))")
+	viper.SetDefault("LDAP_EMAIL_ATTRIBUTE", "mail")
+	viper.SetDefault("LDAP_NAME_ATTRIBUTE", "displayName")
+	viper.SetDefault("LDAP_GROUP_ATTRIBUTE", "memberOf")
+	viper.SetDefault("LDAP_GROUP_ROLES", "")
+	viper.SetDefault("LDAP_DEFAULT_ROLE", "user")
+	viper.SetDefault("LDAP_START_TLS", false)
+	viper.SetDefault("LDAP_TIMEOUT", "5s")
+	
+	// Mail defaults (по умолчанию письма пишутся в лог)
+	viper.SetDefault("MAIL_DRIVER", "log")
+	viper.SetDefault("MAIL_FROM", "no-reply@localhost")
+	viper.SetDefault("SMTP_HOST", "localhost")
+	viper.SetDefault("SMTP_PORT", "587")
+	viper.SetDefault("SMTP_USERNAME", "")
+	viper.SetDefault("SMTP_PASSWORD", "")
 
 	// === ШАГ 3: ЧТЕНИЕ ENVIRONMENT VARIABLES ===
 	// AutomaticEnv() - автоматически читает переменные окружения
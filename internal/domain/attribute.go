@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ================================================================
+// USER ATTRIBUTES - Произвольные атрибуты пользователя по схеме
+// ================================================================
+// Продукты хранят свои данные о пользователе в колонке users.attributes (jsonb),
+// не добавляя колонок в User. Какие ключи допустимы, их тип и кто их видит,
+// задаёт администратор организации (AttributeDefinition)
+
+// Типы значений атрибутов
+const (
+	AttributeTypeString  = "string"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+)
+
+// Видимость атрибутов: кто видит значение в ответах API и может его изменить
+const (
+	AttributeVisibilityPublic  = "public"  // Все, кому доступен пользователь
+	AttributeVisibilityPrivate = "private" // Сам пользователь и администраторы
+	AttributeVisibilityAdmin   = "admin"   // Только администраторы
+)
+
+// AttributeDefinition - схема одного атрибута организации
+type AttributeDefinition struct {
+	ID uint `gorm:"primaryKey" json:"-"`
+
+	// OrganizationID - организация схемы (0 - без организаций)
+	// Ключ уникален в пределах организации
+	OrganizationID uint `gorm:"uniqueIndex:idx_attribute_definition_key;not null;default:0" json:"organization_id"`
+
+	// Key - ключ в users.attributes ("department", "cost_center")
+	Key string `gorm:"size:64;uniqueIndex:idx_attribute_definition_key;not null" json:"key"`
+
+	// Type - тип значения (AttributeType*)
+	Type string `gorm:"size:16;not null" json:"type"`
+
+	// Required - значение обязательно при регистрации и изменении атрибутов
+	Required bool `gorm:"not null;default:false" json:"required"`
+
+	// Enum - допустимые значения строкового атрибута (пусто - любые)
+	Enum []string `gorm:"serializer:json;type:jsonb" json:"enum,omitempty"`
+
+	// Pattern - регулярное выражение для строкового атрибута (RE2, пусто - без проверки)
+	Pattern string `gorm:"size:500" json:"pattern,omitempty"`
+
+	// Visibility - кто видит и меняет значение (AttributeVisibility*)
+	Visibility string `gorm:"size:16;not null;default:'public'" json:"visibility"`
+
+	// Description - описание для администраторов (необязательно)
+	Description string `json:"description,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName - имя таблицы в БД
+func (AttributeDefinition) TableName() string {
+	return "user_attribute_definitions"
+}
+
+// UserAttributes - значения атрибутов пользователя (колонка jsonb)
+// Значения - как после разбора JSON: string, float64, bool
+type UserAttributes map[string]interface{}
+
+// GormDataType - тип колонки для AutoMigrate
+func (UserAttributes) GormDataType() string {
+	return "jsonb"
+}
+
+// Value - сериализация для БД (пустой набор - {})
+func (a UserAttributes) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return "{}", nil
+	}
+	raw, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan - чтение из БД
+func (a *UserAttributes) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return errors.New("user attributes: неподдерживаемый тип")
+	}
+	return json.Unmarshal(raw, a)
+}
+
+// AttributeAccess - кто читает или изменяет атрибуты пользователя
+type AttributeAccess struct {
+	Self  bool // Сам пользователь (или регистрирующийся)
+	Admin bool // Роль admin
+}
+
+// Allows - доступен ли атрибут с видимостью visibility
+// Одно правило для чтения и записи: кто не видит значение, не может его и менять
+func (a AttributeAccess) Allows(visibility string) bool {
+	switch visibility {
+	case AttributeVisibilityPublic:
+		return true
+	case AttributeVisibilityPrivate:
+		return a.Self || a.Admin
+	default:
+		return a.Admin
+	}
+}
+
+// AttributeViolation - нарушение схемы одним атрибутом
+type AttributeViolation struct {
+	Key     string `json:"key"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Коды нарушений (поле AttributeViolation.Code)
+const (
+	AttributeViolationUnknown   = "unknown"   // Атрибут не описан схемой организации
+	AttributeViolationForbidden = "forbidden" // Видимость атрибута не позволяет его менять
+	AttributeViolationRequired  = "required"  // Обязательный атрибут не задан
+	AttributeViolationType      = "type"      // Значение другого типа
+	AttributeViolationEnum      = "enum"      // Значения нет в списке допустимых
+	AttributeViolationPattern   = "pattern"   // Строка не подходит под регулярное выражение
+	AttributeViolationTooLong   = "too_long"  // Строка длиннее допустимого
+)
+
+// AttributeValidationError - атрибуты не прошли проверку схемой
+// Содержит ВСЕ нарушения сразу, чтобы клиент мог показать их списком
+type AttributeValidationError struct {
+	Violations []AttributeViolation `json:"violations"`
+}
+
+// Error - реализация интерфейса error
+func (e *AttributeValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Key+": "+v.Message)
+	}
+	return "атрибуты не соответствуют схеме: " + strings.Join(messages, "; ")
+}
+
+// ================================================================
+// DTO - Запросы схемы атрибутов
+// ================================================================
+
+// AttributeDefinitionRequest - создание (POST) или замена (PUT) схемы атрибута
+// При замене ключ берётся из URL, поле key игнорируется
+type AttributeDefinitionRequest struct {
+	Key         string   `json:"key"`
+	Type        string   `json:"type" binding:"required,oneof=string number boolean"`
+	Required    bool     `json:"required"`
+	Enum        []string `json:"enum" binding:"omitempty,max=100,dive,min=1,max=200"`
+	Pattern     string   `json:"pattern" binding:"max=500"`
+	Visibility  string   `json:"visibility" binding:"required,oneof=public private admin"`
+	Description string   `json:"description" binding:"max=500"`
+}
@@ -0,0 +1,218 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ================================================================
+// AUDIT EVENT - Журнал событий безопасности
+// ================================================================
+
+// AuditEvent - запись о значимом действии с аккаунтом
+// Записи только добавляются и никогда не изменяются
+//
+// Защита от подделки - цепочка хэшей:
+// каждое событие хранит хэш предыдущего (PrevHash) и свой хэш (Hash),
+// посчитанный от PrevHash и всех полей. Изменение или удаление записи
+// из середины журнала ломает цепочку (см. AuditLogService.Verify)
+type AuditEvent struct {
+	// ID - уникальный идентификатор события (порядок в цепочке)
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// ActorID - кто выполнил действие (nil - анонимный запрос или система)
+	ActorID *uint `gorm:"index" json:"actor_id"`
+
+	// TargetID - над каким пользователем выполнено действие
+	TargetID *uint `gorm:"index" json:"target_id"`
+
+	// Action - тип события (см. константы AuditAction*)
+	// gorm:"index" - для выборки по типу события
+	Action string `gorm:"index;not null" json:"action"`
+
+	// IP и UserAgent клиента, выполнившего запрос
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+
+	// RequestID - ID HTTP запроса (заголовок X-Request-ID)
+	RequestID string `gorm:"index" json:"request_id,omitempty"`
+
+	// Details - подробности события (например, "GET /api/v1/users → 200")
+	Details string `gorm:"type:text" json:"details,omitempty"`
+
+	// Changes - изменённые поля: {"email": {"before": "...", "after": "..."}}
+	Changes AuditChanges `json:"changes,omitempty"`
+
+	// PrevHash и Hash - звенья цепочки (SHA-256 в hex)
+	// Hash пуст у событий, записанных до появления цепочки
+	PrevHash string `gorm:"size:64" json:"prev_hash"`
+	Hash     string `gorm:"size:64;index" json:"hash"`
+
+	// CreatedAt - время события
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName - имя таблицы в БД
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// ComputeHash - хэш события: SHA-256 от PrevHash и всех полей
+// ID в хэш не входит - его назначает БД при вставке
+// CreatedAt берётся с точностью до микросекунд (точность PostgreSQL)
+func (e *AuditEvent) ComputeHash() string {
+	var changes string
+	if len(e.Changes) > 0 {
+		raw, _ := json.Marshal(e.Changes)
+		changes = string(raw)
+	}
+
+	// JSON-массив - однозначная сериализация (разделители внутри значений не мешают)
+	payload, _ := json.Marshal([]interface{}{
+		e.PrevHash,
+		e.ActorID,
+		e.TargetID,
+		e.Action,
+		e.IP,
+		e.UserAgent,
+		e.RequestID,
+		e.Details,
+		changes,
+		e.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	})
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditChange - значение поля до и после изменения
+type AuditChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// AuditChanges - изменённые поля события (хранится в колонке jsonb)
+type AuditChanges map[string]AuditChange
+
+// GormDataType - тип колонки для AutoMigrate
+func (AuditChanges) GormDataType() string {
+	return "jsonb"
+}
+
+// Value - сериализация для БД (пустой набор - NULL)
+func (c AuditChanges) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return nil, nil
+	}
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan - чтение из БД
+func (c *AuditChanges) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return errors.New("audit changes: неподдерживаемый тип")
+	}
+	return json.Unmarshal(raw, c)
+}
+
+// Типы событий (значения поля Action)
+const (
+	AuditActionUserRegistered        = "auth.registered"             // Регистрация по паролю
+	AuditActionLoginSucceeded        = "auth.login"                  // Вход по паролю
+	AuditActionLoginFailed           = "auth.login_failed"           // Неверный email или пароль
+	AuditActionPasswordChanged       = "auth.password_changed"       // Пользователь сменил пароль
+	AuditActionMagicLinkRequested    = "auth.magic_link_requested"   // Запрошена ссылка для входа
+	AuditActionMagicLinkLogin        = "auth.magic_link_login"       // Вход по ссылке
+	AuditActionPasskeyAdded          = "auth.passkey_added"          // Зарегистрирован passkey
+	AuditActionPasskeyRemoved        = "auth.passkey_removed"        // Удалён passkey
+	AuditActionPasskeyLogin          = "auth.passkey_login"          // Вход по passkey
+	AuditActionPasskeyCloneDetected  = "auth.passkey_clone_detected" // Счётчик подписей не вырос
+	AuditActionUserUpdated           = "user.updated"                // Изменён профиль (Changes - что именно)
+	AuditActionUserDeleted           = "user.deleted"                // Пользователь удалён
+	AuditActionUserRestored          = "user.restored"               // Удалённый пользователь восстановлен
+	AuditActionUserPurged            = "user.purged"                 // Удалённый пользователь стёрт окончательно
+	AuditActionDataExportRequested   = "user.data_export_requested"  // Запрошена выгрузка персональных данных
+	AuditActionDataExportDownloaded  = "user.data_export_downloaded" // Архив с данными скачан по ссылке
+	AuditActionErasureRequested      = "user.erasure_requested"      // Запрошено стирание данных (Details - срок и причина)
+	AuditActionErasureCancelled      = "user.erasure_cancelled"      // Запрос на стирание отменён
+	AuditActionUserErased            = "user.erased"                 // Персональные данные пользователя стёрты
+	AuditActionImpersonationStarted  = "admin.impersonation_started" // Администратор вошёл от имени пользователя
+	AuditActionImpersonatedRequest   = "admin.impersonated_request"  // Запрос с токеном имперсонации
+	AuditActionRetentionPurged       = "audit.retention_purged"      // Удалены события старше срока хранения
+	AuditActionOrgCreated            = "org.created"                 // Создана организация
+	AuditActionOrgMemberRoleChanged  = "org.member_role_changed"     // Изменена роль участника (Changes - роль)
+	AuditActionOrgMemberRemoved      = "org.member_removed"          // Участник исключён из организации
+	AuditActionOrgInvitationSent     = "org.invitation_sent"         // Отправлено (или отправлено повторно) приглашение
+	AuditActionOrgInvitationAccepted = "org.invitation_accepted"     // Приглашение принято
+	AuditActionOrgInvitationDeclined = "org.invitation_declined"     // Приглашение отклонено
+	AuditActionOrgInvitationRevoked  = "org.invitation_revoked"      // Приглашение отозвано
+	AuditActionGroupMemberAdded      = "group.member_added"          // Пользователь добавлен в группу (Details - группа)
+	AuditActionGroupMemberRemoved    = "group.member_removed"        // Пользователь убран из группы
+	AuditActionGroupRoleGranted      = "group.role_granted"          // Группе выдана роль (Details - группа и роль)
+	AuditActionGroupRoleRevoked      = "group.role_revoked"          // У группы отозвана роль
+	AuditActionGroupDeleted          = "group.deleted"               // Группа удалена
+	AuditActionAttributeDeleted      = "attribute.deleted"           // Удалена схема атрибута вместе со значениями (Details - атрибут)
+	AuditActionUserStatusChanged     = "user.status_changed"         // Изменён статус аккаунта (Changes - статус, Details - причина)
+	AuditActionLoginBlocked          = "auth.login_blocked"          // Верный пароль, но аккаунт не активен
+	AuditActionUsersImported         = "user.imported"               // Импорт пользователей из файла завершён (Details - счётчики)
+	AuditActionUsersExported         = "user.exported"               // Выгрузка пользователей для аналитики (Details - формат, колонки, строки)
+)
+
+// ================================================================
+// DTO - Запросы и ответы журнала
+// ================================================================
+
+// AuditEventFilter - фильтры и пагинация для GET /admin/audit-events
+// Пустое поле - фильтр не применяется
+type AuditEventFilter struct {
+	ActorID   *uint      `form:"actor_id"`
+	TargetID  *uint      `form:"target_id"`
+	Action    string     `form:"action"`
+	RequestID string     `form:"request_id"`
+	From      *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"` // включительно
+	To        *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`   // не включительно
+
+	// Page - номер страницы (с 1), Limit - событий на странице (по умолчанию 50)
+	Page  int `form:"page" binding:"omitempty,min=1"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=200"`
+}
+
+// AuditEventPage - страница журнала (новые события первыми)
+type AuditEventPage struct {
+	Events []AuditEvent `json:"events"`
+	Total  int64        `json:"total"`
+	Page   int          `json:"page"`
+	Limit  int          `json:"limit"`
+}
+
+// AuditVerification - результат проверки цепочки хэшей
+type AuditVerification struct {
+	// Valid - цепочка не нарушена
+	Valid bool `json:"valid"`
+
+	// Checked - сколько событий проверено
+	Checked int64 `json:"checked"`
+
+	// BrokenAt - ID первого события, на котором цепочка нарушена
+	BrokenAt *uint `json:"broken_at,omitempty"`
+
+	// Reason - что именно не сошлось
+	Reason string `json:"reason,omitempty"`
+}
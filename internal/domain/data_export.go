@@ -0,0 +1,133 @@
+package domain
+
+import "time"
+
+// ================================================================
+// DATA EXPORT - Выгрузка персональных данных пользователя (GDPR)
+// ================================================================
+// Запрос на доступ к данным (subject access request) выполняется
+// асинхронно: создаётся задача DataExport, фоновый обработчик собирает
+// архив, после чего клиент получает подписанную ссылку на скачивание
+
+// Статусы задачи выгрузки
+const (
+	DataExportStatusPending    = "pending"    // Ждёт обработки
+	DataExportStatusProcessing = "processing" // Архив собирается
+	DataExportStatusCompleted  = "completed"  // Архив готов (до ExpiresAt)
+	DataExportStatusFailed     = "failed"     // Ошибка сборки (можно запросить заново)
+	DataExportStatusExpired    = "expired"    // Срок ссылки истёк, архив удалён
+)
+
+// Форматы архива
+const (
+	DataExportFormatZIP  = "zip"  // ZIP с отдельным JSON файлом на каждый раздел
+	DataExportFormatJSON = "json" // Один JSON документ
+)
+
+// DataExport - задача выгрузки данных пользователя
+type DataExport struct {
+	// ID - идентификатор задачи (входит в ссылку на скачивание)
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// UserID - чьи данные выгружаются
+	UserID uint `gorm:"index;not null" json:"user_id"`
+
+	// RequestedByID - кто запросил (сам пользователь или администратор)
+	RequestedByID uint `gorm:"not null" json:"requested_by_id"`
+
+	// Format - zip или json
+	Format string `gorm:"size:10;not null" json:"format"`
+
+	// Status - состояние задачи (см. константы DataExportStatus*)
+	Status string `gorm:"size:20;index;not null" json:"status"`
+
+	// Error - причина ошибки сборки (только failed)
+	Error string `gorm:"type:text" json:"error,omitempty"`
+
+	// FileName - имя файла архива в DATA_EXPORT_DIR (клиенту не показываем)
+	FileName string `json:"-"`
+
+	// Size - размер архива в байтах
+	Size int64 `json:"size,omitempty"`
+
+	// CreatedAt - время запроса
+	CreatedAt time.Time `json:"created_at"`
+
+	// StartedAt - когда обработчик взял задачу (зависшую задачу можно взять повторно)
+	StartedAt *time.Time `json:"started_at,omitempty"`
+
+	// CompletedAt - когда архив собран
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	// ExpiresAt - до какого момента действует ссылка (затем архив удаляется)
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at,omitempty"`
+
+	// DownloadURL - подписанная ссылка на скачивание (не хранится в БД)
+	DownloadURL string `gorm:"-" json:"download_url,omitempty"`
+}
+
+// TableName - имя таблицы в БД
+func (DataExport) TableName() string {
+	return "data_exports"
+}
+
+// CreateDataExportRequest - запрос выгрузки (тело необязательно)
+type CreateDataExportRequest struct {
+	// Format - zip (по умолчанию) или json
+	Format string `json:"format" binding:"omitempty,oneof=zip json"`
+}
+
+// ================================================================
+// СОДЕРЖИМОЕ АРХИВА
+// ================================================================
+
+// UserDataArchive - все данные, связанные с пользователем
+// В ZIP каждый раздел - отдельный файл (user.json, sessions.json, ...)
+type UserDataArchive struct {
+	// GeneratedAt - момент сборки архива
+	GeneratedAt time.Time `json:"generated_at"`
+
+	// User - профиль (без хеша пароля)
+	User User `json:"user"`
+
+	// Profile - расширенный профиль (у User поле скрыто из JSON)
+	Profile UserProfile `json:"profile"`
+
+	// Attributes - все атрибуты пользователя, включая видимые только администраторам
+	Attributes UserAttributes `json:"attributes"`
+
+	// Identities - способы входа: источник учётных данных и passkeys
+	Identities UserIdentities `json:"identities"`
+
+	// Sessions - все сеансы, включая завершённые
+	Sessions []Session `json:"sessions"`
+
+	// Organizations - членство в организациях
+	Organizations []Membership `json:"organizations"`
+
+	// Groups - группы, в которых пользователь состоит напрямую
+	Groups []Group `json:"groups"`
+
+	// StatusHistory - история статусов аккаунта
+	StatusHistory []UserStatusChange `json:"status_history"`
+
+	// AuditEvents - события журнала, где пользователь автор или цель
+	// Для событий, выполненных другими (администратором), IP и User-Agent
+	// автора не выгружаются - это чужие персональные данные
+	AuditEvents []AuditEvent `json:"audit_events"`
+}
+
+// UserIdentities - учётные данные пользователя
+type UserIdentities struct {
+	// AuthProvider - local или ldap
+	AuthProvider string `json:"auth_provider"`
+
+	// ExternalID - идентификатор во внешнем каталоге (DN для LDAP)
+	ExternalID string `json:"external_id,omitempty"`
+
+	// PasswordSet - задан ли локальный пароль (сам хеш не выгружается)
+	PasswordSet bool `json:"password_set"`
+
+	// Passkeys - зарегистрированные passkeys (без ключей)
+	Passkeys []WebAuthnCredential `json:"passkeys"`
+}
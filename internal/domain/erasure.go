@@ -0,0 +1,97 @@
+package domain
+
+import "time"
+
+// ================================================================
+// ERASURE - Право на удаление персональных данных (GDPR, ст. 17)
+// ================================================================
+// Мягкое удаление (Delete) оставляет email, имя и хеш пароля в БД.
+// Стирание обезличивает запись пользователя на месте: строка users остаётся,
+// чтобы события журнала (actor_id, target_id) по-прежнему на что-то ссылались,
+// но в ней не остаётся ничего, что указывает на человека.
+//
+// 1. Пользователь (DELETE /users/me) или администратор создаёт запрос
+// 2. ERASURE_COOLING_OFF_DAYS дней запрос можно отменить
+// 3. Фоновая задача стирает данные и закрывает запрос (completed)
+// Запрос остаётся навсегда - это подтверждение, что данные стёрты и почему
+
+// Статусы запроса на стирание
+const (
+	ErasureStatusPending   = "pending"   // Ждёт окончания периода отмены
+	ErasureStatusCancelled = "cancelled" // Отменён пользователем или администратором
+	ErasureStatusCompleted = "completed" // Данные стёрты
+)
+
+// ErasedEmailDomain - домен адресов обезличенных аккаунтов
+// .invalid зарезервирован (RFC 2606): письмо на такой адрес не уйдёт
+const ErasedEmailDomain = "erased.invalid"
+
+// UserErasure - запрос на стирание данных пользователя
+type UserErasure struct {
+	// ID - идентификатор запроса
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// UserID - чьи данные стираются (после стирания - ID обезличенной записи)
+	UserID uint `gorm:"index;not null" json:"user_id"`
+
+	// OrganizationID - организация пользователя (список запросов администратора)
+	OrganizationID uint `gorm:"index;not null;default:0" json:"organization_id"`
+
+	// RequestedByID - кто запросил (сам пользователь или администратор)
+	RequestedByID uint `gorm:"not null" json:"requested_by_id"`
+
+	// Reason - причина стирания (например, номер обращения к DPO)
+	Reason string `gorm:"type:text" json:"reason,omitempty"`
+
+	// Status - состояние запроса (см. константы ErasureStatus*)
+	Status string `gorm:"size:20;index;not null" json:"status"`
+
+	// ScheduledFor - когда данные будут стёрты (до этого запрос можно отменить)
+	ScheduledFor time.Time `gorm:"index;not null" json:"scheduled_for"`
+
+	// CancelledByID - кто отменил запрос
+	CancelledByID *uint `json:"cancelled_by_id,omitempty"`
+
+	// CancelledAt - когда запрос отменён
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+
+	// CompletedAt - когда данные стёрты
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	// CreatedAt - время запроса
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName - имя таблицы в БД
+func (UserErasure) TableName() string {
+	return "user_erasures"
+}
+
+// ================================================================
+// DTO - Запросы стирания
+// ================================================================
+
+// EraseAccountRequest - удаление своего аккаунта (DELETE /users/me)
+type EraseAccountRequest struct {
+	// Password - текущий пароль (повторная аутентификация)
+	// Аккаунту только с passkey пароль не нужен, но вход должен быть недавним
+	Password string `json:"password"`
+
+	// Reason - причина (необязательно)
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// CreateErasureRequest - стирание данных пользователя администратором
+type CreateErasureRequest struct {
+	// Reason - основание (обращение пользователя, решение DPO)
+	Reason string `json:"reason" binding:"required,min=3,max=500"`
+
+	// Immediate - стереть сразу, без периода отмены
+	Immediate bool `json:"immediate"`
+}
+
+// ErasureFilter - фильтр списка запросов (GET /admin/erasures)
+type ErasureFilter struct {
+	// Status - pending, cancelled или completed (пусто - все)
+	Status string `form:"status" binding:"omitempty,oneof=pending cancelled completed"`
+}
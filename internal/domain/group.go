@@ -0,0 +1,96 @@
+package domain
+
+import "time"
+
+// ================================================================
+// GROUP - Группы пользователей (команды)
+// ================================================================
+
+// Group - группа пользователей внутри организации
+// Группы вкладываются друг в друга (ParentID): участник вложенной группы
+// считается участником всех родительских и получает их роли
+type Group struct {
+	// ID - уникальный идентификатор группы
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// OrganizationID - организация группы (0 - без организаций)
+	// Название уникально в пределах организации
+	OrganizationID uint `gorm:"uniqueIndex:idx_group_organization_name;not null;default:0" json:"organization_id"`
+
+	// Name - название группы ("backend", "support-l2")
+	Name string `gorm:"size:100;uniqueIndex:idx_group_organization_name;not null" json:"name"`
+
+	// Description - описание (необязательно)
+	Description string `json:"description"`
+
+	// ParentID - родительская группа (nil - группа верхнего уровня)
+	ParentID *uint `gorm:"index" json:"parent_id"`
+
+	// Roles - роли, которые группа выдаёт участникам (таблица user_group_roles)
+	// gorm:"-" - не колонка, заполняется репозиторием
+	Roles []string `gorm:"-" json:"roles"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName - имя таблицы в БД
+// Не "groups": GROUPS - ключевое слово SQL (оконные функции)
+func (Group) TableName() string {
+	return "user_groups"
+}
+
+// GroupMember - прямое участие пользователя в группе
+type GroupMember struct {
+	ID uint `gorm:"primaryKey" json:"-"`
+
+	// Пара (группа, пользователь) уникальна
+	GroupID uint `gorm:"uniqueIndex:idx_group_member;not null" json:"group_id"`
+	UserID  uint `gorm:"uniqueIndex:idx_group_member;index;not null" json:"user_id"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName - имя таблицы в БД
+func (GroupMember) TableName() string {
+	return "user_group_members"
+}
+
+// GroupRole - роль, выданная группе
+// Участники группы и всех её вложенных групп проходят RequireRole(role)
+type GroupRole struct {
+	ID uint `gorm:"primaryKey" json:"-"`
+
+	GroupID uint   `gorm:"uniqueIndex:idx_group_role;not null" json:"group_id"`
+	Role    string `gorm:"size:32;uniqueIndex:idx_group_role;not null" json:"role"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName - имя таблицы в БД
+func (GroupRole) TableName() string {
+	return "user_group_roles"
+}
+
+// CreateGroupRequest - данные для создания группы
+type CreateGroupRequest struct {
+	Name        string `json:"name" binding:"required,min=2,max=100"`
+	Description string `json:"description" binding:"max=500"`
+
+	// ParentID - родительская группа (необязательно)
+	ParentID *uint `json:"parent_id"`
+}
+
+// UpdateGroupRequest - изменение группы (отсутствующие поля не меняются)
+type UpdateGroupRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=2,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+
+	// ParentID - новая родительская группа, 0 - сделать группой верхнего уровня
+	ParentID *uint `json:"parent_id"`
+}
+
+// GrantGroupRoleRequest - выдать роль группе
+type GrantGroupRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ================================================================
+// IDEMPOTENCY KEY - Повтор POST запросов без повторных действий
+// ================================================================
+// Клиент передаёт заголовок Idempotency-Key; первый ответ сохраняется
+// и отдаётся на повторы того же запроса вместо повторного выполнения
+
+var (
+	// ErrIdempotencyInProgress - запрос с этим ключом ещё выполняется (409)
+	ErrIdempotencyInProgress = errors.New("запрос с этим ключом идемпотентности ещё выполняется")
+
+	// ErrIdempotencyKeyReused - ключ уже использован для другого запроса (422)
+	ErrIdempotencyKeyReused = errors.New("ключ идемпотентности уже использован для другого запроса")
+)
+
+// IdempotencyRecord - запрос с ключом идемпотентности и его ответ
+type IdempotencyRecord struct {
+	// Key - SHA-256 (hex) от ключа клиента, автора запроса и маршрута:
+	// один и тот же ключ разных пользователей или endpoints не пересекается
+	Key string `gorm:"primaryKey;size:64" json:"-"`
+
+	// Fingerprint - SHA-256 метода, URL и тела запроса
+	// Тот же ключ с другим запросом - ошибка клиента, а не повтор
+	Fingerprint string `gorm:"size:64;not null" json:"-"`
+
+	// Status - HTTP статус ответа (0 - запрос ещё выполняется)
+	Status int `gorm:"not null;default:0" json:"-"`
+
+	// Headers - заголовки ответа (Content-Type, Location, ETag...)
+	Headers map[string][]string `gorm:"serializer:json;type:jsonb" json:"-"`
+
+	// Body - тело ответа
+	Body []byte `json:"-"`
+
+	// Rebuild - в Body не ответ, а результат выполнения (например, ID созданного
+	// пользователя): повтор снова вызывает handler, и тот строит ответ по результату.
+	// Так сохраняются ответы с выданным токеном - сам токен в хранилище не попадает
+	Rebuild bool `gorm:"not null;default:false" json:"-"`
+
+	// ExpiresAt - после этого момента ключ можно использовать заново
+	// (пока запрос выполняется - IDEMPOTENCY_LOCK_TTL, с ответом - IDEMPOTENCY_TTL)
+	// gorm:"index" - для удаления устаревших записей
+	ExpiresAt time.Time `gorm:"index;not null" json:"-"`
+
+	CreatedAt time.Time `json:"-"`
+}
+
+// TableName - имя таблицы в БД
+func (IdempotencyRecord) TableName() string {
+	return "idempotency_keys"
+}
+
+// Completed - ответ сохранён (запрос выполнен)
+func (r *IdempotencyRecord) Completed() bool {
+	return r.Status != 0
+}
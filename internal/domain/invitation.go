@@ -0,0 +1,89 @@
+package domain
+
+import "time"
+
+// ================================================================
+// INVITATION - Приглашение в организацию
+// ================================================================
+
+// Invitation - приглашение пользователя с email в организацию с ролью
+// Приглашённый получает ссылку с токеном и либо регистрируется,
+// либо входит в существующий аккаунт и принимает приглашение
+// В БД хранится только SHA-256 токена (как у magic link)
+type Invitation struct {
+	// ID - уникальный идентификатор
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// OrganizationID - куда приглашают
+	OrganizationID uint `gorm:"index;not null" json:"organization_id"`
+
+	// Email - кого приглашают (в нижнем регистре)
+	Email string `gorm:"index;not null" json:"email"`
+
+	// Role - роль в организации после принятия (см. константы OrgRole*)
+	Role string `gorm:"size:20;not null" json:"role"`
+
+	// TokenHash - SHA-256 токена из ссылки (hex)
+	// Меняется при повторной отправке: старая ссылка перестаёт работать
+	TokenHash string `gorm:"uniqueIndex;not null" json:"-"`
+
+	// Status - состояние приглашения (см. константы InvitationStatus*)
+	Status string `gorm:"size:20;index;not null" json:"status"`
+
+	// InvitedByID - кто пригласил
+	InvitedByID uint `json:"invited_by_id"`
+
+	// AcceptedUserID - аккаунт, которым приглашение принято
+	AcceptedUserID *uint `json:"accepted_user_id,omitempty"`
+
+	// ExpiresAt - после этого момента принять приглашение нельзя
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+
+	// SentAt и SendCount - последняя отправка письма и сколько раз оно отправлено
+	SentAt    time.Time `json:"sent_at"`
+	SendCount int       `gorm:"not null;default:1" json:"send_count"`
+
+	// RespondedAt - когда приглашение приняли, отклонили или отозвали
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName - имя таблицы в БД
+func (Invitation) TableName() string {
+	return "organization_invitations"
+}
+
+// Состояния приглашения (значения поля Invitation.Status)
+const (
+	InvitationStatusPending  = "pending"  // Ожидает ответа
+	InvitationStatusAccepted = "accepted" // Принято
+	InvitationStatusDeclined = "declined" // Отклонено приглашённым
+	InvitationStatusRevoked  = "revoked"  // Отозвано организацией
+)
+
+// CreateInvitationRequest - пригласить пользователя
+type CreateInvitationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+
+	// Role - роль после принятия (по умолчанию member)
+	Role string `json:"role" binding:"omitempty,oneof=owner admin member"`
+}
+
+// InvitationTokenRequest - принять (существующим аккаунтом) или отклонить приглашение
+type InvitationTokenRequest struct {
+	// Token - токен из ссылки
+	Token string `json:"token" binding:"required"`
+}
+
+// AcceptInvitationRegisterRequest - принять приглашение, зарегистрировав аккаунт
+// Email берётся из приглашения
+type AcceptInvitationRegisterRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Name     string `json:"name" binding:"required,min=2"`
+	Password string `json:"password" binding:"required"`
+
+	// Attributes - атрибуты по схеме организации (как при регистрации)
+	Attributes UserAttributes `json:"attributes"`
+}
@@ -0,0 +1,67 @@
+package domain
+
+import "time"
+
+// ================================================================
+// MAGIC LINK - Вход по ссылке из письма
+// ================================================================
+
+// MagicLinkToken - одноразовый токен для входа без пароля
+// В БД хранится только SHA-256 токена: утечка таблицы не даёт войти
+type MagicLinkToken struct {
+	// ID - уникальный идентификатор
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// TokenHash - SHA-256 токена (hex)
+	// gorm:"uniqueIndex" - поиск при входе идёт по хешу
+	TokenHash string `gorm:"uniqueIndex;not null" json:"-"`
+
+	// Email - для какого адреса запрошена ссылка (по нему считается лимит)
+	Email string `gorm:"index;not null" json:"email"`
+
+	// UserID - пользователь, для которого выдана ссылка
+	// nil - email не зарегистрирован: запись нужна только для лимита,
+	// письмо не отправляется, войти по ней нельзя
+	UserID *uint `json:"user_id"`
+
+	// DeviceHash - SHA-256 отпечатка устройства, запросившего ссылку
+	// Ссылку можно использовать только с того же устройства
+	DeviceHash string `gorm:"not null" json:"-"`
+
+	// IP - адрес клиента, запросившего ссылку
+	IP string `json:"ip"`
+
+	// ExpiresAt - после этого момента ссылка недействительна
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+
+	// UsedAt - когда ссылку использовали (nil - ещё не использована)
+	UsedAt *time.Time `json:"used_at"`
+
+	// CreatedAt - когда ссылка запрошена
+	// gorm:"index" - для подсчёта запросов за окно времени
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName - имя таблицы в БД
+func (MagicLinkToken) TableName() string {
+	return "magic_link_tokens"
+}
+
+// MagicLinkRequest - запрос ссылки для входа
+type MagicLinkRequest struct {
+	// Email - куда отправить ссылку
+	Email string `json:"email" binding:"required,email"`
+
+	// DeviceID - идентификатор устройства, сгенерированный клиентом (необязательно)
+	// Вместе с User-Agent образует отпечаток устройства
+	DeviceID string `json:"device_id" binding:"omitempty,max=128"`
+}
+
+// MagicLinkVerifyRequest - вход по токену из ссылки
+type MagicLinkVerifyRequest struct {
+	// Token - токен из ссылки
+	Token string `json:"token" binding:"required"`
+
+	// DeviceID - тот же идентификатор устройства, что и при запросе ссылки
+	DeviceID string `json:"device_id" binding:"omitempty,max=128"`
+}
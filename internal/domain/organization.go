@@ -0,0 +1,78 @@
+package domain
+
+import "time"
+
+// ================================================================
+// ORGANIZATION - Организация (тенант)
+// ================================================================
+
+// Organization - клиент, для которого работает API
+// Пользователи разных организаций изолированы друг от друга:
+// UserRepository видит только участников текущей организации
+type Organization struct {
+	// ID - уникальный идентификатор организации
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// Slug - короткое имя для поддомена и заголовка X-Organization (acme → acme.api.example.com)
+	// Не меняется после создания
+	Slug string `gorm:"size:63;uniqueIndex;not null" json:"slug"`
+
+	// Name - отображаемое название
+	Name string `gorm:"not null" json:"name"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName - имя таблицы в БД
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// DefaultOrganizationSlug - организация по умолчанию
+// Сюда попадают пользователи, существовавшие до появления организаций
+const DefaultOrganizationSlug = "default"
+
+// Membership - участие пользователя в организации с ролью в ней
+// Аккаунт создаётся в одной организации (User.OrganizationID),
+// но может состоять и в других (например, принял приглашение)
+type Membership struct {
+	ID uint `gorm:"primaryKey" json:"-"`
+
+	// Пара (организация, пользователь) уникальна
+	OrganizationID uint `gorm:"uniqueIndex:idx_organization_member;not null" json:"organization_id"`
+	UserID         uint `gorm:"uniqueIndex:idx_organization_member;index;not null" json:"user_id"`
+
+	// Role - роль в организации (см. константы OrgRole*)
+	// Не путать с User.Role - ролью на уровне всего сервиса
+	Role string `gorm:"size:20;not null" json:"role"`
+
+	// User - данные участника (заполняется при выводе списка участников)
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName - имя таблицы в БД
+func (Membership) TableName() string {
+	return "organization_members"
+}
+
+// Роли в организации (значения поля Membership.Role)
+const (
+	OrgRoleOwner  = "owner"  // Управляет организацией и её владельцами
+	OrgRoleAdmin  = "admin"  // Управляет участниками
+	OrgRoleMember = "member" // Обычный участник
+)
+
+// CreateOrganizationRequest - данные для создания организации
+type CreateOrganizationRequest struct {
+	// Slug - латиница в нижнем регистре, цифры и дефис (проверяется в сервисе)
+	Slug string `json:"slug" binding:"required,min=2,max=63"`
+	Name string `json:"name" binding:"required,min=2"`
+}
+
+// UpdateMemberRoleRequest - смена роли участника
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner admin member"`
+}
@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ================================================================
+// PASSKEYS - WebAuthn учётные данные
+// ================================================================
+
+// WebAuthnCredential - passkey, зарегистрированный пользователем
+// Хранит публичный ключ; приватный ключ никогда не покидает устройство
+type WebAuthnCredential struct {
+	// ID - идентификатор записи (используется в API управления passkeys)
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// UserID - владелец passkey
+	// gorm:"index" - для выборки всех ключей пользователя
+	UserID uint `gorm:"index;not null" json:"-"`
+
+	// Name - понятное пользователю название ("MacBook", "YubiKey")
+	Name string `gorm:"not null" json:"name"`
+
+	// CredentialID - идентификатор ключа, выданный аутентификатором
+	// gorm:"uniqueIndex" - по нему ищется ключ при входе
+	CredentialID []byte `gorm:"uniqueIndex;not null" json:"-"`
+
+	// PublicKey - публичный ключ в формате COSE
+	PublicKey []byte `gorm:"not null" json:"-"`
+
+	// AttestationType - формат аттестации при регистрации ("none", "packed")
+	AttestationType string `json:"attestation_type"`
+
+	// AAGUID - модель аутентификатора (нули, если аттестация не запрашивалась)
+	AAGUID []byte `json:"-"`
+
+	// SignCount - последнее значение счётчика подписей
+	// Если новое значение не больше сохранённого - ключ мог быть клонирован
+	SignCount uint32 `gorm:"not null;default:0" json:"-"`
+
+	// Transports - способы связи с аутентификатором через запятую ("internal,hybrid")
+	Transports string `json:"transports"`
+
+	// Флаги аутентификатора
+	// BackupEligible - ключ может синхронизироваться (iCloud Keychain, Google Password Manager)
+	// BackupState - ключ сейчас синхронизирован
+	BackupEligible bool `json:"backup_eligible"`
+	BackupState    bool `json:"backup_state"`
+
+	// LastUsedAt - время последнего входа с этим ключом (nil - ещё не использовался)
+	LastUsedAt *time.Time `json:"last_used_at"`
+
+	// CreatedAt / UpdatedAt - время регистрации и последнего изменения
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName - имя таблицы в БД
+func (WebAuthnCredential) TableName() string {
+	return "credentials"
+}
+
+// WebAuthnChallenge - незавершённая церемония регистрации или входа
+// Хранит challenge между запросами begin и finish; удаляется при использовании
+type WebAuthnChallenge struct {
+	// ID - случайный идентификатор, который клиент возвращает в finish
+	ID string `gorm:"primaryKey" json:"id"`
+
+	// Purpose - тип церемонии (см. константы PasskeyPurpose*)
+	Purpose string `gorm:"not null" json:"purpose"`
+
+	// UserID - пользователь (nil - регистрация нового аккаунта или вход)
+	UserID *uint `json:"user_id"`
+
+	// Email / Name / Handle - данные нового аккаунта (только для регистрации)
+	Email  string `json:"-"`
+	Name   string `json:"-"`
+	Handle string `json:"-"`
+
+	// Session - состояние церемонии (challenge, RP ID, параметры) в JSON
+	Session string `gorm:"type:text;not null" json:"-"`
+
+	// ExpiresAt - после этого момента завершить церемонию нельзя
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+
+	// CreatedAt - время начала церемонии
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName - имя таблицы в БД
+func (WebAuthnChallenge) TableName() string {
+	return "webauthn_challenges"
+}
+
+// Типы церемоний (значения поля Purpose)
+const (
+	PasskeyPurposeSignup   = "signup"   // Новый аккаунт только с passkey
+	PasskeyPurposeRegister = "register" // Добавление passkey к существующему аккаунту
+	PasskeyPurposeLogin    = "login"    // Вход
+)
+
+// ================================================================
+// DTO - запросы и ответы passkey API
+// ================================================================
+
+// PasskeySignupRequest - начало регистрации аккаунта без пароля
+type PasskeySignupRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Name  string `json:"name" binding:"required,min=2"`
+}
+
+// PasskeyLoginRequest - начало входа
+// Email принимается для совместимости, но не используется: браузер всегда
+// предлагает любой passkey для этого сайта (ответ не зависит от email)
+type PasskeyLoginRequest struct {
+	Email string `json:"email" binding:"omitempty,email"`
+}
+
+// PasskeyFinishRequest - завершение церемонии
+type PasskeyFinishRequest struct {
+	// ChallengeID - идентификатор из ответа begin
+	ChallengeID string `json:"challenge_id" binding:"required"`
+
+	// Name - название нового passkey (только для регистрации, необязательно)
+	Name string `json:"name" binding:"omitempty,max=64"`
+
+	// Credential - результат navigator.credentials.create() / get() в JSON
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// RenamePasskeyRequest - переименование passkey
+type RenamePasskeyRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=64"`
+}
+
+// PasskeyChallengeResponse - ответ begin: параметры для navigator.credentials
+type PasskeyChallengeResponse struct {
+	// ChallengeID - нужно вернуть в запросе finish
+	ChallengeID string `json:"challenge_id"`
+
+	// Options - передаются в navigator.credentials.create() / get()
+	Options interface{} `json:"options"`
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// ================================================================
+// POLICY - Проверка правил доступа администратором
+// ================================================================
+
+// ExplainPolicyRequest - запрос POST /admin/policies/explain
+// Решение считается для указанного (или текущего) пользователя, но не применяется
+type ExplainPolicyRequest struct {
+	// SubjectID - от чьего имени проверить (0 - текущий пользователь)
+	SubjectID uint `json:"subject_id"`
+
+	// Subject - атрибуты субъекта поверх загруженных ("что, если бы role была support")
+	Subject map[string]interface{} `json:"subject"`
+
+	// Action - действие ("users:update")
+	Action string `json:"action" binding:"required"`
+
+	// ResourceType и ResourceID - ресурс из БД ("user", 7)
+	ResourceType string `json:"resource_type"`
+	ResourceID   uint   `json:"resource_id"`
+
+	// Resource - атрибуты ресурса поверх загруженных (или вместо них)
+	Resource map[string]interface{} `json:"resource"`
+
+	// Context - атрибуты запроса поверх текущих
+	Context map[string]interface{} `json:"context"`
+
+	// Time - время проверки (пусто - сейчас): "а в субботу ночью?"
+	Time *time.Time `json:"time"`
+}
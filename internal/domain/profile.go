@@ -0,0 +1,65 @@
+package domain
+
+import "time"
+
+// ================================================================
+// USER PROFILE - Расширенный профиль пользователя
+// ================================================================
+
+// UserProfile - профиль, хранится в колонках profile_* таблицы users
+// (поле User.Profile). Все поля необязательные
+type UserProfile struct {
+	// DisplayName - имя для показа другим (пусто - показывается User.Name)
+	DisplayName string `gorm:"size:100" json:"display_name"`
+
+	// Bio - о себе
+	Bio string `gorm:"type:text" json:"bio"`
+
+	// Phone - телефон в формате E.164 (+79991234567)
+	// serializer:encrypted - шифруется в БД, если profile_phone есть в FIELD_ENCRYPTION_FIELDS
+	Phone string `gorm:"serializer:encrypted" json:"phone"`
+
+	// Timezone - часовой пояс IANA ("Europe/Moscow")
+	Timezone string `gorm:"size:64" json:"timezone"`
+
+	// Locale - язык интерфейса, тег BCP 47 ("ru", "en-US")
+	Locale string `gorm:"size:35" json:"locale"`
+
+	// AvatarID - случайный идентификатор текущего аватара (пусто - аватара нет)
+	// Каждая загрузка получает новый ID, поэтому адрес файла никогда
+	// не меняет содержимое и может кешироваться бессрочно
+	// json:"-" - клиент получает готовые ссылки (ProfileResponse.AvatarURLs)
+	AvatarID string `gorm:"size:32" json:"-"`
+
+	// AvatarUpdatedAt - когда загружен текущий аватар
+	AvatarUpdatedAt *time.Time `json:"avatar_updated_at,omitempty"`
+}
+
+// ProfileResponse - профиль в ответе API
+type ProfileResponse struct {
+	UserID uint `json:"user_id"`
+
+	UserProfile
+
+	// AvatarURLs - ссылки на аватар по размеру стороны в пикселях
+	// {"512": "...", "128": "...", "64": "..."}; нет аватара - поле отсутствует
+	AvatarURLs map[string]string `json:"avatar_urls,omitempty"`
+}
+
+// UpdateProfileRequest - замена профиля (PUT): пустое поле очищает значение
+type UpdateProfileRequest struct {
+	// DisplayName - до 100 символов
+	DisplayName string `json:"display_name" binding:"max=100"`
+
+	// Bio - до 500 символов
+	Bio string `json:"bio" binding:"max=500"`
+
+	// Phone - E.164: "+", код страны и номер, до 15 цифр
+	Phone string `json:"phone" binding:"omitempty,e164"`
+
+	// Timezone - имя из базы часовых поясов IANA
+	Timezone string `json:"timezone" binding:"omitempty,timezone"`
+
+	// Locale - тег языка BCP 47
+	Locale string `json:"locale" binding:"omitempty,bcp47_language_tag"`
+}
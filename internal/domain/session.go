@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+// ================================================================
+// SESSION - Сеанс пользователя на устройстве
+// ================================================================
+
+// Session - один вход пользователя (пароль, magic link, passkey)
+// Каждый выданный JWT токен содержит ID сеанса (claim "sid"):
+// отозванный сеанс AuthMiddleware больше не пропускает
+type Session struct {
+	// ID - случайный идентификатор (значение claim "sid")
+	ID string `gorm:"primaryKey" json:"id"`
+
+	// UserID - владелец сеанса
+	// gorm:"index" - для списка сеансов пользователя
+	UserID uint `gorm:"index;not null" json:"-"`
+
+	// DeviceName - название устройства ("Chrome on macOS" или из заголовка X-Device-Name)
+	DeviceName string `json:"device_name"`
+
+	// UserAgent / IP - клиент на момент входа
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+
+	// CreatedAt - время входа
+	CreatedAt time.Time `json:"created_at"`
+
+	// LastSeenAt - последний запрос с токеном этого сеанса
+	// Обновляется не чаще раза в минуту, чтобы не писать в БД на каждый запрос
+	LastSeenAt time.Time `gorm:"not null" json:"last_seen_at"`
+
+	// ExpiresAt - когда истекает токен сеанса
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+
+	// RevokedAt - когда сеанс завершён (nil - активен)
+	RevokedAt *time.Time `json:"-"`
+
+	// Current - сеанс, с токеном которого сделан запрос (не хранится в БД)
+	Current bool `gorm:"-" json:"current"`
+}
+
+// TableName - имя таблицы в БД
+func (Session) TableName() string {
+	return "sessions"
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"slices"
 	"time"
 
 	"gorm.io/gorm" // GORM ORM библиотека
@@ -21,16 +22,34 @@ type User struct {
 	// json:"id" - в JSON будет поле "id"
 	ID uint `gorm:"primaryKey" json:"id"`
 
+	// OrganizationID - организация, в которой создан аккаунт
+	// Email уникален в пределах организации: один и тот же адрес
+	// может быть зарегистрирован у разных клиентов
+	// json:"organization_id" - клиент видит, к какой организации относится аккаунт
+	OrganizationID uint `gorm:"uniqueIndex:idx_users_organization_email,where:deleted_at IS NULL;uniqueIndex:idx_users_organization_email_index,where:deleted_at IS NULL AND email_index <> '';not null;default:0" json:"organization_id"`
+
 	// Email - электронная почта пользователя
-	// gorm:"uniqueIndex" - создаёт уникальный индекс (два одинаковых email быть не может)
+	// gorm:"uniqueIndex:idx_users_organization_email" - уникальный индекс (organization_id, email)
+	// where:deleted_at IS NULL - индекс частичный: удалённый аккаунт не мешает
+	// зарегистрироваться с тем же адресом
 	// gorm:"not null" - поле обязательно (не может быть NULL в БД)
+	// serializer:encrypted - шифруется в БД, если email есть в FIELD_ENCRYPTION_FIELDS
+	// (см. internal/pkg/fieldcrypt); зашифрованные значения уникальность
+	// не проверяют - её обеспечивает индекс по EmailIndex
 	// json:"email" - в JSON будет поле "email"
-	Email string `gorm:"uniqueIndex;not null" json:"email"`
+	Email string `gorm:"uniqueIndex:idx_users_organization_email,where:deleted_at IS NULL;not null;serializer:encrypted" json:"email"`
+
+	// EmailIndex - blind index email: HMAC-SHA256 с секретным ключом (hex)
+	// По нему FindByEmail находит пользователя, не расшифровывая email всех записей
+	// Пустой - шифрование не настроено или запись ещё не обработана перешифрованием
+	// json:"-" - внутренняя информация, не отдаём клиенту
+	EmailIndex string `gorm:"size:64;uniqueIndex:idx_users_organization_email_index" json:"-"`
 
 	// Name - имя пользователя
 	// gorm:"not null" - обязательное поле
+	// serializer:encrypted - шифруется в БД, если name есть в FIELD_ENCRYPTION_FIELDS
 	// json:"name" - в JSON будет поле "name"
-	Name string `gorm:"not null" json:"name"`
+	Name string `gorm:"not null;serializer:encrypted" json:"name"`
 
 	// Password - хеш пароля (НЕ сам пароль!)
 	// gorm:"not null" - обязательное поле
@@ -42,6 +61,62 @@ type User struct {
 	// json:"role" - в JSON будет поле "role"
 	Role string `gorm:"default:'user'" json:"role"`
 
+	// AuthProvider - откуда берутся учётные данные пользователя
+	// "local" - хеш пароля хранится в нашей БД (поле Password)
+	// "ldap" - пароль проверяет каталог, Password остаётся пустым
+	// json:"auth_provider" - клиент видит, как создан аккаунт
+	AuthProvider string `gorm:"default:'local';not null" json:"auth_provider"`
+
+	// ExternalID - идентификатор пользователя во внешнем каталоге (DN для LDAP)
+	// gorm:"index" - для быстрого поиска при синхронизации
+	// json:"-" - внутренняя информация, не отдаём клиенту
+	ExternalID string `gorm:"index" json:"-"`
+
+	// TokenVersion - текущая версия JWT токенов пользователя
+	// Попадает в каждый токен (claim "ver"); при смене пароля увеличивается,
+	// и AuthMiddleware отклоняет все токены со старой версией
+	// json:"-" - внутренняя информация, не отдаём клиенту
+	TokenVersion int `gorm:"default:0;not null" json:"-"`
+
+	// Version - версия записи для оптимистичной блокировки
+	// Увеличивается при каждом изменении; ETag ответа GET /users/:id - "версия"
+	// UPDATE выполняется с условием version = прочитанная версия: если запись
+	// успели изменить, изменение не применяется (ErrUserVersionConflict)
+	// json:"-" - клиент получает версию в заголовке ETag
+	Version uint `gorm:"default:1;not null" json:"-"`
+
+	// PasswordChangedAt - когда пароль меняли последний раз (nil - не меняли)
+	// json:"password_changed_at,omitempty" - клиент может показать "пароль изменён ..."
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty"`
+
+	// PasskeyHandle - WebAuthn user handle (случайные 32 байта, base64url)
+	// Генерируется при первой регистрации passkey и хранится в ключе на устройстве
+	// Пустой Password + непустой PasskeyHandle - аккаунт только с passkey
+	// json:"-" - внутренняя информация, не отдаём клиенту
+	PasskeyHandle string `gorm:"index" json:"-"`
+
+	// Status - статус аккаунта (см. константы UserStatus* в user_status.go)
+	// Войти и пользоваться токеном может только active
+	// gorm:"default:'active'" - существующие записи при миграции становятся active
+	Status string `gorm:"size:20;index;not null;default:'active'" json:"status"`
+
+	// StatusReason - причина последнего изменения статуса
+	StatusReason string `gorm:"type:text" json:"status_reason,omitempty"`
+
+	// SuspendedUntil - окончание временной блокировки (nil - бессрочно или не заблокирован)
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+
+	// Profile - отображаемое имя, о себе, телефон, часовой пояс, язык, аватар
+	// gorm:"embedded;embeddedPrefix:profile_" - колонки profile_* в таблице users
+	// json:"-" - отдаётся отдельным ресурсом GET /users/:id/profile
+	Profile UserProfile `gorm:"embedded;embeddedPrefix:profile_" json:"-"`
+
+	// Attributes - атрибуты по схеме организации (см. AttributeDefinition)
+	// gorm:"not null;default:'{}'" - существующие записи при миграции получают {}
+	// GIN индекс (jsonb_path_ops) ускоряет фильтр GET /users?attr.key=value
+	// json:"-" - в ответ попадают только видимые автору запроса (UserResponse)
+	Attributes UserAttributes `gorm:"not null;default:'{}'" json:"-"`
+
 	// CreatedAt - время создания записи
 	// GORM автоматически устанавливает при Create()
 	// json:"created_at" - в JSON будет поле "created_at"
@@ -62,6 +137,12 @@ type User struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// Источники учётных данных (значения поля AuthProvider)
+const (
+	AuthProviderLocal = "local" // Пароль хранится локально (bcrypt)
+	AuthProviderLDAP  = "ldap"  // Пароль проверяет LDAP / Active Directory
+)
+
 // TableName - переопределение имени таблицы в БД
 // По умолчанию GORM использует множественное число от имени структуры: User → "users"
 // Эта функция явно указывает имя таблицы (опционально, если нужно другое имя)
@@ -93,8 +174,18 @@ type RegisterRequest struct {
 	Name string `json:"name" binding:"required,min=2"`
 
 	// Password - пароль (будет хеширован перед сохранением)
-	// binding:"required,min=6" - минимум 6 символов
-	Password string `json:"password" binding:"required,min=6"`
+	// binding:"required" - обязательно
+	// Длину, стойкость и утечки проверяет политика паролей (password.Policy)
+	Password string `json:"password" binding:"required"`
+
+	// Attributes - значения атрибутов по схеме организации (необязательно)
+	// Обязательные атрибуты (required) с видимостью public и private нужно передать здесь
+	Attributes UserAttributes `json:"attributes"`
+
+	// Approved - аккаунт активен сразу, даже при REGISTRATION_APPROVAL=true
+	// (регистрация по приглашению: организация уже одобрила пользователя)
+	// json:"-" - клиент не может выставить поле сам
+	Approved bool `json:"-"`
 }
 
 // LoginRequest - данные для входа (аутентификации)
@@ -108,17 +199,100 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// UpdateUserRequest - данные для обновления профиля пользователя
+// UpdateUserRequest - изменяемые поля пользователя (документ пользователя)
+// PUT заменяет документ целиком: все поля обязательны, атрибуты, которых нет
+// в запросе, удаляются. PATCH применяет патч к этому же документу
+// (см. PatchUserRequest), результат проверяется теми же правилами
 type UpdateUserRequest struct {
-	// Name - новое имя
-	// binding:"omitempty,min=2" - валидация:
-	//   - omitempty: поле опционально (может не присутствовать)
-	//   - min=2: если присутствует, то минимум 2 символа
-	Name string `json:"name" binding:"omitempty,min=2"`
-
-	// Email - новый email
-	// binding:"omitempty,email" - опционально, но если есть - валидный email
-	Email string `json:"email" binding:"omitempty,email"`
+	// Name - имя
+	// binding:"required,min=2" - валидация:
+	//   - required: поле обязательно (PUT - полная замена)
+	//   - min=2: минимум 2 символа
+	Name string `json:"name" binding:"required,min=2"`
+
+	// Email - email
+	// binding:"required,email" - обязательно, валидный email
+	Email string `json:"email" binding:"required,email"`
+
+	// Role - роль; изменить её может только администратор и не себе
+	// Без изменений (то же значение) поле может передать кто угодно
+	// Набор ролей не фиксирован: LDAP выдаёт роли по группам каталога (LDAP_GROUP_ROLES)
+	Role string `json:"role" binding:"required,max=50"`
+
+	// Attributes - все атрибуты, которые видит автор запроса (см. UserResponse)
+	// Атрибут, которого нет в запросе, удаляется; атрибуты, невидимые автору,
+	// не меняются
+	Attributes UserAttributes `json:"attributes"`
+
+	// AsAdmin - изменение выполняет администратор: доступны роль и все атрибуты
+	// json:"-" - выставляет handler по роли автора запроса, не клиент
+	AsAdmin bool `json:"-"`
+
+	// Impersonated - запрос выполняется от имени пользователя (токен имперсонации):
+	// email менять нельзя, иначе администратор перехватит вход в аккаунт
+	Impersonated bool `json:"-"`
+
+	// IfMatch - версии из заголовка If-Match (nil - без проверки)
+	IfMatch VersionCondition `json:"-"`
+}
+
+// VersionCondition - предусловие If-Match: версии пользователя, с которыми
+// клиент согласен выполнить изменение
+// nil - проверки нет (заголовка нет или If-Match: *), пустой - не подходит ни одна
+type VersionCondition []uint
+
+// Allows - подходит ли текущая версия пользователя
+func (c VersionCondition) Allows(version uint) bool {
+	return c == nil || slices.Contains(c, version)
+}
+
+// Форматы PATCH /users/:id (заголовок Content-Type)
+const (
+	PatchFormatMerge = "application/merge-patch+json" // JSON Merge Patch (RFC 7396)
+	PatchFormatJSON  = "application/json-patch+json"  // JSON Patch (RFC 6902)
+)
+
+// PatchUserRequest - частичное изменение пользователя
+// Патч применяется к документу пользователя (UpdateUserRequest в JSON,
+// с атрибутами, которые видит автор), результат проверяется как тело PUT
+type PatchUserRequest struct {
+	// Format - PatchFormatMerge или PatchFormatJSON
+	Format string
+
+	// Patch - тело запроса: объект Merge Patch или массив операций JSON Patch
+	Patch []byte
+
+	// AsAdmin - изменение выполняет администратор (см. UpdateUserRequest.AsAdmin)
+	AsAdmin bool
+
+	// Impersonated - запрос от имени пользователя (см. UpdateUserRequest.Impersonated)
+	Impersonated bool
+
+	// IfMatch - версии из заголовка If-Match (nil - без проверки)
+	IfMatch VersionCondition
+}
+
+// ChangePasswordRequest - смена пароля текущим пользователем
+type ChangePasswordRequest struct {
+	// CurrentPassword - действующий пароль (подтверждение, что это владелец аккаунта)
+	// binding:"required" - обязательно
+	CurrentPassword string `json:"current_password" binding:"required"`
+
+	// NewPassword - новый пароль
+	// Длину, стойкость и утечки проверяет политика паролей (password.Policy)
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ClientInfo - сведения о клиенте, выполнившем запрос
+// Handler извлекает их из HTTP запроса, сервисы пишут в журнал событий
+type ClientInfo struct {
+	IP         string // IP адрес клиента
+	UserAgent  string // Заголовок User-Agent
+	DeviceName string // Заголовок X-Device-Name (необязательно, для списка сеансов)
+	RequestID  string // ID запроса (X-Request-ID) - связывает событие журнала с логами
+
+	// OrganizationID - организация, в которой выполняется запрос (попадает в claim "org")
+	OrganizationID uint
 }
 
 // AuthResponse - ответ после успешной регистрации или входа
@@ -132,3 +306,38 @@ type AuthResponse struct {
 	// Указатель *User позволяет вернуть nil если нужно
 	User *User `json:"user"`
 }
+
+// DeletedUser - удалённый (soft delete) пользователь в списке администратора
+type DeletedUser struct {
+	User
+
+	// DeletedAt - когда аккаунт удалён (у User поле скрыто из JSON)
+	DeletedAt time.Time `json:"deleted_at"`
+
+	// PurgeAt - когда запись будет удалена окончательно (nil - автоочистка выключена)
+	PurgeAt *time.Time `json:"purge_at,omitempty"`
+}
+
+// UserResponse - пользователь в ответе API вместе с атрибутами,
+// которые разрешено видеть автору запроса (у User поле скрыто из JSON)
+type UserResponse struct {
+	User
+
+	// Attributes - видимые атрибуты (без схемы организации - пусто)
+	Attributes UserAttributes `json:"attributes"`
+}
+
+// ImpersonationResponse - токен администратора для работы от имени пользователя
+type ImpersonationResponse struct {
+	// Token - JWT токен пользователя с claim "act" (реальный автор запросов)
+	Token string `json:"token"`
+
+	// User - пользователь, от имени которого выполняются запросы
+	User *User `json:"user"`
+
+	// ActorID - администратор, получивший токен
+	ActorID uint `json:"actor_id"`
+
+	// ExpiresAt - токен короткоживущий и не продлевается
+	ExpiresAt time.Time `json:"expires_at"`
+}
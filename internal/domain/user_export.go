@@ -0,0 +1,45 @@
+package domain
+
+// ================================================================
+// USER EXPORT - Потоковая выгрузка пользователей для аналитики
+// ================================================================
+// Все пользователи организации (или отобранные фильтром) одним ответом
+// в CSV, NDJSON или Parquet. Пользователи читаются из БД пачками по
+// возрастанию ID, поэтому прерванную выгрузку можно продолжить с
+// последнего полученного ID (cursor). Не путать с DataExport - архивом
+// персональных данных одного пользователя (GDPR)
+
+// Форматы выгрузки пользователей
+const (
+	UserExportFormatCSV     = "csv"     // Заголовок - имена колонок
+	UserExportFormatNDJSON  = "ndjson"  // По JSON объекту на пользователя
+	UserExportFormatParquet = "parquet" // Колоночный формат с типами (Apache Parquet)
+)
+
+// UserExportDefaultColumns - колонки, если ?columns не задан
+var UserExportDefaultColumns = []string{"id", "email", "name", "role", "status", "created_at"}
+
+// UserFilter - отбор пользователей (те же фильтры, что у GET /users)
+type UserFilter struct {
+	// GroupID - только участники группы и вложенных в неё групп (0 - без фильтра)
+	GroupID uint
+
+	// Attributes - атрибуты пользователя содержат все пары (пусто - без фильтра)
+	Attributes UserAttributes
+}
+
+// UserExportRequest - параметры выгрузки (query string)
+type UserExportRequest struct {
+	// Format - csv (по умолчанию), ndjson или parquet
+	Format string `form:"format" binding:"omitempty,oneof=csv ndjson parquet"`
+
+	// Columns - колонки через запятую: id,email,attr.department (см. UserExportDefaultColumns)
+	Columns string `form:"columns"`
+
+	// со следующего (0 - с начала); колонка id тогда обязательна
+	// со следующего (0 - с начала)
+	Cursor uint `form:"cursor"`
+
+	// Filter - из параметров group и attr.<key> (заполняет handler)
+	Filter UserFilter `form:"-"`
+}
@@ -0,0 +1,156 @@
+package domain
+
+import "time"
+
+// ================================================================
+// USER IMPORT - Массовое создание пользователей из файла
+// ================================================================
+// Администратор загружает CSV или NDJSON, задача выполняется в фоне:
+// все строки проверяются, затем записываются пачками (одна транзакция
+// на пачку). Ошибки - отдельно по каждой строке, остальные строки
+// импортируются
+
+// Статусы задачи импорта
+const (
+	UserImportStatusPending    = "pending"    // Ждёт обработки
+	UserImportStatusProcessing = "processing" // Строки проверяются и записываются
+	UserImportStatusCompleted  = "completed"  // Файл обработан (ошибки строк - в Errors)
+	UserImportStatusFailed     = "failed"     // Файл не удалось обработать целиком
+)
+
+// Форматы файла импорта
+const (
+	UserImportFormatCSV    = "csv"    // Заголовок: email,name,role,password,password_hash,attr.<key>...
+	UserImportFormatNDJSON = "ndjson" // По JSON объекту UserImportRow на строку
+)
+
+// MaxUserImportErrors - сколько ошибок строк хранится в задаче
+// Остальные только считаются (Failed) - отчёт не разрастается до размера файла
+const MaxUserImportErrors = 1000
+
+// UserImport - задача импорта пользователей
+type UserImport struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// OrganizationID - куда импортируются пользователи (0 - без организаций)
+	OrganizationID uint `gorm:"index;not null;default:0" json:"organization_id"`
+
+	// RequestedByID - администратор, загрузивший файл (0 - CLI)
+	RequestedByID uint `gorm:"not null;default:0" json:"requested_by_id"`
+
+	// Format - csv или ndjson
+	Format string `gorm:"size:10;not null" json:"format"`
+
+	// DryRun - только проверить строки, ничего не записывать
+	DryRun bool `gorm:"not null;default:false" json:"dry_run"`
+
+	// Upsert - существующий email обновляется (иначе - ошибка строки)
+	Upsert bool `gorm:"not null;default:false" json:"upsert"`
+
+	// Invite - строкам без пароля отправляется приглашение в организацию
+	// (иначе пароль или password_hash обязателен)
+	Invite bool `gorm:"not null;default:false" json:"invite"`
+
+	// Status - состояние задачи (см. константы UserImportStatus*)
+	Status string `gorm:"size:20;index;not null" json:"status"`
+
+	// Счётчики строк (в dry-run - сколько было бы создано, обновлено, приглашено)
+	Total     int `gorm:"not null;default:0" json:"total"`
+	Processed int `gorm:"not null;default:0" json:"processed"`
+	Created   int `gorm:"not null;default:0" json:"created"`
+	Updated   int `gorm:"not null;default:0" json:"updated"`
+	Invited   int `gorm:"not null;default:0" json:"invited"`
+	Failed    int `gorm:"not null;default:0" json:"failed"`
+
+	// Errors - ошибки строк (не больше MaxUserImportErrors)
+	Errors []UserImportRowError `gorm:"serializer:json;type:jsonb" json:"errors"`
+
+	// RoleChanges - существующие пользователи, которым импорт меняет роль
+	// (не больше MaxUserImportErrors; в dry-run - какие роли изменились бы)
+	RoleChanges []UserImportRoleChange `gorm:"serializer:json;type:jsonb" json:"role_changes"`
+
+	// Error - почему файл не обработан (только failed)
+	Error string `gorm:"type:text" json:"error,omitempty"`
+
+	// FileName - загруженный файл в USER_IMPORT_DIR (удаляется после обработки)
+	FileName string `json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// StartedAt - когда обработчик взял задачу (зависшую задачу можно взять повторно)
+	StartedAt *time.Time `json:"started_at,omitempty"`
+
+	// CompletedAt - когда обработка завершена
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// TableName - имя таблицы в БД
+func (UserImport) TableName() string {
+	return "user_imports"
+}
+
+// AddRowError - ошибка строки (счётчик Failed растёт всегда, отчёт - до лимита)
+func (i *UserImport) AddRowError(rowError UserImportRowError) {
+	i.Failed++
+	if len(i.Errors) < MaxUserImportErrors {
+		i.Errors = append(i.Errors, rowError)
+	}
+}
+
+// AddRoleChange - изменение роли существующего пользователя (отчёт - до лимита)
+func (i *UserImport) AddRoleChange(change UserImportRoleChange) {
+	if len(i.RoleChanges) < MaxUserImportErrors {
+		i.RoleChanges = append(i.RoleChanges, change)
+	}
+}
+
+// UserImportRoleChange - строка, изменившая роль существующего пользователя
+// Отдельно от счётчика Updated: повышение до admin должно быть заметно в отчёте
+type UserImportRoleChange struct {
+	Row   int    `json:"row"`
+	Email string `json:"email"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// UserImportRowError - почему строка не импортирована
+type UserImportRowError struct {
+	// Row - номер строки файла (с 1; в CSV строка 1 - заголовок)
+	Row int `json:"row"`
+
+	// Email - email строки (если удалось прочитать)
+	Email string `json:"email,omitempty"`
+
+	// Errors - все нарушения строки ("email: невалидный email", ...)
+	Errors []string `json:"errors"`
+}
+
+// UserImportRow - одна строка файла
+// CSV: колонки с теми же именами, атрибуты - колонки attr.<key>
+type UserImportRow struct {
+	Email string `json:"email" binding:"required,email"`
+	Name  string `json:"name" binding:"required,min=2"`
+
+	// Role - роль пользователя: user (по умолчанию) или admin
+	Role string `json:"role" binding:"omitempty,oneof=user admin"`
+
+	// Password - пароль в открытом виде (проверяется политикой паролей и хешируется)
+	Password string `json:"password" binding:"omitempty,max=128"`
+
+	// PasswordHash - готовый хеш в формате PHC: $argon2id$... или $2a$/$2b$/$2y$ (bcrypt)
+	// Переносится как есть - при следующем входе пересчитывается текущими настройками
+	PasswordHash string `json:"password_hash"`
+
+	// Attributes - атрибуты по схеме организации (проверяются правами администратора)
+	Attributes UserAttributes `json:"attributes"`
+}
+
+// CreateUserImportRequest - параметры импорта (query string, файл - тело запроса)
+type CreateUserImportRequest struct {
+	// Format - csv или ndjson (по умолчанию - по Content-Type)
+	Format string `form:"format" binding:"omitempty,oneof=csv ndjson"`
+
+	DryRun bool `form:"dry_run"`
+	Upsert bool `form:"upsert"`
+	Invite bool `form:"invite"`
+}
@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"time"
+)
+
+// ================================================================
+// USER STATUS - Жизненный цикл аккаунта
+// ================================================================
+// Статус определяет, может ли пользователь войти и пользоваться токеном.
+// Допустимые переходы:
+//
+//	pending   → active, banned
+//	active    → suspended, banned
+//	suspended → active, suspended (новый срок), banned
+//	banned    → active
+//
+// Каждое изменение сохраняется в истории (UserStatusChange) с причиной и автором
+
+// Статусы аккаунта (значения поля User.Status)
+const (
+	UserStatusPending   = "pending"   // Создан, ждёт активации администратором (REGISTRATION_APPROVAL)
+	UserStatusActive    = "active"    // Обычная работа
+	UserStatusSuspended = "suspended" // Временно заблокирован (возможно, до SuspendedUntil)
+	UserStatusBanned    = "banned"    // Заблокирован до решения администратора
+
+	// UserStatusErased - персональные данные стёрты по запросу (см. UserErasure)
+	// Конечный статус: переходов из него нет, запись остаётся только для ссылок журнала
+	UserStatusErased = "erased"
+)
+
+// userStatusTransitions - допустимые переходы между статусами
+// suspended → suspended - продление или сокращение срока блокировки
+var userStatusTransitions = map[string][]string{
+	UserStatusPending:   {UserStatusActive, UserStatusBanned},
+	UserStatusActive:    {UserStatusSuspended, UserStatusBanned},
+	UserStatusSuspended: {UserStatusActive, UserStatusSuspended, UserStatusBanned},
+	UserStatusBanned:    {UserStatusActive},
+}
+
+// CanTransitionUserStatus - допустим ли переход из статуса from в статус to
+func CanTransitionUserStatus(from, to string) bool {
+	for _, allowed := range userStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveStatus - статус с учётом истёкшей блокировки
+// Пустой статус (записи до появления статусов) - active
+// Блокировка с истёкшим SuspendedUntil уже не действует, даже если
+// фоновая задача ещё не перевела аккаунт в active
+func (u *User) EffectiveStatus(now time.Time) string {
+	switch {
+	case u.Status == "":
+		return UserStatusActive
+	case u.Status == UserStatusSuspended && u.SuspendedUntil != nil && !now.Before(*u.SuspendedUntil):
+		return UserStatusActive
+	}
+	return u.Status
+}
+
+// CheckStatus - ошибка *AccountStatusError, если аккаунт не активен
+func (u *User) CheckStatus(now time.Time) error {
+	status := u.EffectiveStatus(now)
+	if status == UserStatusActive {
+		return nil
+	}
+
+	err := &AccountStatusError{Status: status}
+	if status == UserStatusSuspended {
+		err.Until = u.SuspendedUntil
+	}
+	return err
+}
+
+// AccountStatusError - вход или запрос отклонён из-за статуса аккаунта
+// Handlers отвечают 403 с кодом ошибки (Code), чтобы клиент показал понятное сообщение
+type AccountStatusError struct {
+	Status string     // pending, suspended или banned
+	Until  *time.Time // Окончание блокировки (только suspended, nil - бессрочно)
+}
+
+// Error - сообщение для клиента (причина блокировки не раскрывается)
+func (e *AccountStatusError) Error() string {
+	switch e.Status {
+	case UserStatusPending:
+		return "аккаунт ожидает активации администратором"
+	case UserStatusSuspended:
+		if e.Until != nil {
+			return "аккаунт временно заблокирован до " + e.Until.UTC().Format(time.RFC3339)
+		}
+		return "аккаунт временно заблокирован"
+	}
+	return "аккаунт заблокирован"
+}
+
+// Code - машиночитаемый код: account_pending, account_suspended, account_banned
+func (e *AccountStatusError) Code() string {
+	return "account_" + e.Status
+}
+
+// UserStatusChange - запись истории статусов аккаунта
+// Записи только добавляются и никогда не изменяются
+type UserStatusChange struct {
+	// ID - уникальный идентификатор (порядок изменений)
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// UserID - чей статус изменён
+	UserID uint `gorm:"index;not null" json:"user_id"`
+
+	// FromStatus / ToStatus - статус до и после изменения
+	FromStatus string `gorm:"size:20;not null" json:"from_status"`
+	ToStatus   string `gorm:"size:20;not null" json:"to_status"`
+
+	// Reason - причина (обязательна для блокировки)
+	Reason string `gorm:"type:text" json:"reason,omitempty"`
+
+	// ActorID - администратор (nil - система, например истёк срок блокировки)
+	ActorID *uint `json:"actor_id"`
+
+	// Until - окончание блокировки (только для suspended)
+	Until *time.Time `json:"until,omitempty"`
+
+	// CreatedAt - время изменения
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName - имя таблицы в БД
+func (UserStatusChange) TableName() string {
+	return "user_status_changes"
+}
+
+// ================================================================
+// DTO - Запросы администратора
+// ================================================================
+
+// SuspendUserRequest - временная блокировка аккаунта
+type SuspendUserRequest struct {
+	// Reason - причина (видна в истории и журнале, пользователю не показывается)
+	Reason string `json:"reason" binding:"required,min=3,max=500"`
+
+	// Until - окончание блокировки в RFC 3339 (не указано - до разблокировки вручную)
+	Until *time.Time `json:"until"`
+}
+
+// ChangeUserStatusRequest - блокировка (ban) или восстановление аккаунта
+type ChangeUserStatusRequest struct {
+	// Reason - причина (для ban обязательна, см. AccountStatusService)
+	Reason string `json:"reason" binding:"max=500"`
+}
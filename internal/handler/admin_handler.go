@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// ADMIN HANDLER - HTTP обработчики для администраторов
+// ================================================================
+
+// AdminHandler - структура для обработки административных запросов
+type AdminHandler struct {
+	impersonationService service.ImpersonationService // Вход от имени пользователя
+	audit                service.AuditService         // Журнал запросов с токеном имперсонации
+	auditLog             service.AuditLogService      // Просмотр и проверка журнала
+	statuses             service.AccountStatusService // Блокировка аккаунтов (nil - endpoints не регистрируются)
+}
+
+// NewAdminHandler - конструктор
+func NewAdminHandler(impersonationService service.ImpersonationService, audit service.AuditService, auditLog service.AuditLogService, statuses service.AccountStatusService) *AdminHandler {
+	return &AdminHandler{
+		impersonationService: impersonationService,
+		audit:                audit,
+		auditLog:             auditLog,
+		statuses:             statuses,
+	}
+}
+
+// Impersonate выдаёт администратору короткоживущий токен пользователя
+// Endpoint: POST /api/v1/admin/users/:id/impersonate
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Response: {"token": "...", "user": {...}, "actor_id": 1, "expires_at": "..."}
+func (h *AdminHandler) Impersonate(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ ID ИЗ URL ===
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "невалидный ID",
+		})
+		return
+	}
+
+	// === ШАГ 2: ПОЛУЧЕНИЕ ID АДМИНИСТРАТОРА ИЗ КОНТЕКСТА ===
+	actorID := middleware.GetUserIDFromContext(c)
+	if actorID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "не удалось определить пользователя",
+		})
+		return
+	}
+
+	// === ШАГ 3: ВЫЗОВ SERVICE ===
+	response, err := h.impersonationService.ForTenant(tenantOf(c)).Impersonate(actorID, uint(id), clientInfo(c))
+	if errors.Is(err, service.ErrImpersonationTargetNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrImpersonationForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if middleware.RespondAccountStatus(c, err) {
+		// Неактивный аккаунт: токен не выдаётся и администратору
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// === ШАГ 4: ОТПРАВКА ОТВЕТА ===
+	respondToken(c, http.StatusOK, response)
+}
+
+// ListAuditEvents возвращает журнал событий с фильтрами и пагинацией
+// Endpoint: GET /api/v1/admin/audit-events?actor_id=1&action=auth.login_failed&from=...&page=2
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Response: {"events": [...], "total": 120, "page": 2, "limit": 50}
+// Журнал общий для сервиса, но администратор видит только события, автор или
+// цель которых - участник организации из токена
+func (h *AdminHandler) ListAuditEvents(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ ФИЛЬТРОВ ===
+	// from/to - в формате RFC 3339 (2025-10-16T00:00:00Z)
+	var filter domain.AuditEventFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: ВЫЗОВ SERVICE ===
+	page, err := h.auditLog.ForTenant(tenantOf(c)).List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения журнала",
+		})
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusOK, page)
+}
+
+// VerifyAuditChain проверяет цепочку хэшей журнала
+// Endpoint: GET /api/v1/admin/audit-events/verify
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Response: {"valid": true, "checked": 1024} или {"valid": false, "broken_at": 18, "reason": "..."}
+// Цепочка одна на все организации и проверяется целиком: ответ содержит
+// только счётчик и ID события, содержимое событий других организаций не отдаётся
+func (h *AdminHandler) VerifyAuditChain(c *gin.Context) {
+	result, err := h.auditLog.Verify()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка проверки журнала",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ================================================================
+// СТАТУС АККАУНТА
+// ================================================================
+
+// SuspendUser временно блокирует аккаунт и отзывает все его токены
+// Endpoint: POST /api/v1/admin/users/:id/suspend
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Body: {"reason": "подозрительная активность", "until": "2025-11-01T00:00:00Z"}
+// Response: пользователь с "status": "suspended"
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req domain.SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.statuses.ForTenant(tenantOf(c)).Suspend(id, &req, middleware.GetUserIDFromContext(c), clientInfo(c))
+	if err != nil {
+		respondStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// BanUser блокирует аккаунт до решения администратора
+// Endpoint: POST /api/v1/admin/users/:id/ban
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Body: {"reason": "спам"}
+// Response: пользователь с "status": "banned"
+func (h *AdminHandler) BanUser(c *gin.Context) {
+	h.changeStatus(c, h.statuses.ForTenant(tenantOf(c)).Ban)
+}
+
+// ReactivateUser активирует ожидающий аккаунт или снимает блокировку
+// Endpoint: POST /api/v1/admin/users/:id/reactivate
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Body (необязательно): {"reason": "проверка пройдена"}
+// Response: пользователь с "status": "active"
+func (h *AdminHandler) ReactivateUser(c *gin.Context) {
+	h.changeStatus(c, h.statuses.ForTenant(tenantOf(c)).Reactivate)
+}
+
+// UserStatusHistory возвращает историю статусов аккаунта (новые первыми)
+// Endpoint: GET /api/v1/admin/users/:id/status-history
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Response: [{"from_status": "active", "to_status": "suspended", "reason": "...", "actor_id": 1, ...}, ...]
+func (h *AdminHandler) UserStatusHistory(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	history, err := h.statuses.ForTenant(tenantOf(c)).History(id)
+	if err != nil {
+		respondStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// statusChange - Ban или Reactivate сервиса статусов
+type statusChange func(userID uint, req *domain.ChangeUserStatusRequest, actorID uint, client domain.ClientInfo) (*domain.User, error)
+
+// changeStatus - общий разбор запроса для ban и reactivate (тело необязательно)
+func (h *AdminHandler) changeStatus(c *gin.Context, change statusChange) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req domain.ChangeUserStatusRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	user, err := change(id, &req, middleware.GetUserIDFromContext(c), clientInfo(c))
+	if err != nil {
+		respondStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// respondStatusError - ошибка сервиса статусов → HTTP статус
+func respondStatusError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrStatusUserNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidStatusTransition):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrStatusSelfChange):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrStatusReasonRequired),
+		errors.Is(err, service.ErrSuspendUntilInPast):
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
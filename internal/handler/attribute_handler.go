@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// ATTRIBUTE HANDLER - Схемы атрибутов пользователей (только роль admin)
+// ================================================================
+
+// AttributeHandler - структура для обработки запросов к схемам атрибутов
+type AttributeHandler struct {
+	attributes service.AttributeService
+}
+
+// NewAttributeHandler - конструктор
+func NewAttributeHandler(attributes service.AttributeService) *AttributeHandler {
+	return &AttributeHandler{attributes: attributes}
+}
+
+// List возвращает схемы атрибутов организации
+// Endpoint: GET /api/v1/admin/attributes
+// Response: [{"key": "department", "type": "string", "required": true, "enum": [...], "visibility": "public", ...}, ...]
+func (h *AttributeHandler) List(c *gin.Context) {
+	definitions, err := h.attributes.ForTenant(tenantOf(c)).ListDefinitions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения атрибутов",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, definitions)
+}
+
+// Create описывает новый атрибут
+// Endpoint: POST /api/v1/admin/attributes
+// Body: {"key": "department", "type": "string", "required": true, "enum": ["sales", "support"], "visibility": "public"}
+func (h *AttributeHandler) Create(c *gin.Context) {
+	var req domain.AttributeDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	definition, err := h.attributes.ForTenant(tenantOf(c)).CreateDefinition(&req)
+	if err != nil {
+		respondAttributeDefinitionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, definition)
+}
+
+// Update заменяет схему атрибута (ключ не меняется)
+// Endpoint: PUT /api/v1/admin/attributes/:key
+// Body: {"type": "string", "pattern": "^CC-[0-9]{4}$", "visibility": "private"}
+func (h *AttributeHandler) Update(c *gin.Context) {
+	var req domain.AttributeDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	definition, err := h.attributes.ForTenant(tenantOf(c)).UpdateDefinition(c.Param("key"), &req)
+	if err != nil {
+		respondAttributeDefinitionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, definition)
+}
+
+// Delete удаляет схему атрибута и его значения у пользователей организации
+// Endpoint: DELETE /api/v1/admin/attributes/:key
+func (h *AttributeHandler) Delete(c *gin.Context) {
+	err := h.attributes.ForTenant(tenantOf(c)).DeleteDefinition(c.Param("key"), middleware.GetUserIDFromContext(c), clientInfo(c))
+	if err != nil {
+		respondAttributeDefinitionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "атрибут удалён",
+	})
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// respondAttributeDefinitionError - ошибка схемы атрибута → HTTP статус
+func respondAttributeDefinitionError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrAttributeNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrAttributeKeyTaken):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrInvalidAttributeKey),
+		errors.Is(err, service.ErrInvalidAttributeDefinition),
+		errors.Is(err, service.ErrInvalidAttributePattern):
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
+
+// respondAttributeError - отвечает 400 со списком нарушений схемы атрибутов
+// Возвращает true, если err - это *domain.AttributeValidationError (ответ уже отправлен)
+//
+// Формат ответа:
+//
+//	{
+//	  "error": "атрибуты не соответствуют схеме: ...",
+//	  "violations": [{"key": "department", "code": "enum", "message": "..."}]
+//	}
+func respondAttributeError(c *gin.Context, err error) bool {
+	var validationErr *domain.AttributeValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":      validationErr.Error(),
+		"violations": validationErr.Violations,
+	})
+	return true
+}
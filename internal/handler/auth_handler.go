@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"advanced-user-api/internal/domain"
 	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/pkg/password"
 	"advanced-user-api/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -36,6 +39,7 @@ func NewAuthHandler(authService service.AuthService, userService service.UserSer
 // Endpoint: POST /api/v1/auth/register
 // Body: {"email": "...", "name": "...", "password": "..."}
 // Response: {"token": "...", "user": {...}}
+// При REGISTRATION_APPROVAL=true: 202 {"message": "...", "code": "account_pending"}
 func (h *AuthHandler) Register(c *gin.Context) {
 	// === ШАГ 1: ПАРСИНГ И ВАЛИДАЦИЯ JSON ===
 	// Создаём структуру для приёма данных
@@ -45,7 +49,6 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	// Автоматически проверяет:
 	//   - required: поля обязательны
 	//   - email: валидный email
-	//   - min=6: минимальная длина пароля
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// Если валидация не прошла - возвращаем 400 Bad Request
 		// err.Error() содержит описание ошибки валидации
@@ -59,12 +62,41 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	// Передаём данные в Auth Service для регистрации
 	// Service:
 	//   - Проверит уникальность email
+	//   - Проверит пароль по политике
 	//   - Захеширует пароль
 	//   - Создаст пользователя в БД
 	//   - Сгенерирует JWT токен
-	authResponse, err := h.authService.Register(&req)
+	//
+	// Повтор с тем же Idempotency-Key: пользователь уже создан первым запросом,
+	// выдаётся только новый токен (сам ответ с токеном не хранится - см. middleware.Idempotency)
+	var authResponse *domain.AuthResponse
+	var err error
+	if result, replayed := middleware.IdempotencyResult(c); replayed {
+		userID, _ := strconv.ParseUint(string(result), 10, 64)
+		authResponse, err = h.authService.ForTenant(tenantOf(c)).ResumeRegistration(uint(userID), clientInfo(c))
+	} else {
+		authResponse, err = h.authService.ForTenant(tenantOf(c)).Register(&req, clientInfo(c))
+	}
+	if respondPasswordPolicyError(c, err) {
+		// Пароль не прошёл политику - клиент получает список причин
+		return
+	}
+	if respondAttributeError(c, err) {
+		// Атрибуты не прошли схему организации - клиент получает список нарушений
+		return
+	}
+	if respondAccountPending(c, err) {
+		// REGISTRATION_APPROVAL=true - аккаунт создан, токен после активации
+		return
+	}
+	if errors.Is(err, service.ErrEmailAlreadyRegistered) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 	if err != nil {
-		// Ошибка регистрации (email уже существует, ошибка БД, etc.)
+		// Ошибка регистрации (ошибка БД, etc.)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
@@ -73,7 +105,9 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
 	// Возвращаем 201 Created с токеном и данными пользователя
-	c.JSON(http.StatusCreated, authResponse)
+	// Для повтора по Idempotency-Key сохраняется только ID пользователя
+	middleware.SetIdempotencyResult(c, []byte(strconv.FormatUint(uint64(authResponse.User.ID), 10)))
+	respondToken(c, http.StatusCreated, authResponse)
 }
 
 // ================================================================
@@ -101,7 +135,18 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	//   - Найдёт пользователя по email
 	//   - Проверит пароль (bcrypt)
 	//   - Сгенерирует JWT токен
-	authResponse, err := h.authService.Login(&req)
+	authResponse, err := h.authService.ForTenant(tenantOf(c)).Login(&req, clientInfo(c))
+	if errors.Is(err, service.ErrLoginMethodDisabled) {
+		// Вход по паролю отключён в этой инсталляции (LOGIN_METHODS)
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if middleware.RespondAccountStatus(c, err) {
+		// Пароль верный, но аккаунт ожидает активации или заблокирован
+		return
+	}
 	if err != nil {
 		// Неверный email или пароль
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -112,7 +157,81 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
 	// Возвращаем 200 OK с токеном
-	c.JSON(http.StatusOK, authResponse)
+	respondToken(c, http.StatusOK, authResponse)
+}
+
+// ================================================================
+// MAGIC LINK - POST /auth/magic-link и POST /auth/magic-link/verify
+// ================================================================
+
+// RequestMagicLink отправляет ссылку для входа на email
+// Endpoint: POST /api/v1/auth/magic-link
+// Body: {"email": "...", "device_id": "..."}
+// Response: 202 {"message": "..."}
+//
+// Ответ одинаковый для зарегистрированных и неизвестных email
+func (h *AuthHandler) RequestMagicLink(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ И ВАЛИДАЦИЯ ===
+	var req domain.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: СОЗДАНИЕ ССЫЛКИ ===
+	err := h.authService.ForTenant(tenantOf(c)).RequestMagicLink(&req, clientInfo(c))
+	switch {
+	case errors.Is(err, service.ErrLoginMethodDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrMagicLinkRateLimited):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	// 202 Accepted - письмо отправлено (или будет отправлено)
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "если email зарегистрирован, на него отправлена ссылка для входа",
+	})
+}
+
+// VerifyMagicLink выполняет вход по токену из ссылки
+// Endpoint: POST /api/v1/auth/magic-link/verify
+// Body: {"token": "...", "device_id": "..."}
+// Response: {"token": "...", "user": {...}}
+func (h *AuthHandler) VerifyMagicLink(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ И ВАЛИДАЦИЯ ===
+	var req domain.MagicLinkVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: ВХОД ===
+	authResponse, err := h.authService.ForTenant(tenantOf(c)).RedeemMagicLink(&req, clientInfo(c))
+	if errors.Is(err, service.ErrLoginMethodDisabled) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if middleware.RespondAccountStatus(c, err) {
+		return
+	}
+	if err != nil {
+		// Ссылка не найдена, истекла, уже использована или открыта на другом устройстве
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	respondToken(c, http.StatusOK, authResponse)
 }
 
 // ================================================================
@@ -143,7 +262,7 @@ func (h *AuthHandler) Me(c *gin.Context) {
 
 	// === ШАГ 2: ПОЛУЧЕНИЕ ДАННЫХ ПОЛЬЗОВАТЕЛЯ ===
 	// Загружаем полные данные пользователя из БД
-	user, err := h.userService.GetCurrentUser(userID)
+	user, err := h.userService.ForTenant(tenantOf(c)).GetCurrentUser(userID)
 	if err != nil {
 		// Пользователь не найден (маловероятно, но возможно если удалён)
 		c.JSON(http.StatusNotFound, gin.H{
@@ -156,3 +275,128 @@ func (h *AuthHandler) Me(c *gin.Context) {
 	// Возвращаем данные пользователя (без пароля - json:"-")
 	c.JSON(http.StatusOK, user)
 }
+
+// ================================================================
+// CHANGE PASSWORD - POST /auth/password/change (защищённый endpoint)
+// ================================================================
+
+// ChangePassword меняет пароль текущего пользователя
+// Endpoint: POST /api/v1/auth/password/change
+// Headers: Authorization: Bearer TOKEN
+// Body: {"current_password": "...", "new_password": "..."}
+// Response: {"token": "...", "user": {...}}
+//
+// После смены пароля ВСЕ ранее выданные токены перестают работать,
+// поэтому клиент должен заменить свой токен на токен из ответа
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	// === ШАГ 1: ПОЛУЧЕНИЕ ID ИЗ КОНТЕКСТА ===
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "не удалось определить пользователя",
+		})
+		return
+	}
+
+	// === ШАГ 2: ПАРСИНГ И ВАЛИДАЦИЯ ===
+	var req domain.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 3: СМЕНА ПАРОЛЯ ===
+	// Service:
+	//   - Проверит текущий пароль
+	//   - Проверит новый пароль по политике
+	//   - Отзовёт все токены, запишет событие и отправит письмо
+	authResponse, err := h.authService.ForTenant(tenantOf(c)).ChangePassword(userID, &req, clientInfo(c))
+	if respondPasswordPolicyError(c, err) {
+		return
+	}
+	if err != nil {
+		// Текущий пароль неверный - 403, чтобы клиент не принял это за истёкший токен
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrWrongCurrentPassword) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 4: ОТПРАВКА ОТВЕТА ===
+	respondToken(c, http.StatusOK, authResponse)
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// respondPasswordPolicyError - отвечает 400 со списком нарушений политики паролей
+// Возвращает true, если err - это *password.PolicyError (ответ уже отправлен)
+//
+// Формат ответа:
+//   {
+//     "error": "пароль не соответствует политике: ...",
+//     "reasons": [{"code": "too_short", "message": "..."}],
+//     "strength": {"score": 1, "guesses": 4.2}
+//   }
+func respondPasswordPolicyError(c *gin.Context, err error) bool {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":    policyErr.Error(),
+		"reasons":  policyErr.Violations,
+		"strength": policyErr.Strength,
+	})
+	return true
+}
+
+// respondAccountPending - отвечает 202, если аккаунт создан, но ждёт активации
+// Возвращает true, если ответ отправлен
+func respondAccountPending(c *gin.Context, err error) bool {
+	var statusErr *domain.AccountStatusError
+	if !errors.As(err, &statusErr) || statusErr.Status != domain.UserStatusPending {
+		return false
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "аккаунт создан и ожидает активации администратором",
+		"code":    statusErr.Code(),
+	})
+	return true
+}
+
+// respondToken - ответ с JWT токеном (вход, регистрация, имперсонация)
+// Cache-Control: no-store - токен не кешируется прокси и не сохраняется
+// для повтора по Idempotency-Key (см. middleware.Idempotency)
+func respondToken(c *gin.Context, status int, response interface{}) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(status, response)
+}
+
+// clientInfo - IP, User-Agent и название устройства клиента для журнала событий и сеансов
+// c.ClientIP() учитывает X-Forwarded-For только от доверенных прокси (см. gin SetTrustedProxies)
+func clientInfo(c *gin.Context) domain.ClientInfo {
+	return domain.ClientInfo{
+		IP:             c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+		DeviceName:     c.GetHeader("X-Device-Name"),
+		RequestID:      middleware.GetRequestIDFromContext(c),
+		OrganizationID: tenantOf(c),
+	}
+}
+
+// tenantOf - организация запроса (0 - без организаций, см. TenantMiddleware)
+// Сервисы с пользователями вызываются через ForTenant(tenantOf(c))
+func tenantOf(c *gin.Context) uint {
+	return middleware.GetTenantIDFromContext(c)
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// DATA EXPORT HANDLER - Выгрузка персональных данных (GDPR)
+// ================================================================
+
+// DataExportHandler - структура для обработки запросов выгрузки
+type DataExportHandler struct {
+	exports service.DataExportService // Зависимость от Data Export Service
+}
+
+// NewDataExportHandler - конструктор
+func NewDataExportHandler(exports service.DataExportService) *DataExportHandler {
+	return &DataExportHandler{exports: exports}
+}
+
+// RequestOwn - выгрузка данных текущего пользователя
+// Endpoint: POST /api/v1/users/me/export
+// Body (необязательно): {"format": "zip" | "json"}
+// Response 202: задача выгрузки {"id": 3, "status": "pending", ...}
+func (h *DataExportHandler) RequestOwn(c *gin.Context) {
+	h.request(c, middleware.GetUserIDFromContext(c))
+}
+
+// GetOwn - статус своей выгрузки (готовая содержит download_url)
+// Endpoint: GET /api/v1/users/me/exports/:id
+func (h *DataExportHandler) GetOwn(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	export, err := h.exports.ForTenant(tenantOf(c)).Get(id)
+	if err == nil && export.UserID != middleware.GetUserIDFromContext(c) {
+		err = service.ErrDataExportNotFound
+	}
+	if err != nil {
+		respondDataExportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, export)
+}
+
+// RequestForUser - выгрузка данных пользователя по запросу администратора
+// Endpoint: POST /api/v1/admin/users/:id/export
+func (h *DataExportHandler) RequestForUser(c *gin.Context) {
+	userID, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	h.request(c, userID)
+}
+
+// GetForAdmin - статус любой выгрузки пользователя организации
+// Endpoint: GET /api/v1/admin/exports/:id
+func (h *DataExportHandler) GetForAdmin(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	export, err := h.exports.ForTenant(tenantOf(c)).Get(id)
+	if err != nil {
+		respondDataExportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, export)
+}
+
+// Download - скачивание архива по подписанной ссылке (без токена)
+// Endpoint: GET /api/v1/exports/:id/download?expires=...&signature=...
+func (h *DataExportHandler) Download(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	file, err := h.exports.Open(id, c.Query("expires"), c.Query("signature"), clientInfo(c))
+	if err != nil {
+		respondDataExportError(c, err)
+		return
+	}
+
+	// Архив с персональными данными не должен оседать в кешах прокси
+	c.Header("Cache-Control", "no-store")
+	c.FileAttachment(file.Path, file.Name)
+}
+
+// request - общая часть RequestOwn и RequestForUser
+func (h *DataExportHandler) request(c *gin.Context, userID uint) {
+	var req domain.CreateDataExportRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	export, err := h.exports.ForTenant(tenantOf(c)).Request(userID, middleware.GetUserIDFromContext(c), &req, clientInfo(c))
+	if err != nil {
+		respondDataExportError(c, err)
+		return
+	}
+
+	// 202 Accepted - архив собирается в фоне, статус: GET .../exports/:id
+	c.JSON(http.StatusAccepted, export)
+}
+
+// respondDataExportError - ошибка сервиса выгрузки → HTTP статус
+func respondDataExportError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrDataExportNotFound),
+		errors.Is(err, service.ErrDataExportUserNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrDataExportInProgress):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrDataExportLinkInvalid):
+		status = http.StatusForbidden
+	}
+
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
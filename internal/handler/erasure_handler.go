@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// ERASURE HANDLER - Удаление аккаунта со стиранием данных (GDPR)
+// ================================================================
+
+// ErasureHandler - структура для обработки запросов на стирание
+type ErasureHandler struct {
+	erasures service.ErasureService // Зависимость от Erasure Service
+}
+
+// NewErasureHandler - конструктор
+func NewErasureHandler(erasures service.ErasureService) *ErasureHandler {
+	return &ErasureHandler{erasures: erasures}
+}
+
+// EraseOwn - удаление своего аккаунта
+// Endpoint: DELETE /api/v1/users/me
+// Body: {"password": "...", "reason": "..."}
+// Аккаунт только с passkey пароль не передаёт, но должен войти не позднее 5 минут назад
+// Response 202: запрос на стирание {"id": 1, "status": "pending", "scheduled_for": ...}
+// Response 200: данные уже стёрты (ERASURE_COOLING_OFF_DAYS=0)
+func (h *ErasureHandler) EraseOwn(c *gin.Context) {
+	var req domain.EraseAccountRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	erasure, err := h.erasures.ForTenant(tenantOf(c)).RequestOwn(
+		middleware.GetUserIDFromContext(c),
+		&req,
+		middleware.GetTokenIssuedAtFromContext(c),
+		clientInfo(c),
+	)
+	if err != nil {
+		respondErasureError(c, err)
+		return
+	}
+
+	respondErasure(c, erasure)
+}
+
+// GetOwn - состояние запроса на удаление своего аккаунта
+// Endpoint: GET /api/v1/users/me/erasure
+func (h *ErasureHandler) GetOwn(c *gin.Context) {
+	erasure, err := h.erasures.ForTenant(tenantOf(c)).Get(middleware.GetUserIDFromContext(c))
+	if err != nil {
+		respondErasureError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, erasure)
+}
+
+// CancelOwn - отмена удаления своего аккаунта (до scheduled_for)
+// Endpoint: DELETE /api/v1/users/me/erasure
+func (h *ErasureHandler) CancelOwn(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	erasure, err := h.erasures.ForTenant(tenantOf(c)).Cancel(userID, userID, clientInfo(c))
+	if err != nil {
+		respondErasureError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, erasure)
+}
+
+// RequestForUser - стирание данных пользователя по решению администратора
+// Endpoint: POST /api/v1/admin/users/:id/erasure
+// Body: {"reason": "обращение DPO-42", "immediate": false}
+func (h *ErasureHandler) RequestForUser(c *gin.Context) {
+	userID, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req domain.CreateErasureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	erasure, err := h.erasures.ForTenant(tenantOf(c)).Request(userID, middleware.GetUserIDFromContext(c), &req, clientInfo(c))
+	if err != nil {
+		respondErasureError(c, err)
+		return
+	}
+
+	respondErasure(c, erasure)
+}
+
+// GetForUser - последний запрос на стирание пользователя
+// Endpoint: GET /api/v1/admin/users/:id/erasure
+func (h *ErasureHandler) GetForUser(c *gin.Context) {
+	userID, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	erasure, err := h.erasures.ForTenant(tenantOf(c)).Get(userID)
+	if err != nil {
+		respondErasureError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, erasure)
+}
+
+// CancelForUser - отмена запроса на стирание администратором
+// Endpoint: DELETE /api/v1/admin/users/:id/erasure
+func (h *ErasureHandler) CancelForUser(c *gin.Context) {
+	userID, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	erasure, err := h.erasures.ForTenant(tenantOf(c)).Cancel(userID, middleware.GetUserIDFromContext(c), clientInfo(c))
+	if err != nil {
+		respondErasureError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, erasure)
+}
+
+// List - запросы на стирание организации
+// Endpoint: GET /api/v1/admin/erasures?status=pending
+func (h *ErasureHandler) List(c *gin.Context) {
+	var filter domain.ErasureFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	erasures, err := h.erasures.ForTenant(tenantOf(c)).List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения запросов на стирание",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, erasures)
+}
+
+// respondErasure - 202, пока запрос ждёт периода отмены, 200 - данные уже стёрты
+func respondErasure(c *gin.Context, erasure *domain.UserErasure) {
+	status := http.StatusAccepted
+	if erasure.Status == domain.ErasureStatusCompleted {
+		status = http.StatusOK
+	}
+	c.JSON(status, erasure)
+}
+
+// respondErasureError - ошибка сервиса стирания → HTTP статус
+func respondErasureError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrErasureNotFound),
+		errors.Is(err, service.ErrErasureUserNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrErasurePending):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrWrongCurrentPassword),
+		errors.Is(err, service.ErrReauthenticationRequired):
+		// 403, а не 401: токен действителен, не подтверждена только личность
+		status = http.StatusForbidden
+	}
+
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// GROUP HANDLER - HTTP обработчики для групп пользователей
+// ================================================================
+
+// GroupHandler - структура для обработки запросов к группам
+type GroupHandler struct {
+	groupService service.GroupService
+}
+
+// NewGroupHandler - конструктор
+func NewGroupHandler(groupService service.GroupService) *GroupHandler {
+	return &GroupHandler{groupService: groupService}
+}
+
+// List возвращает группы организации
+// Endpoint: GET /api/v1/groups
+// Headers: Authorization: Bearer TOKEN
+// Response: [{"id": 1, "name": "backend", "parent_id": null, "roles": ["admin"], ...}, ...]
+func (h *GroupHandler) List(c *gin.Context) {
+	groups, err := h.groupService.ForTenant(tenantOf(c)).List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения групп",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// Get возвращает группу
+// Endpoint: GET /api/v1/groups/:id
+// Headers: Authorization: Bearer TOKEN
+func (h *GroupHandler) Get(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	group, err := h.groupService.ForTenant(tenantOf(c)).Get(id)
+	if err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// Create создаёт группу
+// Endpoint: POST /api/v1/groups
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Body: {"name": "backend", "description": "...", "parent_id": 1}
+// Response: {"id": 2, "name": "backend", "parent_id": 1, "roles": [], ...}
+func (h *GroupHandler) Create(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ JSON ===
+	var req domain.CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: ВЫЗОВ SERVICE ===
+	group, err := h.groupService.ForTenant(tenantOf(c)).Create(&req)
+	if err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusCreated, group)
+}
+
+// Update изменяет группу
+// Endpoint: PUT /api/v1/groups/:id
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Body: {"name": "...", "description": "...", "parent_id": 0} (0 - группа верхнего уровня)
+func (h *GroupHandler) Update(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ ID И JSON ===
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req domain.UpdateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: ВЫЗОВ SERVICE ===
+	group, err := h.groupService.ForTenant(tenantOf(c)).Update(id, &req)
+	if err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusOK, group)
+}
+
+// Delete удаляет группу (вложенные группы переходят к её родителю)
+// Endpoint: DELETE /api/v1/groups/:id
+// Headers: Authorization: Bearer TOKEN (роль admin)
+func (h *GroupHandler) Delete(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	err := h.groupService.ForTenant(tenantOf(c)).Delete(id, middleware.GetUserIDFromContext(c), clientInfo(c))
+	if err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "группа удалена",
+	})
+}
+
+// ListMembers возвращает прямых участников группы
+// Endpoint: GET /api/v1/groups/:id/members
+// Headers: Authorization: Bearer TOKEN
+// Участники вместе с вложенными группами: GET /api/v1/users?group=:id
+func (h *GroupHandler) ListMembers(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	users, err := h.groupService.ForTenant(tenantOf(c)).ListMembers(id)
+	if err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// AddMember добавляет пользователя в группу
+// Endpoint: PUT /api/v1/groups/:id/members/:userId
+// Headers: Authorization: Bearer TOKEN (роль admin)
+func (h *GroupHandler) AddMember(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := memberIDParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.groupService.ForTenant(tenantOf(c)).AddMember(id, userID, middleware.GetUserIDFromContext(c), clientInfo(c))
+	if err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "пользователь добавлен в группу",
+	})
+}
+
+// RemoveMember убирает пользователя из группы
+// Endpoint: DELETE /api/v1/groups/:id/members/:userId
+// Headers: Authorization: Bearer TOKEN (роль admin)
+func (h *GroupHandler) RemoveMember(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := memberIDParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.groupService.ForTenant(tenantOf(c)).RemoveMember(id, userID, middleware.GetUserIDFromContext(c), clientInfo(c))
+	if err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "пользователь убран из группы",
+	})
+}
+
+// GrantRole выдаёт роль группе
+// Endpoint: POST /api/v1/groups/:id/roles
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Body: {"role": "support"}
+// Response: группа с обновлённым списком roles
+func (h *GroupHandler) GrantRole(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ ID И JSON ===
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req domain.GrantGroupRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: ВЫЗОВ SERVICE ===
+	group, err := h.groupService.ForTenant(tenantOf(c)).GrantRole(id, req.Role, middleware.GetUserIDFromContext(c), clientInfo(c))
+	if err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusOK, group)
+}
+
+// RevokeRole отзывает роль у группы
+// Endpoint: DELETE /api/v1/groups/:id/roles/:role
+// Headers: Authorization: Bearer TOKEN (роль admin)
+func (h *GroupHandler) RevokeRole(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	group, err := h.groupService.ForTenant(tenantOf(c)).RevokeRole(id, c.Param("role"), middleware.GetUserIDFromContext(c), clientInfo(c))
+	if err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// respondGroupError - ошибка сервиса групп → HTTP статус
+func respondGroupError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrGroupNotFound),
+		errors.Is(err, service.ErrGroupUserNotFound),
+		errors.Is(err, service.ErrGroupMemberNotFound),
+		errors.Is(err, service.ErrGroupRoleNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrGroupCycle),
+		errors.Is(err, service.ErrInvalidGroupRole):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrGroupNameTaken):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{"error": err.Error()})
+}
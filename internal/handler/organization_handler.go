@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// ORGANIZATION HANDLER - HTTP обработчики для организаций
+// ================================================================
+
+// OrganizationHandler - структура для обработки запросов к организациям
+type OrganizationHandler struct {
+	orgService  service.OrganizationService
+	invitations service.InvitationService // nil - без приглашений
+}
+
+// NewOrganizationHandler - конструктор
+func NewOrganizationHandler(orgService service.OrganizationService, invitations service.InvitationService) *OrganizationHandler {
+	return &OrganizationHandler{orgService: orgService, invitations: invitations}
+}
+
+// Create создаёт организацию, текущий пользователь становится её владельцем
+// Endpoint: POST /api/v1/organizations
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Body: {"slug": "acme", "name": "Acme Corp"}
+// Response: {"id": 2, "slug": "acme", "name": "Acme Corp", ...}
+func (h *OrganizationHandler) Create(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ JSON ===
+	var req domain.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: ВЫЗОВ SERVICE ===
+	org, err := h.orgService.Create(middleware.GetUserIDFromContext(c), &req, clientInfo(c))
+	if errors.Is(err, service.ErrInvalidOrganizationSlug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrOrganizationSlugTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusCreated, org)
+}
+
+// Current возвращает организацию запроса
+// Endpoint: GET /api/v1/organizations/current
+// Headers: Authorization: Bearer TOKEN
+// Response: {"id": 2, "slug": "acme", "name": "Acme Corp", ...}
+func (h *OrganizationHandler) Current(c *gin.Context) {
+	org, err := h.orgService.Get(tenantOf(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "организация не найдена",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+// ListMembers возвращает участников организации запроса
+// Endpoint: GET /api/v1/organizations/current/members
+// Headers: Authorization: Bearer TOKEN
+// Response: [{"organization_id": 2, "user_id": 1, "role": "owner", "user": {...}}, ...]
+func (h *OrganizationHandler) ListMembers(c *gin.Context) {
+	members, err := h.orgService.ListMembers(tenantOf(c), middleware.GetUserIDFromContext(c))
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// UpdateMemberRole меняет роль участника
+// Endpoint: PUT /api/v1/organizations/current/members/:userId
+// Headers: Authorization: Bearer TOKEN (owner или admin организации)
+// Body: {"role": "admin"}
+func (h *OrganizationHandler) UpdateMemberRole(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ ID И JSON ===
+	userID, ok := memberIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req domain.UpdateMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: ВЫЗОВ SERVICE ===
+	member, err := h.orgService.UpdateMemberRole(tenantOf(c), middleware.GetUserIDFromContext(c), userID, req.Role, clientInfo(c))
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveMember исключает участника из организации (или выход из неё, если :userId - свой ID)
+// Endpoint: DELETE /api/v1/organizations/current/members/:userId
+// Headers: Authorization: Bearer TOKEN
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	userID, ok := memberIDParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.orgService.RemoveMember(tenantOf(c), middleware.GetUserIDFromContext(c), userID, clientInfo(c))
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "участник исключён из организации",
+	})
+}
+
+// ================================================================
+// INVITATIONS - Приглашения в организацию
+// ================================================================
+
+// Invite приглашает пользователя в организацию запроса
+// Endpoint: POST /api/v1/organizations/current/invitations
+// Headers: Authorization: Bearer TOKEN (owner или admin организации)
+// Body: {"email": "new@example.com", "role": "member"}
+// Response: {"id": 5, "email": "...", "role": "member", "status": "pending", "expires_at": "...", ...}
+func (h *OrganizationHandler) Invite(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ JSON ===
+	var req domain.CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: ВЫЗОВ SERVICE ===
+	invitation, err := h.invitations.Create(tenantOf(c), middleware.GetUserIDFromContext(c), &req, clientInfo(c))
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// ListInvitations возвращает приглашения, ожидающие ответа
+// Endpoint: GET /api/v1/organizations/current/invitations
+// Headers: Authorization: Bearer TOKEN (owner или admin организации)
+func (h *OrganizationHandler) ListInvitations(c *gin.Context) {
+	invitations, err := h.invitations.ListPending(tenantOf(c), middleware.GetUserIDFromContext(c))
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// ResendInvitation отправляет приглашение повторно (новая ссылка и новый срок)
+// Endpoint: POST /api/v1/organizations/current/invitations/:id/resend
+// Headers: Authorization: Bearer TOKEN (owner или admin организации)
+func (h *OrganizationHandler) ResendInvitation(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	invitation, err := h.invitations.Resend(tenantOf(c), middleware.GetUserIDFromContext(c), id, clientInfo(c))
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitation)
+}
+
+// RevokeInvitation отзывает приглашение
+// Endpoint: DELETE /api/v1/organizations/current/invitations/:id
+// Headers: Authorization: Bearer TOKEN (owner или admin организации)
+func (h *OrganizationHandler) RevokeInvitation(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.invitations.Revoke(tenantOf(c), middleware.GetUserIDFromContext(c), id, clientInfo(c)); err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "приглашение отозвано",
+	})
+}
+
+// AcceptInvitationRegister принимает приглашение, регистрируя новый аккаунт
+// Endpoint: POST /api/v1/invitations/register
+// Body: {"token": "...", "name": "...", "password": "..."} (email - из приглашения)
+// Response: {"token": "...", "user": {...}} - как у регистрации
+func (h *OrganizationHandler) AcceptInvitationRegister(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ JSON ===
+	var req domain.AcceptInvitationRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: ВЫЗОВ SERVICE ===
+	authResponse, err := h.invitations.AcceptWithRegistration(&req, clientInfo(c))
+	if respondPasswordPolicyError(c, err) || respondAttributeError(c, err) {
+		return
+	}
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	respondToken(c, http.StatusCreated, authResponse)
+}
+
+// AcceptInvitation принимает приглашение существующим аккаунтом
+// Endpoint: POST /api/v1/invitations/accept
+// Headers: Authorization: Bearer TOKEN (аккаунт с email из приглашения)
+// Body: {"token": "..."}
+// Response: {"token": "...", "user": {...}} - токен для организации приглашения
+func (h *OrganizationHandler) AcceptInvitation(c *gin.Context) {
+	var req domain.InvitationTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	authResponse, err := h.invitations.AcceptAsUser(middleware.GetUserIDFromContext(c), req.Token, clientInfo(c))
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	respondToken(c, http.StatusOK, authResponse)
+}
+
+// DeclineInvitation отклоняет приглашение
+// Endpoint: POST /api/v1/invitations/decline
+// Body: {"token": "..."}
+func (h *OrganizationHandler) DeclineInvitation(c *gin.Context) {
+	var req domain.InvitationTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := h.invitations.Decline(req.Token, clientInfo(c)); err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "приглашение отклонено",
+	})
+}
+
+// memberIDParam - ID пользователя из URL (при ошибке ответ уже отправлен)
+func memberIDParam(c *gin.Context) (uint, bool) {
+	return uintParam(c, "userId")
+}
+
+// uintParam - числовой параметр URL (при ошибке ответ уже отправлен)
+func uintParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "невалидный ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondOrganizationError - ошибка сервиса организаций → HTTP статус
+func respondOrganizationError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrMemberNotFound),
+		errors.Is(err, service.ErrInvitationNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrOrgPermissionDenied),
+		errors.Is(err, service.ErrInvitationEmailMismatch):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrLastOwner),
+		errors.Is(err, service.ErrAlreadyMember),
+		errors.Is(err, service.ErrInvitationAlreadyPending),
+		errors.Is(err, service.ErrInvitationNotPending):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrInvitationInvalid):
+		status = http.StatusGone
+	}
+
+	c.JSON(status, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,343 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// PASSKEY HANDLER - HTTP обработчики для WebAuthn passkeys
+// ================================================================
+// Каждая церемония - два запроса:
+//   begin  → {"challenge_id": "...", "options": {...}}
+//            options передаются в navigator.credentials.create() / get()
+//   finish ← {"challenge_id": "...", "credential": <ответ браузера>}
+
+// PasskeyHandler - структура для обработки passkey запросов
+type PasskeyHandler struct {
+	passkeyService service.PasskeyService // Зависимость от Passkey Service
+}
+
+// NewPasskeyHandler - конструктор
+func NewPasskeyHandler(passkeyService service.PasskeyService) *PasskeyHandler {
+	return &PasskeyHandler{passkeyService: passkeyService}
+}
+
+// ================================================================
+// SIGNUP - POST /auth/passkeys/signup/begin и /signup/finish
+// ================================================================
+
+// BeginSignup начинает регистрацию аккаунта без пароля
+// Endpoint: POST /api/v1/auth/passkeys/signup/begin
+// Body: {"email": "...", "name": "..."}
+// Response: {"challenge_id": "...", "options": {...}}
+func (h *PasskeyHandler) BeginSignup(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ И ВАЛИДАЦИЯ ===
+	var req domain.PasskeySignupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: ГЕНЕРАЦИЯ CHALLENGE ===
+	challenge, err := h.passkeyService.ForTenant(tenantOf(c)).BeginSignup(&req)
+	if err != nil {
+		// Email уже зарегистрирован или ошибка БД
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusOK, challenge)
+}
+
+// FinishSignup создаёт аккаунт по ответу аутентификатора
+// Endpoint: POST /api/v1/auth/passkeys/signup/finish
+// Body: {"challenge_id": "...", "name": "MacBook", "credential": {...}}
+// Response: 201 {"token": "...", "user": {...}}
+func (h *PasskeyHandler) FinishSignup(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ И ВАЛИДАЦИЯ ===
+	var req domain.PasskeyFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: ПРОВЕРКА И СОЗДАНИЕ АККАУНТА ===
+	authResponse, err := h.passkeyService.ForTenant(tenantOf(c)).FinishSignup(&req, clientInfo(c))
+	if respondAccountPending(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	respondToken(c, http.StatusCreated, authResponse)
+}
+
+// ================================================================
+// LOGIN - POST /auth/passkeys/login/begin и /login/finish
+// ================================================================
+
+// BeginLogin начинает вход по passkey
+// Endpoint: POST /api/v1/auth/passkeys/login/begin
+// Body: {} ("email" принимается, но не влияет на ответ)
+// Response: {"challenge_id": "...", "options": {...}}
+func (h *PasskeyHandler) BeginLogin(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ И ВАЛИДАЦИЯ ===
+	var req domain.PasskeyLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: ГЕНЕРАЦИЯ CHALLENGE ===
+	challenge, err := h.passkeyService.ForTenant(tenantOf(c)).BeginLogin(&req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusOK, challenge)
+}
+
+// FinishLogin выполняет вход по подписи аутентификатора
+// Endpoint: POST /api/v1/auth/passkeys/login/finish
+// Body: {"challenge_id": "...", "credential": {...}}
+// Response: {"token": "...", "user": {...}}
+func (h *PasskeyHandler) FinishLogin(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ И ВАЛИДАЦИЯ ===
+	var req domain.PasskeyFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: ВХОД ===
+	authResponse, err := h.passkeyService.ForTenant(tenantOf(c)).FinishLogin(&req, clientInfo(c))
+	if middleware.RespondAccountStatus(c, err) {
+		return
+	}
+	if err != nil {
+		// Неверная подпись, чужой ключ, истёкший challenge или клонированный ключ
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	respondToken(c, http.StatusOK, authResponse)
+}
+
+// ================================================================
+// REGISTRATION - POST /auth/passkeys/register/begin и /register/finish
+// ================================================================
+
+// BeginRegistration начинает добавление passkey к текущему аккаунту
+// Endpoint: POST /api/v1/auth/passkeys/register/begin
+// Headers: Authorization: Bearer TOKEN
+// Response: {"challenge_id": "...", "options": {...}}
+func (h *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	// === ШАГ 1: ПОЛУЧЕНИЕ ID ИЗ КОНТЕКСТА ===
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "не удалось определить пользователя",
+		})
+		return
+	}
+
+	// === ШАГ 2: ГЕНЕРАЦИЯ CHALLENGE ===
+	challenge, err := h.passkeyService.ForTenant(tenantOf(c)).BeginRegistration(userID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrPasskeyNotAllowed) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusOK, challenge)
+}
+
+// FinishRegistration сохраняет новый passkey текущего пользователя
+// Endpoint: POST /api/v1/auth/passkeys/register/finish
+// Headers: Authorization: Bearer TOKEN
+// Body: {"challenge_id": "...", "name": "YubiKey", "credential": {...}}
+// Response: 201 {"id": 1, "name": "YubiKey", ...}
+func (h *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	// === ШАГ 1: ПОЛУЧЕНИЕ ID ИЗ КОНТЕКСТА ===
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "не удалось определить пользователя",
+		})
+		return
+	}
+
+	// === ШАГ 2: ПАРСИНГ И ВАЛИДАЦИЯ ===
+	var req domain.PasskeyFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 3: ПРОВЕРКА И СОХРАНЕНИЕ ===
+	credential, err := h.passkeyService.ForTenant(tenantOf(c)).FinishRegistration(userID, &req, clientInfo(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 4: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusCreated, credential)
+}
+
+// ================================================================
+// MANAGEMENT - GET, PATCH, DELETE /auth/passkeys
+// ================================================================
+
+// List возвращает passkeys текущего пользователя
+// Endpoint: GET /api/v1/auth/passkeys
+// Headers: Authorization: Bearer TOKEN
+// Response: [{"id": 1, "name": "MacBook", "last_used_at": "...", ...}]
+func (h *PasskeyHandler) List(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "не удалось определить пользователя",
+		})
+		return
+	}
+
+	credentials, err := h.passkeyService.ForTenant(tenantOf(c)).List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения passkeys",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+// Rename меняет название passkey
+// Endpoint: PATCH /api/v1/auth/passkeys/:id
+// Headers: Authorization: Bearer TOKEN
+// Body: {"name": "..."}
+// Response: {"id": 1, "name": "...", ...}
+func (h *PasskeyHandler) Rename(c *gin.Context) {
+	// === ШАГ 1: ИЗВЛЕЧЕНИЕ ID ===
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "не удалось определить пользователя",
+		})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "невалидный ID",
+		})
+		return
+	}
+
+	// === ШАГ 2: ПАРСИНГ И ВАЛИДАЦИЯ ===
+	var req domain.RenamePasskeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 3: ПЕРЕИМЕНОВАНИЕ ===
+	credential, err := h.passkeyService.ForTenant(tenantOf(c)).Rename(userID, uint(id), req.Name)
+	if errors.Is(err, service.ErrPasskeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// === ШАГ 4: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusOK, credential)
+}
+
+// Delete удаляет passkey
+// Endpoint: DELETE /api/v1/auth/passkeys/:id
+// Headers: Authorization: Bearer TOKEN
+// Response: {"message": "passkey удалён"}
+func (h *PasskeyHandler) Delete(c *gin.Context) {
+	// === ШАГ 1: ИЗВЛЕЧЕНИЕ ID ===
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "не удалось определить пользователя",
+		})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "невалидный ID",
+		})
+		return
+	}
+
+	// === ШАГ 2: УДАЛЕНИЕ ===
+	err = h.passkeyService.ForTenant(tenantOf(c)).Delete(userID, uint(id), clientInfo(c))
+	switch {
+	case errors.Is(err, service.ErrPasskeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrLastPasskey):
+		// Иначе пользователь без пароля потеряет доступ к аккаунту
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusOK, gin.H{
+		"message": "passkey удалён",
+	})
+}
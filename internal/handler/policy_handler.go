@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net/http"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/pkg/policy"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// POLICY HANDLER - Правила доступа (ABAC) для администраторов
+// ================================================================
+
+// PolicyHandler - структура для обработки запросов к правилам доступа
+type PolicyHandler struct {
+	authz service.AuthorizationService
+}
+
+// NewPolicyHandler - конструктор
+func NewPolicyHandler(authz service.AuthorizationService) *PolicyHandler {
+	return &PolicyHandler{authz: authz}
+}
+
+// List возвращает загруженные правила
+// Endpoint: GET /api/v1/admin/policies
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Response: [{"id": "...", "effect": "allow", "actions": [...], "conditions": [...], "source": "..."}, ...]
+func (h *PolicyHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, h.authz.Policies())
+}
+
+// Reload перечитывает файлы правил (не дожидаясь POLICY_RELOAD_INTERVAL)
+// Endpoint: POST /api/v1/admin/policies/reload
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Response: {"policies": 5}; при ошибке в файлах действуют прежние правила
+func (h *PolicyHandler) Reload(c *gin.Context) {
+	if err := h.authz.Reload(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"policies": len(h.authz.Policies()),
+	})
+}
+
+// Explain показывает, какое решение приняли бы правила и почему
+// Endpoint: POST /api/v1/admin/policies/explain
+// Headers: Authorization: Bearer TOKEN (роль admin)
+// Body: {"subject_id": 7, "action": "users:update", "resource_type": "user", "resource_id": 9, "time": "2025-10-18T22:00:00Z"}
+// Response: {"decision": {"allowed": false, "effect": "deny", "trace": [...]}, "request": {...}}
+func (h *PolicyHandler) Explain(c *gin.Context) {
+	// === ШАГ 1: ПАРСИНГ JSON ===
+	var req domain.ExplainPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// === ШАГ 2: СУБЪЕКТ ===
+	// Текущий пользователь - с claims токена, другой - только атрибуты из БД
+	var subject policy.Attributes
+	var err error
+	if req.SubjectID == 0 || req.SubjectID == middleware.GetUserIDFromContext(c) {
+		subject, err = middleware.PolicySubject(c, h.authz)
+	} else {
+		subject, err = h.authz.SubjectAttributes(req.SubjectID, tenantOf(c))
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "субъект не найден",
+		})
+		return
+	}
+
+	// === ШАГ 3: РЕСУРС ===
+	resource := policy.Attributes{}
+	if req.ResourceID != 0 {
+		resource, err = h.authz.ResourceAttributes(req.ResourceType, req.ResourceID, tenantOf(c))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	// === ШАГ 4: РЕШЕНИЕ ===
+	request := policy.Request{
+		Subject:      merge(subject, req.Subject),
+		Action:       req.Action,
+		ResourceType: req.ResourceType,
+		Resource:     merge(resource, req.Resource),
+		Context:      merge(middleware.PolicyContext(c), req.Context),
+	}
+	if req.Time != nil {
+		request.Time = *req.Time
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"decision": h.authz.Explain(request),
+		"request":  request,
+	})
+}
+
+// merge - копия base с переопределёнными полями override
+func merge(base policy.Attributes, override map[string]interface{}) policy.Attributes {
+	merged := policy.Attributes{}
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// PROFILE HANDLER - Профиль пользователя и аватар
+// ================================================================
+
+// avatarFormOverhead - запас на заголовки multipart сверх AVATAR_MAX_SIZE
+const avatarFormOverhead = 64 * 1024
+
+// ProfileHandler - структура для обработки запросов профиля
+type ProfileHandler struct {
+	profiles service.ProfileService // Зависимость от Profile Service
+}
+
+// NewProfileHandler - конструктор
+func NewProfileHandler(profiles service.ProfileService) *ProfileHandler {
+	return &ProfileHandler{profiles: profiles}
+}
+
+// GetOwn - профиль текущего пользователя
+// Endpoint: GET /api/v1/users/me/profile
+func (h *ProfileHandler) GetOwn(c *gin.Context) {
+	profile, err := h.profiles.ForTenant(tenantOf(c)).Get(middleware.GetUserIDFromContext(c))
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// Get - профиль пользователя организации
+// Endpoint: GET /api/v1/users/:id/profile
+func (h *ProfileHandler) Get(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	profile, err := h.profiles.ForTenant(tenantOf(c)).Get(id)
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// UpdateOwn - замена профиля текущего пользователя
+// Endpoint: PUT /api/v1/users/me/profile
+// Body: {"display_name": "...", "bio": "...", "phone": "+79991234567", "timezone": "Europe/Moscow", "locale": "ru"}
+func (h *ProfileHandler) UpdateOwn(c *gin.Context) {
+	var req domain.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+	profile, err := h.profiles.ForTenant(tenantOf(c)).Update(userID, &req, userID, clientInfo(c))
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// UploadAvatar - загрузка аватара
+// Endpoint: POST /api/v1/users/me/avatar
+// Body: multipart/form-data, файл в поле "avatar" (JPEG, PNG или GIF)
+func (h *ProfileHandler) UploadAvatar(c *gin.Context) {
+	// === ШАГ 1: ОГРАНИЧЕНИЕ РАЗМЕРА ===
+	// Тело длиннее лимита обрывается при чтении, не дожидаясь конца загрузки
+	maxSize := h.profiles.AvatarMaxSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+avatarFormOverhead)
+
+	file, header, err := c.Request.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondProfileError(c, service.ErrAvatarTooLarge)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "ожидается multipart/form-data с файлом в поле avatar",
+		})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxSize {
+		respondProfileError(c, service.ErrAvatarTooLarge)
+		return
+	}
+
+	// === ШАГ 2: ПРОВЕРКА И СОХРАНЕНИЕ ===
+	userID := middleware.GetUserIDFromContext(c)
+	profile, err := h.profiles.ForTenant(tenantOf(c)).UploadAvatar(userID, file, userID, clientInfo(c))
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// DeleteAvatar - удаление аватара
+// Endpoint: DELETE /api/v1/users/me/avatar
+func (h *ProfileHandler) DeleteAvatar(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	profile, err := h.profiles.ForTenant(tenantOf(c)).DeleteAvatar(userID, userID, clientInfo(c))
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// ServeAvatar - файл аватара
+// Endpoint: GET /api/v1/avatars/:id/:size
+// Без токена: ссылки из профиля содержат случайный ID аватара. Новый аватар
+// получает новый ID, поэтому ответ кешируется бессрочно
+func (h *ProfileHandler) ServeAvatar(c *gin.Context) {
+	object, err := h.profiles.OpenAvatar(c.Param("id"), c.Param("size"))
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+	defer object.Body.Close()
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", `"`+c.Param("id")+"-"+c.Param("size")+`"`)
+	c.Header("Content-Type", object.ContentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	// ServeContent отвечает 304 на If-None-Match / If-Modified-Since и поддерживает Range
+	if body, ok := object.Body.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, "", object.ModTime, body)
+		return
+	}
+	if c.GetHeader("If-None-Match") == c.Writer.Header().Get("ETag") {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, object.Body, nil)
+}
+
+// respondProfileError - ошибка сервиса профиля → HTTP статус
+func respondProfileError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrProfileUserNotFound),
+		errors.Is(err, service.ErrAvatarNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrAvatarTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrAvatarUnsupportedType):
+		status = http.StatusUnsupportedMediaType
+	case errors.Is(err, service.ErrAvatarInvalid):
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
@@ -11,20 +11,61 @@ import (
 // ROUTES SETUP - Настройка всех маршрутов приложения
 // ================================================================
 
+// Handlers - обработчики, из которых SetupRoutes собирает маршруты
+// Auth обязателен; остальные могут быть nil - тогда их endpoints не регистрируются
+type Handlers struct {
+	Auth         *AuthHandler         // Регистрация, вход, пароль
+	User         *UserHandler         // Пользователи
+	Passkey      *PasskeyHandler      // nil - passkeys отключены
+	Session      *SessionHandler      // nil - токены без сеансов
+	Admin        *AdminHandler        // nil - без имперсонации и журнала
+	Organization *OrganizationHandler // nil - без организаций, один общий список пользователей
+	Group        *GroupHandler        // nil - без групп, только роль из токена
+	Policy       *PolicyHandler       // nil - POLICY_FILES не задан, правила не проверяются
+	DataExport   *DataExportHandler   // nil - выгрузка персональных данных недоступна
+	Erasure      *ErasureHandler      // nil - стирание данных недоступно
+	Profile      *ProfileHandler      // nil - профиль и аватар недоступны
+	Attribute    *AttributeHandler    // nil - атрибуты не настраиваются
+	UserImport   *UserImportHandler   // nil - импорт из CSV и NDJSON недоступен
+	UserExport   *UserExportHandler   // nil - потоковая выгрузка пользователей недоступна
+}
+
 // SetupRoutes настраивает все HTTP маршруты (endpoints)
 // Параметры:
 //   - router: Gin роутер
-//   - authHandler: обработчик auth запросов
-//   - userHandler: обработчик user запросов
+//   - h: обработчики (см. Handlers)
+//   - idempotency: ответы на POST с Idempotency-Key (nil - IDEMPOTENCY_STORAGE=off, заголовок игнорируется)
 //   - cfg: конфигурация (для JWT secret в middleware)
-func SetupRoutes(
-	router *gin.Engine,
-	authHandler *AuthHandler,
-	userHandler *UserHandler,
-	cfg *config.Config,
-) {
+func SetupRoutes(router *gin.Engine, h Handlers, idempotency middleware.IdempotencyStore, cfg *config.Config) {
 	// Применяем глобальные middleware
+	// RequestID - первым: ID нужен всем следующим (журнал аудита, ответ клиенту)
+	router.Use(middleware.RequestIDMiddleware())
 	router.Use(middleware.CORSMiddleware())
+
+	// Каждый запрос с токеном имперсонации пишется в журнал аудита
+	if h.Admin != nil && h.Admin.audit != nil {
+		router.Use(middleware.ImpersonationAudit(h.Admin.audit))
+	}
+
+	// Проверка JWT токена + проверка отзыва:
+	//   - Auth Service знает текущую версию токенов (смена пароля)
+	//   - Session Service знает, не завершён ли сеанс токена
+	validators := []middleware.ClaimsValidator{h.Auth.authService}
+	if h.Session != nil {
+		validators = append(validators, h.Session.sessionService)
+	}
+	authMiddleware := middleware.AuthMiddleware(cfg, validators...)
+
+	// Чувствительные действия недоступны администратору, вошедшему от имени пользователя
+	notImpersonated := middleware.BlockImpersonation()
+
+	// Правила доступа (ABAC): без POLICY_FILES - пропускает запрос без проверки
+	requirePolicy := func(action, resourceType, idParam string) gin.HandlerFunc {
+		if h.Policy == nil {
+			return func(c *gin.Context) { c.Next() }
+		}
+		return middleware.RequirePolicy(h.Policy.authz, action, resourceType, idParam)
+	}
 	// ================================================================
 	// API VERSION 1 - Группа маршрутов /api/v1
 	// ================================================================
@@ -33,6 +74,23 @@ func SetupRoutes(
 	// 2. Применять middleware к группе
 	// 3. Организовать код
 	api := router.Group("/api/v1")
+
+	// Организация запроса: заголовок X-Organization, поддомен или организация по умолчанию
+	// Все сервисы с пользователями работают только с её участниками
+	if h.Organization != nil {
+		api.Use(middleware.TenantMiddleware(cfg, h.Organization.orgService))
+	}
+
+	// RequireRole учитывает роли групп пользователя (и их родительских групп)
+	if h.Group != nil {
+		api.Use(middleware.InheritedRoles(h.Group.groupService))
+	}
+
+	// Повтор POST с тем же Idempotency-Key получает первый ответ, а не выполняется заново
+	// После TenantMiddleware: ключи разных организаций не пересекаются
+	if idempotency != nil {
+		api.Use(middleware.Idempotency(idempotency, cfg))
+	}
 	{
 		// ============================================================
 		// PUBLIC ROUTES - Публичные маршруты (без аутентификации)
@@ -44,16 +102,378 @@ func SetupRoutes(
 		{
 			// POST /api/v1/auth/register - Регистрация
 			// Любой может зарегистрироваться (публичный endpoint)
-			auth.POST("/register", authHandler.Register)
+			auth.POST("/register", h.Auth.Register)
 			
 			// POST /api/v1/auth/login - Вход
 			// Любой может войти (публичный endpoint)
-			auth.POST("/login", authHandler.Login)
+			auth.POST("/login", h.Auth.Login)
+			
+			// POST /api/v1/auth/magic-link - Запросить ссылку для входа по email
+			// POST /api/v1/auth/magic-link/verify - Войти по токену из ссылки
+			// Работают, если LOGIN_METHODS содержит "magic_link"
+			auth.POST("/magic-link", h.Auth.RequestMagicLink)
+			auth.POST("/magic-link/verify", h.Auth.VerifyMagicLink)
 			
 			// --- PROTECTED AUTH ROUTES ---
 			// GET /api/v1/auth/me - Текущий пользователь
 			// ТРЕБУЕТ JWT токен (защищён AuthMiddleware)
-			auth.GET("/me", middleware.AuthMiddleware(cfg), authHandler.Me)
+			auth.GET("/me", authMiddleware, h.Auth.Me)
+			
+			// POST /api/v1/auth/password/change - Смена пароля
+			// Body: {"current_password": "...", "new_password": "..."}
+			// Возвращает новый токен, все остальные токены отзываются
+			// Недоступно при имперсонации
+			auth.POST("/password/change", authMiddleware, notImpersonated, h.Auth.ChangePassword)
+		}
+
+		// --- SESSION ROUTES ---
+		// Где пользователь вошёл в систему (все endpoints требуют JWT токен)
+		if h.Session != nil {
+			sessions := api.Group("/auth/sessions")
+			sessions.Use(authMiddleware)
+			{
+				// GET /api/v1/auth/sessions - Активные сеансы (текущий помечен "current": true)
+				sessions.GET("", h.Session.List)
+
+				// DELETE /api/v1/auth/sessions - Завершить все сеансы, кроме текущего
+				// От имени пользователя нельзя: это выход владельца со всех устройств
+				sessions.DELETE("", notImpersonated, h.Session.RevokeOthers)
+
+				// DELETE /api/v1/auth/sessions/:id - Завершить один сеанс
+				sessions.DELETE("/:id", notImpersonated, h.Session.Revoke)
+			}
+		}
+
+		// --- PASSKEY ROUTES ---
+		// Регистрируются, только если LOGIN_METHODS содержит "passkey"
+		if h.Passkey != nil {
+			passkeys := api.Group("/auth/passkeys")
+			{
+				// Публичные: регистрация аккаунта без пароля и вход
+				// Каждая церемония - begin (параметры для браузера) + finish (ответ аутентификатора)
+				passkeys.POST("/signup/begin", h.Passkey.BeginSignup)
+				passkeys.POST("/signup/finish", h.Passkey.FinishSignup)
+				passkeys.POST("/login/begin", h.Passkey.BeginLogin)
+				passkeys.POST("/login/finish", h.Passkey.FinishLogin)
+
+				// Защищённые: passkeys текущего пользователя
+				// Изменения недоступны при имперсонации
+				passkeys.GET("", authMiddleware, h.Passkey.List)
+				passkeys.POST("/register/begin", authMiddleware, notImpersonated, h.Passkey.BeginRegistration)
+				passkeys.POST("/register/finish", authMiddleware, notImpersonated, h.Passkey.FinishRegistration)
+				passkeys.PATCH("/:id", authMiddleware, notImpersonated, h.Passkey.Rename)
+				passkeys.DELETE("/:id", authMiddleware, notImpersonated, h.Passkey.Delete)
+			}
+		}
+
+		// --- ADMIN ROUTES ---
+		// Только для роли admin и только с собственным токеном администратора
+		if h.Admin != nil {
+			admin := api.Group("/admin")
+			admin.Use(authMiddleware, middleware.RequireRole("admin"), notImpersonated)
+			{
+				// POST /api/v1/admin/users/:id/impersonate - Войти от имени пользователя
+				// Возвращает короткоживущий токен с claim "act" (реальный автор запросов)
+				admin.POST("/users/:id/impersonate", h.Admin.Impersonate)
+
+				// Статус аккаунта: блокировка, восстановление, история
+				if h.Admin.statuses != nil {
+					// POST /api/v1/admin/users/:id/suspend - Временная блокировка
+					// Body: {"reason": "...", "until": "2025-11-01T00:00:00Z"} (until - необязательно)
+					admin.POST("/users/:id/suspend", h.Admin.SuspendUser)
+
+					// POST /api/v1/admin/users/:id/ban - Блокировка до решения администратора
+					// Body: {"reason": "..."}
+					admin.POST("/users/:id/ban", h.Admin.BanUser)
+
+					// POST /api/v1/admin/users/:id/reactivate - Активировать или снять блокировку
+					admin.POST("/users/:id/reactivate", h.Admin.ReactivateUser)
+
+					// GET /api/v1/admin/users/:id/status-history - История статусов
+					admin.GET("/users/:id/status-history", h.Admin.UserStatusHistory)
+				}
+
+				// Журнал событий безопасности
+				if h.Admin.auditLog != nil {
+					// GET /api/v1/admin/audit-events - События с фильтрами и пагинацией
+					// Query: actor_id, target_id, action, request_id, from, to, page, limit
+					admin.GET("/audit-events", h.Admin.ListAuditEvents)
+
+					// GET /api/v1/admin/audit-events/verify - Проверка цепочки хэшей
+					admin.GET("/audit-events/verify", h.Admin.VerifyAuditChain)
+				}
+			}
+		}
+
+		// --- POLICY ROUTES ---
+		// Правила доступа: просмотр, перезагрузка, объяснение решения (только роль admin)
+		if h.Policy != nil {
+			policies := api.Group("/admin/policies")
+			policies.Use(authMiddleware, middleware.RequireRole("admin"), notImpersonated)
+			{
+				// GET /api/v1/admin/policies - Загруженные правила
+				policies.GET("", h.Policy.List)
+
+				// POST /api/v1/admin/policies/reload - Перечитать файлы правил
+				policies.POST("/reload", h.Policy.Reload)
+
+				// POST /api/v1/admin/policies/explain - Решение с оценкой каждого правила (не применяется)
+				// Body: {"subject_id": 7, "action": "users:update", "resource_type": "user", "resource_id": 9}
+				policies.POST("/explain", h.Policy.Explain)
+			}
+		}
+
+		// --- ATTRIBUTE ROUTES ---
+		// Схемы атрибутов пользователей организации (только роль admin)
+		if h.Attribute != nil {
+			attributes := api.Group("/admin/attributes")
+			attributes.Use(authMiddleware, middleware.RequireRole("admin"), notImpersonated)
+			{
+				// GET /api/v1/admin/attributes - Схемы атрибутов
+				attributes.GET("", h.Attribute.List)
+
+				// POST /api/v1/admin/attributes - Описать атрибут
+				// Body: {"key": "department", "type": "string", "required": true, "enum": ["sales", "support"], "visibility": "public"}
+				attributes.POST("", h.Attribute.Create)
+
+				// PUT /api/v1/admin/attributes/:key - Заменить схему (ключ не меняется)
+				attributes.PUT("/:key", h.Attribute.Update)
+
+				// DELETE /api/v1/admin/attributes/:key - Удалить схему и значения у пользователей
+				attributes.DELETE("/:key", h.Attribute.Delete)
+			}
+		}
+
+		// --- DELETED USER ROUTES ---
+		// Удалённые пользователи: восстановление и окончательное удаление (только роль admin)
+		if h.User != nil {
+			deletedUsers := api.Group("/admin/deleted-users")
+			deletedUsers.Use(authMiddleware, middleware.RequireRole("admin"), notImpersonated)
+			{
+				// GET /api/v1/admin/deleted-users - Удалённые пользователи и срок их хранения
+				deletedUsers.GET("", h.User.ListDeleted)
+
+				// POST /api/v1/admin/deleted-users/:id/restore - Отменить удаление
+				deletedUsers.POST("/:id/restore", h.User.Restore)
+
+				// DELETE /api/v1/admin/deleted-users/:id - Стереть окончательно
+				deletedUsers.DELETE("/:id", h.User.Purge)
+			}
+		}
+
+		// --- DATA EXPORT ROUTES ---
+		// Выгрузка персональных данных (GDPR): архив собирается в фоне
+		if h.DataExport != nil {
+			// GET /api/v1/exports/:id/download?expires=...&signature=... - Скачать архив
+			// Без токена: ссылку из статуса выгрузки защищают подпись и срок действия
+			api.GET("/exports/:id/download", h.DataExport.Download)
+
+			ownExports := api.Group("/users/me")
+			ownExports.Use(authMiddleware, notImpersonated)
+			{
+				// POST /api/v1/users/me/export - Запросить выгрузку своих данных
+				// Body (необязательно): {"format": "zip" | "json"}
+				ownExports.POST("/export", h.DataExport.RequestOwn)
+
+				// GET /api/v1/users/me/exports/:id - Статус выгрузки и ссылка на архив
+				ownExports.GET("/exports/:id", h.DataExport.GetOwn)
+			}
+
+			adminExports := api.Group("/admin")
+			adminExports.Use(authMiddleware, middleware.RequireRole("admin"), notImpersonated)
+			{
+				// POST /api/v1/admin/users/:id/export - Выгрузка данных пользователя
+				adminExports.POST("/users/:id/export", h.DataExport.RequestForUser)
+
+				// GET /api/v1/admin/exports/:id - Статус выгрузки
+				adminExports.GET("/exports/:id", h.DataExport.GetForAdmin)
+			}
+		}
+
+		// --- ERASURE ROUTES ---
+		// Право на удаление (GDPR): данные стираются после периода отмены
+		if h.Erasure != nil {
+			ownErasure := api.Group("/users/me")
+			ownErasure.Use(authMiddleware, notImpersonated)
+			{
+				// DELETE /api/v1/users/me - Удалить свой аккаунт (нужен пароль или недавний вход)
+				// Body: {"password": "...", "reason": "..."}
+				ownErasure.DELETE("", h.Erasure.EraseOwn)
+
+				// GET /api/v1/users/me/erasure - Состояние запроса на удаление
+				ownErasure.GET("/erasure", h.Erasure.GetOwn)
+
+				// DELETE /api/v1/users/me/erasure - Отменить удаление (до scheduled_for)
+				ownErasure.DELETE("/erasure", h.Erasure.CancelOwn)
+			}
+
+			adminErasures := api.Group("/admin")
+			adminErasures.Use(authMiddleware, middleware.RequireRole("admin"), notImpersonated)
+			{
+				// POST /api/v1/admin/users/:id/erasure - Стереть данные пользователя
+				adminErasures.POST("/users/:id/erasure", h.Erasure.RequestForUser)
+
+				// GET /api/v1/admin/users/:id/erasure - Последний запрос пользователя
+				adminErasures.GET("/users/:id/erasure", h.Erasure.GetForUser)
+
+				// DELETE /api/v1/admin/users/:id/erasure - Отменить запрос
+				adminErasures.DELETE("/users/:id/erasure", h.Erasure.CancelForUser)
+
+				// GET /api/v1/admin/erasures?status=pending - Запросы организации
+				adminErasures.GET("/erasures", h.Erasure.List)
+			}
+		}
+
+		// --- USER IMPORT ROUTES ---
+		// Массовое создание и обновление пользователей из файла (только роль admin)
+		if h.UserImport != nil {
+			adminImports := api.Group("/admin")
+			adminImports.Use(authMiddleware, middleware.RequireRole("admin"), notImpersonated)
+			{
+				// POST /api/v1/admin/users/import?format=csv&dry_run=true&upsert=true&invite=true
+				// Body: файл CSV (text/csv) или NDJSON (application/x-ndjson)
+				adminImports.POST("/users/import", h.UserImport.Request)
+
+				// GET /api/v1/admin/imports/:id - Прогресс и ошибки строк
+				adminImports.GET("/imports/:id", h.UserImport.Get)
+			}
+		}
+
+		// --- USER EXPORT ROUTES ---
+		// Потоковая выгрузка пользователей организации (только роль admin)
+		if h.UserExport != nil {
+			adminUserExport := api.Group("/admin")
+			adminUserExport.Use(authMiddleware, middleware.RequireRole("admin"), notImpersonated)
+			{
+				// GET /api/v1/admin/users/export?format=parquet&columns=id,email,attr.department&group=3&cursor=120
+				// Accept-Encoding: gzip - сжатый ответ
+				adminUserExport.GET("/users/export", h.UserExport.Export)
+			}
+		}
+
+		// --- PROFILE ROUTES ---
+		// Расширенный профиль и аватар текущего пользователя
+		if h.Profile != nil {
+			// GET /api/v1/avatars/:id/:size - Файл аватара (size: 512, 128, 64)
+			// Без токена: ссылки берутся из профиля (avatar_urls), кешируются бессрочно
+			api.GET("/avatars/:id/:size", h.Profile.ServeAvatar)
+
+			ownProfile := api.Group("/users/me")
+			ownProfile.Use(authMiddleware)
+			{
+				// GET /api/v1/users/me/profile - Свой профиль
+				ownProfile.GET("/profile", h.Profile.GetOwn)
+
+				// PUT /api/v1/users/me/profile - Заменить профиль
+				// Body: {"display_name": "...", "bio": "...", "phone": "+79991234567", "timezone": "Europe/Moscow", "locale": "ru"}
+				ownProfile.PUT("/profile", h.Profile.UpdateOwn)
+
+				// POST /api/v1/users/me/avatar - Загрузить аватар (multipart/form-data, поле "avatar")
+				ownProfile.POST("/avatar", h.Profile.UploadAvatar)
+
+				// DELETE /api/v1/users/me/avatar - Удалить аватар
+				ownProfile.DELETE("/avatar", h.Profile.DeleteAvatar)
+			}
+		}
+
+		// --- ORGANIZATION ROUTES ---
+		// Организация запроса и её участники (все endpoints требуют JWT токен)
+		if h.Organization != nil {
+			orgs := api.Group("/organizations")
+			orgs.Use(authMiddleware)
+			{
+				// POST /api/v1/organizations - Создать организацию (только роль admin)
+				// Создатель становится владельцем (owner) новой организации
+				orgs.POST("", middleware.RequireRole("admin"), notImpersonated, h.Organization.Create)
+
+				// GET /api/v1/organizations/current - Организация запроса
+				orgs.GET("/current", h.Organization.Current)
+
+				// GET /api/v1/organizations/current/members - Участники и их роли
+				orgs.GET("/current/members", h.Organization.ListMembers)
+
+				// PUT /api/v1/organizations/current/members/:userId - Сменить роль участника
+				// Body: {"role": "owner" | "admin" | "member"}
+				orgs.PUT("/current/members/:userId", notImpersonated, h.Organization.UpdateMemberRole)
+
+				// DELETE /api/v1/organizations/current/members/:userId - Исключить участника
+				orgs.DELETE("/current/members/:userId", notImpersonated, h.Organization.RemoveMember)
+
+				// Приглашения (owner или admin организации)
+				if h.Organization.invitations != nil {
+					// POST /api/v1/organizations/current/invitations - Пригласить по email
+					// Body: {"email": "...", "role": "member"}
+					orgs.POST("/current/invitations", notImpersonated, h.Organization.Invite)
+
+					// GET /api/v1/organizations/current/invitations - Ожидающие ответа
+					orgs.GET("/current/invitations", h.Organization.ListInvitations)
+
+					// POST /api/v1/organizations/current/invitations/:id/resend - Отправить повторно
+					orgs.POST("/current/invitations/:id/resend", notImpersonated, h.Organization.ResendInvitation)
+
+					// DELETE /api/v1/organizations/current/invitations/:id - Отозвать
+					orgs.DELETE("/current/invitations/:id", notImpersonated, h.Organization.RevokeInvitation)
+				}
+			}
+		}
+
+		// --- INVITATION ROUTES ---
+		// Ответ приглашённого по токену из письма
+		if h.Organization != nil && h.Organization.invitations != nil {
+			invitations := api.Group("/invitations")
+			{
+				// POST /api/v1/invitations/register - Принять, зарегистрировав аккаунт
+				// Body: {"token": "...", "name": "...", "password": "..."}
+				invitations.POST("/register", h.Organization.AcceptInvitationRegister)
+
+				// POST /api/v1/invitations/accept - Принять существующим аккаунтом
+				// Требует токен аккаунта с email из приглашения
+				invitations.POST("/accept", authMiddleware, notImpersonated, h.Organization.AcceptInvitation)
+
+				// POST /api/v1/invitations/decline - Отклонить
+				invitations.POST("/decline", h.Organization.DeclineInvitation)
+			}
+		}
+
+		// --- GROUP ROUTES ---
+		// Группы пользователей организации (все endpoints требуют JWT токен)
+		// Изменения - только роль admin (собственная или от группы)
+		if h.Group != nil {
+			groups := api.Group("/groups")
+			groups.Use(authMiddleware)
+			requireAdmin := middleware.RequireRole("admin")
+			{
+				// GET /api/v1/groups - Все группы с их ролями
+				groups.GET("", h.Group.List)
+
+				// POST /api/v1/groups - Создать группу
+				// Body: {"name": "backend", "description": "...", "parent_id": 1}
+				groups.POST("", requireAdmin, notImpersonated, h.Group.Create)
+
+				// GET /api/v1/groups/:id - Группа
+				groups.GET("/:id", h.Group.Get)
+
+				// PUT /api/v1/groups/:id - Изменить (parent_id: 0 - группа верхнего уровня)
+				groups.PUT("/:id", requireAdmin, notImpersonated, h.Group.Update)
+
+				// DELETE /api/v1/groups/:id - Удалить (вложенные группы переходят к родителю)
+				groups.DELETE("/:id", requireAdmin, notImpersonated, h.Group.Delete)
+
+				// GET /api/v1/groups/:id/members - Прямые участники
+				// С вложенными группами: GET /api/v1/users?group=:id
+				groups.GET("/:id/members", h.Group.ListMembers)
+
+				// PUT/DELETE /api/v1/groups/:id/members/:userId - Добавить/убрать участника
+				groups.PUT("/:id/members/:userId", requireAdmin, notImpersonated, h.Group.AddMember)
+				groups.DELETE("/:id/members/:userId", requireAdmin, notImpersonated, h.Group.RemoveMember)
+
+				// POST /api/v1/groups/:id/roles - Выдать роль группе
+				// Body: {"role": "support"}
+				groups.POST("/:id/roles", requireAdmin, notImpersonated, h.Group.GrantRole)
+
+				// DELETE /api/v1/groups/:id/roles/:role - Отозвать роль
+				groups.DELETE("/:id/roles/:role", requireAdmin, notImpersonated, h.Group.RevokeRole)
+			}
 		}
 
 		// ============================================================
@@ -63,28 +483,46 @@ func SetupRoutes(
 		// --- USER ROUTES ---
 		// Группа для работы с пользователями
 		// ВСЕ endpoints в этой группе требуют JWT токен!
+		// При включённых правилах доступа (POLICY_FILES) каждое действие проверяется
+		// правилами: users:list, users:read, users:update, users:delete (ресурс "user")
 		users := api.Group("/users")
-		users.Use(middleware.AuthMiddleware(cfg)) // Применяем middleware ко всей группе
+		users.Use(authMiddleware) // Применяем middleware ко всей группе
+
+		// REQUIRE_IF_MATCH=true - изменения без If-Match отклоняются (428)
+		requireIfMatch := middleware.RequireIfMatch(cfg.RequireIfMatch)
 		{
 			// GET /api/v1/users - Список всех пользователей
+			// GET /api/v1/users?group=3 - Участники группы (включая вложенные группы)
+			// GET /api/v1/users?attr.department=sales - По значениям атрибутов
 			// Требует: Authorization: Bearer TOKEN
-			users.GET("", userHandler.GetAll)
+			users.GET("", requirePolicy("users:list", "user", ""), h.User.GetAll)
 			
 			// GET /api/v1/users/:id - Получить пользователя по ID
 			// Пример: GET /api/v1/users/42
+			// Ответ с ETag (версия пользователя); If-None-Match - 304 Not Modified
 			// Требует: Authorization: Bearer TOKEN
-			users.GET("/:id", userHandler.GetByID)
+			users.GET("/:id", requirePolicy("users:read", "user", "id"), h.User.GetByID)
 			
-			// PUT /api/v1/users/:id - Обновить пользователя
+			// PUT /api/v1/users/:id - Заменить данные пользователя (все поля)
 			// Пример: PUT /api/v1/users/42
-			// Body: {"name": "New Name", "email": "new@email.com"}
+			// Body: {"name": "New Name", "email": "new@email.com", "role": "user", "attributes": {"department": "sales"}}
+			// Требует: Authorization: Bearer TOKEN
+			users.PUT("/:id", requirePolicy("users:update", "user", "id"), requireIfMatch, h.User.Update)
+
+			// PATCH /api/v1/users/:id - Частичное изменение
+			// Content-Type: application/merge-patch+json (RFC 7396) или application/json-patch+json (RFC 6902)
 			// Требует: Authorization: Bearer TOKEN
-			users.PUT("/:id", userHandler.Update)
+			users.PATCH("/:id", requirePolicy("users:update", "user", "id"), requireIfMatch, h.User.Patch)
 			
 			// DELETE /api/v1/users/:id - Удалить пользователя
 			// Пример: DELETE /api/v1/users/42
 			// Требует: Authorization: Bearer TOKEN
-			users.DELETE("/:id", userHandler.Delete)
+			users.DELETE("/:id", requirePolicy("users:delete", "user", "id"), requireIfMatch, h.User.Delete)
+
+			// GET /api/v1/users/:id/profile - Профиль пользователя (отображаемое имя, аватар)
+			if h.Profile != nil {
+				users.GET("/:id/profile", requirePolicy("users:read", "user", "id"), h.Profile.Get)
+			}
 		}
 	}
 
@@ -108,14 +546,91 @@ func SetupRoutes(
 // PUBLIC (без токена):
 //   POST   /api/v1/auth/register
 //   POST   /api/v1/auth/login
+//   POST   /api/v1/auth/magic-link
+//   POST   /api/v1/auth/magic-link/verify
+//   POST   /api/v1/auth/passkeys/signup/begin
+//   POST   /api/v1/auth/passkeys/signup/finish
+//   POST   /api/v1/auth/passkeys/login/begin
+//   POST   /api/v1/auth/passkeys/login/finish
+//   POST   /api/v1/invitations/register
+//   POST   /api/v1/invitations/decline
+//   GET    /api/v1/exports/:id/download (подписанная ссылка)
+//   GET    /api/v1/avatars/:id/:size
 //   GET    /health
 //
 // PROTECTED (требуют JWT токен):
 //   GET    /api/v1/auth/me
+//   POST   /api/v1/auth/password/change
+//   GET    /api/v1/auth/sessions
+//   DELETE /api/v1/auth/sessions
+//   DELETE /api/v1/auth/sessions/:id
+//   GET    /api/v1/auth/passkeys
+//   POST   /api/v1/auth/passkeys/register/begin
+//   POST   /api/v1/auth/passkeys/register/finish
+//   PATCH  /api/v1/auth/passkeys/:id
+//   DELETE /api/v1/auth/passkeys/:id
+//   GET    /api/v1/organizations/current
+//   GET    /api/v1/organizations/current/members
+//   PUT    /api/v1/organizations/current/members/:userId
+//   DELETE /api/v1/organizations/current/members/:userId
+//   POST   /api/v1/organizations/current/invitations
+//   GET    /api/v1/organizations/current/invitations
+//   POST   /api/v1/organizations/current/invitations/:id/resend
+//   DELETE /api/v1/organizations/current/invitations/:id
+//   POST   /api/v1/invitations/accept
+//   GET    /api/v1/groups
+//   GET    /api/v1/groups/:id
+//   GET    /api/v1/groups/:id/members
 //   GET    /api/v1/users
 //   GET    /api/v1/users/:id
 //   PUT    /api/v1/users/:id
+//   PATCH  /api/v1/users/:id
 //   DELETE /api/v1/users/:id
+//   GET    /api/v1/users/:id/profile
+//   GET    /api/v1/users/me/profile
+//   PUT    /api/v1/users/me/profile
+//   POST   /api/v1/users/me/avatar
+//   DELETE /api/v1/users/me/avatar
+//   POST   /api/v1/users/me/export
+//   GET    /api/v1/users/me/exports/:id
+//   DELETE /api/v1/users/me
+//   GET    /api/v1/users/me/erasure
+//   DELETE /api/v1/users/me/erasure
+//
+// ADMIN (требуют JWT токен с ролью admin):
+//   POST   /api/v1/organizations
+//   POST   /api/v1/admin/users/:id/impersonate
+//   POST   /api/v1/admin/users/:id/suspend
+//   POST   /api/v1/admin/users/:id/ban
+//   POST   /api/v1/admin/users/:id/reactivate
+//   GET    /api/v1/admin/users/:id/status-history
+//   GET    /api/v1/admin/audit-events
+//   GET    /api/v1/admin/audit-events/verify
+//   GET    /api/v1/admin/policies
+//   POST   /api/v1/admin/policies/reload
+//   POST   /api/v1/admin/policies/explain
+//   GET    /api/v1/admin/deleted-users
+//   POST   /api/v1/admin/deleted-users/:id/restore
+//   DELETE /api/v1/admin/deleted-users/:id
+//   GET    /api/v1/admin/attributes
+//   POST   /api/v1/admin/attributes
+//   PUT    /api/v1/admin/attributes/:key
+//   DELETE /api/v1/admin/attributes/:key
+//   POST   /api/v1/admin/users/:id/export
+//   GET    /api/v1/admin/exports/:id
+//   POST   /api/v1/admin/users/:id/erasure
+//   GET    /api/v1/admin/users/:id/erasure
+//   DELETE /api/v1/admin/users/:id/erasure
+//   GET    /api/v1/admin/erasures
+//   POST   /api/v1/groups
+//   PUT    /api/v1/groups/:id
+//   DELETE /api/v1/groups/:id
+//   PUT    /api/v1/groups/:id/members/:userId
+//   DELETE /api/v1/groups/:id/members/:userId
+//   POST   /api/v1/groups/:id/roles
+//   DELETE /api/v1/groups/:id/roles/:role
+//
+// Роль admin может быть выдана и группе пользователя (POST /api/v1/groups/:id/roles)
 //
 // ================================================================
 
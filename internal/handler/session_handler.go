@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// SESSION HANDLER - HTTP обработчики для сеансов пользователя
+// ================================================================
+
+// SessionHandler - структура для обработки запросов к сеансам
+type SessionHandler struct {
+	sessionService service.SessionService // Зависимость от Session Service
+}
+
+// NewSessionHandler - конструктор
+func NewSessionHandler(sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+// List возвращает активные сеансы текущего пользователя
+// Endpoint: GET /api/v1/auth/sessions
+// Headers: Authorization: Bearer TOKEN
+// Response: [{"id": "...", "device_name": "Chrome on macOS", "current": true, ...}]
+func (h *SessionHandler) List(c *gin.Context) {
+	// === ШАГ 1: ПОЛУЧЕНИЕ ID ИЗ КОНТЕКСТА ===
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "не удалось определить пользователя",
+		})
+		return
+	}
+
+	// === ШАГ 2: ВЫЗОВ SERVICE ===
+	// Текущий сеанс (из токена запроса) помечается "current": true
+	sessions, err := h.sessionService.List(userID, middleware.GetSessionIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения сеансов",
+		})
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusOK, sessions)
+}
+
+// Revoke завершает один сеанс
+// Endpoint: DELETE /api/v1/auth/sessions/:id
+// Headers: Authorization: Bearer TOKEN
+// Response: {"message": "сеанс завершён"}
+//
+// Завершение текущего сеанса - это выход: токен запроса перестаёт работать
+func (h *SessionHandler) Revoke(c *gin.Context) {
+	// === ШАГ 1: ПОЛУЧЕНИЕ ID ИЗ КОНТЕКСТА ===
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "не удалось определить пользователя",
+		})
+		return
+	}
+
+	// === ШАГ 2: ЗАВЕРШЕНИЕ СЕАНСА ===
+	err := h.sessionService.Revoke(userID, c.Param("id"))
+	if errors.Is(err, service.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusOK, gin.H{
+		"message": "сеанс завершён",
+	})
+}
+
+// RevokeOthers завершает все сеансы, кроме текущего
+// Endpoint: DELETE /api/v1/auth/sessions
+// Headers: Authorization: Bearer TOKEN
+// Response: {"message": "...", "revoked": 3}
+func (h *SessionHandler) RevokeOthers(c *gin.Context) {
+	// === ШАГ 1: ПОЛУЧЕНИЕ ID ИЗ КОНТЕКСТА ===
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "не удалось определить пользователя",
+		})
+		return
+	}
+
+	// === ШАГ 2: ЗАВЕРШЕНИЕ СЕАНСОВ ===
+	count, err := h.sessionService.RevokeOthers(userID, middleware.GetSessionIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.JSON(http.StatusOK, gin.H{
+		"message": "все остальные сеансы завершены",
+		"revoked": count,
+	})
+}
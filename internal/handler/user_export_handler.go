@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"compress/gzip"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// USER EXPORT HANDLER - Потоковая выгрузка пользователей
+// ================================================================
+
+// UserExportHandler - структура для обработки запросов выгрузки
+type UserExportHandler struct {
+	exports    service.UserExportService // Зависимость от User Export Service
+	attributes service.AttributeService  // Фильтр attr.<key> (nil - фильтр недоступен)
+}
+
+// NewUserExportHandler - конструктор
+func NewUserExportHandler(exports service.UserExportService, attributes service.AttributeService) *UserExportHandler {
+	return &UserExportHandler{exports: exports, attributes: attributes}
+}
+
+// userExportContentTypes - формат выгрузки → Content-Type ответа
+var userExportContentTypes = map[string]string{
+	domain.UserExportFormatCSV:     "text/csv; charset=utf-8",
+	domain.UserExportFormatNDJSON:  "application/x-ndjson",
+	domain.UserExportFormatParquet: "application/vnd.apache.parquet",
+}
+
+// Трейлеры ответа выгрузки: известны только после последней строки
+const (
+	userExportCursorTrailer = "X-Export-Cursor" // ID последнего отданного пользователя
+	userExportRowsTrailer   = "X-Export-Rows"   // Сколько строк отдано
+	userExportStatusTrailer = "X-Export-Status" // complete или failed
+)
+
+// Export - выгрузка пользователей организации одним потоком
+// Endpoint: GET /api/v1/admin/users/export?format=csv&columns=id,email,attr.department&cursor=120
+// Query: ?group=ID, ?attr.department=sales - те же фильтры, что у GET /users (можно вместе)
+// Query: ?cursor=ID - продолжить после пользователя ID (id последней полученной строки; нужна колонка id)
+// Headers: Accept-Encoding: gzip - ответ сжимается (Content-Encoding: gzip)
+// Response 200: файл; трейлеры X-Export-Cursor, X-Export-Rows, X-Export-Status
+func (h *UserExportHandler) Export(c *gin.Context) {
+	// === ШАГ 1: ПАРАМЕТРЫ И ФИЛЬТРЫ ===
+	var req domain.UserExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	filter, ok := parseAttributeFilter(c, h.attributes)
+	if !ok {
+		return
+	}
+	req.Filter.Attributes = filter
+	if group := c.Query("group"); group != "" {
+		groupID, err := strconv.ParseUint(group, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "невалидный ID группы",
+			})
+			return
+		}
+		req.Filter.GroupID = uint(groupID)
+	}
+
+	// === ШАГ 2: ПОДГОТОВКА (ошибки - до начала ответа) ===
+	export, err := h.exports.ForTenant(tenantOf(c)).Prepare(middleware.GetUserIDFromContext(c), &req, clientInfo(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "ошибка подготовки выгрузки"
+		if errors.Is(err, service.ErrUserExportColumn) {
+			status, message = http.StatusBadRequest, err.Error()
+		}
+		c.JSON(status, gin.H{
+			"error": message,
+		})
+		return
+	}
+
+	// === ШАГ 3: ЗАГОЛОВКИ ===
+	// Большая выгрузка пишется дольше WriteTimeout сервера - снимаем его для этого ответа
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", userExportContentTypes[export.Format])
+	header.Set("Content-Disposition", `attachment; filename="users.`+export.Format+`"`)
+	header.Set("Trailer", strings.Join([]string{userExportCursorTrailer, userExportRowsTrailer, userExportStatusTrailer}, ", "))
+	header.Add("Vary", "Accept-Encoding")
+	out := &userExportWriter{response: c.Writer}
+	if acceptsGzip(c.GetHeader("Accept-Encoding")) {
+		header.Set("Content-Encoding", "gzip")
+		out.gzip = gzip.NewWriter(c.Writer)
+	}
+	c.Status(http.StatusOK)
+
+	// === ШАГ 4: ПОТОК ===
+	err = export.Stream(out)
+	if err == nil && out.gzip != nil {
+		err = out.gzip.Close()
+	}
+
+	status := "complete"
+	if err != nil {
+		log.Printf("⚠️  Выгрузка пользователей прервана после %d строк: %v", export.Rows, err)
+		status = "failed"
+
+		// Ничего не отправлено (ошибка первого запроса к БД) - обычный ответ с ошибкой
+		if !c.Writer.Written() {
+			// Content-Type тоже: c.JSON не заменяет уже выставленный text/csv
+			for _, name := range []string{"Content-Type", "Content-Encoding", "Content-Disposition", "Trailer"} {
+				header.Del(name)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "ошибка выгрузки пользователей",
+			})
+			return
+		}
+	}
+
+	// Статус 200 уже отправлен: об ошибке сообщают трейлеры, а сжатый
+	// ответ остаётся без конца потока gzip - клиент не примет его за целый
+	header.Set(userExportCursorTrailer, strconv.FormatUint(uint64(export.Cursor), 10))
+	header.Set(userExportRowsTrailer, strconv.Itoa(export.Rows))
+	header.Set(userExportStatusTrailer, status)
+}
+
+// userExportWriter - тело ответа выгрузки, при необходимости через gzip
+// Flush отдаёт клиенту всё, что уже записано
+type userExportWriter struct {
+	response gin.ResponseWriter
+	gzip     *gzip.Writer // nil - без сжатия
+}
+
+func (w *userExportWriter) Write(data []byte) (int, error) {
+	if w.gzip != nil {
+		return w.gzip.Write(data)
+	}
+	return w.response.Write(data)
+}
+
+func (w *userExportWriter) Flush() error {
+	if w.gzip != nil {
+		if err := w.gzip.Flush(); err != nil {
+			return err
+		}
+	}
+	w.response.Flush()
+	return nil
+}
+
+// acceptsGzip - есть ли gzip в Accept-Encoding (и не с q=0)
+func acceptsGzip(header string) bool {
+	for _, coding := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		if strings.TrimSpace(strings.ToLower(name)) != "gzip" {
+			continue
+		}
+		if quality, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			value, err := strconv.ParseFloat(quality, 64)
+			return err == nil && value > 0
+		}
+		return true
+	}
+	return false
+}
@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
 	"advanced-user-api/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -14,16 +18,25 @@ import (
 // USER HANDLER - HTTP обработчики для пользователей
 // ================================================================
 
+// userDocumentMaxSize - наибольшее тело PUT/PATCH /users/:id
+// Документ пользователя - имя, email, роль и атрибуты: 64 KB с запасом
+const userDocumentMaxSize = 64 * 1024
+
 // UserHandler - структура для обработки user запросов
 type UserHandler struct {
-	userService service.UserService // Зависимость от User Service
+	userService service.UserService      // Зависимость от User Service
+	attributes  service.AttributeService // Видимость атрибутов и фильтр (nil - атрибуты не отдаются)
 }
 
 // NewUserHandler - конструктор
-func NewUserHandler(userService service.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+// attributes может быть nil: пользователи отдаются без атрибутов
+func NewUserHandler(userService service.UserService, attributes service.AttributeService) *UserHandler {
+	return &UserHandler{userService: userService, attributes: attributes}
 }
 
+// attributeQueryPrefix - префикс параметров фильтра по атрибутам: ?attr.department=sales
+const attributeQueryPrefix = "attr."
+
 // ================================================================
 // HANDLERS - Обработчики HTTP запросов
 // ================================================================
@@ -31,11 +44,44 @@ func NewUserHandler(userService service.UserService) *UserHandler {
 // GetAll получает список всех пользователей
 // Endpoint: GET /api/v1/users
 // Headers: Authorization: Bearer TOKEN (защищён!)
-// Response: [{"id": 1, "email": "...", "name": "..."}, ...]
+// Query: ?group=ID - только участники группы (включая вложенные группы)
+// Query: ?attr.department=sales&attr.level=3 - по значениям атрибутов (все условия сразу)
+// Response: [{"id": 1, "email": "...", "name": "...", "attributes": {...}}, ...]
 func (h *UserHandler) GetAll(c *gin.Context) {
-	// === ШАГ 1: ВЫЗОВ SERVICE ===
+	// === ШАГ 1: ФИЛЬТР ПО АТРИБУТАМ ===
+	// Значения приводятся к типам схемы; фильтр по невидимому атрибуту - 400
+	filter, ok := h.attributeFilter(c)
+	if !ok {
+		return
+	}
+
+	// === ШАГ 2: ВЫЗОВ SERVICE ===
 	// Получаем всех пользователей из service
-	users, err := h.userService.GetAllUsers()
+	userService := h.userService.ForTenant(tenantOf(c))
+
+	var users []domain.User
+	var err error
+	group := c.Query("group")
+	if group != "" && len(filter) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "фильтры group и attr.* нельзя использовать вместе",
+		})
+		return
+	}
+	if len(filter) > 0 {
+		users, err = userService.GetUsersByAttributes(filter)
+	} else if group != "" {
+		groupID, parseErr := strconv.ParseUint(group, 10, 32)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "невалидный ID группы",
+			})
+			return
+		}
+		users, err = userService.GetUsersByGroup(uint(groupID))
+	} else {
+		users, err = userService.GetAllUsers()
+	}
 	if err != nil {
 		// Ошибка БД
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -44,16 +90,21 @@ func (h *UserHandler) GetAll(c *gin.Context) {
 		return
 	}
 
-	// === ШАГ 2: ОТПРАВКА ОТВЕТА ===
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
 	// c.JSON() - автоматически устанавливает Content-Type: application/json
-	// и кодирует данные в JSON
-	c.JSON(http.StatusOK, users)
+	// и кодирует данные в JSON. Атрибуты - только видимые автору запроса
+	response, ok := h.present(c, users)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // GetByID получает одного пользователя по ID
 // Endpoint: GET /api/v1/users/:id
 // Headers: Authorization: Bearer TOKEN (защищён!)
-// Response: {"id": 1, "email": "...", "name": "..."}
+// Headers: If-None-Match: "5" - 304 Not Modified, если версия не изменилась
+// Response: {"id": 1, "email": "...", "name": "..."} + ETag: "5"
 func (h *UserHandler) GetByID(c *gin.Context) {
 	// === ШАГ 1: ИЗВЛЕЧЕНИЕ ID ИЗ URL ===
 	// c.Param("id") - получает параметр из URL
@@ -72,7 +123,7 @@ func (h *UserHandler) GetByID(c *gin.Context) {
 
 	// === ШАГ 2: ВЫЗОВ SERVICE ===
 	// Получаем пользователя по ID
-	user, err := h.userService.GetUser(uint(id))
+	user, err := h.userService.ForTenant(tenantOf(c)).GetUser(uint(id))
 	if err != nil {
 		// Пользователь не найден
 		c.JSON(http.StatusNotFound, gin.H{
@@ -81,15 +132,30 @@ func (h *UserHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
-	c.JSON(http.StatusOK, user)
+	// === ШАГ 3: УСЛОВНЫЙ ЗАПРОС ===
+	// Ответ зависит от автора запроса (видимость атрибутов) - только private кеш,
+	// no-cache - кеш обязан перепроверить версию через If-None-Match
+	c.Header("ETag", userETag(user))
+	c.Header("Cache-Control", "private, no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), userETag(user)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	// === ШАГ 4: ОТПРАВКА ОТВЕТА ===
+	response, ok := h.present(c, []domain.User{*user})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, response[0])
 }
 
-// Update обновляет данные пользователя
+// Update заменяет данные пользователя целиком
 // Endpoint: PUT /api/v1/users/:id
 // Headers: Authorization: Bearer TOKEN (защищён!)
-// Body: {"name": "...", "email": "..."}
-// Response: {"id": 1, "email": "...", "name": "..."}
+// Headers: If-Match: "5" - изменить, только если версия не изменилась
+// Body: {"name": "...", "email": "...", "role": "user", "attributes": {"department": "sales"}}
+// Response: {"id": 1, "email": "...", "name": "...", "attributes": {...}} + ETag: "6"
 func (h *UserHandler) Update(c *gin.Context) {
 	// === ШАГ 1: ИЗВЛЕЧЕНИЕ ID ===
 	idStr := c.Param("id")
@@ -105,8 +171,12 @@ func (h *UserHandler) Update(c *gin.Context) {
 	var req domain.UpdateUserRequest
 	
 	// ShouldBindJSON() парсит и валидирует
-	// Проверяет binding теги (omitempty, email, min=2)
+	// Проверяет binding теги (required, email, min=2)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, userDocumentMaxSize)
 	if err := c.ShouldBindJSON(&req); err != nil {
+		if respondDocumentTooLarge(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
@@ -114,22 +184,89 @@ func (h *UserHandler) Update(c *gin.Context) {
 	}
 
 	// === ШАГ 3: ВЫЗОВ SERVICE ===
-	// Service обновит пользователя в БД
-	user, err := h.userService.UpdateUser(uint(id), &req)
+	// Service обновит пользователя в БД и запишет изменения в журнал
+	// Роль и атрибуты с видимостью admin может менять только администратор
+	req.AsAdmin = middleware.HasRole(c, "admin")
+	req.Impersonated = middleware.GetActorIDFromContext(c) != 0
+	req.IfMatch = ifMatchCondition(c)
+	user, err := h.userService.ForTenant(tenantOf(c)).UpdateUser(uint(id), &req, middleware.GetUserIDFromContext(c), clientInfo(c))
 	if err != nil {
+		respondUpdateUserError(c, err)
+		return
+	}
+
+	// === ШАГ 4: ОТПРАВКА ОТВЕТА ===
+	c.Header("ETag", userETag(user))
+	response, ok := h.present(c, []domain.User{*user})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, response[0])
+}
+
+// Patch частично изменяет пользователя
+// Endpoint: PATCH /api/v1/users/:id
+// Headers: Authorization: Bearer TOKEN (защищён!)
+// Content-Type: application/merge-patch+json
+// Body: {"name": "...", "attributes": {"nickname": null}}
+// Content-Type: application/json-patch+json
+// Body: [{"op": "test", "path": "/email", "value": "..."}, {"op": "replace", "path": "/name", "value": "..."}]
+// Headers: If-Match: "5" - изменить, только если версия не изменилась
+// Response: {"id": 1, "email": "...", "name": "...", "attributes": {...}} + ETag: "6"
+func (h *UserHandler) Patch(c *gin.Context) {
+	// === ШАГ 1: ИЗВЛЕЧЕНИЕ ID И ТЕЛА ===
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	// Формат определяется по Content-Type, тело разбирает service
+	format := c.ContentType()
+	if format != domain.PatchFormatMerge && format != domain.PatchFormatJSON {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": service.ErrUnsupportedPatchFormat.Error(),
+		})
+		return
+	}
+	// Тело длиннее лимита обрывается при чтении, как загрузка аватара
+	patch, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, userDocumentMaxSize))
+	if err != nil {
+		if respondDocumentTooLarge(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
+			"error": "ошибка чтения тела запроса",
 		})
 		return
 	}
 
-	// === ШАГ 4: ОТПРАВКА ОТВЕТА ===
-	c.JSON(http.StatusOK, user)
+	// === ШАГ 2: ВЫЗОВ SERVICE ===
+	req := domain.PatchUserRequest{
+		Format:       format,
+		Patch:        patch,
+		AsAdmin:      middleware.HasRole(c, "admin"),
+		Impersonated: middleware.GetActorIDFromContext(c) != 0,
+		IfMatch:      ifMatchCondition(c),
+	}
+	user, err := h.userService.ForTenant(tenantOf(c)).PatchUser(id, &req, middleware.GetUserIDFromContext(c), clientInfo(c))
+	if err != nil {
+		respondUpdateUserError(c, err)
+		return
+	}
+
+	// === ШАГ 3: ОТПРАВКА ОТВЕТА ===
+	c.Header("ETag", userETag(user))
+	response, ok := h.present(c, []domain.User{*user})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, response[0])
 }
 
 // Delete удаляет пользователя (soft delete)
 // Endpoint: DELETE /api/v1/users/:id
 // Headers: Authorization: Bearer TOKEN (защищён!)
+// Headers: If-Match: "5" - удалить, только если версия не изменилась
 // Response: {"message": "пользователь удалён"}
 func (h *UserHandler) Delete(c *gin.Context) {
 	// === ШАГ 1: ИЗВЛЕЧЕНИЕ ID ===
@@ -144,7 +281,14 @@ func (h *UserHandler) Delete(c *gin.Context) {
 
 	// === ШАГ 2: ВЫЗОВ SERVICE ===
 	// Service удалит пользователя (soft delete)
-	if err := h.userService.DeleteUser(uint(id)); err != nil {
+	err = h.userService.ForTenant(tenantOf(c)).DeleteUser(uint(id), ifMatchCondition(c), middleware.GetUserIDFromContext(c), clientInfo(c))
+	if errors.Is(err, service.ErrUserVersionMismatch) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
 		})
@@ -158,3 +302,226 @@ func (h *UserHandler) Delete(c *gin.Context) {
 	})
 }
 
+
+// ================================================================
+// УДАЛЁННЫЕ ПОЛЬЗОВАТЕЛИ (только роль admin)
+// ================================================================
+
+// ListDeleted - удалённые пользователи, которых ещё можно восстановить
+// Endpoint: GET /api/v1/admin/deleted-users
+// Response: [{"id": 5, "email": "...", "deleted_at": "...", "purge_at": "..."}, ...]
+func (h *UserHandler) ListDeleted(c *gin.Context) {
+	users, err := h.userService.ForTenant(tenantOf(c)).ListDeletedUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения удалённых пользователей",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// Restore - отменить удаление
+// Endpoint: POST /api/v1/admin/deleted-users/:id/restore
+// Response: пользователь (войти нужно заново)
+func (h *UserHandler) Restore(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.ForTenant(tenantOf(c)).RestoreUser(id, middleware.GetUserIDFromContext(c), clientInfo(c))
+	if err != nil {
+		respondDeletedUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// Purge - стереть удалённого пользователя окончательно
+// Endpoint: DELETE /api/v1/admin/deleted-users/:id
+// Response: {"message": "пользователь удалён окончательно"}
+func (h *UserHandler) Purge(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.userService.ForTenant(tenantOf(c)).PurgeUser(id, middleware.GetUserIDFromContext(c), clientInfo(c)); err != nil {
+		respondDeletedUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "пользователь удалён окончательно",
+	})
+}
+
+// ================================================================
+// АТРИБУТЫ
+// ================================================================
+
+// attributeFilter - фильтр из параметров attr.<key> (пустой - фильтра нет)
+// Возвращает false, если ответ с ошибкой уже отправлен
+func (h *UserHandler) attributeFilter(c *gin.Context) (domain.UserAttributes, bool) {
+	return parseAttributeFilter(c, h.attributes)
+}
+
+// parseAttributeFilter - фильтр attr.<key> для списка и выгрузки пользователей
+// attributes может быть nil: фильтр по атрибутам - 400
+func parseAttributeFilter(c *gin.Context, attributes service.AttributeService) (domain.UserAttributes, bool) {
+	query := map[string]string{}
+	for name, values := range c.Request.URL.Query() {
+		if key, found := strings.CutPrefix(name, attributeQueryPrefix); found && len(values) > 0 {
+			query[key] = values[0]
+		}
+	}
+	if len(query) == 0 {
+		return nil, true
+	}
+	if attributes == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": service.ErrAttributesDisabled.Error(),
+		})
+		return nil, false
+	}
+
+	// Администратор фильтрует по любым атрибутам, остальные - только по public
+	access := domain.AttributeAccess{Admin: middleware.HasRole(c, "admin")}
+	filter, err := attributes.ForTenant(tenantOf(c)).ParseFilter(query, access)
+	if respondAttributeError(c, err) {
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения атрибутов",
+		})
+		return nil, false
+	}
+	return filter, true
+}
+
+// present - пользователи с атрибутами, которые видит автор запроса:
+// public - все, private - сам пользователь и администраторы, admin - администраторы
+// Возвращает false, если ответ с ошибкой уже отправлен
+func (h *UserHandler) present(c *gin.Context, users []domain.User) ([]domain.UserResponse, bool) {
+	if h.attributes == nil {
+		response := make([]domain.UserResponse, 0, len(users))
+		for _, user := range users {
+			response = append(response, domain.UserResponse{User: user, Attributes: domain.UserAttributes{}})
+		}
+		return response, true
+	}
+
+	viewerID := middleware.GetUserIDFromContext(c)
+	response, err := h.attributes.ForTenant(tenantOf(c)).Present(users, viewerID, middleware.HasRole(c, "admin"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения атрибутов",
+		})
+		return nil, false
+	}
+	return response, true
+}
+
+// ================================================================
+// ETAG - Условные запросы (оптимистичная блокировка)
+// ================================================================
+// ETag пользователя - его версия: "5". Клиент читает пользователя, а изменяет
+// с If-Match: "5" - если кто-то успел изменить запись, ответ 412 и клиент
+// перечитывает её, вместо того чтобы молча затереть чужие изменения
+
+// userETag - сильный ETag пользователя
+func userETag(user *domain.User) string {
+	return `"` + strconv.FormatUint(uint64(user.Version), 10) + `"`
+}
+
+// ifMatchCondition - версии из заголовка If-Match
+// nil - заголовка нет или "*" (любая версия существующего пользователя)
+// If-Match сравнивает ETag строго: слабые (W/"5") и чужие значения не подходят
+func ifMatchCondition(c *gin.Context) domain.VersionCondition {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	condition := domain.VersionCondition{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+			continue
+		}
+		if version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 32); err == nil {
+			condition = append(condition, uint(version))
+		}
+	}
+	return condition
+}
+
+// etagMatches - есть ли etag в заголовке If-None-Match
+// If-None-Match сравнивает слабо: W/"5" совпадает с "5"
+func etagMatches(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// respondDocumentTooLarge - 413, если тело PUT/PATCH длиннее userDocumentMaxSize
+func respondDocumentTooLarge(c *gin.Context, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error": "тело запроса больше " + strconv.Itoa(userDocumentMaxSize/1024) + " KB",
+	})
+	return true
+}
+
+// respondUpdateUserError - ошибка PUT/PATCH → HTTP статус
+// Атрибуты, не прошедшие схему, - 400 со списком нарушений
+func respondUpdateUserError(c *gin.Context, err error) {
+	if respondAttributeError(c, err) {
+		return
+	}
+
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, service.ErrUserUpdateForbidden), errors.Is(err, service.ErrRoleChangeForbidden), errors.Is(err, service.ErrEmailChangeImpersonated):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrPatchTestFailed), errors.Is(err, service.ErrEmailAlreadyRegistered):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrUnsupportedPatchFormat):
+		status = http.StatusUnsupportedMediaType
+	case errors.Is(err, service.ErrUserVersionMismatch):
+		status = http.StatusPreconditionFailed
+	}
+
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
+
+// respondDeletedUserError - ошибка восстановления/удаления → HTTP статус
+func respondDeletedUserError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrDeletedUserNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrRestoreEmailTaken):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
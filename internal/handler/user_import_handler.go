@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// USER IMPORT HANDLER - Массовый импорт пользователей
+// ================================================================
+
+// UserImportHandler - структура для обработки запросов импорта
+type UserImportHandler struct {
+	imports service.UserImportService // Зависимость от User Import Service
+}
+
+// NewUserImportHandler - конструктор
+func NewUserImportHandler(imports service.UserImportService) *UserImportHandler {
+	return &UserImportHandler{imports: imports}
+}
+
+// importContentTypes - Content-Type тела → формат файла
+var importContentTypes = map[string]string{
+	"text/csv":             domain.UserImportFormatCSV,
+	"application/x-ndjson": domain.UserImportFormatNDJSON,
+	"application/ndjson":   domain.UserImportFormatNDJSON,
+}
+
+// Request - загрузка файла пользователей
+// Endpoint: POST /api/v1/admin/users/import?format=csv&dry_run=true&upsert=true&invite=true
+// Body: файл целиком (curl --data-binary @users.csv -H "Content-Type: text/csv")
+// Формат - из ?format или из Content-Type (text/csv, application/x-ndjson)
+// Response 202: задача импорта {"id": 5, "status": "pending", ...}
+func (h *UserImportHandler) Request(c *gin.Context) {
+	var req domain.CreateUserImportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if req.Format == "" {
+		mediaType, _, _ := mime.ParseMediaType(c.ContentType())
+		req.Format = importContentTypes[mediaType]
+	}
+
+	job, err := h.imports.ForTenant(tenantOf(c)).Request(middleware.GetUserIDFromContext(c), &req, c.Request.Body)
+	if err != nil {
+		respondUserImportError(c, err)
+		return
+	}
+
+	// 202 Accepted - строки проверяются и записываются в фоне, статус: GET /admin/imports/:id
+	c.JSON(http.StatusAccepted, job)
+}
+
+// Get - статус и отчёт импорта (счётчики, ошибки строк)
+// Endpoint: GET /api/v1/admin/imports/:id
+func (h *UserImportHandler) Get(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	job, err := h.imports.ForTenant(tenantOf(c)).Get(id)
+	if err != nil {
+		respondUserImportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// respondUserImportError - ошибка сервиса импорта → HTTP статус
+func respondUserImportError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrUserImportNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrUserImportFormat):
+		status = http.StatusUnsupportedMediaType
+	case errors.Is(err, service.ErrUserImportEmpty):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrUserImportTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	}
+
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = "ошибка сохранения файла импорта"
+	}
+	c.JSON(status, gin.H{
+		"error": message,
+	})
+}
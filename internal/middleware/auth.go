@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
 	"advanced-user-api/internal/pkg/jwt"
 
 	"github.com/gin-gonic/gin" // Gin фреймворк
@@ -14,9 +17,16 @@ import (
 // AUTH MIDDLEWARE - Проверка JWT токена
 // ================================================================
 
+// ClaimsValidator - дополнительная проверка токена после подписи и срока действия
+// Например, не отозван ли токен сменой пароля (реализует service.AuthService)
+type ClaimsValidator interface {
+	ValidateClaims(claims *jwt.Claims) error
+}
+
 // AuthMiddleware создаёт middleware для проверки JWT токена
 // Параметры:
 //   - cfg: конфигурация (для получения JWT secret)
+//   - validators: дополнительные проверки claims (необязательно)
 // Возвращает:
 //   - gin.HandlerFunc: middleware функцию
 //
@@ -26,7 +36,7 @@ import (
 //   {
 //       authorized.GET("", handler.GetAll) // Требует токен
 //   }
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+func AuthMiddleware(cfg *config.Config, validators ...ClaimsValidator) gin.HandlerFunc {
 	// Возвращаем функцию-обработчик
 	// Эта функция будет вызываться для каждого запроса к защищённым routes
 	return func(c *gin.Context) {
@@ -75,6 +85,22 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		// Подпись верна, но токен мог быть отозван (например, сменой пароля)
+		// или аккаунт заблокирован - тогда 403 с кодом статуса (account_suspended, ...)
+		for _, validator := range validators {
+			if err := validator.ValidateClaims(claims); err != nil {
+				if RespondAccountStatus(c, err) {
+					c.Abort()
+					return
+				}
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "невалидный или истёкший токен",
+				})
+				c.Abort()
+				return
+			}
+		}
+
 		// === ШАГ 4: СОХРАНЕНИЕ ДАННЫХ В КОНТЕКСТ ===
 		// Gin Context - хранилище данных для текущего запроса
 		// Сохраняем данные из токена, чтобы handlers могли их использовать
@@ -87,6 +113,28 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 		
 		// Сохраняем роль (для проверки прав доступа)
 		c.Set("userRole", claims.Role)
+		
+		// Сохраняем ID сеанса (для списка сеансов и выхода)
+		c.Set("sessionID", claims.SessionID)
+		
+		// Сохраняем время выдачи токена (повторная аутентификация по недавнему входу)
+		if claims.IssuedAt != nil {
+			c.Set("tokenIssuedAt", claims.IssuedAt.Time)
+		}
+		
+		// Токен имперсонации - сохраняем реального автора запроса
+		if claims.Actor != nil {
+			c.Set("actorID", claims.Actor.UserID)
+		}
+
+		// Токен действует только в своей организации (см. TenantMiddleware)
+		if !applyTokenTenant(c, claims.OrganizationID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "токен выдан для другой организации",
+			})
+			c.Abort()
+			return
+		}
 
 		// === ШАГ 5: ПРОДОЛЖЕНИЕ ОБРАБОТКИ ===
 		// c.Next() - вызывает следующий handler в цепочке
@@ -136,6 +184,59 @@ func GetUserRoleFromContext(c *gin.Context) string {
 	return ""
 }
 
+// GetSessionIDFromContext - извлекает ID сеанса текущего токена из контекста
+// Пустая строка - токен выдан без сеанса
+func GetSessionIDFromContext(c *gin.Context) string {
+	sessionID, exists := c.Get("sessionID")
+	if !exists {
+		return ""
+	}
+	
+	if id, ok := sessionID.(string); ok {
+		return id
+	}
+	
+	return ""
+}
+
+// GetTokenIssuedAtFromContext - извлекает время выдачи текущего токена из контекста
+// Нулевое время - токен без iat
+func GetTokenIssuedAtFromContext(c *gin.Context) time.Time {
+	issuedAt, exists := c.Get("tokenIssuedAt")
+	if !exists {
+		return time.Time{}
+	}
+	
+	if t, ok := issuedAt.(time.Time); ok {
+		return t
+	}
+	
+	return time.Time{}
+}
+
+// RespondAccountStatus - отвечает 403, если err - *domain.AccountStatusError
+// Возвращает true, если ответ отправлен
+// Используется AuthMiddleware и handlers входа (пароль, ссылка, passkey)
+//
+// Формат ответа:
+//   {"error": "аккаунт временно заблокирован до ...", "code": "account_suspended", "until": "..."}
+func RespondAccountStatus(c *gin.Context, err error) bool {
+	var statusErr *domain.AccountStatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+
+	body := gin.H{
+		"error": statusErr.Error(),
+		"code":  statusErr.Code(),
+	}
+	if statusErr.Until != nil {
+		body["until"] = statusErr.Until
+	}
+	c.JSON(http.StatusForbidden, body)
+	return true
+}
+
 // RequireRole - middleware для проверки роли пользователя
 // Используется для ограничения доступа (например, только для admin)
 //
@@ -145,11 +246,8 @@ func GetUserRoleFromContext(c *gin.Context) string {
 //   admin.Use(middleware.RequireRole("admin"))
 func RequireRole(requiredRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Получаем роль из контекста (установлена в AuthMiddleware)
-		userRole := GetUserRoleFromContext(c)
-		
-		// Проверяем роль
-		if userRole != requiredRole {
+		// Проверяем роль: собственную (из токена) или унаследованную от групп
+		if !HasRole(c, requiredRole) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error": "недостаточно прав доступа",
 			})
@@ -24,12 +24,20 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Header("Access-Control-Allow-Origin", "*")
 		
 		// Access-Control-Allow-Methods - какие HTTP методы разрешены
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		
 		// Access-Control-Allow-Headers - какие заголовки может отправлять клиент
 		// Authorization - для JWT токена
 		// Content-Type - для JSON
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
+		// X-Device-Name - название устройства для списка сеансов
+		// X-Request-ID - ID запроса (см. RequestIDMiddleware)
+		// X-Organization - организация запроса (см. TenantMiddleware)
+		// If-Match, If-None-Match - условные запросы к пользователям (ETag)
+		// Idempotency-Key - безопасный повтор POST (см. Idempotency)
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Device-Name, X-Request-ID, X-Organization, If-Match, If-None-Match, Idempotency-Key")
+		
+		// Access-Control-Expose-Headers - какие заголовки ответа доступны JavaScript
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID, ETag, Idempotent-Replayed")
 		
 		// Access-Control-Allow-Credentials - разрешить отправку cookies
 		c.Header("Access-Control-Allow-Credentials", "true")
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// GROUP ROLES - Роли, унаследованные через группы пользователей
+// ================================================================
+
+// RoleProvider - роли пользователя, полученные от его групп (реализует service.GroupService)
+type RoleProvider interface {
+	InheritedRoles(userID, orgID uint) ([]string, error)
+}
+
+// InheritedRoles - подключает роли групп к проверке RequireRole
+// Сам запрос к БД не выполняет: роли загружаются лениво, только когда
+// собственной роли пользователя не хватило, и кэшируются до конца запроса
+//
+// Пример:
+//
+//	api.Use(middleware.InheritedRoles(groupService))
+func InheritedRoles(provider RoleProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("roleProvider", provider)
+		c.Next()
+	}
+}
+
+// HasRole - есть ли у пользователя запроса роль role
+// Порядок проверки:
+//  1. Роль из токена (users.role)
+//  2. Роли групп пользователя и их родительских групп (если подключён InheritedRoles)
+func HasRole(c *gin.Context, role string) bool {
+	if GetUserRoleFromContext(c) == role {
+		return true
+	}
+
+	for _, inherited := range inheritedRolesOf(c) {
+		if inherited == role {
+			return true
+		}
+	}
+	return false
+}
+
+// inheritedRolesOf - роли групп пользователя (один запрос на HTTP запрос)
+// Ошибка провайдера - пустой список: при сбое БД доступ не расширяется
+func inheritedRolesOf(c *gin.Context) []string {
+	if cached, exists := c.Get("inheritedRoles"); exists {
+		return cached.([]string)
+	}
+
+	value, exists := c.Get("roleProvider")
+	userID := GetUserIDFromContext(c)
+	if !exists || userID == 0 {
+		return nil
+	}
+
+	roles, err := value.(RoleProvider).InheritedRoles(userID, GetTenantIDFromContext(c))
+	if err != nil {
+		log.Printf("⚠️  Не удалось загрузить роли групп пользователя %d: %v", userID, err)
+		roles = []string{}
+	}
+
+	c.Set("inheritedRoles", roles)
+	return roles
+}
@@ -0,0 +1,371 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// IDEMPOTENCY MIDDLEWARE - Повтор POST запросов по Idempotency-Key
+// ================================================================
+// Клиент на нестабильной сети не знает, выполнен ли запрос, и повторяет его.
+// С одинаковым заголовком Idempotency-Key повтор не выполняется заново,
+// а получает первый ответ (статус, заголовки, тело) с Idempotent-Replayed: true
+
+const (
+	// IdempotencyKeyHeader - заголовок с ключом от клиента (обычно UUID)
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader - ответ повторён из хранилища, а не выполнен заново
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength - наибольшая длина ключа
+	maxIdempotencyKeyLength = 255
+
+	// idempotencyResultKey - ключ gin контекста: результат для сохранения (SetIdempotencyResult)
+	idempotencyResultKey = "idempotencyResult"
+
+	// idempotencyReplayKey - ключ gin контекста: сохранённый результат на повторе (IdempotencyResult)
+	idempotencyReplayKey = "idempotencyReplay"
+
+	// defaultIdempotencyMaxBodySize - лимит тела в памяти, если IDEMPOTENCY_MAX_BODY_SIZE не задан (1 МБ)
+	defaultIdempotencyMaxBodySize = 1024 * 1024
+)
+
+// IdempotencyStore - сохранённые ответы (реализует service.IdempotencyService)
+type IdempotencyStore interface {
+	Begin(key, fingerprint string) (*domain.IdempotencyRecord, error)
+	Complete(key, fingerprint string, status int, headers map[string][]string, body []byte) error
+	CompleteResult(key, fingerprint string, status int, result []byte) error
+	Hold(key string) (stop func())
+	Release(key string) error
+}
+
+// Idempotency - выполняет POST запрос с Idempotency-Key не больше одного раза
+// Ключ действует в пределах автора запроса (пользователь токена или anonymous),
+// организации и маршрута. Повтор с тем же ключом:
+//   - тот же запрос, ответ готов - сохранённый ответ
+//   - тот же запрос ещё выполняется - 409 Conflict
+//   - другой метод, URL или тело - 422 Unprocessable Entity
+//
+// Ответы 5xx, 401 и 429 не сохраняются: ключ освобождается и запрос можно повторить
+// Ответы с Cache-Control: no-store (выданные токены) не сохраняются тоже:
+// токен не должен лежать в хранилище и переживать отзыв сеанса. Handler такого
+// ответа может сохранить вместо него результат (SetIdempotencyResult) - тогда повтор
+// снова вызывает handler, а тот по IdempotencyResult выдаёт новый токен без повторного действия
+//
+// Отпечаток запроса - метод, URL и SHA-256 тела. Тело до IDEMPOTENCY_MAX_BODY_SIZE
+// читается в память заранее; длиннее (импорт CSV, аватар) - не буферизуется:
+// хеш считается по ходу чтения handler'ом, а лимит размера - забота handler.
+// Если handler не дочитал такое тело, ответ не сохраняется. Ответ длиннее
+// IDEMPOTENCY_MAX_BODY_SIZE отдаётся клиенту, но не сохраняется
+// Подключается к группе /api/v1 после TenantMiddleware
+func Idempotency(store IdempotencyStore, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientKey := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || clientKey == "" {
+			c.Next()
+			return
+		}
+		if !validIdempotencyKey(clientKey) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Idempotency-Key - от 1 до %d печатных ASCII символов", maxIdempotencyKeyLength),
+			})
+			c.Abort()
+			return
+		}
+
+		// === ШАГ 1: КЛЮЧ И ОТПЕЧАТОК ЗАПРОСА ===
+		// Тело до лимита читается в память и возвращается в запрос для handler;
+		// длиннее - handler читает его из hasher, отпечаток известен после чтения
+		maxBodySize := cfg.IdempotencyMaxBodySize
+		if maxBodySize <= 0 {
+			maxBodySize = defaultIdempotencyMaxBodySize
+		}
+		head, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodySize+1))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "не удалось прочитать тело запроса",
+			})
+			c.Abort()
+			return
+		}
+		var hasher *bodyHasher
+		fingerprint := ""
+		if int64(len(head)) <= maxBodySize {
+			digest := sha256.Sum256(head)
+			fingerprint = requestFingerprint(c, hex.EncodeToString(digest[:]))
+			c.Request.Body = io.NopCloser(bytes.NewReader(head))
+		} else {
+			// Пустой отпечаток - Begin не сравнивает его с сохранённым
+			hasher = newBodyHasher(io.MultiReader(bytes.NewReader(head), c.Request.Body))
+			c.Request.Body = readCloser{Reader: hasher, Closer: c.Request.Body}
+		}
+		key := hashParts(idempotencyPrincipal(c, cfg), c.Request.Method, c.FullPath(), clientKey)
+
+		// === ШАГ 2: ПОВТОР ИЛИ ПЕРВЫЙ ЗАПРОС ===
+		record, err := store.Begin(key, fingerprint)
+		switch {
+		case errors.Is(err, domain.ErrIdempotencyInProgress):
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case errors.Is(err, domain.ErrIdempotencyKeyReused):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "ошибка проверки ключа идемпотентности",
+			})
+			c.Abort()
+			return
+		case record != nil && fingerprint == "" && requestFingerprint(c, hasher.finish(-1)) != record.Fingerprint:
+			// Длинное тело сравнивается с сохранённым только сейчас
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": domain.ErrIdempotencyKeyReused.Error()})
+			c.Abort()
+			return
+		case record != nil && record.Rebuild:
+			// Ответ строит handler по результату первого выполнения
+			c.Set(idempotencyReplayKey, record.Body)
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Next()
+			return
+		case record != nil:
+			replayResponse(c, record)
+			return
+		}
+
+		// === ШАГ 3: ВЫПОЛНЕНИЕ С ЗАПИСЬЮ ОТВЕТА ===
+		// Заголовки, выставленные до этого middleware (X-Request-ID, CORS),
+		// не сохраняются: на повторе их выставит тот же middleware
+		before := make(map[string]bool, len(c.Writer.Header()))
+		for name := range c.Writer.Header() {
+			before[name] = true
+		}
+		writer := &idempotencyWriter{ResponseWriter: c.Writer, limit: maxBodySize}
+		c.Writer = writer
+
+		completed := false
+		defer func() {
+			// panic или ответ, который не сохраняется - ключ свободен для повтора
+			if !completed {
+				if err := store.Release(key); err != nil {
+					log.Printf("⚠️  Ошибка освобождения ключа идемпотентности: %v", err)
+				}
+			}
+		}()
+
+		// Пока handler выполняется, ключ остаётся занятым (аренда продлевается)
+		stop := store.Hold(key)
+		defer stop()
+
+		c.Next()
+		stop()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError || status == http.StatusUnauthorized || status == http.StatusTooManyRequests {
+			return
+		}
+		if fingerprint == "" {
+			// Остаток длинного тела (эпилог multipart) дочитывается, но не больше лимита:
+			// тело, которое handler отверг, не дочитав, не сохраняется
+			digest := hasher.finish(maxBodySize)
+			if digest == "" {
+				return
+			}
+			fingerprint = requestFingerprint(c, digest)
+		}
+		if strings.Contains(writer.Header().Get("Cache-Control"), "no-store") {
+			result, ok := c.Get(idempotencyResultKey)
+			if !ok {
+				return
+			}
+			if err := store.CompleteResult(key, fingerprint, status, result.([]byte)); err != nil {
+				log.Printf("⚠️  Ошибка сохранения результата по ключу идемпотентности: %v", err)
+				return
+			}
+			completed = true
+			return
+		}
+		if writer.truncated {
+			return
+		}
+		headers := map[string][]string{}
+		for name, values := range writer.Header() {
+			if !before[name] {
+				headers[name] = values
+			}
+		}
+		if err := store.Complete(key, fingerprint, status, headers, writer.body.Bytes()); err != nil {
+			log.Printf("⚠️  Ошибка сохранения ответа по ключу идемпотентности: %v", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// SetIdempotencyResult - вместо ответа с no-store (выданным токеном) сохранить result
+// Повтор с тем же Idempotency-Key снова вызовет handler, и IdempotencyResult вернёт result
+// (тело длиннее IDEMPOTENCY_MAX_BODY_SIZE к этому моменту уже прочитано для сравнения)
+func SetIdempotencyResult(c *gin.Context, result []byte) {
+	c.Set(idempotencyResultKey, result)
+}
+
+// IdempotencyResult - результат первого выполнения (SetIdempotencyResult), если запрос - повтор
+func IdempotencyResult(c *gin.Context) ([]byte, bool) {
+	result, ok := c.Get(idempotencyReplayKey)
+	if !ok {
+		return nil, false
+	}
+	return result.([]byte), true
+}
+
+// replayResponse - отдаёт сохранённый ответ
+func replayResponse(c *gin.Context, record *domain.IdempotencyRecord) {
+	for name, values := range record.Headers {
+		c.Writer.Header()[name] = values
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Status(record.Status)
+	_, _ = c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+// idempotencyPrincipal - автор запроса для ключа
+// AuthMiddleware подключается к маршрутам позже, поэтому токен разбирается
+// здесь же; без токена или с невалидным - "anonymous"
+// (отзыв токена не проверяется: запрос всё равно отклонит AuthMiddleware)
+func idempotencyPrincipal(c *gin.Context, cfg *config.Config) string {
+	principal := "anonymous"
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		if claims, err := jwt.ValidateToken(token, cfg.JWTSecret); err == nil {
+			principal = fmt.Sprintf("user:%d", claims.UserID)
+			if claims.Actor != nil {
+				principal += fmt.Sprintf(":actor:%d", claims.Actor.UserID)
+			}
+		}
+	}
+	return fmt.Sprintf("org:%d:%s", GetTenantIDFromContext(c), principal)
+}
+
+// validIdempotencyKey - 1-255 печатных ASCII символов
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return key != ""
+}
+
+// requestFingerprint - отпечаток запроса: метод, URL и SHA-256 тела
+func requestFingerprint(c *gin.Context, bodyDigest string) string {
+	return hashParts(c.Request.Method, c.Request.URL.RequestURI(), bodyDigest)
+}
+
+// hashParts - SHA-256 (hex) частей, разделённых нулевым байтом
+func hashParts(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// idempotencyWriter - копирует тело ответа для сохранения
+// Копия не растёт больше limit: длинный ответ отмечается truncated и не сохраняется
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+// Write - запись клиенту и в копию
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.copy(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString - запись клиенту и в копию
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.copy([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// copy - дописывает data в копию, пока она не превысит limit
+func (w *idempotencyWriter) copy(data []byte) {
+	if w.truncated {
+		return
+	}
+	if int64(w.body.Len()+len(data)) > w.limit {
+		w.truncated = true
+		w.body = bytes.Buffer{}
+		return
+	}
+	w.body.Write(data)
+}
+
+// bodyHasher - считает SHA-256 тела запроса по мере чтения, не храня его
+type bodyHasher struct {
+	source io.Reader
+	hash   hash.Hash
+	eof    bool
+}
+
+// newBodyHasher - конструктор
+func newBodyHasher(source io.Reader) *bodyHasher {
+	return &bodyHasher{source: source, hash: sha256.New()}
+}
+
+// Read - чтение с добавлением прочитанного в хеш
+func (h *bodyHasher) Read(p []byte) (int, error) {
+	n, err := h.source.Read(p)
+	h.hash.Write(p[:n])
+	if err == io.EOF {
+		h.eof = true
+	}
+	return n, err
+}
+
+// finish - дочитывает не больше limit байт (отрицательный - без ограничения)
+// и возвращает hex SHA-256 всего тела; "" - тело длиннее, чем удалось дочитать
+func (h *bodyHasher) finish(limit int64) string {
+	if !h.eof {
+		var rest io.Reader = h
+		if limit >= 0 {
+			rest = io.LimitReader(h, limit+1)
+		}
+		_, _ = io.Copy(io.Discard, rest)
+	}
+	if !h.eof {
+		return ""
+	}
+	return hex.EncodeToString(h.hash.Sum(nil))
+}
+
+// readCloser - тело запроса: чтение через bodyHasher, Close - исходного тела
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
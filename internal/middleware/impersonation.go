@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"advanced-user-api/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// IMPERSONATION MIDDLEWARE - Ограничения для токенов имперсонации
+// ================================================================
+
+// AuditRecorder - запись событий в журнал (реализует service.AuditService)
+type AuditRecorder interface {
+	Record(event *domain.AuditEvent)
+}
+
+// GetActorIDFromContext - ID администратора, если запрос выполнен токеном имперсонации
+// 0 - обычный токен (запрос выполняет сам пользователь)
+func GetActorIDFromContext(c *gin.Context) uint {
+	actorID, exists := c.Get("actorID")
+	if !exists {
+		return 0
+	}
+
+	if id, ok := actorID.(uint); ok {
+		return id
+	}
+
+	return 0
+}
+
+// BlockImpersonation - запрещает действие при имперсонации
+// Используется для чувствительных операций: смена пароля, управление passkeys
+// Ставится ПОСЛЕ AuthMiddleware
+//
+// Пример:
+//
+//	auth.POST("/password/change", authMiddleware, middleware.BlockImpersonation(), handler)
+func BlockImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetActorIDFromContext(c) != 0 {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "действие недоступно при входе от имени пользователя",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ImpersonationAudit - пишет в журнал каждый запрос с токеном имперсонации
+// Подключается глобально (router.Use): actorID появляется в контексте,
+// когда AuthMiddleware выполнится внутри c.Next()
+func ImpersonationAudit(recorder AuditRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Сначала выполняем запрос - статус ответа нужен для журнала
+		c.Next()
+
+		actorID := GetActorIDFromContext(c)
+		if actorID == 0 {
+			return
+		}
+		userID := GetUserIDFromContext(c)
+
+		recorder.Record(&domain.AuditEvent{
+			ActorID:   &actorID,
+			TargetID:  &userID,
+			Action:    domain.AuditActionImpersonatedRequest,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			RequestID: GetRequestIDFromContext(c),
+			Details:   fmt.Sprintf("%s %s → %d", c.Request.Method, c.Request.URL.RequestURI(), c.Writer.Status()),
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"advanced-user-api/internal/pkg/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// POLICY MIDDLEWARE - Проверка правил доступа (ABAC)
+// ================================================================
+
+// PolicyAuthorizer - решения по правилам (реализует service.AuthorizationService)
+type PolicyAuthorizer interface {
+	Authorize(req policy.Request) policy.Decision
+	SubjectAttributes(userID, orgID uint) (policy.Attributes, error)
+	ResourceAttributes(resourceType string, id, orgID uint) (policy.Attributes, error)
+}
+
+// RequirePolicy - пропускает запрос, только если правила разрешают action
+// idParam - параметр URL с ID ресурса ("id"); пусто - действие без конкретного ресурса
+// Используется после AuthMiddleware
+//
+// Пример:
+//
+//	users.PUT("/:id", middleware.RequirePolicy(authz, "users:update", "user", "id"), userHandler.Update)
+func RequirePolicy(authz PolicyAuthorizer, action, resourceType, idParam string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// === ШАГ 1: РЕСУРС ===
+		var resource policy.Attributes
+		if idParam != "" {
+			id, err := strconv.ParseUint(c.Param(idParam), 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "невалидный ID",
+				})
+				c.Abort()
+				return
+			}
+
+			resource, err = authz.ResourceAttributes(resourceType, uint(id), GetTenantIDFromContext(c))
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": "ресурс не найден",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		// === ШАГ 2: РЕШЕНИЕ ===
+		decision, err := AuthorizeRequest(c, authz, action, resourceType, resource)
+		if err != nil || !decision.Allowed {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":     "действие запрещено правилами доступа",
+				"policy_id": decision.PolicyID,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AuthorizeRequest - решение для пользователя запроса (для проверок внутри handlers)
+// Ошибка - атрибуты субъекта не загрузились (доступ в этом случае запрещается)
+func AuthorizeRequest(c *gin.Context, authz PolicyAuthorizer, action, resourceType string, resource policy.Attributes) (policy.Decision, error) {
+	subject, err := PolicySubject(c, authz)
+	if err != nil {
+		return policy.Decision{Effect: policy.EffectDeny, Reason: err.Error()}, err
+	}
+
+	return authz.Authorize(policy.Request{
+		Subject:      subject,
+		Action:       action,
+		ResourceType: resourceType,
+		Resource:     resource,
+		Context:      PolicyContext(c),
+	}), nil
+}
+
+// PolicySubject - атрибуты пользователя запроса (один запрос к БД на HTTP запрос)
+// К атрибутам пользователя добавляются claims токена: сеанс и имперсонация
+func PolicySubject(c *gin.Context, authz PolicyAuthorizer) (policy.Attributes, error) {
+	if cached, exists := c.Get("policySubject"); exists {
+		return cached.(policy.Attributes), nil
+	}
+
+	subject, err := authz.SubjectAttributes(GetUserIDFromContext(c), GetTenantIDFromContext(c))
+	if err != nil {
+		return nil, err
+	}
+
+	subject["session_id"] = GetSessionIDFromContext(c)
+	subject["impersonated"] = GetActorIDFromContext(c) != 0
+	if actorID := GetActorIDFromContext(c); actorID != 0 {
+		subject["actor_id"] = actorID
+	}
+
+	c.Set("policySubject", subject)
+	return subject, nil
+}
+
+// PolicyContext - атрибуты запроса (context.*); время добавляет движок
+func PolicyContext(c *gin.Context) policy.Attributes {
+	return policy.Attributes{
+		"ip":              c.ClientIP(),
+		"method":          c.Request.Method,
+		"path":            c.FullPath(),
+		"request_id":      GetRequestIDFromContext(c),
+		"organization_id": GetTenantIDFromContext(c),
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// PRECONDITION MIDDLEWARE - Обязательный If-Match
+// ================================================================
+// Без If-Match изменение применяется к любой текущей версии записи - два
+// администратора молча затирают изменения друг друга. Middleware заставляет
+// клиента передать ETag прочитанной версии (сама проверка - в handler и service)
+
+// RequireIfMatch - запрос без заголовка If-Match отклоняется (428 Precondition Required)
+// enabled = false - middleware ничего не проверяет (REQUIRE_IF_MATCH=false)
+func RequireIfMatch(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if enabled && c.GetHeader("If-Match") == "" {
+			c.JSON(http.StatusPreconditionRequired, gin.H{
+				"error": "требуется заголовок If-Match с ETag пользователя",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
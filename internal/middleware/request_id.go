@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// REQUEST ID MIDDLEWARE - Сквозной ID запроса
+// ================================================================
+
+// RequestIDHeader - заголовок с ID запроса (во входящем запросе и в ответе)
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID - допустимый ID от клиента или прокси
+// Остальное заменяется своим: значение попадает в журнал аудита
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestIDMiddleware - берёт X-Request-ID из запроса или генерирует новый
+// ID сохраняется в контексте и возвращается в заголовке ответа -
+// по нему событие журнала аудита находится в логах и наоборот
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Set("requestID", requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// GetRequestIDFromContext - ID текущего запроса ("" - middleware не подключён)
+func GetRequestIDFromContext(c *gin.Context) string {
+	return c.GetString("requestID")
+}
+
+// newRequestID - случайные 16 байт в hex
+func newRequestID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
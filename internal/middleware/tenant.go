@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================================================================
+// TENANT MIDDLEWARE - Определение организации запроса
+// ================================================================
+
+// TenantResolver - поиск организации по slug (реализует service.OrganizationService)
+type TenantResolver interface {
+	FindBySlug(slug string) (*domain.Organization, error)
+}
+
+// TenantMiddleware - определяет организацию, в которой выполняется запрос
+// Порядок (первый найденный источник):
+//  1. Заголовок TENANT_HEADER (по умолчанию X-Organization: acme)
+//  2. Поддомен TENANT_BASE_DOMAIN (acme.api.example.com → acme)
+//  3. Claim "org" токена - подставляет AuthMiddleware на защищённых маршрутах
+//  4. Организация по умолчанию (TENANT_DEFAULT)
+//
+// Явно указанная организация (1, 2) должна совпадать с организацией токена,
+// иначе AuthMiddleware отклоняет запрос
+func TenantMiddleware(cfg *config.Config, resolver TenantResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slug := c.GetHeader(cfg.TenantHeader)
+		if slug == "" {
+			slug = tenantFromHost(c.Request.Host, cfg.TenantBaseDomain)
+		}
+		explicit := slug != ""
+		if !explicit {
+			slug = cfg.TenantDefault
+		}
+
+		org, err := resolver.FindBySlug(strings.ToLower(slug))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "организация не найдена",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("tenantID", org.ID)
+		c.Set("tenantExplicit", explicit)
+		c.Next()
+	}
+}
+
+// GetTenantIDFromContext - ID организации запроса
+// 0 - TenantMiddleware не подключён (без организаций)
+func GetTenantIDFromContext(c *gin.Context) uint {
+	tenantID, exists := c.Get("tenantID")
+	if !exists {
+		return 0
+	}
+
+	if id, ok := tenantID.(uint); ok {
+		return id
+	}
+
+	return 0
+}
+
+// applyTokenTenant - сверяет организацию запроса с claim "org" токена
+// Возвращает false, если организация указана явно и не совпадает с токеном
+// Без явного указания организацией запроса становится организация токена
+func applyTokenTenant(c *gin.Context, orgID uint) bool {
+	if orgID == 0 {
+		return true
+	}
+
+	if c.GetBool("tenantExplicit") && GetTenantIDFromContext(c) != orgID {
+		return false
+	}
+
+	c.Set("tenantID", orgID)
+	return true
+}
+
+// tenantFromHost - slug из поддомена: acme.api.example.com:8080 → acme
+// Пустая строка - baseDomain не задан или хост не его поддомен первого уровня
+func tenantFromHost(host, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	sub, found := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(baseDomain))
+	if !found || sub == "" || strings.Contains(sub, ".") {
+		return ""
+	}
+	return sub
+}
@@ -0,0 +1,212 @@
+package fieldcrypt
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ================================================================
+// FIELDCRYPT - Шифрование отдельных полей в БД
+// ================================================================
+// Формат зашифрованного значения (одна строка в той же колонке):
+//
+//	enc:v1:<версия KEK>:<DEK, зашифрованный KEK>:<значение, зашифрованное DEK>
+//
+// Двоичные части - base64url без "=". Значение шифруется AES-256-GCM,
+// имя колонки - дополнительные данные (AAD): шифртекст email нельзя
+// подставить в колонку name. Строка без префикса enc: считается открытым
+// текстом (записи до включения шифрования) и читается как есть.
+//
+// Принятый риск: AAD не содержит таблицу и первичный ключ записи.
+// Тот, кто может писать в БД, может переставить шифртекст той же колонки
+// из одной строки в другую (email пользователя A - пользователю B), и он
+// расшифруется без ошибки. Привязка к строке не защитит от такого
+// нарушителя: он и так меняет role, password и token_version открытым
+// UPDATE. А ID новой записи неизвестен, пока сериализатор шифрует INSERT,
+// поэтому привязка к ключу потребовала бы второй записи после вставки.
+// Шифрование защищает от чтения дампов и реплик, а не от записи в БД.
+// Шифруемые колонки есть только в users, поэтому таблицу в AAD не добавляем.
+
+// Prefix - начало любого зашифрованного значения
+const Prefix = "enc:"
+
+// formatVersion - версия формата строки (не путать с версией ключа)
+const formatVersion = "v1"
+
+// ErrMalformedValue - значение начинается с enc:, но не разбирается
+var ErrMalformedValue = errors.New("fieldcrypt: повреждённое зашифрованное значение")
+
+// Encryptor - шифрование полей, перечисленных в конфигурации
+type Encryptor struct {
+	keys     KeyProvider
+	fields   map[string]bool // Колонки, которые шифруются (email, name)
+	indexKey []byte
+}
+
+// NewEncryptor - шифрование колонок fields ключами из keys
+func NewEncryptor(keys KeyProvider, fields []string) (*Encryptor, error) {
+	indexKey, err := keys.BlindIndexKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(indexKey) < keySize {
+		return nil, fmt.Errorf("%w: ключ blind index короче %d байт", ErrInvalidKeyFile, keySize)
+	}
+
+	enc := &Encryptor{
+		keys:     keys,
+		fields:   make(map[string]bool, len(fields)),
+		indexKey: indexKey,
+	}
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			enc.fields[field] = true
+		}
+	}
+	return enc, nil
+}
+
+// Encrypts - шифруется ли колонка column
+func (e *Encryptor) Encrypts(column string) bool {
+	return e.fields[column]
+}
+
+// Encrypt - шифрует значение колонки column текущим ключом
+// Пустая строка не шифруется
+func (e *Encryptor) Encrypt(column, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(aead, []byte(plaintext), []byte(column))
+	if err != nil {
+		return "", err
+	}
+
+	version, wrapped, err := e.keys.WrapKey(dek)
+	if err != nil {
+		return "", err
+	}
+
+	return Prefix + formatVersion + ":" + strconv.Itoa(version) + ":" +
+		base64.RawURLEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt - расшифровывает значение колонки column
+// Открытый текст (без префикса enc:) возвращается как есть
+func (e *Encryptor) Decrypt(column, stored string) (string, error) {
+	if !IsEncrypted(stored) {
+		return stored, nil
+	}
+
+	version, wrapped, sealed, err := parse(stored)
+	if err != nil {
+		return "", err
+	}
+	dek, err := e.keys.UnwrapKey(version, wrapped)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, sealed, []byte(column))
+	if err != nil {
+		return "", ErrMalformedValue
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation - нужно ли перезаписать значение колонки column:
+// - колонка шифруется, а значение открыто или зашифровано старым ключом
+// - колонка больше не шифруется, а значение зашифровано
+func (e *Encryptor) NeedsRotation(column, stored string) bool {
+	if stored == "" {
+		return false
+	}
+	if !e.Encrypts(column) {
+		return IsEncrypted(stored)
+	}
+	return !strings.HasPrefix(stored, e.CurrentPrefix())
+}
+
+// CurrentPrefix - начало значений, зашифрованных текущим ключом
+// Позволяет найти устаревшие значения запросом NOT LIKE '<prefix>%'
+func (e *Encryptor) CurrentPrefix() string {
+	return Prefix + formatVersion + ":" + strconv.Itoa(e.keys.CurrentVersion()) + ":"
+}
+
+// BlindIndex - детерминированный HMAC-SHA256 значения (hex)
+// Равные значения дают равный индекс - по нему работает точный поиск
+// и уникальность, но без ключа по индексу нельзя восстановить значение
+func (e *Encryptor) BlindIndex(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, e.indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncrypted - значение в формате fieldcrypt
+func IsEncrypted(stored string) bool {
+	return strings.HasPrefix(stored, Prefix)
+}
+
+// parse - разбор enc:v1:<версия>:<DEK>:<значение>
+func parse(stored string) (int, []byte, []byte, error) {
+	parts := strings.Split(stored, ":")
+	if len(parts) != 5 || parts[1] != formatVersion {
+		return 0, nil, nil, ErrMalformedValue
+	}
+	version, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, nil, nil, ErrMalformedValue
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return 0, nil, nil, ErrMalformedValue
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(parts[4])
+	if err != nil {
+		return 0, nil, nil, ErrMalformedValue
+	}
+	return version, wrapped, sealed, nil
+}
+
+// ================================================================
+// АКТИВНЫЙ ENCRYPTOR
+// ================================================================
+// GORM регистрирует сериализаторы глобально по имени, поэтому и
+// Encryptor, которым они пользуются, один на процесс
+
+var active atomic.Pointer[Encryptor]
+
+// SetActive - Encryptor для сериализатора "encrypted" и blind index
+// nil - шифрование выключено (значения пишутся открытым текстом)
+func SetActive(e *Encryptor) {
+	active.Store(e)
+}
+
+// Active - текущий Encryptor (nil - шифрование выключено)
+func Active() *Encryptor {
+	return active.Load()
+}
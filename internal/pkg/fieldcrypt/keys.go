@@ -0,0 +1,189 @@
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ================================================================
+// KEY PROVIDER - Ключи шифрования ключей (KEK)
+// ================================================================
+// Каждое значение шифруется своим случайным ключом данных (DEK),
+// а DEK - ключом шифрования ключей (KEK) из KeyProvider (envelope encryption).
+// Сами KEK в БД не попадают: в значении хранится только номер версии KEK
+// и зашифрованный ею DEK. Поэтому KEK может жить в KMS - реализации
+// KeyProvider достаточно уметь зашифровать и расшифровать DEK.
+
+// KeyProvider - источник ключей шифрования
+// Реализации: FileKeyProvider (локальный файл); KMS - через тот же интерфейс
+type KeyProvider interface {
+	// CurrentVersion - версия KEK для новых значений
+	CurrentVersion() int
+
+	// WrapKey - шифрует ключ данных текущей KEK
+	// Возвращает версию KEK, которой зашифрован ключ
+	WrapKey(dek []byte) (version int, wrapped []byte, err error)
+
+	// UnwrapKey - расшифровывает ключ данных KEK указанной версии
+	UnwrapKey(version int, wrapped []byte) ([]byte, error)
+
+	// BlindIndexKey - ключ HMAC для поиска по зашифрованным полям
+	// Не ротируется вместе с KEK: смена ключа требует пересчёта индекса
+	BlindIndexKey() ([]byte, error)
+}
+
+var (
+	// ErrUnknownKeyVersion - KEK такой версии нет (удалена из файла ключей раньше времени)
+	ErrUnknownKeyVersion = errors.New("fieldcrypt: неизвестная версия ключа")
+
+	// ErrInvalidKeyFile - файл ключей повреждён или неполон
+	ErrInvalidKeyFile = errors.New("fieldcrypt: некорректный файл ключей")
+)
+
+// keySize - длина KEK, DEK и ключа индекса (AES-256, HMAC-SHA256)
+const keySize = 32
+
+// ================================================================
+// FILE KEY PROVIDER - Ключи из локального JSON файла
+// ================================================================
+
+// keyFile - формат файла ключей:
+//
+//	{
+//	  "current": 2,
+//	  "keys": {"1": "<base64, 32 байта>", "2": "<base64, 32 байта>"},
+//	  "blind_index_key": "<base64, 32 байта>"
+//	}
+//
+// Ротация: добавить ключ новой версии и указать его в current.
+// Старые версии удаляются только после перешифрования всех записей
+type keyFile struct {
+	Current       int               `json:"current"`
+	Keys          map[string]string `json:"keys"`
+	BlindIndexKey string            `json:"blind_index_key"`
+}
+
+// FileKeyProvider - KEK хранятся в файле на диске сервера
+// Подходит для одного сервера и разработки; в production - KMS
+type FileKeyProvider struct {
+	current  int
+	keys     map[int]cipher.AEAD
+	indexKey []byte
+}
+
+// NewFileKeyProvider - загружает ключи из файла path
+func NewFileKeyProvider(path string) (*FileKeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fieldcrypt: чтение файла ключей: %w", err)
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeyFile, err)
+	}
+
+	provider := &FileKeyProvider{
+		current: file.Current,
+		keys:    make(map[int]cipher.AEAD, len(file.Keys)),
+	}
+	for name, encoded := range file.Keys {
+		version, err := strconv.Atoi(name)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%w: версия ключа %q", ErrInvalidKeyFile, name)
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: ключ версии %d: %v", ErrInvalidKeyFile, version, err)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		provider.keys[version] = aead
+	}
+	if _, ok := provider.keys[provider.current]; !ok {
+		return nil, fmt.Errorf("%w: нет ключа текущей версии %d", ErrInvalidKeyFile, provider.current)
+	}
+
+	provider.indexKey, err = decodeKey(file.BlindIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: blind_index_key: %v", ErrInvalidKeyFile, err)
+	}
+	return provider, nil
+}
+
+// CurrentVersion - версия KEK для новых значений
+func (p *FileKeyProvider) CurrentVersion() int {
+	return p.current
+}
+
+// WrapKey - AES-256-GCM(KEK, DEK), nonce в начале результата
+func (p *FileKeyProvider) WrapKey(dek []byte) (int, []byte, error) {
+	wrapped, err := seal(p.keys[p.current], dek, nil)
+	return p.current, wrapped, err
+}
+
+// UnwrapKey - расшифровка DEK ключом версии version
+func (p *FileKeyProvider) UnwrapKey(version int, wrapped []byte) ([]byte, error) {
+	aead, ok := p.keys[version]
+	if !ok {
+		return nil, ErrUnknownKeyVersion
+	}
+	return open(aead, wrapped, nil)
+}
+
+// BlindIndexKey - ключ HMAC для blind index
+func (p *FileKeyProvider) BlindIndexKey() ([]byte, error) {
+	return p.indexKey, nil
+}
+
+// decodeKey - base64 → ключ длиной keySize
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("нужно %d байта, получено %d", keySize, len(key))
+	}
+	return key, nil
+}
+
+// ================================================================
+// AES-GCM
+// ================================================================
+
+// newAEAD - AES-256-GCM для ключа key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal - шифрование со случайным nonce: nonce || ciphertext
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open - расшифровка результата seal
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformedValue
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
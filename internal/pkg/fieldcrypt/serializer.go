@@ -0,0 +1,81 @@
+package fieldcrypt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// ================================================================
+// GORM SERIALIZER - Прозрачное шифрование строковых полей
+// ================================================================
+// Использование в модели:
+//
+//	Email string `gorm:"serializer:encrypted"`
+//
+// Шифруются только колонки, перечисленные в FIELD_ENCRYPTION_FIELDS.
+// Остальные поля с этим тегом пишутся открытым текстом, но зашифрованное
+// значение (поле исключили из списка) всё равно читается.
+//
+// ВАЖНО: сериализатор применяется к Create, Save и Updates со структурой.
+// Updates с map пишет значение как есть - так можно записывать только
+// несекретные значения (например, заглушку стёртого аккаунта)
+
+// SerializerName - имя сериализатора в теге gorm
+const SerializerName = "encrypted"
+
+// ErrNoKeys - в БД зашифрованное значение, а ключи не настроены
+var ErrNoKeys = errors.New("fieldcrypt: значение зашифровано, но ключи шифрования не настроены")
+
+func init() {
+	schema.RegisterSerializer(SerializerName, Serializer{})
+}
+
+// Serializer - реализация schema.SerializerInterface поверх Active()
+type Serializer struct{}
+
+// Scan - значение из БД → расшифрованная строка в поле модели
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var stored string
+	switch value := dbValue.(type) {
+	case nil:
+	case string:
+		stored = value
+	case []byte:
+		stored = string(value)
+	default:
+		return fmt.Errorf("fieldcrypt: неподдерживаемый тип значения %T", dbValue)
+	}
+
+	plaintext := stored
+	if IsEncrypted(stored) {
+		enc := Active()
+		if enc == nil {
+			return ErrNoKeys
+		}
+		var err error
+		if plaintext, err = enc.Decrypt(field.DBName, stored); err != nil {
+			return fmt.Errorf("fieldcrypt: колонка %s: %w", field.DBName, err)
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).SetString(plaintext)
+	return nil
+}
+
+// Value - строка из модели → значение для БД (зашифрованное, если колонка в списке)
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("fieldcrypt: поле %s должно быть строкой", field.Name)
+	}
+
+	enc := Active()
+	if enc == nil || !enc.Encrypts(field.DBName) {
+		return plaintext, nil
+	}
+	return enc.Encrypt(field.DBName, plaintext)
+}
@@ -0,0 +1,144 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Регистрация декодера GIF (первый кадр)
+	"image/jpeg"
+	_ "image/png" // Регистрация декодера PNG
+	"io"
+	"net/http"
+)
+
+// ================================================================
+// IMAGING - Проверка и перекодирование загруженных изображений
+// ================================================================
+// Загруженный файл никогда не отдаётся как есть: изображение декодируется
+// и кодируется заново в JPEG. Так из файла пропадают метаданные (EXIF
+// с геопозицией) и всё, что не является пикселями (полиглоты HTML/JS)
+
+// Поддерживаемые форматы: тип содержимого → имя декодера image
+var formats = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// jpegQuality - качество JPEG для перекодированных изображений
+const jpegQuality = 85
+
+var (
+	// ErrUnsupportedFormat - содержимое не JPEG, PNG или GIF
+	ErrUnsupportedFormat = errors.New("imaging: неподдерживаемый формат изображения")
+
+	// ErrTooManyPixels - размеры изображения больше допустимых
+	// Проверяется по заголовку до декодирования: маленький файл может
+	// описывать огромную картинку ("декомпрессионная бомба")
+	ErrTooManyPixels = errors.New("imaging: слишком большое разрешение изображения")
+
+	// ErrCorrupted - заголовок верный, но изображение не декодируется
+	ErrCorrupted = errors.New("imaging: повреждённое изображение")
+)
+
+// DetectFormat - тип содержимого по первым байтам (заголовку клиента не доверяем)
+// Возвращает ErrUnsupportedFormat для всего, кроме JPEG, PNG и GIF
+func DetectFormat(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := formats[contentType]; !ok {
+		return "", ErrUnsupportedFormat
+	}
+	return contentType, nil
+}
+
+// Decode - проверка формата и размеров, затем декодирование
+// maxPixels - наибольшее допустимое число пикселей (ширина × высота)
+func Decode(data []byte, maxPixels int) (image.Image, error) {
+	contentType, err := DetectFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || format != formats[contentType] {
+		return nil, ErrCorrupted
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	return img, nil
+}
+
+// Square - квадрат из центра изображения со стороной size
+// Изображение не увеличивается: если оно меньше, сторона равна меньшей
+// стороне исходного. Прозрачные области заливаются белым (в JPEG нет альфа-канала)
+func Square(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	origin := image.Point{
+		X: bounds.Min.X + (bounds.Dx()-side)/2,
+		Y: bounds.Min.Y + (bounds.Dy()-side)/2,
+	}
+
+	// === ШАГ 1: ОБРЕЗКА ПО ЦЕНТРУ НА БЕЛОМ ФОНЕ ===
+	cropped := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(cropped, cropped.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(cropped, cropped.Bounds(), img, origin, draw.Over)
+
+	if size >= side {
+		return cropped
+	}
+
+	// === ШАГ 2: УМЕНЬШЕНИЕ ===
+	return downscale(cropped, size)
+}
+
+// downscale - уменьшение квадрата усреднением по области (box filter)
+// Каждый пиксель результата - среднее всех исходных пикселей, которые
+// он покрывает: без муара и "лесенок" при сильном уменьшении
+func downscale(src *image.RGBA, size int) *image.RGBA {
+	side := src.Bounds().Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	for y := 0; y < size; y++ {
+		y0, y1 := y*side/size, (y+1)*side/size
+		for x := 0; x < size; x++ {
+			x0, x1 := x*side/size, (x+1)*side/size
+
+			var r, g, b, a, count uint32
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					pixel := row[sx*4 : sx*4+4]
+					r += uint32(pixel[0])
+					g += uint32(pixel[1])
+					b += uint32(pixel[2])
+					a += uint32(pixel[3])
+					count++
+				}
+			}
+
+			offset := y*dst.Stride + x*4
+			dst.Pix[offset] = uint8(r / count)
+			dst.Pix[offset+1] = uint8(g / count)
+			dst.Pix[offset+2] = uint8(b / count)
+			dst.Pix[offset+3] = uint8(a / count)
+		}
+	}
+	return dst
+}
+
+// EncodeJPEG - запись изображения в JPEG
+func EncodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+}
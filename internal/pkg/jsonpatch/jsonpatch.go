@@ -0,0 +1,390 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ================================================================
+// JSON PATCH - Частичное изменение JSON документов
+// ================================================================
+// Два формата тела PATCH:
+//   - JSON Merge Patch (RFC 7396, application/merge-patch+json) - объект,
+//     который "накладывается" на документ: null удаляет поле
+//   - JSON Patch (RFC 6902, application/json-patch+json) - список операций
+//     add, remove, replace, move, copy, test над путями JSON Pointer (RFC 6901)
+//
+// Документы - значения после encoding/json: map[string]interface{},
+// []interface{}, string, float64, bool, nil. Исходный документ не меняется
+
+var (
+	// ErrInvalidPatch - тело не является патчем (не JSON, неизвестная операция, плохой путь)
+	ErrInvalidPatch = errors.New("jsonpatch: некорректный патч")
+
+	// ErrPathNotFound - путь операции не существует в документе
+	ErrPathNotFound = errors.New("jsonpatch: путь не найден")
+
+	// ErrTestFailed - значение по пути операции test отличается от ожидаемого
+	ErrTestFailed = errors.New("jsonpatch: проверка test не пройдена")
+)
+
+// Operation - одна операция JSON Patch
+type Operation struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	From string `json:"from,omitempty"` // Для move и copy
+
+	// Value - для add, replace и test
+	// json.RawMessage отличает отсутствующее значение от null
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ================================================================
+// JSON MERGE PATCH (RFC 7396)
+// ================================================================
+
+// MergePatch - применяет Merge Patch к документу target
+// Объекты сливаются рекурсивно, null удаляет поле, любое другое значение
+// (в том числе массив) заменяет поле целиком
+func MergePatch(target []byte, patch []byte) ([]byte, error) {
+	doc, err := decode(target)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(doc, changes))
+}
+
+// merge - алгоритм MergePatch из RFC 7396 (раздел 2)
+func merge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	// Копия target: исходный документ не меняется
+	result := map[string]interface{}{}
+	if targetObject, ok := target.(map[string]interface{}); ok {
+		for key, value := range targetObject {
+			result[key] = value
+		}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(result, key)
+			continue
+		}
+		result[key] = merge(result[key], value)
+	}
+	return result
+}
+
+// ================================================================
+// JSON PATCH (RFC 6902)
+// ================================================================
+
+// Apply - применяет операции JSON Patch к документу
+// Операции выполняются по порядку; при первой ошибке патч не применяется целиком
+// Ошибки: ErrInvalidPatch, ErrPathNotFound, ErrTestFailed (с номером операции)
+func Apply(target []byte, patch []byte) ([]byte, error) {
+	doc, err := decode(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []Operation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("%w: ожидается массив операций", ErrInvalidPatch)
+	}
+
+	for i, operation := range operations {
+		doc, err = operation.apply(doc)
+		if err != nil {
+			return nil, fmt.Errorf("%w (операция %d: %s %s)", err, i, operation.Op, operation.Path)
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// apply - выполняет одну операцию, возвращает новый документ
+func (o Operation) apply(doc interface{}) (interface{}, error) {
+	path, err := parsePointer(o.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o.Op {
+	case "add":
+		value, err := o.value()
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+
+	case "replace":
+		value, err := o.value()
+		if err != nil {
+			return nil, err
+		}
+		// replace = remove + add, но путь обязан существовать
+		if len(path) == 0 {
+			return value, nil
+		}
+		doc, _, err := remove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "move":
+		from, err := parsePointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		// Нельзя переместить объект внутрь самого себя
+		if o.Path != o.From && strings.HasPrefix(o.Path, o.From+"/") {
+			return nil, ErrInvalidPatch
+		}
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "copy":
+		from, err := parsePointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		// Копия не нужна: add и remove не меняют узлы на месте
+		return add(doc, path, value)
+
+	case "test":
+		expected, err := o.value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		// Числа после encoding/json - float64: 1 и 1.0 равны, как требует RFC
+		if !reflect.DeepEqual(actual, expected) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	}
+
+	return nil, ErrInvalidPatch
+}
+
+// value - значение операции (обязательно для add, replace и test)
+func (o Operation) value() (interface{}, error) {
+	if len(o.Value) == 0 {
+		return nil, ErrInvalidPatch
+	}
+	return decode(o.Value)
+}
+
+// ================================================================
+// JSON POINTER (RFC 6901)
+// ================================================================
+
+// parsePointer - "/a/b~1c" → ["a", "b/c"]; "" - весь документ
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, ErrInvalidPatch
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		// Порядок важен: "~01" - это "~1", а не "/"
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// get - значение по пути
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			doc = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+	return doc, nil
+}
+
+// add - вставляет value по пути: в объекте поле создаётся или заменяется,
+// в массиве элемент вставляется перед index ("-" - в конец)
+// Родитель пути обязан существовать
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		result := copyObject(node)
+		if len(rest) == 0 {
+			result[token] = value
+			return result, nil
+		}
+		child, ok := node[token]
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+		updated, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		result[token] = updated
+		return result, nil
+
+	case []interface{}:
+		if len(rest) == 0 {
+			index := len(node)
+			if token != "-" {
+				var err error
+				if index, err = arrayIndex(token, len(node)); err != nil {
+					return nil, err
+				}
+			}
+			result := make([]interface{}, 0, len(node)+1)
+			result = append(result, node[:index]...)
+			result = append(result, value)
+			return append(result, node[index:]...), nil
+		}
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := add(node[index], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		result := append([]interface{}(nil), node...)
+		result[index] = updated
+		return result, nil
+	}
+
+	return nil, ErrPathNotFound
+}
+
+// remove - удаляет значение по пути, возвращает новый документ и удалённое значение
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		// Удалить документ целиком нельзя - останется пустое тело
+		return nil, nil, ErrInvalidPatch
+	}
+
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok {
+			return nil, nil, ErrPathNotFound
+		}
+		result := copyObject(node)
+		if len(rest) == 0 {
+			delete(result, token)
+			return result, child, nil
+		}
+		updated, removed, err := remove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		result[token] = updated
+		return result, removed, nil
+
+	case []interface{}:
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			result := make([]interface{}, 0, len(node)-1)
+			result = append(result, node[:index]...)
+			return append(result, node[index+1:]...), node[index], nil
+		}
+		updated, removed, err := remove(node[index], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		result := append([]interface{}(nil), node...)
+		result[index] = updated
+		return result, removed, nil
+	}
+
+	return nil, nil, ErrPathNotFound
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// decode - разбор JSON значения (лишние данные после значения - ошибка)
+func decode(data []byte) (interface{}, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("%w: лишние данные после JSON", ErrInvalidPatch)
+	}
+	return value, nil
+}
+
+// arrayIndex - индекс массива из пути: только цифры без ведущих нулей, не больше max
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+		return 0, ErrInvalidPatch
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index > max {
+		return 0, ErrPathNotFound
+	}
+	return index, nil
+}
+
+// copyObject - поверхностная копия объекта (вложенные значения не меняются на месте)
+func copyObject(node map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(node)+1)
+	for key, value := range node {
+		result[key] = value
+	}
+	return result
+}
@@ -23,6 +23,24 @@ type Claims struct {
 	// Role - роль пользователя (для проверки прав доступа)
 	Role string `json:"role"`
 	
+	// TokenVersion - версия токенов пользователя на момент выдачи
+	// Увеличивается при смене пароля: все ранее выданные токены
+	// перестают приниматься AuthMiddleware
+	TokenVersion int `json:"ver,omitempty"`
+	
+	// SessionID - сеанс, для которого выдан токен (см. domain.Session)
+	// Завершение сеанса отзывает только его токен, остальные устройства остаются в системе
+	SessionID string `json:"sid,omitempty"`
+	
+	// Actor - администратор, действующий от имени пользователя (RFC 8693 "act")
+	// nil - обычный токен; UserID/Email/Role в этом случае - данные пользователя,
+	// от имени которого выполняются запросы
+	Actor *Actor `json:"act,omitempty"`
+
+	// OrganizationID - организация, для которой выдан токен
+	// С токеном другой организации запрос отклоняется (см. middleware.TenantMiddleware)
+	OrganizationID uint `json:"org,omitempty"`
+	
 	// RegisteredClaims - стандартные JWT claims (exp, iat, iss, etc.)
 	// Включает:
 	//   - ExpiresAt: время истечения токена
@@ -33,6 +51,12 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// Actor - реальный автор запросов в токене имперсонации
+type Actor struct {
+	UserID uint   `json:"user_id"`
+	Email  string `json:"email"`
+}
+
 // ================================================================
 // GENERATE TOKEN - Создание JWT токена
 // ================================================================
@@ -48,23 +72,30 @@ type Claims struct {
 //   - string: JWT токен (строка вида "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...")
 //   - error: ошибка генерации
 func GenerateToken(userID uint, email, role, secret string, expiration time.Duration) (string, error) {
-	// === ШАГ 1: СОЗДАНИЕ CLAIMS ===
-	// Claims - данные, которые будут закодированы в токене
-	claims := Claims{
+	return GenerateTokenFromClaims(Claims{
 		UserID: userID,
 		Email:  email,
 		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			// ExpiresAt - время истечения токена
-			// time.Now().Add(expiration) - текущее время + 24 часа (например)
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
-			
-			// IssuedAt - время создания токена
-			IssuedAt: jwt.NewNumericDate(time.Now()),
-			
-			// Issuer - кто выдал токен (название вашего приложения)
-			Issuer: "advanced-user-api",
-		},
+	}, secret, expiration)
+}
+
+// GenerateTokenFromClaims создаёт JWT токен из заполненных Claims
+// Используется, когда кроме ID/email/роли нужны дополнительные данные
+// (например, версия токенов пользователя)
+// Стандартные поля (exp, iat, iss) заполняются здесь
+func GenerateTokenFromClaims(claims Claims, secret string, expiration time.Duration) (string, error) {
+	// === ШАГ 1: СОЗДАНИЕ CLAIMS ===
+	// Claims - данные, которые будут закодированы в токене
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		// ExpiresAt - время истечения токена
+		// time.Now().Add(expiration) - текущее время + 24 часа (например)
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
+		
+		// IssuedAt - время создания токена
+		IssuedAt: jwt.NewNumericDate(time.Now()),
+		
+		// Issuer - кто выдал токен (название вашего приложения)
+		Issuer: "advanced-user-api",
 	}
 
 	// === ШАГ 2: СОЗДАНИЕ ТОКЕНА ===
@@ -0,0 +1,263 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3" // LDAP клиент
+)
+
+// ================================================================
+// LDAP UTILITIES - Аутентификация через LDAP / Active Directory
+// ================================================================
+
+// Ошибки аутентификации
+// Service слой различает их, чтобы решить: пробовать следующий
+// источник учётных данных или сразу отказать
+var (
+	// ErrUserNotFound - в каталоге нет пользователя с таким логином
+	ErrUserNotFound = errors.New("пользователь не найден в LDAP")
+
+	// ErrInvalidCredentials - пользователь найден, но пароль неверный
+	ErrInvalidCredentials = errors.New("неверные учётные данные LDAP")
+)
+
+// Config - настройки подключения к каталогу
+type Config struct {
+	URL          string        // Адрес сервера: ldap://host:389 или ldaps://host:636
+	BindDN       string        // Сервисная учётная запись для поиска (пусто - анонимно)
+	BindPassword string        // Пароль сервисной учётной записи
+	BaseDN       string        // Корень поиска пользователей
+	UserFilter   string        // Фильтр поиска, %s - экранированный логин
+	StartTLS     bool          // Выполнить StartTLS после подключения
+	Timeout      time.Duration // Таймаут подключения и операций
+
+	// Атрибуты, из которых читаются данные пользователя
+	EmailAttribute string // Обычно "mail"
+	NameAttribute  string // Обычно "displayName" или "cn"
+	GroupAttribute string // Обычно "memberOf"
+}
+
+// Entry - данные пользователя, прочитанные из каталога
+type Entry struct {
+	DN     string   // Distinguished Name пользователя
+	Email  string   // Email из EmailAttribute
+	Name   string   // Отображаемое имя из NameAttribute
+	Groups []string // DN групп из GroupAttribute
+}
+
+// Client - LDAP клиент для проверки учётных данных
+// Каждый вызов Authenticate открывает отдельное соединение:
+// после bind от имени пользователя соединение нельзя переиспользовать
+// для поиска от имени сервисной учётной записи
+type Client struct {
+	cfg Config
+}
+
+// NewClient - конструктор LDAP клиента
+func NewClient(cfg Config) *Client {
+	// Значения по умолчанию для необязательных настроек
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(&(objectClass=person)(mail=%s))"
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.NameAttribute == "" {
+		cfg.NameAttribute = "displayName"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+
+	return &Client{cfg: cfg}
+}
+
+// ================================================================
+// AUTHENTICATE - Search-and-bind
+// ================================================================
+
+// Authenticate проверяет логин и пароль пользователя в каталоге
+// Параметры:
+//   - username: логин (в нашем API это email)
+//   - password: пароль в открытом виде
+// Возвращает:
+//   - *Entry: данные пользователя из каталога
+//   - error: ErrUserNotFound, ErrInvalidCredentials или сетевая ошибка
+//
+// Процесс (классический search-and-bind):
+// 1. Подключаемся к серверу (и выполняем StartTLS, если нужно)
+// 2. Входим под сервисной учётной записью
+// 3. Ищем пользователя по фильтру и получаем его DN
+// 4. Выполняем bind с DN пользователя и его паролем
+func (c *Client) Authenticate(username, password string) (*Entry, error) {
+	// ВАЖНО: bind с пустым паролем на многих серверах - это
+	// "unauthenticated bind", который ВСЕГДА успешен. Отсекаем сразу.
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	// === ШАГ 1: ПОДКЛЮЧЕНИЕ ===
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// === ШАГ 2: BIND СЕРВИСНОЙ УЧЁТНОЙ ЗАПИСИ ===
+	if c.cfg.BindDN != "" {
+		if err := conn.Bind(c.cfg.BindDN, c.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap: bind сервисной учётной записи: %w", err)
+		}
+	}
+
+	// === ШАГ 3: ПОИСК ПОЛЬЗОВАТЕЛЯ ===
+	// EscapeFilter защищает от LDAP-инъекций (например, логин "*")
+	filter := fmt.Sprintf(c.cfg.UserFilter, goldap.EscapeFilter(username))
+
+	searchRequest := goldap.NewSearchRequest(
+		c.cfg.BaseDN,
+		goldap.ScopeWholeSubtree,
+		goldap.NeverDerefAliases,
+		2,                            // Нам нужна ровно одна запись, 2 - чтобы заметить дубликаты
+		int(c.cfg.Timeout.Seconds()), // Лимит времени на стороне сервера
+		false,
+		filter,
+		[]string{c.cfg.EmailAttribute, c.cfg.NameAttribute, c.cfg.GroupAttribute},
+		nil,
+	)
+
+	result, err := conn.Search(searchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: поиск пользователя: %w", err)
+	}
+
+	if len(result.Entries) == 0 {
+		return nil, ErrUserNotFound
+	}
+	if len(result.Entries) > 1 {
+		// Несколько записей под одним логином - не угадываем, отказываем
+		return nil, fmt.Errorf("ldap: найдено несколько записей для %q", username)
+	}
+
+	found := result.Entries[0]
+
+	// === ШАГ 4: BIND ОТ ИМЕНИ ПОЛЬЗОВАТЕЛЯ ===
+	// Сервер сам проверяет пароль, политики блокировки и срок действия
+	if err := conn.Bind(found.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap: bind пользователя: %w", err)
+	}
+
+	return &Entry{
+		DN:     found.DN,
+		Email:  found.GetAttributeValue(c.cfg.EmailAttribute),
+		Name:   found.GetAttributeValue(c.cfg.NameAttribute),
+		Groups: found.GetAttributeValues(c.cfg.GroupAttribute),
+	}, nil
+}
+
+// dial - открывает соединение с сервером с учётом таймаута и StartTLS
+func (c *Client) dial() (*goldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.cfg.Timeout}
+
+	conn, err := goldap.DialURL(c.cfg.URL, goldap.DialWithDialer(dialer))
+	if err != nil {
+		return nil, fmt.Errorf("ldap: подключение к %s: %w", c.cfg.URL, err)
+	}
+
+	// Таймаут на каждую операцию (bind, search)
+	conn.SetTimeout(c.cfg.Timeout)
+
+	if c.cfg.StartTLS {
+		// ServerName нужен для проверки сертификата сервера
+		u, err := url.Parse(c.cfg.URL)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap: невалидный URL: %w", err)
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: u.Hostname()}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap: StartTLS: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// ================================================================
+// GROUP → ROLE MAPPING - Сопоставление групп каталога ролям
+// ================================================================
+
+// GroupRole - одно правило сопоставления: группа каталога → роль в API
+type GroupRole struct {
+	GroupDN string
+	Role    string
+}
+
+// GroupRoleMapping - упорядоченный список правил
+// Порядок важен: первая совпавшая группа определяет роль
+type GroupRoleMapping []GroupRole
+
+// ParseGroupRoles разбирает строку вида "groupDN=role;groupDN=role"
+// DN сам содержит "=", поэтому роль отделяется ПОСЛЕДНИМ "="
+//
+// Пример:
+//   "cn=admins,ou=groups,dc=example,dc=com=admin;cn=support,ou=groups,dc=example,dc=com=support"
+func ParseGroupRoles(s string) (GroupRoleMapping, error) {
+	var mapping GroupRoleMapping
+
+	for _, pair := range strings.Split(s, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		idx := strings.LastIndex(pair, "=")
+		if idx <= 0 || idx == len(pair)-1 {
+			return nil, fmt.Errorf("ldap: неверное правило группы %q (ожидается groupDN=role)", pair)
+		}
+
+		mapping = append(mapping, GroupRole{
+			GroupDN: strings.TrimSpace(pair[:idx]),
+			Role:    strings.TrimSpace(pair[idx+1:]),
+		})
+	}
+
+	return mapping, nil
+}
+
+// Resolve возвращает роль для списка групп пользователя
+// Если ни одно правило не подошло - возвращает fallback
+func (m GroupRoleMapping) Resolve(groups []string, fallback string) string {
+	for _, rule := range m {
+		for _, group := range groups {
+			if sameDN(rule.GroupDN, group) {
+				return rule.Role
+			}
+		}
+	}
+	return fallback
+}
+
+// sameDN сравнивает два DN без учёта регистра и пробелов между RDN
+// "CN=Admins, DC=Example" и "cn=admins,dc=example" - одна и та же группа
+func sameDN(a, b string) bool {
+	dnA, errA := goldap.ParseDN(a)
+	dnB, errB := goldap.ParseDN(b)
+	if errA != nil || errB != nil {
+		// Не удалось разобрать - сравниваем как строки
+		return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+	}
+	return dnA.EqualFold(dnB)
+}
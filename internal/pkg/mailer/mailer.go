@@ -0,0 +1,114 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// ================================================================
+// MAILER - Отправка писем пользователям
+// ================================================================
+// Сервисы зависят только от интерфейса Mailer, поэтому транспорт
+// (лог для разработки, SMTP, внешний API) меняется без правок бизнес-логики
+
+// Message - одно письмо (text/plain)
+type Message struct {
+	To      string // Адрес получателя
+	Subject string // Тема
+	Body    string // Текст письма
+}
+
+// Mailer - интерфейс отправки писем
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// ================================================================
+// LOG MAILER - Письма только в лог (для разработки и тестов)
+// ================================================================
+
+// logMailer - пишет письма в стандартный лог вместо отправки
+type logMailer struct{}
+
+// NewLogMailer - конструктор
+func NewLogMailer() Mailer {
+	return &logMailer{}
+}
+
+// Send - выводит письмо в лог
+func (m *logMailer) Send(msg Message) error {
+	log.Printf("📧 Письмо для %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// ================================================================
+// SMTP MAILER - Отправка через SMTP сервер
+// ================================================================
+
+// SMTPConfig - параметры SMTP сервера
+type SMTPConfig struct {
+	Host     string // smtp.example.com
+	Port     string // 587 (STARTTLS) или 25
+	Username string // Пусто - без аутентификации
+	Password string
+	From     string // Адрес отправителя
+}
+
+// smtpMailer - отправка через net/smtp
+// STARTTLS включается автоматически, если сервер его поддерживает
+type smtpMailer struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPMailer - конструктор
+func NewSMTPMailer(cfg SMTPConfig) Mailer {
+	return &smtpMailer{cfg: cfg}
+}
+
+// Send - отправляет письмо через SMTP
+func (m *smtpMailer) Send(msg Message) error {
+	// === ШАГ 1: ПРОВЕРКА АДРЕСОВ ===
+	// Разбор через net/mail не даёт подставить заголовки через "\r\n" в адресе
+	from, err := mail.ParseAddress(m.cfg.From)
+	if err != nil {
+		return fmt.Errorf("неверный адрес отправителя: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("неверный адрес получателя: %w", err)
+	}
+
+	// === ШАГ 2: АУТЕНТИФИКАЦИЯ ===
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	// === ШАГ 3: ОТПРАВКА ===
+	addr := net.JoinHostPort(m.cfg.Host, m.cfg.Port)
+	return smtp.SendMail(addr, auth, from.Address, []string{to.Address}, buildMessage(from, to, msg))
+}
+
+// buildMessage - формирует письмо в формате RFC 5322
+func buildMessage(from, to *mail.Address, msg Message) []byte {
+	// Тема может содержать кириллицу - кодируем по RFC 2047
+	subject := mime.QEncoding.Encode("utf-8", strings.ReplaceAll(msg.Subject, "\n", " "))
+
+	var b strings.Builder
+	b.WriteString("From: " + from.String() + "\r\n")
+	b.WriteString("To: " + to.String() + "\r\n")
+	b.WriteString("Subject: " + subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
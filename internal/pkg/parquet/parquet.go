@@ -0,0 +1,407 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ================================================================
+// PARQUET - Потоковая запись файлов Apache Parquet
+// ================================================================
+// Минимальный писатель формата (https://parquet.apache.org/docs/file-format/):
+// плоская схема из OPTIONAL колонок, кодирование PLAIN, страницы без
+// сжатия (файл целиком можно сжать gzip) и без статистик
+//
+// Строки копятся в памяти до rowGroupSize, затем записываются группой
+// строк (row group) - по одной странице данных на колонку. В памяти
+// только текущая группа и метаданные записанных групп: файл любого
+// размера пишется потоком
+//
+// Структура файла: PAR1 | группы строк | FileMetaData (Thrift compact) | длина | PAR1
+
+// Type - тип колонки
+type Type int
+
+const (
+	String    Type = iota // BYTE_ARRAY (UTF8), значения string
+	Int64                 // INT64, значения int64
+	Double                // DOUBLE, значения float64
+	Boolean               // BOOLEAN, значения bool
+	Timestamp             // INT64 (TIMESTAMP_MILLIS, UTC), значения time.Time
+)
+
+// Column - колонка схемы файла
+type Column struct {
+	Name string
+	Type Type
+}
+
+// DefaultRowGroupSize - строк в группе, если rowGroupSize не задан
+const DefaultRowGroupSize = 10000
+
+var (
+	// ErrNoColumns - схема без колонок
+	ErrNoColumns = errors.New("parquet: нет колонок")
+
+	// ErrRowLength - число значений строки не совпадает с числом колонок
+	ErrRowLength = errors.New("parquet: число значений не совпадает с числом колонок")
+
+	// ErrValueType - тип значения не совпадает с типом колонки
+	ErrValueType = errors.New("parquet: тип значения не совпадает с типом колонки")
+
+	// ErrClosed - запись после Close
+	ErrClosed = errors.New("parquet: файл уже закрыт")
+)
+
+// magic - первые и последние 4 байта файла
+const magic = "PAR1"
+
+// Значения enum из parquet.thrift
+const (
+	thriftTypeBoolean   = 0
+	thriftTypeInt64     = 2
+	thriftTypeDouble    = 5
+	thriftTypeByteArray = 6
+
+	thriftRepetitionOptional = 1
+
+	thriftConvertedUTF8            = 0
+	thriftConvertedTimestampMillis = 9
+
+	thriftEncodingPlain = 0
+	thriftEncodingRLE   = 3
+
+	thriftCodecUncompressed = 0
+	thriftPageData          = 0
+)
+
+// Writer - запись файла Parquet в поток
+type Writer struct {
+	w            io.Writer
+	columns      []Column
+	rowGroupSize int
+
+	started bool
+	closed  bool
+	offset  int64 // Сколько байт уже записано в w
+
+	buffers   []*columnBuffer // Текущая группа строк
+	rows      int             // Строк в текущей группе
+	numRows   int64           // Строк во всём файле
+	rowGroups []rowGroup      // Метаданные записанных групп (для FileMetaData)
+}
+
+// columnBuffer - значения колонки текущей группы
+type columnBuffer struct {
+	defined []bool // Уровни определения: false - null
+	values  []byte // Не-null значения в кодировке PLAIN (кроме BOOLEAN)
+	bools   []bool // Не-null значения BOOLEAN (упаковываются по битам при записи)
+}
+
+// rowGroup - метаданные записанной группы строк
+type rowGroup struct {
+	numRows int64
+	offset  int64
+	size    int64
+	chunks  []columnChunk
+}
+
+// columnChunk - метаданные колонки в группе строк
+type columnChunk struct {
+	numValues  int64
+	size       int64
+	pageOffset int64
+}
+
+// NewWriter - писатель файла с колонками columns
+// rowGroupSize - строк в группе (0 - DefaultRowGroupSize). Больше группа -
+// лучше сжатие и чтение, но больше памяти на запись
+func NewWriter(w io.Writer, columns []Column, rowGroupSize int) (*Writer, error) {
+	if len(columns) == 0 {
+		return nil, ErrNoColumns
+	}
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+
+	buffers := make([]*columnBuffer, len(columns))
+	for i := range buffers {
+		buffers[i] = &columnBuffer{}
+	}
+	return &Writer{w: w, columns: columns, rowGroupSize: rowGroupSize, buffers: buffers}, nil
+}
+
+// ================================================================
+// ЗАПИСЬ СТРОК
+// ================================================================
+
+// Write - строка: по значению на колонку, nil - null
+// Строка проверяется целиком до записи: при ошибке группа не меняется
+func (w *Writer) Write(row []interface{}) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if len(row) != len(w.columns) {
+		return ErrRowLength
+	}
+	for i, value := range row {
+		if value != nil && !valueMatches(w.columns[i].Type, value) {
+			return fmt.Errorf("%w: %s (%T)", ErrValueType, w.columns[i].Name, value)
+		}
+	}
+
+	for i, value := range row {
+		w.buffers[i].append(value)
+	}
+	w.rows++
+	if w.rows >= w.rowGroupSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush - записывает накопленные строки отдельной группой
+// Вызывается автоматически каждые rowGroupSize строк
+func (w *Writer) Flush() error {
+	if w.closed {
+		return ErrClosed
+	}
+	if w.rows == 0 {
+		return nil
+	}
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	group := rowGroup{numRows: int64(w.rows), offset: w.offset}
+	for i, buffer := range w.buffers {
+		header, body := buffer.page(w.columns[i].Type)
+		chunk := columnChunk{
+			numValues:  int64(len(buffer.defined)),
+			size:       int64(len(header) + len(body)),
+			pageOffset: w.offset,
+		}
+		if err := w.write(header); err != nil {
+			return err
+		}
+		if err := w.write(body); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.size
+		w.buffers[i] = &columnBuffer{}
+	}
+
+	w.rowGroups = append(w.rowGroups, group)
+	w.numRows += group.numRows
+	w.rows = 0
+	return nil
+}
+
+// Close - записывает оставшиеся строки и метаданные файла
+// Без Close файл нечитаем: метаданные находятся в конце
+func (w *Writer) Close() error {
+	if w.closed {
+		return ErrClosed
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := w.start(); err != nil {
+		return err
+	}
+	w.closed = true
+
+	footer := w.footer()
+	if err := w.write(footer); err != nil {
+		return err
+	}
+	if err := w.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))); err != nil {
+		return err
+	}
+	return w.write([]byte(magic))
+}
+
+// start - PAR1 в начале файла (при первой записи)
+func (w *Writer) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	return w.write([]byte(magic))
+}
+
+func (w *Writer) write(data []byte) error {
+	n, err := w.w.Write(data)
+	w.offset += int64(n)
+	return err
+}
+
+// valueMatches - подходит ли значение к типу колонки
+func valueMatches(columnType Type, value interface{}) bool {
+	switch value.(type) {
+	case string:
+		return columnType == String
+	case int64:
+		return columnType == Int64
+	case float64:
+		return columnType == Double
+	case bool:
+		return columnType == Boolean
+	case time.Time:
+		return columnType == Timestamp
+	}
+	return false
+}
+
+// ================================================================
+// СТРАНИЦЫ ДАННЫХ
+// ================================================================
+
+// append - значение в кодировке PLAIN
+func (b *columnBuffer) append(value interface{}) {
+	b.defined = append(b.defined, value != nil)
+	switch value := value.(type) {
+	case string:
+		b.values = binary.LittleEndian.AppendUint32(b.values, uint32(len(value)))
+		b.values = append(b.values, value...)
+	case int64:
+		b.values = binary.LittleEndian.AppendUint64(b.values, uint64(value))
+	case float64:
+		b.values = binary.LittleEndian.AppendUint64(b.values, math.Float64bits(value))
+	case bool:
+		b.bools = append(b.bools, value)
+	case time.Time:
+		b.values = binary.LittleEndian.AppendUint64(b.values, uint64(value.UnixMilli()))
+	}
+}
+
+// page - заголовок и тело страницы данных (DATA_PAGE v1)
+// Тело: уровни определения (RLE/bit-packing, с длиной) и не-null значения
+// Уровней повторения нет: схема плоская
+func (b *columnBuffer) page(columnType Type) (header []byte, body []byte) {
+	levels := bitPackedRun(b.defined)
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(levels)))
+	body = append(body, levels...)
+	if columnType == Boolean {
+		body = append(body, packBits(b.bools)...)
+	} else {
+		body = append(body, b.values...)
+	}
+
+	// PageHeader
+	w := newCompactWriter()
+	w.i32(1, thriftPageData)
+	w.i32(2, int32(len(body))) // uncompressed_page_size
+	w.i32(3, int32(len(body))) // compressed_page_size
+	w.structField(5)           // data_page_header
+	w.i32(1, int32(len(b.defined)))
+	w.i32(2, thriftEncodingPlain)
+	w.i32(3, thriftEncodingRLE) // definition_level_encoding
+	w.i32(4, thriftEncodingRLE) // repetition_level_encoding
+	w.structEnd()
+	w.structEnd()
+	return w.buf, body
+}
+
+// bitPackedRun - уровни ширины 1 бит одним bit-packed отрезком гибридной
+// кодировки RLE: заголовок (число групп по 8 значений << 1 | 1) и биты
+func bitPackedRun(values []bool) []byte {
+	groups := (len(values) + 7) / 8
+	run := binary.AppendUvarint(nil, uint64(groups)<<1|1)
+	return append(run, packBits(values)...)
+}
+
+// packBits - по биту на значение, начиная с младшего
+func packBits(values []bool) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, value := range values {
+		if value {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+// ================================================================
+// МЕТАДАННЫЕ ФАЙЛА
+// ================================================================
+
+// footer - FileMetaData
+func (w *Writer) footer() []byte {
+	m := newCompactWriter()
+	m.i32(1, 1) // version
+
+	// schema: корень и колонки
+	m.list(2, compactStruct, len(w.columns)+1)
+	m.structBegin()
+	m.binary(4, "schema")
+	m.i32(5, int32(len(w.columns))) // num_children
+	m.structEnd()
+	for _, column := range w.columns {
+		m.structBegin()
+		m.i32(1, physicalType(column.Type))
+		m.i32(3, thriftRepetitionOptional)
+		m.binary(4, column.Name)
+		switch column.Type {
+		case String:
+			m.i32(6, thriftConvertedUTF8)
+		case Timestamp:
+			m.i32(6, thriftConvertedTimestampMillis)
+		}
+		m.structEnd()
+	}
+
+	m.i64(3, w.numRows)
+
+	// row_groups
+	m.list(4, compactStruct, len(w.rowGroups))
+	for _, group := range w.rowGroups {
+		m.structBegin()
+		m.list(1, compactStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			column := w.columns[i]
+			m.structBegin()
+			m.i64(2, chunk.pageOffset) // file_offset
+			m.structField(3)           // meta_data
+			m.i32(1, physicalType(column.Type))
+			m.list(2, compactI32, 2)
+			m.listI32(thriftEncodingPlain)
+			m.listI32(thriftEncodingRLE)
+			m.list(3, compactBinary, 1)
+			m.listBinary(column.Name)
+			m.i32(4, thriftCodecUncompressed)
+			m.i64(5, chunk.numValues)
+			m.i64(6, chunk.size) // total_uncompressed_size
+			m.i64(7, chunk.size) // total_compressed_size
+			m.i64(9, chunk.pageOffset)
+			m.structEnd()
+			m.structEnd()
+		}
+		m.i64(2, group.size) // total_byte_size
+		m.i64(3, group.numRows)
+		m.i64(5, group.offset) // file_offset
+		m.i64(6, group.size)   // total_compressed_size
+		m.structEnd()
+	}
+
+	m.structEnd()
+	return m.buf
+}
+
+// physicalType - тип хранения колонки
+func physicalType(columnType Type) int32 {
+	switch columnType {
+	case Int64, Timestamp:
+		return thriftTypeInt64
+	case Double:
+		return thriftTypeDouble
+	case Boolean:
+		return thriftTypeBoolean
+	}
+	return thriftTypeByteArray
+}
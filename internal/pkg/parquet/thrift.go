@@ -0,0 +1,106 @@
+package parquet
+
+import "encoding/binary"
+
+// ================================================================
+// THRIFT COMPACT PROTOCOL - Кодирование метаданных файла
+// ================================================================
+// Метаданные Parquet (FileMetaData, PageHeader) - структуры Thrift,
+// записанные компактным протоколом:
+// https://github.com/apache/thrift/blob/master/doc/specs/thrift-compact-protocol.md
+// Нужна только запись и только типы, которые встречаются в метаданных
+
+// Типы полей компактного протокола
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// compactWriter - буфер с закодированной структурой
+// lastID - ID последнего поля каждой открытой структуры (заголовок поля
+// хранит разницу с ним)
+type compactWriter struct {
+	buf    []byte
+	lastID []int16
+}
+
+// newCompactWriter - запись структуры верхнего уровня (закрывается structEnd)
+func newCompactWriter() *compactWriter {
+	return &compactWriter{lastID: []int16{0}}
+}
+
+// field - заголовок поля: разница ID (1..15) и тип в одном байте, иначе тип и ID отдельно
+func (w *compactWriter) field(id int16, fieldType byte) {
+	last := w.lastID[len(w.lastID)-1]
+	if delta := id - last; delta > 0 && delta <= 15 {
+		w.buf = append(w.buf, byte(delta)<<4|fieldType)
+	} else {
+		w.buf = append(w.buf, fieldType)
+		w.buf = binary.AppendUvarint(w.buf, uint64(uint16((id<<1)^(id>>15))))
+	}
+	w.lastID[len(w.lastID)-1] = id
+}
+
+// i32 - поле int32 (zigzag varint)
+func (w *compactWriter) i32(id int16, value int32) {
+	w.field(id, compactI32)
+	w.buf = binary.AppendUvarint(w.buf, uint64(uint32((value<<1)^(value>>31))))
+}
+
+// i64 - поле int64 (zigzag varint)
+func (w *compactWriter) i64(id int16, value int64) {
+	w.field(id, compactI64)
+	w.buf = binary.AppendUvarint(w.buf, uint64((value<<1)^(value>>63)))
+}
+
+// binary - поле string/binary: длина и байты
+func (w *compactWriter) binary(id int16, value string) {
+	w.field(id, compactBinary)
+	w.appendBinary(value)
+}
+
+// structField - начало вложенной структуры (закрывается structEnd)
+func (w *compactWriter) structField(id int16) {
+	w.field(id, compactStruct)
+	w.structBegin()
+}
+
+// list - заголовок поля-списка; элементы пишутся следом (listI32, listBinary, structBegin)
+func (w *compactWriter) list(id int16, elemType byte, size int) {
+	w.field(id, compactList)
+	if size < 15 {
+		w.buf = append(w.buf, byte(size)<<4|elemType)
+		return
+	}
+	w.buf = append(w.buf, 0xF0|elemType)
+	w.buf = binary.AppendUvarint(w.buf, uint64(size))
+}
+
+// listI32 - элемент списка int32 (значения enum)
+func (w *compactWriter) listI32(value int32) {
+	w.buf = binary.AppendUvarint(w.buf, uint64(uint32((value<<1)^(value>>31))))
+}
+
+// listBinary - элемент списка строк
+func (w *compactWriter) listBinary(value string) {
+	w.appendBinary(value)
+}
+
+// structBegin - структура без заголовка поля (элемент списка)
+func (w *compactWriter) structBegin() {
+	w.lastID = append(w.lastID, 0)
+}
+
+// structEnd - конец структуры (поле STOP)
+func (w *compactWriter) structEnd() {
+	w.buf = append(w.buf, 0)
+	w.lastID = w.lastID[:len(w.lastID)-1]
+}
+
+func (w *compactWriter) appendBinary(value string) {
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(value)))
+	w.buf = append(w.buf, value...)
+}
@@ -0,0 +1,152 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ================================================================
+// BREACHED PASSWORDS - Проверка по базе утёкших паролей (офлайн)
+// ================================================================
+// Формат совместим с "Pwned Passwords" (haveibeenpwned.com):
+// пароли хранятся как SHA-1, и поиск идёт по модели k-anonymity -
+// по первым 5 символам хеша (prefix) выбирается "корзина" суффиксов
+//
+// Поддерживаются два варианта хранения:
+//
+// 1. Один файл - строки "SHA1:COUNT" (или просто "SHA1")
+//    Весь файл загружается в память, сгруппированный по prefix
+//    Подходит для курируемого списка (топ-миллион паролей)
+//
+// 2. Каталог range-файлов "<PREFIX>.txt" со строками "SUFFIX:COUNT"
+//    (так их сохраняет официальный PwnedPasswordsDownloader)
+//    В память ничего не грузится: при проверке читается одна корзина
+
+// prefixLength - длина prefix для k-anonymity (как в API HIBP)
+const prefixLength = 5
+
+// BreachChecker - источник сведений об утечках
+type BreachChecker interface {
+	// Count - сколько раз пароль встречался в утечках (0 - не встречался)
+	Count(password string) (int, error)
+}
+
+// BreachedCorpus - локальная база SHA-1 хешей утёкших паролей
+type BreachedCorpus struct {
+	dir     string                    // Каталог range-файлов (вариант 2)
+	buckets map[string]map[string]int // prefix → suffix → count (вариант 1)
+}
+
+// LoadBreachedCorpus загружает базу из файла или каталога range-файлов
+func LoadBreachedCorpus(path string) (*BreachedCorpus, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("база утёкших паролей: %w", err)
+	}
+
+	// Каталог - читаем корзины лениво
+	if info.IsDir() {
+		return &BreachedCorpus{dir: path}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("база утёкших паролей: %w", err)
+	}
+	defer file.Close()
+
+	corpus := &BreachedCorpus{buckets: make(map[string]map[string]int)}
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		hash, count, err := parseCorpusLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("база утёкших паролей, строка %d: %w", line, err)
+		}
+		if hash == "" {
+			continue
+		}
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("база утёкших паролей, строка %d: ожидается SHA-1", line)
+		}
+
+		prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+		if corpus.buckets[prefix] == nil {
+			corpus.buckets[prefix] = make(map[string]int)
+		}
+		corpus.buckets[prefix][suffix] += count
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("база утёкших паролей: %w", err)
+	}
+
+	return corpus, nil
+}
+
+// Count - сколько раз пароль встречался в утечках
+func (c *BreachedCorpus) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	if c.dir == "" {
+		return c.buckets[prefix][suffix], nil
+	}
+
+	// Range-файл: только корзина с нашим prefix
+	file, err := os.Open(filepath.Join(c.dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		candidate, count, err := parseCorpusLine(scanner.Text())
+		if err != nil {
+			return 0, err
+		}
+		if candidate == suffix {
+			return count, nil
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// parseCorpusLine - разбирает "HASH:COUNT", "HASH" или пустую строку
+// Хеш приводится к верхнему регистру, COUNT по умолчанию 1
+func parseCorpusLine(line string) (string, int, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", 0, nil
+	}
+
+	hash, rawCount, hasCount := strings.Cut(line, ":")
+	count := 1
+	if hasCount {
+		var err error
+		if count, err = strconv.Atoi(strings.TrimSpace(rawCount)); err != nil {
+			return "", 0, fmt.Errorf("неверный счётчик %q", rawCount)
+		}
+	}
+
+	hash = strings.ToUpper(strings.TrimSpace(hash))
+	// Суффикс в range-файле - 35 символов, поэтому hex.DecodeString не подходит
+	if hash == "" || strings.Trim(hash, "0123456789ABCDEF") != "" {
+		return "", 0, fmt.Errorf("неверный хеш %q", hash)
+	}
+
+	return hash, count, nil
+}
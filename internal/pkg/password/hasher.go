@@ -0,0 +1,405 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/crypto/argon2" // Argon2id - победитель Password Hashing Competition
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ================================================================
+// HASHER - Настраиваемое хеширование паролей (argon2id / bcrypt)
+// ================================================================
+
+// Поддерживаемые алгоритмы
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// Форматы хранимых хешей (PHC string format):
+//
+//   $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>       - argon2id
+//   $argon2id$v=19$m=65536,t=3,p=2,k=1$<salt>$<hash>   - argon2id + pepper
+//   $2a$10$<salt+hash>                                   - bcrypt (как раньше)
+//   $bcrypt-hmac$2a$10$<salt+hash>                       - bcrypt + pepper
+//
+// Параметры хранятся вместе с хешем, поэтому Verify работает
+// со старыми хешами даже после смены настроек
+const (
+	argon2Prefix     = "$argon2id$"
+	bcryptHMACPrefix = "$bcrypt-hmac"
+)
+
+var (
+	// ErrUnknownFormat - хеш в неизвестном формате
+	ErrUnknownFormat = errors.New("неизвестный формат хеша пароля")
+
+	// ErrCostTooHigh - параметры argon2id хеша выше допустимых (см. maxCostFactor)
+	ErrCostTooHigh = errors.New("параметры хеша пароля превышают допустимые")
+)
+
+// maxCostFactor - во сколько раз параметры argon2id хеша (m, t, p) могут
+// превышать настроенные (но не меньше значений по умолчанию)
+// Параметры берутся из самого хеша: без ограничения один импортированный
+// хеш с m=4294967295 заставил бы первую же проверку пароля выделить 4 ТиБ
+const maxCostFactor = 4
+
+// Params - настройки хеширования
+type Params struct {
+	// Algorithm - алгоритм для НОВЫХ хешей ("argon2id" или "bcrypt")
+	Algorithm string
+
+	// Argon2id параметры
+	Memory      uint32 // Память в KiB (65536 = 64 MiB)
+	Iterations  uint32 // Количество проходов (t)
+	Parallelism uint8  // Количество потоков (p)
+	SaltLength  uint32 // Длина соли в байтах
+	KeyLength   uint32 // Длина хеша в байтах
+
+	// BcryptCost - сложность bcrypt (4..31)
+	BcryptCost int
+
+	// Pepper - секрет сервера, подмешиваемый через HMAC-SHA256
+	// Хранится в конфигурации, НЕ в БД: утечка одной только БД
+	// не позволяет перебирать пароли
+	Pepper []byte
+}
+
+// DefaultParams - рекомендованные значения (OWASP)
+func DefaultParams() Params {
+	return Params{
+		Algorithm:   AlgorithmArgon2id,
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+		BcryptCost:  bcrypt.DefaultCost,
+	}
+}
+
+// Hasher - хеширует и проверяет пароли
+type Hasher struct {
+	params Params
+}
+
+// NewHasher - конструктор
+// Незаполненные параметры заменяются значениями по умолчанию
+func NewHasher(params Params) *Hasher {
+	defaults := DefaultParams()
+
+	if params.Algorithm == "" {
+		params.Algorithm = defaults.Algorithm
+	}
+	if params.Memory == 0 {
+		params.Memory = defaults.Memory
+	}
+	if params.Iterations == 0 {
+		params.Iterations = defaults.Iterations
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = defaults.Parallelism
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = defaults.SaltLength
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = defaults.KeyLength
+	}
+	if params.BcryptCost == 0 {
+		params.BcryptCost = defaults.BcryptCost
+	}
+
+	return &Hasher{params: params}
+}
+
+// ================================================================
+// HASH
+// ================================================================
+
+// Hash хеширует пароль текущим алгоритмом
+// Возвращает строку в формате PHC (см. описание форматов выше)
+func (h *Hasher) Hash(password string) (string, error) {
+	switch h.params.Algorithm {
+	case AlgorithmArgon2id:
+		return h.hashArgon2id(password)
+	case AlgorithmBcrypt:
+		return h.hashBcrypt(password)
+	default:
+		return "", fmt.Errorf("неизвестный алгоритм хеширования: %s", h.params.Algorithm)
+	}
+}
+
+func (h *Hasher) hashArgon2id(password string) (string, error) {
+	// Случайная соль - одинаковые пароли дают разные хеши
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey(h.pepper(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", h.params.Memory, h.params.Iterations, h.params.Parallelism)
+	if h.hasPepper() {
+		params += ",k=1"
+	}
+
+	return fmt.Sprintf("%sv=%d$%s$%s$%s",
+		argon2Prefix,
+		argon2.Version,
+		params,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Hasher) hashBcrypt(password string) (string, error) {
+	if !h.hasPepper() {
+		// Без pepper - классический bcrypt
+		// ВАЖНО: bcrypt учитывает только первые 72 байта,
+		// x/crypto возвращает ошибку для более длинных паролей
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.params.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	}
+
+	// С pepper - bcrypt от HMAC (44 символа base64), ограничение 72 байта не действует
+	hashed, err := bcrypt.GenerateFromPassword(h.pepper(password), h.params.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return bcryptHMACPrefix + string(hashed), nil
+}
+
+// ================================================================
+// VERIFY
+// ================================================================
+
+// Verify проверяет пароль, выбирая алгоритм по формату хеша
+// Работает с хешами, созданными ЛЮБЫМИ прежними настройками
+func (h *Hasher) Verify(encoded, password string) bool {
+	switch {
+	case strings.HasPrefix(encoded, argon2Prefix):
+		return h.verifyArgon2id(encoded, password)
+
+	case strings.HasPrefix(encoded, bcryptHMACPrefix):
+		if !h.hasPepper() {
+			return false // Pepper удалён из конфигурации - проверить невозможно
+		}
+		mcf := strings.TrimPrefix(encoded, bcryptHMACPrefix)
+		return bcrypt.CompareHashAndPassword([]byte(mcf), h.pepper(password)) == nil
+
+	case strings.HasPrefix(encoded, "$2"):
+		// $2a$, $2b$, $2y$ - обычный bcrypt
+		return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+
+	default:
+		return false
+	}
+}
+
+func (h *Hasher) verifyArgon2id(encoded, password string) bool {
+	hash, err := parseArgon2id(encoded)
+	if err != nil || !h.withinLimits(hash) {
+		return false
+	}
+
+	input := []byte(password)
+	if hash.peppered {
+		if !h.hasPepper() {
+			return false
+		}
+		input = h.pepper(password)
+	}
+
+	key := argon2.IDKey(input, hash.salt, hash.iterations, hash.memory, hash.parallelism, uint32(len(hash.key)))
+
+	// ConstantTimeCompare - защита от timing атак
+	return subtle.ConstantTimeCompare(key, hash.key) == 1
+}
+
+// ================================================================
+// NEEDS REHASH
+// ================================================================
+
+// NeedsRehash сообщает, что хеш создан устаревшими настройками
+// Вызывается после УСПЕШНОЙ проверки пароля: только тогда у нас есть
+// пароль в открытом виде, чтобы пересчитать хеш
+//
+// Хеш устарел, если:
+// - алгоритм отличается от текущего
+// - параметры (memory, iterations, parallelism, cost) отличаются
+// - pepper настроен, а хеш создан без него
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	switch {
+	case strings.HasPrefix(encoded, argon2Prefix):
+		if h.params.Algorithm != AlgorithmArgon2id {
+			return true
+		}
+		hash, err := parseArgon2id(encoded)
+		if err != nil {
+			return true
+		}
+		return hash.memory != h.params.Memory ||
+			hash.iterations != h.params.Iterations ||
+			hash.parallelism != h.params.Parallelism ||
+			uint32(len(hash.key)) != h.params.KeyLength ||
+			hash.peppered != h.hasPepper()
+
+	case strings.HasPrefix(encoded, bcryptHMACPrefix), strings.HasPrefix(encoded, "$2"):
+		if h.params.Algorithm != AlgorithmBcrypt {
+			return true
+		}
+		peppered := strings.HasPrefix(encoded, bcryptHMACPrefix)
+		cost, err := bcrypt.Cost([]byte(strings.TrimPrefix(encoded, bcryptHMACPrefix)))
+		if err != nil {
+			return true
+		}
+		return cost != h.params.BcryptCost || peppered != h.hasPepper()
+
+	default:
+		return true
+	}
+}
+
+// ================================================================
+// CHECK FORMAT
+// ================================================================
+
+// CheckFormat проверяет готовый хеш, перенесённый из другой системы
+// (импорт пользователей): Verify должен уметь его проверить
+// Хеш с pepper (k=1, $bcrypt-hmac) допустим, только если pepper настроен -
+// его нельзя проверить без того же секрета
+// argon2id с параметрами выше допустимых - ErrCostTooHigh: Verify его отклонит
+func (h *Hasher) CheckFormat(encoded string) error {
+	switch {
+	case strings.HasPrefix(encoded, argon2Prefix):
+		hash, err := parseArgon2id(encoded)
+		if err != nil {
+			return err
+		}
+		if hash.peppered && !h.hasPepper() {
+			return ErrUnknownFormat
+		}
+		if !h.withinLimits(hash) {
+			return ErrCostTooHigh
+		}
+		return nil
+
+	case strings.HasPrefix(encoded, bcryptHMACPrefix), strings.HasPrefix(encoded, "$2"):
+		if strings.HasPrefix(encoded, bcryptHMACPrefix) && !h.hasPepper() {
+			return ErrUnknownFormat
+		}
+		if _, err := bcrypt.Cost([]byte(strings.TrimPrefix(encoded, bcryptHMACPrefix))); err != nil {
+			return ErrUnknownFormat
+		}
+		return nil
+
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+func (h *Hasher) hasPepper() bool {
+	return len(h.params.Pepper) > 0
+}
+
+// pepper - HMAC-SHA256(pepper, password) в base64
+// Без pepper возвращает пароль как есть
+func (h *Hasher) pepper(password string) []byte {
+	if !h.hasPepper() {
+		return []byte(password)
+	}
+	mac := hmac.New(sha256.New, h.params.Pepper)
+	mac.Write([]byte(password))
+	return []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// withinLimits - параметры хеша не выше настроенных (или по умолчанию) × maxCostFactor
+func (h *Hasher) withinLimits(hash *argon2Hash) bool {
+	defaults := DefaultParams()
+	limit := func(configured, fallback uint32) uint64 {
+		return uint64(max(configured, fallback)) * maxCostFactor
+	}
+	return uint64(hash.memory) <= limit(h.params.Memory, defaults.Memory) &&
+		uint64(hash.iterations) <= limit(h.params.Iterations, defaults.Iterations) &&
+		uint64(hash.parallelism) <= limit(uint32(h.params.Parallelism), uint32(defaults.Parallelism))
+}
+
+// argon2Hash - разобранная строка $argon2id$...
+type argon2Hash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	peppered    bool
+	salt        []byte
+	key         []byte
+}
+
+// parseArgon2id - разбирает "$argon2id$v=19$m=..,t=..,p=..[,k=1]$salt$hash"
+func parseArgon2id(encoded string) (*argon2Hash, error) {
+	parts := strings.Split(encoded, "$")
+	// ["", "argon2id", "v=19", "m=..,t=..,p=..", salt, hash]
+	if len(parts) != 6 {
+		return nil, ErrUnknownFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrUnknownFormat
+	}
+
+	hash := &argon2Hash{}
+	for _, kv := range strings.Split(parts[3], ",") {
+		var value uint64
+		name, raw, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, ErrUnknownFormat
+		}
+		if _, err := fmt.Sscanf(raw, "%d", &value); err != nil {
+			return nil, ErrUnknownFormat
+		}
+		// Значение больше типа параметра не обрезается молча
+		if (name == "p" && value > math.MaxUint8) || value > math.MaxUint32 {
+			return nil, ErrUnknownFormat
+		}
+		switch name {
+		case "m":
+			hash.memory = uint32(value)
+		case "t":
+			hash.iterations = uint32(value)
+		case "p":
+			hash.parallelism = uint8(value)
+		case "k":
+			hash.peppered = value == 1
+		}
+	}
+	if hash.memory == 0 || hash.iterations == 0 || hash.parallelism == 0 {
+		return nil, ErrUnknownFormat
+	}
+
+	var err error
+	if hash.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnknownFormat
+	}
+	if hash.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(hash.key) == 0 {
+		return nil, ErrUnknownFormat
+	}
+
+	return hash, nil
+}
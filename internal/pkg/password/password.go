@@ -9,6 +9,7 @@ import (
 // ================================================================
 
 // Hash хеширует пароль с помощью bcrypt
+// Для argon2id, pepper и настраиваемой сложности используйте Hasher (hasher.go)
 // Параметры:
 //   - password: пароль в открытом виде (например, "secret123")
 // Возвращает:
@@ -49,19 +50,17 @@ func Hash(password string) (string, error) {
 //       // Пароль неправильный - отклоняем вход
 //   }
 func Verify(hashedPassword, password string) bool {
-	// bcrypt.CompareHashAndPassword() - сравнивает хеш и пароль
-	// Параметры:
-	//   - []byte(hashedPassword): хеш из БД
-	//   - []byte(password): пароль для проверки
-	// Возвращает:
-	//   - nil: если пароль правильный
-	//   - error: если пароль неправильный
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-	
-	// Если err == nil, значит пароль правильный
-	return err == nil
+	// Формат хеша определяет алгоритм:
+	//   - "$2a$..." - bcrypt.CompareHashAndPassword()
+	//   - "$argon2id$..." - argon2.IDKey() с параметрами из хеша
+	// Хеши с pepper здесь НЕ проверяются - для них нужен Hasher с pepper
+	return plainHasher.Verify(hashedPassword, password)
 }
 
+// plainHasher - Hasher без pepper для функций Hash/Verify уровня пакета
+// Алгоритм bcrypt, чтобы Hash() вёл себя как раньше
+var plainHasher = NewHasher(Params{Algorithm: AlgorithmBcrypt})
+
 // ================================================================
 // ПРИМЕРЫ ИСПОЛЬЗОВАНИЯ
 // ================================================================
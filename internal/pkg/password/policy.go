@@ -0,0 +1,214 @@
+package password
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ================================================================
+// PASSWORD POLICY - Правила для новых паролей
+// ================================================================
+// Применяется везде, где пользователь задаёт пароль:
+// регистрация, смена пароля, сброс пароля
+
+// Коды нарушений - стабильные строки для клиента
+// (клиент показывает свой текст по коду, message - для отладки)
+const (
+	ViolationTooShort     = "too_short"
+	ViolationTooLong      = "too_long"
+	ViolationNoLowercase  = "missing_lowercase"
+	ViolationNoUppercase  = "missing_uppercase"
+	ViolationNoDigit      = "missing_digit"
+	ViolationNoSymbol     = "missing_symbol"
+	ViolationPersonalInfo = "contains_personal_info"
+	ViolationBannedWord   = "contains_banned_word"
+	ViolationTooWeak      = "too_weak"
+	ViolationBreached     = "breached"
+)
+
+// Violation - одно нарушение политики
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PolicyError - пароль не прошёл проверку
+// Содержит ВСЕ нарушения сразу, чтобы клиент мог показать их списком
+type PolicyError struct {
+	Violations []Violation `json:"reasons"`
+	Strength   Strength    `json:"strength"`
+}
+
+// Error - реализация интерфейса error
+func (e *PolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return "пароль не соответствует политике: " + strings.Join(messages, "; ")
+}
+
+// PolicyConfig - настройки политики
+type PolicyConfig struct {
+	MinLength      int      // Минимальная длина (в символах)
+	MaxLength      int      // Максимальная длина (защита от DoS через argon2/bcrypt)
+	RequireLower   bool     // Нужна строчная буква
+	RequireUpper   bool     // Нужна заглавная буква
+	RequireDigit   bool     // Нужна цифра
+	RequireSymbol  bool     // Нужен спецсимвол
+	MinScore       int      // Минимальная оценка стойкости 0..4 (0 - не проверять)
+	BannedWords    []string // Запрещённые слова (название компании, продукта)
+	BreachMinCount int      // Сколько утечек достаточно для запрета (0 → 1)
+}
+
+// PolicyInput - сведения о пользователе, которые нельзя использовать в пароле
+type PolicyInput struct {
+	Email string
+	Name  string
+}
+
+// Policy - движок проверки паролей
+type Policy struct {
+	cfg      PolicyConfig
+	breached BreachChecker // nil - проверка утечек выключена
+}
+
+// NewPolicy - конструктор
+// breached может быть nil, если база утечек не настроена
+func NewPolicy(cfg PolicyConfig, breached BreachChecker) *Policy {
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 8
+	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = 128
+	}
+	if cfg.BreachMinCount <= 0 {
+		cfg.BreachMinCount = 1
+	}
+	return &Policy{cfg: cfg, breached: breached}
+}
+
+// ================================================================
+// CHECK
+// ================================================================
+
+// Check проверяет пароль и возвращает *PolicyError со всеми нарушениями
+// Возвращает nil, если пароль подходит
+// Ошибка чтения базы утечек не блокирует пользователя (fail-open), а логируется
+func (p *Policy) Check(password string, input PolicyInput) error {
+	var violations []Violation
+	add := func(code, format string, args ...interface{}) {
+		violations = append(violations, Violation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// === ШАГ 1: ДЛИНА ===
+	length := utf8.RuneCountInString(password)
+	if length < p.cfg.MinLength {
+		add(ViolationTooShort, "минимальная длина пароля - %d символов", p.cfg.MinLength)
+	}
+	if length > p.cfg.MaxLength {
+		add(ViolationTooLong, "максимальная длина пароля - %d символов", p.cfg.MaxLength)
+	}
+
+	// === ШАГ 2: КЛАССЫ СИМВОЛОВ ===
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.cfg.RequireLower && !lower {
+		add(ViolationNoLowercase, "пароль должен содержать строчную букву")
+	}
+	if p.cfg.RequireUpper && !upper {
+		add(ViolationNoUppercase, "пароль должен содержать заглавную букву")
+	}
+	if p.cfg.RequireDigit && !digit {
+		add(ViolationNoDigit, "пароль должен содержать цифру")
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		add(ViolationNoSymbol, "пароль должен содержать спецсимвол")
+	}
+
+	// === ШАГ 3: ЛИЧНЫЕ ДАННЫЕ И ЗАПРЕЩЁННЫЕ СЛОВА ===
+	// Сравниваем без учёта регистра и leet-замен (p@ssw0rd = password)
+	normalized := unleet(strings.ToLower(password))
+	personal := personalWords(input)
+	for _, word := range personal {
+		if strings.Contains(normalized, unleet(word)) {
+			add(ViolationPersonalInfo, "пароль не должен содержать email или имя")
+			break
+		}
+	}
+	for _, word := range p.cfg.BannedWords {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" && strings.Contains(normalized, unleet(word)) {
+			add(ViolationBannedWord, "пароль содержит запрещённое слово")
+			break
+		}
+	}
+
+	// === ШАГ 4: СТОЙКОСТЬ ===
+	strength := EstimateStrength(password, append(personal, p.cfg.BannedWords...)...)
+	if p.cfg.MinScore > 0 && strength.Score < p.cfg.MinScore {
+		add(ViolationTooWeak, "пароль слишком простой (оценка %d из 4, нужно минимум %d)", strength.Score, p.cfg.MinScore)
+	}
+
+	// === ШАГ 5: УТЕЧКИ ===
+	if p.breached != nil {
+		count, err := p.breached.Count(password)
+		if err != nil {
+			log.Printf("⚠️  Проверка по базе утёкших паролей: %v", err)
+		} else if count >= p.cfg.BreachMinCount {
+			add(ViolationBreached, "пароль встречается в утечках данных (%d раз)", count)
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations, Strength: strength}
+	}
+	return nil
+}
+
+// personalWords - части email и имени длиной от 3 символов
+// "john.smith@acme.com" + "John Smith" → john.smith, john, smith, acme
+func personalWords(input PolicyInput) []string {
+	var words []string
+	seen := map[string]bool{}
+
+	addWord := func(w string) {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if utf8.RuneCountInString(w) >= 3 && !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+
+	local, domain, _ := strings.Cut(input.Email, "@")
+	addWord(local)
+	for _, part := range strings.FieldsFunc(local, isSeparator) {
+		addWord(part)
+	}
+	if host, _, ok := strings.Cut(domain, "."); ok {
+		addWord(host)
+	}
+	for _, part := range strings.FieldsFunc(input.Name, isSeparator) {
+		addWord(part)
+	}
+
+	return words
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
@@ -0,0 +1,232 @@
+package password
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ================================================================
+// STRENGTH - Оценка стойкости пароля (в духе zxcvbn)
+// ================================================================
+// Упрощённая модель атакующего: пароль "стоит" столько бит, сколько
+// нужно угадать, если перебирать сначала словарь, повторы,
+// последовательности и соседние клавиши, и только потом - случайные символы
+//
+// Шкала, как у zxcvbn (log10 количества попыток):
+//   0 - < 10^3   (мгновенно: "password", "123456")
+//   1 - < 10^6   (онлайн-перебор без ограничений)
+//   2 - < 10^8   (онлайн-перебор с ограничениями)
+//   3 - < 10^10  (офлайн-перебор медленного хеша)
+//   4 - ≥ 10^10  (стойкий пароль)
+
+// Strength - результат оценки
+type Strength struct {
+	Score   int     `json:"score"`   // 0..4
+	Guesses float64 `json:"guesses"` // log10 от числа попыток
+}
+
+// commonWords - самые частые пароли и их основы
+// Совпадение засчитывается как одно "слово" словаря (~6 бит)
+var commonWords = []string{
+	"password", "passw0rd", "qwerty", "azerty", "letmein", "welcome", "admin",
+	"login", "master", "monkey", "dragon", "football", "baseball", "iloveyou",
+	"sunshine", "princess", "shadow", "superman", "batman", "trustno1",
+	"starwars", "whatever", "freedom", "secret", "hello", "charlie", "michael",
+	"jordan", "hunter", "ranger", "summer", "winter", "spring", "autumn",
+	"abc123", "changeme", "default", "pokemon", "killer", "soccer", "hockey",
+	"matrix", "cookie", "flower", "orange", "banana", "computer", "internet",
+	"parol", "privet", "qazwsx", "zaq1", "user", "test", "guest", "root",
+}
+
+// keyboardRows - ряды клавиатуры для поиска "соседних" клавиш (qwerty, asdf)
+var keyboardRows = []string{
+	"1234567890",
+	"qwertyuiop",
+	"asdfghjkl",
+	"zxcvbnm",
+}
+
+// EstimateStrength оценивает стойкость пароля
+// Дополнительные слова (email, имя пользователя) считаются частью словаря
+func EstimateStrength(password string, userWords ...string) Strength {
+	if password == "" {
+		return Strength{Score: 0, Guesses: 0}
+	}
+
+	original := []rune(password)
+	lowered := []rune(strings.ToLower(password))
+	normalized := []rune(unleet(string(lowered))) // Для поиска слов словаря
+	if len(lowered) != len(original) || len(normalized) != len(lowered) {
+		// Экзотический Unicode, у которого меняется длина при ToLower
+		lowered, normalized = original, original
+	}
+	covered := make([]bool, len(normalized))
+
+	bits := 0.0
+
+	// === ШАГ 1: СЛОВАРНЫЕ СЛОВА ===
+	// Каждое найденное слово стоит log2(размер словаря) бит
+	// +1 бит за заглавные буквы внутри слова
+	dictionary := append(append([]string{}, commonWords...), userWords...)
+	wordBits := math.Log2(float64(len(dictionary)))
+
+	// Длинные слова первыми: "password" важнее, чем "pass" внутри него
+	sort.SliceStable(dictionary, func(i, j int) bool {
+		return len([]rune(dictionary[i])) > len([]rune(dictionary[j]))
+	})
+	for _, word := range dictionary {
+		word = unleet(strings.ToLower(strings.TrimSpace(word)))
+		if len([]rune(word)) < 3 {
+			continue
+		}
+		for _, start := range findAll(normalized, []rune(word)) {
+			if anyCovered(covered, start, len([]rune(word))) {
+				continue
+			}
+			bits += wordBits
+			if hasUpper(original[start : start+len([]rune(word))]) {
+				bits++
+			}
+			markCovered(covered, start, len([]rune(word)))
+		}
+	}
+
+	// === ШАГ 2: ОСТАВШИЕСЯ СИМВОЛЫ ===
+	// Первый символ "серии" стоит log2(размер алфавита)
+	// Серия - повтор (aaaa), последовательность (abcd, 4321)
+	// или соседние клавиши (qwer); вся серия добавляет лишь log2(длины)
+	poolBits := math.Log2(float64(charsetSize(original)))
+	run := 0 // Сколько символов продолжают текущую серию
+	for i, r := range lowered {
+		if covered[i] {
+			continue
+		}
+		if i > 0 && !covered[i-1] {
+			prev := lowered[i-1]
+			if r == prev || r == prev+1 || r == prev-1 || keyboardAdjacent(prev, r) {
+				run++
+				continue
+			}
+		}
+		if run > 0 {
+			bits += math.Log2(float64(run + 1))
+			run = 0
+		}
+		bits += poolBits
+	}
+	if run > 0 {
+		bits += math.Log2(float64(run + 1))
+	}
+
+	// === ШАГ 3: ПЕРЕВОД В ШКАЛУ 0..4 ===
+	guesses := bits * math.Log10(2)
+
+	score := 4
+	switch {
+	case guesses < 3:
+		score = 0
+	case guesses < 6:
+		score = 1
+	case guesses < 8:
+		score = 2
+	case guesses < 10:
+		score = 3
+	}
+
+	return Strength{Score: score, Guesses: math.Round(guesses*100) / 100}
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// unleet - обратная замена "leet" символов: p@ssw0rd → password
+func unleet(s string) string {
+	return strings.NewReplacer(
+		"@", "a", "4", "a", "3", "e", "1", "i", "!", "i",
+		"0", "o", "$", "s", "5", "s", "7", "t", "+", "t",
+	).Replace(s)
+}
+
+// charsetSize - размер алфавита по встречающимся классам символов
+func charsetSize(runes []rune) int {
+	var lower, upper, digit, other bool
+	for _, r := range runes {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0
+	if lower {
+		size += 26
+	}
+	if upper {
+		size += 26
+	}
+	if digit {
+		size += 10
+	}
+	if other {
+		size += 33
+	}
+	if size == 0 {
+		size = 26
+	}
+	return size
+}
+
+// keyboardAdjacent - стоят ли клавиши рядом в одном ряду
+func keyboardAdjacent(a, b rune) bool {
+	for _, row := range keyboardRows {
+		ia := strings.IndexRune(row, a)
+		ib := strings.IndexRune(row, b)
+		if ia >= 0 && ib >= 0 && (ia-ib == 1 || ib-ia == 1) {
+			return true
+		}
+	}
+	return false
+}
+
+func findAll(haystack, needle []rune) []int {
+	var positions []int
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		if string(haystack[i:i+len(needle)]) == string(needle) {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+func anyCovered(covered []bool, start, length int) bool {
+	for i := start; i < start+length; i++ {
+		if covered[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func markCovered(covered []bool, start, length int) {
+	for i := start; i < start+length; i++ {
+		covered[i] = true
+	}
+}
+
+func hasUpper(runes []rune) bool {
+	for _, r := range runes {
+		if unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
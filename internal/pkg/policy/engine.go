@@ -0,0 +1,318 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ================================================================
+// ENGINE - Загрузка правил и принятие решений
+// ================================================================
+// Правила читаются из YAML/JSON файлов (или всех *.yaml, *.yml, *.json
+// в каталоге). Файлы перечитываются при изменении (RunReload): при ошибке
+// в новом файле продолжают действовать старые правила
+
+// Engine - движок правил
+type Engine struct {
+	paths    []string       // Файлы и каталоги с правилами
+	location *time.Location // Часовой пояс для context.hour/weekday/date
+
+	mu          sync.RWMutex
+	policies    []Policy
+	fingerprint string // Имена, размеры и время изменения файлов при последней загрузке
+}
+
+// file - формат файла правил
+type file struct {
+	Policies []Policy `yaml:"policies"`
+}
+
+// NewEngine - загружает правила из paths
+// location - часовой пояс рабочего времени (nil - UTC)
+func NewEngine(paths []string, location *time.Location) (*Engine, error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	e := &Engine{paths: paths, location: location}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Policies - загруженные правила
+func (e *Engine) Policies() []Policy {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]Policy(nil), e.policies...)
+}
+
+// Reload - перечитывает все файлы
+// При ошибке текущие правила не меняются
+func (e *Engine) Reload() error {
+	files, err := e.files()
+	if err != nil {
+		return err
+	}
+
+	policies, err := load(files)
+	if err != nil {
+		return err
+	}
+	fingerprint, err := fingerprintOf(files)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.policies = policies
+	e.fingerprint = fingerprint
+	e.mu.Unlock()
+	return nil
+}
+
+// RunReload - перечитывает правила при изменении файлов, пока не отменён ctx
+// Опрос раз в interval: без inotify, работает и с файлами из ConfigMap/volume
+func (e *Engine) RunReload(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := e.changed()
+		if err != nil {
+			log.Printf("⚠️  Правила доступа: %v", err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		if err := e.Reload(); err != nil {
+			log.Printf("⚠️  Правила доступа не перезагружены, действуют прежние: %v", err)
+			continue
+		}
+		log.Printf("🛡️  Правила доступа перезагружены: %d", len(e.Policies()))
+	}
+}
+
+// ================================================================
+// РЕШЕНИЯ
+// ================================================================
+
+// Evaluate - решение по запросу (deny важнее allow, без подходящих правил - запрет)
+func (e *Engine) Evaluate(req Request) Decision {
+	return e.decide(req, false)
+}
+
+// Explain - решение вместе с оценкой каждого правила
+func (e *Engine) Explain(req Request) Decision {
+	return e.decide(req, true)
+}
+
+// decide - оценивает правила, подходящие к action и типу ресурса
+func (e *Engine) decide(req Request, explain bool) Decision {
+	input := e.input(req)
+
+	e.mu.RLock()
+	policies := e.policies
+	e.mu.RUnlock()
+
+	var allow, deny *Policy
+	var trace []PolicyTrace
+	for i := range policies {
+		p := &policies[i]
+		if !p.appliesTo(req.Action, req.ResourceType) {
+			continue
+		}
+
+		matched, reason := p.evaluate(input)
+		if explain {
+			trace = append(trace, PolicyTrace{PolicyID: p.ID, Effect: p.Effect, Matched: matched, Reason: reason})
+		}
+		if !matched {
+			continue
+		}
+
+		if p.Effect == EffectDeny && deny == nil {
+			deny = p
+			if !explain {
+				break // deny окончательно, остальные правила можно не смотреть
+			}
+		}
+		if p.Effect == EffectAllow && allow == nil {
+			allow = p
+		}
+	}
+
+	decision := Decision{Effect: EffectNotApplicable, Reason: "нет подходящего разрешающего правила", Trace: trace}
+	switch {
+	case deny != nil:
+		decision.Effect = EffectDeny
+		decision.PolicyID = deny.ID
+		decision.Reason = "запрещено правилом " + deny.ID
+	case allow != nil:
+		decision.Allowed = true
+		decision.Effect = EffectAllow
+		decision.PolicyID = allow.ID
+		decision.Reason = "разрешено правилом " + allow.ID
+	}
+	return decision
+}
+
+// input - атрибуты для условий: subject, resource, context (+ время запроса)
+func (e *Engine) input(req Request) Attributes {
+	at := req.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	at = at.In(e.location)
+
+	ctx := Attributes{}
+	for k, v := range req.Context {
+		ctx[k] = v
+	}
+	ctx["action"] = req.Action
+	ctx["hour"] = at.Hour()
+	ctx["weekday"] = strings.ToLower(at.Weekday().String()[:3]) // mon, tue, ...
+	ctx["date"] = at.Format("2006-01-02")
+	ctx["time"] = at.Format("15:04")
+
+	resource := req.Resource
+	if resource == nil {
+		resource = Attributes{}
+	}
+	if req.ResourceType != "" {
+		resource = withType(resource, req.ResourceType)
+	}
+
+	return Attributes{
+		"subject":  req.Subject,
+		"resource": resource,
+		"context":  ctx,
+	}
+}
+
+// withType - копия атрибутов ресурса с полем type
+func withType(resource Attributes, resourceType string) Attributes {
+	copied := make(Attributes, len(resource)+1)
+	for k, v := range resource {
+		copied[k] = v
+	}
+	copied["type"] = resourceType
+	return copied
+}
+
+// ================================================================
+// ЗАГРУЗКА ФАЙЛОВ
+// ================================================================
+
+// files - файлы правил (каталоги раскрываются в *.yaml, *.yml, *.json)
+func (e *Engine) files() ([]string, error) {
+	var files []string
+	for _, path := range e.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("файл правил: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("каталог правил: %w", err)
+		}
+		for _, entry := range entries {
+			switch strings.ToLower(filepath.Ext(entry.Name())) {
+			case ".yaml", ".yml", ".json":
+				if !entry.IsDir() {
+					files = append(files, filepath.Join(path, entry.Name()))
+				}
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// changed - изменились ли файлы с последней загрузки
+func (e *Engine) changed() (bool, error) {
+	files, err := e.files()
+	if err != nil {
+		return false, err
+	}
+	fingerprint, err := fingerprintOf(files)
+	if err != nil {
+		return false, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return fingerprint != e.fingerprint, nil
+}
+
+// fingerprintOf - имена, размеры и время изменения файлов
+func fingerprintOf(files []string) (string, error) {
+	var b strings.Builder
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+// load - читает и проверяет правила из всех файлов
+// JSON - подмножество YAML, поэтому достаточно одного парсера
+func load(files []string) ([]Policy, error) {
+	var policies []Policy
+	seen := map[string]string{}
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var f file
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		for _, p := range f.Policies {
+			if err := p.validate(); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if other, ok := seen[p.ID]; ok {
+				return nil, fmt.Errorf("%s: правило %s уже объявлено в %s", path, p.ID, other)
+			}
+			seen[p.ID] = path
+			p.Source = path
+			policies = append(policies, p)
+		}
+	}
+	return policies, nil
+}
@@ -0,0 +1,373 @@
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ================================================================
+// POLICY - Декларативные правила доступа (ABAC)
+// ================================================================
+// Правило описывает, КТО (subject), ЧТО (action) и НАД ЧЕМ (resource)
+// может делать и при каких условиях (атрибуты субъекта, ресурса и запроса).
+// Пример - поддержка меняет пользователей своей организации в рабочее время:
+//
+//	- id: support-edit-own-organization
+//	  effect: allow
+//	  actions: ["users:update"]
+//	  resources: ["user"]
+//	  conditions:
+//	    - {attribute: subject.roles, operator: contains, value: support}
+//	    - {attribute: resource.organization_id, operator: eq, ref: subject.organization_id}
+//	    - {attribute: context.hour, operator: between, value: [9, 18]}
+
+// Эффекты правил и решений
+const (
+	EffectAllow         = "allow"
+	EffectDeny          = "deny"
+	EffectNotApplicable = "not_applicable" // Ни одно правило не подошло
+)
+
+// Операторы условий
+const (
+	OperatorEq       = "eq"       // Равно
+	OperatorNe       = "ne"       // Не равно
+	OperatorIn       = "in"       // Значение атрибута - один из элементов списка
+	OperatorNotIn    = "not_in"   // Значение атрибута - ни один из элементов списка
+	OperatorContains = "contains" // Атрибут-список содержит значение
+	OperatorGt       = "gt"       // Больше (числа)
+	OperatorGte      = "gte"      // Больше или равно
+	OperatorLt       = "lt"       // Меньше
+	OperatorLte      = "lte"      // Меньше или равно
+	OperatorBetween  = "between"  // [от, до) - до не включается: hour between [9, 18] = 9:00-17:59
+	OperatorExists   = "exists"   // Атрибут задан (value: false - не задан)
+	OperatorMatches  = "matches"  // Строка соответствует регулярному выражению
+)
+
+// Attributes - атрибуты субъекта, ресурса или контекста запроса
+// Значения - строки, числа, bool, списки и вложенные Attributes
+type Attributes map[string]interface{}
+
+// Policy - одно правило
+type Policy struct {
+	// ID - уникальное имя правила (попадает в решение и журнал)
+	ID          string `yaml:"id" json:"id"`
+	Description string `yaml:"description" json:"description,omitempty"`
+
+	// Effect - allow или deny (deny важнее: одно подошедшее deny запрещает действие)
+	Effect string `yaml:"effect" json:"effect"`
+
+	// Actions - действия ("users:update"), "users:*" - все действия users, "*" - любое
+	Actions []string `yaml:"actions" json:"actions"`
+
+	// Resources - типы ресурсов ("user"), пусто или "*" - любой
+	Resources []string `yaml:"resources" json:"resources,omitempty"`
+
+	// Conditions - все условия должны выполниться (И); для ИЛИ - несколько правил
+	Conditions []Condition `yaml:"conditions" json:"conditions,omitempty"`
+
+	// Source - файл, из которого загружено правило
+	Source string `yaml:"-" json:"source"`
+}
+
+// Condition - одно условие правила
+type Condition struct {
+	// Attribute - путь к атрибуту: subject.roles, resource.organization_id, context.hour
+	Attribute string `yaml:"attribute" json:"attribute"`
+	Operator  string `yaml:"operator" json:"operator"`
+
+	// Value - константа для сравнения; Ref - путь к другому атрибуту
+	// (resource.organization_id eq subject.organization_id). Задаётся одно из двух
+	Value interface{} `yaml:"value" json:"value,omitempty"`
+	Ref   string      `yaml:"ref" json:"ref,omitempty"`
+
+	pattern *regexp.Regexp // скомпилированное выражение для matches
+}
+
+// Request - запрос решения
+type Request struct {
+	Subject      Attributes `json:"subject"`
+	Action       string     `json:"action"`
+	ResourceType string     `json:"resource_type"`
+	Resource     Attributes `json:"resource"`
+	Context      Attributes `json:"context"`
+
+	// Time - время запроса (пусто - текущее); из него считаются context.hour/weekday/date
+	Time time.Time `json:"time"`
+}
+
+// Decision - решение движка
+type Decision struct {
+	// Allowed - действие разрешено (в режиме dry-run - всегда true, см. DryRun)
+	Allowed bool `json:"allowed"`
+
+	// Effect - allow, deny или not_applicable
+	Effect string `json:"effect"`
+
+	// PolicyID - правило, определившее решение (пусто при not_applicable)
+	PolicyID string `json:"policy_id,omitempty"`
+
+	// Reason - объяснение решения для журнала и explain
+	Reason string `json:"reason"`
+
+	// DryRun - решение только записано в журнал, но не применено
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Trace - как оценено каждое подходящее по action/resource правило (только explain)
+	Trace []PolicyTrace `json:"trace,omitempty"`
+}
+
+// PolicyTrace - результат оценки одного правила
+type PolicyTrace struct {
+	PolicyID string `json:"policy_id"`
+	Effect   string `json:"effect"`
+	Matched  bool   `json:"matched"`
+
+	// Reason - первое невыполненное условие (или "все условия выполнены")
+	Reason string `json:"reason"`
+}
+
+// ================================================================
+// ПРОВЕРКА ПРАВИЛ
+// ================================================================
+
+// validate - проверяет правило и компилирует регулярные выражения
+func (p *Policy) validate() error {
+	if p.ID == "" {
+		return fmt.Errorf("правило без id")
+	}
+	if p.Effect != EffectAllow && p.Effect != EffectDeny {
+		return fmt.Errorf("правило %s: effect должен быть allow или deny", p.ID)
+	}
+	if len(p.Actions) == 0 {
+		return fmt.Errorf("правило %s: не указаны actions", p.ID)
+	}
+
+	for i := range p.Conditions {
+		cond := &p.Conditions[i]
+		if !strings.HasPrefix(cond.Attribute, "subject.") &&
+			!strings.HasPrefix(cond.Attribute, "resource.") &&
+			!strings.HasPrefix(cond.Attribute, "context.") {
+			return fmt.Errorf("правило %s: атрибут %q должен начинаться с subject., resource. или context.", p.ID, cond.Attribute)
+		}
+		if cond.Ref != "" && cond.Value != nil {
+			return fmt.Errorf("правило %s: у условия %s задан и value, и ref", p.ID, cond.Attribute)
+		}
+
+		switch cond.Operator {
+		case OperatorEq, OperatorNe, OperatorContains, OperatorGt, OperatorGte, OperatorLt, OperatorLte, OperatorExists:
+		case OperatorIn, OperatorNotIn:
+			if _, ok := cond.Value.([]interface{}); !ok && cond.Ref == "" {
+				return fmt.Errorf("правило %s: %s %s требует список", p.ID, cond.Attribute, cond.Operator)
+			}
+		case OperatorBetween:
+			bounds, ok := cond.Value.([]interface{})
+			if !ok || len(bounds) != 2 {
+				return fmt.Errorf("правило %s: between требует [от, до]", p.ID)
+			}
+		case OperatorMatches:
+			source, ok := cond.Value.(string)
+			if !ok {
+				return fmt.Errorf("правило %s: matches требует строку", p.ID)
+			}
+			pattern, err := regexp.Compile(source)
+			if err != nil {
+				return fmt.Errorf("правило %s: %w", p.ID, err)
+			}
+			cond.pattern = pattern
+		default:
+			return fmt.Errorf("правило %s: неизвестный оператор %q", p.ID, cond.Operator)
+		}
+	}
+	return nil
+}
+
+// appliesTo - подходит ли правило к действию и типу ресурса
+func (p *Policy) appliesTo(action, resourceType string) bool {
+	actionMatched := false
+	for _, pattern := range p.Actions {
+		if pattern == "*" || pattern == action ||
+			(strings.HasSuffix(pattern, ":*") && strings.HasPrefix(action, strings.TrimSuffix(pattern, "*"))) {
+			actionMatched = true
+			break
+		}
+	}
+	if !actionMatched {
+		return false
+	}
+
+	if len(p.Resources) == 0 {
+		return true
+	}
+	for _, resource := range p.Resources {
+		if resource == "*" || resource == resourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate - выполняются ли все условия правила
+// Возвращает причину: первое невыполненное условие
+func (p *Policy) evaluate(input Attributes) (bool, string) {
+	for _, cond := range p.Conditions {
+		if !cond.holds(input) {
+			return false, cond.describe(input)
+		}
+	}
+	return true, "все условия выполнены"
+}
+
+// ================================================================
+// УСЛОВИЯ
+// ================================================================
+
+// holds - выполняется ли условие
+func (c *Condition) holds(input Attributes) bool {
+	actual, exists := lookup(input, c.Attribute)
+
+	expected := c.Value
+	if c.Ref != "" {
+		var refExists bool
+		expected, refExists = lookup(input, c.Ref)
+		if !refExists {
+			return false
+		}
+	}
+
+	if c.Operator == OperatorExists {
+		want, ok := expected.(bool)
+		if !ok {
+			want = true
+		}
+		return exists == want
+	}
+	if !exists {
+		// Отсутствующий атрибут не удовлетворяет ни одному сравнению, даже ne
+		return false
+	}
+
+	switch c.Operator {
+	case OperatorEq:
+		return equal(actual, expected)
+	case OperatorNe:
+		return !equal(actual, expected)
+	case OperatorIn:
+		return containsValue(expected, actual)
+	case OperatorNotIn:
+		return !containsValue(expected, actual)
+	case OperatorContains:
+		return containsValue(actual, expected)
+	case OperatorGt, OperatorGte, OperatorLt, OperatorLte:
+		a, okA := toNumber(actual)
+		b, okB := toNumber(expected)
+		if !okA || !okB {
+			return false
+		}
+		switch c.Operator {
+		case OperatorGt:
+			return a > b
+		case OperatorGte:
+			return a >= b
+		case OperatorLt:
+			return a < b
+		}
+		return a <= b
+	case OperatorBetween:
+		bounds, _ := expected.([]interface{})
+		if len(bounds) != 2 {
+			return false
+		}
+		value, okV := toNumber(actual)
+		from, okF := toNumber(bounds[0])
+		to, okT := toNumber(bounds[1])
+		return okV && okF && okT && value >= from && value < to
+	case OperatorMatches:
+		s, ok := actual.(string)
+		return ok && c.pattern != nil && c.pattern.MatchString(s)
+	}
+	return false
+}
+
+// describe - условие и фактическое значение атрибута (для trace и журнала)
+func (c *Condition) describe(input Attributes) string {
+	actual, exists := lookup(input, c.Attribute)
+	if !exists {
+		actual = "<нет>"
+	}
+
+	expected := fmt.Sprintf("%v", c.Value)
+	if c.Ref != "" {
+		refValue, _ := lookup(input, c.Ref)
+		expected = fmt.Sprintf("%s (%v)", c.Ref, refValue)
+	}
+	return fmt.Sprintf("не выполнено: %s %s %s (фактически %v)", c.Attribute, c.Operator, expected, actual)
+}
+
+// lookup - значение по пути "subject.attributes.region"
+func lookup(input Attributes, path string) (interface{}, bool) {
+	var current interface{} = input
+	for _, key := range strings.Split(path, ".") {
+		var value interface{}
+		var ok bool
+		switch m := current.(type) {
+		case Attributes:
+			value, ok = m[key]
+		case map[string]interface{}:
+			value, ok = m[key]
+		}
+		if !ok {
+			return nil, false
+		}
+		current = value
+	}
+	return current, current != nil
+}
+
+// equal - сравнение с приведением чисел (uint из Go и int из YAML равны)
+func equal(a, b interface{}) bool {
+	if x, ok := toNumber(a); ok {
+		y, ok := toNumber(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+// containsValue - list содержит value (list - []interface{} или []string)
+func containsValue(list, value interface{}) bool {
+	items := reflect.ValueOf(list)
+	if items.Kind() != reflect.Slice {
+		return false
+	}
+	for i := 0; i < items.Len(); i++ {
+		if equal(items.Index(i).Interface(), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// toNumber - любое число как float64
+func toNumber(v interface{}) (float64, bool) {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+	return 0, false
+}
+
+// normalize - время как RFC3339 (в правилах время - строка)
+func normalize(v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok {
+		return t.UTC().Format(time.RFC3339)
+	}
+	return v
+}
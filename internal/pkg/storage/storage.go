@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ================================================================
+// STORAGE - Хранилище файлов (аватары и т.п.)
+// ================================================================
+// Интерфейс повторяет модель объектных хранилищ (S3 и совместимые):
+// плоские ключи вида "avatars/3f9c.../128.jpg", объект записывается
+// целиком, метаданные - только тип содержимого. Сейчас есть локальная
+// реализация (LocalStorage); S3 подключается реализацией того же интерфейса
+
+// Storage - хранилище объектов
+type Storage interface {
+	// Put - записывает объект (существующий с тем же ключом заменяется)
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+
+	// Get - открывает объект для чтения (Body закрывает вызывающий)
+	Get(ctx context.Context, key string) (*Object, error)
+
+	// Delete - удаляет объект (отсутствующий объект - не ошибка)
+	Delete(ctx context.Context, key string) error
+}
+
+// Object - объект хранилища
+type Object struct {
+	// Body - содержимое; у локальных файлов реализует io.ReadSeeker
+	// (http.ServeContent отдаёт Range и If-Modified-Since сам)
+	Body io.ReadCloser
+
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+var (
+	// ErrNotFound - объекта с таким ключом нет
+	ErrNotFound = errors.New("storage: объект не найден")
+
+	// ErrInvalidKey - ключ пустой, абсолютный или выходит за пределы хранилища
+	ErrInvalidKey = errors.New("storage: недопустимый ключ")
+)
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"errors"
+
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// ================================================================
+// ATTRIBUTE REPOSITORY - Схемы атрибутов пользователей
+// ================================================================
+
+// AttributeRepository - интерфейс для работы со схемами атрибутов
+type AttributeRepository interface {
+	Create(definition *domain.AttributeDefinition) error
+	FindAll() ([]domain.AttributeDefinition, error)
+	FindByKey(key string) (*domain.AttributeDefinition, error)
+	Update(definition *domain.AttributeDefinition) error
+
+	// Delete - удаляет схему и значения атрибута у участников организации
+	Delete(key string) error
+
+	// ForTenant - копия репозитория, ограниченная схемами организации orgID
+	// (0 - без организаций)
+	ForTenant(orgID uint) AttributeRepository
+}
+
+// ErrAttributeDefinitionNotFound - в организации нет атрибута с таким ключом
+var ErrAttributeDefinitionNotFound = errors.New("атрибут не найден")
+
+// attributeRepository - реализация с GORM
+type attributeRepository struct {
+	db    *gorm.DB
+	orgID uint // Текущая организация
+}
+
+// NewAttributeRepository - конструктор
+func NewAttributeRepository(db *gorm.DB) AttributeRepository {
+	return &attributeRepository{db: db}
+}
+
+// ForTenant - репозиторий схем организации orgID
+// В отличие от пользователей, схемы без организации (0) - отдельный набор,
+// а не "все организации": у каждой организации свои атрибуты
+func (r *attributeRepository) ForTenant(orgID uint) AttributeRepository {
+	return &attributeRepository{db: r.db, orgID: orgID}
+}
+
+// scoped - запрос к схемам текущей организации
+func (r *attributeRepository) scoped() *gorm.DB {
+	return r.db.Where("user_attribute_definitions.organization_id = ?", r.orgID)
+}
+
+// Create - сохраняет схему атрибута в текущей организации
+func (r *attributeRepository) Create(definition *domain.AttributeDefinition) error {
+	definition.OrganizationID = r.orgID
+	return r.db.Create(definition).Error
+}
+
+// FindAll - схемы организации (по ключу)
+func (r *attributeRepository) FindAll() ([]domain.AttributeDefinition, error) {
+	var definitions []domain.AttributeDefinition
+	err := r.scoped().Order("key").Find(&definitions).Error
+	return definitions, err
+}
+
+// FindByKey - схема атрибута по ключу
+func (r *attributeRepository) FindByKey(key string) (*domain.AttributeDefinition, error) {
+	var definition domain.AttributeDefinition
+	err := r.scoped().Where("key = ?", key).First(&definition).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAttributeDefinitionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+// Update - сохраняет все поля схемы, кроме ключа
+func (r *attributeRepository) Update(definition *domain.AttributeDefinition) error {
+	result := r.scoped().Model(&domain.AttributeDefinition{}).Where("id = ?", definition.ID).
+		Select("type", "required", "enum", "pattern", "visibility", "description", "updated_at").
+		Updates(definition)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAttributeDefinitionNotFound
+	}
+	return nil
+}
+
+// Delete - удаляет схему в одной транзакции:
+// 1. Ключ убирается из users.attributes участников организации
+// 2. Удаляется сама схема
+// Генерирует SQL: UPDATE users SET attributes = attributes - 'key', version = version + 1
+//                 WHERE jsonb_exists(attributes, 'key') AND ...
+func (r *attributeRepository) Delete(key string) error {
+	definition, err := r.FindByKey(key)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		users := tx.Model(&domain.User{}).Unscoped().Where("jsonb_exists(users.attributes, ?)", key)
+		if r.orgID != 0 {
+			members := tx.Model(&domain.Membership{}).Select("user_id").Where("organization_id = ?", r.orgID)
+			users = users.Where("users.id IN (?)", members)
+		}
+		err := users.UpdateColumns(map[string]interface{}{
+			"attributes": gorm.Expr("users.attributes - ?::text", key),
+			"version":    gorm.Expr("users.version + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&domain.AttributeDefinition{}, definition.ID).Error
+	})
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"time"
+
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// ================================================================
+// AUDIT REPOSITORY - Хранение журнала событий безопасности
+// ================================================================
+
+// auditChainLock - ключ advisory lock PostgreSQL для записи в цепочку
+// Пока транзакция держит блокировку, другие события ждут:
+// иначе два события получили бы один и тот же PrevHash
+const auditChainLock = 0x61756474 // "audt"
+
+// AuditRepository - интерфейс для работы с журналом событий
+// Журнал только пополняется: методов изменения нет,
+// удаление - только целиком старых событий по сроку хранения
+type AuditRepository interface {
+	Create(event *domain.AuditEvent) error
+	List(filter domain.AuditEventFilter) ([]domain.AuditEvent, int64, error)
+	FindAfter(afterID uint, limit int) ([]domain.AuditEvent, error)
+	DeleteBefore(cutoff time.Time) (int64, error)
+
+	// ForTenant - копия репозитория, List которой возвращает только события,
+	// автор или цель которых - участник организации orgID (0 - все события)
+	// Цепочка хэшей общая: Create, FindAfter и DeleteBefore не ограничиваются
+	ForTenant(orgID uint) AuditRepository
+}
+
+// auditRepository - реализация с GORM
+type auditRepository struct {
+	db    *gorm.DB
+	orgID uint // 0 - все организации
+}
+
+// NewAuditRepository - конструктор
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Create - добавляет событие в конец цепочки
+// Заполняет CreatedAt, PrevHash и Hash
+// Генерирует SQL: INSERT INTO audit_events (actor_id, target_id, action, ...) VALUES (...)
+func (r *auditRepository) Create(event *domain.AuditEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Блокировка снимается автоматически в конце транзакции
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLock).Error; err != nil {
+			return err
+		}
+
+		// Хэш последнего события (пустой журнал - пустая строка)
+		var last domain.AuditEvent
+		if err := tx.Select("hash").Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+			return err
+		}
+
+		event.PrevHash = last.Hash
+		event.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		event.Hash = event.ComputeHash()
+		return tx.Create(event).Error
+	})
+}
+
+// ForTenant - копия репозитория для организации orgID
+func (r *auditRepository) ForTenant(orgID uint) AuditRepository {
+	return &auditRepository{db: r.db, orgID: orgID}
+}
+
+// List - события по фильтру (новые первыми) и общее количество
+// В организации - только события её участников; события без автора и цели
+// (вход с неизвестным email, очистка журнала) видны только без организации
+// Генерирует SQL: ... WHERE (actor_id IN (участники) OR target_id IN (участники))
+func (r *auditRepository) List(filter domain.AuditEventFilter) ([]domain.AuditEvent, int64, error) {
+	query := r.db.Model(&domain.AuditEvent{})
+	if r.orgID != 0 {
+		members := r.db.Model(&domain.Membership{}).Select("user_id").Where("organization_id = ?", r.orgID)
+		query = query.Where("actor_id IN (?) OR target_id IN (?)", members, members)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.TargetID != nil {
+		query = query.Where("target_id = ?", *filter.TargetID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []domain.AuditEvent
+	err := query.Order("id DESC").
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Find(&events).Error
+	return events, total, err
+}
+
+// FindAfter - следующие limit событий после afterID в порядке цепочки
+// Используется для проверки цепочки порциями
+func (r *auditRepository) FindAfter(afterID uint, limit int) ([]domain.AuditEvent, error) {
+	var events []domain.AuditEvent
+	err := r.db.Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// DeleteBefore - удаляет события старше cutoff (срок хранения)
+// Удаляется только начало цепочки: первое оставшееся событие становится её началом
+func (r *auditRepository) DeleteBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", cutoff).Delete(&domain.AuditEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"errors"
+
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// ================================================================
+// CREDENTIAL REPOSITORY - Passkeys (WebAuthn учётные данные)
+// ================================================================
+
+// CredentialRepository - интерфейс для работы с passkeys
+type CredentialRepository interface {
+	Create(credential *domain.WebAuthnCredential) error
+	FindByID(id uint) (*domain.WebAuthnCredential, error)
+	FindByCredentialID(credentialID []byte) (*domain.WebAuthnCredential, error)
+	FindByUserID(userID uint) ([]domain.WebAuthnCredential, error)
+	Update(credential *domain.WebAuthnCredential) error
+	Delete(id uint) error
+}
+
+// credentialRepository - реализация с GORM
+type credentialRepository struct {
+	db *gorm.DB
+}
+
+// NewCredentialRepository - конструктор
+func NewCredentialRepository(db *gorm.DB) CredentialRepository {
+	return &credentialRepository{db: db}
+}
+
+// Create - сохраняет новый passkey
+func (r *credentialRepository) Create(credential *domain.WebAuthnCredential) error {
+	return r.db.Create(credential).Error
+}
+
+// FindByID - ищет passkey по ID записи
+func (r *credentialRepository) FindByID(id uint) (*domain.WebAuthnCredential, error) {
+	var credential domain.WebAuthnCredential
+	err := r.db.First(&credential, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("passkey не найден")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// FindByCredentialID - ищет passkey по идентификатору аутентификатора
+// Используется при входе без email (браузер сообщает, каким ключом подписал)
+func (r *credentialRepository) FindByCredentialID(credentialID []byte) (*domain.WebAuthnCredential, error) {
+	var credential domain.WebAuthnCredential
+	err := r.db.Where("credential_id = ?", credentialID).First(&credential).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("passkey не найден")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// FindByUserID - все passkeys пользователя (старые первыми)
+func (r *credentialRepository) FindByUserID(userID uint) ([]domain.WebAuthnCredential, error) {
+	var credentials []domain.WebAuthnCredential
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&credentials).Error
+	if err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// Update - сохраняет изменения (счётчик подписей, название, время входа)
+func (r *credentialRepository) Update(credential *domain.WebAuthnCredential) error {
+	return r.db.Save(credential).Error
+}
+
+// Delete - удаляет passkey (физически: восстанавливать ключ нет смысла)
+func (r *credentialRepository) Delete(id uint) error {
+	result := r.db.Delete(&domain.WebAuthnCredential{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("passkey не найден")
+	}
+	return nil
+}
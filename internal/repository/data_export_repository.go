@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ================================================================
+// DATA EXPORT REPOSITORY - Задачи выгрузки и сбор данных пользователя
+// ================================================================
+
+// DataExportRepository - интерфейс для задач выгрузки персональных данных
+type DataExportRepository interface {
+	Create(export *domain.DataExport) error
+	FindByID(id uint) (*domain.DataExport, error)
+	Update(export *domain.DataExport) error
+
+	// FindActive - незавершённая (pending или processing) задача пользователя
+	FindActive(userID uint) (*domain.DataExport, error)
+
+	// ClaimPending - забирает самую старую задачу pending (или processing, начатую
+	// раньше staleBefore - обработчик упал) и переводит её в processing
+	// Несколько экземпляров сервиса не получат одну и ту же задачу (SKIP LOCKED)
+	// Возвращает ErrDataExportRecordNotFound, если задач нет
+	ClaimPending(now, staleBefore time.Time) (*domain.DataExport, error)
+
+	// FindExpired - готовые архивы, срок ссылки которых истёк к now
+	FindExpired(now time.Time) ([]domain.DataExport, error)
+
+	// CollectUserData - все данные пользователя одним согласованным снимком
+	CollectUserData(userID uint) (*domain.UserDataArchive, error)
+}
+
+// ErrDataExportRecordNotFound - задача выгрузки не найдена
+var ErrDataExportRecordNotFound = errors.New("запись не найдена")
+
+// dataExportRepository - реализация с GORM
+type dataExportRepository struct {
+	db *gorm.DB
+}
+
+// NewDataExportRepository - конструктор
+func NewDataExportRepository(db *gorm.DB) DataExportRepository {
+	return &dataExportRepository{db: db}
+}
+
+// Create - сохраняет новую задачу
+func (r *dataExportRepository) Create(export *domain.DataExport) error {
+	return r.db.Create(export).Error
+}
+
+// FindByID - задача по ID
+func (r *dataExportRepository) FindByID(id uint) (*domain.DataExport, error) {
+	var export domain.DataExport
+	if err := r.db.First(&export, id).Error; err != nil {
+		return nil, exportNotFound(err)
+	}
+	return &export, nil
+}
+
+// Update - сохраняет состояние задачи
+func (r *dataExportRepository) Update(export *domain.DataExport) error {
+	return r.db.Save(export).Error
+}
+
+// FindActive - задача пользователя, которая ещё не завершена
+func (r *dataExportRepository) FindActive(userID uint) (*domain.DataExport, error) {
+	var export domain.DataExport
+	err := r.db.
+		Where("user_id = ? AND status IN ?", userID, []string{domain.DataExportStatusPending, domain.DataExportStatusProcessing}).
+		Order("id").
+		First(&export).Error
+	if err != nil {
+		return nil, exportNotFound(err)
+	}
+	return &export, nil
+}
+
+// ClaimPending - выбор и блокировка задачи в одной транзакции
+// Генерирует SQL: SELECT * FROM data_exports WHERE status = 'pending' OR (...) ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED
+func (r *dataExportRepository) ClaimPending(now, staleBefore time.Time) (*domain.DataExport, error) {
+	var export domain.DataExport
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? OR (status = ? AND started_at < ?)",
+				domain.DataExportStatusPending, domain.DataExportStatusProcessing, staleBefore).
+			Order("id").
+			First(&export).Error
+		if err != nil {
+			return err
+		}
+
+		export.Status = domain.DataExportStatusProcessing
+		export.StartedAt = &now
+		return tx.Model(&export).Updates(map[string]interface{}{
+			"status":     export.Status,
+			"started_at": export.StartedAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, exportNotFound(err)
+	}
+	return &export, nil
+}
+
+// FindExpired - готовые архивы с истёкшей ссылкой
+func (r *dataExportRepository) FindExpired(now time.Time) ([]domain.DataExport, error) {
+	var exports []domain.DataExport
+	err := r.db.
+		Where("status = ? AND expires_at <= ?", domain.DataExportStatusCompleted, now).
+		Order("id").
+		Find(&exports).Error
+	return exports, err
+}
+
+// CollectUserData - собирает разделы архива
+// Все запросы выполняются в одной транзакции REPEATABLE READ: архив
+// отражает состояние на один момент, даже если пользователь параллельно
+// меняет профиль или входит с нового устройства
+func (r *dataExportRepository) CollectUserData(userID uint) (*domain.UserDataArchive, error) {
+	archive := &domain.UserDataArchive{GeneratedAt: time.Now()}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// === ШАГ 1: ПРОФИЛЬ И СПОСОБЫ ВХОДА ===
+		if err := tx.First(&archive.User, userID).Error; err != nil {
+			return err
+		}
+		archive.Profile = archive.User.Profile
+		archive.Attributes = archive.User.Attributes
+		archive.Identities = domain.UserIdentities{
+			AuthProvider: archive.User.AuthProvider,
+			ExternalID:   archive.User.ExternalID,
+			PasswordSet:  archive.User.Password != "",
+		}
+		if err := tx.Where("user_id = ?", userID).Order("id").Find(&archive.Identities.Passkeys).Error; err != nil {
+			return err
+		}
+
+		// === ШАГ 2: СЕАНСЫ, ОРГАНИЗАЦИИ, ГРУППЫ, СТАТУСЫ ===
+		if err := tx.Where("user_id = ?", userID).Order("created_at").Find(&archive.Sessions).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Order("organization_id").Find(&archive.Organizations).Error; err != nil {
+			return err
+		}
+
+		err := tx.Where("id IN (?)", tx.Model(&domain.GroupMember{}).Select("group_id").Where("user_id = ?", userID)).
+			Order("id").
+			Find(&archive.Groups).Error
+		if err != nil {
+			return err
+		}
+		groups := make([]*domain.Group, len(archive.Groups))
+		for i := range archive.Groups {
+			groups[i] = &archive.Groups[i]
+		}
+		if err := (&groupRepository{db: tx}).loadRoles(groups); err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userID).Order("id").Find(&archive.StatusHistory).Error; err != nil {
+			return err
+		}
+
+		// === ШАГ 3: ЖУРНАЛ СОБЫТИЙ ===
+		err = tx.Where("actor_id = ? OR target_id = ?", userID, userID).Order("id").Find(&archive.AuditEvents).Error
+		if err != nil {
+			return err
+		}
+		for i := range archive.AuditEvents {
+			event := &archive.AuditEvents[i]
+			if event.ActorID == nil || *event.ActorID != userID {
+				event.IP = ""
+				event.UserAgent = ""
+			}
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, exportNotFound(err)
+	}
+	return archive, nil
+}
+
+// exportNotFound - gorm.ErrRecordNotFound → ErrDataExportRecordNotFound
+func exportNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrDataExportRecordNotFound
+	}
+	return err
+}
@@ -64,13 +64,47 @@ func InitDB(cfg *config.Config) (*gorm.DB, error) {
 	// 2. Добавляет недостающие колонки (если структура изменилась)
 	// 3. Создаёт индексы (uniqueIndex, index)
 	// 4. НЕ удаляет существующие колонки (безопасно)
-	if err := db.AutoMigrate(&domain.User{}); err != nil {
+	if err := db.AutoMigrate(
+		&domain.User{},
+		&domain.AuditEvent{},
+		&domain.MagicLinkToken{},
+		&domain.WebAuthnCredential{},
+		&domain.WebAuthnChallenge{},
+		&domain.Session{},
+		&domain.Organization{},
+		&domain.Membership{},
+		&domain.Invitation{},
+		&domain.Group{},
+		&domain.GroupMember{},
+		&domain.GroupRole{},
+		&domain.UserStatusChange{},
+		&domain.DataExport{},
+		&domain.UserErasure{},
+		&domain.AttributeDefinition{},
+		&domain.IdempotencyRecord{},
+		&domain.UserImport{},
+	); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
+	
+	// Организации: организация по умолчанию и перенос старых пользователей
+	if err := migrateOrganizations(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate organizations: %w", err)
+	}
+
+	// Уникальность email только среди неудалённых аккаунтов
+	if err := migrateLiveEmailIndex(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate users email index: %w", err)
+	}
+
+	// Фильтр пользователей по атрибутам (GET /users?attr.key=value)
+	if err := migrateAttributesIndex(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate users attributes index: %w", err)
+	}
 
 	// Логируем успешное подключение
 	log.Println("✅ База данных подключена")
-	log.Println("✅ Auto Migration выполнен (таблица users создана/обновлена)")
+	log.Println("✅ Auto Migration выполнен (таблицы созданы/обновлены)")
 
 	// === ШАГ 4: НАСТРОЙКА CONNECTION POOL (опционально) ===
 	// Получаем базовый sql.DB для тонкой настройки
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ================================================================
+// ERASURE REPOSITORY - Запросы на стирание и обезличивание пользователя
+// ================================================================
+
+// ErasureRepository - интерфейс для запросов на стирание данных
+type ErasureRepository interface {
+	Create(erasure *domain.UserErasure) error
+	FindByID(id uint) (*domain.UserErasure, error)
+	Update(erasure *domain.UserErasure) error
+
+	// FindPending - запрос пользователя, который ещё можно отменить
+	FindPending(userID uint) (*domain.UserErasure, error)
+
+	// FindLatest - последний запрос пользователя (любой статус)
+	FindLatest(userID uint) (*domain.UserErasure, error)
+
+	// List - запросы организации, новые первыми
+	List(filter domain.ErasureFilter) ([]domain.UserErasure, error)
+
+	// FindDue - запросы pending, период отмены которых истёк к now
+	// Без ограничения организацией: вызывается фоновой задачей
+	FindDue(now time.Time) ([]domain.UserErasure, error)
+
+	// Erase - обезличивает пользователя и закрывает запрос в одной транзакции
+	// Возвращает файлы пользователя вне БД, которые нужно удалить
+	Erase(erasure *domain.UserErasure, now time.Time) (*ErasedFiles, error)
+
+	// ForTenant - репозиторий, работающий только с запросами организации orgID
+	ForTenant(orgID uint) ErasureRepository
+}
+
+// ErrErasureRecordNotFound - запрос на стирание не найден
+var ErrErasureRecordNotFound = errors.New("запрос на стирание не найден")
+
+// ErasedFiles - файлы стёртого пользователя, на которые больше нет ссылок в БД
+type ErasedFiles struct {
+	Exports  []string // Имена архивов выгрузок (DataExport.FileName)
+	AvatarID string   // Аватар (UserProfile.AvatarID, пусто - не было)
+}
+
+// erasedUserTables - таблицы, строки которых удаляются при стирании (колонка user_id)
+// В отличие от userReferenceTables, история статусов остаётся: она, как и журнал
+// событий, ссылается на обезличенную запись и не содержит данных пользователя
+var erasedUserTables = []string{
+	"sessions",
+	"credentials",
+	"webauthn_challenges",
+	"magic_link_tokens",
+	"organization_members",
+	"user_group_members",
+	"data_exports",
+}
+
+// erasureRepository - реализация с GORM
+type erasureRepository struct {
+	db    *gorm.DB
+	orgID uint // 0 - все организации
+}
+
+// NewErasureRepository - конструктор
+func NewErasureRepository(db *gorm.DB) ErasureRepository {
+	return &erasureRepository{db: db}
+}
+
+// ForTenant - копия репозитория с фильтром по организации
+func (r *erasureRepository) ForTenant(orgID uint) ErasureRepository {
+	return &erasureRepository{db: r.db, orgID: orgID}
+}
+
+// scoped - запрос с учётом организации
+func (r *erasureRepository) scoped() *gorm.DB {
+	if r.orgID == 0 {
+		return r.db
+	}
+	return r.db.Where("organization_id = ?", r.orgID)
+}
+
+// Create - сохраняет новый запрос
+func (r *erasureRepository) Create(erasure *domain.UserErasure) error {
+	return r.db.Create(erasure).Error
+}
+
+// FindByID - запрос по ID
+func (r *erasureRepository) FindByID(id uint) (*domain.UserErasure, error) {
+	var erasure domain.UserErasure
+	if err := r.scoped().First(&erasure, id).Error; err != nil {
+		return nil, erasureNotFound(err)
+	}
+	return &erasure, nil
+}
+
+// Update - сохраняет состояние запроса
+func (r *erasureRepository) Update(erasure *domain.UserErasure) error {
+	return r.db.Save(erasure).Error
+}
+
+// FindPending - незавершённый запрос пользователя
+func (r *erasureRepository) FindPending(userID uint) (*domain.UserErasure, error) {
+	var erasure domain.UserErasure
+	err := r.scoped().
+		Where("user_id = ? AND status = ?", userID, domain.ErasureStatusPending).
+		Order("id").
+		First(&erasure).Error
+	if err != nil {
+		return nil, erasureNotFound(err)
+	}
+	return &erasure, nil
+}
+
+// FindLatest - последний запрос пользователя
+func (r *erasureRepository) FindLatest(userID uint) (*domain.UserErasure, error) {
+	var erasure domain.UserErasure
+	err := r.scoped().Where("user_id = ?", userID).Order("id DESC").First(&erasure).Error
+	if err != nil {
+		return nil, erasureNotFound(err)
+	}
+	return &erasure, nil
+}
+
+// List - запросы с фильтром по статусу
+func (r *erasureRepository) List(filter domain.ErasureFilter) ([]domain.UserErasure, error) {
+	var erasures []domain.UserErasure
+	query := r.scoped()
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	err := query.Order("id DESC").Find(&erasures).Error
+	return erasures, err
+}
+
+// FindDue - запросы, которые пора выполнить
+func (r *erasureRepository) FindDue(now time.Time) ([]domain.UserErasure, error) {
+	var erasures []domain.UserErasure
+	err := r.db.
+		Where("status = ? AND scheduled_for <= ?", domain.ErasureStatusPending, now).
+		Order("scheduled_for").
+		Find(&erasures).Error
+	return erasures, err
+}
+
+// Erase - стирание данных пользователя
+// Строка users не удаляется: на неё ссылаются журнал событий и история статусов.
+// Вместо этого все поля, по которым можно узнать человека, заменяются
+func (r *erasureRepository) Erase(erasure *domain.UserErasure, now time.Time) (*ErasedFiles, error) {
+	files := &ErasedFiles{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// === ШАГ 1: БЛОКИРОВКА ЗАПРОСА И ПОЛЬЗОВАТЕЛЯ ===
+		// Запрос мог быть отменён, пока фоновая задача до него добиралась
+		var current domain.UserErasure
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, erasure.ID).Error
+		if err != nil {
+			return erasureNotFound(err)
+		}
+		if current.Status != domain.ErasureStatusPending {
+			return ErrErasureRecordNotFound
+		}
+
+		var user domain.User
+		err = tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, erasure.UserID).Error
+		if err != nil {
+			return err
+		}
+
+		// === ШАГ 2: УЧЁТНЫЕ ДАННЫЕ, СЕАНСЫ, ЧЛЕНСТВО, ВЫГРУЗКИ ===
+		err = tx.Model(&domain.DataExport{}).
+			Where("user_id = ? AND file_name <> ''", user.ID).
+			Pluck("file_name", &files.Exports).Error
+		if err != nil {
+			return err
+		}
+
+		for _, table := range erasedUserTables {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE user_id = ?", user.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		// Ссылки на вход, запрошенные до регистрации, хранят только email
+		if err := tx.Where("email = ?", user.Email).Delete(&domain.MagicLinkToken{}).Error; err != nil {
+			return err
+		}
+
+		// === ШАГ 3: ОБЕЗЛИЧИВАНИЕ ===
+		erasedEmail := fmt.Sprintf("erased-%d@%s", user.ID, domain.ErasedEmailDomain)
+
+		err = tx.Model(&domain.Invitation{}).Where("email = ?", user.Email).Update("email", erasedEmail).Error
+		if err != nil {
+			return err
+		}
+
+		// Updates с map не проходит через сериализатор шифрования: заглушка
+		// пишется открытым текстом - данных пользователя в ней нет
+		updates := map[string]interface{}{
+			"email":               erasedEmail,
+			"email_index":         "",
+			"name":                fmt.Sprintf("Erased user %d", user.ID),
+			"password":            "",
+			"external_id":         "",
+			"passkey_handle":      "",
+			"password_changed_at": nil,
+			"status":              domain.UserStatusErased,
+			"status_reason":       "",
+			"suspended_until":     nil,
+			"token_version":       user.TokenVersion + 1,
+			"version":             gorm.Expr("version + 1"),
+
+			"profile_display_name":      "",
+			"profile_bio":               "",
+			"profile_phone":             "",
+			"profile_timezone":          "",
+			"profile_locale":            "",
+			"profile_avatar_id":         "",
+			"profile_avatar_updated_at": nil,
+
+			"attributes": domain.UserAttributes{},
+		}
+		if !user.DeletedAt.Valid {
+			updates["deleted_at"] = now
+		}
+		if err := tx.Unscoped().Model(&domain.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		files.AvatarID = user.Profile.AvatarID
+
+		actorID := erasure.RequestedByID
+		change := &domain.UserStatusChange{
+			UserID:     user.ID,
+			FromStatus: user.EffectiveStatus(now),
+			ToStatus:   domain.UserStatusErased,
+			Reason:     fmt.Sprintf("запрос на стирание #%d", erasure.ID),
+			ActorID:    &actorID,
+			CreatedAt:  now,
+		}
+		if err := tx.Create(change).Error; err != nil {
+			return err
+		}
+
+		// === ШАГ 4: ЗАКРЫТИЕ ЗАПРОСА ===
+		erasure.Status = domain.ErasureStatusCompleted
+		erasure.CompletedAt = &now
+		return tx.Model(&domain.UserErasure{}).Where("id = ?", erasure.ID).Updates(map[string]interface{}{
+			"status":       erasure.Status,
+			"completed_at": erasure.CompletedAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// erasureNotFound - gorm.ErrRecordNotFound → ErrErasureRecordNotFound
+func erasureNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrErasureRecordNotFound
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"strings"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/fieldcrypt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ================================================================
+// FIELD ENCRYPTION REPOSITORY - Перешифрование полей пользователей
+// ================================================================
+
+// FieldEncryptionRepository - поиск и перезапись устаревших значений
+type FieldEncryptionRepository interface {
+	// ReencryptUsers - перезаписывает до limit устаревших пользователей
+	// Возвращает, сколько записей перезаписано (0 - больше нечего)
+	ReencryptUsers(limit int) (int, error)
+}
+
+// encryptedUserColumns - колонки users с тегом serializer:encrypted
+var encryptedUserColumns = []string{"email", "name", "profile_phone"}
+
+// fieldEncryptionRepository - реализация с GORM
+type fieldEncryptionRepository struct {
+	db  *gorm.DB
+	enc *fieldcrypt.Encryptor
+}
+
+// NewFieldEncryptionRepository - конструктор
+func NewFieldEncryptionRepository(db *gorm.DB, enc *fieldcrypt.Encryptor) FieldEncryptionRepository {
+	return &fieldEncryptionRepository{db: db, enc: enc}
+}
+
+// stale - пользователи (включая удалённые), у которых:
+//   - шифруемая колонка открыта или зашифрована не текущим ключом
+//   - нешифруемая колонка зашифрована (поле исключили из FIELD_ENCRYPTION_FIELDS)
+//   - не заполнен blind index email
+func (r *fieldEncryptionRepository) stale(db *gorm.DB) *gorm.DB {
+	conditions := []string{"(email <> '' AND email_index = '')"}
+	args := []interface{}{}
+	for _, column := range encryptedUserColumns {
+		if r.enc.Encrypts(column) {
+			conditions = append(conditions, "("+column+" <> '' AND "+column+" NOT LIKE ?)")
+			args = append(args, r.enc.CurrentPrefix()+"%")
+		} else {
+			conditions = append(conditions, column+" LIKE ?")
+			args = append(args, fieldcrypt.Prefix+"%")
+		}
+	}
+	return db.Unscoped().Model(&domain.User{}).Where("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+// ReencryptUsers - чтение (расшифровка любым известным ключом) и запись текущим ключом
+// Строки блокируются до конца транзакции: параллельное изменение профиля
+// не будет перезаписано старым значением, а другой экземпляр сервиса
+// возьмёт следующие записи (SKIP LOCKED)
+func (r *fieldEncryptionRepository) ReencryptUsers(limit int) (int, error) {
+	var users []domain.User
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := r.stale(tx).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Order("id").
+			Limit(limit).
+			Find(&users).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range users {
+			user := &users[i]
+			user.EmailIndex = r.enc.BlindIndex(user.Email)
+
+			// UpdateColumns - без хуков и без изменения updated_at:
+			// для пользователя профиль не менялся
+			err := tx.Unscoped().Model(user).
+				Select(append(encryptedUserColumns, "email_index")).
+				UpdateColumns(user).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(users), nil
+}
@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"errors"
+
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ================================================================
+// GROUP REPOSITORY - Группы пользователей, участники и роли групп
+// ================================================================
+
+// GroupRepository - интерфейс для работы с группами
+type GroupRepository interface {
+	Create(group *domain.Group) error
+	FindByID(id uint) (*domain.Group, error)
+	FindByName(name string) (*domain.Group, error)
+	FindAll() ([]domain.Group, error)
+	Update(group *domain.Group) error
+
+	// Delete - удаляет группу; вложенные группы переходят к её родителю
+	Delete(id uint) error
+
+	// SubtreeIDs - ID группы и всех вложенных в неё групп
+	SubtreeIDs(id uint) ([]uint, error)
+
+	// Участники (прямое членство)
+	AddMember(groupID, userID uint) error
+	RemoveMember(groupID, userID uint) error
+	ListMembers(groupID uint) ([]domain.User, error)
+
+	// Роли групп
+	AddRole(groupID uint, role string) error
+	RemoveRole(groupID uint, role string) error
+
+	// RolesForUser - роли всех групп пользователя и их родителей
+	RolesForUser(userID uint) ([]string, error)
+
+	// ForTenant - копия репозитория, ограниченная группами организации orgID
+	// (0 - без ограничения)
+	ForTenant(orgID uint) GroupRepository
+}
+
+// ErrGroupRecordNotFound - группа, участник или роль группы не найдены
+var ErrGroupRecordNotFound = errors.New("запись не найдена")
+
+// groupSubtreeSQL - рекурсивный запрос: группа и все её потомки
+// Параметры: ID группы, ID организации (дважды; 0 - любая организация)
+// UNION (а не UNION ALL) отбрасывает повторы - запрос конечен даже при цикле в данных
+const groupSubtreeSQL = `WITH RECURSIVE subtree AS (
+		SELECT id FROM user_groups WHERE id = ? AND (? = 0 OR organization_id = ?)
+		UNION
+		SELECT g.id FROM user_groups g JOIN subtree s ON g.parent_id = s.id
+	) SELECT id FROM subtree`
+
+// groupRepository - реализация с GORM
+type groupRepository struct {
+	db    *gorm.DB
+	orgID uint // Текущая организация (0 - все группы)
+}
+
+// NewGroupRepository - конструктор
+func NewGroupRepository(db *gorm.DB) GroupRepository {
+	return &groupRepository{db: db}
+}
+
+// ForTenant - репозиторий групп организации orgID
+func (r *groupRepository) ForTenant(orgID uint) GroupRepository {
+	return &groupRepository{db: r.db, orgID: orgID}
+}
+
+// scoped - запрос к user_groups, ограниченный текущей организацией
+func (r *groupRepository) scoped() *gorm.DB {
+	if r.orgID == 0 {
+		return r.db
+	}
+	return r.db.Where("user_groups.organization_id = ?", r.orgID)
+}
+
+// Create - сохраняет новую группу в текущей организации
+func (r *groupRepository) Create(group *domain.Group) error {
+	group.OrganizationID = r.orgID
+	return r.db.Create(group).Error
+}
+
+// FindByID - группа по ID (вместе с ролями)
+func (r *groupRepository) FindByID(id uint) (*domain.Group, error) {
+	var group domain.Group
+	if err := r.scoped().First(&group, id).Error; err != nil {
+		return nil, groupNotFound(err)
+	}
+	if err := r.loadRoles([]*domain.Group{&group}); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// FindByName - группа по названию (проверка уникальности)
+func (r *groupRepository) FindByName(name string) (*domain.Group, error) {
+	var group domain.Group
+	if err := r.scoped().Where("name = ?", name).First(&group).Error; err != nil {
+		return nil, groupNotFound(err)
+	}
+	return &group, nil
+}
+
+// FindAll - все группы организации (по названию)
+func (r *groupRepository) FindAll() ([]domain.Group, error) {
+	var groups []domain.Group
+	if err := r.scoped().Order("name").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	ptrs := make([]*domain.Group, len(groups))
+	for i := range groups {
+		ptrs[i] = &groups[i]
+	}
+	if err := r.loadRoles(ptrs); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// Update - сохраняет название, описание и родителя группы
+func (r *groupRepository) Update(group *domain.Group) error {
+	result := r.scoped().Model(&domain.Group{}).Where("id = ?", group.ID).
+		Select("name", "description", "parent_id", "updated_at").
+		Updates(group)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrGroupRecordNotFound
+	}
+	return nil
+}
+
+// Delete - удаляет группу в одной транзакции:
+// 1. Вложенные группы переносятся к родителю удаляемой (дерево не рвётся)
+// 2. Удаляются участники и роли группы
+// 3. Удаляется сама группа
+func (r *groupRepository) Delete(id uint) error {
+	group, err := r.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Group{}).Where("parent_id = ?", id).
+			Update("parent_id", group.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", id).Delete(&domain.GroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", id).Delete(&domain.GroupRole{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.Group{}, id).Error
+	})
+}
+
+// SubtreeIDs - группа id и все её потомки
+// Пустой результат - группы нет в текущей организации
+func (r *groupRepository) SubtreeIDs(id uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Raw(groupSubtreeSQL, id, r.orgID, r.orgID).Scan(&ids).Error
+	return ids, err
+}
+
+// ================================================================
+// УЧАСТНИКИ
+// ================================================================
+
+// AddMember - добавляет пользователя в группу (повторное добавление - не ошибка)
+func (r *groupRepository) AddMember(groupID, userID uint) error {
+	member := domain.GroupMember{GroupID: groupID, UserID: userID}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error
+}
+
+// RemoveMember - убирает пользователя из группы
+func (r *groupRepository) RemoveMember(groupID, userID uint) error {
+	result := r.db.Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&domain.GroupMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrGroupRecordNotFound
+	}
+	return nil
+}
+
+// ListMembers - прямые участники группы (без вложенных групп)
+func (r *groupRepository) ListMembers(groupID uint) ([]domain.User, error) {
+	var users []domain.User
+	err := r.db.Joins("JOIN user_group_members ON user_group_members.user_id = users.id").
+		Where("user_group_members.group_id = ?", groupID).
+		Order("users.id").
+		Find(&users).Error
+	return users, err
+}
+
+// ================================================================
+// РОЛИ ГРУПП
+// ================================================================
+
+// AddRole - выдаёт роль группе (повторная выдача - не ошибка)
+func (r *groupRepository) AddRole(groupID uint, role string) error {
+	grant := domain.GroupRole{GroupID: groupID, Role: role}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&grant).Error
+}
+
+// RemoveRole - отзывает роль у группы
+func (r *groupRepository) RemoveRole(groupID uint, role string) error {
+	result := r.db.Where("group_id = ? AND role = ?", groupID, role).Delete(&domain.GroupRole{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrGroupRecordNotFound
+	}
+	return nil
+}
+
+// RolesForUser - роли, унаследованные пользователем через группы
+// Рекурсивно поднимается от групп пользователя к корню: участник вложенной
+// группы получает роли всех её родителей
+func (r *groupRepository) RolesForUser(userID uint) ([]string, error) {
+	var roles []string
+	err := r.db.Raw(`WITH RECURSIVE chain AS (
+			SELECT g.id, g.parent_id FROM user_groups g
+			JOIN user_group_members m ON m.group_id = g.id
+			WHERE m.user_id = ? AND (? = 0 OR g.organization_id = ?)
+			UNION
+			SELECT p.id, p.parent_id FROM user_groups p JOIN chain c ON p.id = c.parent_id
+		)
+		SELECT DISTINCT r.role FROM user_group_roles r JOIN chain c ON r.group_id = c.id
+		ORDER BY r.role`, userID, r.orgID, r.orgID).
+		Scan(&roles).Error
+	return roles, err
+}
+
+// loadRoles - заполняет Group.Roles одним запросом на все группы
+func (r *groupRepository) loadRoles(groups []*domain.Group) error {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	byID := make(map[uint]*domain.Group, len(groups))
+	ids := make([]uint, 0, len(groups))
+	for _, group := range groups {
+		group.Roles = []string{}
+		byID[group.ID] = group
+		ids = append(ids, group.ID)
+	}
+
+	var grants []domain.GroupRole
+	if err := r.db.Where("group_id IN ?", ids).Order("role").Find(&grants).Error; err != nil {
+		return err
+	}
+	for _, grant := range grants {
+		byID[grant.GroupID].Roles = append(byID[grant.GroupID].Roles, grant.Role)
+	}
+	return nil
+}
+
+// groupNotFound - gorm.ErrRecordNotFound → ErrGroupRecordNotFound
+func groupNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrGroupRecordNotFound
+	}
+	return err
+}
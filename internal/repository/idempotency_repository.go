@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ================================================================
+// IDEMPOTENCY REPOSITORY - Ответы на запросы с Idempotency-Key
+// ================================================================
+// Две реализации (IDEMPOTENCY_STORAGE):
+//   - database - таблица idempotency_keys, общая для всех экземпляров API
+//   - memory - map в памяти процесса (один экземпляр, тесты)
+
+// IdempotencyRepository - хранилище ключей идемпотентности
+type IdempotencyRepository interface {
+	// Acquire - занимает ключ: сохраняет запись "выполняется" (Status 0)
+	// Возвращает nil, если ключ свободен (или его запись устарела к now),
+	// иначе - существующую запись (выполняется или с готовым ответом)
+	Acquire(record *domain.IdempotencyRecord, now time.Time) (*domain.IdempotencyRecord, error)
+
+	// Complete - сохраняет ответ (Status, Headers, Body, Rebuild) занятого ключа
+	// и продлевает запись до ExpiresAt, уточняет Fingerprint (если заданы)
+	Complete(record *domain.IdempotencyRecord) error
+
+	// Extend - продлевает занятость ключа до expiresAt, пока ответ не сохранён
+	Extend(key string, expiresAt time.Time) error
+
+	// Release - освобождает ключ без ответа (запрос можно повторить)
+	Release(key string) error
+
+	// DeleteExpired - удаляет записи, устаревшие к now
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+// ErrIdempotencyKeyBusy - ключ одновременно заняли и освободили другие запросы
+var ErrIdempotencyKeyBusy = errors.New("ключ идемпотентности занят другим запросом")
+
+// NewIdempotencyStorage - хранилище по IDEMPOTENCY_STORAGE
+// nil без ошибки - "off", ключи идемпотентности не поддерживаются
+func NewIdempotencyStorage(cfg *config.Config, db *gorm.DB) (IdempotencyRepository, error) {
+	switch cfg.IdempotencyStorage {
+	case "", "database":
+		return NewIdempotencyRepository(db), nil
+	case "memory":
+		return NewMemoryIdempotencyRepository(), nil
+	case "off":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("неизвестное хранилище ключей идемпотентности IDEMPOTENCY_STORAGE=%q", cfg.IdempotencyStorage)
+	}
+}
+
+// ================================================================
+// DATABASE
+// ================================================================
+
+// idempotencyRepository - реализация с GORM
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository - конструктор
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Acquire - INSERT ... ON CONFLICT DO NOTHING: из параллельных запросов
+// с одним ключом запись создаёт ровно один, остальные читают его запись
+func (r *idempotencyRepository) Acquire(record *domain.IdempotencyRecord, now time.Time) (*domain.IdempotencyRecord, error) {
+	// Устаревшая запись не мешает: ключ можно использовать заново
+	err := r.db.Where("key = ? AND expires_at <= ?", record.Key, now).
+		Delete(&domain.IdempotencyRecord{}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	var existing domain.IdempotencyRecord
+	err = r.db.Where("key = ?", record.Key).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Запись освободили между INSERT и SELECT - клиент повторит запрос
+		return nil, ErrIdempotencyKeyBusy
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// Complete - сохраняет ответ
+func (r *idempotencyRepository) Complete(record *domain.IdempotencyRecord) error {
+	columns := []string{"status", "headers", "body", "rebuild"}
+	if !record.ExpiresAt.IsZero() {
+		columns = append(columns, "expires_at")
+	}
+	if record.Fingerprint != "" {
+		columns = append(columns, "fingerprint")
+	}
+	return r.db.Model(record).Select(columns).Updates(record).Error
+}
+
+// Extend - продлевает запись, пока ответ не сохранён
+func (r *idempotencyRepository) Extend(key string, expiresAt time.Time) error {
+	return r.db.Model(&domain.IdempotencyRecord{}).
+		Where("key = ? AND status = 0", key).
+		Update("expires_at", expiresAt).Error
+}
+
+// Release - удаляет запись, пока ответ не сохранён
+func (r *idempotencyRepository) Release(key string) error {
+	return r.db.Where("key = ? AND status = 0", key).Delete(&domain.IdempotencyRecord{}).Error
+}
+
+// DeleteExpired - удаляет устаревшие записи
+func (r *idempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&domain.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
+
+// ================================================================
+// MEMORY
+// ================================================================
+
+// memoryIdempotencyRepository - записи в памяти процесса
+// Хранятся копии: вызывающий не меняет сохранённую запись
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]domain.IdempotencyRecord
+}
+
+// NewMemoryIdempotencyRepository - конструктор
+// Ключи не видны другим экземплярам API и теряются при перезапуске
+func NewMemoryIdempotencyRepository() IdempotencyRepository {
+	return &memoryIdempotencyRepository{records: map[string]domain.IdempotencyRecord{}}
+}
+
+// Acquire - занимает ключ
+func (r *memoryIdempotencyRepository) Acquire(record *domain.IdempotencyRecord, now time.Time) (*domain.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.records[record.Key]; ok && existing.ExpiresAt.After(now) {
+		return &existing, nil
+	}
+	r.records[record.Key] = *record
+	return nil, nil
+}
+
+// Complete - сохраняет ответ
+func (r *memoryIdempotencyRepository) Complete(record *domain.IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.records[record.Key]; ok {
+		existing.Status = record.Status
+		existing.Headers = record.Headers
+		existing.Body = record.Body
+		existing.Rebuild = record.Rebuild
+		if !record.ExpiresAt.IsZero() {
+			existing.ExpiresAt = record.ExpiresAt
+		}
+		if record.Fingerprint != "" {
+			existing.Fingerprint = record.Fingerprint
+		}
+		r.records[record.Key] = existing
+	}
+	return nil
+}
+
+// Extend - продлевает запись, пока ответ не сохранён
+func (r *memoryIdempotencyRepository) Extend(key string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.records[key]; ok && !existing.Completed() {
+		existing.ExpiresAt = expiresAt
+		r.records[key] = existing
+	}
+	return nil
+}
+
+// Release - удаляет запись, пока ответ не сохранён
+func (r *memoryIdempotencyRepository) Release(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.records[key]; ok && !existing.Completed() {
+		delete(r.records, key)
+	}
+	return nil
+}
+
+// DeleteExpired - удаляет устаревшие записи
+func (r *memoryIdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for key, record := range r.records {
+		if !record.ExpiresAt.After(now) {
+			delete(r.records, key)
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"time"
+
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// ================================================================
+// INVITATION REPOSITORY - Приглашения в организации
+// ================================================================
+
+// InvitationRepository - интерфейс для работы с приглашениями
+type InvitationRepository interface {
+	Create(invitation *domain.Invitation) error
+	Update(invitation *domain.Invitation) error
+
+	// FindByID - приглашение организации (чужое не найдётся)
+	FindByID(orgID, id uint) (*domain.Invitation, error)
+	FindByHash(tokenHash string) (*domain.Invitation, error)
+
+	// FindPending / ListPending - ожидающие ответа приглашения
+	FindPending(orgID uint, email string) (*domain.Invitation, error)
+	ListPending(orgID uint) ([]domain.Invitation, error)
+
+	// Respond - меняет состояние ожидающего приглашения
+	// Возвращает false, если приглашение уже не в состоянии pending
+	Respond(id uint, status string, userID *uint, at time.Time) (bool, error)
+}
+
+// invitationRepository - реализация с GORM
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+// NewInvitationRepository - конструктор
+func NewInvitationRepository(db *gorm.DB) InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+// Create - сохраняет новое приглашение
+func (r *invitationRepository) Create(invitation *domain.Invitation) error {
+	return r.db.Create(invitation).Error
+}
+
+// Update - сохраняет изменения (новый токен и срок при повторной отправке)
+func (r *invitationRepository) Update(invitation *domain.Invitation) error {
+	return r.db.Save(invitation).Error
+}
+
+// FindByID - приглашение по ID в пределах организации
+func (r *invitationRepository) FindByID(orgID, id uint) (*domain.Invitation, error) {
+	var invitation domain.Invitation
+	err := r.db.Where("organization_id = ? AND id = ?", orgID, id).First(&invitation).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &invitation, nil
+}
+
+// FindByHash - приглашение по SHA-256 токена
+func (r *invitationRepository) FindByHash(tokenHash string) (*domain.Invitation, error) {
+	var invitation domain.Invitation
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&invitation).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &invitation, nil
+}
+
+// FindPending - ожидающее приглашение email в организацию
+func (r *invitationRepository) FindPending(orgID uint, email string) (*domain.Invitation, error) {
+	var invitation domain.Invitation
+	err := r.db.Where("organization_id = ? AND email = ? AND status = ?", orgID, email, domain.InvitationStatusPending).
+		First(&invitation).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &invitation, nil
+}
+
+// ListPending - ожидающие приглашения организации (новые первыми)
+// Просроченные тоже попадают в список - их можно отправить повторно или отозвать
+func (r *invitationRepository) ListPending(orgID uint) ([]domain.Invitation, error) {
+	var invitations []domain.Invitation
+	err := r.db.Where("organization_id = ? AND status = ?", orgID, domain.InvitationStatusPending).
+		Order("id DESC").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+// Respond - переводит приглашение из pending в status
+// Условие "status = pending" в одном UPDATE: из двух параллельных ответов засчитывается один
+func (r *invitationRepository) Respond(id uint, status string, userID *uint, at time.Time) (bool, error) {
+	result := r.db.Model(&domain.Invitation{}).
+		Where("id = ? AND status = ?", id, domain.InvitationStatusPending).
+		Updates(map[string]interface{}{
+			"status":           status,
+			"accepted_user_id": userID,
+			"responded_at":     at,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// ================================================================
+// MAGIC LINK REPOSITORY - Токены входа по ссылке
+// ================================================================
+
+// MagicLinkRepository - интерфейс для работы с токенами magic link
+type MagicLinkRepository interface {
+	Create(token *domain.MagicLinkToken) error
+	FindByHash(tokenHash string) (*domain.MagicLinkToken, error)
+	CountSince(email string, since time.Time) (int64, error)
+	MarkUsed(id uint, usedAt time.Time) (bool, error)
+}
+
+// magicLinkRepository - реализация с GORM
+type magicLinkRepository struct {
+	db *gorm.DB
+}
+
+// NewMagicLinkRepository - конструктор
+func NewMagicLinkRepository(db *gorm.DB) MagicLinkRepository {
+	return &magicLinkRepository{db: db}
+}
+
+// Create - сохраняет новый токен
+func (r *magicLinkRepository) Create(token *domain.MagicLinkToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByHash - ищет токен по SHA-256
+func (r *magicLinkRepository) FindByHash(tokenHash string) (*domain.MagicLinkToken, error) {
+	var token domain.MagicLinkToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("ссылка не найдена")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// CountSince - сколько ссылок запрошено для email начиная с since
+// Используется для ограничения частоты запросов
+func (r *magicLinkRepository) CountSince(email string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.MagicLinkToken{}).
+		Where("email = ? AND created_at >= ?", email, since).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkUsed - помечает токен использованным
+// Возвращает false, если токен уже использован (например, параллельным запросом)
+// Условие "used_at IS NULL" в одном UPDATE гарантирует одноразовость без блокировок
+func (r *magicLinkRepository) MarkUsed(id uint, usedAt time.Time) (bool, error) {
+	result := r.db.Model(&domain.MagicLinkToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
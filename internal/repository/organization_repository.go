@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"errors"
+
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// ================================================================
+// ORGANIZATION REPOSITORY - Организации и их участники
+// ================================================================
+
+// OrganizationRepository - интерфейс для работы с организациями
+type OrganizationRepository interface {
+	Create(org *domain.Organization, ownerID uint) error
+	FindByID(id uint) (*domain.Organization, error)
+	FindBySlug(slug string) (*domain.Organization, error)
+
+	// Участники
+	AddMember(member *domain.Membership) error
+	FindMember(orgID, userID uint) (*domain.Membership, error)
+	ListMembers(orgID uint) ([]domain.Membership, error)
+	UpdateMemberRole(orgID, userID uint, role string) error
+	RemoveMember(orgID, userID uint) error
+	CountMembersWithRole(orgID uint, role string) (int64, error)
+}
+
+// ErrOrganizationRecordNotFound - организация или участник не найдены
+var ErrOrganizationRecordNotFound = errors.New("запись не найдена")
+
+// organizationRepository - реализация с GORM
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+// NewOrganizationRepository - конструктор
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+// Create - создаёт организацию и делает ownerID её владельцем
+// Организация без владельца не создаётся (одна транзакция)
+func (r *organizationRepository) Create(org *domain.Organization, ownerID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&domain.Membership{
+			OrganizationID: org.ID,
+			UserID:         ownerID,
+			Role:           domain.OrgRoleOwner,
+		}).Error
+	})
+}
+
+// FindByID - организация по ID
+func (r *organizationRepository) FindByID(id uint) (*domain.Organization, error) {
+	var org domain.Organization
+	if err := r.db.First(&org, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &org, nil
+}
+
+// FindBySlug - организация по короткому имени (поддомен, заголовок)
+func (r *organizationRepository) FindBySlug(slug string) (*domain.Organization, error) {
+	var org domain.Organization
+	if err := r.db.Where("slug = ?", slug).First(&org).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &org, nil
+}
+
+// AddMember - добавляет пользователя в организацию
+func (r *organizationRepository) AddMember(member *domain.Membership) error {
+	return r.db.Create(member).Error
+}
+
+// FindMember - членство пользователя в организации
+func (r *organizationRepository) FindMember(orgID, userID uint) (*domain.Membership, error) {
+	var member domain.Membership
+	err := r.db.Where("organization_id = ? AND user_id = ?", orgID, userID).First(&member).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &member, nil
+}
+
+// ListMembers - участники организации с данными пользователей
+// Удалённые пользователи (soft delete) в список не попадают
+func (r *organizationRepository) ListMembers(orgID uint) ([]domain.Membership, error) {
+	var members []domain.Membership
+	err := r.db.Joins("User").
+		Where("organization_members.organization_id = ?", orgID).
+		Order("organization_members.id").
+		Find(&members).Error
+	return members, err
+}
+
+// UpdateMemberRole - меняет роль участника
+func (r *organizationRepository) UpdateMemberRole(orgID, userID uint, role string) error {
+	result := r.db.Model(&domain.Membership{}).
+		Where("organization_id = ? AND user_id = ?", orgID, userID).
+		Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrganizationRecordNotFound
+	}
+	return nil
+}
+
+// RemoveMember - исключает пользователя из организации
+// Аккаунт не удаляется: он остаётся в своей организации
+func (r *organizationRepository) RemoveMember(orgID, userID uint) error {
+	result := r.db.Where("organization_id = ? AND user_id = ?", orgID, userID).Delete(&domain.Membership{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrganizationRecordNotFound
+	}
+	return nil
+}
+
+// CountMembersWithRole - сколько участников с ролью (нельзя остаться без владельца)
+func (r *organizationRepository) CountMembersWithRole(orgID uint, role string) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.Membership{}).
+		Where("organization_id = ? AND role = ?", orgID, role).
+		Count(&count).Error
+	return count, err
+}
+
+// notFound - gorm.ErrRecordNotFound → ErrOrganizationRecordNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrOrganizationRecordNotFound
+	}
+	return err
+}
+
+// ================================================================
+// МИГРАЦИЯ - Переход к организациям
+// ================================================================
+
+// migrateOrganizations - выполняется после AutoMigrate:
+//  1. Удаляет старый глобальный уникальный индекс email
+//     (теперь email уникален в пределах организации)
+//  2. Создаёт организацию по умолчанию
+//  3. Переносит в неё пользователей, созданных до появления организаций
+func migrateOrganizations(db *gorm.DB) error {
+	if db.Migrator().HasIndex(&domain.User{}, "idx_users_email") {
+		if err := db.Migrator().DropIndex(&domain.User{}, "idx_users_email"); err != nil {
+			return err
+		}
+	}
+
+	org := domain.Organization{Slug: domain.DefaultOrganizationSlug, Name: "Default"}
+	if err := db.Where("slug = ?", org.Slug).FirstOrCreate(&org).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE users SET organization_id = ? WHERE organization_id = 0", org.ID).Error; err != nil {
+			return err
+		}
+
+		// Администраторы сервиса становятся администраторами организации
+		return tx.Exec(`
+			INSERT INTO organization_members (organization_id, user_id, role, created_at)
+			SELECT ?, u.id, CASE WHEN u.role = 'admin' THEN ? ELSE ? END, NOW()
+			FROM users u
+			WHERE u.organization_id = ?
+			  AND NOT EXISTS (SELECT 1 FROM organization_members m WHERE m.organization_id = ? AND m.user_id = u.id)`,
+			org.ID, domain.OrgRoleAdmin, domain.OrgRoleMember, org.ID, org.ID).Error
+	})
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// ================================================================
+// SESSION REPOSITORY - Сеансы пользователей
+// ================================================================
+
+// SessionRepository - интерфейс для работы с сеансами
+type SessionRepository interface {
+	Create(session *domain.Session) error
+	FindByID(id string) (*domain.Session, error)
+	FindActiveByUserID(userID uint, now time.Time) ([]domain.Session, error)
+	Touch(id string, seenAt time.Time) error
+	Revoke(id string, userID uint, at time.Time) (bool, error)
+	RevokeAllExcept(userID uint, keepID string, at time.Time) (int64, error)
+}
+
+// sessionRepository - реализация с GORM
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository - конструктор
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+// Create - сохраняет новый сеанс
+func (r *sessionRepository) Create(session *domain.Session) error {
+	return r.db.Create(session).Error
+}
+
+// FindByID - ищет сеанс по ID (включая завершённые)
+func (r *sessionRepository) FindByID(id string) (*domain.Session, error) {
+	var session domain.Session
+	err := r.db.Where("id = ?", id).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("сеанс не найден")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindActiveByUserID - активные сеансы пользователя, последние использованные первыми
+func (r *sessionRepository) FindActiveByUserID(userID uint, now time.Time) ([]domain.Session, error) {
+	var sessions []domain.Session
+	err := r.db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Touch - обновляет время последнего запроса
+func (r *sessionRepository) Touch(id string, seenAt time.Time) error {
+	return r.db.Model(&domain.Session{}).
+		Where("id = ?", id).
+		Update("last_seen_at", seenAt).Error
+}
+
+// Revoke - завершает один сеанс пользователя
+// Возвращает false, если сеанса нет, он чужой или уже завершён
+func (r *sessionRepository) Revoke(id string, userID uint, at time.Time) (bool, error) {
+	result := r.db.Model(&domain.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeAllExcept - завершает все сеансы пользователя, кроме keepID
+// keepID = "" - завершает все сеансы
+// Возвращает количество завершённых сеансов
+func (r *sessionRepository) RevokeAllExcept(userID uint, keepID string, at time.Time) (int64, error) {
+	result := r.db.Model(&domain.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+		Update("revoked_at", at)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ================================================================
+// USER IMPORT REPOSITORY - Задачи импорта и пакетная запись пользователей
+// ================================================================
+
+// UserImportRepository - интерфейс для задач импорта пользователей
+type UserImportRepository interface {
+	Create(job *domain.UserImport) error
+	FindByID(id uint) (*domain.UserImport, error)
+	Update(job *domain.UserImport) error
+
+	// ClaimPending - забирает самую старую задачу pending (или processing, начатую
+	// раньше staleBefore - обработчик упал) и переводит её в processing
+	// Несколько экземпляров сервиса не получат одну и ту же задачу (SKIP LOCKED)
+	// Возвращает ErrUserImportRecordNotFound, если задач нет
+	ClaimPending(now, staleBefore time.Time) (*domain.UserImport, error)
+
+	// SaveBatch - создаёт новых (ID 0) и обновляет существующих пользователей
+	// одной транзакцией: ошибка любого из них отменяет всю пачку
+	// Новые пользователи при orgID != 0 становятся участниками организации
+	// Существующие обновляются с проверкой версии (ErrUserVersionConflict)
+	SaveBatch(orgID uint, users []*domain.User) error
+}
+
+// ErrUserImportRecordNotFound - задача импорта не найдена
+var ErrUserImportRecordNotFound = errors.New("запись не найдена")
+
+// userImportRepository - реализация с GORM
+type userImportRepository struct {
+	db *gorm.DB
+}
+
+// NewUserImportRepository - конструктор
+func NewUserImportRepository(db *gorm.DB) UserImportRepository {
+	return &userImportRepository{db: db}
+}
+
+// Create - сохраняет новую задачу
+func (r *userImportRepository) Create(job *domain.UserImport) error {
+	return r.db.Create(job).Error
+}
+
+// FindByID - задача по ID
+func (r *userImportRepository) FindByID(id uint) (*domain.UserImport, error) {
+	var job domain.UserImport
+	if err := r.db.First(&job, id).Error; err != nil {
+		return nil, importNotFound(err)
+	}
+	return &job, nil
+}
+
+// Update - сохраняет состояние задачи (прогресс, счётчики, ошибки строк)
+func (r *userImportRepository) Update(job *domain.UserImport) error {
+	return r.db.Save(job).Error
+}
+
+// ClaimPending - выбор и блокировка задачи в одной транзакции
+// Генерирует SQL: SELECT * FROM user_imports WHERE status = 'pending' OR (...) ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED
+func (r *userImportRepository) ClaimPending(now, staleBefore time.Time) (*domain.UserImport, error) {
+	var job domain.UserImport
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? OR (status = ? AND started_at < ?)",
+				domain.UserImportStatusPending, domain.UserImportStatusProcessing, staleBefore).
+			Order("id").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+
+		job.Status = domain.UserImportStatusProcessing
+		job.StartedAt = &now
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":     job.Status,
+			"started_at": job.StartedAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, importNotFound(err)
+	}
+	return &job, nil
+}
+
+// SaveBatch - пачка пользователей в одной транзакции
+// При ошибке ID новых пользователей и версии существующих возвращаются
+// к прежним значениям: пачку можно записать повторно (например, по одному)
+func (r *userImportRepository) SaveBatch(orgID uint, users []*domain.User) error {
+	ids := make([]uint, len(users))
+	versions := make([]uint, len(users))
+	for i, user := range users {
+		ids[i], versions[i] = user.ID, user.Version
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for i, user := range users {
+			withEmailIndex(user)
+
+			// === НОВЫЙ ПОЛЬЗОВАТЕЛЬ (и членство в организации) ===
+			if ids[i] == 0 {
+				user.OrganizationID = orgID
+				if err := tx.Create(user).Error; err != nil {
+					return err
+				}
+				if orgID == 0 {
+					continue
+				}
+				err := tx.Create(&domain.Membership{
+					OrganizationID: orgID,
+					UserID:         user.ID,
+					Role:           domain.OrgRoleMember,
+				}).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			// === СУЩЕСТВУЮЩИЙ: UPDATE с проверкой версии (как userRepository.Update) ===
+			user.Version++
+			result := tx.Model(user).Where("users.version = ?", versions[i]).Select("*").Updates(user)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrUserVersionConflict
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		for i, user := range users {
+			user.ID, user.Version = ids[i], versions[i]
+		}
+	}
+	return err
+}
+
+// importNotFound - ErrUserImportRecordNotFound вместо gorm.ErrRecordNotFound
+func importNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserImportRecordNotFound
+	}
+	return err
+}
@@ -2,8 +2,11 @@ package repository
 
 import (
 	"errors"
+	"strings"
+	"time"
 
 	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/fieldcrypt"
 
 	"gorm.io/gorm" // GORM ORM
 )
@@ -20,8 +23,45 @@ type UserRepository interface {
 	FindByID(id uint) (*domain.User, error)
 	FindByEmail(email string) (*domain.User, error)
 	FindAll() ([]domain.User, error)
+
+	// Update - сохраняет пользователя, если его версия в БД всё ещё user.Version
+	// (иначе ErrUserVersionConflict) и увеличивает версию
+	// ErrUserEmailTaken - новый email уже занят живым аккаунтом той же организации
 	Update(user *domain.User) error
-	Delete(id uint) error
+
+	// Delete - soft delete; version != 0 - только если версия в БД совпадает
+	Delete(id uint, version uint) error
+
+	// FindByGroup - участники группы groupID и всех вложенных в неё групп
+	FindByGroup(groupID uint) ([]domain.User, error)
+
+	// FindByAttributes - пользователи, у которых атрибуты содержат все пары filter
+	FindByAttributes(filter domain.UserAttributes) ([]domain.User, error)
+
+	// FindPage - до limit пользователей с ID больше afterID по возрастанию ID
+	// (keyset пагинация: следующая страница - afterID = ID последнего)
+	FindPage(filter domain.UserFilter, afterID uint, limit int) ([]domain.User, error)
+
+	// FindDeleted - удалённые (soft delete) пользователи, недавно удалённые первыми
+	FindDeleted() ([]domain.User, error)
+
+	// Restore - снимает отметку удаления и отзывает выданные до удаления токены
+	// ErrDeletedUserNotFound - пользователь не удалён или не существует
+	// ErrUserEmailTaken - email уже занят живым аккаунтом той же организации
+	Restore(id uint) (*domain.User, error)
+
+	// Purge - окончательно удаляет ранее удалённого пользователя вместе со связанными записями
+	// Возвращает и имена файлов архивов выгрузки (DataExport.FileName): записи
+	// о них удалены, сами файлы удаляет вызывающий
+	Purge(id uint) (*domain.User, []string, error)
+
+	// PurgeDeletedBefore - окончательно удаляет пользователей, удалённых раньше cutoff
+	// Возвращает число пользователей и имена файлов их архивов выгрузки
+	PurgeDeletedBefore(cutoff time.Time) (int64, []string, error)
+
+	// ForTenant - копия репозитория, все запросы которой ограничены
+	// участниками организации orgID (0 - без ограничения)
+	ForTenant(orgID uint) UserRepository
 }
 
 // ================================================================
@@ -31,7 +71,8 @@ type UserRepository interface {
 // userRepository - приватная структура, реализующая UserRepository
 // Содержит подключение к БД через GORM
 type userRepository struct {
-	db *gorm.DB // Подключение к БД
+	db    *gorm.DB // Подключение к БД
+	orgID uint     // Текущая организация (0 - все пользователи)
 }
 
 // NewUserRepository - конструктор для создания нового repository
@@ -41,6 +82,24 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
+// ForTenant - репозиторий организации orgID
+// Дешёвая операция: создаётся на каждый запрос
+func (r *userRepository) ForTenant(orgID uint) UserRepository {
+	return &userRepository{db: r.db, orgID: orgID}
+}
+
+// scoped - запрос к users, ограниченный текущей организацией
+// Генерирует SQL: ... WHERE users.id IN (SELECT user_id FROM organization_members WHERE organization_id = ?)
+// Участник - не только аккаунт, созданный в организации, но и принявший приглашение
+func (r *userRepository) scoped() *gorm.DB {
+	if r.orgID == 0 {
+		return r.db
+	}
+
+	members := r.db.Model(&domain.Membership{}).Select("user_id").Where("organization_id = ?", r.orgID)
+	return r.db.Where("users.id IN (?)", members)
+}
+
 // ================================================================
 // CRUD OPERATIONS - Операции с базой данных
 // ================================================================
@@ -55,12 +114,32 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 // - Генерирует ID (автоинкремент)
 // - Устанавливает CreatedAt и UpdatedAt
 // - Возвращает созданную запись с ID в ту же структуру user
+//
+// В репозитории организации пользователь создаётся в ней
+// и сразу становится её участником (роль member)
 func (r *userRepository) Create(user *domain.User) error {
-	// db.Create() - вставляет новую запись в БД
-	// Генерирует SQL: INSERT INTO users (email, name, password, ...) VALUES (?, ?, ?, ...)
-	// После выполнения user.ID будет содержать ID из БД!
-	// .Error - возвращает ошибку (если есть)
-	return r.db.Create(user).Error
+	if r.orgID == 0 {
+		// db.Create() - вставляет новую запись в БД
+		// Генерирует SQL: INSERT INTO users (email, name, password, ...) VALUES (?, ?, ?, ...)
+		// После выполнения user.ID будет содержать ID из БД!
+		// .Error - возвращает ошибку (если есть)
+		withEmailIndex(user)
+		return r.db.Create(user).Error
+	}
+
+	// Пользователь и членство - в одной транзакции
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		user.OrganizationID = r.orgID
+		withEmailIndex(user)
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&domain.Membership{
+			OrganizationID: r.orgID,
+			UserID:         user.ID,
+			Role:           domain.OrgRoleMember,
+		}).Error
+	})
 }
 
 // FindByID - ищет пользователя по ID
@@ -78,7 +157,7 @@ func (r *userRepository) FindByID(id uint) (*domain.User, error) {
 	// &user - указатель на структуру, куда GORM запишет результат
 	// id - значение для поиска (подставится вместо ?)
 	// .Error - ошибка выполнения
-	err := r.db.First(&user, id).Error
+	err := r.scoped().First(&user, id).Error
 	
 	// Проверяем специальную ошибку "запись не найдена"
 	if err == gorm.ErrRecordNotFound {
@@ -95,6 +174,10 @@ func (r *userRepository) FindByID(id uint) (*domain.User, error) {
 	return &user, nil
 }
 
+// ErrUserNotFound - пользователя с таким email нет
+// Позволяет отличить "не найден" от ошибки БД
+var ErrUserNotFound = errors.New("пользователь с таким email не найден")
+
 // FindByEmail - ищет пользователя по email адресу
 // Используется для аутентификации (login)
 // Параметры:
@@ -109,12 +192,13 @@ func (r *userRepository) FindByEmail(email string) (*domain.User, error) {
 	// db.Where() - добавляет условие WHERE в запрос
 	// Генерирует SQL: SELECT * FROM users WHERE email = ? AND deleted_at IS NULL
 	// "email = ?" - условие (? заменится на значение email)
+	// При шифровании полей поиск идёт по blind index (см. whereEmail)
 	// .First(&user) - выполняет запрос и сканирует результат в user
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := whereEmail(r.scoped(), email).First(&user).Error
 	
 	// Проверяем, найден ли пользователь
 	if err == gorm.ErrRecordNotFound {
-		return nil, errors.New("пользователь с таким email не найден")
+		return nil, ErrUserNotFound
 	}
 	
 	if err != nil {
@@ -136,7 +220,7 @@ func (r *userRepository) FindAll() ([]domain.User, error) {
 	// Генерирует SQL: SELECT * FROM users WHERE deleted_at IS NULL
 	// &users - указатель на slice, куда GORM запишет все найденные записи
 	// GORM автоматически исключает "удалённые" записи (где deleted_at не NULL)
-	err := r.db.Find(&users).Error
+	err := r.scoped().Find(&users).Error
 	
 	// Если ошибка - возвращаем nil и ошибку
 	if err != nil {
@@ -154,28 +238,54 @@ func (r *userRepository) FindAll() ([]domain.User, error) {
 // Возвращает:
 //   - error: ошибка обновления
 func (r *userRepository) Update(user *domain.User) error {
-	// db.Save() - обновляет ВСЕ поля записи
-	// Генерирует SQL: UPDATE users SET email=?, name=?, updated_at=? WHERE id=?
+	// Оптимистичная блокировка: обновляем, только если запись не изменилась
+	// с момента чтения. Проверка и запись - один UPDATE, без гонки между ними
+	// Генерирует SQL: UPDATE users SET email=?, name=?, ..., version=5, updated_at=?
+	//                 WHERE id=? AND users.version = 4 AND deleted_at IS NULL
 	// GORM автоматически:
 	// 1. Обновляет UpdatedAt на текущее время
 	// 2. Использует user.ID для поиска записи
-	// 3. Обновляет все поля (кроме ID, CreatedAt, DeletedAt)
+	// 3. Select("*") - обновляет все поля, включая нулевые значения (как Save)
 	//
-	// Альтернатива - db.Updates() для обновления только изменённых полей:
-	// r.db.Model(&domain.User{}).Where("id = ?", user.ID).Updates(user)
-	return r.db.Save(user).Error
+	// ВАЖНО: Save() здесь не подходит - без совпавших строк он делает INSERT.
+	// Условие организации проверяется заранее
+	if r.orgID != 0 {
+		if _, err := r.FindByID(user.ID); err != nil {
+			return err
+		}
+	}
+	withEmailIndex(user)
+
+	version := user.Version
+	user.Version++
+	result := r.db.Model(user).Where("users.version = ?", version).Select("*").Updates(user)
+	if result.Error != nil {
+		user.Version = version
+		// Уникальные индексы users - только по email: занял параллельный запрос
+		if translator, ok := r.db.Dialector.(gorm.ErrorTranslator); ok && errors.Is(translator.Translate(result.Error), gorm.ErrDuplicatedKey) {
+			return ErrUserEmailTaken
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Запись изменили (или удалили) после чтения
+		user.Version = version
+		return ErrUserVersionConflict
+	}
+	return nil
 }
 
 // Delete - "мягко" удаляет пользователя (soft delete)
 // Параметры:
 //   - id: ID пользователя для удаления
+//   - version: ожидаемая версия (0 - без проверки)
 // Возвращает:
 //   - error: ошибка удаления
 //
 // ВАЖНО: Это НЕ физическое удаление!
 // GORM просто устанавливает deleted_at = NOW()
 // Запись остаётся в БД, но игнорируется во всех запросах
-func (r *userRepository) Delete(id uint) error {
+func (r *userRepository) Delete(id uint, version uint) error {
 	// db.Delete() - "мягкое" удаление (soft delete)
 	// Генерирует SQL: UPDATE users SET deleted_at = NOW() WHERE id = ?
 	// &domain.User{} - пустая структура (нужна только для определения таблицы)
@@ -183,7 +293,12 @@ func (r *userRepository) Delete(id uint) error {
 	//
 	// Если нужно ФИЗИЧЕСКОЕ удаление (hard delete):
 	// r.db.Unscoped().Delete(&domain.User{}, id)
-	result := r.db.Delete(&domain.User{}, id)
+	query := r.scoped()
+	if version != 0 {
+		// If-Match: удаляем только ту версию, которую видел клиент
+		query = query.Where("users.version = ?", version)
+	}
+	result := query.Delete(&domain.User{}, id)
 	
 	// Проверяем ошибку выполнения
 	if result.Error != nil {
@@ -193,7 +308,11 @@ func (r *userRepository) Delete(id uint) error {
 	// Проверяем, была ли затронута хотя бы одна строка
 	// RowsAffected - количество затронутых строк
 	// Если 0 - пользователь с таким ID не существует (или уже удалён)
+	// С проверкой версии - возможно, его изменили после чтения
 	if result.RowsAffected == 0 {
+		if version != 0 {
+			return ErrUserVersionConflict
+		}
 		return errors.New("пользователь не найден")
 	}
 	
@@ -211,11 +330,68 @@ func (r *userRepository) FindByRole(role string) ([]domain.User, error) {
 	var users []domain.User
 	
 	// WHERE с условием по role
-	err := r.db.Where("role = ?", role).Find(&users).Error
+	err := r.scoped().Where("role = ?", role).Find(&users).Error
 	
 	return users, err
 }
 
+// FindByGroup - находит участников группы, включая участников вложенных групп
+// Генерирует SQL: ... WHERE users.id IN (WITH RECURSIVE subtree AS (...)
+//   SELECT user_id FROM user_group_members WHERE group_id IN (SELECT id FROM subtree))
+// Группа другой организации даёт пустой список
+func (r *userRepository) FindByGroup(groupID uint) ([]domain.User, error) {
+	var users []domain.User
+	
+	members := "SELECT user_id FROM user_group_members WHERE group_id IN (" + groupSubtreeSQL + ")"
+	err := r.scoped().Where("users.id IN ("+members+")", groupID, r.orgID, r.orgID).
+		Order("users.id").
+		Find(&users).Error
+	
+	return users, err
+}
+
+// FindByAttributes - поиск по значениям атрибутов
+// Генерирует SQL: ... WHERE users.attributes @> '{"department":"sales"}'
+// Оператор @> (содержит) использует GIN индекс idx_users_attributes
+func (r *userRepository) FindByAttributes(filter domain.UserAttributes) ([]domain.User, error) {
+	var users []domain.User
+
+	value, err := filter.Value()
+	if err != nil {
+		return nil, err
+	}
+	err = r.scoped().Where("users.attributes @> ?::jsonb", value).
+		Order("users.id").
+		Find(&users).Error
+
+	return users, err
+}
+
+// FindPage - страница пользователей для потоковой выгрузки
+// Генерирует SQL: ... WHERE users.id > ? [AND users.id IN (группа)] [AND users.attributes @> ?]
+//   ORDER BY users.id LIMIT ?
+// В отличие от OFFSET, каждая страница читается по первичному ключу
+// за одно и то же время, сколько бы страниц ни было до неё
+func (r *userRepository) FindPage(filter domain.UserFilter, afterID uint, limit int) ([]domain.User, error) {
+	var users []domain.User
+
+	query := r.scoped().Where("users.id > ?", afterID)
+	if filter.GroupID != 0 {
+		members := "SELECT user_id FROM user_group_members WHERE group_id IN (" + groupSubtreeSQL + ")"
+		query = query.Where("users.id IN ("+members+")", filter.GroupID, r.orgID, r.orgID)
+	}
+	if len(filter.Attributes) > 0 {
+		value, err := filter.Attributes.Value()
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("users.attributes @> ?::jsonb", value)
+	}
+	err := query.Order("users.id").Limit(limit).Find(&users).Error
+
+	return users, err
+}
+
 // CountAll - подсчитывает общее количество пользователей
 func (r *userRepository) CountAll() (int64, error) {
 	var count int64
@@ -223,8 +399,221 @@ func (r *userRepository) CountAll() (int64, error) {
 	// db.Model() - указываем модель
 	// Count() - подсчитывает количество записей
 	// Генерирует SQL: SELECT COUNT(*) FROM users WHERE deleted_at IS NULL
-	err := r.db.Model(&domain.User{}).Count(&count).Error
+	err := r.scoped().Model(&domain.User{}).Count(&count).Error
 	
 	return count, err
 }
 
+
+// ================================================================
+// УДАЛЁННЫЕ ПОЛЬЗОВАТЕЛИ - Восстановление и окончательное удаление
+// ================================================================
+
+var (
+	// ErrDeletedUserNotFound - среди удалённых пользователей нет такого ID
+	ErrDeletedUserNotFound = errors.New("удалённый пользователь не найден")
+
+	// ErrUserEmailTaken - email восстанавливаемого аккаунта уже занят
+	ErrUserEmailTaken = errors.New("email уже используется другим аккаунтом")
+
+	// ErrUserVersionConflict - пользователя изменили после того, как его прочитали
+	ErrUserVersionConflict = errors.New("пользователь изменён другим запросом")
+)
+
+// userReferenceTables - таблицы, строки которых принадлежат пользователю (колонка user_id)
+// Удаляются вместе с пользователем при окончательном удалении.
+// Журнал событий (audit_events) не трогаем: записи связаны цепочкой хешей
+// Файлы архивов data_exports удаляет service по именам из purgeUsers
+var userReferenceTables = []string{
+	"sessions",
+	"credentials",
+	"webauthn_challenges",
+	"magic_link_tokens",
+	"organization_members",
+	"user_group_members",
+	"user_status_changes",
+	"data_exports",
+}
+
+// deleted - запрос только к удалённым пользователям текущей организации
+// db - подключение или транзакция
+// Обезличенные записи (status = 'erased', см. ErasureRepository) сюда не входят:
+// восстанавливать в них нечего, а удалять нельзя - на них ссылается журнал
+// Генерирует SQL: SELECT * FROM users WHERE users.deleted_at IS NOT NULL AND users.status <> 'erased' [AND users.id IN (...)]
+func (r *userRepository) deleted(db *gorm.DB) *gorm.DB {
+	if r.orgID != 0 {
+		members := db.Model(&domain.Membership{}).Select("user_id").Where("organization_id = ?", r.orgID)
+		db = db.Where("users.id IN (?)", members)
+	}
+	return db.Unscoped().Where("users.deleted_at IS NOT NULL AND users.status <> ?", domain.UserStatusErased)
+}
+
+// FindDeleted - удалённые пользователи (последние удалённые первыми)
+func (r *userRepository) FindDeleted() ([]domain.User, error) {
+	var users []domain.User
+	err := r.deleted(r.db).Order("users.deleted_at DESC").Find(&users).Error
+	return users, err
+}
+
+// Restore - восстановление удалённого пользователя
+// Email проверяется заново: пока аккаунт был удалён, адрес мог занять новый пользователь
+func (r *userRepository) Restore(id uint) (*domain.User, error) {
+	var user domain.User
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := r.deleted(tx).First(&user, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDeletedUserNotFound
+			}
+			return err
+		}
+
+		var taken int64
+		err := whereEmail(tx.Model(&domain.User{}).Where("organization_id = ?", user.OrganizationID), user.Email).
+			Count(&taken).Error
+		if err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrUserEmailTaken
+		}
+
+		// Токены, выданные до удаления, не должны снова заработать
+		user.DeletedAt = gorm.DeletedAt{}
+		user.TokenVersion++
+		user.Version++
+		return tx.Unscoped().Model(&domain.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"deleted_at":    nil,
+			"token_version": user.TokenVersion,
+			"version":       gorm.Expr("version + 1"),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Purge - окончательное удаление одного удалённого пользователя
+// Живого пользователя сначала нужно удалить обычным Delete
+func (r *userRepository) Purge(id uint) (*domain.User, []string, error) {
+	var user domain.User
+	var exports []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := r.deleted(tx).First(&user, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDeletedUserNotFound
+			}
+			return err
+		}
+
+		var err error
+		exports, err = purgeUsers(tx, []uint{user.ID})
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &user, exports, nil
+}
+
+// PurgeDeletedBefore - окончательное удаление всех пользователей, удалённых раньше cutoff
+// Без ограничения организацией: вызывается фоновой задачей
+func (r *userRepository) PurgeDeletedBefore(cutoff time.Time) (int64, []string, error) {
+	var purged int64
+	var exports []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		err := tx.Unscoped().Model(&domain.User{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ? AND status <> ?", cutoff, domain.UserStatusErased).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		purged = int64(len(ids))
+		exports, err = purgeUsers(tx, ids)
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return purged, exports, nil
+}
+
+// purgeUsers - удаляет пользователей и принадлежащие им записи
+// Ссылки из чужих записей (принятое приглашение) обнуляются
+// Возвращает имена файлов архивов выгрузки (как ErasureRepository.Erase)
+func purgeUsers(tx *gorm.DB, ids []uint) ([]string, error) {
+	var exports []string
+	err := tx.Model(&domain.DataExport{}).
+		Where("user_id IN ? AND file_name <> ''", ids).
+		Pluck("file_name", &exports).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range userReferenceTables {
+		if err := tx.Exec("DELETE FROM "+table+" WHERE user_id IN ?", ids).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Model(&domain.Invitation{}).
+		Where("accepted_user_id IN ?", ids).
+		Update("accepted_user_id", nil).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return exports, tx.Unscoped().Delete(&domain.User{}, ids).Error
+}
+
+// migrateLiveEmailIndex - делает индекс (organization_id, email) частичным
+// AutoMigrate не пересоздаёт существующий индекс, поэтому в базах, созданных
+// до появления условия deleted_at IS NULL, индекс пересоздаётся здесь
+func migrateLiveEmailIndex(db *gorm.DB) error {
+	const index = "idx_users_organization_email"
+
+	var definition string
+	err := db.Raw("SELECT indexdef FROM pg_indexes WHERE tablename = 'users' AND indexname = ?", index).
+		Scan(&definition).Error
+	if err != nil || definition == "" || strings.Contains(strings.ToUpper(definition), " WHERE ") {
+		return err
+	}
+
+	if err := db.Migrator().DropIndex(&domain.User{}, index); err != nil {
+		return err
+	}
+	return db.Migrator().CreateIndex(&domain.User{}, index)
+}
+
+// migrateAttributesIndex - GIN индекс по users.attributes
+// Тег gorm не задаёт класс операторов: jsonb_path_ops меньше стандартного
+// jsonb_ops и поддерживает @>, которым FindByAttributes фильтрует атрибуты
+func migrateAttributesIndex(db *gorm.DB) error {
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_users_attributes ON users USING GIN (attributes jsonb_path_ops)").Error
+}
+
+// ================================================================
+// ШИФРОВАНИЕ ПОЛЕЙ - Поиск по зашифрованному email
+// ================================================================
+
+// withEmailIndex - пересчитывает blind index перед записью пользователя
+// Без настроенного шифрования индекс не заполняется
+func withEmailIndex(user *domain.User) {
+	if enc := fieldcrypt.Active(); enc != nil {
+		user.EmailIndex = enc.BlindIndex(user.Email)
+	}
+}
+
+// whereEmail - условие точного поиска по email
+// Зашифрованный email сравнить в SQL нельзя (у каждой записи свой шифртекст),
+// поэтому сравнивается blind index. Записи, ещё не обработанные
+// перешифрованием, хранят email открытым текстом - для них остаётся email = ?
+// Генерирует SQL: ... WHERE (users.email_index = ? OR users.email = ?)
+func whereEmail(db *gorm.DB, email string) *gorm.DB {
+	if enc := fieldcrypt.Active(); enc != nil {
+		return db.Where("(users.email_index = ? OR users.email = ?)", enc.BlindIndex(email), email)
+	}
+	return db.Where("users.email = ?", email)
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"time"
+
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// ================================================================
+// USER STATUS REPOSITORY - Статусы аккаунтов и их история
+// ================================================================
+
+// UserStatusRepository - интерфейс для изменения статуса и истории
+type UserStatusRepository interface {
+	// Change - сохраняет новый статус пользователя и запись истории в одной транзакции
+	// Сохраняются поля Status, StatusReason и SuspendedUntil (версия пользователя
+	// увеличивается); revokeTokens - увеличить и token_version
+	Change(user *domain.User, change *domain.UserStatusChange, revokeTokens bool) error
+
+	// History - история статусов пользователя, новые изменения первыми
+	History(userID uint) ([]domain.UserStatusChange, error)
+
+	// FindExpiredSuspensions - заблокированные пользователи, чей срок блокировки истёк к now
+	// Без ограничения организацией: вызывается фоновой задачей
+	FindExpiredSuspensions(now time.Time) ([]domain.User, error)
+}
+
+// userStatusRepository - реализация с GORM
+type userStatusRepository struct {
+	db *gorm.DB
+}
+
+// NewUserStatusRepository - конструктор
+func NewUserStatusRepository(db *gorm.DB) UserStatusRepository {
+	return &userStatusRepository{db: db}
+}
+
+// Change - обновляет статус и добавляет запись в историю
+// Обновляются только поля статуса: Save() перезаписал бы изменения профиля,
+// сделанные параллельно. token_version увеличивается в SQL, а не записывается
+// из памяти: иначе параллельная смена пароля или отзыв сеансов были бы потеряны
+// и старые токены снова заработали бы
+func (r *userStatusRepository) Change(user *domain.User, change *domain.UserStatusChange, revokeTokens bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		columns := map[string]interface{}{
+			"status":          user.Status,
+			"status_reason":   user.StatusReason,
+			"suspended_until": user.SuspendedUntil,
+			"version":         gorm.Expr("version + 1"), // ETag пользователя меняется
+		}
+		if revokeTokens {
+			columns["token_version"] = gorm.Expr("token_version + 1")
+		}
+		err := tx.Model(&domain.User{}).Where("id = ?", user.ID).Updates(columns).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+}
+
+// History - изменения статуса пользователя (новые первыми)
+func (r *userStatusRepository) History(userID uint) ([]domain.UserStatusChange, error) {
+	var changes []domain.UserStatusChange
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Find(&changes).Error
+	return changes, err
+}
+
+// FindExpiredSuspensions - блокировки с истёкшим сроком
+// Генерирует SQL: SELECT * FROM users WHERE status = 'suspended' AND suspended_until <= ? AND deleted_at IS NULL
+func (r *userStatusRepository) FindExpiredSuspensions(now time.Time) ([]domain.User, error) {
+	var users []domain.User
+	err := r.db.
+		Where("status = ? AND suspended_until IS NOT NULL AND suspended_until <= ?", domain.UserStatusSuspended, now).
+		Order("id").
+		Find(&users).Error
+	return users, err
+}
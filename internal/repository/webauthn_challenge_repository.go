@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"errors"
+
+	"advanced-user-api/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ================================================================
+// WEBAUTHN CHALLENGE REPOSITORY - Незавершённые церемонии passkey
+// ================================================================
+
+// WebAuthnChallengeRepository - интерфейс для хранения challenge между begin и finish
+type WebAuthnChallengeRepository interface {
+	Create(challenge *domain.WebAuthnChallenge) error
+	Take(id string) (*domain.WebAuthnChallenge, error)
+}
+
+// webAuthnChallengeRepository - реализация с GORM
+type webAuthnChallengeRepository struct {
+	db *gorm.DB
+}
+
+// NewWebAuthnChallengeRepository - конструктор
+func NewWebAuthnChallengeRepository(db *gorm.DB) WebAuthnChallengeRepository {
+	return &webAuthnChallengeRepository{db: db}
+}
+
+// Create - сохраняет challenge
+func (r *webAuthnChallengeRepository) Create(challenge *domain.WebAuthnChallenge) error {
+	return r.db.Create(challenge).Error
+}
+
+// Take - извлекает challenge и сразу удаляет его
+// Генерирует SQL: DELETE FROM webauthn_challenges WHERE id = ? RETURNING *
+// Один запрос гарантирует, что challenge нельзя использовать дважды
+func (r *webAuthnChallengeRepository) Take(id string) (*domain.WebAuthnChallenge, error) {
+	var challenge domain.WebAuthnChallenge
+	result := r.db.Clauses(clause.Returning{}).Where("id = ?", id).Delete(&challenge)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("challenge не найден")
+	}
+	return &challenge, nil
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// ACCOUNT STATUS SERVICE - Блокировка и восстановление аккаунтов
+// ================================================================
+// Переходы между статусами описаны в domain (CanTransitionUserStatus).
+// Неактивный аккаунт не может войти (Login, TokenIssuer) и пользоваться
+// выданными токенами (ValidateClaims); блокировка дополнительно
+// отзывает все токены, поэтому после восстановления нужно войти заново
+
+// AccountStatusService - интерфейс для администраторов
+type AccountStatusService interface {
+	// Suspend - временная (req.Until) или бессрочная блокировка
+	Suspend(userID uint, req *domain.SuspendUserRequest, actorID uint, client domain.ClientInfo) (*domain.User, error)
+
+	// Ban - блокировка до решения администратора (причина обязательна)
+	Ban(userID uint, req *domain.ChangeUserStatusRequest, actorID uint, client domain.ClientInfo) (*domain.User, error)
+
+	// Reactivate - активация ожидающего аккаунта или снятие блокировки
+	Reactivate(userID uint, req *domain.ChangeUserStatusRequest, actorID uint, client domain.ClientInfo) (*domain.User, error)
+
+	// History - история статусов пользователя (новые первыми)
+	History(userID uint) ([]domain.UserStatusChange, error)
+
+	// ExpireSuspensions - переводит в active аккаунты с истёкшим сроком блокировки
+	ExpireSuspensions(now time.Time) (int, error)
+
+	// RunExpiry - вызывает ExpireSuspensions с интервалом до отмены ctx
+	RunExpiry(ctx context.Context, interval time.Duration)
+
+	// ForTenant - сервис, работающий только с пользователями организации orgID
+	ForTenant(orgID uint) AccountStatusService
+}
+
+var (
+	// ErrStatusUserNotFound - пользователь не существует (или в другой организации)
+	ErrStatusUserNotFound = errors.New("пользователь не найден")
+
+	// ErrInvalidStatusTransition - переход не разрешён (например, pending → suspended)
+	ErrInvalidStatusTransition = errors.New("недопустимое изменение статуса")
+
+	// ErrStatusSelfChange - администратор не может заблокировать сам себя
+	ErrStatusSelfChange = errors.New("нельзя изменить статус своего аккаунта")
+
+	// ErrStatusReasonRequired - блокировка без причины
+	ErrStatusReasonRequired = errors.New("укажите причину (не короче 3 символов)")
+
+	// ErrSuspendUntilInPast - срок блокировки уже прошёл
+	ErrSuspendUntilInPast = errors.New("срок блокировки должен быть в будущем")
+)
+
+// statusExpiredReason - причина автоматического снятия блокировки
+const statusExpiredReason = "срок блокировки истёк"
+
+// accountStatusService - реализация
+type accountStatusService struct {
+	userRepo   repository.UserRepository
+	statusRepo repository.UserStatusRepository
+	sessions   repository.SessionRepository // nil - сеансы не завершаются (токены отзываются TokenVersion)
+	audit      AuditService                 // nil - события не пишем
+}
+
+// NewAccountStatusService - конструктор
+func NewAccountStatusService(userRepo repository.UserRepository, statusRepo repository.UserStatusRepository, sessions repository.SessionRepository, audit AuditService) AccountStatusService {
+	return &accountStatusService{
+		userRepo:   userRepo,
+		statusRepo: statusRepo,
+		sessions:   sessions,
+		audit:      audit,
+	}
+}
+
+// ForTenant - копия сервиса с репозиторием организации orgID
+func (s *accountStatusService) ForTenant(orgID uint) AccountStatusService {
+	scoped := *s
+	scoped.userRepo = s.userRepo.ForTenant(orgID)
+	return &scoped
+}
+
+// Suspend - временная блокировка
+func (s *accountStatusService) Suspend(userID uint, req *domain.SuspendUserRequest, actorID uint, client domain.ClientInfo) (*domain.User, error) {
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		return nil, ErrSuspendUntilInPast
+	}
+	return s.change(userID, domain.UserStatusSuspended, req.Reason, req.Until, actorID, client)
+}
+
+// Ban - блокировка до решения администратора
+func (s *accountStatusService) Ban(userID uint, req *domain.ChangeUserStatusRequest, actorID uint, client domain.ClientInfo) (*domain.User, error) {
+	return s.change(userID, domain.UserStatusBanned, req.Reason, nil, actorID, client)
+}
+
+// Reactivate - перевод в active
+func (s *accountStatusService) Reactivate(userID uint, req *domain.ChangeUserStatusRequest, actorID uint, client domain.ClientInfo) (*domain.User, error) {
+	return s.change(userID, domain.UserStatusActive, req.Reason, nil, actorID, client)
+}
+
+// History - история статусов пользователя организации
+func (s *accountStatusService) History(userID uint) ([]domain.UserStatusChange, error) {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return nil, ErrStatusUserNotFound
+	}
+	return s.statusRepo.History(userID)
+}
+
+// change - проверяет переход и сохраняет новый статус
+func (s *accountStatusService) change(userID uint, to, reason string, until *time.Time, actorID uint, client domain.ClientInfo) (*domain.User, error) {
+	// === ШАГ 1: ПРОВЕРКИ ===
+	if userID == actorID {
+		return nil, ErrStatusSelfChange
+	}
+
+	reason = strings.TrimSpace(reason)
+	if to != domain.UserStatusActive && len([]rune(reason)) < 3 {
+		return nil, ErrStatusReasonRequired
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrStatusUserNotFound
+	}
+
+	// Переход считается от сохранённого статуса: истёкшую блокировку
+	// можно снять вручную, не дожидаясь фоновой задачи
+	from := user.Status
+	if from == "" {
+		from = domain.UserStatusActive
+	}
+	if !domain.CanTransitionUserStatus(from, to) {
+		return nil, fmt.Errorf("%w: %s → %s", ErrInvalidStatusTransition, from, to)
+	}
+
+	// === ШАГ 2: СОХРАНЕНИЕ ===
+	// Блокировка отзывает все выданные токены (в БД - token_version + 1)
+	blocking := to != domain.UserStatusActive
+	if blocking {
+		user.TokenVersion++
+	}
+
+	if err := s.apply(user, from, to, reason, until, actorID, blocking); err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 3: СЕАНСЫ И ЖУРНАЛ ===
+	if blocking && s.sessions != nil {
+		if _, err := s.sessions.RevokeAllExcept(user.ID, "", time.Now()); err != nil {
+			log.Printf("⚠️  Не удалось завершить сеансы пользователя %d: %v", user.ID, err)
+		}
+	}
+	s.recordAudit(user, from, until, actorID, client)
+
+	return user, nil
+}
+
+// apply - новый статус пользователя + запись истории
+// actorID = 0 - изменение системой; revokeTokens - отозвать выданные токены
+func (s *accountStatusService) apply(user *domain.User, from, to, reason string, until *time.Time, actorID uint, revokeTokens bool) error {
+	user.Status = to
+	user.StatusReason = reason
+	user.SuspendedUntil = until
+
+	change := &domain.UserStatusChange{
+		UserID:     user.ID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+		Until:      until,
+	}
+	if actorID != 0 {
+		change.ActorID = &actorID
+	}
+
+	return s.statusRepo.Change(user, change, revokeTokens)
+}
+
+// ================================================================
+// АВТОМАТИЧЕСКОЕ СНЯТИЕ БЛОКИРОВКИ
+// ================================================================
+
+// ExpireSuspensions - снимает блокировки с истёкшим сроком
+// До этого момента такие аккаунты уже могут входить (User.EffectiveStatus),
+// задача лишь приводит в порядок статус и историю
+func (s *accountStatusService) ExpireSuspensions(now time.Time) (int, error) {
+	users, err := s.statusRepo.FindExpiredSuspensions(now)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := range users {
+		user := &users[i]
+		if err := s.apply(user, domain.UserStatusSuspended, domain.UserStatusActive, statusExpiredReason, nil, 0, false); err != nil {
+			log.Printf("⚠️  Не удалось снять блокировку пользователя %d: %v", user.ID, err)
+			continue
+		}
+		s.recordAudit(user, domain.UserStatusSuspended, nil, 0, domain.ClientInfo{})
+		count++
+	}
+	return count, nil
+}
+
+// RunExpiry - фоновое снятие истёкших блокировок (сразу и далее раз в interval)
+func (s *accountStatusService) RunExpiry(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := s.ExpireSuspensions(time.Now())
+		if err != nil {
+			log.Printf("⚠️  Ошибка снятия истёкших блокировок: %v", err)
+		} else if count > 0 {
+			log.Printf("🔓 Сняты истёкшие блокировки аккаунтов: %d", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordAudit - событие изменения статуса (Changes - статус, Details - причина и срок)
+func (s *accountStatusService) recordAudit(user *domain.User, from string, until *time.Time, actorID uint, client domain.ClientInfo) {
+	if s.audit == nil {
+		return
+	}
+
+	event := newAuditEvent(domain.AuditActionUserStatusChanged, actorID, user.ID, client)
+	event.Changes = diffFields(map[string][2]string{"status": {from, user.Status}})
+	event.Details = user.StatusReason
+	if until != nil {
+		event.Details += " (до " + until.UTC().Format(time.RFC3339) + ")"
+	}
+	s.audit.Record(event)
+}
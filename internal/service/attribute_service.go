@@ -0,0 +1,452 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// ATTRIBUTE SERVICE - Атрибуты пользователей по схеме организации
+// ================================================================
+// Администратор описывает атрибуты (тип, обязательность, enum, regex, видимость),
+// сервис проверяет по схеме значения при регистрации и изменении пользователя
+// и убирает из ответов атрибуты, которые автору запроса видеть не положено
+
+// AttributeService - интерфейс для работы с атрибутами
+type AttributeService interface {
+	// Схемы атрибутов (только роль admin)
+	ListDefinitions() ([]domain.AttributeDefinition, error)
+	CreateDefinition(req *domain.AttributeDefinitionRequest) (*domain.AttributeDefinition, error)
+	UpdateDefinition(key string, req *domain.AttributeDefinitionRequest) (*domain.AttributeDefinition, error)
+
+	// DeleteDefinition - удаляет схему и значения атрибута у всех участников организации
+	DeleteDefinition(key string, actorID uint, client domain.ClientInfo) error
+
+	// Apply - проверяет изменения changes по схеме и применяет их к current
+	// (значение nil - удалить атрибут). current не меняется
+	// Возвращает *domain.AttributeValidationError со всеми нарушениями
+	Apply(current, changes domain.UserAttributes, access domain.AttributeAccess) (domain.UserAttributes, error)
+
+	// Replace - заменяет видимые автору атрибуты набором replacement (PUT):
+	// видимый атрибут, которого нет в replacement, удаляется, невидимые не меняются
+	// Проверяются только изменившиеся значения. current не меняется
+	Replace(current, replacement domain.UserAttributes, access domain.AttributeAccess) (domain.UserAttributes, error)
+
+	// Visible - атрибуты, которые видит автор запроса с правами access
+	Visible(attributes domain.UserAttributes, access domain.AttributeAccess) (domain.UserAttributes, error)
+
+	// ParseFilter - фильтр GET /users?attr.key=value: строки из query
+	// приводятся к типам схемы. Фильтровать можно только по видимым атрибутам
+	ParseFilter(query map[string]string, access domain.AttributeAccess) (domain.UserAttributes, error)
+
+	// Present - пользователи с атрибутами, которые видит автор запроса viewerID
+	Present(users []domain.User, viewerID uint, admin bool) ([]domain.UserResponse, error)
+
+	// ForTenant - сервис со схемами организации orgID
+	ForTenant(orgID uint) AttributeService
+}
+
+var (
+	// ErrAttributeNotFound - в организации нет атрибута с таким ключом
+	ErrAttributeNotFound = errors.New("атрибут не найден")
+
+	// ErrAttributeKeyTaken - атрибут с таким ключом уже описан
+	ErrAttributeKeyTaken = errors.New("атрибут с таким ключом уже существует")
+
+	// ErrInvalidAttributeKey - недопустимый ключ атрибута
+	ErrInvalidAttributeKey = errors.New("ключ атрибута может содержать только латиницу в нижнем регистре, цифры и '_' (до 64 символов, начинается с буквы)")
+
+	// ErrInvalidAttributeDefinition - enum или pattern у атрибута не строкового типа
+	ErrInvalidAttributeDefinition = errors.New("enum и pattern допустимы только для атрибутов типа string")
+
+	// ErrInvalidAttributePattern - pattern не компилируется
+	ErrInvalidAttributePattern = errors.New("невалидное регулярное выражение pattern")
+
+	// ErrAttributesDisabled - атрибуты переданы, но сервис атрибутов не подключён
+	ErrAttributesDisabled = errors.New("атрибуты пользователей не настроены")
+)
+
+// attributeKeyPattern - допустимый ключ атрибута (ключ jsonb и часть query attr.<key>)
+var attributeKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// attributeMaxLength - максимальная длина строкового значения (в символах)
+const attributeMaxLength = 1000
+
+// attributeService - реализация
+type attributeService struct {
+	attributeRepo repository.AttributeRepository
+	audit         AuditService // nil - события не пишем
+}
+
+// NewAttributeService - конструктор
+func NewAttributeService(attributeRepo repository.AttributeRepository, audit AuditService) AttributeService {
+	return &attributeService{attributeRepo: attributeRepo, audit: audit}
+}
+
+// ForTenant - копия сервиса с репозиторием организации orgID
+func (s *attributeService) ForTenant(orgID uint) AttributeService {
+	scoped := *s
+	scoped.attributeRepo = s.attributeRepo.ForTenant(orgID)
+	return &scoped
+}
+
+// ================================================================
+// СХЕМЫ АТРИБУТОВ
+// ================================================================
+
+// ListDefinitions - схемы атрибутов организации
+func (s *attributeService) ListDefinitions() ([]domain.AttributeDefinition, error) {
+	return s.attributeRepo.FindAll()
+}
+
+// CreateDefinition - описывает новый атрибут
+func (s *attributeService) CreateDefinition(req *domain.AttributeDefinitionRequest) (*domain.AttributeDefinition, error) {
+	// === ШАГ 1: ПРОВЕРКИ ===
+	if !attributeKeyPattern.MatchString(req.Key) {
+		return nil, ErrInvalidAttributeKey
+	}
+	if err := checkAttributeDefinition(req); err != nil {
+		return nil, err
+	}
+	_, err := s.attributeRepo.FindByKey(req.Key)
+	if err == nil {
+		return nil, ErrAttributeKeyTaken
+	}
+	if !errors.Is(err, repository.ErrAttributeDefinitionNotFound) {
+		return nil, err
+	}
+
+	// === ШАГ 2: СОХРАНЕНИЕ ===
+	definition := &domain.AttributeDefinition{Key: req.Key}
+	applyAttributeDefinition(definition, req)
+	if err := s.attributeRepo.Create(definition); err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// UpdateDefinition - заменяет схему атрибута key
+// Сохранённые значения не перепроверяются: новая схема действует
+// при следующем изменении атрибутов пользователя
+func (s *attributeService) UpdateDefinition(key string, req *domain.AttributeDefinitionRequest) (*domain.AttributeDefinition, error) {
+	if err := checkAttributeDefinition(req); err != nil {
+		return nil, err
+	}
+	definition, err := s.findDefinition(key)
+	if err != nil {
+		return nil, err
+	}
+
+	applyAttributeDefinition(definition, req)
+	if err := s.attributeRepo.Update(definition); err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// DeleteDefinition - удаляет схему вместе со значениями атрибута
+func (s *attributeService) DeleteDefinition(key string, actorID uint, client domain.ClientInfo) error {
+	definition, err := s.findDefinition(key)
+	if err != nil {
+		return err
+	}
+
+	if err := s.attributeRepo.Delete(key); errors.Is(err, repository.ErrAttributeDefinitionNotFound) {
+		return ErrAttributeNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionAttributeDeleted, actorID, 0, client)
+		event.Details = fmt.Sprintf("атрибут %s (%s, %s)", definition.Key, definition.Type, definition.Visibility)
+		s.audit.Record(event)
+	}
+	return nil
+}
+
+// ================================================================
+// ЗНАЧЕНИЯ АТРИБУТОВ
+// ================================================================
+
+// Apply - проверка и применение изменений атрибутов
+func (s *attributeService) Apply(current, changes domain.UserAttributes, access domain.AttributeAccess) (domain.UserAttributes, error) {
+	definitions, err := s.definitionsByKey()
+	if err != nil {
+		return nil, err
+	}
+	return applyAttributes(definitions, current, changes, access)
+}
+
+// Replace - полная замена видимых атрибутов
+func (s *attributeService) Replace(current, replacement domain.UserAttributes, access domain.AttributeAccess) (domain.UserAttributes, error) {
+	definitions, err := s.definitionsByKey()
+	if err != nil {
+		return nil, err
+	}
+
+	// Замена сводится к изменениям: удалить пропавшие видимые атрибуты,
+	// записать новые и изменившиеся. Неизменённые значения не проверяются -
+	// клиент может вернуть документ из GET как есть
+	changes := domain.UserAttributes{}
+	for key := range current {
+		if _, kept := replacement[key]; !kept && attributeVisible(definitions, key, access) {
+			changes[key] = nil
+		}
+	}
+	for key, value := range replacement {
+		if previous, ok := current[key]; !ok || !reflect.DeepEqual(previous, value) {
+			changes[key] = value
+		}
+	}
+	return applyAttributes(definitions, current, changes, access)
+}
+
+// Visible - видимые атрибуты
+func (s *attributeService) Visible(attributes domain.UserAttributes, access domain.AttributeAccess) (domain.UserAttributes, error) {
+	definitions, err := s.definitionsByKey()
+	if err != nil {
+		return nil, err
+	}
+	return visibleAttributes(definitions, attributes, access), nil
+}
+
+// applyAttributes - проверка изменений по схеме (общая часть Apply и Replace)
+func applyAttributes(definitions map[string]domain.AttributeDefinition, current, changes domain.UserAttributes, access domain.AttributeAccess) (domain.UserAttributes, error) {
+	// === ШАГ 1: ПРОВЕРКА КАЖДОГО ИЗМЕНЕНИЯ ===
+	// Ключи по порядку - нарушения в ответе всегда в одном порядке
+	var violations []domain.AttributeViolation
+	result := make(domain.UserAttributes, len(current)+len(changes))
+	for key, value := range current {
+		result[key] = value
+	}
+	for _, key := range sortedAttributeKeys(changes) {
+		value := changes[key]
+		definition, ok := definitions[key]
+		switch {
+		case !ok && value == nil && access.Admin:
+			// Администратор убирает значения, оставшиеся без схемы
+			delete(result, key)
+		case !ok:
+			violations = append(violations, attributeViolation(key, domain.AttributeViolationUnknown, "атрибут не описан в схеме организации"))
+		case !access.Allows(definition.Visibility):
+			violations = append(violations, attributeViolation(key, domain.AttributeViolationForbidden, "недостаточно прав для изменения атрибута"))
+		case value == nil:
+			delete(result, key)
+		default:
+			if violation := checkAttributeValue(definition, value); violation != nil {
+				violations = append(violations, *violation)
+				continue
+			}
+			result[key] = value
+		}
+	}
+
+	// === ШАГ 2: ОБЯЗАТЕЛЬНЫЕ АТРИБУТЫ ===
+	// Требуются только те, которые автор может задать сам: обязательный атрибут
+	// с видимостью admin при регистрации заполняет потом администратор
+	for _, key := range sortedAttributeKeys(definitions) {
+		definition := definitions[key]
+		if !definition.Required || !access.Allows(definition.Visibility) {
+			continue
+		}
+		// Неверное значение уже в нарушениях - второй раз о ключе не сообщаем
+		if _, ok := result[key]; !ok && changes[key] == nil {
+			violations = append(violations, attributeViolation(key, domain.AttributeViolationRequired, "обязательный атрибут"))
+		}
+	}
+
+	if len(violations) > 0 {
+		return nil, &domain.AttributeValidationError{Violations: violations}
+	}
+	return result, nil
+}
+
+// ParseFilter - фильтр по атрибутам из query
+func (s *attributeService) ParseFilter(query map[string]string, access domain.AttributeAccess) (domain.UserAttributes, error) {
+	definitions, err := s.definitionsByKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []domain.AttributeViolation
+	filter := make(domain.UserAttributes, len(query))
+	for _, key := range sortedAttributeKeys(query) {
+		raw := query[key]
+		definition, ok := definitions[key]
+		if !ok {
+			violations = append(violations, attributeViolation(key, domain.AttributeViolationUnknown, "атрибут не описан в схеме организации"))
+			continue
+		}
+		// Фильтр по невидимому атрибуту выдал бы его значение, поэтому запрещён
+		if !access.Allows(definition.Visibility) {
+			violations = append(violations, attributeViolation(key, domain.AttributeViolationForbidden, "недостаточно прав для фильтра по атрибуту"))
+			continue
+		}
+
+		var value interface{} = raw
+		switch definition.Type {
+		case domain.AttributeTypeNumber:
+			value, err = strconv.ParseFloat(raw, 64)
+		case domain.AttributeTypeBoolean:
+			value, err = strconv.ParseBool(raw)
+		}
+		if err != nil {
+			violations = append(violations, attributeViolation(key, domain.AttributeViolationType, "ожидается значение типа "+definition.Type))
+			continue
+		}
+		filter[key] = value
+	}
+
+	if len(violations) > 0 {
+		return nil, &domain.AttributeValidationError{Violations: violations}
+	}
+	return filter, nil
+}
+
+// Present - атрибуты в ответе: public - всем, private - самому пользователю
+// и администраторам, admin и атрибуты без схемы - только администраторам
+func (s *attributeService) Present(users []domain.User, viewerID uint, admin bool) ([]domain.UserResponse, error) {
+	definitions, err := s.definitionsByKey()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.UserResponse, 0, len(users))
+	for _, user := range users {
+		access := domain.AttributeAccess{Self: user.ID == viewerID, Admin: admin}
+		result = append(result, domain.UserResponse{User: user, Attributes: visibleAttributes(definitions, user.Attributes, access)})
+	}
+	return result, nil
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// findDefinition - схема по ключу (ErrAttributeNotFound, если нет)
+func (s *attributeService) findDefinition(key string) (*domain.AttributeDefinition, error) {
+	definition, err := s.attributeRepo.FindByKey(key)
+	if errors.Is(err, repository.ErrAttributeDefinitionNotFound) {
+		return nil, ErrAttributeNotFound
+	}
+	return definition, err
+}
+
+// definitionsByKey - все схемы организации: ключ → схема
+func (s *attributeService) definitionsByKey() (map[string]domain.AttributeDefinition, error) {
+	definitions, err := s.attributeRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]domain.AttributeDefinition, len(definitions))
+	for _, definition := range definitions {
+		byKey[definition.Key] = definition
+	}
+	return byKey, nil
+}
+
+// attributeVisible - видит ли автор атрибут key (атрибут без схемы - только администратор)
+func attributeVisible(definitions map[string]domain.AttributeDefinition, key string, access domain.AttributeAccess) bool {
+	definition, ok := definitions[key]
+	if !ok {
+		return access.Admin
+	}
+	return access.Allows(definition.Visibility)
+}
+
+// visibleAttributes - видимая автору часть атрибутов (всегда не nil)
+func visibleAttributes(definitions map[string]domain.AttributeDefinition, attributes domain.UserAttributes, access domain.AttributeAccess) domain.UserAttributes {
+	visible := domain.UserAttributes{}
+	for key, value := range attributes {
+		if attributeVisible(definitions, key, access) {
+			visible[key] = value
+		}
+	}
+	return visible
+}
+
+// checkAttributeDefinition - enum и pattern только у строк, pattern компилируется
+func checkAttributeDefinition(req *domain.AttributeDefinitionRequest) error {
+	if req.Type != domain.AttributeTypeString && (len(req.Enum) > 0 || req.Pattern != "") {
+		return ErrInvalidAttributeDefinition
+	}
+	if req.Pattern != "" {
+		if _, err := regexp.Compile(req.Pattern); err != nil {
+			return ErrInvalidAttributePattern
+		}
+	}
+	return nil
+}
+
+// applyAttributeDefinition - переносит поля запроса в схему (кроме ключа)
+func applyAttributeDefinition(definition *domain.AttributeDefinition, req *domain.AttributeDefinitionRequest) {
+	definition.Type = req.Type
+	definition.Required = req.Required
+	definition.Enum = req.Enum
+	definition.Pattern = req.Pattern
+	definition.Visibility = req.Visibility
+	definition.Description = req.Description
+}
+
+// checkAttributeValue - значение по схеме: тип, длина, enum, pattern
+// Значения после разбора JSON: строка - string, число - float64, логическое - bool
+func checkAttributeValue(definition domain.AttributeDefinition, value interface{}) *domain.AttributeViolation {
+	key := definition.Key
+	typeViolation := attributeViolation(key, domain.AttributeViolationType, "ожидается значение типа "+definition.Type)
+
+	switch definition.Type {
+	case domain.AttributeTypeNumber:
+		if _, ok := value.(float64); !ok {
+			return &typeViolation
+		}
+	case domain.AttributeTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return &typeViolation
+		}
+	case domain.AttributeTypeString:
+		str, ok := value.(string)
+		if !ok {
+			return &typeViolation
+		}
+		if utf8.RuneCountInString(str) > attributeMaxLength {
+			violation := attributeViolation(key, domain.AttributeViolationTooLong, fmt.Sprintf("максимальная длина - %d символов", attributeMaxLength))
+			return &violation
+		}
+		if len(definition.Enum) > 0 && !slices.Contains(definition.Enum, str) {
+			violation := attributeViolation(key, domain.AttributeViolationEnum, fmt.Sprintf("допустимые значения: %v", definition.Enum))
+			return &violation
+		}
+		// Схема проверена при сохранении, ошибка компиляции здесь невозможна
+		if definition.Pattern != "" && !regexp.MustCompile(definition.Pattern).MatchString(str) {
+			violation := attributeViolation(key, domain.AttributeViolationPattern, "значение не соответствует шаблону "+definition.Pattern)
+			return &violation
+		}
+	}
+	return nil
+}
+
+// attributeViolation - нарушение схемы атрибутом key
+func attributeViolation(key, code, message string) domain.AttributeViolation {
+	return domain.AttributeViolation{Key: key, Code: code, Message: message}
+}
+
+// sortedAttributeKeys - ключи map по алфавиту
+func sortedAttributeKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
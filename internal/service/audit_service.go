@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// AUDIT SERVICE - Запись событий безопасности
+// ================================================================
+
+// AuditService - интерфейс для записи событий в журнал
+type AuditService interface {
+	// Record - записывает событие
+	// Ошибка записи не должна отменять уже выполненное действие,
+	// поэтому она логируется, а не возвращается
+	Record(event *domain.AuditEvent)
+}
+
+// auditService - реализация поверх AuditRepository
+type auditService struct {
+	auditRepo repository.AuditRepository
+}
+
+// NewAuditService - конструктор
+func NewAuditService(auditRepo repository.AuditRepository) AuditService {
+	return &auditService{auditRepo: auditRepo}
+}
+
+// Record - сохраняет событие в журнал
+func (s *auditService) Record(event *domain.AuditEvent) {
+	if err := s.auditRepo.Create(event); err != nil {
+		log.Printf("⚠️  Не удалось записать событие %s: %v", event.Action, err)
+	}
+}
+
+// newAuditEvent - событие с данными клиента
+// actorID/targetID = 0 - не заполняются (анонимный запрос, неизвестный пользователь)
+func newAuditEvent(action string, actorID, targetID uint, client domain.ClientInfo) *domain.AuditEvent {
+	event := &domain.AuditEvent{
+		Action:    action,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		RequestID: client.RequestID,
+	}
+	if actorID != 0 {
+		event.ActorID = &actorID
+	}
+	if targetID != 0 {
+		event.TargetID = &targetID
+	}
+	return event
+}
+
+// diffFields - изменённые поля: имя → [до, после]
+// Неизменённые поля в результат не попадают
+func diffFields(fields map[string][2]string) domain.AuditChanges {
+	changes := domain.AuditChanges{}
+	for name, values := range fields {
+		if values[0] != values[1] {
+			changes[name] = domain.AuditChange{Before: values[0], After: values[1]}
+		}
+	}
+	return changes
+}
+
+// ================================================================
+// AUDIT LOG SERVICE - Просмотр, проверка и хранение журнала
+// ================================================================
+
+// AuditLogService - интерфейс для администраторов
+type AuditLogService interface {
+	// List - события по фильтру с пагинацией
+	List(filter domain.AuditEventFilter) (*domain.AuditEventPage, error)
+
+	// Verify - проверяет цепочку хэшей от первого хранимого события
+	Verify() (*domain.AuditVerification, error)
+
+	// Purge - удаляет события старше AUDIT_RETENTION_DAYS
+	Purge(now time.Time) (int64, error)
+
+	// RunRetention - вызывает Purge с интервалом до отмены ctx
+	RunRetention(ctx context.Context, interval time.Duration)
+
+	// ForTenant - List только событий участников организации orgID
+	// Verify проверяет всю цепочку: она общая для всех организаций
+	ForTenant(orgID uint) AuditLogService
+}
+
+const (
+	auditDefaultLimit = 50  // Событий на странице по умолчанию
+	auditVerifyBatch  = 500 // Событий за один запрос при проверке цепочки
+)
+
+// auditLogService - реализация поверх AuditRepository
+type auditLogService struct {
+	auditRepo repository.AuditRepository
+	retention time.Duration // 0 - хранить всегда
+}
+
+// NewAuditLogService - конструктор
+func NewAuditLogService(auditRepo repository.AuditRepository, cfg *config.Config) AuditLogService {
+	var retention time.Duration
+	if cfg.AuditRetentionDays > 0 {
+		retention = time.Duration(cfg.AuditRetentionDays) * 24 * time.Hour
+	}
+
+	return &auditLogService{
+		auditRepo: auditRepo,
+		retention: retention,
+	}
+}
+
+// ForTenant - копия сервиса с репозиторием организации orgID
+func (s *auditLogService) ForTenant(orgID uint) AuditLogService {
+	scoped := *s
+	scoped.auditRepo = s.auditRepo.ForTenant(orgID)
+	return &scoped
+}
+
+// List - события по фильтру (новые первыми)
+func (s *auditLogService) List(filter domain.AuditEventFilter) (*domain.AuditEventPage, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = auditDefaultLimit
+	}
+
+	events, total, err := s.auditRepo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []domain.AuditEvent{}
+	}
+
+	return &domain.AuditEventPage{
+		Events: events,
+		Total:  total,
+		Page:   filter.Page,
+		Limit:  filter.Limit,
+	}, nil
+}
+
+// Verify - проходит журнал порциями и пересчитывает хэши
+// Для каждого события проверяется:
+//  1. PrevHash совпадает с Hash предыдущего события (нет удалённых/вставленных записей)
+//  2. Hash совпадает с пересчитанным (запись не изменялась)
+//
+// PrevHash первого события не проверяется: начало цепочки могло быть
+// удалено по сроку хранения. События без хэша в начале журнала
+// (записанные до появления цепочки) пропускаются
+func (s *auditLogService) Verify() (*domain.AuditVerification, error) {
+	result := &domain.AuditVerification{Valid: true}
+
+	var afterID uint
+	var prevHash string
+	for {
+		events, err := s.auditRepo.FindAfter(afterID, auditVerifyBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range events {
+			event := &events[i]
+			if result.Checked == 0 && event.Hash == "" {
+				afterID = event.ID
+				continue
+			}
+			if result.Checked > 0 && event.PrevHash != prevHash {
+				return brokenChain(result, event.ID, "prev_hash не совпадает с хэшем предыдущего события"), nil
+			}
+			if event.ComputeHash() != event.Hash {
+				return brokenChain(result, event.ID, "хэш не совпадает с содержимым события"), nil
+			}
+
+			prevHash = event.Hash
+			afterID = event.ID
+			result.Checked++
+		}
+
+		if len(events) < auditVerifyBatch {
+			return result, nil
+		}
+	}
+}
+
+// brokenChain - результат проверки с нарушенной цепочкой
+func brokenChain(result *domain.AuditVerification, id uint, reason string) *domain.AuditVerification {
+	result.Valid = false
+	result.BrokenAt = &id
+	result.Reason = reason
+	return result
+}
+
+// Purge - удаляет события старше срока хранения
+// Само удаление тоже записывается в журнал
+func (s *auditLogService) Purge(now time.Time) (int64, error) {
+	if s.retention == 0 {
+		return 0, nil
+	}
+
+	cutoff := now.Add(-s.retention)
+	count, err := s.auditRepo.DeleteBefore(cutoff)
+	if err != nil || count == 0 {
+		return count, err
+	}
+
+	event := newAuditEvent(domain.AuditActionRetentionPurged, 0, 0, domain.ClientInfo{})
+	event.Details = fmt.Sprintf("удалено событий: %d, старше %s", count, cutoff.UTC().Format(time.RFC3339))
+	if err := s.auditRepo.Create(event); err != nil {
+		log.Printf("⚠️  Не удалось записать событие %s: %v", event.Action, err)
+	}
+
+	return count, nil
+}
+
+// RunRetention - фоновая очистка журнала (сразу и далее раз в interval)
+func (s *auditLogService) RunRetention(ctx context.Context, interval time.Duration) {
+	if s.retention == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := s.Purge(time.Now())
+		if err != nil {
+			log.Printf("⚠️  Ошибка очистки журнала аудита: %v", err)
+		} else if count > 0 {
+			log.Printf("🧹 Журнал аудита: удалено событий старше срока хранения: %d", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -2,11 +2,14 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	"advanced-user-api/internal/config"
 	"advanced-user-api/internal/domain"
 	"advanced-user-api/internal/pkg/jwt"
+	"advanced-user-api/internal/pkg/mailer"
 	"advanced-user-api/internal/pkg/password"
 	"advanced-user-api/internal/repository"
 )
@@ -17,22 +20,160 @@ import (
 
 // AuthService - интерфейс для аутентификации пользователей
 type AuthService interface {
-	Register(req *domain.RegisterRequest) (*domain.AuthResponse, error)
-	Login(req *domain.LoginRequest) (*domain.AuthResponse, error)
+	Register(req *domain.RegisterRequest, client domain.ClientInfo) (*domain.AuthResponse, error)
+
+	// ResumeRegistration - новый токен уже зарегистрированного пользователя
+	// (повтор регистрации с тем же Idempotency-Key, см. middleware.IdempotencyResult)
+	ResumeRegistration(userID uint, client domain.ClientInfo) (*domain.AuthResponse, error)
+
+	Login(req *domain.LoginRequest, client domain.ClientInfo) (*domain.AuthResponse, error)
+	ChangePassword(userID uint, req *domain.ChangePasswordRequest, client domain.ClientInfo) (*domain.AuthResponse, error)
+	RequestMagicLink(req *domain.MagicLinkRequest, client domain.ClientInfo) error
+	RedeemMagicLink(req *domain.MagicLinkVerifyRequest, client domain.ClientInfo) (*domain.AuthResponse, error)
+
+	// Reauthenticate - подтверждение личности перед опасным действием (удаление аккаунта)
+	Reauthenticate(userID uint, plainPassword string, tokenIssuedAt time.Time) error
+
+	// ValidateClaims - проверяет, что токен не отозван (см. middleware.ClaimsValidator)
+	ValidateClaims(claims *jwt.Claims) error
+
+	// ForTenant - сервис, работающий только с пользователями организации orgID
+	ForTenant(orgID uint) AuthService
 }
 
+// Ошибки смены пароля и проверки токена
+var (
+	ErrWrongCurrentPassword = errors.New("неверный текущий пароль")
+	ErrPasswordUnchanged    = errors.New("новый пароль должен отличаться от текущего")
+	ErrPasswordManagedByIdP = errors.New("пароль этого аккаунта управляется внешним каталогом")
+	ErrTokenRevoked         = errors.New("токен отозван")
+
+	// ErrReauthenticationRequired - аккаунт без пароля (только passkey), а вход был давно
+	ErrReauthenticationRequired = errors.New("требуется повторный вход в систему")
+
+	// ErrEmailAlreadyRegistered - email занят другим аккаунтом организации
+	// (регистрация и смена email в PUT/PATCH /users/:id - 409 Conflict)
+	ErrEmailAlreadyRegistered = errors.New("пользователь с таким email уже зарегистрирован")
+)
+
+// reauthMaxTokenAge - токен аккаунта без пароля должен быть выдан не раньше этого срока
+// (пользователь только что вошёл с passkey) - иначе Reauthenticate требует войти заново
+const reauthMaxTokenAge = 5 * time.Minute
+
 // authService - реализация сервиса аутентификации
 type authService struct {
-	userRepo repository.UserRepository // Зависимость от Repository
-	cfg      *config.Config            // Конфигурация (для JWT secret)
+	userRepo  repository.UserRepository // Зависимость от Repository
+	cfg       *config.Config            // Конфигурация (для JWT secret)
+	verifiers []CredentialVerifier      // Цепочка проверки учётных данных для Login
+	hasher    *password.Hasher          // Хеширование паролей (argon2id / bcrypt)
+	policy    *password.Policy          // Политика для новых паролей
+	audit     AuditService              // Журнал событий безопасности (nil - не пишем)
+	mailer    mailer.Mailer             // Уведомления пользователю по email
+	tokens    TokenIssuer               // Выдача JWT токенов
+
+	magicLinks repository.MagicLinkRepository // Токены входа по ссылке (nil - выключено)
+	sessions   repository.SessionRepository   // Сеансы (nil - не завершаем при смене пароля)
+	attributes AttributeService               // Схема атрибутов (nil - атрибуты при регистрации не принимаются)
+	roles      RoleProvider                   // Роли групп (nil - автор имперсонации проверяется только по users.role)
+}
+
+// AuthOption - необязательная настройка Auth Service
+// Позволяет подключать новые зависимости, не меняя сигнатуру конструктора
+type AuthOption func(*authService)
+
+// WithCredentialVerifiers - заменяет цепочку проверки учётных данных
+// По умолчанию: локальный bcrypt + LDAP (если LDAP_ENABLED=true)
+func WithCredentialVerifiers(verifiers ...CredentialVerifier) AuthOption {
+	return func(s *authService) {
+		s.verifiers = verifiers
+	}
+}
+
+// WithPasswordHasher - заменяет Hasher, собранный из PASSWORD_* настроек
+func WithPasswordHasher(hasher *password.Hasher) AuthOption {
+	return func(s *authService) {
+		s.hasher = hasher
+	}
+}
+
+// WithPasswordPolicy - заменяет политику паролей, собранную из PASSWORD_* настроек
+// Позволяет использовать одну политику (и одну загруженную базу утечек) в нескольких сервисах
+func WithPasswordPolicy(policy *password.Policy) AuthOption {
+	return func(s *authService) {
+		s.policy = policy
+	}
+}
+
+// WithAuditService - подключает журнал событий безопасности
+func WithAuditService(audit AuditService) AuthOption {
+	return func(s *authService) {
+		s.audit = audit
+	}
+}
+
+// WithMailer - заменяет отправку писем (по умолчанию письма пишутся в лог)
+func WithMailer(m mailer.Mailer) AuthOption {
+	return func(s *authService) {
+		s.mailer = m
+	}
+}
+
+// WithTokenIssuer - заменяет выдачу токенов (общая для всех способов входа)
+func WithTokenIssuer(tokens TokenIssuer) AuthOption {
+	return func(s *authService) {
+		s.tokens = tokens
+	}
+}
+
+// WithSessionRepository - подключает сеансы: смена пароля завершает все сеансы пользователя
+func WithSessionRepository(sessions repository.SessionRepository) AuthOption {
+	return func(s *authService) {
+		s.sessions = sessions
+	}
+}
+
+// WithAttributeService - атрибуты при регистрации проверяются схемой организации
+func WithAttributeService(attributes AttributeService) AuthOption {
+	return func(s *authService) {
+		s.attributes = attributes
+	}
+}
+
+// WithRoleProvider - роль admin автора имперсонации засчитывается и от групп
+// (та же проверка, что в ImpersonationService и middleware.RequireRole)
+func WithRoleProvider(roles RoleProvider) AuthOption {
+	return func(s *authService) {
+		s.roles = roles
+	}
 }
 
 // NewAuthService - конструктор для создания Auth Service
-func NewAuthService(userRepo repository.UserRepository, cfg *config.Config) AuthService {
-	return &authService{
+func NewAuthService(userRepo repository.UserRepository, cfg *config.Config, opts ...AuthOption) AuthService {
+	s := &authService{
 		userRepo: userRepo,
 		cfg:      cfg,
+		hasher:   NewPasswordHasher(cfg),
+		mailer:   mailer.NewLogMailer(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.policy == nil {
+		s.policy = NewPasswordPolicy(cfg)
+	}
+	if s.tokens == nil {
+		s.tokens = NewTokenIssuer(cfg, s.sessions)
 	}
+
+	// Цепочка по умолчанию собирается ПОСЛЕ опций,
+	// чтобы локальный источник использовал итоговый Hasher
+	if s.verifiers == nil {
+		s.verifiers = defaultVerifiers(userRepo, cfg, s.hasher)
+	}
+
+	return s
 }
 
 // ================================================================
@@ -42,73 +183,87 @@ func NewAuthService(userRepo repository.UserRepository, cfg *config.Config) Auth
 // Register регистрирует нового пользователя в системе
 // Параметры:
 //   - req: данные для регистрации (email, name, password)
+//   - client: IP, User-Agent и название устройства (для сеанса)
 // Возвращает:
 //   - *domain.AuthResponse: JWT токен и данные пользователя
 //   - error: ошибка регистрации
 //
 // Процесс:
 // 1. Проверяем, не существует ли уже пользователь с таким email
-// 2. Хешируем пароль (bcrypt)
-// 3. Создаём пользователя в БД
-// 4. Генерируем JWT токен
-// 5. Возвращаем токен и данные пользователя
-func (s *authService) Register(req *domain.RegisterRequest) (*domain.AuthResponse, error) {
+// 2. Проверяем пароль по политике (длина, стойкость, утечки)
+// 3. Хешируем пароль (argon2id или bcrypt - см. PASSWORD_ALGORITHM)
+// 4. Создаём пользователя в БД
+// 5. Генерируем JWT токен
+// 6. Возвращаем токен и данные пользователя
+func (s *authService) Register(req *domain.RegisterRequest, client domain.ClientInfo) (*domain.AuthResponse, error) {
 	// === ШАГ 1: ПРОВЕРКА СУЩЕСТВОВАНИЯ ПОЛЬЗОВАТЕЛЯ ===
 	// Проверяем, не зарегистрирован ли уже пользователь с таким email
 	existingUser, _ := s.userRepo.FindByEmail(req.Email)
 	if existingUser != nil {
 		// Пользователь с таким email уже существует
-		return nil, errors.New("пользователь с таким email уже зарегистрирован")
+		return nil, ErrEmailAlreadyRegistered
 	}
 
-	// === ШАГ 2: ХЕШИРОВАНИЕ ПАРОЛЯ ===
+	// === ШАГ 2: ПРОВЕРКА ПОЛИТИКИ ПАРОЛЕЙ ===
+	// Длина, классы символов, личные данные, стойкость, утечки
+	// Возвращает *password.PolicyError со списком причин для клиента
+	if err := s.policy.Check(req.Password, password.PolicyInput{Email: req.Email, Name: req.Name}); err != nil {
+		return nil, err
+	}
+
+	// Атрибуты - по схеме организации: обязательные, типы, enum, regex
+	// Возвращает *domain.AttributeValidationError со списком нарушений
+	attributes, err := s.registrationAttributes(req.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 3: ХЕШИРОВАНИЕ ПАРОЛЯ ===
 	// НИКОГДА не сохраняйте пароли в открытом виде!
-	// Хешируем пароль с помощью bcrypt
-	hashedPassword, err := password.Hash(req.Password)
+	// Хешируем пароль текущим алгоритмом (argon2id по умолчанию)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return nil, errors.New("ошибка хеширования пароля")
 	}
 
-	// === ШАГ 3: СОЗДАНИЕ ПОЛЬЗОВАТЕЛЯ ===
+	// === ШАГ 4: СОЗДАНИЕ ПОЛЬЗОВАТЕЛЯ ===
 	// Создаём структуру User для сохранения в БД
 	user := &domain.User{
-		Email:    req.Email,
-		Name:     req.Name,
-		Password: hashedPassword, // Сохраняем ХЕШ, не сам пароль!
-		Role:     "user",         // По умолчанию роль "user"
+		Email:        req.Email,
+		Name:         req.Name,
+		Password:     hashedPassword, // Сохраняем ХЕШ, не сам пароль!
+		Role:         "user",         // По умолчанию роль "user"
+		AuthProvider: domain.AuthProviderLocal,
+		Status:       domain.UserStatusActive,
+		Attributes:   attributes,
+	}
+
+	// REGISTRATION_APPROVAL=true - аккаунт ждёт активации администратором,
+	// токен не выдаётся (TokenIssuer вернёт *domain.AccountStatusError)
+	if s.cfg.RegistrationApproval && !req.Approved {
+		user.Status = domain.UserStatusPending
 	}
 
 	// Сохраняем пользователя в БД через repository
 	if err := s.userRepo.Create(user); err != nil {
 		return nil, errors.New("ошибка создания пользователя")
 	}
+	s.recordAudit(domain.AuditActionUserRegistered, user.ID, user.ID, client)
 
-	// === ШАГ 4: ГЕНЕРАЦИЯ JWT ТОКЕНА ===
-	// Парсим время жизни токена из конфигурации
-	// "24h" → 24 часа
-	expiration, err := time.ParseDuration(s.cfg.JWTExpiration)
-	if err != nil {
-		expiration = 24 * time.Hour // Если ошибка парсинга - используем 24 часа
-	}
+	// === ШАГ 5: ГЕНЕРАЦИЯ JWT ТОКЕНА ===
+	// === ШАГ 6: ФОРМИРОВАНИЕ ОТВЕТА ===
+	// Возвращаем токен и данные пользователя (без пароля!)
+	return s.tokens.Issue(user, client)
+}
 
-	// Генерируем JWT токен с данными пользователя
-	token, err := jwt.GenerateToken(
-		user.ID,            // ID пользователя
-		user.Email,         // Email
-		user.Role,          // Роль
-		s.cfg.JWTSecret,    // Секретный ключ из конфигурации
-		expiration,         // Время жизни токена
-	)
+// ResumeRegistration - выдаёт токен пользователю, созданному первым запросом регистрации
+// Действие не повторяется: пользователь не создаётся, событие в журнал не пишется
+func (s *authService) ResumeRegistration(userID uint, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
-		return nil, errors.New("ошибка генерации токена")
+		return nil, err
 	}
-
-	// === ШАГ 5: ФОРМИРОВАНИЕ ОТВЕТА ===
-	// Возвращаем токен и данные пользователя (без пароля!)
-	return &domain.AuthResponse{
-		Token: token,
-		User:  user,
-	}, nil
+	return s.tokens.Issue(user, client)
 }
 
 // ================================================================
@@ -118,58 +273,304 @@ func (s *authService) Register(req *domain.RegisterRequest) (*domain.AuthRespons
 // Login аутентифицирует пользователя и выдаёт JWT токен
 // Параметры:
 //   - req: данные для входа (email, password)
+//   - client: IP, User-Agent и название устройства (для сеанса)
 // Возвращает:
 //   - *domain.AuthResponse: JWT токен и данные пользователя
 //   - error: ошибка аутентификации
 //
 // Процесс:
-// 1. Находим пользователя по email
-// 2. Проверяем пароль (bcrypt.Compare)
+// 1. Проверяем email + пароль по цепочке источников (bcrypt, argon2id, LDAP, ...)
+//    и статус аккаунта (*domain.AccountStatusError, если не active)
+// 2. Пересчитываем устаревший хеш пароля
 // 3. Генерируем JWT токен
 // 4. Возвращаем токен и данные пользователя
-func (s *authService) Login(req *domain.LoginRequest) (*domain.AuthResponse, error) {
-	// === ШАГ 1: ПОИСК ПОЛЬЗОВАТЕЛЯ ===
-	// Ищем пользователя по email
-	user, err := s.userRepo.FindByEmail(req.Email)
+func (s *authService) Login(req *domain.LoginRequest, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	// Вход по паролю можно отключить (LOGIN_METHODS=magic_link)
+	if !LoginMethodEnabled(s.cfg, LoginMethodPassword) {
+		return nil, ErrLoginMethodDisabled
+	}
+
+	// === ШАГ 1: ПРОВЕРКА УЧЁТНЫХ ДАННЫХ ===
+	// Источники опрашиваются по порядку (см. CredentialVerifier)
+	// ВАЖНО: при любой неудаче ошибка одна и та же - "неверный email или пароль"
+	user, err := verifyCredentials(s.verifiers, req.Email, req.Password)
 	if err != nil {
-		// Пользователь не найден
-		// ВАЖНО: Не говорим "email не найден" - это утечка информации
-		// Говорим общее "неверные credentials"
-		return nil, errors.New("неверный email или пароль")
+		s.recordLoginFailure(req.Email, client)
+		return nil, err
 	}
 
-	// === ШАГ 2: ПРОВЕРКА ПАРОЛЯ ===
-	// Сравниваем хеш из БД с введённым паролем
-	// password.Verify() использует bcrypt.CompareHashAndPassword()
-	if !password.Verify(user.Password, req.Password) {
-		// Пароль неправильный
-		return nil, errors.New("неверный email или пароль")
+	// Пароль верный, но аккаунт не активен (ожидает активации или заблокирован)
+	// Статус сообщается только после проверки пароля - иначе по ответу
+	// можно было бы узнать статус чужого аккаунта
+	if err := user.CheckStatus(time.Now()); err != nil {
+		event := newAuditEvent(domain.AuditActionLoginBlocked, 0, user.ID, client)
+		event.Details = "статус: " + user.EffectiveStatus(time.Now())
+		s.recordEvent(event)
+		return nil, err
 	}
+	s.recordAudit(domain.AuditActionLoginSucceeded, user.ID, user.ID, client)
+
+	// === ШАГ 2: ПРОЗРАЧНОЕ ОБНОВЛЕНИЕ ХЕША ===
+	// Только сейчас у нас есть проверенный пароль в открытом виде,
+	// поэтому именно здесь можно перевести хеш на новый алгоритм/параметры
+	s.upgradePasswordHash(user, req.Password)
 
 	// === ШАГ 3: ГЕНЕРАЦИЯ JWT ТОКЕНА ===
-	// Парсим время жизни токена
-	expiration, err := time.ParseDuration(s.cfg.JWTExpiration)
+	// === ШАГ 4: ВОЗВРАТ ОТВЕТА ===
+	return s.tokens.Issue(user, client)
+}
+
+// ================================================================
+// CHANGE PASSWORD - Смена пароля текущим пользователем
+// ================================================================
+
+// ChangePassword меняет пароль аутентифицированного пользователя
+// Параметры:
+//   - userID: ID пользователя из JWT токена
+//   - req: текущий и новый пароль
+//   - client: IP и User-Agent (для журнала и письма)
+// Возвращает:
+//   - *domain.AuthResponse: НОВЫЙ JWT токен (старый перестаёт работать)
+//   - error: ошибка смены пароля (*password.PolicyError, если пароль не прошёл политику)
+//
+// Процесс:
+// 1. Загружаем пользователя и проверяем текущий пароль
+// 2. Проверяем новый пароль по политике
+// 3. Хешируем новый пароль и увеличиваем версию токенов
+// 4. Записываем событие в журнал и уведомляем пользователя
+// 5. Выдаём новый токен, чтобы текущий клиент остался в системе
+func (s *authService) ChangePassword(userID uint, req *domain.ChangePasswordRequest, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	// === ШАГ 1: ПРОВЕРКА ТЕКУЩЕГО ПАРОЛЯ ===
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("пользователь не найден")
+	}
+
+	// Пароль LDAP пользователей меняется в каталоге, а не у нас
+	if user.AuthProvider != "" && user.AuthProvider != domain.AuthProviderLocal {
+		return nil, ErrPasswordManagedByIdP
+	}
+
+	if !s.hasher.Verify(user.Password, req.CurrentPassword) {
+		return nil, ErrWrongCurrentPassword
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return nil, ErrPasswordUnchanged
+	}
+
+	// === ШАГ 2: ПРОВЕРКА ПОЛИТИКИ ПАРОЛЕЙ ===
+	if err := s.policy.Check(req.NewPassword, password.PolicyInput{Email: user.Email, Name: user.Name}); err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 3: НОВЫЙ ХЕШ И ОТЗЫВ ТОКЕНОВ ===
+	// Увеличение TokenVersion делает недействительными ВСЕ выданные ранее токены
+	hashedPassword, err := s.hasher.Hash(req.NewPassword)
 	if err != nil {
-		expiration = 24 * time.Hour
+		return nil, errors.New("ошибка хеширования пароля")
 	}
 
-	// Генерируем токен для аутентифицированного пользователя
-	token, err := jwt.GenerateToken(
-		user.ID,
-		user.Email,
-		user.Role,
-		s.cfg.JWTSecret,
-		expiration,
-	)
+	now := time.Now()
+	user.Password = hashedPassword
+	user.PasswordChangedAt = &now
+	user.TokenVersion++
+
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, errors.New("ошибка сохранения пароля")
+	}
+
+	// Токены старых сеансов уже не работают (TokenVersion) - убираем их из списка сеансов
+	if s.sessions != nil {
+		if _, err := s.sessions.RevokeAllExcept(user.ID, "", now); err != nil {
+			log.Printf("⚠️  Не удалось завершить сеансы пользователя %d: %v", user.ID, err)
+		}
+	}
+
+	// === ШАГ 4: ЖУРНАЛ И УВЕДОМЛЕНИЕ ===
+	s.recordAudit(domain.AuditActionPasswordChanged, user.ID, user.ID, client)
+	s.notify(user, "Пароль изменён", fmt.Sprintf(
+		"Здравствуйте, %s!\n\n"+
+			"Пароль вашего аккаунта был изменён %s.\n"+
+			"IP адрес: %s\n\n"+
+			"Все остальные сеансы завершены.\n"+
+			"Если это были не вы, немедленно восстановите доступ и свяжитесь с поддержкой.",
+		user.Name, now.Format("02.01.2006 15:04 MST"), client.IP,
+	))
+
+	// === ШАГ 5: НОВЫЙ ТОКЕН ДЛЯ ТЕКУЩЕГО КЛИЕНТА ===
+	return s.tokens.Issue(user, client)
+}
+
+// ================================================================
+// REAUTHENTICATE - Повторное подтверждение личности
+// ================================================================
+
+// Reauthenticate проверяет, что запрос делает сам владелец аккаунта
+// Одного токена недостаточно: его могли украсть или оставить в открытом браузере
+//
+// Аккаунт с паролем (локальным или LDAP) подтверждается паролем через ту же
+// цепочку источников, что и вход. Аккаунт только с passkey пароля не имеет -
+// для него токен должен быть выдан не раньше reauthMaxTokenAge назад
+func (s *authService) Reauthenticate(userID uint, plainPassword string, tokenIssuedAt time.Time) error {
+	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
-		return nil, errors.New("ошибка генерации токена")
+		return errors.New("пользователь не найден")
 	}
 
-	// === ШАГ 4: ВОЗВРАТ ОТВЕТА ===
-	// Возвращаем токен и данные пользователя
-	return &domain.AuthResponse{
-		Token: token,
-		User:  user,
-	}, nil
+	// Только passkey: локальный аккаунт без пароля
+	if user.Password == "" && (user.AuthProvider == "" || user.AuthProvider == domain.AuthProviderLocal) {
+		if tokenIssuedAt.IsZero() || time.Since(tokenIssuedAt) > reauthMaxTokenAge {
+			return ErrReauthenticationRequired
+		}
+		return nil
+	}
+
+	if plainPassword == "" {
+		return ErrWrongCurrentPassword
+	}
+	verified, err := verifyCredentials(s.verifiers, user.Email, plainPassword)
+	if err != nil || verified.ID != user.ID {
+		return ErrWrongCurrentPassword
+	}
+	return nil
+}
+
+// ================================================================
+// VALIDATE CLAIMS - Проверка отзыва токена
+// ================================================================
+
+// ValidateClaims проверяет, что токен выдан для текущей версии токенов пользователя
+// Вызывается AuthMiddleware после проверки подписи и срока действия
+// Возвращает ErrTokenRevoked, если пароль сменили после выдачи токена,
+// пользователь удалён или исключён из организации токена,
+// или автор токена имперсонации больше не администратор (или заблокирован)
+// Возвращает *domain.AccountStatusError, если аккаунт не активен
+func (s *authService) ValidateClaims(claims *jwt.Claims) error {
+	users := s.userRepo.ForTenant(claims.OrganizationID)
+	user, err := users.FindByID(claims.UserID)
+	if err != nil {
+		return ErrTokenRevoked
+	}
+
+	// Статус проверяется раньше версии: блокировка тоже увеличивает TokenVersion,
+	// но клиент должен получить код блокировки, а не "токен отозван"
+	now := time.Now()
+	if err := user.CheckStatus(now); err != nil {
+		return err
+	}
+	if claims.TokenVersion != user.TokenVersion {
+		return ErrTokenRevoked
+	}
+
+	if claims.Actor != nil {
+		actor, err := users.FindByID(claims.Actor.UserID)
+		if err != nil || actor.CheckStatus(now) != nil {
+			return ErrTokenRevoked
+		}
+		if isAdmin, err := hasRole(actor, "admin", s.roles, claims.OrganizationID); err != nil || !isAdmin {
+			return ErrTokenRevoked
+		}
+	}
+	return nil
+}
+
+// ForTenant - копия сервиса с репозиторием организации orgID
+// Создаётся на каждый запрос (см. handler), поэтому копируется только то,
+// что зависит от организации: репозиторий и источники учётных данных
+func (s *authService) ForTenant(orgID uint) AuthService {
+	scoped := *s
+	scoped.userRepo = s.userRepo.ForTenant(orgID)
+	if s.attributes != nil {
+		scoped.attributes = s.attributes.ForTenant(orgID)
+	}
+
+	scoped.verifiers = make([]CredentialVerifier, len(s.verifiers))
+	for i, verifier := range s.verifiers {
+		if v, ok := verifier.(userScopedVerifier); ok {
+			verifier = v.withUserRepository(scoped.userRepo)
+		}
+		scoped.verifiers[i] = verifier
+	}
+
+	return &scoped
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// registrationAttributes - атрибуты нового пользователя
+// Без схемы атрибутов регистрация возможна только без них
+func (s *authService) registrationAttributes(attributes domain.UserAttributes) (domain.UserAttributes, error) {
+	if s.attributes == nil {
+		if len(attributes) > 0 {
+			return nil, ErrAttributesDisabled
+		}
+		return domain.UserAttributes{}, nil
+	}
+	return s.attributes.Apply(nil, attributes, domain.AttributeAccess{Self: true})
+}
+
+// recordAudit - записывает событие, если журнал подключён
+func (s *authService) recordAudit(action string, actorID, targetID uint, client domain.ClientInfo) {
+	s.recordEvent(newAuditEvent(action, actorID, targetID, client))
+}
+
+// recordEvent - записывает подготовленное событие, если журнал подключён
+func (s *authService) recordEvent(event *domain.AuditEvent) {
+	if s.audit == nil {
+		return
+	}
+	s.audit.Record(event)
 }
 
+// recordLoginFailure - записывает неудачный вход
+// Если аккаунт с таким email существует - он указывается как цель события
+func (s *authService) recordLoginFailure(email string, client domain.ClientInfo) {
+	if s.audit == nil {
+		return
+	}
+
+	var targetID uint
+	if user, err := s.userRepo.FindByEmail(email); err == nil && user != nil {
+		targetID = user.ID
+	}
+
+	event := newAuditEvent(domain.AuditActionLoginFailed, 0, targetID, client)
+	event.Details = "email: " + email
+	s.audit.Record(event)
+}
+
+// notify - отправляет письмо пользователю
+// Ошибка отправки не отменяет уже выполненное действие - только логируется
+func (s *authService) notify(user *domain.User, subject, body string) {
+	err := s.mailer.Send(mailer.Message{To: user.Email, Subject: subject, Body: body})
+	if err != nil {
+		log.Printf("⚠️  Не удалось отправить письмо пользователю %d: %v", user.ID, err)
+	}
+}
+
+// upgradePasswordHash - пересчитывает хеш, созданный устаревшими настройками
+// (bcrypt → argon2id, изменились параметры, добавлен pepper)
+// Ошибка не мешает входу - пользователь уже аутентифицирован,
+// попробуем снова при следующем входе
+func (s *authService) upgradePasswordHash(user *domain.User, plainPassword string) {
+	// Пароли внешних каталогов (LDAP) у нас не хранятся
+	if user.AuthProvider != "" && user.AuthProvider != domain.AuthProviderLocal {
+		return
+	}
+	if !s.hasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	hashedPassword, err := s.hasher.Hash(plainPassword)
+	if err != nil {
+		log.Printf("⚠️  Не удалось пересчитать хеш пароля пользователя %d: %v", user.ID, err)
+		return
+	}
+
+	user.Password = hashedPassword
+	if err := s.userRepo.Update(user); err != nil {
+		log.Printf("⚠️  Не удалось сохранить новый хеш пароля пользователя %d: %v", user.ID, err)
+	}
+}
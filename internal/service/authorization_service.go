@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/policy"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// AUTHORIZATION SERVICE - Решения по правилам доступа (ABAC)
+// ================================================================
+// Роль из токена отвечает на вопрос "кто ты", правила - "что тебе можно
+// с ЭТИМ ресурсом СЕЙЧАС". Правила читаются из POLICY_FILES (см. internal/pkg/policy),
+// атрибуты субъекта - из domain.User, групп и claims токена (см. middleware.RequirePolicy)
+
+// AuthorizationService - интерфейс решений по правилам
+type AuthorizationService interface {
+	// Authorize - решение с учётом POLICY_MODE (dry_run не запрещает) и журналом решений
+	Authorize(req policy.Request) policy.Decision
+
+	// Explain - решение с оценкой каждого правила; не применяется и не пишется в журнал
+	Explain(req policy.Request) policy.Decision
+
+	// SubjectAttributes - атрибуты пользователя как субъекта (поля User + роли групп)
+	SubjectAttributes(userID, orgID uint) (policy.Attributes, error)
+
+	// ResourceAttributes - атрибуты ресурса по типу и ID (сейчас - "user")
+	ResourceAttributes(resourceType string, id, orgID uint) (policy.Attributes, error)
+
+	// Policies / Reload - загруженные правила и перечитывание файлов
+	Policies() []policy.Policy
+	Reload() error
+
+	// RunReload - перечитывает правила при изменении файлов (POLICY_RELOAD_INTERVAL)
+	RunReload(ctx context.Context)
+}
+
+// Режимы применения решений (POLICY_MODE)
+const (
+	PolicyModeEnforce = "enforce"
+	PolicyModeDryRun  = "dry_run"
+)
+
+// Журнал решений (POLICY_DECISION_LOG)
+const (
+	PolicyLogOff  = "off"
+	PolicyLogDeny = "deny"
+	PolicyLogAll  = "all"
+)
+
+// Типы ресурсов
+const (
+	PolicyResourceUser = "user"
+)
+
+var (
+	// ErrPolicyResourceNotFound - ресурс для проверки не найден
+	ErrPolicyResourceNotFound = errors.New("ресурс не найден")
+
+	// ErrPolicyUnknownResource - тип ресурса не поддерживается ResourceAttributes
+	ErrPolicyUnknownResource = errors.New("неизвестный тип ресурса")
+)
+
+// authorizationService - реализация
+type authorizationService struct {
+	engine    *policy.Engine
+	userRepo  repository.UserRepository
+	groupRepo repository.GroupRepository // nil - без ролей групп
+
+	dryRun         bool
+	logMode        string
+	reloadInterval time.Duration
+}
+
+// NewAuthorizationService - загружает правила из POLICY_FILES
+// Возвращает ошибку, если файлы не читаются или правила некорректны
+func NewAuthorizationService(userRepo repository.UserRepository, groupRepo repository.GroupRepository, cfg *config.Config) (AuthorizationService, error) {
+	var paths []string
+	for _, path := range strings.Split(cfg.PolicyFiles, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	location, err := time.LoadLocation(cfg.PolicyTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("POLICY_TIMEZONE: %w", err)
+	}
+
+	engine, err := policy.NewEngine(paths, location)
+	if err != nil {
+		return nil, err
+	}
+
+	interval, err := time.ParseDuration(cfg.PolicyReloadInterval)
+	if err != nil || interval < 0 {
+		interval = 30 * time.Second
+	}
+
+	return &authorizationService{
+		engine:         engine,
+		userRepo:       userRepo,
+		groupRepo:      groupRepo,
+		dryRun:         cfg.PolicyMode == PolicyModeDryRun,
+		logMode:        cfg.PolicyDecisionLog,
+		reloadInterval: interval,
+	}, nil
+}
+
+// Authorize - решение движка + режим dry_run + журнал
+func (s *authorizationService) Authorize(req policy.Request) policy.Decision {
+	decision := s.engine.Evaluate(req)
+	if !decision.Allowed && s.dryRun {
+		decision.Allowed = true
+		decision.DryRun = true
+	}
+
+	if s.logMode == PolicyLogAll || (s.logMode == PolicyLogDeny && decision.Effect != policy.EffectAllow) {
+		suffix := ""
+		if decision.DryRun {
+			suffix = " (dry-run, не применено)"
+		}
+		log.Printf("🛡️  Правила доступа: subject=%v action=%s resource=%s:%v → %s: %s%s",
+			req.Subject["id"], req.Action, req.ResourceType, req.Resource["id"],
+			decision.Effect, decision.Reason, suffix)
+	}
+	return decision
+}
+
+// Explain - решение с трассировкой (для POST /admin/policies/explain)
+func (s *authorizationService) Explain(req policy.Request) policy.Decision {
+	return s.engine.Explain(req)
+}
+
+// SubjectAttributes - атрибуты пользователя + все его роли
+// roles - собственная роль и роли групп (как у RequireRole)
+func (s *authorizationService) SubjectAttributes(userID, orgID uint) (policy.Attributes, error) {
+	user, err := s.userRepo.ForTenant(orgID).FindByID(userID)
+	if err != nil {
+		return nil, ErrPolicyResourceNotFound
+	}
+
+	attrs := UserPolicyAttributes(user, orgID)
+	roles := []interface{}{user.Role}
+	if s.groupRepo != nil {
+		inherited, err := s.groupRepo.ForTenant(orgID).RolesForUser(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, role := range inherited {
+			if role != user.Role {
+				roles = append(roles, role)
+			}
+		}
+	}
+	attrs["roles"] = roles
+	return attrs, nil
+}
+
+// ResourceAttributes - атрибуты ресурса организации orgID
+func (s *authorizationService) ResourceAttributes(resourceType string, id, orgID uint) (policy.Attributes, error) {
+	switch resourceType {
+	case PolicyResourceUser:
+		user, err := s.userRepo.ForTenant(orgID).FindByID(id)
+		if err != nil {
+			return nil, ErrPolicyResourceNotFound
+		}
+		return UserPolicyAttributes(user, orgID), nil
+	}
+	return nil, ErrPolicyUnknownResource
+}
+
+// Policies - загруженные правила
+func (s *authorizationService) Policies() []policy.Policy {
+	return s.engine.Policies()
+}
+
+// Reload - перечитывает файлы правил
+func (s *authorizationService) Reload() error {
+	return s.engine.Reload()
+}
+
+// RunReload - фоновая перезагрузка правил при изменении файлов
+func (s *authorizationService) RunReload(ctx context.Context) {
+	s.engine.RunReload(ctx, s.reloadInterval)
+}
+
+// UserPolicyAttributes - атрибуты пользователя для правил
+// Одинаковы для субъекта (subject.*) и ресурса (resource.*):
+// правило "resource.id eq subject.id" - действие над самим собой,
+// "resource.attributes.region eq subject.attributes.region" - атрибуты по схеме организации
+func UserPolicyAttributes(user *domain.User, orgID uint) policy.Attributes {
+	return policy.Attributes{
+		"attributes":      map[string]interface{}(user.Attributes),
+		"id":              user.ID,
+		"email":           user.Email,
+		"name":            user.Name,
+		"role":            user.Role,
+		"organization_id": orgID,
+		"created_at":      user.CreatedAt,
+	}
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/ldap"
+	"advanced-user-api/internal/pkg/password"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// CREDENTIAL VERIFIERS - Цепочка проверки учётных данных
+// ================================================================
+
+// CredentialVerifier - источник проверки email + пароля
+// AuthService.Login опрашивает verifiers по порядку:
+//   - вернул пользователя → вход успешен
+//   - вернул ErrCredentialsNotApplicable → пробуем следующий источник
+//   - вернул любую другую ошибку → вход отклонён
+type CredentialVerifier interface {
+	// Name - короткое имя источника (для логов)
+	Name() string
+
+	// Verify проверяет учётные данные и возвращает локального пользователя
+	Verify(email, plainPassword string) (*domain.User, error)
+}
+
+// userScopedVerifier - источник, который работает с UserRepository
+// AuthService.ForTenant подменяет репозиторий на репозиторий организации
+type userScopedVerifier interface {
+	withUserRepository(userRepo repository.UserRepository) CredentialVerifier
+}
+
+var (
+	// ErrInvalidCredentials - общая ошибка входа
+	// ВАЖНО: одинаковая для "нет такого email" и "неверный пароль",
+	// чтобы не раскрывать, какие email зарегистрированы
+	ErrInvalidCredentials = errors.New("неверный email или пароль")
+
+	// ErrCredentialsNotApplicable - источник не отвечает за этого пользователя
+	ErrCredentialsNotApplicable = errors.New("источник учётных данных не применим")
+)
+
+// ================================================================
+// LOCAL VERIFIER - Пароль в нашей БД (bcrypt / argon2id)
+// ================================================================
+
+// localVerifier - проверка пароля по хешу из таблицы users
+type localVerifier struct {
+	userRepo repository.UserRepository
+	hasher   *password.Hasher
+}
+
+// NewLocalVerifier - конструктор локального источника
+// hasher определяет pepper; алгоритм проверки выбирается по формату хеша
+func NewLocalVerifier(userRepo repository.UserRepository, hasher *password.Hasher) CredentialVerifier {
+	return &localVerifier{userRepo: userRepo, hasher: hasher}
+}
+
+// Name - имя источника
+func (v *localVerifier) Name() string {
+	return domain.AuthProviderLocal
+}
+
+// withUserRepository - копия источника с другим репозиторием
+func (v *localVerifier) withUserRepository(userRepo repository.UserRepository) CredentialVerifier {
+	scoped := *v
+	scoped.userRepo = userRepo
+	return &scoped
+}
+
+// Verify - находит пользователя по email и сравнивает хеш (bcrypt / argon2id)
+func (v *localVerifier) Verify(email, plainPassword string) (*domain.User, error) {
+	user, err := findUserByEmail(v.userRepo, email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		// Локально такого пользователя нет - возможно, он есть в LDAP
+		return nil, ErrCredentialsNotApplicable
+	}
+
+	// Пользователи из внешних каталогов не имеют локального пароля
+	if user.AuthProvider != "" && user.AuthProvider != domain.AuthProviderLocal {
+		return nil, ErrCredentialsNotApplicable
+	}
+
+	// Hasher.Verify() выбирает алгоритм по формату хеша ($2a$, $argon2id$)
+	if !v.hasher.Verify(user.Password, plainPassword) {
+		// Локальный аккаунт с неверным паролем - дальше НЕ идём,
+		// иначе пароль из каталога мог бы открыть чужой локальный аккаунт
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// ================================================================
+// LDAP VERIFIER - Search-and-bind + just-in-time provisioning
+// ================================================================
+
+// ldapVerifier - проверка через LDAP с созданием/обновлением локального пользователя
+type ldapVerifier struct {
+	userRepo    repository.UserRepository
+	client      *ldap.Client
+	roles       ldap.GroupRoleMapping
+	defaultRole string
+}
+
+// NewLDAPVerifier - конструктор LDAP источника
+// Параметры:
+//   - userRepo: для just-in-time создания локального пользователя
+//   - client: LDAP клиент (search-and-bind)
+//   - roles: сопоставление групп каталога ролям
+//   - defaultRole: роль, если ни одна группа не совпала
+func NewLDAPVerifier(
+	userRepo repository.UserRepository,
+	client *ldap.Client,
+	roles ldap.GroupRoleMapping,
+	defaultRole string,
+) CredentialVerifier {
+	if defaultRole == "" {
+		defaultRole = "user"
+	}
+	return &ldapVerifier{
+		userRepo:    userRepo,
+		client:      client,
+		roles:       roles,
+		defaultRole: defaultRole,
+	}
+}
+
+// Name - имя источника
+func (v *ldapVerifier) Name() string {
+	return domain.AuthProviderLDAP
+}
+
+// withUserRepository - копия источника с другим репозиторием (LDAP клиент общий)
+func (v *ldapVerifier) withUserRepository(userRepo repository.UserRepository) CredentialVerifier {
+	scoped := *v
+	scoped.userRepo = userRepo
+	return &scoped
+}
+
+// Verify - проверяет пароль в каталоге и синхронизирует локального пользователя
+//
+// Процесс:
+// 1. Search-and-bind в LDAP
+// 2. Определяем роль по группам (memberOf)
+// 3. Создаём пользователя при первом входе или обновляем имя/роль
+func (v *ldapVerifier) Verify(email, plainPassword string) (*domain.User, error) {
+	// === ШАГ 1: ПРОВЕРКА В КАТАЛОГЕ ===
+	entry, err := v.client.Authenticate(email, plainPassword)
+	if errors.Is(err, ldap.ErrUserNotFound) {
+		return nil, ErrCredentialsNotApplicable
+	}
+	if errors.Is(err, ldap.ErrInvalidCredentials) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 2: ДАННЫЕ ИЗ КАТАЛОГА ===
+	if entry.Email == "" {
+		entry.Email = email
+	}
+	entry.Email = strings.ToLower(entry.Email)
+	if entry.Name == "" {
+		entry.Name = entry.Email
+	}
+	role := v.roles.Resolve(entry.Groups, v.defaultRole)
+
+	// === ШАГ 3: JUST-IN-TIME PROVISIONING ===
+	user, err := findUserByEmail(v.userRepo, entry.Email)
+	if err != nil {
+		// БД недоступна - не создаём дубликат существующего аккаунта
+		return nil, err
+	}
+	if user == nil {
+		// Первый вход - создаём локальную запись без пароля
+		user = &domain.User{
+			Email:        entry.Email,
+			Name:         entry.Name,
+			Password:     "", // Пароль хранится только в каталоге
+			Role:         role,
+			AuthProvider: domain.AuthProviderLDAP,
+			ExternalID:   entry.DN,
+		}
+		if err := v.userRepo.Create(user); err != nil {
+			return nil, errors.New("ошибка создания пользователя")
+		}
+		return user, nil
+	}
+
+	// Локальный аккаунт с тем же email не "перехватываем" через каталог
+	if user.AuthProvider != domain.AuthProviderLDAP {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Повторный вход - синхронизируем изменившиеся данные
+	if user.Name != entry.Name || user.Role != role || user.ExternalID != entry.DN {
+		user.Name = entry.Name
+		user.Role = role
+		user.ExternalID = entry.DN
+		if err := v.userRepo.Update(user); err != nil {
+			return nil, errors.New("ошибка обновления пользователя")
+		}
+	}
+
+	return user, nil
+}
+
+// ================================================================
+// CHAIN - Сборка и обход цепочки
+// ================================================================
+
+// defaultVerifiers - цепочка по умолчанию, собранная из конфигурации
+// Порядок: сначала локальные аккаунты, затем LDAP (если включён)
+func defaultVerifiers(userRepo repository.UserRepository, cfg *config.Config, hasher *password.Hasher) []CredentialVerifier {
+	verifiers := []CredentialVerifier{NewLocalVerifier(userRepo, hasher)}
+
+	if cfg != nil && cfg.LDAPEnabled {
+		verifiers = append(verifiers, newLDAPVerifierFromConfig(userRepo, cfg))
+	}
+
+	return verifiers
+}
+
+// newLDAPVerifierFromConfig - создаёт LDAP источник по настройкам LDAP_*
+func newLDAPVerifierFromConfig(userRepo repository.UserRepository, cfg *config.Config) CredentialVerifier {
+	timeout, err := time.ParseDuration(cfg.LDAPTimeout)
+	if err != nil {
+		timeout = 5 * time.Second
+	}
+
+	roles, err := ldap.ParseGroupRoles(cfg.LDAPGroupRoles)
+	if err != nil {
+		// Ошибка конфигурации не должна ронять вход - все получат роль по умолчанию
+		log.Printf("⚠️  LDAP_GROUP_ROLES: %v", err)
+	}
+
+	client := ldap.NewClient(ldap.Config{
+		URL:            cfg.LDAPURL,
+		BindDN:         cfg.LDAPBindDN,
+		BindPassword:   cfg.LDAPBindPassword,
+		BaseDN:         cfg.LDAPBaseDN,
+		UserFilter:     cfg.LDAPUserFilter,
+		StartTLS:       cfg.LDAPStartTLS,
+		Timeout:        timeout,
+		EmailAttribute: cfg.LDAPEmailAttribute,
+		NameAttribute:  cfg.LDAPNameAttribute,
+		GroupAttribute: cfg.LDAPGroupAttribute,
+	})
+
+	return NewLDAPVerifier(userRepo, client, roles, cfg.LDAPDefaultRole)
+}
+
+// findUserByEmail - ищет пользователя, отличая "не найден" (nil, nil) от ошибки БД
+func findUserByEmail(userRepo repository.UserRepository, email string) (*domain.User, error) {
+	user, err := userRepo.FindByEmail(email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// verifyCredentials - опрашивает источники по порядку (см. CredentialVerifier)
+func verifyCredentials(verifiers []CredentialVerifier, email, plainPassword string) (*domain.User, error) {
+	for _, verifier := range verifiers {
+		user, err := verifier.Verify(email, plainPassword)
+		if err == nil {
+			return user, nil
+		}
+		if errors.Is(err, ErrCredentialsNotApplicable) {
+			continue
+		}
+		if !errors.Is(err, ErrInvalidCredentials) {
+			// Инфраструктурная ошибка (например, LDAP недоступен)
+			// Клиенту детали не показываем, но логируем
+			log.Printf("⚠️  Ошибка источника учётных данных %s: %v", verifier.Name(), err)
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	// Ни один источник не знает этого пользователя
+	return nil, ErrInvalidCredentials
+}
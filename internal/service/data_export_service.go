@@ -0,0 +1,432 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// DATA EXPORT SERVICE - Выгрузка персональных данных (GDPR)
+// ================================================================
+// 1. Пользователь (или администратор) создаёт задачу - ответ 202 сразу
+// 2. Фоновый обработчик (RunWorker) собирает архив в DATA_EXPORT_DIR
+// 3. Статус задачи содержит подписанную ссылку: HMAC-SHA256 от ID задачи
+//    и срока действия, поэтому скачать архив можно без токена
+// 4. Через DATA_EXPORT_TTL ссылка перестаёт работать, архив удаляется
+
+// DataExportService - интерфейс выгрузки данных
+type DataExportService interface {
+	// Request - создаёт задачу выгрузки данных userID (actorID - кто запросил)
+	Request(userID, actorID uint, req *domain.CreateDataExportRequest, client domain.ClientInfo) (*domain.DataExport, error)
+
+	// Get - задача с подписанной ссылкой (если архив готов)
+	Get(id uint) (*domain.DataExport, error)
+
+	// Open - проверяет подпись ссылки и возвращает путь к архиву
+	Open(id uint, expires, signature string, client domain.ClientInfo) (*DataExportFile, error)
+
+	// ProcessPending - собирает архивы всех ожидающих задач
+	ProcessPending() (int, error)
+
+	// ExpireArchives - удаляет архивы с истёкшей ссылкой
+	ExpireArchives(now time.Time) (int, error)
+
+	// RunWorker - обработка задач сразу после запроса и раз в interval до отмены ctx
+	RunWorker(ctx context.Context, interval time.Duration)
+
+	ArchiveRemover
+
+	// ForTenant - сервис, работающий только с пользователями организации orgID
+	ForTenant(orgID uint) DataExportService
+}
+
+// ArchiveRemover - удаление файлов архивов, записи о которых уже удалены
+// (окончательное удаление аккаунта)
+type ArchiveRemover interface {
+	RemoveArchiveFiles(fileNames []string)
+}
+
+// DataExportFile - архив для отдачи клиенту
+type DataExportFile struct {
+	Path string // Путь к файлу на диске
+	Name string // Имя файла для Content-Disposition
+}
+
+var (
+	// ErrDataExportNotFound - задача не существует (или пользователь в другой организации)
+	ErrDataExportNotFound = errors.New("выгрузка не найдена")
+
+	// ErrDataExportUserNotFound - пользователь для выгрузки не найден
+	ErrDataExportUserNotFound = errors.New("пользователь не найден")
+
+	// ErrDataExportInProgress - предыдущая выгрузка пользователя ещё собирается
+	ErrDataExportInProgress = errors.New("выгрузка данных уже выполняется")
+
+	// ErrDataExportLinkInvalid - подпись неверна, срок истёк или архив удалён
+	// ВАЖНО: одна ошибка на все случаи, чтобы ссылку нельзя было подобрать
+	ErrDataExportLinkInvalid = errors.New("ссылка недействительна или истекла")
+)
+
+// dataExportStaleAfter - задача processing дольше этого считается зависшей
+const dataExportStaleAfter = 15 * time.Minute
+
+// dataExportService - реализация
+type dataExportService struct {
+	exports  repository.DataExportRepository
+	userRepo repository.UserRepository
+	audit    AuditService // nil - события не пишем
+	cfg      *config.Config
+
+	// wake - сигнал обработчику о новой задаче (буфер 1, общий для копий ForTenant)
+	wake chan struct{}
+}
+
+// NewDataExportService - конструктор
+func NewDataExportService(exports repository.DataExportRepository, userRepo repository.UserRepository, audit AuditService, cfg *config.Config) DataExportService {
+	return &dataExportService{
+		exports:  exports,
+		userRepo: userRepo,
+		audit:    audit,
+		cfg:      cfg,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// ForTenant - копия сервиса с репозиторием организации orgID
+func (s *dataExportService) ForTenant(orgID uint) DataExportService {
+	scoped := *s
+	scoped.userRepo = s.userRepo.ForTenant(orgID)
+	return &scoped
+}
+
+// ================================================================
+// ЗАДАЧИ
+// ================================================================
+
+// Request - новая задача выгрузки
+func (s *dataExportService) Request(userID, actorID uint, req *domain.CreateDataExportRequest, client domain.ClientInfo) (*domain.DataExport, error) {
+	// === ШАГ 1: ПРОВЕРКИ ===
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return nil, ErrDataExportUserNotFound
+	}
+	if _, err := s.exports.FindActive(userID); err == nil {
+		return nil, ErrDataExportInProgress
+	}
+
+	format := req.Format
+	if format == "" {
+		format = domain.DataExportFormatZIP
+	}
+
+	// === ШАГ 2: СОЗДАНИЕ ===
+	export := &domain.DataExport{
+		UserID:        userID,
+		RequestedByID: actorID,
+		Format:        format,
+		Status:        domain.DataExportStatusPending,
+	}
+	if err := s.exports.Create(export); err != nil {
+		return nil, errors.New("ошибка создания выгрузки")
+	}
+
+	// === ШАГ 3: ОБРАБОТЧИК И ЖУРНАЛ ===
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionDataExportRequested, actorID, userID, client)
+		event.Details = "format: " + format
+		s.audit.Record(event)
+	}
+	return export, nil
+}
+
+// Get - задача пользователя организации
+func (s *dataExportService) Get(id uint) (*domain.DataExport, error) {
+	export, err := s.exports.FindByID(id)
+	if err != nil {
+		return nil, ErrDataExportNotFound
+	}
+	if _, err := s.userRepo.FindByID(export.UserID); err != nil {
+		return nil, ErrDataExportNotFound
+	}
+
+	if export.Status == domain.DataExportStatusCompleted && export.ExpiresAt != nil {
+		export.DownloadURL = s.downloadURL(export)
+	}
+	return export, nil
+}
+
+// Open - проверка ссылки на скачивание
+func (s *dataExportService) Open(id uint, expires, signature string, client domain.ClientInfo) (*DataExportFile, error) {
+	// === ШАГ 1: ПОДПИСЬ И СРОК ===
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(s.sign(id, expiresAt))) {
+		return nil, ErrDataExportLinkInvalid
+	}
+	if time.Now().Unix() >= expiresAt {
+		return nil, ErrDataExportLinkInvalid
+	}
+
+	// === ШАГ 2: АРХИВ ===
+	export, err := s.exports.FindByID(id)
+	if err != nil || export.Status != domain.DataExportStatusCompleted || export.FileName == "" {
+		return nil, ErrDataExportLinkInvalid
+	}
+
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionDataExportDownloaded, 0, export.UserID, client)
+		event.Details = "export: " + strconv.FormatUint(uint64(export.ID), 10)
+		s.audit.Record(event)
+	}
+
+	return &DataExportFile{
+		Path: filepath.Join(s.cfg.DataExportDir, export.FileName),
+		Name: fmt.Sprintf("user-%d-data.%s", export.UserID, export.Format),
+	}, nil
+}
+
+// downloadURL - DATA_EXPORT_URL/:id/download?expires=...&signature=...
+func (s *dataExportService) downloadURL(export *domain.DataExport) string {
+	expires := export.ExpiresAt.Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(export.ID, expires))
+	return fmt.Sprintf("%s/%d/download?%s", strings.TrimRight(s.cfg.DataExportURL, "/"), export.ID, query.Encode())
+}
+
+// sign - HMAC-SHA256("data-export:<id>:<expires>") ключом JWT_SECRET (base64url)
+// Префикс отделяет эти подписи от любых других, сделанных тем же ключом
+func (s *dataExportService) sign(id uint, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.JWTSecret))
+	fmt.Fprintf(mac, "data-export:%d:%d", id, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ================================================================
+// ФОНОВЫЙ ОБРАБОТЧИК
+// ================================================================
+
+// ProcessPending - берёт задачи по одной, пока они есть
+func (s *dataExportService) ProcessPending() (int, error) {
+	count := 0
+	for {
+		now := time.Now()
+		export, err := s.exports.ClaimPending(now, now.Add(-dataExportStaleAfter))
+		if errors.Is(err, repository.ErrDataExportRecordNotFound) {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+
+		s.process(export)
+		count++
+	}
+}
+
+// process - собирает архив одной задачи и сохраняет результат
+func (s *dataExportService) process(export *domain.DataExport) {
+	fileName, size, err := s.build(export)
+	if err != nil {
+		log.Printf("⚠️  Ошибка выгрузки данных %d: %v", export.ID, err)
+		export.Status = domain.DataExportStatusFailed
+		export.Error = "не удалось собрать архив, запросите выгрузку заново"
+	} else {
+		completedAt := time.Now()
+		expiresAt := completedAt.Add(dataExportTTL(s.cfg))
+		export.Status = domain.DataExportStatusCompleted
+		export.FileName = fileName
+		export.Size = size
+		export.CompletedAt = &completedAt
+		export.ExpiresAt = &expiresAt
+	}
+
+	if err := s.exports.Update(export); err != nil {
+		log.Printf("⚠️  Не удалось сохранить выгрузку %d: %v", export.ID, err)
+	}
+}
+
+// build - пишет архив во временный файл и переименовывает его после успешной записи
+func (s *dataExportService) build(export *domain.DataExport) (string, int64, error) {
+	archive, err := s.exports.CollectUserData(export.UserID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if err := os.MkdirAll(s.cfg.DataExportDir, 0o700); err != nil {
+		return "", 0, err
+	}
+
+	// Имя файла случайное: знание ID задачи не позволяет найти архив на диске
+	suffix, err := generateMagicLinkToken()
+	if err != nil {
+		return "", 0, err
+	}
+	fileName := fmt.Sprintf("export-%d-%s.%s", export.ID, suffix[:16], export.Format)
+	path := filepath.Join(s.cfg.DataExportDir, fileName)
+
+	file, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", 0, err
+	}
+	if export.Format == domain.DataExportFormatJSON {
+		err = writeDataExportJSON(file, archive)
+	} else {
+		err = writeDataExportZIP(file, archive)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return "", 0, err
+	}
+
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return "", 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	return fileName, info.Size(), nil
+}
+
+// RemoveArchiveFiles - удаляет файлы архивов из DATA_EXPORT_DIR (ошибки только в лог)
+func (s *dataExportService) RemoveArchiveFiles(fileNames []string) {
+	for _, name := range fileNames {
+		if err := os.Remove(filepath.Join(s.cfg.DataExportDir, name)); err != nil && !os.IsNotExist(err) {
+			log.Printf("⚠️  Не удалось удалить архив выгрузки %s: %v", name, err)
+		}
+	}
+}
+
+// ExpireArchives - удаляет файлы архивов с истёкшей ссылкой
+func (s *dataExportService) ExpireArchives(now time.Time) (int, error) {
+	exports, err := s.exports.FindExpired(now)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := range exports {
+		export := &exports[i]
+		if err := os.Remove(filepath.Join(s.cfg.DataExportDir, export.FileName)); err != nil && !os.IsNotExist(err) {
+			log.Printf("⚠️  Не удалось удалить архив выгрузки %d: %v", export.ID, err)
+			continue
+		}
+
+		export.Status = domain.DataExportStatusExpired
+		export.FileName = ""
+		if err := s.exports.Update(export); err != nil {
+			log.Printf("⚠️  Не удалось сохранить выгрузку %d: %v", export.ID, err)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// RunWorker - фоновая обработка задач (сразу, по сигналу Request и раз в interval)
+func (s *dataExportService) RunWorker(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := s.ProcessPending(); err != nil {
+			log.Printf("⚠️  Ошибка обработки выгрузок данных: %v", err)
+		} else if count > 0 {
+			log.Printf("📦 Обработано выгрузок данных: %d", count)
+		}
+		if _, err := s.ExpireArchives(time.Now()); err != nil {
+			log.Printf("⚠️  Ошибка удаления истёкших выгрузок: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// ================================================================
+// ФОРМАТ АРХИВА
+// ================================================================
+
+// writeDataExportJSON - архив (или его раздел) одним JSON документом
+func writeDataExportJSON(w io.Writer, data interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// writeDataExportZIP - каждый раздел отдельным файлом
+func writeDataExportZIP(w io.Writer, archive *domain.UserDataArchive) error {
+	sections := []struct {
+		name string
+		data interface{}
+	}{
+		{"export.json", map[string]interface{}{"generated_at": archive.GeneratedAt, "user_id": archive.User.ID}},
+		{"user.json", archive.User},
+		{"profile.json", archive.Profile},
+		{"attributes.json", archive.Attributes},
+		{"identities.json", archive.Identities},
+		{"sessions.json", archive.Sessions},
+		{"organizations.json", archive.Organizations},
+		{"groups.json", archive.Groups},
+		{"status_history.json", archive.StatusHistory},
+		{"audit_events.json", archive.AuditEvents},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, section := range sections {
+		file, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     section.name,
+			Method:   zip.Deflate,
+			Modified: archive.GeneratedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if err := writeDataExportJSON(file, section.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// dataExportTTL - срок действия ссылки на архив (по умолчанию сутки)
+func dataExportTTL(cfg *config.Config) time.Duration {
+	ttl, err := time.ParseDuration(cfg.DataExportTTL)
+	if err != nil || ttl <= 0 {
+		return 24 * time.Hour
+	}
+	return ttl
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// ERASURE SERVICE - Право на удаление персональных данных (GDPR)
+// ================================================================
+// 1. Пользователь подтверждает личность (пароль или недавний вход с passkey)
+//    и создаёт запрос; администратор создаёт запрос с основанием
+// 2. ERASURE_COOLING_OFF_DAYS дней запрос можно отменить, аккаунт работает
+// 3. Фоновая задача (RunErasure) обезличивает пользователя:
+//    учётные данные, сеансы, членство и выгрузки удаляются, профиль
+//    заменяется заглушкой, запрос остаётся как подтверждение стирания
+
+// ErasureService - интерфейс стирания данных
+type ErasureService interface {
+	// RequestOwn - запрос на стирание своего аккаунта (DELETE /users/me)
+	// tokenIssuedAt - время выдачи токена (повторная аутентификация без пароля)
+	RequestOwn(userID uint, req *domain.EraseAccountRequest, tokenIssuedAt time.Time, client domain.ClientInfo) (*domain.UserErasure, error)
+
+	// Request - запрос на стирание данных userID администратором actorID
+	Request(userID, actorID uint, req *domain.CreateErasureRequest, client domain.ClientInfo) (*domain.UserErasure, error)
+
+	// Get - последний запрос пользователя (ErrErasureNotFound - запросов не было)
+	Get(userID uint) (*domain.UserErasure, error)
+
+	// Cancel - отмена запроса пользователя, пока не истёк период отмены
+	Cancel(userID, actorID uint, client domain.ClientInfo) (*domain.UserErasure, error)
+
+	// List - запросы организации (для DPO и администраторов)
+	List(filter domain.ErasureFilter) ([]domain.UserErasure, error)
+
+	// ProcessDue - выполняет запросы, период отмены которых истёк к now
+	ProcessDue(now time.Time) (int, error)
+
+	// RunErasure - выполнение запросов сразу и затем раз в interval до отмены ctx
+	RunErasure(ctx context.Context, interval time.Duration)
+
+	// ForTenant - сервис, работающий только с пользователями организации orgID
+	ForTenant(orgID uint) ErasureService
+}
+
+// Reauthenticator - повторная проверка личности (реализует AuthService)
+type Reauthenticator interface {
+	Reauthenticate(userID uint, plainPassword string, tokenIssuedAt time.Time) error
+}
+
+var (
+	// ErrErasureNotFound - запроса на стирание нет (или пользователь в другой организации)
+	ErrErasureNotFound = errors.New("запрос на стирание не найден")
+
+	// ErrErasureUserNotFound - пользователь для стирания не найден
+	ErrErasureUserNotFound = errors.New("пользователь не найден")
+
+	// ErrErasurePending - у пользователя уже есть запрос, ожидающий выполнения
+	ErrErasurePending = errors.New("запрос на стирание уже создан")
+)
+
+// erasureService - реализация
+type erasureService struct {
+	erasures repository.ErasureRepository
+	userRepo repository.UserRepository
+	auth     Reauthenticator // Повторная аутентификация для RequestOwn
+	avatars  AvatarRemover   // Файлы аватаров (nil - не удаляем)
+	audit    AuditService    // nil - события не пишем
+	cfg      *config.Config
+}
+
+// NewErasureService - конструктор
+func NewErasureService(erasures repository.ErasureRepository, userRepo repository.UserRepository, auth Reauthenticator, avatars AvatarRemover, audit AuditService, cfg *config.Config) ErasureService {
+	return &erasureService{
+		erasures: erasures,
+		userRepo: userRepo,
+		auth:     auth,
+		avatars:  avatars,
+		audit:    audit,
+		cfg:      cfg,
+	}
+}
+
+// ForTenant - копия сервиса с репозиториями организации orgID
+func (s *erasureService) ForTenant(orgID uint) ErasureService {
+	scoped := *s
+	scoped.erasures = s.erasures.ForTenant(orgID)
+	scoped.userRepo = s.userRepo.ForTenant(orgID)
+	if auth, ok := s.auth.(AuthService); ok {
+		scoped.auth = auth.ForTenant(orgID)
+	}
+	return &scoped
+}
+
+// ================================================================
+// ЗАПРОСЫ
+// ================================================================
+
+// RequestOwn - пользователь удаляет свой аккаунт
+func (s *erasureService) RequestOwn(userID uint, req *domain.EraseAccountRequest, tokenIssuedAt time.Time, client domain.ClientInfo) (*domain.UserErasure, error) {
+	// Токена недостаточно: стирание необратимо
+	if err := s.auth.Reauthenticate(userID, req.Password, tokenIssuedAt); err != nil {
+		return nil, err
+	}
+	return s.create(userID, userID, req.Reason, false, client)
+}
+
+// Request - администратор запрашивает стирание данных пользователя
+func (s *erasureService) Request(userID, actorID uint, req *domain.CreateErasureRequest, client domain.ClientInfo) (*domain.UserErasure, error) {
+	return s.create(userID, actorID, req.Reason, req.Immediate, client)
+}
+
+// create - общая часть RequestOwn и Request
+func (s *erasureService) create(userID, actorID uint, reason string, immediate bool, client domain.ClientInfo) (*domain.UserErasure, error) {
+	// === ШАГ 1: ПРОВЕРКИ ===
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrErasureUserNotFound
+	}
+	if _, err := s.erasures.FindPending(userID); err == nil {
+		return nil, ErrErasurePending
+	}
+
+	// === ШАГ 2: ЗАПРОС ===
+	now := time.Now()
+	scheduledFor := now
+	if !immediate {
+		scheduledFor = now.AddDate(0, 0, s.cfg.ErasureCoolingOffDays)
+	}
+
+	erasure := &domain.UserErasure{
+		UserID:         user.ID,
+		OrganizationID: user.OrganizationID,
+		RequestedByID:  actorID,
+		Reason:         reason,
+		Status:         domain.ErasureStatusPending,
+		ScheduledFor:   scheduledFor,
+	}
+	if err := s.erasures.Create(erasure); err != nil {
+		return nil, errors.New("ошибка создания запроса на стирание")
+	}
+
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionErasureRequested, actorID, user.ID, client)
+		event.Details = "scheduled_for: " + scheduledFor.UTC().Format(time.RFC3339)
+		if reason != "" {
+			event.Details += ", причина: " + reason
+		}
+		s.audit.Record(event)
+	}
+
+	// === ШАГ 3: БЕЗ ПЕРИОДА ОТМЕНЫ - СРАЗУ ===
+	if !scheduledFor.After(now) {
+		if err := s.erase(erasure, now); err != nil {
+			return nil, err
+		}
+	}
+	return erasure, nil
+}
+
+// Get - последний запрос пользователя организации
+func (s *erasureService) Get(userID uint) (*domain.UserErasure, error) {
+	erasure, err := s.erasures.FindLatest(userID)
+	if err != nil {
+		return nil, ErrErasureNotFound
+	}
+	return erasure, nil
+}
+
+// Cancel - отмена ожидающего запроса
+func (s *erasureService) Cancel(userID, actorID uint, client domain.ClientInfo) (*domain.UserErasure, error) {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return nil, ErrErasureUserNotFound
+	}
+	erasure, err := s.erasures.FindPending(userID)
+	if err != nil {
+		return nil, ErrErasureNotFound
+	}
+
+	now := time.Now()
+	erasure.Status = domain.ErasureStatusCancelled
+	erasure.CancelledByID = &actorID
+	erasure.CancelledAt = &now
+	if err := s.erasures.Update(erasure); err != nil {
+		return nil, errors.New("ошибка отмены запроса на стирание")
+	}
+
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionErasureCancelled, actorID, userID, client)
+		event.Details = fmt.Sprintf("запрос: %d", erasure.ID)
+		s.audit.Record(event)
+	}
+	return erasure, nil
+}
+
+// List - запросы организации
+func (s *erasureService) List(filter domain.ErasureFilter) ([]domain.UserErasure, error) {
+	return s.erasures.List(filter)
+}
+
+// ================================================================
+// ВЫПОЛНЕНИЕ
+// ================================================================
+
+// ProcessDue - стирание по всем запросам с истёкшим периодом отмены
+// Ошибка одного запроса не останавливает остальные: он будет повторён в следующий раз
+func (s *erasureService) ProcessDue(now time.Time) (int, error) {
+	due, err := s.erasures.FindDue(now)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := range due {
+		if err := s.erase(&due[i], now); err != nil {
+			log.Printf("⚠️  Ошибка стирания данных пользователя %d (запрос %d): %v", due[i].UserID, due[i].ID, err)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// RunErasure - фоновое выполнение запросов
+func (s *erasureService) RunErasure(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := s.ProcessDue(time.Now())
+		if err != nil {
+			log.Printf("⚠️  Ошибка выполнения запросов на стирание: %v", err)
+		} else if count > 0 {
+			log.Printf("🧹 Стёрты данные пользователей: %d", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// erase - обезличивание пользователя и удаление архивов выгрузки
+func (s *erasureService) erase(erasure *domain.UserErasure, now time.Time) error {
+	files, err := s.erasures.Erase(erasure, now)
+	if err != nil {
+		if errors.Is(err, repository.ErrErasureRecordNotFound) {
+			// Запрос отменили, пока до него дошла очередь
+			return ErrErasureNotFound
+		}
+		return errors.New("ошибка стирания данных пользователя")
+	}
+
+	// Записи о выгрузках и аватар уже удалены - файлы больше ни на что не ссылаются
+	for _, name := range files.Exports {
+		if err := os.Remove(filepath.Join(s.cfg.DataExportDir, name)); err != nil && !os.IsNotExist(err) {
+			log.Printf("⚠️  Не удалось удалить архив выгрузки %s: %v", name, err)
+		}
+	}
+	if s.avatars != nil {
+		s.avatars.RemoveAvatarFiles(files.AvatarID)
+	}
+
+	// Email и имя в событие не пишем - их только что стёрли
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionUserErased, erasure.RequestedByID, erasure.UserID, domain.ClientInfo{})
+		event.Details = fmt.Sprintf("запрос: %d", erasure.ID)
+		s.audit.Record(event)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/pkg/fieldcrypt"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// FIELD ENCRYPTION SERVICE - Шифрование персональных данных в БД
+// ================================================================
+// Поля domain.User с тегом serializer:encrypted шифруются прозрачно
+// (см. internal/pkg/fieldcrypt). Сервис отвечает за перешифрование:
+// после смены текущего ключа, добавления поля в FIELD_ENCRYPTION_FIELDS
+// или первого включения шифрования старые записи перезаписываются
+// в фоне небольшими порциями
+
+// FieldEncryptionService - перешифрование записей
+type FieldEncryptionService interface {
+	// Reencrypt - перешифровывает все устаревшие записи
+	Reencrypt() (int, error)
+
+	// RunReencryption - перешифрование сразу и затем раз в interval до отмены ctx
+	// interval <= 0 - только один проход при запуске
+	RunReencryption(ctx context.Context, interval time.Duration)
+}
+
+// fieldEncryptionBatch - записей в одной транзакции перешифрования
+const fieldEncryptionBatch = 200
+
+// NewFieldEncryptor - Encryptor по настройкам FIELD_ENCRYPTION_*
+// nil без ошибки - FIELD_ENCRYPTION_KEY_FILE не задан, шифрование выключено
+func NewFieldEncryptor(cfg *config.Config) (*fieldcrypt.Encryptor, error) {
+	if cfg.FieldEncryptionKeyFile == "" {
+		return nil, nil
+	}
+
+	keys, err := fieldcrypt.NewFileKeyProvider(cfg.FieldEncryptionKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return fieldcrypt.NewEncryptor(keys, strings.Split(cfg.FieldEncryptionFields, ","))
+}
+
+// fieldEncryptionService - реализация
+type fieldEncryptionService struct {
+	repo repository.FieldEncryptionRepository
+}
+
+// NewFieldEncryptionService - конструктор
+func NewFieldEncryptionService(repo repository.FieldEncryptionRepository) FieldEncryptionService {
+	return &fieldEncryptionService{repo: repo}
+}
+
+// Reencrypt - порции по fieldEncryptionBatch, пока устаревшие записи не закончатся
+func (s *fieldEncryptionService) Reencrypt() (int, error) {
+	total := 0
+	for {
+		count, err := s.repo.ReencryptUsers(fieldEncryptionBatch)
+		total += count
+		if err != nil || count < fieldEncryptionBatch {
+			return total, err
+		}
+	}
+}
+
+// RunReencryption - фоновое перешифрование
+func (s *fieldEncryptionService) RunReencryption(ctx context.Context, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		count, err := s.Reencrypt()
+		if err != nil {
+			log.Printf("⚠️  Ошибка перешифрования персональных данных: %v", err)
+		} else if count > 0 {
+			log.Printf("🔐 Перешифрованы записи пользователей: %d", count)
+		}
+
+		if tick == nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// GROUP SERVICE - Группы пользователей и роли групп
+// ================================================================
+// Роль, выданная группе, действует для участников группы и всех вложенных
+// в неё групп: middleware.RequireRole проверяет её через InheritedRoles
+
+// GroupService - интерфейс для работы с группами
+type GroupService interface {
+	Create(req *domain.CreateGroupRequest) (*domain.Group, error)
+	Get(id uint) (*domain.Group, error)
+	List() ([]domain.Group, error)
+	Update(id uint, req *domain.UpdateGroupRequest) (*domain.Group, error)
+	Delete(id, actorID uint, client domain.ClientInfo) error
+
+	// Участники: actorID, client - кто изменяет (для журнала)
+	ListMembers(groupID uint) ([]domain.User, error)
+	AddMember(groupID, userID, actorID uint, client domain.ClientInfo) error
+	RemoveMember(groupID, userID, actorID uint, client domain.ClientInfo) error
+
+	// Роли групп
+	GrantRole(groupID uint, role string, actorID uint, client domain.ClientInfo) (*domain.Group, error)
+	RevokeRole(groupID uint, role string, actorID uint, client domain.ClientInfo) (*domain.Group, error)
+
+	// InheritedRoles - роли пользователя от групп организации orgID
+	// (реализует middleware.RoleProvider)
+	InheritedRoles(userID, orgID uint) ([]string, error)
+
+	// ForTenant - сервис, работающий только с группами и пользователями организации orgID
+	ForTenant(orgID uint) GroupService
+}
+
+var (
+	// ErrGroupNotFound - группа не существует (или принадлежит другой организации)
+	ErrGroupNotFound = errors.New("группа не найдена")
+
+	// ErrGroupNameTaken - название группы уже занято в организации
+	ErrGroupNameTaken = errors.New("группа с таким названием уже существует")
+
+	// ErrGroupCycle - группа не может быть вложена сама в себя или в своего потомка
+	ErrGroupCycle = errors.New("группа не может быть вложена в саму себя или в свою вложенную группу")
+
+	// ErrInvalidGroupRole - недопустимое имя роли
+	ErrInvalidGroupRole = errors.New("роль может содержать только латиницу в нижнем регистре, цифры, '_' и '-' (2-32 символа)")
+
+	// ErrGroupUserNotFound - пользователь не найден в организации
+	ErrGroupUserNotFound = errors.New("пользователь не найден")
+
+	// ErrGroupMemberNotFound - пользователь не состоит в группе напрямую
+	ErrGroupMemberNotFound = errors.New("пользователь не состоит в группе")
+
+	// ErrGroupRoleNotFound - у группы нет такой роли
+	ErrGroupRoleNotFound = errors.New("у группы нет такой роли")
+)
+
+// RoleProvider - роли пользователя, унаследованные от групп (реализует GroupService)
+// Сервисам, которые сами проверяют роль (имперсонация), нужна та же
+// проверка, что и middleware.HasRole: иначе администратор через группу
+// проходит RequireRole, но не проверку в сервисе
+type RoleProvider interface {
+	InheritedRoles(userID, orgID uint) ([]string, error)
+}
+
+// hasRole - есть ли у пользователя роль role: собственная (users.role)
+// или унаследованная от групп организации orgID (roles nil - только собственная)
+func hasRole(user *domain.User, role string, roles RoleProvider, orgID uint) (bool, error) {
+	if user.Role == role {
+		return true, nil
+	}
+	if roles == nil {
+		return false, nil
+	}
+
+	inherited, err := roles.InheritedRoles(user.ID, orgID)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(inherited, role), nil
+}
+
+// groupRolePattern - допустимое имя роли (как users.role: "admin", "support_l2")
+var groupRolePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+// groupService - реализация
+type groupService struct {
+	groupRepo repository.GroupRepository
+	userRepo  repository.UserRepository
+	audit     AuditService // nil - события не пишем
+}
+
+// NewGroupService - конструктор
+func NewGroupService(groupRepo repository.GroupRepository, userRepo repository.UserRepository, audit AuditService) GroupService {
+	return &groupService{groupRepo: groupRepo, userRepo: userRepo, audit: audit}
+}
+
+// ForTenant - копия сервиса с репозиториями организации orgID
+func (s *groupService) ForTenant(orgID uint) GroupService {
+	scoped := *s
+	scoped.groupRepo = s.groupRepo.ForTenant(orgID)
+	scoped.userRepo = s.userRepo.ForTenant(orgID)
+	return &scoped
+}
+
+// ================================================================
+// ГРУППЫ
+// ================================================================
+
+// Create - создаёт группу (при ParentID - вложенную)
+func (s *groupService) Create(req *domain.CreateGroupRequest) (*domain.Group, error) {
+	// === ШАГ 1: ПРОВЕРКИ ===
+	if err := s.ensureNameFree(req.Name, 0); err != nil {
+		return nil, err
+	}
+	if req.ParentID != nil {
+		if _, err := s.Get(*req.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	// === ШАГ 2: СОХРАНЕНИЕ ===
+	group := &domain.Group{
+		Name:        req.Name,
+		Description: req.Description,
+		ParentID:    req.ParentID,
+		Roles:       []string{},
+	}
+	if err := s.groupRepo.Create(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// Get - группа по ID
+func (s *groupService) Get(id uint) (*domain.Group, error) {
+	group, err := s.groupRepo.FindByID(id)
+	if errors.Is(err, repository.ErrGroupRecordNotFound) {
+		return nil, ErrGroupNotFound
+	}
+	return group, err
+}
+
+// List - все группы организации
+func (s *groupService) List() ([]domain.Group, error) {
+	return s.groupRepo.FindAll()
+}
+
+// Update - меняет название, описание или родителя группы
+// ParentID = 0 - сделать группой верхнего уровня
+func (s *groupService) Update(id uint, req *domain.UpdateGroupRequest) (*domain.Group, error) {
+	// === ШАГ 1: ПОИСК ===
+	group, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 2: ИЗМЕНЕНИЕ ПОЛЕЙ ===
+	if req.Name != nil && *req.Name != group.Name {
+		if err := s.ensureNameFree(*req.Name, id); err != nil {
+			return nil, err
+		}
+		group.Name = *req.Name
+	}
+	if req.Description != nil {
+		group.Description = *req.Description
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			group.ParentID = nil
+		} else {
+			if err := s.ensureNoCycle(id, *req.ParentID); err != nil {
+				return nil, err
+			}
+			parentID := *req.ParentID
+			group.ParentID = &parentID
+		}
+	}
+
+	// === ШАГ 3: СОХРАНЕНИЕ ===
+	if err := s.groupRepo.Update(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// Delete - удаляет группу (вложенные группы переходят к её родителю)
+func (s *groupService) Delete(id, actorID uint, client domain.ClientInfo) error {
+	group, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.groupRepo.Delete(id); err != nil {
+		return err
+	}
+
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionGroupDeleted, actorID, 0, client)
+		event.Details = groupDetails(group, "")
+		s.audit.Record(event)
+	}
+	return nil
+}
+
+// ================================================================
+// УЧАСТНИКИ
+// ================================================================
+
+// ListMembers - прямые участники группы
+func (s *groupService) ListMembers(groupID uint) ([]domain.User, error) {
+	if _, err := s.Get(groupID); err != nil {
+		return nil, err
+	}
+	return s.groupRepo.ListMembers(groupID)
+}
+
+// AddMember - добавляет пользователя организации в группу
+func (s *groupService) AddMember(groupID, userID, actorID uint, client domain.ClientInfo) error {
+	// === ШАГ 1: ПРОВЕРКИ ===
+	// Группа и пользователь - из одной организации (репозитории ограничены ForTenant)
+	group, err := s.Get(groupID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return ErrGroupUserNotFound
+	}
+
+	// === ШАГ 2: ДОБАВЛЕНИЕ ===
+	if err := s.groupRepo.AddMember(groupID, userID); err != nil {
+		return err
+	}
+
+	// === ШАГ 3: ЖУРНАЛ ===
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionGroupMemberAdded, actorID, userID, client)
+		event.Details = groupDetails(group, "")
+		s.audit.Record(event)
+	}
+	return nil
+}
+
+// RemoveMember - убирает пользователя из группы
+func (s *groupService) RemoveMember(groupID, userID, actorID uint, client domain.ClientInfo) error {
+	group, err := s.Get(groupID)
+	if err != nil {
+		return err
+	}
+
+	err = s.groupRepo.RemoveMember(groupID, userID)
+	if errors.Is(err, repository.ErrGroupRecordNotFound) {
+		return ErrGroupMemberNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionGroupMemberRemoved, actorID, userID, client)
+		event.Details = groupDetails(group, "")
+		s.audit.Record(event)
+	}
+	return nil
+}
+
+// ================================================================
+// РОЛИ ГРУПП
+// ================================================================
+
+// GrantRole - выдаёт роль группе, её получают все участники группы и вложенных групп
+func (s *groupService) GrantRole(groupID uint, role string, actorID uint, client domain.ClientInfo) (*domain.Group, error) {
+	// === ШАГ 1: ПРОВЕРКИ ===
+	if !groupRolePattern.MatchString(role) {
+		return nil, ErrInvalidGroupRole
+	}
+	group, err := s.Get(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 2: ВЫДАЧА ===
+	if err := s.groupRepo.AddRole(groupID, role); err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 3: ЖУРНАЛ ===
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionGroupRoleGranted, actorID, 0, client)
+		event.Details = groupDetails(group, role)
+		s.audit.Record(event)
+	}
+	return s.Get(groupID)
+}
+
+// RevokeRole - отзывает роль у группы
+func (s *groupService) RevokeRole(groupID uint, role string, actorID uint, client domain.ClientInfo) (*domain.Group, error) {
+	group, err := s.Get(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.groupRepo.RemoveRole(groupID, role)
+	if errors.Is(err, repository.ErrGroupRecordNotFound) {
+		return nil, ErrGroupRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionGroupRoleRevoked, actorID, 0, client)
+		event.Details = groupDetails(group, role)
+		s.audit.Record(event)
+	}
+	return s.Get(groupID)
+}
+
+// InheritedRoles - роли групп пользователя в организации orgID
+// Вызывается middleware.RequireRole, когда собственной роли не хватило
+func (s *groupService) InheritedRoles(userID, orgID uint) ([]string, error) {
+	return s.groupRepo.ForTenant(orgID).RolesForUser(userID)
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// ensureNameFree - ErrGroupNameTaken, если название занято другой группой
+func (s *groupService) ensureNameFree(name string, exceptID uint) error {
+	existing, err := s.groupRepo.FindByName(name)
+	if errors.Is(err, repository.ErrGroupRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != exceptID {
+		return ErrGroupNameTaken
+	}
+	return nil
+}
+
+// ensureNoCycle - parentID не может быть самой группой или её потомком
+func (s *groupService) ensureNoCycle(groupID, parentID uint) error {
+	if _, err := s.Get(parentID); err != nil {
+		return err
+	}
+
+	subtree, err := s.groupRepo.SubtreeIDs(groupID)
+	if err != nil {
+		return err
+	}
+	for _, id := range subtree {
+		if id == parentID {
+			return ErrGroupCycle
+		}
+	}
+	return nil
+}
+
+// groupDetails - описание группы для журнала ("group 3 backend: role support")
+func groupDetails(group *domain.Group, role string) string {
+	details := fmt.Sprintf("group %d %s", group.ID, group.Name)
+	if role != "" {
+		details += ": role " + role
+	}
+	return details
+}
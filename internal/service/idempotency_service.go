@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// IDEMPOTENCY SERVICE - Повторы запросов с Idempotency-Key
+// ================================================================
+// Первый запрос с ключом занимает его на IDEMPOTENCY_LOCK_TTL (аренда продлевается,
+// пока запрос выполняется) и выполняется,
+// ответ сохраняется на IDEMPOTENCY_TTL. Повтор с тем же ключом получает сохранённый ответ,
+// пока первый запрос не завершён - domain.ErrIdempotencyInProgress.
+// HTTP часть (какие ответы сохранять, заголовки) - в middleware.Idempotency
+
+// IdempotencyService - интерфейс хранилища ответов (см. middleware.IdempotencyStore)
+type IdempotencyService interface {
+	// Begin - занимает ключ запроса
+	// key - SHA-256 от ключа клиента, автора и маршрута; fingerprint - SHA-256 запроса
+	// (пустой - отпечаток станет известен после выполнения, сравнивает вызывающий)
+	// Возвращает nil - ключ свободен, запрос нужно выполнить и вызвать Complete или Release;
+	// запись - ответ на тот же запрос, его нужно повторить клиенту
+	Begin(key, fingerprint string) (*domain.IdempotencyRecord, error)
+
+	// Complete - сохраняет ответ на запрос, занявший key, и его отпечаток
+	Complete(key, fingerprint string, status int, headers map[string][]string, body []byte) error
+
+	// CompleteResult - сохраняет вместо ответа результат выполнения (domain.IdempotencyRecord.Rebuild):
+	// повтор получит его и построит ответ заново
+	CompleteResult(key, fingerprint string, status int, result []byte) error
+
+	// Hold - продлевает занятость key, пока запрос выполняется; stop - прекратить
+	Hold(key string) (stop func())
+
+	// Release - освобождает key без ответа: следующий запрос выполнится заново
+	Release(key string) error
+
+	// PurgeExpired - удаляет ключи, устаревшие к now
+	PurgeExpired(now time.Time) (int64, error)
+
+	// RunCleanup - PurgeExpired раз в interval до отмены ctx
+	RunCleanup(ctx context.Context, interval time.Duration)
+}
+
+const (
+	// defaultIdempotencyTTL - срок хранения ответа, если IDEMPOTENCY_TTL не разобран
+	defaultIdempotencyTTL = 24 * time.Hour
+
+	// defaultIdempotencyLockTTL - срок занятости ключа, если IDEMPOTENCY_LOCK_TTL не разобран
+	defaultIdempotencyLockTTL = time.Minute
+)
+
+// idempotencyService - реализация
+type idempotencyService struct {
+	repo    repository.IdempotencyRepository
+	ttl     time.Duration
+	lockTTL time.Duration
+}
+
+// NewIdempotencyService - конструктор
+func NewIdempotencyService(repo repository.IdempotencyRepository, cfg *config.Config) IdempotencyService {
+	ttl, err := time.ParseDuration(cfg.IdempotencyTTL)
+	if err != nil || ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	lockTTL, err := time.ParseDuration(cfg.IdempotencyLockTTL)
+	if err != nil || lockTTL <= 0 {
+		lockTTL = defaultIdempotencyLockTTL
+	}
+	return &idempotencyService{repo: repo, ttl: ttl, lockTTL: lockTTL}
+}
+
+// Begin - занимает ключ или возвращает ответ на тот же запрос
+// Занятый ключ истекает через lockTTL: запрос, не дошедший до Complete
+// или Release (падение процесса), не блокирует ключ на весь ttl
+func (s *idempotencyService) Begin(key, fingerprint string) (*domain.IdempotencyRecord, error) {
+	now := time.Now()
+	existing, err := s.repo.Acquire(&domain.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(s.lockTTL),
+		CreatedAt:   now,
+	}, now)
+	if errors.Is(err, repository.ErrIdempotencyKeyBusy) {
+		return nil, domain.ErrIdempotencyInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+
+	// === ШАГ 1: ТОТ ЖЕ ЛИ ЗАПРОС ===
+	// Проверяется раньше статуса: другой запрос с ключом - ошибка,
+	// даже если первый ещё выполняется. Пустой отпечаток (длинное тело) не сравнивается
+	if existing.Fingerprint != "" && fingerprint != "" && existing.Fingerprint != fingerprint {
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+
+	// === ШАГ 2: ГОТОВ ЛИ ОТВЕТ ===
+	if !existing.Completed() {
+		return nil, domain.ErrIdempotencyInProgress
+	}
+	return existing, nil
+}
+
+// Complete - сохраняет ответ на ttl
+func (s *idempotencyService) Complete(key, fingerprint string, status int, headers map[string][]string, body []byte) error {
+	return s.repo.Complete(&domain.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Status:      status,
+		Headers:     headers,
+		Body:        body,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	})
+}
+
+// CompleteResult - сохраняет результат на ttl
+func (s *idempotencyService) CompleteResult(key, fingerprint string, status int, result []byte) error {
+	return s.repo.Complete(&domain.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Status:      status,
+		Body:        result,
+		Rebuild:     true,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	})
+}
+
+// Hold - продлевает аренду ключа на lockTTL каждую треть lockTTL
+// Долгий запрос (большой импорт) не теряет ключ: повтор получает 409,
+// а не выполняется параллельно. Упавший процесс продлевать перестаёт -
+// ключ освобождается через lockTTL, как и раньше
+func (s *idempotencyService) Hold(key string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(s.lockTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.repo.Extend(key, time.Now().Add(s.lockTTL)); err != nil {
+					log.Printf("⚠️  Ошибка продления ключа идемпотентности: %v", err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// Release - освобождает ключ
+func (s *idempotencyService) Release(key string) error {
+	return s.repo.Release(key)
+}
+
+// PurgeExpired - удаляет устаревшие ключи
+func (s *idempotencyService) PurgeExpired(now time.Time) (int64, error) {
+	return s.repo.DeleteExpired(now)
+}
+
+// RunCleanup - периодическое удаление устаревших ключей
+func (s *idempotencyService) RunCleanup(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := s.PurgeExpired(time.Now())
+		if err != nil {
+			log.Printf("⚠️  Ошибка удаления устаревших ключей идемпотентности: %v", err)
+		} else if count > 0 {
+			log.Printf("🗑️  Удалены устаревшие ключи идемпотентности: %d", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// IMPERSONATION SERVICE - Вход администратора от имени пользователя
+// ================================================================
+// Поддержка воспроизводит проблему пользователя, не спрашивая его пароль.
+// Токен имперсонации:
+//   - короткоживущий (IMPERSONATION_TTL) и не продлевается
+//   - содержит claim "act" с реальным автором запросов
+//   - не даёт менять пароль и passkeys (middleware.BlockImpersonation)
+//   - каждый запрос с ним пишется в журнал (middleware.ImpersonationAudit)
+
+// ImpersonationService - интерфейс для имперсонации
+type ImpersonationService interface {
+	Impersonate(actorID, targetID uint, client domain.ClientInfo) (*domain.ImpersonationResponse, error)
+
+	// ForTenant - имперсонация только в пределах организации orgID
+	ForTenant(orgID uint) ImpersonationService
+}
+
+var (
+	// ErrImpersonationTargetNotFound - пользователь не существует
+	ErrImpersonationTargetNotFound = errors.New("пользователь не найден")
+
+	// ErrImpersonationForbidden - нельзя войти от имени администратора или самого себя
+	ErrImpersonationForbidden = errors.New("вход от имени этого пользователя запрещён")
+)
+
+// impersonationService - реализация
+type impersonationService struct {
+	userRepo repository.UserRepository
+	tokens   TokenIssuer
+	audit    AuditService // nil - события не пишем
+	roles    RoleProvider // Роли групп (nil - только users.role)
+	orgID    uint         // Организация ForTenant (для ролей групп)
+	ttl      time.Duration
+}
+
+// ImpersonationOption - необязательная настройка Impersonation Service
+type ImpersonationOption func(*impersonationService)
+
+// WithImpersonationRoles - администратором считается и участник группы с ролью admin
+// (как в middleware.RequireRole с InheritedRoles)
+func WithImpersonationRoles(roles RoleProvider) ImpersonationOption {
+	return func(s *impersonationService) {
+		s.roles = roles
+	}
+}
+
+// NewImpersonationService - конструктор
+func NewImpersonationService(userRepo repository.UserRepository, tokens TokenIssuer, audit AuditService, cfg *config.Config, opts ...ImpersonationOption) ImpersonationService {
+	ttl, err := time.ParseDuration(cfg.ImpersonationTTL)
+	if err != nil || ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+
+	s := &impersonationService{
+		userRepo: userRepo,
+		tokens:   tokens,
+		audit:    audit,
+		ttl:      ttl,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ForTenant - копия сервиса с репозиторием организации orgID
+func (s *impersonationService) ForTenant(orgID uint) ImpersonationService {
+	scoped := *s
+	scoped.userRepo = s.userRepo.ForTenant(orgID)
+	scoped.orgID = orgID
+	return &scoped
+}
+
+// Impersonate выдаёт администратору actorID токен пользователя targetID
+func (s *impersonationService) Impersonate(actorID, targetID uint, client domain.ClientInfo) (*domain.ImpersonationResponse, error) {
+	// === ШАГ 1: АДМИНИСТРАТОР ===
+	// Роль проверяет и RequireRole, но в токене она могла устареть
+	// Роль группы засчитывается так же, как в RequireRole
+	actor, err := s.userRepo.FindByID(actorID)
+	if err != nil {
+		return nil, ErrImpersonationForbidden
+	}
+	if isAdmin, err := hasRole(actor, "admin", s.roles, s.orgID); err != nil || !isAdmin {
+		return nil, ErrImpersonationForbidden
+	}
+
+	// === ШАГ 2: ПОЛЬЗОВАТЕЛЬ ===
+	// Администраторов имперсонировать нельзя - иначе это повышение прав в обход журнала
+	// Администратор через группу - тоже администратор: при ошибке загрузки
+	// ролей групп вход запрещается, а не разрешается
+	target, err := s.userRepo.FindByID(targetID)
+	if err != nil {
+		return nil, ErrImpersonationTargetNotFound
+	}
+	if target.ID == actor.ID {
+		return nil, ErrImpersonationForbidden
+	}
+	if isAdmin, err := hasRole(target, "admin", s.roles, s.orgID); err != nil || isAdmin {
+		return nil, ErrImpersonationForbidden
+	}
+
+	// === ШАГ 3: ТОКЕН ===
+	expiresAt := time.Now().Add(s.ttl)
+	response, err := s.tokens.Impersonate(target, actor, client, s.ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 4: ЖУРНАЛ ===
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionImpersonationStarted, actor.ID, target.ID, client)
+		event.Details = "срок действия до " + expiresAt.UTC().Format(time.RFC3339)
+		s.audit.Record(event)
+	}
+
+	return &domain.ImpersonationResponse{
+		Token:     response.Token,
+		User:      target,
+		ActorID:   actor.ID,
+		ExpiresAt: expiresAt,
+	}, nil
+}
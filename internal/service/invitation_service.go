@@ -0,0 +1,428 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/mailer"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// INVITATION SERVICE - Приглашения в организации
+// ================================================================
+// 1. owner/admin организации приглашает email с ролью - в БД кладём SHA-256
+//    случайного токена, сам токен уходит письмом ссылкой на INVITATION_URL
+// 2. Приглашённый по ссылке:
+//    - регистрирует аккаунт в организации (POST /invitations/register), или
+//    - входит в существующий аккаунт с тем же email и принимает (POST /invitations/accept), или
+//    - отклоняет приглашение (POST /invitations/decline)
+// 3. Пока приглашение не принято, его можно отправить повторно (новая ссылка
+//    и новый срок) или отозвать
+
+// InvitationService - интерфейс для работы с приглашениями
+type InvitationService interface {
+	// Управление приглашениями организации orgID (actorID - owner или admin)
+	Create(orgID, actorID uint, req *domain.CreateInvitationRequest, client domain.ClientInfo) (*domain.Invitation, error)
+	ListPending(orgID, actorID uint) ([]domain.Invitation, error)
+	Resend(orgID, actorID, id uint, client domain.ClientInfo) (*domain.Invitation, error)
+	Revoke(orgID, actorID, id uint, client domain.ClientInfo) error
+
+	// Invite - приглашение без проверки прав actorID в организации
+	// (импорт пользователей: права администратора проверены при загрузке файла)
+	Invite(orgID, actorID uint, req *domain.CreateInvitationRequest, client domain.ClientInfo) (*domain.Invitation, error)
+
+	// AcceptWithRegistration - новый аккаунт в организации приглашения (логика Register)
+	AcceptWithRegistration(req *domain.AcceptInvitationRegisterRequest, client domain.ClientInfo) (*domain.AuthResponse, error)
+
+	// AcceptAsUser - принять приглашение аккаунтом userID (email должен совпадать)
+	// Возвращает токен для организации приглашения
+	AcceptAsUser(userID uint, token string, client domain.ClientInfo) (*domain.AuthResponse, error)
+
+	// Decline - отклонить приглашение (достаточно токена из письма)
+	Decline(token string, client domain.ClientInfo) error
+}
+
+var (
+	// ErrInvitationInvalid - общая ошибка по токену приглашения
+	// ВАЖНО: одинаковая для "не найдено", "истекло", "уже принято" и "отозвано"
+	ErrInvitationInvalid = errors.New("приглашение недействительно или истекло")
+
+	// ErrInvitationNotFound - приглашение организации не найдено (управление по ID)
+	ErrInvitationNotFound = errors.New("приглашение не найдено")
+
+	// ErrInvitationNotPending - приглашение уже принято, отклонено или отозвано
+	ErrInvitationNotPending = errors.New("приглашение уже не ожидает ответа")
+
+	// ErrInvitationAlreadyPending - email уже приглашён (используйте повторную отправку)
+	ErrInvitationAlreadyPending = errors.New("этот email уже приглашён в организацию")
+
+	// ErrAlreadyMember - пользователь с этим email уже состоит в организации
+	ErrAlreadyMember = errors.New("пользователь уже состоит в организации")
+
+	// ErrInvitationEmailMismatch - приглашение выдано на другой email
+	ErrInvitationEmailMismatch = errors.New("приглашение выдано на другой email")
+)
+
+// invitationService - реализация
+type invitationService struct {
+	invitations repository.InvitationRepository
+	orgRepo     repository.OrganizationRepository
+	userRepo    repository.UserRepository // без организации: ForTenant по приглашению
+	authService AuthService               // регистрация по приглашению
+	tokens      TokenIssuer
+	mailer      mailer.Mailer
+	audit       AuditService // nil - события не пишем
+	cfg         *config.Config
+}
+
+// NewInvitationService - конструктор
+func NewInvitationService(
+	invitations repository.InvitationRepository,
+	orgRepo repository.OrganizationRepository,
+	userRepo repository.UserRepository,
+	authService AuthService,
+	tokens TokenIssuer,
+	mail mailer.Mailer,
+	audit AuditService,
+	cfg *config.Config,
+) InvitationService {
+	return &invitationService{
+		invitations: invitations,
+		orgRepo:     orgRepo,
+		userRepo:    userRepo,
+		authService: authService,
+		tokens:      tokens,
+		mailer:      mail,
+		audit:       audit,
+		cfg:         cfg,
+	}
+}
+
+// ================================================================
+// УПРАВЛЕНИЕ ПРИГЛАШЕНИЯМИ
+// ================================================================
+
+// Create - приглашает email в организацию и отправляет письмо
+// Владельцев может приглашать только owner (как и назначать их)
+func (s *invitationService) Create(orgID, actorID uint, req *domain.CreateInvitationRequest, client domain.ClientInfo) (*domain.Invitation, error) {
+	// === ШАГ 1: ПРАВА ===
+	role := req.Role
+	if role == "" {
+		role = domain.OrgRoleMember
+	}
+	actor, err := requireOrgManager(s.orgRepo, orgID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if role == domain.OrgRoleOwner && actor.Role != domain.OrgRoleOwner {
+		return nil, ErrOrgPermissionDenied
+	}
+	return s.invite(orgID, actorID, strings.ToLower(strings.TrimSpace(req.Email)), role, client)
+}
+
+// Invite - приглашение от имени организации (роль по умолчанию member)
+func (s *invitationService) Invite(orgID, actorID uint, req *domain.CreateInvitationRequest, client domain.ClientInfo) (*domain.Invitation, error) {
+	role := req.Role
+	if role == "" {
+		role = domain.OrgRoleMember
+	}
+	return s.invite(orgID, actorID, strings.ToLower(strings.TrimSpace(req.Email)), role, client)
+}
+
+// invite - создаёт приглашение и отправляет письмо (права уже проверены)
+func (s *invitationService) invite(orgID, actorID uint, email, role string, client domain.ClientInfo) (*domain.Invitation, error) {
+	// === ШАГ 2: ПОВТОРЫ ===
+	if user, _ := s.userRepo.ForTenant(orgID).FindByEmail(email); user != nil {
+		return nil, ErrAlreadyMember
+	}
+	if _, err := s.invitations.FindPending(orgID, email); err == nil {
+		return nil, ErrInvitationAlreadyPending
+	}
+
+	// === ШАГ 3: СОЗДАНИЕ ===
+	token, err := generateMagicLinkToken()
+	if err != nil {
+		return nil, errors.New("ошибка генерации приглашения")
+	}
+	now := time.Now()
+	invitation := &domain.Invitation{
+		OrganizationID: orgID,
+		Email:          email,
+		Role:           role,
+		TokenHash:      hashMagicLinkToken(token),
+		Status:         domain.InvitationStatusPending,
+		InvitedByID:    actorID,
+		ExpiresAt:      now.Add(invitationTTL(s.cfg)),
+		SentAt:         now,
+		SendCount:      1,
+	}
+	if err := s.invitations.Create(invitation); err != nil {
+		return nil, errors.New("ошибка сохранения приглашения")
+	}
+
+	// === ШАГ 4: ПИСЬМО И ЖУРНАЛ ===
+	s.send(invitation, token)
+	s.record(domain.AuditActionOrgInvitationSent, actorID, 0, invitation, client)
+	return invitation, nil
+}
+
+// ListPending - приглашения организации, ожидающие ответа
+func (s *invitationService) ListPending(orgID, actorID uint) ([]domain.Invitation, error) {
+	if _, err := requireOrgManager(s.orgRepo, orgID, actorID); err != nil {
+		return nil, err
+	}
+	return s.invitations.ListPending(orgID)
+}
+
+// Resend - повторная отправка: новая ссылка (старая перестаёт работать) и новый срок
+func (s *invitationService) Resend(orgID, actorID, id uint, client domain.ClientInfo) (*domain.Invitation, error) {
+	// === ШАГ 1: ПРАВА И СОСТОЯНИЕ ===
+	invitation, err := s.managedInvitation(orgID, actorID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 2: НОВЫЙ ТОКЕН ===
+	token, err := generateMagicLinkToken()
+	if err != nil {
+		return nil, errors.New("ошибка генерации приглашения")
+	}
+	now := time.Now()
+	invitation.TokenHash = hashMagicLinkToken(token)
+	invitation.ExpiresAt = now.Add(invitationTTL(s.cfg))
+	invitation.SentAt = now
+	invitation.SendCount++
+	if err := s.invitations.Update(invitation); err != nil {
+		return nil, errors.New("ошибка сохранения приглашения")
+	}
+
+	// === ШАГ 3: ПИСЬМО И ЖУРНАЛ ===
+	s.send(invitation, token)
+	s.record(domain.AuditActionOrgInvitationSent, actorID, 0, invitation, client)
+	return invitation, nil
+}
+
+// Revoke - отзывает приглашение: ссылка перестаёт работать
+func (s *invitationService) Revoke(orgID, actorID, id uint, client domain.ClientInfo) error {
+	invitation, err := s.managedInvitation(orgID, actorID, id)
+	if err != nil {
+		return err
+	}
+
+	responded, err := s.invitations.Respond(invitation.ID, domain.InvitationStatusRevoked, nil, time.Now())
+	if err != nil {
+		return err
+	}
+	if !responded {
+		return ErrInvitationNotPending
+	}
+
+	s.record(domain.AuditActionOrgInvitationRevoked, actorID, 0, invitation, client)
+	return nil
+}
+
+// ================================================================
+// ОТВЕТ НА ПРИГЛАШЕНИЕ
+// ================================================================
+
+// AcceptWithRegistration - регистрация нового аккаунта по приглашению
+// Аккаунт создаётся в организации приглашения с email из приглашения,
+// пароль проверяется той же политикой, что и при обычной регистрации
+func (s *invitationService) AcceptWithRegistration(req *domain.AcceptInvitationRegisterRequest, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	// === ШАГ 1: ПРОВЕРКА ТОКЕНА ===
+	invitation, err := s.pendingInvitation(req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 2: РЕГИСТРАЦИЯ В ОРГАНИЗАЦИИ ПРИГЛАШЕНИЯ ===
+	// Аккаунт с этим email уже в организации - приглашение не нужно
+	if user, _ := s.userRepo.ForTenant(invitation.OrganizationID).FindByEmail(invitation.Email); user != nil {
+		return nil, ErrAlreadyMember
+	}
+
+	// Register создаёт аккаунт и членство с ролью member
+	client.OrganizationID = invitation.OrganizationID
+	response, err := s.authService.ForTenant(invitation.OrganizationID).Register(&domain.RegisterRequest{
+		Email:      invitation.Email,
+		Name:       req.Name,
+		Password:   req.Password,
+		Attributes: req.Attributes,
+		Approved:   true, // Приглашение уже одобрено организацией
+	}, client)
+	if err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 3: РОЛЬ ИЗ ПРИГЛАШЕНИЯ ===
+	if invitation.Role != domain.OrgRoleMember {
+		if err := s.orgRepo.UpdateMemberRole(invitation.OrganizationID, response.User.ID, invitation.Role); err != nil {
+			log.Printf("⚠️  Не удалось назначить роль по приглашению %d: %v", invitation.ID, err)
+		}
+	}
+
+	s.complete(invitation, response.User.ID, client)
+	return response, nil
+}
+
+// AcceptAsUser - принять приглашение существующим аккаунтом
+// Пользователь входит как обычно (в своей организации), затем принимает
+// приглашение своим токеном и получает токен для организации приглашения
+func (s *invitationService) AcceptAsUser(userID uint, token string, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	// === ШАГ 1: ПРОВЕРКА ТОКЕНА И EMAIL ===
+	invitation, err := s.pendingInvitation(token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrInvitationInvalid
+	}
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+
+	// === ШАГ 2: ЧЛЕНСТВО ===
+	if _, err := s.orgRepo.FindMember(invitation.OrganizationID, user.ID); err == nil {
+		return nil, ErrAlreadyMember
+	}
+	// Email уникален в пределах организации: второй аккаунт с тем же адресом не пускаем
+	if existing, _ := s.userRepo.ForTenant(invitation.OrganizationID).FindByEmail(user.Email); existing != nil {
+		return nil, ErrAlreadyMember
+	}
+	err = s.orgRepo.AddMember(&domain.Membership{
+		OrganizationID: invitation.OrganizationID,
+		UserID:         user.ID,
+		Role:           invitation.Role,
+	})
+	if err != nil {
+		return nil, errors.New("ошибка добавления в организацию")
+	}
+
+	// === ШАГ 3: ТОКЕН ДЛЯ ОРГАНИЗАЦИИ ПРИГЛАШЕНИЯ ===
+	s.complete(invitation, user.ID, client)
+	client.OrganizationID = invitation.OrganizationID
+	return s.tokens.Issue(user, client)
+}
+
+// Decline - приглашённый отказывается от приглашения
+func (s *invitationService) Decline(token string, client domain.ClientInfo) error {
+	invitation, err := s.pendingInvitation(token)
+	if err != nil {
+		return err
+	}
+
+	responded, err := s.invitations.Respond(invitation.ID, domain.InvitationStatusDeclined, nil, time.Now())
+	if err != nil || !responded {
+		return ErrInvitationInvalid
+	}
+
+	s.record(domain.AuditActionOrgInvitationDeclined, 0, 0, invitation, client)
+	return nil
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// pendingInvitation - ожидающее ответа непросроченное приглашение по токену
+func (s *invitationService) pendingInvitation(token string) (*domain.Invitation, error) {
+	invitation, err := s.invitations.FindByHash(hashMagicLinkToken(token))
+	if err != nil {
+		return nil, ErrInvitationInvalid
+	}
+	if invitation.Status != domain.InvitationStatusPending || time.Now().After(invitation.ExpiresAt) {
+		return nil, ErrInvitationInvalid
+	}
+	return invitation, nil
+}
+
+// managedInvitation - ожидающее приглашение организации, которым может управлять actorID
+// Приглашением владельца управляет только owner
+func (s *invitationService) managedInvitation(orgID, actorID, id uint) (*domain.Invitation, error) {
+	actor, err := requireOrgManager(s.orgRepo, orgID, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.invitations.FindByID(orgID, id)
+	if errors.Is(err, repository.ErrOrganizationRecordNotFound) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Role == domain.OrgRoleOwner && actor.Role != domain.OrgRoleOwner {
+		return nil, ErrOrgPermissionDenied
+	}
+	if invitation.Status != domain.InvitationStatusPending {
+		return nil, ErrInvitationNotPending
+	}
+	return invitation, nil
+}
+
+// complete - помечает приглашение принятым
+// Вызывается после вступления: параллельное второе принятие уже отсечено
+// уникальностью email и членства в организации
+func (s *invitationService) complete(invitation *domain.Invitation, userID uint, client domain.ClientInfo) {
+	responded, err := s.invitations.Respond(invitation.ID, domain.InvitationStatusAccepted, &userID, time.Now())
+	if err != nil || !responded {
+		log.Printf("⚠️  Приглашение %d не помечено принятым: %v", invitation.ID, err)
+	}
+	s.record(domain.AuditActionOrgInvitationAccepted, userID, userID, invitation, client)
+}
+
+// send - письмо со ссылкой на приглашение
+func (s *invitationService) send(invitation *domain.Invitation, token string) {
+	if s.mailer == nil {
+		return
+	}
+
+	orgName := fmt.Sprintf("#%d", invitation.OrganizationID)
+	if org, err := s.orgRepo.FindByID(invitation.OrganizationID); err == nil {
+		orgName = org.Name
+	}
+
+	err := s.mailer.Send(mailer.Message{
+		To:      invitation.Email,
+		Subject: "Приглашение в " + orgName,
+		Body: fmt.Sprintf(
+			"Здравствуйте!\n\n"+
+				"Вас пригласили в организацию %s (роль: %s).\n"+
+				"Чтобы принять приглашение, откройте ссылку:\n%s\n\n"+
+				"Ссылка действует до %s.\n"+
+				"Если вы не ждали приглашения, просто проигнорируйте это письмо.",
+			orgName, invitation.Role, magicLinkURL(s.cfg.InvitationURL, token),
+			invitation.ExpiresAt.Format("02.01.2006 15:04 MST"),
+		),
+	})
+	if err != nil {
+		log.Printf("⚠️  Не удалось отправить приглашение %d: %v", invitation.ID, err)
+	}
+}
+
+// record - событие журнала с email и организацией приглашения
+func (s *invitationService) record(action string, actorID, targetID uint, invitation *domain.Invitation, client domain.ClientInfo) {
+	if s.audit == nil {
+		return
+	}
+	event := newAuditEvent(action, actorID, targetID, client)
+	event.Details = fmt.Sprintf("организация %d, email: %s, роль: %s", invitation.OrganizationID, invitation.Email, invitation.Role)
+	s.audit.Record(event)
+}
+
+// invitationTTL - срок действия приглашения (по умолчанию 7 дней)
+func invitationTTL(cfg *config.Config) time.Duration {
+	ttl, err := time.ParseDuration(cfg.InvitationTTL)
+	if err != nil || ttl <= 0 {
+		return 7 * 24 * time.Hour
+	}
+	return ttl
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"advanced-user-api/internal/config"
+)
+
+// ================================================================
+// LOGIN METHODS - Какие способы входа включены
+// ================================================================
+
+// Способы входа (значения LOGIN_METHODS)
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodMagicLink = "magic_link"
+	LoginMethodPasskey   = "passkey"
+)
+
+// ErrLoginMethodDisabled - способ входа выключен в LOGIN_METHODS
+var ErrLoginMethodDisabled = errors.New("этот способ входа отключён")
+
+// LoginMethodEnabled - включён ли способ входа в LOGIN_METHODS
+// Пустое значение - только вход по паролю
+func LoginMethodEnabled(cfg *config.Config, method string) bool {
+	if strings.TrimSpace(cfg.LoginMethods) == "" {
+		return method == LoginMethodPassword
+	}
+	for _, m := range strings.Split(cfg.LoginMethods, ",") {
+		if strings.TrimSpace(m) == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// MAGIC LINK - Вход по одноразовой ссылке из письма
+// ================================================================
+// 1. POST /auth/magic-link - генерируем случайный токен, в БД кладём его SHA-256,
+//    а сам токен отправляем пользователю ссылкой на MAGIC_LINK_URL
+// 2. POST /auth/magic-link/verify - фронтенд отправляет токен из ссылки,
+//    мы проверяем срок, одноразовость и устройство и выдаём обычный JWT
+//
+// Ссылка привязана к отпечатку устройства (User-Agent + device_id клиента):
+// перехваченное письмо нельзя использовать в другом браузере
+
+// magicLinkTokenBytes - длина токена (256 бит случайности)
+const magicLinkTokenBytes = 32
+
+var (
+	// ErrMagicLinkRateLimited - слишком много запросов ссылки для одного email
+	ErrMagicLinkRateLimited = errors.New("слишком много запросов ссылки, попробуйте позже")
+
+	// ErrMagicLinkInvalid - общая ошибка входа по ссылке
+	// ВАЖНО: одинаковая для "не найдена", "истекла", "использована" и "другое устройство"
+	ErrMagicLinkInvalid = errors.New("ссылка недействительна или истекла")
+)
+
+// WithMagicLinkRepository - подключает хранилище токенов magic link
+// Без него вход по ссылке недоступен, даже если включён в LOGIN_METHODS
+func WithMagicLinkRepository(repo repository.MagicLinkRepository) AuthOption {
+	return func(s *authService) {
+		s.magicLinks = repo
+	}
+}
+
+// ================================================================
+// REQUEST MAGIC LINK
+// ================================================================
+
+// RequestMagicLink создаёт одноразовую ссылку и отправляет её на email
+// Параметры:
+//   - req: email и идентификатор устройства
+//   - client: IP и User-Agent (входят в отпечаток устройства)
+// Возвращает:
+//   - error: ErrLoginMethodDisabled, ErrMagicLinkRateLimited или ошибка БД
+//
+// ВАЖНО: для незарегистрированного email ответ такой же, как для существующего
+// (письмо просто не отправляется), чтобы не раскрывать список пользователей
+func (s *authService) RequestMagicLink(req *domain.MagicLinkRequest, client domain.ClientInfo) error {
+	if !s.magicLinkEnabled() {
+		return ErrLoginMethodDisabled
+	}
+
+	// === ШАГ 1: ОГРАНИЧЕНИЕ ЧАСТОТЫ ===
+	// Лимит считается по email: нельзя завалить письмами чужой ящик
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	limit, window := magicLinkRateLimit(s.cfg)
+	count, err := s.magicLinks.CountSince(email, time.Now().Add(-window))
+	if err != nil {
+		return errors.New("ошибка проверки лимита запросов")
+	}
+	if count >= int64(limit) {
+		return ErrMagicLinkRateLimited
+	}
+
+	// === ШАГ 2: ПОИСК ПОЛЬЗОВАТЕЛЯ ===
+	// Ссылки только для локальных аккаунтов: для LDAP доступ определяет каталог
+	user, _ := s.userRepo.FindByEmail(req.Email)
+	if user != nil && user.AuthProvider != "" && user.AuthProvider != domain.AuthProviderLocal {
+		user = nil
+	}
+
+	// === ШАГ 3: ГЕНЕРАЦИЯ ТОКЕНА ===
+	token, err := generateMagicLinkToken()
+	if err != nil {
+		return errors.New("ошибка генерации ссылки")
+	}
+
+	record := &domain.MagicLinkToken{
+		TokenHash:  hashMagicLinkToken(token),
+		Email:      email,
+		DeviceHash: deviceFingerprint(client.UserAgent, req.DeviceID),
+		IP:         client.IP,
+		ExpiresAt:  time.Now().Add(magicLinkTTL(s.cfg)),
+	}
+	if user != nil {
+		record.UserID = &user.ID
+	}
+
+	// Запись сохраняется и для неизвестного email - она участвует в лимите
+	if err := s.magicLinks.Create(record); err != nil {
+		return errors.New("ошибка сохранения ссылки")
+	}
+	if user == nil {
+		return nil
+	}
+
+	// === ШАГ 4: ОТПРАВКА ПИСЬМА ===
+	s.recordAudit(domain.AuditActionMagicLinkRequested, user.ID, user.ID, client)
+	s.notify(user, "Ссылка для входа", fmt.Sprintf(
+		"Здравствуйте, %s!\n\n"+
+			"Чтобы войти, откройте ссылку в том же браузере, где вы её запросили:\n%s\n\n"+
+			"Ссылка действует до %s и может быть использована один раз.\n"+
+			"Если вы не запрашивали вход, просто проигнорируйте это письмо.",
+		user.Name, magicLinkURL(s.cfg.MagicLinkURL, token), record.ExpiresAt.Format("02.01.2006 15:04 MST"),
+	))
+
+	return nil
+}
+
+// ================================================================
+// REDEEM MAGIC LINK
+// ================================================================
+
+// RedeemMagicLink выполняет вход по токену из ссылки
+// Параметры:
+//   - req: токен и идентификатор устройства
+//   - client: IP и User-Agent (должны дать тот же отпечаток устройства)
+// Возвращает:
+//   - *domain.AuthResponse: JWT токен и данные пользователя (как у Login)
+//   - error: ErrLoginMethodDisabled или ErrMagicLinkInvalid
+func (s *authService) RedeemMagicLink(req *domain.MagicLinkVerifyRequest, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	if !s.magicLinkEnabled() {
+		return nil, ErrLoginMethodDisabled
+	}
+
+	// === ШАГ 1: ПОИСК ТОКЕНА ===
+	record, err := s.magicLinks.FindByHash(hashMagicLinkToken(req.Token))
+	if err != nil || record.UserID == nil {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	// === ШАГ 2: СРОК, ОДНОРАЗОВОСТЬ, УСТРОЙСТВО ===
+	if record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+		return nil, ErrMagicLinkInvalid
+	}
+	device := deviceFingerprint(client.UserAgent, req.DeviceID)
+	if subtle.ConstantTimeCompare([]byte(device), []byte(record.DeviceHash)) != 1 {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	// Помечаем использованной ДО выдачи токена:
+	// из двух параллельных запросов успешным будет только один
+	marked, err := s.magicLinks.MarkUsed(record.ID, time.Now())
+	if err != nil {
+		log.Printf("⚠️  Не удалось пометить ссылку %d использованной: %v", record.ID, err)
+		return nil, ErrMagicLinkInvalid
+	}
+	if !marked {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	// === ШАГ 3: ВХОД ===
+	user, err := s.userRepo.FindByID(*record.UserID)
+	if err != nil {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	s.recordAudit(domain.AuditActionMagicLinkLogin, user.ID, user.ID, client)
+	return s.tokens.Issue(user, client)
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// magicLinkEnabled - включён ли вход по ссылке
+func (s *authService) magicLinkEnabled() bool {
+	return s.magicLinks != nil && LoginMethodEnabled(s.cfg, LoginMethodMagicLink)
+}
+
+// magicLinkTTL - время жизни ссылки (по умолчанию 15 минут)
+func magicLinkTTL(cfg *config.Config) time.Duration {
+	ttl, err := time.ParseDuration(cfg.MagicLinkTTL)
+	if err != nil || ttl <= 0 {
+		return 15 * time.Minute
+	}
+	return ttl
+}
+
+// magicLinkRateLimit - лимит запросов на email (по умолчанию 3 за 15 минут)
+func magicLinkRateLimit(cfg *config.Config) (int, time.Duration) {
+	limit := cfg.MagicLinkRateLimit
+	if limit <= 0 {
+		limit = 3
+	}
+	window, err := time.ParseDuration(cfg.MagicLinkRateWindow)
+	if err != nil || window <= 0 {
+		window = 15 * time.Minute
+	}
+	return limit, window
+}
+
+// generateMagicLinkToken - случайный токен, безопасный для URL
+func generateMagicLinkToken() (string, error) {
+	buf := make([]byte, magicLinkTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashMagicLinkToken - SHA-256 токена для хранения в БД
+// Соль не нужна: токен случайный и длинный, перебор невозможен
+func hashMagicLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// deviceFingerprint - отпечаток устройства: SHA-256 от User-Agent и device_id
+// IP не используется - он меняется при переходе между Wi-Fi и мобильной сетью
+func deviceFingerprint(userAgent, deviceID string) string {
+	sum := sha256.Sum256([]byte(userAgent + "\n" + deviceID))
+	return hex.EncodeToString(sum[:])
+}
+
+// magicLinkURL - добавляет токен к адресу страницы фронтенда
+func magicLinkURL(base, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// ORGANIZATION SERVICE - Организации (тенанты) и участники
+// ================================================================
+// Организация запроса определяется middleware.TenantMiddleware,
+// а сервисы работают с её пользователями через ForTenant(orgID)
+
+// OrganizationService - интерфейс для работы с организациями
+type OrganizationService interface {
+	// FindBySlug - организация по короткому имени (см. middleware.TenantResolver)
+	FindBySlug(slug string) (*domain.Organization, error)
+	Get(id uint) (*domain.Organization, error)
+
+	// Create - новая организация, creatorID становится её владельцем
+	Create(creatorID uint, req *domain.CreateOrganizationRequest, client domain.ClientInfo) (*domain.Organization, error)
+
+	// Участники: actorID - кто выполняет действие (права проверяются по его роли в организации)
+	ListMembers(orgID, actorID uint) ([]domain.Membership, error)
+	UpdateMemberRole(orgID, actorID, userID uint, role string, client domain.ClientInfo) (*domain.Membership, error)
+	RemoveMember(orgID, actorID, userID uint, client domain.ClientInfo) error
+}
+
+var (
+	// ErrOrganizationNotFound - организация не существует
+	ErrOrganizationNotFound = errors.New("организация не найдена")
+
+	// ErrOrganizationSlugTaken - короткое имя уже занято
+	ErrOrganizationSlugTaken = errors.New("организация с таким slug уже существует")
+
+	// ErrInvalidOrganizationSlug - slug не подходит для поддомена
+	ErrInvalidOrganizationSlug = errors.New("slug может содержать только латиницу в нижнем регистре, цифры и дефис")
+
+	// ErrMemberNotFound - пользователь не состоит в организации
+	ErrMemberNotFound = errors.New("участник не найден")
+
+	// ErrOrgPermissionDenied - недостаточно прав в организации
+	ErrOrgPermissionDenied = errors.New("недостаточно прав в организации")
+
+	// ErrLastOwner - у организации должен остаться хотя бы один владелец
+	ErrLastOwner = errors.New("нельзя лишить организацию последнего владельца")
+)
+
+// organizationSlugPattern - допустимый slug (метка DNS: поддомен acme.api.example.com)
+var organizationSlugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// organizationService - реализация
+type organizationService struct {
+	orgRepo repository.OrganizationRepository
+	audit   AuditService // nil - события не пишем
+
+	// bySlug - кэш организаций для TenantMiddleware (вызывается на каждый запрос)
+	// slug не меняется после создания, поэтому кэш не устаревает
+	bySlug sync.Map
+}
+
+// NewOrganizationService - конструктор
+func NewOrganizationService(orgRepo repository.OrganizationRepository, audit AuditService) OrganizationService {
+	return &organizationService{orgRepo: orgRepo, audit: audit}
+}
+
+// FindBySlug - организация по slug (с кэшем)
+func (s *organizationService) FindBySlug(slug string) (*domain.Organization, error) {
+	if cached, ok := s.bySlug.Load(slug); ok {
+		return cached.(*domain.Organization), nil
+	}
+
+	org, err := s.orgRepo.FindBySlug(slug)
+	if errors.Is(err, repository.ErrOrganizationRecordNotFound) {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.bySlug.Store(slug, org)
+	return org, nil
+}
+
+// Get - организация по ID
+func (s *organizationService) Get(id uint) (*domain.Organization, error) {
+	org, err := s.orgRepo.FindByID(id)
+	if errors.Is(err, repository.ErrOrganizationRecordNotFound) {
+		return nil, ErrOrganizationNotFound
+	}
+	return org, err
+}
+
+// Create - создаёт организацию
+// Кто может создавать организации, решает маршрут (роль admin сервиса)
+func (s *organizationService) Create(creatorID uint, req *domain.CreateOrganizationRequest, client domain.ClientInfo) (*domain.Organization, error) {
+	// === ШАГ 1: ПРОВЕРКА SLUG ===
+	if !organizationSlugPattern.MatchString(req.Slug) {
+		return nil, ErrInvalidOrganizationSlug
+	}
+	if _, err := s.orgRepo.FindBySlug(req.Slug); err == nil {
+		return nil, ErrOrganizationSlugTaken
+	}
+
+	// === ШАГ 2: СОЗДАНИЕ С ВЛАДЕЛЬЦЕМ ===
+	org := &domain.Organization{Slug: req.Slug, Name: req.Name}
+	if err := s.orgRepo.Create(org, creatorID); err != nil {
+		return nil, errors.New("ошибка создания организации")
+	}
+
+	// === ШАГ 3: ЖУРНАЛ ===
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionOrgCreated, creatorID, creatorID, client)
+		event.Details = "организация: " + org.Slug
+		s.audit.Record(event)
+	}
+
+	return org, nil
+}
+
+// ListMembers - участники организации (видны любому участнику)
+func (s *organizationService) ListMembers(orgID, actorID uint) ([]domain.Membership, error) {
+	if _, err := s.member(orgID, actorID); err != nil {
+		return nil, ErrOrgPermissionDenied
+	}
+	return s.orgRepo.ListMembers(orgID)
+}
+
+// UpdateMemberRole - меняет роль участника
+// Правила:
+//   - менять роли могут owner и admin
+//   - назначать владельцев и менять роль владельца может только owner
+//   - последнего владельца понизить нельзя
+func (s *organizationService) UpdateMemberRole(orgID, actorID, userID uint, role string, client domain.ClientInfo) (*domain.Membership, error) {
+	// === ШАГ 1: ПРАВА ===
+	actor, target, err := s.actorAndTarget(orgID, actorID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if (role == domain.OrgRoleOwner || target.Role == domain.OrgRoleOwner) && actor.Role != domain.OrgRoleOwner {
+		return nil, ErrOrgPermissionDenied
+	}
+	if target.Role == role {
+		return target, nil
+	}
+	if target.Role == domain.OrgRoleOwner {
+		if err := s.ensureAnotherOwner(orgID); err != nil {
+			return nil, err
+		}
+	}
+
+	// === ШАГ 2: СМЕНА РОЛИ ===
+	if err := s.orgRepo.UpdateMemberRole(orgID, userID, role); err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 3: ЖУРНАЛ ===
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionOrgMemberRoleChanged, actorID, userID, client)
+		event.Changes = diffFields(map[string][2]string{"role": {target.Role, role}})
+		s.audit.Record(event)
+	}
+
+	target.Role = role
+	return target, nil
+}
+
+// RemoveMember - исключает участника из организации
+// Исключать могут owner и admin (владельцев - только owner); выйти сам может любой участник
+func (s *organizationService) RemoveMember(orgID, actorID, userID uint, client domain.ClientInfo) error {
+	// === ШАГ 1: ПРАВА ===
+	var target *domain.Membership
+	if actorID == userID {
+		member, err := s.member(orgID, userID)
+		if err != nil {
+			return ErrMemberNotFound
+		}
+		target = member
+	} else {
+		actor, member, err := s.actorAndTarget(orgID, actorID, userID)
+		if err != nil {
+			return err
+		}
+		if member.Role == domain.OrgRoleOwner && actor.Role != domain.OrgRoleOwner {
+			return ErrOrgPermissionDenied
+		}
+		target = member
+	}
+	if target.Role == domain.OrgRoleOwner {
+		if err := s.ensureAnotherOwner(orgID); err != nil {
+			return err
+		}
+	}
+
+	// === ШАГ 2: ИСКЛЮЧЕНИЕ ===
+	if err := s.orgRepo.RemoveMember(orgID, userID); err != nil {
+		return err
+	}
+
+	// === ШАГ 3: ЖУРНАЛ ===
+	if s.audit != nil {
+		s.audit.Record(newAuditEvent(domain.AuditActionOrgMemberRemoved, actorID, userID, client))
+	}
+	return nil
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// member - членство пользователя в организации
+func (s *organizationService) member(orgID, userID uint) (*domain.Membership, error) {
+	member, err := s.orgRepo.FindMember(orgID, userID)
+	if errors.Is(err, repository.ErrOrganizationRecordNotFound) {
+		return nil, ErrMemberNotFound
+	}
+	return member, err
+}
+
+// actorAndTarget - членство управляющего (owner или admin) и управляемого участника
+func (s *organizationService) actorAndTarget(orgID, actorID, userID uint) (*domain.Membership, *domain.Membership, error) {
+	actor, err := requireOrgManager(s.orgRepo, orgID, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target, err := s.member(orgID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return actor, target, nil
+}
+
+// requireOrgManager - членство actorID, если он owner или admin организации
+// Иначе ErrOrgPermissionDenied (используется и приглашениями)
+func requireOrgManager(orgRepo repository.OrganizationRepository, orgID, actorID uint) (*domain.Membership, error) {
+	actor, err := orgRepo.FindMember(orgID, actorID)
+	if err != nil || (actor.Role != domain.OrgRoleOwner && actor.Role != domain.OrgRoleAdmin) {
+		return nil, ErrOrgPermissionDenied
+	}
+	return actor, nil
+}
+
+// ensureAnotherOwner - ErrLastOwner, если владелец в организации один
+func (s *organizationService) ensureAnotherOwner(orgID uint) error {
+	owners, err := s.orgRepo.CountMembersWithRole(orgID, domain.OrgRoleOwner)
+	if err != nil {
+		return err
+	}
+	if owners < 2 {
+		return ErrLastOwner
+	}
+	return nil
+}
@@ -0,0 +1,643 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// ================================================================
+// PASSKEY SERVICE - Вход по WebAuthn (passkeys)
+// ================================================================
+// Каждая операция - две стадии ("церемонии" WebAuthn):
+//   1. begin  - генерируем challenge, сохраняем его и отдаём параметры браузеру
+//   2. finish - браузер возвращает подписанный ответ аутентификатора,
+//               проверяем подпись, origin, RP ID, флаги и счётчик подписей
+//
+// Регистрация нового аккаунта через passkey создаёт пользователя БЕЗ пароля
+
+// PasskeyService - интерфейс для работы с passkeys
+type PasskeyService interface {
+	// Регистрация нового аккаунта только с passkey
+	BeginSignup(req *domain.PasskeySignupRequest) (*domain.PasskeyChallengeResponse, error)
+	FinishSignup(req *domain.PasskeyFinishRequest, client domain.ClientInfo) (*domain.AuthResponse, error)
+
+	// Добавление passkey к существующему аккаунту
+	BeginRegistration(userID uint) (*domain.PasskeyChallengeResponse, error)
+	FinishRegistration(userID uint, req *domain.PasskeyFinishRequest, client domain.ClientInfo) (*domain.WebAuthnCredential, error)
+
+	// Вход
+	BeginLogin(req *domain.PasskeyLoginRequest) (*domain.PasskeyChallengeResponse, error)
+	FinishLogin(req *domain.PasskeyFinishRequest, client domain.ClientInfo) (*domain.AuthResponse, error)
+
+	// Управление passkeys текущего пользователя
+	List(userID uint) ([]domain.WebAuthnCredential, error)
+	Rename(userID, credentialID uint, name string) (*domain.WebAuthnCredential, error)
+	Delete(userID, credentialID uint, client domain.ClientInfo) error
+
+	// ForTenant - сервис, работающий только с пользователями организации orgID
+	// Ключ пользователя другой организации не подойдёт для входа
+	ForTenant(orgID uint) PasskeyService
+}
+
+var (
+	// ErrPasskeyFailed - общая ошибка церемонии
+	// ВАЖНО: одна и та же для неверной подписи, чужого ключа, истёкшего challenge,
+	// чтобы не раскрывать, какие email и ключи зарегистрированы
+	ErrPasskeyFailed = errors.New("не удалось проверить passkey")
+
+	// ErrPasskeyCloned - счётчик подписей не увеличился: ключ мог быть скопирован
+	ErrPasskeyCloned = errors.New("passkey заблокирован: обнаружено возможное клонирование ключа")
+
+	// ErrPasskeyNotFound - passkey не существует или принадлежит другому пользователю
+	ErrPasskeyNotFound = errors.New("passkey не найден")
+
+	// ErrLastPasskey - нельзя удалить единственный способ входа
+	ErrLastPasskey = errors.New("нельзя удалить последний passkey аккаунта без пароля")
+
+	// ErrPasskeyNotAllowed - passkeys доступны только локальным аккаунтам
+	ErrPasskeyNotAllowed = errors.New("passkeys недоступны для аккаунтов внешнего каталога")
+)
+
+// allowedAttestationFormats - принимаемые форматы аттестации
+// "none" - браузер скрыл модель аутентификатора (по умолчанию для passkeys)
+// "packed" - аутентификатор подписал регистрацию своим ключом (self или x5c)
+var allowedAttestationFormats = []protocol.AttestationFormat{
+	protocol.AttestationFormatNone,
+	protocol.AttestationFormatPacked,
+}
+
+// passkeyHandleBytes - длина WebAuthn user handle (спецификация: до 64 байт)
+const passkeyHandleBytes = 32
+
+// passkeyService - реализация
+type passkeyService struct {
+	userRepo      repository.UserRepository
+	credRepo      repository.CredentialRepository
+	challengeRepo repository.WebAuthnChallengeRepository
+	tokens        TokenIssuer
+	audit         AuditService // nil - события не пишем
+	webAuthn      *webauthn.WebAuthn
+	timeout       time.Duration
+	approval      bool // REGISTRATION_APPROVAL - новые аккаунты ждут активации
+}
+
+// NewPasskeyService - конструктор
+// Возвращает ошибку, если настройки WEBAUTHN_* некорректны
+func NewPasskeyService(
+	userRepo repository.UserRepository,
+	credRepo repository.CredentialRepository,
+	challengeRepo repository.WebAuthnChallengeRepository,
+	tokens TokenIssuer,
+	audit AuditService,
+	cfg *config.Config,
+) (PasskeyService, error) {
+	timeout, err := time.ParseDuration(cfg.WebAuthnTimeout)
+	if err != nil || timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+
+	var origins []string
+	for _, origin := range strings.Split(cfg.WebAuthnRPOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	attestation := protocol.PreferNoAttestation
+	if cfg.WebAuthnAttestation == string(protocol.PreferDirectAttestation) {
+		attestation = protocol.PreferDirectAttestation
+	}
+
+	timeouts := webauthn.TimeoutConfig{Enforce: true, Timeout: timeout, TimeoutUVD: timeout}
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:                  cfg.WebAuthnRPID,
+		RPDisplayName:         cfg.WebAuthnRPName,
+		RPOrigins:             origins,
+		AttestationPreference: attestation,
+		Timeouts:              webauthn.TimeoutsConfig{Login: timeouts, Registration: timeouts},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("настройки WebAuthn: %w", err)
+	}
+
+	return &passkeyService{
+		userRepo:      userRepo,
+		credRepo:      credRepo,
+		challengeRepo: challengeRepo,
+		tokens:        tokens,
+		audit:         audit,
+		approval:      cfg.RegistrationApproval,
+		webAuthn:      w,
+		timeout:       timeout,
+	}, nil
+}
+
+// ForTenant - копия сервиса с репозиторием организации orgID
+func (s *passkeyService) ForTenant(orgID uint) PasskeyService {
+	scoped := *s
+	scoped.userRepo = s.userRepo.ForTenant(orgID)
+	return &scoped
+}
+
+// ================================================================
+// SIGNUP - Новый аккаунт только с passkey
+// ================================================================
+
+// BeginSignup начинает регистрацию аккаунта без пароля
+// Пользователь ещё не создаётся: email, имя и user handle
+// сохраняются вместе с challenge до успешного finish
+func (s *passkeyService) BeginSignup(req *domain.PasskeySignupRequest) (*domain.PasskeyChallengeResponse, error) {
+	// === ШАГ 1: ПРОВЕРКА EMAIL ===
+	if existing, _ := s.userRepo.FindByEmail(req.Email); existing != nil {
+		return nil, errors.New("пользователь с таким email уже зарегистрирован")
+	}
+
+	// === ШАГ 2: НОВЫЙ USER HANDLE ===
+	handle, err := randomPasskeyHandle()
+	if err != nil {
+		return nil, errors.New("ошибка генерации passkey")
+	}
+	candidate := &domain.User{Email: req.Email, Name: req.Name, PasskeyHandle: handle}
+
+	// === ШАГ 3: ПАРАМЕТРЫ ДЛЯ navigator.credentials.create() ===
+	// Discoverable (resident) ключ обязателен - иначе войти без email не получится
+	creation, session, err := s.webAuthn.BeginRegistration(
+		newPasskeyUser(candidate, nil),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithAttestationFormats(allowedAttestationFormats),
+	)
+	if err != nil {
+		return nil, errors.New("ошибка генерации passkey")
+	}
+
+	return s.saveChallenge(&domain.WebAuthnChallenge{
+		Purpose: domain.PasskeyPurposeSignup,
+		Email:   req.Email,
+		Name:    req.Name,
+		Handle:  handle,
+	}, session, creation)
+}
+
+// FinishSignup проверяет ответ аутентификатора и создаёт аккаунт без пароля
+func (s *passkeyService) FinishSignup(req *domain.PasskeyFinishRequest, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	// === ШАГ 1: CHALLENGE ===
+	challenge, session, err := s.takeChallenge(req.ChallengeID, domain.PasskeyPurposeSignup)
+	if err != nil {
+		return nil, err
+	}
+	candidate := &domain.User{Email: challenge.Email, Name: challenge.Name, PasskeyHandle: challenge.Handle}
+
+	// === ШАГ 2: ПРОВЕРКА АТТЕСТАЦИИ ===
+	credential, err := s.createCredential(candidate, nil, session, req.Credential)
+	if err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 3: СОЗДАНИЕ ПОЛЬЗОВАТЕЛЯ И PASSKEY ===
+	// Email могли занять, пока пользователь подтверждал ключ
+	if existing, _ := s.userRepo.FindByEmail(candidate.Email); existing != nil {
+		return nil, errors.New("пользователь с таким email уже зарегистрирован")
+	}
+
+	user := &domain.User{
+		Email:         candidate.Email,
+		Name:          candidate.Name,
+		Password:      "", // Аккаунт только с passkey - хеша пароля нет
+		Role:          "user",
+		AuthProvider:  domain.AuthProviderLocal,
+		PasskeyHandle: candidate.PasskeyHandle,
+		Status:        domain.UserStatusActive,
+	}
+	if s.approval {
+		user.Status = domain.UserStatusPending // Вход после активации администратором
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, errors.New("ошибка создания пользователя")
+	}
+
+	record := newCredentialRecord(user.ID, req.Name, credential)
+	if err := s.credRepo.Create(record); err != nil {
+		return nil, errors.New("ошибка сохранения passkey")
+	}
+
+	s.recordAudit(domain.AuditActionPasskeyAdded, user.ID, client)
+
+	// === ШАГ 4: ВХОД ===
+	return s.tokens.Issue(user, client)
+}
+
+// ================================================================
+// REGISTRATION - Новый passkey для существующего аккаунта
+// ================================================================
+
+// BeginRegistration начинает добавление passkey текущему пользователю
+func (s *passkeyService) BeginRegistration(userID uint) (*domain.PasskeyChallengeResponse, error) {
+	// === ШАГ 1: ПОЛЬЗОВАТЕЛЬ И ЕГО КЛЮЧИ ===
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("пользователь не найден")
+	}
+	if user.AuthProvider != "" && user.AuthProvider != domain.AuthProviderLocal {
+		return nil, ErrPasskeyNotAllowed
+	}
+
+	// User handle создаётся один раз и дальше не меняется
+	if user.PasskeyHandle == "" {
+		if user.PasskeyHandle, err = randomPasskeyHandle(); err != nil {
+			return nil, errors.New("ошибка генерации passkey")
+		}
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, errors.New("ошибка сохранения пользователя")
+		}
+	}
+
+	records, err := s.credRepo.FindByUserID(user.ID)
+	if err != nil {
+		return nil, errors.New("ошибка загрузки passkeys")
+	}
+	passkeyUser := newPasskeyUser(user, records)
+
+	// === ШАГ 2: ПАРАМЕТРЫ ДЛЯ navigator.credentials.create() ===
+	// excludeCredentials - браузер не даст повторно зарегистрировать тот же ключ
+	creation, session, err := s.webAuthn.BeginRegistration(
+		passkeyUser,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithAttestationFormats(allowedAttestationFormats),
+		webauthn.WithExclusions(webauthn.Credentials(passkeyUser.credentials).CredentialDescriptors()),
+	)
+	if err != nil {
+		return nil, errors.New("ошибка генерации passkey")
+	}
+
+	return s.saveChallenge(&domain.WebAuthnChallenge{
+		Purpose: domain.PasskeyPurposeRegister,
+		UserID:  &user.ID,
+	}, session, creation)
+}
+
+// FinishRegistration проверяет ответ аутентификатора и сохраняет passkey
+func (s *passkeyService) FinishRegistration(userID uint, req *domain.PasskeyFinishRequest, client domain.ClientInfo) (*domain.WebAuthnCredential, error) {
+	// === ШАГ 1: CHALLENGE ===
+	// Challenge должен принадлежать тому же пользователю
+	challenge, session, err := s.takeChallenge(req.ChallengeID, domain.PasskeyPurposeRegister)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserID == nil || *challenge.UserID != userID {
+		return nil, ErrPasskeyFailed
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrPasskeyFailed
+	}
+	records, err := s.credRepo.FindByUserID(user.ID)
+	if err != nil {
+		return nil, errors.New("ошибка загрузки passkeys")
+	}
+
+	// === ШАГ 2: ПРОВЕРКА АТТЕСТАЦИИ И СОХРАНЕНИЕ ===
+	credential, err := s.createCredential(user, records, session, req.Credential)
+	if err != nil {
+		return nil, err
+	}
+
+	record := newCredentialRecord(user.ID, req.Name, credential)
+	if err := s.credRepo.Create(record); err != nil {
+		return nil, errors.New("ошибка сохранения passkey")
+	}
+
+	s.recordAudit(domain.AuditActionPasskeyAdded, user.ID, client)
+	return record, nil
+}
+
+// ================================================================
+// LOGIN - Вход по passkey
+// ================================================================
+
+// BeginLogin начинает вход
+// Всегда discoverable: браузер предложит любой passkey для сайта, пользователь
+// определяется по ключу в FinishLogin. Email из запроса не используется -
+// ответ со списком ключей (allowCredentials) раскрыл бы, зарегистрирован ли email
+func (s *passkeyService) BeginLogin(req *domain.PasskeyLoginRequest) (*domain.PasskeyChallengeResponse, error) {
+	challenge := &domain.WebAuthnChallenge{Purpose: domain.PasskeyPurposeLogin}
+
+	assertion, session, err := s.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, errors.New("ошибка генерации passkey")
+	}
+	return s.saveChallenge(challenge, session, assertion)
+}
+
+// FinishLogin проверяет подпись аутентификатора и выдаёт JWT токен
+func (s *passkeyService) FinishLogin(req *domain.PasskeyFinishRequest, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	// === ШАГ 1: CHALLENGE ===
+	_, session, err := s.takeChallenge(req.ChallengeID, domain.PasskeyPurposeLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, ErrPasskeyFailed
+	}
+
+	// === ШАГ 2: ПРОВЕРКА ПОДПИСИ ===
+	// Библиотека проверяет challenge, origin, RP ID, флаги UP/UV/BE и подпись,
+	// а также сравнивает счётчик подписей с сохранённым
+	// Пользователь определяется по ключу, которым подписан ответ
+	var user *domain.User
+	credential, err := s.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		found, err := s.findPasskeyOwner(rawID, userHandle)
+		if err != nil {
+			return nil, err
+		}
+		user = found.user
+		return found, nil
+	}, *session, parsed)
+	if err != nil {
+		return nil, ErrPasskeyFailed
+	}
+
+	// === ШАГ 3: СЧЁТЧИК ПОДПИСЕЙ ===
+	record, err := s.credRepo.FindByCredentialID(credential.ID)
+	if err != nil || record.UserID != user.ID {
+		return nil, ErrPasskeyFailed
+	}
+	if credential.Authenticator.CloneWarning {
+		// Счётчик не вырос - подписал другой экземпляр ключа
+		log.Printf("⚠️  Passkey %d пользователя %d: счётчик подписей %d не больше сохранённого %d",
+			record.ID, user.ID, parsed.Response.AuthenticatorData.Counter, record.SignCount)
+		s.recordAudit(domain.AuditActionPasskeyCloneDetected, user.ID, client)
+		return nil, ErrPasskeyCloned
+	}
+
+	now := time.Now()
+	record.SignCount = credential.Authenticator.SignCount
+	record.BackupState = credential.Flags.BackupState
+	record.LastUsedAt = &now
+	if err := s.credRepo.Update(record); err != nil {
+		return nil, errors.New("ошибка сохранения passkey")
+	}
+
+	// === ШАГ 4: ВХОД ===
+	s.recordAudit(domain.AuditActionPasskeyLogin, user.ID, client)
+	return s.tokens.Issue(user, client)
+}
+
+// ================================================================
+// MANAGEMENT - Список, переименование, удаление
+// ================================================================
+
+// List - passkeys текущего пользователя
+func (s *passkeyService) List(userID uint) ([]domain.WebAuthnCredential, error) {
+	return s.credRepo.FindByUserID(userID)
+}
+
+// Rename - меняет название passkey
+func (s *passkeyService) Rename(userID, credentialID uint, name string) (*domain.WebAuthnCredential, error) {
+	record, err := s.credRepo.FindByID(credentialID)
+	if err != nil || record.UserID != userID {
+		return nil, ErrPasskeyNotFound
+	}
+
+	record.Name = name
+	if err := s.credRepo.Update(record); err != nil {
+		return nil, errors.New("ошибка сохранения passkey")
+	}
+	return record, nil
+}
+
+// Delete - удаляет passkey
+// У аккаунта без пароля должен остаться хотя бы один passkey
+func (s *passkeyService) Delete(userID, credentialID uint, client domain.ClientInfo) error {
+	record, err := s.credRepo.FindByID(credentialID)
+	if err != nil || record.UserID != userID {
+		return ErrPasskeyNotFound
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("пользователь не найден")
+	}
+	if user.Password == "" {
+		records, err := s.credRepo.FindByUserID(userID)
+		if err != nil {
+			return errors.New("ошибка загрузки passkeys")
+		}
+		if len(records) <= 1 {
+			return ErrLastPasskey
+		}
+	}
+
+	if err := s.credRepo.Delete(record.ID); err != nil {
+		return errors.New("ошибка удаления passkey")
+	}
+
+	s.recordAudit(domain.AuditActionPasskeyRemoved, userID, client)
+	return nil
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// saveChallenge - сохраняет состояние церемонии и формирует ответ begin
+func (s *passkeyService) saveChallenge(challenge *domain.WebAuthnChallenge, session *webauthn.SessionData, options interface{}) (*domain.PasskeyChallengeResponse, error) {
+	id, err := randomPasskeyHandle()
+	if err != nil {
+		return nil, errors.New("ошибка генерации passkey")
+	}
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return nil, errors.New("ошибка генерации passkey")
+	}
+
+	challenge.ID = id
+	challenge.Session = string(raw)
+	challenge.ExpiresAt = time.Now().Add(s.timeout)
+	if err := s.challengeRepo.Create(challenge); err != nil {
+		return nil, errors.New("ошибка сохранения challenge")
+	}
+
+	return &domain.PasskeyChallengeResponse{ChallengeID: id, Options: options}, nil
+}
+
+// takeChallenge - извлекает (и удаляет) challenge нужного типа
+func (s *passkeyService) takeChallenge(id, purpose string) (*domain.WebAuthnChallenge, *webauthn.SessionData, error) {
+	challenge, err := s.challengeRepo.Take(id)
+	if err != nil || challenge.Purpose != purpose || time.Now().After(challenge.ExpiresAt) {
+		return nil, nil, ErrPasskeyFailed
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal([]byte(challenge.Session), &session); err != nil {
+		return nil, nil, ErrPasskeyFailed
+	}
+	return challenge, &session, nil
+}
+
+// createCredential - проверяет ответ navigator.credentials.create()
+func (s *passkeyService) createCredential(user *domain.User, records []domain.WebAuthnCredential, session *webauthn.SessionData, raw []byte) (*webauthn.Credential, error) {
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(raw)
+	if err != nil {
+		return nil, ErrPasskeyFailed
+	}
+
+	// Проверка challenge, origin, RP ID, флагов и подписи аттестации
+	credential, err := s.webAuthn.CreateCredential(newPasskeyUser(user, records), *session, parsed)
+	if err != nil {
+		return nil, ErrPasskeyFailed
+	}
+
+	// Форматы, которые мы не поддерживаем (tpm, android-key, ...), отклоняем явно
+	allowed := false
+	for _, format := range allowedAttestationFormats {
+		if credential.AttestationType == string(format) {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return nil, ErrPasskeyFailed
+	}
+
+	return credential, nil
+}
+
+// findPasskeyOwner - пользователь по ключу и user handle (вход без email)
+func (s *passkeyService) findPasskeyOwner(rawID, userHandle []byte) (*passkeyUser, error) {
+	record, err := s.credRepo.FindByCredentialID(rawID)
+	if err != nil {
+		return nil, ErrPasskeyFailed
+	}
+	user, err := s.userRepo.FindByID(record.UserID)
+	if err != nil {
+		return nil, ErrPasskeyFailed
+	}
+	if base64.RawURLEncoding.EncodeToString(userHandle) != user.PasskeyHandle {
+		return nil, ErrPasskeyFailed
+	}
+
+	records, err := s.credRepo.FindByUserID(user.ID)
+	if err != nil {
+		return nil, ErrPasskeyFailed
+	}
+	return newPasskeyUser(user, records), nil
+}
+
+// recordAudit - записывает событие, если журнал подключён
+func (s *passkeyService) recordAudit(action string, userID uint, client domain.ClientInfo) {
+	if s.audit == nil {
+		return
+	}
+	s.audit.Record(newAuditEvent(action, userID, userID, client))
+}
+
+// randomPasskeyHandle - случайные 32 байта в base64url
+func randomPasskeyHandle() (string, error) {
+	buf := make([]byte, passkeyHandleBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// newCredentialRecord - запись для БД из проверенного ключа
+func newCredentialRecord(userID uint, name string, credential *webauthn.Credential) *domain.WebAuthnCredential {
+	if name == "" {
+		name = "Passkey " + time.Now().Format("02.01.2006")
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, t := range credential.Transport {
+		transports = append(transports, string(t))
+	}
+
+	return &domain.WebAuthnCredential{
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      strings.Join(transports, ","),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+}
+
+// ================================================================
+// PASSKEY USER - Адаптер domain.User → webauthn.User
+// ================================================================
+
+// passkeyUser - пользователь в представлении библиотеки WebAuthn
+type passkeyUser struct {
+	user        *domain.User
+	credentials []webauthn.Credential
+}
+
+// newPasskeyUser - адаптер с ключами пользователя из БД
+func newPasskeyUser(user *domain.User, records []domain.WebAuthnCredential) *passkeyUser {
+	credentials := make([]webauthn.Credential, 0, len(records))
+	for _, r := range records {
+		var transports []protocol.AuthenticatorTransport
+		for _, t := range strings.Split(r.Transports, ",") {
+			if t != "" {
+				transports = append(transports, protocol.AuthenticatorTransport(t))
+			}
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              r.CredentialID,
+			PublicKey:       r.PublicKey,
+			AttestationType: r.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: r.BackupEligible,
+				BackupState:    r.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    r.AAGUID,
+				SignCount: r.SignCount,
+			},
+		})
+	}
+	return &passkeyUser{user: user, credentials: credentials}
+}
+
+// WebAuthnID - user handle (хранится на устройстве вместе с ключом)
+func (u *passkeyUser) WebAuthnID() []byte {
+	handle, _ := base64.RawURLEncoding.DecodeString(u.user.PasskeyHandle)
+	return handle
+}
+
+// WebAuthnName - имя аккаунта, которое показывает браузер (email)
+func (u *passkeyUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+// WebAuthnDisplayName - отображаемое имя
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	return u.user.Name
+}
+
+// WebAuthnCredentials - зарегистрированные ключи
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
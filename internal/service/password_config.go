@@ -0,0 +1,74 @@
+package service
+
+import (
+	"log"
+	"strings"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/pkg/password"
+)
+
+// ================================================================
+// PASSWORD SETTINGS - Сборка Hasher и Policy из конфигурации
+// ================================================================
+
+// NewPasswordHasher - создаёт password.Hasher по настройкам PASSWORD_*
+// Незаполненные настройки заменяются рекомендованными значениями
+func NewPasswordHasher(cfg *config.Config) *password.Hasher {
+	if cfg == nil {
+		return password.NewHasher(password.Params{})
+	}
+
+	params := password.Params{
+		Algorithm:   cfg.PasswordAlgorithm,
+		Memory:      cfg.PasswordArgon2Memory,
+		Iterations:  cfg.PasswordArgon2Iterations,
+		Parallelism: cfg.PasswordArgon2Parallelism,
+		BcryptCost:  cfg.PasswordBcryptCost,
+	}
+	if cfg.PasswordPepper != "" {
+		params.Pepper = []byte(cfg.PasswordPepper)
+	}
+
+	return password.NewHasher(params)
+}
+
+// NewPasswordPolicy - создаёт password.Policy по настройкам PASSWORD_*
+// Если база утёкших паролей не загрузилась - политика работает без неё
+// (ошибка логируется, сервис не падает)
+func NewPasswordPolicy(cfg *config.Config) *password.Policy {
+	if cfg == nil {
+		return password.NewPolicy(password.PolicyConfig{}, nil)
+	}
+
+	var bannedWords []string
+	for _, word := range strings.Split(cfg.PasswordBannedWords, ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			bannedWords = append(bannedWords, word)
+		}
+	}
+
+	// BreachChecker - интерфейс: nil-указатель *BreachedCorpus в нём
+	// был бы не nil, поэтому присваиваем только успешно загруженную базу
+	var breached password.BreachChecker
+	if cfg.PasswordBreachedCorpus != "" {
+		corpus, err := password.LoadBreachedCorpus(cfg.PasswordBreachedCorpus)
+		if err != nil {
+			log.Printf("⚠️  %v - проверка утёкших паролей отключена", err)
+		} else {
+			breached = corpus
+			log.Printf("✅ База утёкших паролей загружена: %s", cfg.PasswordBreachedCorpus)
+		}
+	}
+
+	return password.NewPolicy(password.PolicyConfig{
+		MinLength:     cfg.PasswordMinLength,
+		MaxLength:     cfg.PasswordMaxLength,
+		RequireLower:  cfg.PasswordRequireLower,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+		MinScore:      cfg.PasswordMinScore,
+		BannedWords:   bannedWords,
+	}, breached)
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/imaging"
+	"advanced-user-api/internal/pkg/storage"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// PROFILE SERVICE - Профиль пользователя и аватар
+// ================================================================
+// Аватар:
+// 1. Тип файла определяется по содержимому (JPEG, PNG, GIF), размер - AVATAR_MAX_SIZE
+// 2. Изображение обрезается до квадрата и перекодируется в JPEG размеров
+//    avatarSizes - исходный файл не сохраняется
+// 3. Файлы пишутся в хранилище (storage.Storage) под новым случайным ID,
+//    после сохранения профиля файлы прежнего аватара удаляются
+
+// ProfileService - интерфейс профиля
+type ProfileService interface {
+	// Get - профиль пользователя
+	Get(userID uint) (*domain.ProfileResponse, error)
+
+	// Update - замена полей профиля (аватар не меняется)
+	Update(userID uint, req *domain.UpdateProfileRequest, actorID uint, client domain.ClientInfo) (*domain.ProfileResponse, error)
+
+	// UploadAvatar - новый аватар из загруженного файла
+	UploadAvatar(userID uint, upload io.Reader, actorID uint, client domain.ClientInfo) (*domain.ProfileResponse, error)
+
+	// DeleteAvatar - удаляет аватар
+	DeleteAvatar(userID uint, actorID uint, client domain.ClientInfo) (*domain.ProfileResponse, error)
+
+	// OpenAvatar - файл аватара avatarID размера size (для GET /avatars/:id/:size)
+	OpenAvatar(avatarID, size string) (*storage.Object, error)
+
+	// AvatarMaxSize - наибольший размер загружаемого файла в байтах
+	AvatarMaxSize() int64
+
+	AvatarRemover
+
+	// ForTenant - сервис, работающий только с пользователями организации orgID
+	ForTenant(orgID uint) ProfileService
+}
+
+// AvatarRemover - удаление файлов аватара, на который больше не ссылается профиль
+// (стирание данных, окончательное удаление аккаунта)
+type AvatarRemover interface {
+	RemoveAvatarFiles(avatarID string)
+}
+
+var (
+	// ErrProfileUserNotFound - пользователь не найден (или в другой организации)
+	ErrProfileUserNotFound = errors.New("пользователь не найден")
+
+	// ErrAvatarTooLarge - файл больше AVATAR_MAX_SIZE или разрешение больше допустимого
+	ErrAvatarTooLarge = errors.New("изображение слишком большое")
+
+	// ErrAvatarUnsupportedType - содержимое файла не JPEG, PNG или GIF
+	ErrAvatarUnsupportedType = errors.New("поддерживаются только изображения JPEG, PNG и GIF")
+
+	// ErrAvatarInvalid - файл повреждён
+	ErrAvatarInvalid = errors.New("не удалось прочитать изображение")
+
+	// ErrAvatarNotFound - аватар не существует (удалён или заменён)
+	ErrAvatarNotFound = errors.New("аватар не найден")
+)
+
+// avatarSizes - стороны сохраняемых квадратов в пикселях, от большего к меньшему
+var avatarSizes = []int{512, 128, 64}
+
+// avatarMaxPixels - наибольшее разрешение загружаемого изображения (4096×4096)
+const avatarMaxPixels = 4096 * 4096
+
+// NewAvatarStorage - хранилище аватаров по AVATAR_STORAGE
+// S3 и совместимые хранилища подключаются реализацией storage.Storage
+func NewAvatarStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.AvatarStorage {
+	case "", "local":
+		return storage.NewLocalStorage(cfg.AvatarDir), nil
+	default:
+		return nil, fmt.Errorf("неизвестное хранилище аватаров AVATAR_STORAGE=%q", cfg.AvatarStorage)
+	}
+}
+
+// profileService - реализация
+type profileService struct {
+	userRepo repository.UserRepository
+	avatars  storage.Storage
+	audit    AuditService // nil - события не пишем
+	cfg      *config.Config
+}
+
+// NewProfileService - конструктор
+func NewProfileService(userRepo repository.UserRepository, avatars storage.Storage, audit AuditService, cfg *config.Config) ProfileService {
+	return &profileService{
+		userRepo: userRepo,
+		avatars:  avatars,
+		audit:    audit,
+		cfg:      cfg,
+	}
+}
+
+// ForTenant - копия сервиса с репозиторием организации orgID
+func (s *profileService) ForTenant(orgID uint) ProfileService {
+	scoped := *s
+	scoped.userRepo = s.userRepo.ForTenant(orgID)
+	return &scoped
+}
+
+// AvatarMaxSize - AVATAR_MAX_SIZE (по умолчанию 5 МБ)
+func (s *profileService) AvatarMaxSize() int64 {
+	if s.cfg.AvatarMaxSize <= 0 {
+		return 5 * 1024 * 1024
+	}
+	return s.cfg.AvatarMaxSize
+}
+
+// ================================================================
+// ПРОФИЛЬ
+// ================================================================
+
+// Get - профиль пользователя организации
+func (s *profileService) Get(userID uint) (*domain.ProfileResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrProfileUserNotFound
+	}
+	return s.response(user), nil
+}
+
+// Update - PUT: поля запроса заменяют профиль целиком
+func (s *profileService) Update(userID uint, req *domain.UpdateProfileRequest, actorID uint, client domain.ClientInfo) (*domain.ProfileResponse, error) {
+	// === ШАГ 1: ПОЛЬЗОВАТЕЛЬ ===
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrProfileUserNotFound
+	}
+	before := user.Profile
+
+	// === ШАГ 2: ОБНОВЛЕНИЕ ===
+	user.Profile.DisplayName = strings.TrimSpace(req.DisplayName)
+	user.Profile.Bio = strings.TrimSpace(req.Bio)
+	user.Profile.Phone = req.Phone
+	user.Profile.Timezone = req.Timezone
+	user.Profile.Locale = req.Locale
+
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, errors.New("ошибка сохранения профиля")
+	}
+
+	// === ШАГ 3: ЖУРНАЛ ===
+	// Телефон и текст "о себе" в журнал не пишем - только факт изменения
+	changes := diffFields(map[string][2]string{
+		"display_name": {before.DisplayName, user.Profile.DisplayName},
+		"bio":          redactedChange(before.Bio, user.Profile.Bio),
+		"phone":        redactedChange(before.Phone, user.Profile.Phone),
+		"timezone":     {before.Timezone, user.Profile.Timezone},
+		"locale":       {before.Locale, user.Profile.Locale},
+	})
+	if s.audit != nil && len(changes) > 0 {
+		event := newAuditEvent(domain.AuditActionUserUpdated, actorID, user.ID, client)
+		event.Changes = changes
+		s.audit.Record(event)
+	}
+
+	return s.response(user), nil
+}
+
+// ================================================================
+// АВАТАР
+// ================================================================
+
+// UploadAvatar - проверка, перекодирование и сохранение нового аватара
+func (s *profileService) UploadAvatar(userID uint, upload io.Reader, actorID uint, client domain.ClientInfo) (*domain.ProfileResponse, error) {
+	// === ШАГ 1: ПОЛЬЗОВАТЕЛЬ ===
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrProfileUserNotFound
+	}
+
+	// === ШАГ 2: ПРОВЕРКА ФАЙЛА ===
+	// Читаем на байт больше лимита: так видно, что файл длиннее
+	maxSize := s.AvatarMaxSize()
+	data, err := io.ReadAll(io.LimitReader(upload, maxSize+1))
+	if err != nil {
+		return nil, ErrAvatarInvalid
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrAvatarTooLarge
+	}
+
+	img, err := imaging.Decode(data, avatarMaxPixels)
+	switch {
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		return nil, ErrAvatarUnsupportedType
+	case errors.Is(err, imaging.ErrTooManyPixels):
+		return nil, ErrAvatarTooLarge
+	case err != nil:
+		return nil, ErrAvatarInvalid
+	}
+
+	// === ШАГ 3: ФАЙЛЫ ВСЕХ РАЗМЕРОВ ===
+	avatarID, err := newAvatarID()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storeAvatar(avatarID, img); err != nil {
+		log.Printf("⚠️  Не удалось сохранить аватар пользователя %d: %v", user.ID, err)
+		s.RemoveAvatarFiles(avatarID)
+		return nil, errors.New("ошибка сохранения аватара")
+	}
+
+	// === ШАГ 4: ПРОФИЛЬ ===
+	previous := user.Profile.AvatarID
+	now := time.Now()
+	user.Profile.AvatarID = avatarID
+	user.Profile.AvatarUpdatedAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		s.RemoveAvatarFiles(avatarID)
+		return nil, errors.New("ошибка сохранения профиля")
+	}
+	s.RemoveAvatarFiles(previous)
+
+	// === ШАГ 5: ЖУРНАЛ ===
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionUserUpdated, actorID, user.ID, client)
+		event.Changes = diffFields(map[string][2]string{"avatar": {previous, avatarID}})
+		s.audit.Record(event)
+	}
+
+	return s.response(user), nil
+}
+
+// storeAvatar - квадраты avatarSizes в JPEG
+// Каждый следующий размер уменьшается из предыдущего, а не из оригинала
+func (s *profileService) storeAvatar(avatarID string, img image.Image) error {
+	for _, size := range avatarSizes {
+		square := imaging.Square(img, size)
+		img = square
+
+		var buf bytes.Buffer
+		if err := imaging.EncodeJPEG(&buf, square); err != nil {
+			return err
+		}
+		if err := s.avatars.Put(context.Background(), avatarKey(avatarID, size), &buf, "image/jpeg"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteAvatar - профиль без аватара, файлы удаляются
+func (s *profileService) DeleteAvatar(userID uint, actorID uint, client domain.ClientInfo) (*domain.ProfileResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrProfileUserNotFound
+	}
+	previous := user.Profile.AvatarID
+	if previous == "" {
+		return nil, ErrAvatarNotFound
+	}
+
+	user.Profile.AvatarID = ""
+	user.Profile.AvatarUpdatedAt = nil
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, errors.New("ошибка сохранения профиля")
+	}
+	s.RemoveAvatarFiles(previous)
+
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionUserUpdated, actorID, user.ID, client)
+		event.Changes = diffFields(map[string][2]string{"avatar": {previous, ""}})
+		s.audit.Record(event)
+	}
+	return s.response(user), nil
+}
+
+// OpenAvatar - файл для отдачи клиенту
+// ID и размер проверяются до обращения к хранилищу: ключ собирается
+// только из известных значений
+func (s *profileService) OpenAvatar(avatarID, size string) (*storage.Object, error) {
+	side, err := strconv.Atoi(size)
+	if err != nil || !validAvatarID(avatarID) || !validAvatarSize(side) {
+		return nil, ErrAvatarNotFound
+	}
+
+	object, err := s.avatars.Get(context.Background(), avatarKey(avatarID, side))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrAvatarNotFound
+	}
+	return object, err
+}
+
+// RemoveAvatarFiles - удаляет файлы всех размеров (ошибки только в лог)
+func (s *profileService) RemoveAvatarFiles(avatarID string) {
+	if avatarID == "" || s.avatars == nil {
+		return
+	}
+	for _, size := range avatarSizes {
+		if err := s.avatars.Delete(context.Background(), avatarKey(avatarID, size)); err != nil {
+			log.Printf("⚠️  Не удалось удалить аватар %s (%d): %v", avatarID, size, err)
+		}
+	}
+}
+
+// ================================================================
+// ВСПОМОГАТЕЛЬНЫЕ ФУНКЦИИ
+// ================================================================
+
+// response - профиль со ссылками на аватар
+func (s *profileService) response(user *domain.User) *domain.ProfileResponse {
+	response := &domain.ProfileResponse{UserID: user.ID, UserProfile: user.Profile}
+	if user.Profile.AvatarID != "" {
+		base := strings.TrimRight(s.cfg.AvatarURL, "/")
+		response.AvatarURLs = make(map[string]string, len(avatarSizes))
+		for _, size := range avatarSizes {
+			response.AvatarURLs[strconv.Itoa(size)] = fmt.Sprintf("%s/%s/%d", base, user.Profile.AvatarID, size)
+		}
+	}
+	return response
+}
+
+// avatarKey - ключ файла в хранилище: avatars/<id>/<size>.jpg
+func avatarKey(avatarID string, size int) string {
+	return fmt.Sprintf("avatars/%s/%d.jpg", avatarID, size)
+}
+
+// newAvatarID - 16 случайных байт (hex)
+func newAvatarID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validAvatarID - 32 символа hex в нижнем регистре
+func validAvatarID(avatarID string) bool {
+	if len(avatarID) != 32 {
+		return false
+	}
+	for _, r := range avatarID {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// validAvatarSize - один из avatarSizes
+func validAvatarSize(size int) bool {
+	for _, known := range avatarSizes {
+		if size == known {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedChange - изменение поля без значений: в журнал попадает только факт
+func redactedChange(before, after string) [2]string {
+	if before == after {
+		return [2]string{}
+	}
+	return [2]string{"", "(изменено)"}
+}
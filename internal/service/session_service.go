@@ -0,0 +1,186 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/jwt"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// SESSION SERVICE - Сеансы и устройства пользователя
+// ================================================================
+// Сеанс создаётся TokenIssuer при каждом входе. Здесь - просмотр,
+// завершение сеансов и проверка токена в AuthMiddleware
+
+// SessionService - интерфейс для управления сеансами
+type SessionService interface {
+	// List - активные сеансы пользователя, currentID помечается как текущий
+	List(userID uint, currentID string) ([]domain.Session, error)
+
+	// Revoke - завершает один сеанс пользователя (в том числе текущий - это выход)
+	Revoke(userID uint, sessionID string) error
+
+	// RevokeOthers - завершает все сеансы, кроме текущего
+	RevokeOthers(userID uint, currentID string) (int64, error)
+
+	// ValidateClaims - проверяет, что сеанс токена не завершён (см. middleware.ClaimsValidator)
+	ValidateClaims(claims *jwt.Claims) error
+}
+
+// ErrSessionNotFound - сеанса нет, он чужой или уже завершён
+var ErrSessionNotFound = errors.New("сеанс не найден")
+
+// sessionTouchInterval - как часто обновлять last_seen_at
+// Без ограничения каждый запрос превращался бы в запись в БД
+const sessionTouchInterval = time.Minute
+
+// sessionIDBytes - длина ID сеанса
+const sessionIDBytes = 16
+
+// sessionService - реализация
+type sessionService struct {
+	sessionRepo repository.SessionRepository
+}
+
+// NewSessionService - конструктор
+func NewSessionService(sessionRepo repository.SessionRepository) SessionService {
+	return &sessionService{sessionRepo: sessionRepo}
+}
+
+// List - активные сеансы пользователя
+func (s *sessionService) List(userID uint, currentID string) ([]domain.Session, error) {
+	sessions, err := s.sessionRepo.FindActiveByUserID(userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// Revoke - завершает один сеанс
+func (s *sessionService) Revoke(userID uint, sessionID string) error {
+	revoked, err := s.sessionRepo.Revoke(sessionID, userID, time.Now())
+	if err != nil {
+		return errors.New("ошибка завершения сеанса")
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOthers - завершает все сеансы, кроме текущего
+func (s *sessionService) RevokeOthers(userID uint, currentID string) (int64, error) {
+	count, err := s.sessionRepo.RevokeAllExcept(userID, currentID, time.Now())
+	if err != nil {
+		return 0, errors.New("ошибка завершения сеансов")
+	}
+	return count, nil
+}
+
+// ValidateClaims - токен принимается, только если его сеанс активен
+// Токены без "sid" (выданные до появления сеансов) не принимаются:
+// иначе их нельзя было бы завершить
+func (s *sessionService) ValidateClaims(claims *jwt.Claims) error {
+	if claims.SessionID == "" {
+		return ErrTokenRevoked
+	}
+
+	session, err := s.sessionRepo.FindByID(claims.SessionID)
+	if err != nil {
+		return ErrTokenRevoked
+	}
+
+	now := time.Now()
+	if session.UserID != claims.UserID || session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return ErrTokenRevoked
+	}
+
+	// Время последней активности - с точностью до sessionTouchInterval
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.sessionRepo.Touch(session.ID, now); err != nil {
+			log.Printf("⚠️  Не удалось обновить сеанс %s: %v", session.ID, err)
+		}
+	}
+	return nil
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// generateSessionID - случайный ID сеанса
+func generateSessionID() (string, error) {
+	buf := make([]byte, sessionIDBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// deviceName - название устройства для списка сеансов
+// Приоритет у X-Device-Name от клиента (например, "iPhone Анны"),
+// иначе - браузер и ОС из User-Agent
+func deviceName(client domain.ClientInfo) string {
+	if name := strings.TrimSpace(client.DeviceName); name != "" {
+		if runes := []rune(name); len(runes) > 64 {
+			name = string(runes[:64])
+		}
+		return name
+	}
+	return describeUserAgent(client.UserAgent)
+}
+
+// describeUserAgent - "Chrome on macOS" из строки User-Agent
+// Порядок проверок важен: UA Edge содержит "Chrome", UA Chrome - "Safari"
+func describeUserAgent(ua string) string {
+	if strings.TrimSpace(ua) == "" {
+		return "Неизвестное устройство"
+	}
+
+	browser := ""
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	default:
+		// curl/8.0, okhttp/4.12, PostmanRuntime/7.36 - имя клиента до "/"
+		browser, _, _ = strings.Cut(strings.Fields(ua)[0], "/")
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		platform = "iOS"
+	case strings.Contains(ua, "Android"):
+		platform = "Android"
+	case strings.Contains(ua, "Windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "Mac OS X"):
+		platform = "macOS"
+	case strings.Contains(ua, "Linux"):
+		platform = "Linux"
+	}
+
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
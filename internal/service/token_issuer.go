@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/jwt"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// TOKEN ISSUER - Выдача JWT токенов
+// ================================================================
+
+// TokenIssuer - выдаёт JWT токен после успешной аутентификации
+// Один на все способы входа (пароль, magic link, passkey),
+// чтобы токены везде имели одинаковый формат и срок жизни
+type TokenIssuer interface {
+	// Issue - открывает сеанс для клиента и выдаёт токен с его ID
+	Issue(user *domain.User, client domain.ClientInfo) (*domain.AuthResponse, error)
+
+	// Impersonate - токен пользователя target для администратора actor
+	// Срок жизни ttl, в токене claim "act" с данными администратора
+	Impersonate(target, actor *domain.User, client domain.ClientInfo, ttl time.Duration) (*domain.AuthResponse, error)
+}
+
+// tokenIssuer - реализация на основе JWT_SECRET / JWT_EXPIRATION
+type tokenIssuer struct {
+	cfg      *config.Config
+	sessions repository.SessionRepository // nil - токены без сеансов
+}
+
+// NewTokenIssuer - конструктор
+// sessions = nil - токены не привязываются к сеансам (например, в тестах)
+func NewTokenIssuer(cfg *config.Config, sessions repository.SessionRepository) TokenIssuer {
+	return &tokenIssuer{cfg: cfg, sessions: sessions}
+}
+
+// Issue - генерирует JWT токен и формирует ответ для клиента
+func (t *tokenIssuer) Issue(user *domain.User, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	// Парсим время жизни токена из конфигурации
+	// "24h" → 24 часа
+	expiration, err := time.ParseDuration(t.cfg.JWTExpiration)
+	if err != nil {
+		expiration = 24 * time.Hour // Если ошибка парсинга - используем 24 часа
+	}
+
+	return t.issue(user, nil, client, expiration)
+}
+
+// Impersonate - токен имперсонации
+// Сеанс открывается у пользователя target: он видит его в списке сеансов и может завершить
+func (t *tokenIssuer) Impersonate(target, actor *domain.User, client domain.ClientInfo, ttl time.Duration) (*domain.AuthResponse, error) {
+	client.DeviceName = "Поддержка: " + actor.Email
+	return t.issue(target, &jwt.Actor{UserID: actor.ID, Email: actor.Email}, client, ttl)
+}
+
+// issue - открывает сеанс и подписывает токен
+// Неактивному аккаунту токен не выдаётся (*domain.AccountStatusError) -
+// проверка общая для всех способов входа и имперсонации
+func (t *tokenIssuer) issue(user *domain.User, actor *jwt.Actor, client domain.ClientInfo, expiration time.Duration) (*domain.AuthResponse, error) {
+	if err := user.CheckStatus(time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Сеанс создаётся ДО токена: его ID записывается в claim "sid"
+	var sessionID string
+	if t.sessions != nil {
+		session, err := t.openSession(user, client, expiration)
+		if err != nil {
+			return nil, errors.New("ошибка создания сеанса")
+		}
+		sessionID = session.ID
+	}
+
+	// Генерируем JWT токен с данными пользователя
+	// TokenVersion позволяет отозвать токен сменой пароля (см. ValidateClaims)
+	token, err := jwt.GenerateTokenFromClaims(jwt.Claims{
+		UserID:         user.ID,               // ID пользователя
+		Email:          user.Email,            // Email
+		Role:           user.Role,             // Роль
+		TokenVersion:   user.TokenVersion,     // Версия токенов
+		SessionID:      sessionID,             // Сеанс (пусто - без сеансов)
+		Actor:          actor,                 // Администратор (nil - обычный вход)
+		OrganizationID: client.OrganizationID, // Организация запроса (0 - без организаций)
+	}, t.cfg.JWTSecret, expiration)
+	if err != nil {
+		return nil, errors.New("ошибка генерации токена")
+	}
+
+	// Возвращаем токен и данные пользователя (без пароля!)
+	return &domain.AuthResponse{
+		Token: token,
+		User:  user,
+	}, nil
+}
+
+// openSession - сохраняет новый сеанс со сроком жизни токена
+func (t *tokenIssuer) openSession(user *domain.User, client domain.ClientInfo, expiration time.Duration) (*domain.Session, error) {
+	id, err := generateSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &domain.Session{
+		ID:         id,
+		UserID:     user.ID,
+		DeviceName: deviceName(client),
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(expiration),
+	}
+	if err := t.sessions.Create(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
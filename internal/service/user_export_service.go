@@ -0,0 +1,489 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/parquet"
+	"advanced-user-api/internal/repository"
+)
+
+// ================================================================
+// USER EXPORT SERVICE - Потоковая выгрузка пользователей
+// ================================================================
+// GET /users собирает весь список в памяти (FindAll). Выгрузка читает
+// пользователей пачками по USER_EXPORT_BATCH_SIZE (keyset пагинация:
+// WHERE id > последний ID ORDER BY id) и сразу пишет их в ответ -
+// память не зависит от числа пользователей
+
+// ErrUserExportColumn - колонки нет среди доступных или она указана дважды
+var ErrUserExportColumn = errors.New("некорректная колонка выгрузки")
+
+// UserExportService - интерфейс для выгрузки пользователей
+type UserExportService interface {
+	// Prepare - проверяет формат и колонки; пользователи читаются только
+	// в UserExport.Stream, поэтому ошибки параметров известны до начала ответа
+	Prepare(actorID uint, req *domain.UserExportRequest, client domain.ClientInfo) (*UserExport, error)
+
+	// ForTenant - копия сервиса для организации orgID
+	ForTenant(orgID uint) UserExportService
+}
+
+// UserExport - подготовленная выгрузка
+type UserExport struct {
+	Format  string
+	Columns []string
+
+	// Rows и Cursor - сколько строк отдано клиенту и ID последнего из них
+	// Растут после каждой пачки (Parquet - только после записи файла целиком):
+	// прерванную выгрузку можно продолжить с ?cursor=Cursor
+	Rows   int
+	Cursor uint
+
+	service *userExportService
+	users   repository.UserRepository
+	columns []userExportColumn
+	filter  domain.UserFilter
+	start   uint // Cursor из запроса
+	actorID uint
+	client  domain.ClientInfo
+}
+
+// userExportService - реализация
+type userExportService struct {
+	userRepo   repository.UserRepository
+	attributes AttributeService // nil - колонки attr.<key> недоступны
+	audit      AuditService     // nil - события не пишем
+	cfg        *config.Config
+	orgID      uint
+}
+
+// NewUserExportService - конструктор
+func NewUserExportService(userRepo repository.UserRepository, attributes AttributeService, audit AuditService, cfg *config.Config) UserExportService {
+	return &userExportService{
+		userRepo:   userRepo,
+		attributes: attributes,
+		audit:      audit,
+		cfg:        cfg,
+	}
+}
+
+// ForTenant - копия сервиса для организации orgID
+func (s *userExportService) ForTenant(orgID uint) UserExportService {
+	scoped := *s
+	scoped.orgID = orgID
+	return &scoped
+}
+
+// Prepare - формат, колонки и фильтр выгрузки
+func (s *userExportService) Prepare(actorID uint, req *domain.UserExportRequest, client domain.ClientInfo) (*UserExport, error) {
+	format := req.Format
+	if format == "" {
+		format = domain.UserExportFormatCSV
+	}
+
+	names := domain.UserExportDefaultColumns
+	if strings.TrimSpace(req.Columns) != "" {
+		names = nil
+		for _, name := range strings.Split(req.Columns, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	columns, err := s.resolveColumns(names)
+	if err != nil {
+		return nil, err
+	}
+
+	// Продолжают с id последней полученной строки: трейлеры X-Export-Cursor
+	// теряются в прокси и клиентах, поэтому id должен быть в самих строках
+	if req.Cursor != 0 && !slices.Contains(names, "id") {
+		return nil, fmt.Errorf("%w: продолжение выгрузки (cursor) требует колонку id", ErrUserExportColumn)
+	}
+
+	return &UserExport{
+		Format:  format,
+		Columns: names,
+		Cursor:  req.Cursor,
+		service: s,
+		users:   s.userRepo.ForTenant(s.orgID),
+		columns: columns,
+		filter:  req.Filter,
+		start:   req.Cursor,
+		actorID: actorID,
+		client:  client,
+	}, nil
+}
+
+// ================================================================
+// КОЛОНКИ
+// ================================================================
+
+// userExportColumn - колонка выгрузки: тип (для Parquet) и значение пользователя
+// value возвращает nil (пусто в CSV, null в NDJSON и Parquet), string,
+// int64, float64, bool, time.Time или json.RawMessage (колонка attributes)
+type userExportColumn struct {
+	kind  parquet.Type
+	value func(user *domain.User) interface{}
+}
+
+// userExportColumns - колонки пользователя (атрибуты - attr.<key>)
+var userExportColumns = map[string]userExportColumn{
+	"id":              {parquet.Int64, func(u *domain.User) interface{} { return int64(u.ID) }},
+	"organization_id": {parquet.Int64, func(u *domain.User) interface{} { return int64(u.OrganizationID) }},
+	"email":           {parquet.String, func(u *domain.User) interface{} { return u.Email }},
+	"name":            {parquet.String, func(u *domain.User) interface{} { return u.Name }},
+	"role":            {parquet.String, func(u *domain.User) interface{} { return u.Role }},
+	"status":          {parquet.String, func(u *domain.User) interface{} { return u.Status }},
+	"auth_provider":   {parquet.String, func(u *domain.User) interface{} { return u.AuthProvider }},
+	"created_at":      {parquet.Timestamp, func(u *domain.User) interface{} { return u.CreatedAt.UTC() }},
+	"updated_at":      {parquet.Timestamp, func(u *domain.User) interface{} { return u.UpdatedAt.UTC() }},
+	"password_changed_at": {parquet.Timestamp, func(u *domain.User) interface{} {
+		if u.PasswordChangedAt == nil {
+			return nil
+		}
+		return u.PasswordChangedAt.UTC()
+	}},
+	// attributes - все атрибуты одним JSON объектом (в Parquet - строкой)
+	"attributes": {parquet.String, func(u *domain.User) interface{} {
+		if len(u.Attributes) == 0 {
+			return json.RawMessage("{}")
+		}
+		data, err := json.Marshal(u.Attributes)
+		if err != nil {
+			return nil
+		}
+		return json.RawMessage(data)
+	}},
+}
+
+// resolveColumns - колонки по именам; attr.<key> - по схеме атрибутов организации
+// Выгрузка только для администраторов: видимость атрибутов не ограничивает колонки
+func (s *userExportService) resolveColumns(names []string) ([]userExportColumn, error) {
+	var definitions map[string]domain.AttributeDefinition
+	seen := map[string]bool{}
+	columns := make([]userExportColumn, 0, len(names))
+	for _, name := range names {
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %s указана дважды", ErrUserExportColumn, name)
+		}
+		seen[name] = true
+
+		if column, ok := userExportColumns[name]; ok {
+			columns = append(columns, column)
+			continue
+		}
+		key, found := strings.CutPrefix(name, "attr.")
+		if !found || s.attributes == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUserExportColumn, name)
+		}
+		if definitions == nil {
+			list, err := s.attributes.ForTenant(s.orgID).ListDefinitions()
+			if err != nil {
+				return nil, err
+			}
+			definitions = make(map[string]domain.AttributeDefinition, len(list))
+			for _, definition := range list {
+				definitions[definition.Key] = definition
+			}
+		}
+		definition, ok := definitions[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s (атрибута нет в схеме)", ErrUserExportColumn, name)
+		}
+		columns = append(columns, attributeExportColumn(definition))
+	}
+	return columns, nil
+}
+
+// attributeExportColumn - колонка атрибута с типом из схемы
+// Значение другого типа (например, записанное до изменения схемы) выгружается как null
+func attributeExportColumn(definition domain.AttributeDefinition) userExportColumn {
+	key := definition.Key
+	switch definition.Type {
+	case domain.AttributeTypeNumber:
+		return userExportColumn{parquet.Double, func(u *domain.User) interface{} {
+			if value, ok := u.Attributes[key].(float64); ok {
+				return value
+			}
+			return nil
+		}}
+	case domain.AttributeTypeBoolean:
+		return userExportColumn{parquet.Boolean, func(u *domain.User) interface{} {
+			if value, ok := u.Attributes[key].(bool); ok {
+				return value
+			}
+			return nil
+		}}
+	}
+	return userExportColumn{parquet.String, func(u *domain.User) interface{} {
+		if value, ok := u.Attributes[key].(string); ok {
+			return value
+		}
+		return nil
+	}}
+}
+
+// ================================================================
+// ПОТОКОВАЯ ЗАПИСЬ
+// ================================================================
+
+// Stream - пишет выгрузку в w
+// После каждой пачки данные отдаются клиенту: w.Flush(), если w его поддерживает
+// При ошибке записанное раньше остаётся у клиента, Rows и Cursor - сколько именно
+func (e *UserExport) Stream(w io.Writer) error {
+	err := e.stream(w)
+	e.service.record(e, err)
+	return err
+}
+
+// stream - пачки пользователей по возрастанию ID
+func (e *UserExport) stream(w io.Writer) error {
+	encoder, err := newUserExportEncoder(e, w)
+	if err != nil {
+		return err
+	}
+
+	batchSize := e.service.cfg.UserExportBatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	flusher, _ := w.(interface{ Flush() error })
+
+	// pending - строки, записанные в encoder, но ещё не отданные клиенту
+	pendingRows, pendingCursor := 0, e.Cursor
+	row := make([]interface{}, len(e.columns))
+	for {
+		// === ШАГ 1: СЛЕДУЮЩАЯ ПАЧКА ===
+		users, err := e.users.FindPage(e.filter, pendingCursor, batchSize)
+		if err != nil {
+			return err
+		}
+
+		// === ШАГ 2: ЗАПИСЬ ===
+		for i := range users {
+			for j, column := range e.columns {
+				row[j] = column.value(&users[i])
+			}
+			if err := encoder.Write(row); err != nil {
+				return err
+			}
+			pendingRows++
+			pendingCursor = users[i].ID
+		}
+
+		// === ШАГ 3: ОТДАЁМ КЛИЕНТУ ===
+		delivered, err := encoder.Flush()
+		if err != nil {
+			return err
+		}
+		if flusher != nil {
+			if err := flusher.Flush(); err != nil {
+				return err
+			}
+		}
+		if delivered {
+			e.Rows, e.Cursor = e.Rows+pendingRows, pendingCursor
+			pendingRows = 0
+		}
+
+		if len(users) < batchSize {
+			break
+		}
+	}
+
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	e.Rows, e.Cursor = e.Rows+pendingRows, pendingCursor
+	return nil
+}
+
+// record - событие журнала о выгрузке (и о прерванной тоже)
+func (s *userExportService) record(e *UserExport, err error) {
+	if s.audit == nil {
+		return
+	}
+
+	event := newAuditEvent(domain.AuditActionUsersExported, e.actorID, 0, e.client)
+	event.Details = fmt.Sprintf("выгрузка %s: организация %d, строк %d, колонки %s",
+		e.Format, s.orgID, e.Rows, strings.Join(e.Columns, ","))
+	if e.start != 0 {
+		event.Details += fmt.Sprintf(", продолжение после %d", e.start)
+	}
+	if err != nil {
+		event.Details += ", прервана"
+	}
+	s.audit.Record(event)
+}
+
+// ================================================================
+// ФОРМАТЫ
+// ================================================================
+
+// userExportEncoder - запись строк в формате выгрузки
+type userExportEncoder interface {
+	Write(row []interface{}) error
+
+	// Flush - пачка записана; true - строки отданы в поток и полезны
+	// клиенту даже без конца файла (CSV, NDJSON)
+	Flush() (bool, error)
+
+	// Close - конец файла
+	Close() error
+}
+
+// newUserExportEncoder - encoder формата выгрузки
+func newUserExportEncoder(e *UserExport, w io.Writer) (userExportEncoder, error) {
+	switch e.Format {
+	case domain.UserExportFormatNDJSON:
+		keys := make([][]byte, len(e.Columns))
+		for i, name := range e.Columns {
+			keys[i], _ = json.Marshal(name)
+		}
+		return &ndjsonUserExportEncoder{w: bufio.NewWriter(w), keys: keys}, nil
+
+	case domain.UserExportFormatParquet:
+		columns := make([]parquet.Column, len(e.columns))
+		for i, column := range e.columns {
+			columns[i] = parquet.Column{Name: e.Columns[i], Type: column.kind}
+		}
+		writer, err := parquet.NewWriter(w, columns, e.service.cfg.UserExportRowGroupSize)
+		if err != nil {
+			return nil, err
+		}
+		return &parquetUserExportEncoder{w: writer, row: make([]interface{}, len(columns))}, nil
+	}
+
+	// CSV: заголовок - имена колонок (и в продолжении выгрузки)
+	writer := csv.NewWriter(w)
+	if err := writer.Write(e.Columns); err != nil {
+		return nil, err
+	}
+	return &csvUserExportEncoder{w: writer, record: make([]string, len(e.Columns)), raw: e.service.cfg.UserExportCSVRaw}, nil
+}
+
+// csvUserExportEncoder - CSV: время в RFC 3339, null - пустая ячейка
+type csvUserExportEncoder struct {
+	w      *csv.Writer
+	record []string
+	raw    bool // USER_EXPORT_CSV_RAW: строки как есть, без защиты от формул
+}
+
+// csvFormulaPrefixes - с этих символов Excel и LibreOffice начинают формулу
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// escapeCSVFormula - защита от CSV injection: имя "=HYPERLINK(...)" из
+// регистрации не должно выполниться в таблице администратора
+// Ячейка, похожая на формулу, начинается с ', который таблица не показывает
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.IndexByte(csvFormulaPrefixes, value[0]) >= 0 {
+		return "'" + value
+	}
+	return value
+}
+
+func (e *csvUserExportEncoder) Write(row []interface{}) error {
+	for i, value := range row {
+		switch value := value.(type) {
+		case nil:
+			e.record[i] = ""
+		case string:
+			// Экранируются только строки: число -5 и JSON атрибутов - не формулы
+			if !e.raw {
+				value = escapeCSVFormula(value)
+			}
+			e.record[i] = value
+		case int64:
+			e.record[i] = strconv.FormatInt(value, 10)
+		case float64:
+			e.record[i] = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			e.record[i] = strconv.FormatBool(value)
+		case time.Time:
+			e.record[i] = value.Format(time.RFC3339)
+		case json.RawMessage:
+			e.record[i] = string(value)
+		}
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvUserExportEncoder) Flush() (bool, error) {
+	e.w.Flush()
+	return true, e.w.Error()
+}
+
+func (e *csvUserExportEncoder) Close() error {
+	_, err := e.Flush()
+	return err
+}
+
+// ndjsonUserExportEncoder - по JSON объекту на строку, поля в порядке колонок
+type ndjsonUserExportEncoder struct {
+	w    *bufio.Writer
+	keys [][]byte // Имена колонок в JSON
+}
+
+func (e *ndjsonUserExportEncoder) Write(row []interface{}) error {
+	e.w.WriteByte('{')
+	for i, value := range row {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		e.w.Write(e.keys[i])
+		e.w.WriteByte(':')
+		e.w.Write(data)
+	}
+	_, err := e.w.WriteString("}\n")
+	return err
+}
+
+func (e *ndjsonUserExportEncoder) Flush() (bool, error) {
+	return true, e.w.Flush()
+}
+
+func (e *ndjsonUserExportEncoder) Close() error {
+	return e.w.Flush()
+}
+
+// parquetUserExportEncoder - Parquet: группы строк по USER_EXPORT_ROW_GROUP_SIZE
+// Недописанный файл нечитаем (метаданные в конце), поэтому строки
+// считаются отданными только после Close
+type parquetUserExportEncoder struct {
+	w   *parquet.Writer
+	row []interface{}
+}
+
+func (e *parquetUserExportEncoder) Write(row []interface{}) error {
+	for i, value := range row {
+		if raw, ok := value.(json.RawMessage); ok {
+			value = string(raw)
+		}
+		e.row[i] = value
+	}
+	return e.w.Write(e.row)
+}
+
+func (e *parquetUserExportEncoder) Flush() (bool, error) {
+	return false, nil
+}
+
+func (e *parquetUserExportEncoder) Close() error {
+	return e.w.Close()
+}
@@ -0,0 +1,924 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/password"
+	"advanced-user-api/internal/repository"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ================================================================
+// USER IMPORT SERVICE - Массовый импорт пользователей (CSV / NDJSON)
+// ================================================================
+// 1. Администратор загружает файл - он сохраняется в USER_IMPORT_DIR,
+//    ответ 202 с задачей сразу (CLI выполняет задачу синхронно)
+// 2. Фоновый обработчик (RunWorker) проверяет каждую строку: поля, политику
+//    паролей или формат готового хеша, атрибуты по схеме организации,
+//    повторы email в файле и среди существующих пользователей
+// 3. Правильные строки записываются пачками по USER_IMPORT_BATCH_SIZE,
+//    одна транзакция на пачку; ошибки - отдельно по каждой строке
+// 4. Прогресс (processed/total) сохраняется после каждой пачки
+
+// UserImportService - интерфейс импорта пользователей
+type UserImportService interface {
+	// Request - сохраняет файл и создаёт задачу для фонового обработчика
+	// actorID - администратор, загрузивший файл (0 - CLI)
+	Request(actorID uint, req *domain.CreateUserImportRequest, data io.Reader) (*domain.UserImport, error)
+
+	// Import - то же, но задача выполняется сразу, до возврата (CLI)
+	Import(actorID uint, req *domain.CreateUserImportRequest, data io.Reader) (*domain.UserImport, error)
+
+	// Get - задача импорта организации
+	Get(id uint) (*domain.UserImport, error)
+
+	// ProcessPending - выполняет все ожидающие задачи
+	ProcessPending() (int, error)
+
+	// RunWorker - обработка задач сразу после запроса и раз в interval до отмены ctx
+	RunWorker(ctx context.Context, interval time.Duration)
+
+	// ForTenant - сервис, импортирующий пользователей в организацию orgID
+	ForTenant(orgID uint) UserImportService
+}
+
+var (
+	// ErrUserImportNotFound - задача не существует (или относится к другой организации)
+	ErrUserImportNotFound = errors.New("импорт не найден")
+
+	// ErrUserImportFormat - формат файла не указан или не поддерживается
+	ErrUserImportFormat = errors.New("поддерживаются форматы csv и ndjson")
+
+	// ErrUserImportEmpty - пустой файл
+	ErrUserImportEmpty = errors.New("файл импорта пуст")
+
+	// ErrUserImportTooLarge - файл больше USER_IMPORT_MAX_SIZE
+	ErrUserImportTooLarge = errors.New("файл импорта слишком большой")
+
+	// ErrUserImportInvalidFile - файл не разбирается целиком (заголовок CSV)
+	ErrUserImportInvalidFile = errors.New("некорректный файл импорта")
+)
+
+// Значения по умолчанию для незаданных USER_IMPORT_*
+const (
+	defaultUserImportMaxSize   = 20 * 1024 * 1024
+	defaultUserImportBatchSize = 500
+)
+
+// userImportStaleAfter - задача processing дольше этого считается зависшей
+const userImportStaleAfter = 15 * time.Minute
+
+// userImportMaxLine - наибольшая длина строки NDJSON
+const userImportMaxLine = 1024 * 1024
+
+// Действия со строкой после проверки
+const (
+	importActionCreate = "create"
+	importActionUpdate = "update"
+	importActionInvite = "invite"
+)
+
+// UserImportOption - необязательная настройка User Import Service
+type UserImportOption func(*userImportService)
+
+// WithUserImportPasswordHasher - заменяет Hasher, собранный из PASSWORD_* настроек
+func WithUserImportPasswordHasher(hasher *password.Hasher) UserImportOption {
+	return func(s *userImportService) {
+		s.hasher = hasher
+	}
+}
+
+// WithUserImportPasswordPolicy - заменяет политику паролей, собранную из PASSWORD_* настроек
+func WithUserImportPasswordPolicy(policy *password.Policy) UserImportOption {
+	return func(s *userImportService) {
+		s.policy = policy
+	}
+}
+
+// WithUserImportSessions - подключает сеансы: новый пароль существующего
+// пользователя завершает его сеансы (как смена пароля)
+func WithUserImportSessions(sessions repository.SessionRepository) UserImportOption {
+	return func(s *userImportService) {
+		s.sessions = sessions
+	}
+}
+
+// userImportService - реализация
+type userImportService struct {
+	imports     repository.UserImportRepository
+	userRepo    repository.UserRepository
+	attributes  AttributeService             // nil - строки с атрибутами отклоняются
+	invitations InvitationService            // nil - invite недоступен
+	audit       AuditService                 // nil - события не пишем
+	sessions    repository.SessionRepository // nil - токены без сеансов
+	hasher      *password.Hasher
+	policy      *password.Policy
+	cfg         *config.Config
+	orgID       uint
+
+	// wake - сигнал обработчику о новой задаче (буфер 1, общий для копий ForTenant)
+	wake chan struct{}
+}
+
+// NewUserImportService - конструктор
+func NewUserImportService(
+	imports repository.UserImportRepository,
+	userRepo repository.UserRepository,
+	attributes AttributeService,
+	invitations InvitationService,
+	audit AuditService,
+	cfg *config.Config,
+	opts ...UserImportOption,
+) UserImportService {
+	s := &userImportService{
+		imports:     imports,
+		userRepo:    userRepo,
+		attributes:  attributes,
+		invitations: invitations,
+		audit:       audit,
+		cfg:         cfg,
+		wake:        make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.hasher == nil {
+		s.hasher = NewPasswordHasher(cfg)
+	}
+	if s.policy == nil {
+		s.policy = NewPasswordPolicy(cfg)
+	}
+	return s
+}
+
+// ForTenant - копия сервиса для организации orgID
+// Репозитории выбираются по организации задачи при её обработке
+func (s *userImportService) ForTenant(orgID uint) UserImportService {
+	scoped := *s
+	scoped.orgID = orgID
+	return &scoped
+}
+
+// ================================================================
+// ЗАДАЧИ
+// ================================================================
+
+// Request - задача для фонового обработчика
+func (s *userImportService) Request(actorID uint, req *domain.CreateUserImportRequest, data io.Reader) (*domain.UserImport, error) {
+	job, err := s.create(actorID, req, data, domain.UserImportStatusPending)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Import - задача создаётся сразу в processing (обработчик API её не возьмёт)
+// и выполняется в текущей горутине
+func (s *userImportService) Import(actorID uint, req *domain.CreateUserImportRequest, data io.Reader) (*domain.UserImport, error) {
+	job, err := s.create(actorID, req, data, domain.UserImportStatusProcessing)
+	if err != nil {
+		return nil, err
+	}
+	s.process(job)
+	return job, nil
+}
+
+// Get - задача текущей организации
+func (s *userImportService) Get(id uint) (*domain.UserImport, error) {
+	job, err := s.imports.FindByID(id)
+	if err != nil || job.OrganizationID != s.orgID {
+		return nil, ErrUserImportNotFound
+	}
+	return job, nil
+}
+
+// create - сохраняет файл в USER_IMPORT_DIR и создаёт задачу
+func (s *userImportService) create(actorID uint, req *domain.CreateUserImportRequest, data io.Reader, status string) (*domain.UserImport, error) {
+	if req.Format != domain.UserImportFormatCSV && req.Format != domain.UserImportFormatNDJSON {
+		return nil, ErrUserImportFormat
+	}
+
+	// === ШАГ 1: ФАЙЛ ===
+	fileName, err := s.store(req.Format, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 2: ЗАДАЧА ===
+	job := &domain.UserImport{
+		OrganizationID: s.orgID,
+		RequestedByID:  actorID,
+		Format:         req.Format,
+		DryRun:         req.DryRun,
+		Upsert:         req.Upsert,
+		Invite:         req.Invite,
+		Status:         status,
+		FileName:       fileName,
+	}
+	if status == domain.UserImportStatusProcessing {
+		now := time.Now()
+		job.StartedAt = &now
+	}
+	if err := s.imports.Create(job); err != nil {
+		os.Remove(filepath.Join(s.cfg.UserImportDir, fileName))
+		return nil, errors.New("ошибка создания импорта")
+	}
+	return job, nil
+}
+
+// store - копирует файл не больше USER_IMPORT_MAX_SIZE под случайным именем
+func (s *userImportService) store(format string, data io.Reader) (string, error) {
+	maxSize := s.cfg.UserImportMaxSize
+	if maxSize <= 0 {
+		maxSize = defaultUserImportMaxSize
+	}
+	if err := os.MkdirAll(s.cfg.UserImportDir, 0o700); err != nil {
+		return "", err
+	}
+
+	suffix, err := generateMagicLinkToken()
+	if err != nil {
+		return "", err
+	}
+	fileName := fmt.Sprintf("import-%s.%s", suffix[:16], format)
+	path := filepath.Join(s.cfg.UserImportDir, fileName)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	size, err := io.Copy(file, io.LimitReader(data, maxSize+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	switch {
+	case err != nil:
+	case size > maxSize:
+		err = ErrUserImportTooLarge
+	case size == 0:
+		err = ErrUserImportEmpty
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return fileName, nil
+}
+
+// ================================================================
+// ФОНОВЫЙ ОБРАБОТЧИК
+// ================================================================
+
+// ProcessPending - берёт задачи по одной, пока они есть
+func (s *userImportService) ProcessPending() (int, error) {
+	count := 0
+	for {
+		now := time.Now()
+		job, err := s.imports.ClaimPending(now, now.Add(-userImportStaleAfter))
+		if errors.Is(err, repository.ErrUserImportRecordNotFound) {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+
+		s.process(job)
+		count++
+	}
+}
+
+// RunWorker - фоновая обработка задач (сразу, по сигналу Request и раз в interval)
+func (s *userImportService) RunWorker(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := s.ProcessPending(); err != nil {
+			log.Printf("⚠️  Ошибка обработки импорта пользователей: %v", err)
+		} else if count > 0 {
+			log.Printf("📦 Обработано импортов пользователей: %d", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// process - выполняет задачу и сохраняет результат; файл удаляется в любом случае
+func (s *userImportService) process(job *domain.UserImport) {
+	path := filepath.Join(s.cfg.UserImportDir, job.FileName)
+	defer os.Remove(path)
+
+	// Задачу могли начать раньше (обработчик упал) - начинаем заново
+	job.Total, job.Processed, job.Created, job.Updated, job.Invited, job.Failed = 0, 0, 0, 0, 0, 0
+	job.Errors, job.RoleChanges = nil, nil
+
+	err := s.run(job, path)
+	completedAt := time.Now()
+	job.CompletedAt = &completedAt
+	if err != nil {
+		log.Printf("⚠️  Ошибка импорта пользователей %d: %v", job.ID, err)
+		job.Status = domain.UserImportStatusFailed
+		job.Error = "не удалось обработать файл, загрузите его заново"
+		if errors.Is(err, ErrUserImportInvalidFile) {
+			job.Error = err.Error()
+		}
+	} else {
+		job.Status = domain.UserImportStatusCompleted
+	}
+
+	if err := s.imports.Update(job); err != nil {
+		log.Printf("⚠️  Не удалось сохранить импорт %d: %v", job.ID, err)
+	}
+	if err == nil && s.audit != nil {
+		event := newAuditEvent(domain.AuditActionUsersImported, job.RequestedByID, 0, domain.ClientInfo{})
+		event.Details = fmt.Sprintf("импорт %d (%s): организация %d, создано %d, обновлено %d, приглашено %d, ошибок %d",
+			job.ID, job.Format, job.OrganizationID, job.Created, job.Updated, job.Invited, job.Failed)
+		if job.DryRun {
+			event.Details += ", dry-run"
+		}
+		s.audit.Record(event)
+	}
+}
+
+// run - разбор файла и обработка строк пачками
+func (s *userImportService) run(job *domain.UserImport, path string) error {
+	// === ШАГ 1: РАЗБОР ФАЙЛА ===
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var rows []importRow
+	if job.Format == domain.UserImportFormatCSV {
+		rows, err = parseImportCSV(file)
+	} else {
+		rows, err = parseImportNDJSON(file)
+	}
+	if err != nil {
+		return err
+	}
+	job.Total = len(rows)
+	if err := s.imports.Update(job); err != nil {
+		return err
+	}
+
+	// === ШАГ 2: ПАЧКИ ===
+	batchSize := s.cfg.UserImportBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultUserImportBatchSize
+	}
+	batch := s.newBatch(job)
+	for start := 0; start < len(rows); start += batchSize {
+		end := min(start+batchSize, len(rows))
+		batch.run(rows[start:end])
+
+		job.Processed = end
+		if err := s.imports.Update(job); err != nil {
+			return err
+		}
+		log.Printf("📦 Импорт пользователей %d: %d из %d строк", job.ID, job.Processed, job.Total)
+	}
+	return nil
+}
+
+// ================================================================
+// ОБРАБОТКА СТРОК
+// ================================================================
+
+// importRow - строка файла до проверки
+type importRow struct {
+	line   int                  // Номер строки файла
+	row    domain.UserImportRow // Поля строки
+	attrs  map[string]string    // CSV: атрибуты строками (приводятся к типам схемы)
+	errors []string             // Ошибки разбора строки
+}
+
+// importItem - проверенная строка, готовая к записи
+type importItem struct {
+	line   int
+	action string
+	user   *domain.User // create/update
+	email  string       // invite
+
+	// passwordChanged - новый пароль существующего пользователя:
+	// после записи его сеансы завершаются
+	passwordChanged bool
+
+	// roleChange - строка меняет роль существующего пользователя (nil - не меняет)
+	roleChange *domain.UserImportRoleChange
+}
+
+// importBatch - состояние обработки одной задачи
+type importBatch struct {
+	s          *userImportService
+	job        *domain.UserImport
+	users      repository.UserRepository
+	attributes AttributeService
+	seen       map[string]int // email в нижнем регистре → строка, где он встретился
+}
+
+// newBatch - репозитории организации задачи
+func (s *userImportService) newBatch(job *domain.UserImport) *importBatch {
+	batch := &importBatch{
+		s:     s,
+		job:   job,
+		users: s.userRepo.ForTenant(job.OrganizationID),
+		seen:  map[string]int{},
+	}
+	if s.attributes != nil {
+		batch.attributes = s.attributes.ForTenant(job.OrganizationID)
+	}
+	return batch
+}
+
+// run - проверяет строки пачки и записывает правильные
+func (b *importBatch) run(rows []importRow) {
+	var items []importItem
+	for i := range rows {
+		item, problems := b.validate(&rows[i])
+		if len(problems) > 0 {
+			b.job.AddRowError(domain.UserImportRowError{Row: rows[i].line, Email: rows[i].row.Email, Errors: problems})
+			continue
+		}
+		items = append(items, item)
+	}
+
+	if b.job.DryRun {
+		for _, item := range items {
+			b.count(item)
+		}
+		return
+	}
+	b.write(items)
+}
+
+// validate - все нарушения строки; без нарушений - что с ней сделать
+func (b *importBatch) validate(raw *importRow) (importItem, []string) {
+	problems := append([]string(nil), raw.errors...)
+	if len(problems) > 0 {
+		return importItem{}, problems
+	}
+	row := raw.row
+	row.Email = strings.TrimSpace(row.Email)
+	row.Name = strings.TrimSpace(row.Name)
+
+	// === ШАГ 1: ПОЛЯ ===
+	if err := binding.Validator.ValidateStruct(&row); err != nil {
+		problems = append(problems, importFieldErrors(err)...)
+	}
+	if row.Email != "" {
+		key := strings.ToLower(row.Email)
+		if line, ok := b.seen[key]; ok {
+			problems = append(problems, fmt.Sprintf("email: повторяет строку %d", line))
+		} else {
+			b.seen[key] = raw.line
+		}
+	}
+
+	// === ШАГ 2: ПАРОЛЬ ИЛИ ХЕШ ===
+	switch {
+	case row.Password != "" && row.PasswordHash != "":
+		problems = append(problems, "password: укажите password или password_hash, но не оба")
+	case row.Password != "":
+		var policyErr *password.PolicyError
+		if err := b.s.policy.Check(row.Password, password.PolicyInput{Email: row.Email, Name: row.Name}); errors.As(err, &policyErr) {
+			for _, violation := range policyErr.Violations {
+				problems = append(problems, "password: "+violation.Message)
+			}
+		} else if err != nil {
+			problems = append(problems, "password: "+err.Error())
+		}
+	case row.PasswordHash != "":
+		if err := b.s.hasher.CheckFormat(row.PasswordHash); errors.Is(err, password.ErrCostTooHigh) {
+			problems = append(problems, "password_hash: "+err.Error())
+		} else if err != nil {
+			problems = append(problems, "password_hash: ожидается $argon2id$... (PHC) или bcrypt ($2a$, $2b$, $2y$)")
+		}
+	}
+	if len(problems) > 0 {
+		return importItem{}, problems
+	}
+
+	// === ШАГ 3: СУЩЕСТВУЮЩИЙ ПОЛЬЗОВАТЕЛЬ ИЛИ ПРИГЛАШЕНИЕ ===
+	existing, _ := b.users.FindByEmail(row.Email)
+	item := importItem{line: raw.line, action: importActionCreate, email: row.Email}
+	switch {
+	case existing != nil && !b.job.Upsert:
+		return item, []string{"email: пользователь уже существует (для обновления включите upsert)"}
+	case existing != nil:
+		item.action = importActionUpdate
+	case row.Password == "" && row.PasswordHash == "" && !b.job.Invite:
+		return item, []string{"password: укажите password или password_hash (или включите invite)"}
+	case row.Password == "" && row.PasswordHash == "":
+		item.action = importActionInvite
+		return item, b.validateInvite(raw, row)
+	}
+
+	// === ШАГ 4: АТРИБУТЫ ===
+	var current domain.UserAttributes
+	if existing != nil {
+		current = existing.Attributes
+	}
+	attributes, err := b.applyAttributes(current, raw, row)
+	if err != nil {
+		return item, importAttributeErrors(err)
+	}
+
+	// === ШАГ 5: ПОЛЬЗОВАТЕЛЬ ДЛЯ ЗАПИСИ ===
+	user := existing
+	if user == nil {
+		user = &domain.User{
+			Email:        row.Email,
+			Role:         "user",
+			AuthProvider: domain.AuthProviderLocal,
+			Status:       domain.UserStatusActive,
+		}
+	}
+	user.Name = row.Name
+	user.Attributes = attributes
+	if row.Role != "" {
+		if existing != nil && existing.Role != row.Role {
+			item.roleChange = &domain.UserImportRoleChange{Row: raw.line, Email: row.Email, From: existing.Role, To: row.Role}
+		}
+		user.Role = row.Role
+	}
+	if b.job.DryRun {
+		item.user = user
+		return item, nil
+	}
+
+	now := time.Now()
+	switch {
+	case row.PasswordHash != "":
+		// Готовый хеш переносится как есть: при следующем входе
+		// upgradePasswordHash пересчитает его текущими настройками
+		user.Password = row.PasswordHash
+		user.PasswordChangedAt = &now
+	case row.Password != "":
+		hashed, err := b.s.hasher.Hash(row.Password)
+		if err != nil {
+			return item, []string{"password: ошибка хеширования пароля"}
+		}
+		user.Password = hashed
+		user.PasswordChangedAt = &now
+	}
+
+	// Новый пароль существующего пользователя отзывает выданные токены,
+	// как ChangePassword: старый пароль мог быть скомпрометирован
+	if existing != nil && (row.Password != "" || row.PasswordHash != "") {
+		user.TokenVersion++
+		item.passwordChanged = true
+	}
+	item.user = user
+	return item, nil
+}
+
+// validateInvite - строка без пароля: пользователь получит приглашение в организацию
+// Имя и атрибуты он укажет сам при регистрации по приглашению
+func (b *importBatch) validateInvite(raw *importRow, row domain.UserImportRow) []string {
+	var problems []string
+	if b.s.invitations == nil || b.job.OrganizationID == 0 {
+		problems = append(problems, "invite: приглашения доступны только при импорте в организацию")
+	}
+	if row.Role != "" && row.Role != "user" {
+		problems = append(problems, "role: приглашённый получает роль user")
+	}
+	if len(row.Attributes) > 0 || len(raw.attrs) > 0 {
+		problems = append(problems, "attributes: атрибуты приглашённый укажет при регистрации")
+	}
+	return problems
+}
+
+// applyAttributes - атрибуты строки по схеме организации (права администратора)
+// Существующему пользователю атрибуты добавляются к текущим
+func (b *importBatch) applyAttributes(current domain.UserAttributes, raw *importRow, row domain.UserImportRow) (domain.UserAttributes, error) {
+	changes := domain.UserAttributes{}
+	for key, value := range row.Attributes {
+		changes[key] = value
+	}
+	if b.attributes == nil {
+		if len(changes) > 0 || len(raw.attrs) > 0 {
+			return nil, ErrAttributesDisabled
+		}
+		if current == nil {
+			current = domain.UserAttributes{}
+		}
+		return current, nil
+	}
+
+	// CSV: значения приводятся к типам схемы по одному, чтобы ошибка
+	// одного атрибута не скрывала нарушения остальных
+	access := domain.AttributeAccess{Admin: true}
+	var violations []domain.AttributeViolation
+	failed := map[string]bool{}
+	for key, value := range raw.attrs {
+		parsed, err := b.attributes.ParseFilter(map[string]string{key: value}, access)
+		var validationErr *domain.AttributeValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			violations = append(violations, validationErr.Violations...)
+			failed[key] = true
+		case err != nil:
+			return nil, err
+		default:
+			changes[key] = parsed[key]
+		}
+	}
+
+	attributes, err := b.attributes.Apply(current, changes, access)
+	var validationErr *domain.AttributeValidationError
+	if errors.As(err, &validationErr) {
+		for _, violation := range validationErr.Violations {
+			if !failed[violation.Key] { // "обязательный" для значения, которое не разобралось
+				violations = append(violations, violation)
+			}
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if len(violations) > 0 {
+		sort.Slice(violations, func(i, j int) bool { return violations[i].Key < violations[j].Key })
+		return nil, &domain.AttributeValidationError{Violations: violations}
+	}
+	return attributes, nil
+}
+
+// write - создание и обновление пачкой, приглашения - по одному
+// Если пачка не записалась, её строки записываются по одной:
+// ошибка остаётся только у строки, которая её вызвала
+func (b *importBatch) write(items []importItem) {
+	var users []*domain.User
+	var saved []importItem
+	for _, item := range items {
+		if item.action == importActionInvite {
+			b.invite(item)
+			continue
+		}
+		users = append(users, item.user)
+		saved = append(saved, item)
+	}
+	if len(users) == 0 {
+		return
+	}
+
+	if err := b.s.imports.SaveBatch(b.job.OrganizationID, users); err == nil {
+		for _, item := range saved {
+			b.saved(item)
+		}
+		return
+	}
+	for _, item := range saved {
+		if err := b.s.imports.SaveBatch(b.job.OrganizationID, []*domain.User{item.user}); err != nil {
+			log.Printf("⚠️  Импорт %d, строка %d: %v", b.job.ID, item.line, err)
+			message := "не удалось сохранить пользователя"
+			if errors.Is(err, repository.ErrUserVersionConflict) {
+				message = err.Error()
+			}
+			b.job.AddRowError(domain.UserImportRowError{Row: item.line, Email: item.user.Email, Errors: []string{message}})
+			continue
+		}
+		b.saved(item)
+	}
+}
+
+// saved - записанный пользователь: счётчик, событие изменения роли и,
+// при новом пароле, завершение сеансов
+// Токены уже не принимаются (TokenVersion) - сеансы убираются из списка
+func (b *importBatch) saved(item importItem) {
+	b.count(item)
+	if item.roleChange != nil && b.s.audit != nil {
+		event := newAuditEvent(domain.AuditActionUserUpdated, b.job.RequestedByID, item.user.ID, domain.ClientInfo{})
+		event.Changes = diffFields(map[string][2]string{"role": {item.roleChange.From, item.roleChange.To}})
+		event.Details = fmt.Sprintf("импорт %d, строка %d", b.job.ID, item.line)
+		b.s.audit.Record(event)
+	}
+	if !item.passwordChanged || b.s.sessions == nil {
+		return
+	}
+	if _, err := b.s.sessions.RevokeAllExcept(item.user.ID, "", time.Now()); err != nil {
+		log.Printf("⚠️  Импорт %d: не удалось завершить сеансы пользователя %d: %v", b.job.ID, item.user.ID, err)
+	}
+}
+
+// invite - приглашение в организацию задачи от имени администратора
+func (b *importBatch) invite(item importItem) {
+	_, err := b.s.invitations.Invite(b.job.OrganizationID, b.job.RequestedByID,
+		&domain.CreateInvitationRequest{Email: item.email}, domain.ClientInfo{OrganizationID: b.job.OrganizationID})
+	if err != nil {
+		message := "не удалось отправить приглашение"
+		if errors.Is(err, ErrInvitationAlreadyPending) || errors.Is(err, ErrAlreadyMember) {
+			message = "email: " + err.Error()
+		}
+		b.job.AddRowError(domain.UserImportRowError{Row: item.line, Email: item.email, Errors: []string{message}})
+		return
+	}
+	b.count(item)
+}
+
+// count - счётчик выполненного (или, в dry-run, будущего) действия
+func (b *importBatch) count(item importItem) {
+	if item.roleChange != nil {
+		b.job.AddRoleChange(*item.roleChange)
+	}
+	switch item.action {
+	case importActionInvite:
+		b.job.Invited++
+	case importActionUpdate:
+		b.job.Updated++
+	default:
+		b.job.Created++
+	}
+}
+
+// ================================================================
+// РАЗБОР ФАЙЛА
+// ================================================================
+
+// Колонки CSV (кроме attr.<key>)
+var importCSVColumns = map[string]bool{
+	"email": true, "name": true, "role": true, "password": true, "password_hash": true,
+}
+
+// parseImportCSV - первая строка - заголовок, остальные - пользователи
+// Неизвестная колонка или отсутствие email/name - ошибка всего файла,
+// ошибка в строке (число полей, кавычки) - ошибка только этой строки
+func parseImportCSV(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: не удалось прочитать заголовок CSV", ErrUserImportInvalidFile)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !importCSVColumns[name] && !strings.HasPrefix(name, "attr.") {
+			return nil, fmt.Errorf("%w: неизвестная колонка %q", ErrUserImportInvalidFile, name)
+		}
+		if _, duplicate := columns[name]; duplicate {
+			return nil, fmt.Errorf("%w: колонка %q повторяется", ErrUserImportInvalidFile, name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"email", "name"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: нет колонки %q", ErrUserImportInvalidFile, required)
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, importRow{line: parseErr.StartLine, errors: []string{"csv: " + parseErr.Err.Error()}})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := importRow{line: line, attrs: map[string]string{}}
+		for name, i := range columns {
+			value := strings.TrimSpace(record[i])
+			switch {
+			case name == "email":
+				row.row.Email = value
+			case name == "name":
+				row.row.Name = value
+			case name == "role":
+				row.row.Role = value
+			case name == "password":
+				row.row.Password = record[i] // Пробелы - часть пароля
+			case name == "password_hash":
+				row.row.PasswordHash = value
+			case value != "":
+				row.attrs[strings.TrimPrefix(name, "attr.")] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// parseImportNDJSON - по JSON объекту на строку, пустые строки пропускаются
+func parseImportNDJSON(r io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), userImportMaxLine)
+
+	var rows []importRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		row := importRow{line: line}
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row.row); err != nil {
+			row.errors = []string{"json: " + err.Error()}
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("%w: строка %d длиннее %d байт", ErrUserImportInvalidFile, line+1, userImportMaxLine)
+		}
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ================================================================
+// СООБЩЕНИЯ ОБ ОШИБКАХ
+// ================================================================
+
+// importFieldNames - поле UserImportRow → колонка файла
+var importFieldNames = map[string]string{
+	"Email":        "email",
+	"Name":         "name",
+	"Role":         "role",
+	"Password":     "password",
+	"PasswordHash": "password_hash",
+}
+
+// importFieldErrors - ошибки тегов binding в виде "колонка: причина"
+func importFieldErrors(err error) []string {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return []string{err.Error()}
+	}
+
+	messages := make([]string, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		var reason string
+		switch fieldError.Tag() {
+		case "required":
+			reason = "обязательное поле"
+		case "email":
+			reason = "невалидный email"
+		case "min":
+			reason = fmt.Sprintf("не короче %s символов", fieldError.Param())
+		case "max":
+			reason = fmt.Sprintf("не длиннее %s символов", fieldError.Param())
+		case "oneof":
+			reason = "допустимые значения: " + strings.ReplaceAll(fieldError.Param(), " ", ", ")
+		default:
+			reason = "не проходит проверку " + fieldError.Tag()
+		}
+		messages = append(messages, importFieldNames[fieldError.Field()]+": "+reason)
+	}
+	return messages
+}
+
+// importAttributeErrors - нарушения схемы атрибутов в виде "attr.<key>: причина"
+func importAttributeErrors(err error) []string {
+	var validationErr *domain.AttributeValidationError
+	if !errors.As(err, &validationErr) {
+		return []string{"attributes: " + err.Error()}
+	}
+
+	messages := make([]string, 0, len(validationErr.Violations))
+	for _, violation := range validationErr.Violations {
+		messages = append(messages, "attr."+violation.Key+": "+violation.Message)
+	}
+	return messages
+}
@@ -1,8 +1,19 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
 	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/jsonpatch"
 	"advanced-user-api/internal/repository"
+
+	"github.com/gin-gonic/gin/binding"
 )
 
 // ================================================================
@@ -13,19 +24,137 @@ import (
 type UserService interface {
 	GetUser(id uint) (*domain.User, error)
 	GetAllUsers() ([]domain.User, error)
-	UpdateUser(id uint, req *domain.UpdateUserRequest) (*domain.User, error)
-	DeleteUser(id uint) error
+	GetUsersByGroup(groupID uint) ([]domain.User, error)
+
+	// GetUsersByAttributes - пользователи, у которых есть все атрибуты filter с этими значениями
+	GetUsersByAttributes(filter domain.UserAttributes) ([]domain.User, error)
+	UpdateUser(id uint, req *domain.UpdateUserRequest, actorID uint, client domain.ClientInfo) (*domain.User, error)
+
+	// PatchUser - применяет JSON Merge Patch или JSON Patch к документу пользователя
+	PatchUser(id uint, req *domain.PatchUserRequest, actorID uint, client domain.ClientInfo) (*domain.User, error)
+
+	// DeleteUser - soft delete; ifMatch - версии из If-Match (nil - без проверки)
+	DeleteUser(id uint, ifMatch domain.VersionCondition, actorID uint, client domain.ClientInfo) error
 	GetCurrentUser(id uint) (*domain.User, error)
+
+	// ListDeletedUsers - удалённые пользователи, которых ещё можно восстановить
+	ListDeletedUsers() ([]domain.DeletedUser, error)
+
+	// RestoreUser - отменяет удаление пользователя
+	RestoreUser(id uint, actorID uint, client domain.ClientInfo) (*domain.User, error)
+
+	// PurgeUser - окончательно стирает удалённого пользователя, не дожидаясь срока
+	PurgeUser(id uint, actorID uint, client domain.ClientInfo) error
+
+	// PurgeExpired - стирает пользователей, удалённых раньше now - срок хранения
+	PurgeExpired(now time.Time) (int64, error)
+
+	// RunPurge - вызывает PurgeExpired с интервалом до отмены ctx
+	RunPurge(ctx context.Context, interval time.Duration)
+
+	// ForTenant - сервис, работающий только с пользователями организации orgID
+	ForTenant(orgID uint) UserService
 }
 
+var (
+	// ErrDeletedUserNotFound - пользователь не удалён, уже стёрт или в другой организации
+	ErrDeletedUserNotFound = errors.New("удалённый пользователь не найден")
+
+	// ErrRestoreEmailTaken - после удаления email занял другой аккаунт
+	ErrRestoreEmailTaken = errors.New("email пользователя уже занят другим аккаунтом")
+
+	// ErrUserUpdateForbidden - чужой документ меняет только администратор
+	ErrUserUpdateForbidden = errors.New("недостаточно прав для изменения пользователя")
+
+	// ErrRoleChangeForbidden - роль меняет только администратор и не себе
+	ErrRoleChangeForbidden = errors.New("недостаточно прав для изменения роли")
+
+	// ErrEmailChangeImpersonated - email не меняется при входе от имени пользователя
+	ErrEmailChangeImpersonated = errors.New("email нельзя изменить при входе от имени пользователя")
+
+	// ErrUnsupportedPatchFormat - Content-Type PATCH не merge-patch+json и не json-patch+json
+	ErrUnsupportedPatchFormat = errors.New("поддерживаются application/merge-patch+json и application/json-patch+json")
+
+	// ErrInvalidPatch - тело PATCH не разбирается или путь операции не существует
+	ErrInvalidPatch = errors.New("некорректный патч")
+
+	// ErrPatchTestFailed - не выполнена операция test (документ изменился)
+	ErrPatchTestFailed = errors.New("проверка test не пройдена")
+
+	// ErrInvalidUserDocument - документ после патча не проходит проверку
+	ErrInvalidUserDocument = errors.New("документ пользователя после патча невалиден")
+
+	// ErrUserVersionMismatch - версия пользователя не совпадает с If-Match
+	// или пользователя изменили параллельно (412 Precondition Failed)
+	ErrUserVersionMismatch = errors.New("пользователь изменён: версия не совпадает с If-Match")
+)
+
 // userService - реализация сервиса
 type userService struct {
-	userRepo repository.UserRepository // Зависимость от Repository
+	userRepo   repository.UserRepository // Зависимость от Repository
+	audit      AuditService              // Журнал изменений (nil - не пишем)
+	purgeAfter time.Duration             // Срок хранения удалённых аккаунтов (0 - бессрочно)
+	avatars    AvatarRemover             // Файлы аватаров стираемых аккаунтов (nil - не удаляем)
+	archives   ArchiveRemover            // Архивы выгрузок стираемых аккаунтов (nil - не удаляем)
+	attributes AttributeService          // Схема атрибутов (nil - атрибуты не меняются)
+}
+
+// UserOption - необязательная настройка User Service
+type UserOption func(*userService)
+
+// WithUserAuditService - подключает журнал изменений пользователей
+func WithUserAuditService(audit AuditService) UserOption {
+	return func(s *userService) {
+		s.audit = audit
+	}
+}
+
+// WithDeletedUserPurge - удалённые аккаунты стираются окончательно через after
+// (0 - хранятся, пока администратор не сотрёт их вручную)
+func WithDeletedUserPurge(after time.Duration) UserOption {
+	return func(s *userService) {
+		s.purgeAfter = after
+	}
+}
+
+// WithAvatarRemover - при окончательном удалении аккаунта удаляются и файлы аватара
+func WithAvatarRemover(avatars AvatarRemover) UserOption {
+	return func(s *userService) {
+		s.avatars = avatars
+	}
+}
+
+// WithArchiveRemover - при окончательном удалении аккаунта удаляются и архивы его выгрузок
+func WithArchiveRemover(archives ArchiveRemover) UserOption {
+	return func(s *userService) {
+		s.archives = archives
+	}
+}
+
+// WithUserAttributeService - изменения атрибутов проверяются схемой организации
+func WithUserAttributeService(attributes AttributeService) UserOption {
+	return func(s *userService) {
+		s.attributes = attributes
+	}
 }
 
 // NewUserService - конструктор
-func NewUserService(userRepo repository.UserRepository) UserService {
-	return &userService{userRepo: userRepo}
+func NewUserService(userRepo repository.UserRepository, opts ...UserOption) UserService {
+	s := &userService{userRepo: userRepo}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ForTenant - копия сервиса с репозиторием организации orgID
+func (s *userService) ForTenant(orgID uint) UserService {
+	scoped := *s
+	scoped.userRepo = s.userRepo.ForTenant(orgID)
+	if s.attributes != nil {
+		scoped.attributes = s.attributes.ForTenant(orgID)
+	}
+	return &scoped
 }
 
 // ================================================================
@@ -45,54 +174,217 @@ func (s *userService) GetAllUsers() ([]domain.User, error) {
 	return s.userRepo.FindAll()
 }
 
-// UpdateUser - обновляет данные пользователя
+// GetUsersByGroup - участники группы (включая вложенные группы)
+func (s *userService) GetUsersByGroup(groupID uint) ([]domain.User, error) {
+	return s.userRepo.FindByGroup(groupID)
+}
+
+// GetUsersByAttributes - поиск по атрибутам (фильтр уже приведён к типам схемы)
+func (s *userService) GetUsersByAttributes(filter domain.UserAttributes) ([]domain.User, error) {
+	return s.userRepo.FindByAttributes(filter)
+}
+
+// UpdateUser - заменяет документ пользователя (PUT)
 // Параметры:
 //   - id: ID пользователя для обновления
-//   - req: новые данные (email, name)
+//   - req: новый документ (email, name, роль, атрибуты) - все поля
+//   - actorID, client: кто изменяет (для журнала)
 // Возвращает:
 //   - *domain.User: обновлённый пользователь
 //   - error: ошибка обновления
-func (s *userService) UpdateUser(id uint, req *domain.UpdateUserRequest) (*domain.User, error) {
+func (s *userService) UpdateUser(id uint, req *domain.UpdateUserRequest, actorID uint, client domain.ClientInfo) (*domain.User, error) {
 	// === ШАГ 1: ПРОВЕРКА СУЩЕСТВОВАНИЯ ===
 	// Находим пользователя по ID
 	user, err := s.userRepo.FindByID(id)
 	if err != nil {
 		return nil, err // Пользователь не найден
 	}
+	if !req.IfMatch.Allows(user.Version) {
+		return nil, ErrUserVersionMismatch
+	}
+
+	// === ШАГ 2: ЗАМЕНА ДОКУМЕНТА ===
+	return s.replaceUser(user, req, actorID, client)
+}
+
+// PatchUser - частичное изменение пользователя (PATCH)
+// Патч применяется к документу, который видит автор запроса (поля
+// UpdateUserRequest, атрибуты - только видимые), результат проверяется
+// правилами тела PUT и сохраняется как полная замена
+func (s *userService) PatchUser(id uint, req *domain.PatchUserRequest, actorID uint, client domain.ClientInfo) (*domain.User, error) {
+	// === ШАГ 1: ТЕКУЩИЙ ДОКУМЕНТ ===
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !req.IfMatch.Allows(user.Version) {
+		return nil, ErrUserVersionMismatch
+	}
+	// Чужой документ не показывается даже через ошибки операций test
+	if actorID != user.ID && !req.AsAdmin {
+		return nil, ErrUserUpdateForbidden
+	}
+	access := domain.AttributeAccess{Self: actorID == user.ID, Admin: req.AsAdmin}
+	current := domain.UpdateUserRequest{Name: user.Name, Email: user.Email, Role: user.Role, Attributes: domain.UserAttributes{}}
+	if s.attributes != nil {
+		if current.Attributes, err = s.attributes.Visible(user.Attributes, access); err != nil {
+			return nil, err
+		}
+	}
+	document, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	// === ШАГ 2: ПРИМЕНЕНИЕ ПАТЧА ===
+	var patched []byte
+	switch req.Format {
+	case domain.PatchFormatMerge:
+		patched, err = jsonpatch.MergePatch(document, req.Patch)
+	case domain.PatchFormatJSON:
+		patched, err = jsonpatch.Apply(document, req.Patch)
+	default:
+		return nil, ErrUnsupportedPatchFormat
+	}
+	switch {
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		return nil, fmt.Errorf("%w: %v", ErrPatchTestFailed, err)
+	case err != nil:
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	// === ШАГ 3: ПРОВЕРКА РЕЗУЛЬТАТА ===
+	// Неизвестные поля (id, created_at...) не изменяются - это ошибка патча,
+	// а не молчаливое игнорирование. Правила полей - теги binding тела PUT
+	var replacement domain.UpdateUserRequest
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&replacement); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUserDocument, err)
+	}
+	if err := binding.Validator.ValidateStruct(&replacement); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUserDocument, err)
+	}
+	replacement.AsAdmin = req.AsAdmin
+	replacement.Impersonated = req.Impersonated
 
-	// === ШАГ 2: ОБНОВЛЕНИЕ ПОЛЕЙ ===
-	// Обновляем только те поля, которые переданы
-	
-	// Если передан новый email - обновляем
-	if req.Email != "" {
-		user.Email = req.Email
+	// === ШАГ 4: СОХРАНЕНИЕ ===
+	return s.replaceUser(user, &replacement, actorID, client)
+}
+
+// replaceUser - общая часть PUT и PATCH: права на поля, атрибуты, сохранение, журнал
+func (s *userService) replaceUser(user *domain.User, req *domain.UpdateUserRequest, actorID uint, client domain.ClientInfo) (*domain.User, error) {
+	before := *user
+
+	// === ШАГ 1: ПРАВА НА ПОЛЯ ===
+	// Чужого пользователя меняет только администратор: без POLICY_FILES
+	// правила доступа (users:update) пропускают всех, а смена чужого email
+	// и вход по magic link - это захват аккаунта
+	if actorID != user.ID && !req.AsAdmin {
+		return nil, ErrUserUpdateForbidden
+	}
+	// Роль - только администратор и не свою: иначе можно выдать себе admin
+	// или случайно лишиться прав. То же значение роли передавать можно всем
+	if req.Role != user.Role && (!req.AsAdmin || actorID == user.ID) {
+		return nil, ErrRoleChangeForbidden
+	}
+	// Email - это вход и восстановление пароля: от имени пользователя его
+	// не меняют, как пароль и сеансы (BlockImpersonation в routes)
+	if req.Impersonated && req.Email != user.Email {
+		return nil, ErrEmailChangeImpersonated
 	}
-	
-	// Если передано новое имя - обновляем
-	if req.Name != "" {
-		user.Name = req.Name
+	// Занятый email - та же ошибка, что при регистрации (409), а не ошибка БД
+	if req.Email != user.Email {
+		if existing, _ := s.userRepo.FindByEmail(req.Email); existing != nil && existing.ID != user.ID {
+			return nil, ErrEmailAlreadyRegistered
+		}
+	}
+
+	// === ШАГ 2: ЗАМЕНА ПОЛЕЙ ===
+	// Пустая строка - это значение, а не "не передано": полная замена
+	user.Email = req.Email
+	user.Name = req.Name
+	user.Role = req.Role
+
+	// Атрибуты проверяются схемой организации: тип, enum, regex, видимость
+	// Пользователь меняет свои private атрибуты, admin - только администратор
+	if s.attributes != nil {
+		access := domain.AttributeAccess{Self: actorID == user.ID, Admin: req.AsAdmin}
+		attributes, err := s.attributes.Replace(user.Attributes, req.Attributes, access)
+		if err != nil {
+			return nil, err
+		}
+		user.Attributes = attributes
+	} else if len(req.Attributes) > 0 {
+		return nil, ErrAttributesDisabled
 	}
 
 	// === ШАГ 3: СОХРАНЕНИЕ В БД ===
-	// Save() обновит запись в БД
-	if err := s.userRepo.Update(user); err != nil {
+	// Update() сохранит запись, только если её не изменили после чтения:
+	// проверка If-Match выше и запись не разделены гонкой
+	if err := s.userRepo.Update(user); errors.Is(err, repository.ErrUserVersionConflict) {
+		return nil, ErrUserVersionMismatch
+	} else if errors.Is(err, repository.ErrUserEmailTaken) {
+		// Email заняли между проверкой выше и записью
+		return nil, ErrEmailAlreadyRegistered
+	} else if err != nil {
 		return nil, err
 	}
 
+	// === ШАГ 4: ЖУРНАЛ ===
+	// Пишем только реально изменённые поля
+	fields := map[string][2]string{
+		"email": {before.Email, user.Email},
+		"name":  {before.Name, user.Name},
+		"role":  {before.Role, user.Role},
+	}
+	for _, attributes := range []domain.UserAttributes{before.Attributes, user.Attributes} {
+		for key := range attributes {
+			fields["attributes."+key] = [2]string{attributeString(before.Attributes[key]), attributeString(user.Attributes[key])}
+		}
+	}
+	changes := diffFields(fields)
+	if s.audit != nil && len(changes) > 0 {
+		event := newAuditEvent(domain.AuditActionUserUpdated, actorID, user.ID, client)
+		event.Changes = changes
+		s.audit.Record(event)
+	}
+
 	// Возвращаем обновлённого пользователя
 	return user, nil
 }
 
 // DeleteUser - удаляет пользователя (soft delete)
-func (s *userService) DeleteUser(id uint) error {
+func (s *userService) DeleteUser(id uint, ifMatch domain.VersionCondition, actorID uint, client domain.ClientInfo) error {
 	// Проверяем существование пользователя
-	_, err := s.userRepo.FindByID(id)
+	user, err := s.userRepo.FindByID(id)
 	if err != nil {
 		return err
 	}
 
+	// If-Match: удаляется только версия, которую видел клиент
+	var version uint
+	if ifMatch != nil {
+		if !ifMatch.Allows(user.Version) {
+			return ErrUserVersionMismatch
+		}
+		version = user.Version
+	}
+
 	// Удаляем через repository
-	return s.userRepo.Delete(id)
+	if err := s.userRepo.Delete(id, version); errors.Is(err, repository.ErrUserVersionConflict) {
+		return ErrUserVersionMismatch
+	} else if err != nil {
+		return err
+	}
+
+	// В журнале остаётся, кто и какой аккаунт удалил
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionUserDeleted, actorID, user.ID, client)
+		event.Details = "email: " + user.Email
+		s.audit.Record(event)
+	}
+	return nil
 }
 
 // GetCurrentUser - получает данные текущего аутентифицированного пользователя
@@ -102,3 +394,142 @@ func (s *userService) GetCurrentUser(id uint) (*domain.User, error) {
 	return s.userRepo.FindByID(id)
 }
 
+
+// ================================================================
+// УДАЛЁННЫЕ ПОЛЬЗОВАТЕЛИ
+// ================================================================
+// DeleteUser только отмечает аккаунт удалённым: в течение срока хранения
+// его можно восстановить, затем фоновая задача стирает запись вместе
+// с сеансами, passkeys, членством и историей статусов
+
+// ListDeletedUsers - удалённые пользователи с датой окончательного удаления
+func (s *userService) ListDeletedUsers() ([]domain.DeletedUser, error) {
+	users, err := s.userRepo.FindDeleted()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.DeletedUser, 0, len(users))
+	for _, user := range users {
+		item := domain.DeletedUser{User: user, DeletedAt: user.DeletedAt.Time}
+		if s.purgeAfter > 0 {
+			purgeAt := user.DeletedAt.Time.Add(s.purgeAfter)
+			item.PurgeAt = &purgeAt
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// RestoreUser - восстановление удалённого пользователя
+// Пользователю нужно войти заново: токены, выданные до удаления, отозваны
+func (s *userService) RestoreUser(id uint, actorID uint, client domain.ClientInfo) (*domain.User, error) {
+	user, err := s.userRepo.Restore(id)
+	switch {
+	case errors.Is(err, repository.ErrDeletedUserNotFound):
+		return nil, ErrDeletedUserNotFound
+	case errors.Is(err, repository.ErrUserEmailTaken):
+		return nil, ErrRestoreEmailTaken
+	case err != nil:
+		return nil, err
+	}
+
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionUserRestored, actorID, user.ID, client)
+		event.Details = "email: " + user.Email
+		s.audit.Record(event)
+	}
+	return user, nil
+}
+
+// PurgeUser - окончательное удаление по запросу администратора
+func (s *userService) PurgeUser(id uint, actorID uint, client domain.ClientInfo) error {
+	user, exports, err := s.userRepo.Purge(id)
+	if errors.Is(err, repository.ErrDeletedUserNotFound) {
+		return ErrDeletedUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if s.avatars != nil {
+		s.avatars.RemoveAvatarFiles(user.Profile.AvatarID)
+	}
+	if s.archives != nil {
+		s.archives.RemoveArchiveFiles(exports)
+	}
+
+	// В журнале остаётся только ID и email стёртого аккаунта
+	if s.audit != nil {
+		event := newAuditEvent(domain.AuditActionUserPurged, actorID, user.ID, client)
+		event.Details = "email: " + user.Email
+		s.audit.Record(event)
+	}
+	return nil
+}
+
+// PurgeExpired - стирает аккаунты, срок хранения которых истёк
+func (s *userService) PurgeExpired(now time.Time) (int64, error) {
+	if s.purgeAfter <= 0 {
+		return 0, nil
+	}
+	cutoff := now.Add(-s.purgeAfter)
+
+	// Аватары запоминаем до удаления: после него ссылок на файлы не останется
+	var avatarIDs []string
+	if s.avatars != nil {
+		users, err := s.userRepo.FindDeleted()
+		if err != nil {
+			return 0, err
+		}
+		for _, user := range users {
+			if user.DeletedAt.Time.Before(cutoff) && user.Profile.AvatarID != "" {
+				avatarIDs = append(avatarIDs, user.Profile.AvatarID)
+			}
+		}
+	}
+
+	count, exports, err := s.userRepo.PurgeDeletedBefore(cutoff)
+	if err != nil {
+		return count, err
+	}
+	for _, avatarID := range avatarIDs {
+		s.avatars.RemoveAvatarFiles(avatarID)
+	}
+	if s.archives != nil {
+		s.archives.RemoveArchiveFiles(exports)
+	}
+	return count, nil
+}
+
+// RunPurge - фоновое удаление аккаунтов (сразу и далее раз в interval)
+func (s *userService) RunPurge(ctx context.Context, interval time.Duration) {
+	if s.purgeAfter <= 0 || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := s.PurgeExpired(time.Now())
+		if err != nil {
+			log.Printf("⚠️  Ошибка удаления аккаунтов с истёкшим сроком хранения: %v", err)
+		} else if count > 0 {
+			log.Printf("🗑️  Окончательно удалены аккаунты: %d", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// attributeString - значение атрибута для журнала (отсутствует - пустая строка)
+func attributeString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
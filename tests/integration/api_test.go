@@ -64,7 +64,7 @@ func TestFullAuthFlow(t *testing.T) {
 	// Создаём роутер
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	handler.SetupRoutes(router, authHandler, nil, cfg)
+	handler.SetupRoutes(router, handler.Handlers{Auth: authHandler}, nil, cfg)
 
 	// === TEST: РЕГИСТРАЦИЯ ===
 	registerReq := domain.RegisterRequest{
@@ -0,0 +1,153 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/handler"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ================================================================
+// TENANT ISOLATION - Пользователи организаций не видят друг друга
+// ================================================================
+
+// setupTenantDB - тестовая БД с организациями acme и globex
+func setupTenantDB(t *testing.T) (*gorm.DB, *domain.Organization, *domain.Organization) {
+	db := setupTestDB()
+	require.NoError(t, db.AutoMigrate(&domain.User{}, &domain.Organization{}, &domain.Membership{}))
+
+	// Email уникален в пределах организации - старый глобальный индекс мешает
+	if db.Migrator().HasIndex(&domain.User{}, "idx_users_email") {
+		require.NoError(t, db.Migrator().DropIndex(&domain.User{}, "idx_users_email"))
+	}
+
+	acme := &domain.Organization{Slug: "acme", Name: "Acme"}
+	globex := &domain.Organization{Slug: "globex", Name: "Globex"}
+	require.NoError(t, db.Create(acme).Error)
+	require.NoError(t, db.Create(globex).Error)
+	return db, acme, globex
+}
+
+// cleanupTenantDB - очищает таблицы организаций и пользователей
+func cleanupTenantDB(db *gorm.DB) {
+	db.Exec("DELETE FROM organization_members")
+	db.Exec("DELETE FROM organizations")
+	cleanupTestDB(db)
+}
+
+// TestTenantIsolation - один email в двух организациях, токены и списки не пересекаются
+func TestTenantIsolation(t *testing.T) {
+	// === SETUP ===
+	db, acme, globex := setupTenantDB(t)
+	defer cleanupTenantDB(db)
+
+	userRepo := repository.NewUserRepository(db)
+	cfg := &config.Config{
+		JWTSecret:     "test-secret",
+		JWTExpiration: "24h",
+		TenantHeader:  "X-Organization",
+		TenantDefault: acme.Slug,
+	}
+	authService := service.NewAuthService(userRepo, cfg)
+	userService := service.NewUserService(userRepo)
+	orgService := service.NewOrganizationService(repository.NewOrganizationRepository(db), nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler.SetupRoutes(router, handler.Handlers{
+		Auth:         handler.NewAuthHandler(authService, userService),
+		User:         handler.NewUserHandler(userService, nil),
+		Organization: handler.NewOrganizationHandler(orgService, nil),
+	}, nil, cfg)
+
+	do := func(method, path, org, token string, payload interface{}) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		if payload != nil {
+			require.NoError(t, json.NewEncoder(&body).Encode(payload))
+		}
+		req := httptest.NewRequest(method, path, &body)
+		req.Header.Set("Content-Type", "application/json")
+		if org != "" {
+			req.Header.Set("X-Organization", org)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	register := func(org, email, password string) domain.AuthResponse {
+		w := do("POST", "/api/v1/auth/register", org, "", domain.RegisterRequest{Email: email, Name: "Tenant User", Password: password})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var resp domain.AuthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// === TEST: ОДИН EMAIL В ДВУХ ОРГАНИЗАЦИЯХ ===
+	acmeUser := register(acme.Slug, "shared@test.com", "acme-password-1")
+	globexUser := register(globex.Slug, "shared@test.com", "globex-password-1")
+	register(acme.Slug, "colleague@test.com", "acme-password-2")
+
+	assert.NotEqual(t, acmeUser.User.ID, globexUser.User.ID)
+	assert.Equal(t, acme.ID, acmeUser.User.OrganizationID)
+	assert.Equal(t, globex.ID, globexUser.User.OrganizationID)
+
+	// Повторная регистрация в той же организации - email уже занят
+	w := do("POST", "/api/v1/auth/register", acme.Slug, "", domain.RegisterRequest{Email: "shared@test.com", Name: "Dup", Password: "acme-password-3"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// === TEST: ПАРОЛЬ ОДНОЙ ОРГАНИЗАЦИИ НЕ ПОДХОДИТ К ДРУГОЙ ===
+	w = do("POST", "/api/v1/auth/login", globex.Slug, "", domain.LoginRequest{Email: "shared@test.com", Password: "acme-password-1"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = do("POST", "/api/v1/auth/login", acme.Slug, "", domain.LoginRequest{Email: "shared@test.com", Password: "acme-password-1"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// === TEST: СПИСОК ПОЛЬЗОВАТЕЛЕЙ ТОЛЬКО СВОЕЙ ОРГАНИЗАЦИИ ===
+	// Организация берётся из токена, заголовок не обязателен
+	w = do("GET", "/api/v1/users", "", acmeUser.Token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var users []domain.User
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &users))
+	assert.Len(t, users, 2)
+	for _, u := range users {
+		assert.Equal(t, acme.ID, u.OrganizationID)
+	}
+
+	// Пользователь другой организации "не существует"
+	w = do("GET", fmt.Sprintf("/api/v1/users/%d", globexUser.User.ID), "", acmeUser.Token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = do("DELETE", fmt.Sprintf("/api/v1/users/%d", globexUser.User.ID), "", acmeUser.Token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// === TEST: ТОКЕН НЕ ДЕЙСТВУЕТ В ЧУЖОЙ ОРГАНИЗАЦИИ ===
+	w = do("GET", "/api/v1/users", globex.Slug, acmeUser.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// === TEST: УЧАСТНИКИ ОРГАНИЗАЦИИ ===
+	w = do("GET", "/api/v1/organizations/current/members", "", globexUser.Token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var members []domain.Membership
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &members))
+	require.Len(t, members, 1)
+	assert.Equal(t, globexUser.User.ID, members[0].UserID)
+}
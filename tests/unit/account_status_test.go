@@ -0,0 +1,265 @@
+package unit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/pkg/jwt"
+	"advanced-user-api/internal/pkg/password"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// MOCK USER STATUS REPOSITORY
+// ================================================================
+
+// MockUserStatusRepository - мок статусов и истории
+type MockUserStatusRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserStatusRepository) Change(user *domain.User, change *domain.UserStatusChange, revokeTokens bool) error {
+	args := m.Called(user, change, revokeTokens)
+	return args.Error(0)
+}
+
+func (m *MockUserStatusRepository) History(userID uint) ([]domain.UserStatusChange, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]domain.UserStatusChange), args.Error(1)
+}
+
+func (m *MockUserStatusRepository) FindExpiredSuspensions(now time.Time) ([]domain.User, error) {
+	args := m.Called(now)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+// ================================================================
+// ТЕСТЫ СТАТУСОВ АККАУНТА
+// ================================================================
+
+// TestUserStatus_TransitionsAndExpiry - допустимые переходы и истёкшая блокировка
+func TestUserStatus_TransitionsAndExpiry(t *testing.T) {
+	// Переходы
+	assert.True(t, domain.CanTransitionUserStatus(domain.UserStatusPending, domain.UserStatusActive))
+	assert.True(t, domain.CanTransitionUserStatus(domain.UserStatusActive, domain.UserStatusSuspended))
+	assert.True(t, domain.CanTransitionUserStatus(domain.UserStatusSuspended, domain.UserStatusSuspended), "новый срок")
+	assert.True(t, domain.CanTransitionUserStatus(domain.UserStatusBanned, domain.UserStatusActive))
+	assert.False(t, domain.CanTransitionUserStatus(domain.UserStatusPending, domain.UserStatusSuspended))
+	assert.False(t, domain.CanTransitionUserStatus(domain.UserStatusBanned, domain.UserStatusSuspended))
+	assert.False(t, domain.CanTransitionUserStatus(domain.UserStatusActive, domain.UserStatusActive))
+
+	// Статус с учётом срока блокировки
+	now := time.Now()
+	until := now.Add(time.Hour)
+	user := &domain.User{Status: domain.UserStatusSuspended, SuspendedUntil: &until}
+
+	err := user.CheckStatus(now)
+	var statusErr *domain.AccountStatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, "account_suspended", statusErr.Code())
+	assert.Equal(t, &until, statusErr.Until)
+
+	assert.NoError(t, user.CheckStatus(until), "срок истёк - аккаунт активен")
+	assert.NoError(t, (&domain.User{}).CheckStatus(now), "записи без статуса - active")
+	assert.Error(t, (&domain.User{Status: domain.UserStatusBanned}).CheckStatus(now))
+}
+
+// TestAccountStatusService_Suspend - блокировка отзывает токены и пишет историю и журнал
+func TestAccountStatusService_Suspend(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	mockStatuses := new(MockUserStatusRepository)
+	mockSessions := new(MockSessionRepository)
+	mockAudit := new(MockAuditService)
+	statusService := service.NewAccountStatusService(mockRepo, mockStatuses, mockSessions, mockAudit)
+
+	user := &domain.User{ID: 9, Email: "bob@example.com", Role: "user", Status: domain.UserStatusActive, TokenVersion: 3}
+	until := time.Now().Add(24 * time.Hour)
+
+	mockRepo.On("FindByID", uint(9)).Return(user, nil)
+	mockStatuses.On("Change", user, mock.MatchedBy(func(change *domain.UserStatusChange) bool {
+		return change.UserID == 9 && change.FromStatus == domain.UserStatusActive &&
+			change.ToStatus == domain.UserStatusSuspended && change.Reason == "подозрительная активность" &&
+			change.ActorID != nil && *change.ActorID == 1 && change.Until == &until
+	}), true).Return(nil)
+	mockSessions.On("RevokeAllExcept", uint(9), "", mock.AnythingOfType("time.Time")).Return(int64(2), nil)
+	mockAudit.On("Record", mock.MatchedBy(func(event *domain.AuditEvent) bool {
+		return event.Action == domain.AuditActionUserStatusChanged &&
+			event.Changes["status"] == domain.AuditChange{Before: "active", After: "suspended"}
+	})).Return()
+
+	// Act
+	updated, err := statusService.ForTenant(5).Suspend(9, &domain.SuspendUserRequest{
+		Reason: "  подозрительная активность ",
+		Until:  &until,
+	}, 1, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserStatusSuspended, updated.Status)
+	assert.Equal(t, 4, updated.TokenVersion, "все токены отозваны")
+	assert.Equal(t, uint(5), mockRepo.TenantID)
+	mockStatuses.AssertExpectations(t)
+	mockSessions.AssertExpectations(t)
+	mockAudit.AssertExpectations(t)
+
+	// Недопустимые изменения не сохраняются
+	_, err = statusService.Suspend(1, &domain.SuspendUserRequest{Reason: "сам себя"}, 1, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrStatusSelfChange)
+
+	_, err = statusService.Ban(9, &domain.ChangeUserStatusRequest{}, 1, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrStatusReasonRequired)
+
+	past := time.Now().Add(-time.Minute)
+	_, err = statusService.Suspend(9, &domain.SuspendUserRequest{Reason: "спам", Until: &past}, 1, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrSuspendUntilInPast)
+
+	mockRepo.On("FindByID", uint(10)).Return(&domain.User{ID: 10, Status: domain.UserStatusPending}, nil)
+	_, err = statusService.Suspend(10, &domain.SuspendUserRequest{Reason: "спам"}, 1, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrInvalidStatusTransition)
+
+	mockStatuses.AssertNumberOfCalls(t, "Change", 1)
+}
+
+// TestAccountStatusService_ExpireSuspensions - истёкшие блокировки снимаются системой
+func TestAccountStatusService_ExpireSuspensions(t *testing.T) {
+	// Arrange
+	mockStatuses := new(MockUserStatusRepository)
+	statusService := service.NewAccountStatusService(new(MockUserRepository), mockStatuses, nil, nil)
+
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+	mockStatuses.On("FindExpiredSuspensions", now).Return([]domain.User{
+		{ID: 3, Status: domain.UserStatusSuspended, SuspendedUntil: &expired},
+	}, nil)
+	mockStatuses.On("Change", mock.MatchedBy(func(user *domain.User) bool {
+		return user.ID == 3 && user.Status == domain.UserStatusActive && user.SuspendedUntil == nil
+	}), mock.MatchedBy(func(change *domain.UserStatusChange) bool {
+		return change.ActorID == nil && change.FromStatus == domain.UserStatusSuspended && change.ToStatus == domain.UserStatusActive
+	}), false).Return(nil)
+
+	// Act
+	count, err := statusService.ExpireSuspensions(now)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	mockStatuses.AssertExpectations(t)
+}
+
+// TestLogin_RejectsInactiveAccount - верный пароль, но аккаунт заблокирован
+func TestLogin_RejectsInactiveAccount(t *testing.T) {
+	// Arrange
+	hasher := password.NewHasher(fastArgon2())
+	hash, err := hasher.Hash("old-Passw0rd!")
+	require.NoError(t, err)
+	mockRepo := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	authService := service.NewAuthService(mockRepo, &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"},
+		service.WithPasswordHasher(hasher),
+		service.WithAuditService(mockAudit),
+	)
+	user := &domain.User{ID: 7, Email: "alice@example.com", Password: hash, Role: "user",
+		AuthProvider: domain.AuthProviderLocal, Status: domain.UserStatusBanned}
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+	mockAudit.On("Record", mock.MatchedBy(func(event *domain.AuditEvent) bool {
+		return event.Action == domain.AuditActionLoginBlocked && *event.TargetID == user.ID
+	})).Return()
+
+	// Act
+	response, err := authService.Login(&domain.LoginRequest{Email: user.Email, Password: "old-Passw0rd!"}, domain.ClientInfo{})
+
+	// Assert
+	assert.Nil(t, response)
+	var statusErr *domain.AccountStatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, "account_banned", statusErr.Code())
+	mockAudit.AssertExpectations(t)
+
+	// Неверный пароль - прежняя ошибка, статус не раскрывается
+	mockAudit.On("Record", mock.Anything).Return()
+	_, err = authService.Login(&domain.LoginRequest{Email: user.Email, Password: "wrong-Passw0rd!"}, domain.ClientInfo{})
+	assert.False(t, errors.As(err, &statusErr))
+}
+
+// TestRegister_RequiresApproval - при REGISTRATION_APPROVAL аккаунт ждёт активации
+func TestRegister_RequiresApproval(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	cfg := &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h", RegistrationApproval: true}
+	authService := service.NewAuthService(mockRepo, cfg)
+
+	var created []*domain.User
+	mockRepo.On("FindByEmail", mock.Anything).Return(nil, nil)
+	mockRepo.On("Create", mock.AnythingOfType("*domain.User")).Run(func(args mock.Arguments) {
+		created = append(created, args.Get(0).(*domain.User))
+	}).Return(nil)
+
+	// Act
+	response, err := authService.Register(&domain.RegisterRequest{
+		Email: "new@example.com", Name: "New User", Password: "correct-Horse-battery-9",
+	}, domain.ClientInfo{})
+
+	// Assert
+	assert.Nil(t, response)
+	var statusErr *domain.AccountStatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, domain.UserStatusPending, statusErr.Status)
+	require.Len(t, created, 1)
+	assert.Equal(t, domain.UserStatusPending, created[0].Status)
+
+	// Регистрация по приглашению - аккаунт активен сразу
+	response, err = authService.Register(&domain.RegisterRequest{
+		Email: "invited@example.com", Name: "Invited", Password: "correct-Horse-battery-9", Approved: true,
+	}, domain.ClientInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	assert.Equal(t, domain.UserStatusActive, created[1].Status)
+}
+
+// TestAuthMiddleware_RejectsSuspendedAccount - 403 с кодом статуса вместо 401
+func TestAuthMiddleware_RejectsSuspendedAccount(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	mockRepo := new(MockUserRepository)
+	authService := service.NewAuthService(mockRepo, &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"})
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	user := &domain.User{ID: 7, Email: "alice@example.com", Role: "user", TokenVersion: 2,
+		Status: domain.UserStatusSuspended, SuspendedUntil: &until}
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+
+	router := gin.New()
+	router.GET("/me", middleware.AuthMiddleware(&config.Config{JWTSecret: "test-secret"}, authService),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	token, err := jwt.GenerateTokenFromClaims(jwt.Claims{UserID: user.ID, Role: user.Role, TokenVersion: user.TokenVersion}, "test-secret", time.Hour)
+	require.NoError(t, err)
+
+	// Act
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	// Assert
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	var body struct {
+		Code  string    `json:"code"`
+		Until time.Time `json:"until"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "account_suspended", body.Code)
+	assert.True(t, until.Equal(body.Until))
+}
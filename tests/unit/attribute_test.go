@@ -0,0 +1,328 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/handler"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// MOCK ATTRIBUTE REPOSITORY
+// ================================================================
+
+// MockAttributeRepository - мок репозитория схем атрибутов
+type MockAttributeRepository struct {
+	mock.Mock
+	TenantID uint // организация последнего ForTenant
+}
+
+func (m *MockAttributeRepository) Create(definition *domain.AttributeDefinition) error {
+	return m.Called(definition).Error(0)
+}
+
+func (m *MockAttributeRepository) FindAll() ([]domain.AttributeDefinition, error) {
+	args := m.Called()
+	return args.Get(0).([]domain.AttributeDefinition), args.Error(1)
+}
+
+func (m *MockAttributeRepository) FindByKey(key string) (*domain.AttributeDefinition, error) {
+	args := m.Called(key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AttributeDefinition), args.Error(1)
+}
+
+func (m *MockAttributeRepository) Update(definition *domain.AttributeDefinition) error {
+	return m.Called(definition).Error(0)
+}
+
+func (m *MockAttributeRepository) Delete(key string) error {
+	return m.Called(key).Error(0)
+}
+
+// ForTenant - тот же мок, запоминает организацию
+func (m *MockAttributeRepository) ForTenant(orgID uint) repository.AttributeRepository {
+	m.TenantID = orgID
+	return m
+}
+
+// ================================================================
+// ВСПОМОГАТЕЛЬНЫЕ ФУНКЦИИ
+// ================================================================
+
+// attributeSchema - схема: отдел (public, обязательный, enum), код затрат (private, regex),
+// уровень (public, число) и VIP (только администраторы)
+func attributeSchema() []domain.AttributeDefinition {
+	return []domain.AttributeDefinition{
+		{Key: "department", Type: domain.AttributeTypeString, Required: true, Enum: []string{"sales", "support"}, Visibility: domain.AttributeVisibilityPublic},
+		{Key: "cost_center", Type: domain.AttributeTypeString, Pattern: `^CC-[0-9]{4}$`, Visibility: domain.AttributeVisibilityPrivate},
+		{Key: "level", Type: domain.AttributeTypeNumber, Visibility: domain.AttributeVisibilityPublic},
+		{Key: "vip", Type: domain.AttributeTypeBoolean, Required: true, Visibility: domain.AttributeVisibilityAdmin},
+	}
+}
+
+// attributeViolationCodes - ключ → код нарушения
+func attributeViolationCodes(t *testing.T, err error) map[string]string {
+	var validationErr *domain.AttributeValidationError
+	require.ErrorAs(t, err, &validationErr)
+	codes := map[string]string{}
+	for _, v := range validationErr.Violations {
+		codes[v.Key] = v.Code
+	}
+	return codes
+}
+
+// ================================================================
+// ТЕСТЫ ATTRIBUTE SERVICE
+// ================================================================
+
+// TestAttributes_ApplyValidatesSchema - тип, enum, regex, видимость и обязательность
+func TestAttributes_ApplyValidatesSchema(t *testing.T) {
+	mockRepo := new(MockAttributeRepository)
+	mockRepo.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockRepo, nil)
+	self := domain.AttributeAccess{Self: true}
+
+	// Act: все нарушения сразу
+	_, err := attributes.Apply(nil, domain.UserAttributes{
+		"department":  "marketing",
+		"cost_center": "42",
+		"level":       "high",
+		"vip":         true,
+		"nickname":    "bob",
+	}, self)
+
+	// Assert
+	assert.Equal(t, map[string]string{
+		"department":  domain.AttributeViolationEnum,
+		"cost_center": domain.AttributeViolationPattern,
+		"level":       domain.AttributeViolationType,
+		"vip":         domain.AttributeViolationForbidden,
+		"nickname":    domain.AttributeViolationUnknown,
+	}, attributeViolationCodes(t, err))
+
+	// Регистрация без обязательного атрибута; обязательный vip задаёт администратор
+	_, err = attributes.Apply(nil, domain.UserAttributes{}, self)
+	assert.Equal(t, map[string]string{"department": domain.AttributeViolationRequired}, attributeViolationCodes(t, err))
+
+	// Изменение: null удаляет атрибут, исходный набор не меняется
+	current := domain.UserAttributes{"department": "sales", "cost_center": "CC-0001", "vip": true}
+	result, err := attributes.Apply(current, domain.UserAttributes{"cost_center": nil, "level": float64(3)}, self)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserAttributes{"department": "sales", "level": float64(3), "vip": true}, result)
+	assert.Equal(t, "CC-0001", current["cost_center"])
+
+	// Чужие private атрибуты меняет только администратор
+	_, err = attributes.Apply(current, domain.UserAttributes{"cost_center": "CC-0002"}, domain.AttributeAccess{})
+	assert.Equal(t, map[string]string{"cost_center": domain.AttributeViolationForbidden}, attributeViolationCodes(t, err))
+
+	// Администратору нужен и обязательный vip
+	_, err = attributes.Apply(nil, domain.UserAttributes{"department": "support"}, domain.AttributeAccess{Admin: true})
+	assert.Equal(t, map[string]string{"vip": domain.AttributeViolationRequired}, attributeViolationCodes(t, err))
+}
+
+// TestAttributes_PresentEnforcesVisibility - ответ содержит только видимые атрибуты
+func TestAttributes_PresentEnforcesVisibility(t *testing.T) {
+	mockRepo := new(MockAttributeRepository)
+	mockRepo.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockRepo, nil)
+	users := []domain.User{{ID: 7, Attributes: domain.UserAttributes{
+		"department":  "sales",
+		"cost_center": "CC-0001",
+		"vip":         true,
+		"legacy":      "removed from schema",
+	}}}
+
+	visible := func(viewerID uint, admin bool) domain.UserAttributes {
+		response, err := attributes.Present(users, viewerID, admin)
+		require.NoError(t, err)
+		require.Len(t, response, 1)
+		return response[0].Attributes
+	}
+
+	assert.Equal(t, domain.UserAttributes{"department": "sales"}, visible(8, false), "другой пользователь - только public")
+	assert.Equal(t, domain.UserAttributes{"department": "sales", "cost_center": "CC-0001"}, visible(7, false), "сам пользователь - и private")
+	assert.Len(t, visible(8, true), 4, "администратор видит всё, включая атрибуты без схемы")
+}
+
+// TestAttributes_ParseFilter - значения query приводятся к типам схемы
+func TestAttributes_ParseFilter(t *testing.T) {
+	mockRepo := new(MockAttributeRepository)
+	mockRepo.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockRepo, nil)
+
+	filter, err := attributes.ParseFilter(map[string]string{"department": "sales", "level": "3"}, domain.AttributeAccess{})
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserAttributes{"department": "sales", "level": float64(3)}, filter)
+
+	// Фильтр по private выдал бы чужие значения - только администратору
+	_, err = attributes.ParseFilter(map[string]string{"cost_center": "CC-0001", "level": "x"}, domain.AttributeAccess{})
+	assert.Equal(t, map[string]string{
+		"cost_center": domain.AttributeViolationForbidden,
+		"level":       domain.AttributeViolationType,
+	}, attributeViolationCodes(t, err))
+
+	filter, err = attributes.ParseFilter(map[string]string{"vip": "true"}, domain.AttributeAccess{Admin: true})
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserAttributes{"vip": true}, filter)
+}
+
+// TestAttributes_DefinitionChecks - ключ, enum/pattern только у строк, уникальность
+func TestAttributes_DefinitionChecks(t *testing.T) {
+	mockRepo := new(MockAttributeRepository)
+	mockRepo.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockRepo, nil)
+	mockRepo.On("FindByKey", "department").Return(&attributeSchema()[0], nil)
+	mockRepo.On("FindByKey", "region").Return(nil, repository.ErrAttributeDefinitionNotFound)
+	mockRepo.On("Create", mock.Anything).Return(nil)
+
+	cases := map[string]struct {
+		req domain.AttributeDefinitionRequest
+		err error
+	}{
+		"key":       {domain.AttributeDefinitionRequest{Key: "Region!", Type: "string", Visibility: "public"}, service.ErrInvalidAttributeKey},
+		"enum":      {domain.AttributeDefinitionRequest{Key: "region", Type: "number", Enum: []string{"1"}, Visibility: "public"}, service.ErrInvalidAttributeDefinition},
+		"pattern":   {domain.AttributeDefinitionRequest{Key: "region", Type: "string", Pattern: "([", Visibility: "public"}, service.ErrInvalidAttributePattern},
+		"duplicate": {domain.AttributeDefinitionRequest{Key: "department", Type: "string", Visibility: "public"}, service.ErrAttributeKeyTaken},
+	}
+	for name, tc := range cases {
+		_, err := attributes.CreateDefinition(&tc.req)
+		assert.ErrorIs(t, err, tc.err, name)
+	}
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+
+	definition, err := attributes.CreateDefinition(&domain.AttributeDefinitionRequest{
+		Key: "region", Type: "string", Enum: []string{"eu", "us"}, Visibility: "private",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eu", "us"}, definition.Enum)
+	assert.Equal(t, domain.AttributeVisibilityPrivate, definition.Visibility)
+}
+
+// ================================================================
+// ТЕСТЫ USER HANDLER
+// ================================================================
+
+// TestUserHandler_AttributeFilterAndVisibility - фильтр ?attr.*, видимость и ошибки схемы
+func TestUserHandler_AttributeFilterAndVisibility(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+	mockRepo := new(MockUserRepository)
+	userService := service.NewUserService(mockRepo, service.WithUserAttributeService(attributes))
+	userHandler := handler.NewUserHandler(userService, attributes)
+
+	alice := domain.User{ID: 7, Name: "Alice", Email: "alice@example.com", Role: "user", Attributes: domain.UserAttributes{"department": "sales", "cost_center": "CC-0001", "vip": true}}
+	mockRepo.On("FindByAttributes", domain.UserAttributes{"department": "sales", "level": float64(3)}).
+		Return([]domain.User{alice}, nil)
+	mockRepo.On("FindByID", uint(7)).Return(&alice, nil)
+
+	request := func(method, path string, viewerID uint, role string, body interface{}) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", viewerID)
+			c.Set("userRole", role)
+		})
+		router.GET("/users", userHandler.GetAll)
+		router.PUT("/users/:id", userHandler.Update)
+
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req := httptest.NewRequest(method, path, &payload)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act & Assert: фильтр с приведением типов, в ответе только public
+	rec := request(http.MethodGet, "/users?attr.department=sales&attr.level=3", 8, "user", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var users []map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &users))
+	require.Len(t, users, 1)
+	assert.Equal(t, map[string]interface{}{"department": "sales"}, users[0]["attributes"])
+
+	// Фильтр по private и совмещение с group - 400
+	rec = request(http.MethodGet, "/users?attr.cost_center=CC-0001", 8, "user", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"forbidden"`)
+	rec = request(http.MethodGet, "/users?group=1&attr.department=sales", 8, "admin", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Пользователь не может выставить себе атрибут администратора
+	rec = request(http.MethodPut, "/users/7", 7, "user", map[string]interface{}{
+		"name": "Alice", "email": "alice@example.com", "role": "user",
+		"attributes": map[string]interface{}{"vip": false, "department": "hr"},
+	})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var body struct {
+		Violations []domain.AttributeViolation `json:"violations"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Len(t, body.Violations, 2)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+// TestUserService_UpdateRecordsAttributeChanges - изменения атрибутов попадают в журнал
+func TestUserService_UpdateRecordsAttributeChanges(t *testing.T) {
+	// Arrange
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+	mockRepo := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	user := &domain.User{ID: 7, Attributes: domain.UserAttributes{"department": "sales", "vip": true}}
+	mockRepo.On("FindByID", uint(7)).Return(user, nil)
+	mockRepo.On("Update", user).Return(nil)
+
+	var event *domain.AuditEvent
+	mockAudit.On("Record", mock.Anything).Run(func(args mock.Arguments) {
+		event = args.Get(0).(*domain.AuditEvent)
+	}).Return()
+	userService := service.NewUserService(mockRepo,
+		service.WithUserAuditService(mockAudit),
+		service.WithUserAttributeService(attributes),
+	)
+
+	// Act: администратор меняет атрибуты другого пользователя
+	updated, err := userService.UpdateUser(7, &domain.UpdateUserRequest{
+		Attributes: domain.UserAttributes{"department": "support", "vip": nil, "level": float64(2)},
+		AsAdmin:    true,
+	}, 1, domain.ClientInfo{})
+
+	// Assert
+	var validationErr *domain.AttributeValidationError
+	require.ErrorAs(t, err, &validationErr, "vip обязателен - удалить нельзя")
+	assert.Nil(t, updated)
+
+	updated, err = userService.UpdateUser(7, &domain.UpdateUserRequest{
+		Attributes: domain.UserAttributes{"department": "support", "level": float64(2), "vip": true},
+		AsAdmin:    true,
+	}, 1, domain.ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserAttributes{"department": "support", "level": float64(2), "vip": true}, updated.Attributes)
+
+	require.NotNil(t, event)
+	assert.Equal(t, domain.AuditChanges{
+		"attributes.department": {Before: "sales", After: "support"},
+		"attributes.level":      {Before: "", After: "2"},
+	}, event.Changes)
+}
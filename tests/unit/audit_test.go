@@ -0,0 +1,275 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/handler"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/pkg/password"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// MOCK AUDIT REPOSITORY
+// ================================================================
+
+// MockAuditRepository - мок хранилища журнала
+type MockAuditRepository struct {
+	mock.Mock
+	TenantID uint // Последний ForTenant
+}
+
+func (m *MockAuditRepository) Create(event *domain.AuditEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) List(filter domain.AuditEventFilter) ([]domain.AuditEvent, int64, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]domain.AuditEvent), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockAuditRepository) FindAfter(afterID uint, limit int) ([]domain.AuditEvent, error) {
+	args := m.Called(afterID, limit)
+	return args.Get(0).([]domain.AuditEvent), args.Error(1)
+}
+
+func (m *MockAuditRepository) DeleteBefore(cutoff time.Time) (int64, error) {
+	args := m.Called(cutoff)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// ForTenant - тот же мок, запоминает организацию
+func (m *MockAuditRepository) ForTenant(orgID uint) repository.AuditRepository {
+	m.TenantID = orgID
+	return m
+}
+
+// auditChain - цепочка событий, как её записал бы AuditRepository.Create
+func auditChain(events ...domain.AuditEvent) []domain.AuditEvent {
+	var prevHash string
+	for i := range events {
+		events[i].ID = uint(i + 1)
+		events[i].PrevHash = prevHash
+		events[i].CreatedAt = time.Date(2025, 10, 16, 9, 0, i, 123456000, time.UTC)
+		events[i].Hash = events[i].ComputeHash()
+		prevHash = events[i].Hash
+	}
+	return events
+}
+
+// ================================================================
+// ТЕСТЫ AUDIT LOG
+// ================================================================
+
+// TestAuditLog_VerifyDetectsTampering - изменение или удаление события ломает цепочку
+func TestAuditLog_VerifyDetectsTampering(t *testing.T) {
+	// Arrange
+	actorID := uint(3)
+	newChain := func() []domain.AuditEvent {
+		return auditChain(
+			domain.AuditEvent{Action: domain.AuditActionUserRegistered, ActorID: &actorID, TargetID: &actorID},
+			domain.AuditEvent{Action: domain.AuditActionUserUpdated, ActorID: &actorID, TargetID: &actorID,
+				Changes: domain.AuditChanges{"name": {Before: "Bob", After: "Robert"}}},
+			domain.AuditEvent{Action: domain.AuditActionLoginSucceeded, ActorID: &actorID, TargetID: &actorID},
+		)
+	}
+	verify := func(events []domain.AuditEvent) *domain.AuditVerification {
+		repo := new(MockAuditRepository)
+		repo.On("FindAfter", uint(0), mock.Anything).Return(events, nil)
+		result, err := service.NewAuditLogService(repo, &config.Config{}).Verify()
+		require.NoError(t, err)
+		return result
+	}
+
+	// Act & Assert
+	result := verify(newChain())
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(3), result.Checked)
+
+	// Изменено поле события
+	edited := newChain()
+	edited[1].Changes["name"] = domain.AuditChange{Before: "Bob", After: "Mallory"}
+	result = verify(edited)
+	assert.False(t, result.Valid)
+	require.NotNil(t, result.BrokenAt)
+	assert.Equal(t, uint(2), *result.BrokenAt)
+
+	// Удалено событие из середины
+	full := newChain()
+	result = verify([]domain.AuditEvent{full[0], full[2]})
+	assert.False(t, result.Valid)
+	assert.Equal(t, uint(3), *result.BrokenAt)
+
+	// Начало удалено по сроку хранения - цепочка валидна
+	result = verify(newChain()[1:])
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(2), result.Checked)
+}
+
+// TestAdminHandler_AuditEventsScopedToTenant - администратор видит события своей организации
+func TestAdminHandler_AuditEventsScopedToTenant(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	repo := new(MockAuditRepository)
+	actorID := uint(3)
+	events := auditChain(domain.AuditEvent{Action: domain.AuditActionLoginSucceeded, ActorID: &actorID, TargetID: &actorID})
+	repo.On("List", domain.AuditEventFilter{Action: domain.AuditActionLoginSucceeded, Page: 1, Limit: 50}).Return(events, int64(1), nil)
+	repo.On("FindAfter", uint(0), mock.Anything).Return(events, nil)
+	adminHandler := handler.NewAdminHandler(nil, nil, service.NewAuditLogService(repo, &config.Config{}), nil)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Set("userRole", "admin")
+		c.Set("tenantID", uint(2))
+	})
+	router.GET("/admin/audit-events", adminHandler.ListAuditEvents)
+	router.GET("/admin/audit-events/verify", adminHandler.VerifyAuditChain)
+
+	// Act
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/audit-events?action="+domain.AuditActionLoginSucceeded, nil))
+
+	// Assert: список - только организации 2 из токена
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, uint(2), repo.TenantID)
+	assert.Contains(t, rec.Body.String(), `"total":1`)
+
+	// Проверка цепочки - всего журнала: отдаются только счётчик и результат
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/audit-events/verify", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"valid": true, "checked": 1}`, rec.Body.String())
+	repo.AssertExpectations(t)
+}
+
+// TestAuditLog_PurgeRecordsRetention - очистка по сроку хранения сама попадает в журнал
+func TestAuditLog_PurgeRecordsRetention(t *testing.T) {
+	// Arrange
+	repo := new(MockAuditRepository)
+	auditLog := service.NewAuditLogService(repo, &config.Config{AuditRetentionDays: 30})
+	now := time.Date(2025, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	repo.On("DeleteBefore", now.AddDate(0, 0, -30)).Return(int64(12), nil)
+	repo.On("Create", mock.MatchedBy(func(e *domain.AuditEvent) bool {
+		return e.Action == domain.AuditActionRetentionPurged && e.ActorID == nil
+	})).Return(nil)
+
+	// Act
+	count, err := auditLog.Purge(now)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), count)
+	repo.AssertExpectations(t)
+
+	// AUDIT_RETENTION_DAYS=0 - ничего не удаляется
+	count, err = service.NewAuditLogService(repo, &config.Config{}).Purge(now)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	repo.AssertNumberOfCalls(t, "DeleteBefore", 1)
+}
+
+// TestUserService_UpdateRecordsChanges - в журнал попадают только изменённые поля
+func TestUserService_UpdateRecordsChanges(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	userService := service.NewUserService(mockRepo, service.WithUserAuditService(mockAudit))
+	user := &domain.User{ID: 5, Email: "grace@example.com", Name: "Grace"}
+
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("Update", user).Return(nil)
+
+	var event *domain.AuditEvent
+	mockAudit.On("Record", mock.Anything).Run(func(args mock.Arguments) {
+		event = args.Get(0).(*domain.AuditEvent)
+	}).Return()
+
+	// Act
+	_, err := userService.UpdateUser(user.ID, &domain.UpdateUserRequest{Name: "Grace H.", Email: "grace@example.com", AsAdmin: true},
+		1, domain.ClientInfo{IP: "192.0.2.7", RequestID: "req-42"})
+
+	// Assert
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, domain.AuditActionUserUpdated, event.Action)
+	assert.Equal(t, uint(1), *event.ActorID)
+	assert.Equal(t, user.ID, *event.TargetID)
+	assert.Equal(t, "req-42", event.RequestID)
+	assert.Equal(t, domain.AuditChanges{"name": {Before: "Grace", After: "Grace H."}}, event.Changes)
+}
+
+// TestLogin_RecordsFailure - неудачный вход пишется в журнал с целевым аккаунтом
+func TestLogin_RecordsFailure(t *testing.T) {
+	// Arrange
+	hasher := password.NewHasher(fastArgon2())
+	hash, err := hasher.Hash("old-Passw0rd!")
+	require.NoError(t, err)
+	mockRepo := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	authService := service.NewAuthService(mockRepo, &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"},
+		service.WithPasswordHasher(hasher),
+		service.WithAuditService(mockAudit),
+	)
+	user := &domain.User{ID: 7, Email: "alice@example.com", Password: hash, Role: "user", AuthProvider: domain.AuthProviderLocal}
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+	mockAudit.On("Record", mock.Anything).Return()
+
+	// Act
+	_, err = authService.Login(&domain.LoginRequest{Email: user.Email, Password: "wrong-password"}, domain.ClientInfo{IP: "203.0.113.9"})
+
+	// Assert
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	mockAudit.AssertCalled(t, "Record", mock.MatchedBy(func(e *domain.AuditEvent) bool {
+		return e.Action == domain.AuditActionLoginFailed && e.ActorID == nil &&
+			*e.TargetID == user.ID && e.IP == "203.0.113.9"
+	}))
+}
+
+// TestRequestIDMiddleware - ID клиента сохраняется, недопустимый заменяется
+func TestRequestIDMiddleware(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	var seen string
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware())
+	router.GET("/ping", func(c *gin.Context) {
+		seen = middleware.GetRequestIDFromContext(c)
+		c.Status(http.StatusOK)
+	})
+
+	request := func(header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		if header != "" {
+			req.Header.Set(middleware.RequestIDHeader, header)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act & Assert
+	rec := request("lb-7f3a.19")
+	assert.Equal(t, "lb-7f3a.19", seen)
+	assert.Equal(t, "lb-7f3a.19", rec.Header().Get(middleware.RequestIDHeader))
+
+	rec = request("bad id\nwith newline")
+	assert.Len(t, seen, 32)
+	assert.Equal(t, seen, rec.Header().Get(middleware.RequestIDHeader))
+
+	request("")
+	assert.Len(t, seen, 32)
+}
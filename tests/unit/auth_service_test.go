@@ -2,9 +2,11 @@ package unit
 
 import (
 	"testing"
+	"time"
 
 	"advanced-user-api/internal/config"
 	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
 	"advanced-user-api/internal/service"
 
 	"github.com/stretchr/testify/assert"
@@ -19,6 +21,9 @@ import (
 // Реализует interface UserRepository
 type MockUserRepository struct {
 	mock.Mock // Встраиваем testify/mock
+
+	// TenantID - организация последнего ForTenant (0 - не вызывался)
+	TenantID uint
 }
 
 // FindByEmail - мок метод
@@ -55,11 +60,60 @@ func (m *MockUserRepository) Update(user *domain.User) error {
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) Delete(id uint) error {
-	args := m.Called(id)
+func (m *MockUserRepository) Delete(id uint, version uint) error {
+	args := m.Called(id, version)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) FindByGroup(groupID uint) ([]domain.User, error) {
+	args := m.Called(groupID)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) FindByAttributes(filter domain.UserAttributes) ([]domain.User, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) FindPage(filter domain.UserFilter, afterID uint, limit int) ([]domain.User, error) {
+	args := m.Called(filter, afterID, limit)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) FindDeleted() ([]domain.User, error) {
+	args := m.Called()
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Restore(id uint) (*domain.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Purge(id uint) (*domain.User, []string, error) {
+	args := m.Called(id)
+	exports, _ := args.Get(1).([]string)
+	if args.Get(0) == nil {
+		return nil, exports, args.Error(2)
+	}
+	return args.Get(0).(*domain.User), exports, args.Error(2)
+}
+
+func (m *MockUserRepository) PurgeDeletedBefore(cutoff time.Time) (int64, []string, error) {
+	args := m.Called(cutoff)
+	exports, _ := args.Get(1).([]string)
+	return args.Get(0).(int64), exports, args.Error(2)
+}
+
+// ForTenant - тот же мок, запоминает организацию (без m.Called, чтобы не ломать ожидания тестов)
+func (m *MockUserRepository) ForTenant(orgID uint) repository.UserRepository {
+	m.TenantID = orgID
+	return m
+}
+
 // ================================================================
 // ТЕСТЫ AUTH SERVICE
 // ================================================================
@@ -87,7 +141,7 @@ func TestRegister_Success(t *testing.T) {
 	mockRepo.On("Create", mock.AnythingOfType("*domain.User")).Return(nil)
 
 	// Act (Действие)
-	response, err := authService.Register(req)
+	response, err := authService.Register(req, domain.ClientInfo{})
 
 	// Assert (Проверка)
 	assert.NoError(t, err)
@@ -122,12 +176,13 @@ func TestRegister_EmailAlreadyExists(t *testing.T) {
 	mockRepo.On("FindByEmail", req.Email).Return(existingUser, nil)
 
 	// Act
-	response, err := authService.Register(req)
+	response, err := authService.Register(req, domain.ClientInfo{})
 
 	// Assert
 	assert.Error(t, err)
 	assert.Nil(t, response)
 	assert.Contains(t, err.Error(), "уже зарегистрирован")
+	assert.ErrorIs(t, err, service.ErrEmailAlreadyRegistered)
 
 	mockRepo.AssertExpectations(t)
 }
@@ -161,7 +216,7 @@ func TestLogin_Success(t *testing.T) {
 	mockRepo.On("FindByEmail", req.Email).Return(user, nil)
 
 	// Act
-	response, err := authService.Login(req)
+	response, err := authService.Login(req, domain.ClientInfo{})
 
 	// Assert
 	// Примечание: тест может не пройти из-за bcrypt хеша
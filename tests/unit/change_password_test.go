@@ -0,0 +1,190 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/pkg/jwt"
+	"advanced-user-api/internal/pkg/mailer"
+	"advanced-user-api/internal/pkg/password"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// MOCKS
+// ================================================================
+
+// MockAuditService - мок журнала событий
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) Record(event *domain.AuditEvent) {
+	m.Called(event)
+}
+
+// MockMailer - мок отправки писем
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(msg mailer.Message) error {
+	args := m.Called(msg)
+	return args.Error(0)
+}
+
+// ================================================================
+// ТЕСТЫ CHANGE PASSWORD
+// ================================================================
+
+// TestChangePassword_Success - новый хеш, отзыв токенов, событие в журнале и письмо
+func TestChangePassword_Success(t *testing.T) {
+	// Arrange
+	hasher := password.NewHasher(fastArgon2())
+	hash, err := hasher.Hash("old-Passw0rd!")
+	require.NoError(t, err)
+	mockRepo := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	mockMailer := new(MockMailer)
+	authService := service.NewAuthService(mockRepo, &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"},
+		service.WithPasswordHasher(hasher),
+		service.WithAuditService(mockAudit),
+		service.WithMailer(mockMailer),
+	)
+	user := &domain.User{ID: 7, Email: "alice@example.com", Name: "Alice", Password: hash, Role: "user",
+		AuthProvider: domain.AuthProviderLocal, TokenVersion: 2}
+	client := domain.ClientInfo{IP: "203.0.113.5", UserAgent: "curl/8.0"}
+
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("Update", user).Return(nil)
+	mockAudit.On("Record", mock.MatchedBy(func(e *domain.AuditEvent) bool {
+		return e.Action == domain.AuditActionPasswordChanged &&
+			*e.ActorID == user.ID && *e.TargetID == user.ID && e.IP == client.IP
+	})).Return()
+	mockMailer.On("Send", mock.MatchedBy(func(msg mailer.Message) bool {
+		return msg.To == user.Email
+	})).Return(nil)
+
+	// Act
+	response, err := authService.ChangePassword(user.ID, &domain.ChangePasswordRequest{
+		CurrentPassword: "old-Passw0rd!",
+		NewPassword:     "vT8#qLp2!zR4",
+	}, client)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 3, user.TokenVersion)
+	assert.NotNil(t, user.PasswordChangedAt)
+	assert.True(t, password.NewHasher(fastArgon2()).Verify(user.Password, "vT8#qLp2!zR4"))
+
+	// Новый токен выдан для новой версии
+	claims, err := jwt.ValidateToken(response.Token, "test-secret")
+	require.NoError(t, err)
+	assert.Equal(t, 3, claims.TokenVersion)
+
+	mockRepo.AssertExpectations(t)
+	mockAudit.AssertExpectations(t)
+	mockMailer.AssertExpectations(t)
+}
+
+// TestChangePassword_WrongCurrentPassword - без верного текущего пароля ничего не меняется
+func TestChangePassword_WrongCurrentPassword(t *testing.T) {
+	// Arrange
+	hasher := password.NewHasher(fastArgon2())
+	hash, err := hasher.Hash("old-Passw0rd!")
+	require.NoError(t, err)
+	mockRepo := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	mockMailer := new(MockMailer)
+	authService := service.NewAuthService(mockRepo, &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"},
+		service.WithPasswordHasher(hasher),
+		service.WithAuditService(mockAudit),
+		service.WithMailer(mockMailer),
+	)
+	user := &domain.User{ID: 7, Email: "alice@example.com", Name: "Alice", Password: hash, Role: "user",
+		AuthProvider: domain.AuthProviderLocal, TokenVersion: 2}
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+
+	// Act
+	response, err := authService.ChangePassword(user.ID, &domain.ChangePasswordRequest{
+		CurrentPassword: "guess",
+		NewPassword:     "vT8#qLp2!zR4",
+	}, domain.ClientInfo{})
+
+	// Assert
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, service.ErrWrongCurrentPassword)
+	assert.Equal(t, 2, user.TokenVersion)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	mockAudit.AssertNotCalled(t, "Record", mock.Anything)
+	mockMailer.AssertNotCalled(t, "Send", mock.Anything)
+}
+
+// TestChangePassword_AppliesPolicy - новый пароль проверяется политикой
+func TestChangePassword_AppliesPolicy(t *testing.T) {
+	// Arrange
+	hasher := password.NewHasher(fastArgon2())
+	hash, err := hasher.Hash("old-Passw0rd!")
+	require.NoError(t, err)
+	mockRepo := new(MockUserRepository)
+	authService := service.NewAuthService(mockRepo, &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"},
+		service.WithPasswordHasher(hasher),
+	)
+	user := &domain.User{ID: 7, Email: "alice@example.com", Name: "Alice", Password: hash, Role: "user",
+		AuthProvider: domain.AuthProviderLocal, TokenVersion: 2}
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+
+	// Act
+	_, err = authService.ChangePassword(user.ID, &domain.ChangePasswordRequest{
+		CurrentPassword: "old-Passw0rd!",
+		NewPassword:     "alice2024",
+	}, domain.ClientInfo{})
+
+	// Assert
+	assert.Contains(t, violationCodes(t, err), password.ViolationPersonalInfo)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+// TestAuthMiddleware_RejectsRevokedToken - токен старой версии отклоняется
+func TestAuthMiddleware_RejectsRevokedToken(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	mockRepo := new(MockUserRepository)
+	authService := service.NewAuthService(mockRepo, &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"})
+	user := &domain.User{ID: 7, Email: "alice@example.com", Role: "user", TokenVersion: 2}
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+
+	router := gin.New()
+	router.GET("/me", middleware.AuthMiddleware(&config.Config{JWTSecret: "test-secret"}, authService),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(version int) int {
+		token, err := jwt.GenerateTokenFromClaims(jwt.Claims{
+			UserID:       user.ID,
+			Email:        user.Email,
+			Role:         user.Role,
+			TokenVersion: version,
+		}, "test-secret", time.Hour)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Act & Assert
+	assert.Equal(t, http.StatusOK, request(user.TokenVersion))
+	assert.Equal(t, http.StatusUnauthorized, request(user.TokenVersion-1))
+}
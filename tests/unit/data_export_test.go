@@ -0,0 +1,304 @@
+package unit
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// MOCK DATA EXPORT REPOSITORY
+// ================================================================
+
+// MockDataExportRepository - мок задач выгрузки
+type MockDataExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockDataExportRepository) Create(export *domain.DataExport) error {
+	args := m.Called(export)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) FindByID(id uint) (*domain.DataExport, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) Update(export *domain.DataExport) error {
+	args := m.Called(export)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) FindActive(userID uint) (*domain.DataExport, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) ClaimPending(now, staleBefore time.Time) (*domain.DataExport, error) {
+	args := m.Called(now, staleBefore)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) FindExpired(now time.Time) ([]domain.DataExport, error) {
+	args := m.Called(now)
+	return args.Get(0).([]domain.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) CollectUserData(userID uint) (*domain.UserDataArchive, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.UserDataArchive), args.Error(1)
+}
+
+// ================================================================
+// ТЕСТЫ ВЫГРУЗКИ ДАННЫХ
+// ================================================================
+
+// TestDataExport_RequestProcessDownload - задача → архив → подписанная ссылка → файл
+func TestDataExport_RequestProcessDownload(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		JWTSecret:     "test-secret",
+		DataExportDir: t.TempDir(),
+		DataExportURL: "https://api.example.com/api/v1/exports/",
+		DataExportTTL: "1h",
+	}
+	mockExports := new(MockDataExportRepository)
+	mockRepo := new(MockUserRepository)
+	exportService := service.NewDataExportService(mockExports, mockRepo, nil, cfg)
+	user := &domain.User{ID: 7, Email: "alice@example.com", Name: "Alice"}
+
+	mockRepo.On("FindByID", uint(7)).Return(user, nil)
+	mockExports.On("FindActive", uint(7)).Return(nil, repository.ErrDataExportRecordNotFound)
+
+	var export *domain.DataExport
+	mockExports.On("Create", mock.AnythingOfType("*domain.DataExport")).Run(func(args mock.Arguments) {
+		export = args.Get(0).(*domain.DataExport)
+		export.ID = 3
+	}).Return(nil)
+
+	// Act: запрос
+	created, err := exportService.ForTenant(2).Request(7, 7, &domain.CreateDataExportRequest{}, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, domain.DataExportStatusPending, created.Status)
+	assert.Equal(t, domain.DataExportFormatZIP, created.Format, "формат по умолчанию")
+	assert.Equal(t, uint(2), mockRepo.TenantID)
+
+	// Act: обработка
+	mockExports.On("ClaimPending", mock.Anything, mock.Anything).Return(export, nil).Once()
+	mockExports.On("ClaimPending", mock.Anything, mock.Anything).Return(nil, repository.ErrDataExportRecordNotFound)
+	mockExports.On("CollectUserData", uint(7)).Return(&domain.UserDataArchive{
+		GeneratedAt: time.Now(),
+		User:        *user,
+		Sessions:    []domain.Session{{ID: "s1", DeviceName: "Chrome on macOS"}},
+	}, nil)
+	mockExports.On("Update", export).Return(nil)
+
+	count, err := exportService.ProcessPending()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, domain.DataExportStatusCompleted, export.Status)
+	assert.Positive(t, export.Size)
+
+	// Act: статус и ссылка
+	mockExports.On("FindByID", uint(3)).Return(export, nil)
+	status, err := exportService.Get(3)
+	require.NoError(t, err)
+	require.NotEmpty(t, status.DownloadURL)
+
+	link, err := url.Parse(status.DownloadURL)
+	require.NoError(t, err)
+	assert.Equal(t, "/api/v1/exports/3/download", link.Path)
+	assert.Equal(t, strconv.FormatInt(export.ExpiresAt.Unix(), 10), link.Query().Get("expires"))
+
+	file, err := exportService.Open(3, link.Query().Get("expires"), link.Query().Get("signature"), domain.ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "user-7-data.zip", file.Name)
+
+	// Assert: содержимое архива
+	reader, err := zip.OpenReader(file.Path)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	names := map[string]*zip.File{}
+	for _, f := range reader.File {
+		names[f.Name] = f
+	}
+	assert.Contains(t, names, "sessions.json")
+	assert.Contains(t, names, "audit_events.json")
+	require.Contains(t, names, "user.json")
+
+	rc, err := names["user.json"].Open()
+	require.NoError(t, err)
+	body, err := io.ReadAll(rc)
+	rc.Close()
+	require.NoError(t, err)
+
+	var exported map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &exported))
+	assert.Equal(t, "alice@example.com", exported["email"])
+	assert.NotContains(t, exported, "password")
+}
+
+// TestDataExport_OpenRejectsTamperedLink - подпись, срок и ID проверяются вместе
+func TestDataExport_OpenRejectsTamperedLink(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		JWTSecret:     "test-secret",
+		DataExportDir: t.TempDir(),
+		DataExportURL: "https://api.example.com/api/v1/exports/",
+		DataExportTTL: "1h",
+	}
+	mockExports := new(MockDataExportRepository)
+	mockRepo := new(MockUserRepository)
+	exportService := service.NewDataExportService(mockExports, mockRepo, nil, cfg)
+	expiresAt := time.Now().Add(time.Hour)
+	export := &domain.DataExport{ID: 3, UserID: 7, Format: domain.DataExportFormatJSON,
+		Status: domain.DataExportStatusCompleted, FileName: "export-3.json", ExpiresAt: &expiresAt}
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.DataExportDir, export.FileName), []byte("{}"), 0o600))
+
+	mockRepo.On("FindByID", uint(7)).Return(&domain.User{ID: 7}, nil)
+	mockExports.On("FindByID", uint(3)).Return(export, nil)
+	mockExports.On("FindByID", uint(4)).Return(&domain.DataExport{ID: 4, UserID: 7, Status: domain.DataExportStatusCompleted,
+		FileName: "export-4.json", ExpiresAt: &expiresAt}, nil)
+
+	status, err := exportService.Get(3)
+	require.NoError(t, err)
+	link, err := url.Parse(status.DownloadURL)
+	require.NoError(t, err)
+	expires, signature := link.Query().Get("expires"), link.Query().Get("signature")
+
+	// Act & Assert
+	_, err = exportService.Open(3, expires, signature, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	_, err = exportService.Open(4, expires, signature, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrDataExportLinkInvalid, "подпись другой выгрузки")
+
+	later := strconv.FormatInt(expiresAt.Add(24*time.Hour).Unix(), 10)
+	_, err = exportService.Open(3, later, signature, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrDataExportLinkInvalid, "продлённый срок")
+
+	_, err = exportService.Open(3, expires, signature+"x", domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrDataExportLinkInvalid)
+}
+
+// TestDataExport_RequestRejected - вторая выгрузка, пока идёт первая, и чужой пользователь
+func TestDataExport_RequestRejected(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		JWTSecret:     "test-secret",
+		DataExportDir: t.TempDir(),
+		DataExportURL: "https://api.example.com/api/v1/exports/",
+		DataExportTTL: "1h",
+	}
+	mockExports := new(MockDataExportRepository)
+	mockRepo := new(MockUserRepository)
+	exportService := service.NewDataExportService(mockExports, mockRepo, nil, cfg)
+	mockRepo.On("FindByID", uint(7)).Return(&domain.User{ID: 7}, nil)
+	mockRepo.On("FindByID", uint(8)).Return(nil, repository.ErrDataExportRecordNotFound)
+	mockExports.On("FindActive", uint(7)).Return(&domain.DataExport{ID: 1, Status: domain.DataExportStatusProcessing}, nil)
+
+	// Act & Assert
+	_, err := exportService.Request(7, 7, &domain.CreateDataExportRequest{}, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrDataExportInProgress)
+
+	_, err = exportService.ForTenant(5).Request(8, 1, &domain.CreateDataExportRequest{}, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrDataExportUserNotFound)
+
+	mockExports.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+// TestDataExport_ExpireArchives - истёкший архив удаляется с диска
+func TestDataExport_ExpireArchives(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		JWTSecret:     "test-secret",
+		DataExportDir: t.TempDir(),
+		DataExportURL: "https://api.example.com/api/v1/exports/",
+		DataExportTTL: "1h",
+	}
+	mockExports := new(MockDataExportRepository)
+	exportService := service.NewDataExportService(mockExports, new(MockUserRepository), nil, cfg)
+	path := filepath.Join(cfg.DataExportDir, "export-3.zip")
+	require.NoError(t, os.WriteFile(path, []byte("zip"), 0o600))
+
+	now := time.Now()
+	mockExports.On("FindExpired", now).Return([]domain.DataExport{
+		{ID: 3, Status: domain.DataExportStatusCompleted, FileName: "export-3.zip"},
+	}, nil)
+	mockExports.On("Update", mock.MatchedBy(func(export *domain.DataExport) bool {
+		return export.ID == 3 && export.Status == domain.DataExportStatusExpired && export.FileName == ""
+	})).Return(nil)
+
+	// Act
+	count, err := exportService.ExpireArchives(now)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoFileExists(t, path)
+	mockExports.AssertExpectations(t)
+}
+
+// TestDataExport_PurgeRemovesArchives - окончательное удаление аккаунта удаляет и файлы его архивов
+func TestDataExport_PurgeRemovesArchives(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		JWTSecret:     "test-secret",
+		DataExportDir: t.TempDir(),
+		DataExportURL: "https://api.example.com/api/v1/exports/",
+		DataExportTTL: "1h",
+	}
+	exportService := service.NewDataExportService(new(MockDataExportRepository), new(MockUserRepository), nil, cfg)
+	for _, name := range []string{"export-3.zip", "export-4.zip", "export-9.zip"} {
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.DataExportDir, name), []byte("zip"), 0o600))
+	}
+	mockRepo := new(MockUserRepository)
+	now := time.Now()
+	mockRepo.On("Purge", uint(6)).Return(&domain.User{ID: 6, Email: "old@example.com"}, []string{"export-3.zip", "missing.zip"}, nil)
+	mockRepo.On("PurgeDeletedBefore", now.Add(-24*time.Hour)).Return(int64(1), []string{"export-4.zip"}, nil)
+	userService := service.NewUserService(mockRepo,
+		service.WithDeletedUserPurge(24*time.Hour),
+		service.WithArchiveRemover(exportService),
+	)
+
+	// Act
+	require.NoError(t, userService.PurgeUser(6, 1, domain.ClientInfo{}))
+	_, err := userService.PurgeExpired(now)
+	require.NoError(t, err)
+
+	// Assert: удалены только архивы стёртых аккаунтов, отсутствующий файл - не ошибка
+	assert.NoFileExists(t, filepath.Join(cfg.DataExportDir, "export-3.zip"))
+	assert.NoFileExists(t, filepath.Join(cfg.DataExportDir, "export-4.zip"))
+	assert.FileExists(t, filepath.Join(cfg.DataExportDir, "export-9.zip"))
+}
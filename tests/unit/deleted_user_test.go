@@ -0,0 +1,138 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/handler"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ================================================================
+// ТЕСТЫ УДАЛЁННЫХ ПОЛЬЗОВАТЕЛЕЙ
+// ================================================================
+
+// TestUserService_ListDeletedUsers - дата окончательного удаления считается от срока хранения
+func TestUserService_ListDeletedUsers(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	deletedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	mockRepo.On("FindDeleted").Return([]domain.User{
+		{ID: 4, Email: "gone@example.com", DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}},
+	}, nil)
+
+	// Act
+	users, err := service.NewUserService(mockRepo, service.WithDeletedUserPurge(30*24*time.Hour)).
+		ForTenant(2).ListDeletedUsers()
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, deletedAt, users[0].DeletedAt)
+	require.NotNil(t, users[0].PurgeAt)
+	assert.Equal(t, deletedAt.AddDate(0, 0, 30), *users[0].PurgeAt)
+	assert.Equal(t, uint(2), mockRepo.TenantID)
+
+	// Без автоочистки срока нет
+	users, err = service.NewUserService(mockRepo).ListDeletedUsers()
+	require.NoError(t, err)
+	assert.Nil(t, users[0].PurgeAt)
+
+	// В JSON видна дата удаления (у User она скрыта)
+	body, err := json.Marshal(users[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"deleted_at":"2026-03-01T12:00:00Z"`)
+	assert.Contains(t, string(body), `"email":"gone@example.com"`)
+}
+
+// TestUserService_RestoreAndPurge - восстановление и окончательное удаление пишутся в журнал
+func TestUserService_RestoreAndPurge(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	userService := service.NewUserService(mockRepo, service.WithUserAuditService(mockAudit))
+
+	mockRepo.On("Restore", uint(4)).Return(&domain.User{ID: 4, Email: "gone@example.com"}, nil)
+	mockRepo.On("Restore", uint(5)).Return(nil, repository.ErrUserEmailTaken)
+	mockRepo.On("Purge", uint(6)).Return(&domain.User{ID: 6, Email: "old@example.com"}, nil, nil)
+	mockRepo.On("Purge", uint(7)).Return(nil, nil, repository.ErrDeletedUserNotFound)
+	mockAudit.On("Record", mock.MatchedBy(func(event *domain.AuditEvent) bool {
+		return event.Action == domain.AuditActionUserRestored && *event.TargetID == 4 && *event.ActorID == 1
+	})).Return()
+	mockAudit.On("Record", mock.MatchedBy(func(event *domain.AuditEvent) bool {
+		return event.Action == domain.AuditActionUserPurged && *event.TargetID == 6 && event.Details == "email: old@example.com"
+	})).Return()
+
+	// Act & Assert
+	user, err := userService.RestoreUser(4, 1, domain.ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, uint(4), user.ID)
+
+	_, err = userService.RestoreUser(5, 1, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrRestoreEmailTaken)
+
+	require.NoError(t, userService.PurgeUser(6, 1, domain.ClientInfo{}))
+	assert.ErrorIs(t, userService.PurgeUser(7, 1, domain.ClientInfo{}), service.ErrDeletedUserNotFound)
+
+	mockAudit.AssertExpectations(t)
+	mockAudit.AssertNumberOfCalls(t, "Record", 2)
+}
+
+// TestUserService_PurgeExpired - граница срока хранения и выключенная автоочистка
+func TestUserService_PurgeExpired(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	now := time.Now()
+	mockRepo.On("PurgeDeletedBefore", now.Add(-7*24*time.Hour)).Return(int64(3), nil, nil)
+
+	// Act
+	count, err := service.NewUserService(mockRepo, service.WithDeletedUserPurge(7*24*time.Hour)).PurgeExpired(now)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	// USER_PURGE_AFTER_DAYS=0 - ничего не удаляется
+	count, err = service.NewUserService(mockRepo).PurgeExpired(now)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	mockRepo.AssertNumberOfCalls(t, "PurgeDeletedBefore", 1)
+}
+
+// TestUserHandler_RestoreConflict - занятый email при восстановлении - 409
+func TestUserHandler_RestoreConflict(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("Restore", uint(5)).Return(nil, repository.ErrUserEmailTaken)
+	mockRepo.On("Purge", uint(8)).Return(nil, nil, repository.ErrDeletedUserNotFound)
+	userHandler := handler.NewUserHandler(service.NewUserService(mockRepo), nil)
+
+	router := gin.New()
+	router.POST("/deleted-users/:id/restore", userHandler.Restore)
+	router.DELETE("/deleted-users/:id", userHandler.Purge)
+
+	// Act & Assert
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/deleted-users/5/restore", nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/deleted-users/8", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/deleted-users/abc", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
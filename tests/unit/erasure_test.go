@@ -0,0 +1,287 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/password"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// MOCK ERASURE REPOSITORY
+// ================================================================
+
+// MockErasureRepository - мок запросов на стирание
+type MockErasureRepository struct {
+	mock.Mock
+}
+
+func (m *MockErasureRepository) Create(erasure *domain.UserErasure) error {
+	args := m.Called(erasure)
+	return args.Error(0)
+}
+
+func (m *MockErasureRepository) FindByID(id uint) (*domain.UserErasure, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.UserErasure), args.Error(1)
+}
+
+func (m *MockErasureRepository) Update(erasure *domain.UserErasure) error {
+	args := m.Called(erasure)
+	return args.Error(0)
+}
+
+func (m *MockErasureRepository) FindPending(userID uint) (*domain.UserErasure, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.UserErasure), args.Error(1)
+}
+
+func (m *MockErasureRepository) FindLatest(userID uint) (*domain.UserErasure, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.UserErasure), args.Error(1)
+}
+
+func (m *MockErasureRepository) List(filter domain.ErasureFilter) ([]domain.UserErasure, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]domain.UserErasure), args.Error(1)
+}
+
+func (m *MockErasureRepository) FindDue(now time.Time) ([]domain.UserErasure, error) {
+	args := m.Called(now)
+	return args.Get(0).([]domain.UserErasure), args.Error(1)
+}
+
+func (m *MockErasureRepository) Erase(erasure *domain.UserErasure, now time.Time) (*repository.ErasedFiles, error) {
+	args := m.Called(erasure, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repository.ErasedFiles), args.Error(1)
+}
+
+func (m *MockErasureRepository) ForTenant(orgID uint) repository.ErasureRepository {
+	return m
+}
+
+// ================================================================
+// ТЕСТЫ СТИРАНИЯ ДАННЫХ
+// ================================================================
+
+// TestErasure_RequestOwnSchedulesCoolingOff - верный пароль → запрос с периодом отмены
+func TestErasure_RequestOwnSchedulesCoolingOff(t *testing.T) {
+	// Arrange
+	hasher := password.NewHasher(fastArgon2())
+	hash, err := hasher.Hash("old-Passw0rd!")
+	require.NoError(t, err)
+	mockRepo := new(MockUserRepository)
+	mockErasures := new(MockErasureRepository)
+	mockAudit := new(MockAuditService)
+	authService := service.NewAuthService(mockRepo, &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"},
+		service.WithPasswordHasher(hasher),
+	)
+	cfg := &config.Config{
+		ErasureCoolingOffDays: 14,
+		DataExportDir:         t.TempDir(),
+	}
+	erasureService := service.NewErasureService(mockErasures, mockRepo, authService, nil, mockAudit, cfg)
+	user := &domain.User{ID: 7, Email: "alice@example.com", Name: "Alice", Password: hash, Role: "user",
+		AuthProvider: domain.AuthProviderLocal}
+	user.OrganizationID = 3
+
+	mockRepo.On("FindByID", uint(7)).Return(user, nil)
+	mockRepo.On("FindByEmail", "alice@example.com").Return(user, nil)
+	mockErasures.On("FindPending", uint(7)).Return(nil, repository.ErrErasureRecordNotFound)
+	mockErasures.On("Create", mock.AnythingOfType("*domain.UserErasure")).Return(nil)
+	mockAudit.On("Record", mock.MatchedBy(func(e *domain.AuditEvent) bool {
+		return e.Action == domain.AuditActionErasureRequested
+	})).Return()
+
+	// Act
+	erasure, err := erasureService.RequestOwn(7, &domain.EraseAccountRequest{
+		Password: "old-Passw0rd!",
+		Reason:   "больше не пользуюсь",
+	}, time.Time{}, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErasureStatusPending, erasure.Status)
+	assert.Equal(t, uint(3), erasure.OrganizationID)
+	assert.Equal(t, uint(7), erasure.RequestedByID)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 14), erasure.ScheduledFor, time.Minute)
+	mockErasures.AssertNotCalled(t, "Erase", mock.Anything, mock.Anything)
+	mockAudit.AssertExpectations(t)
+}
+
+// TestErasure_RequestOwnRequiresReauthentication - неверный пароль и старый вход без пароля
+func TestErasure_RequestOwnRequiresReauthentication(t *testing.T) {
+	// Arrange
+	hasher := password.NewHasher(fastArgon2())
+	hash, err := hasher.Hash("old-Passw0rd!")
+	require.NoError(t, err)
+	mockRepo := new(MockUserRepository)
+	mockErasures := new(MockErasureRepository)
+	authService := service.NewAuthService(mockRepo, &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"},
+		service.WithPasswordHasher(hasher),
+	)
+	cfg := &config.Config{
+		ErasureCoolingOffDays: 14,
+		DataExportDir:         t.TempDir(),
+	}
+	erasureService := service.NewErasureService(mockErasures, mockRepo, authService, nil, new(MockAuditService), cfg)
+	user := &domain.User{ID: 7, Email: "alice@example.com", Name: "Alice", Password: hash, Role: "user",
+		AuthProvider: domain.AuthProviderLocal}
+	passkeyOnly := &domain.User{ID: 8, Email: "bob@example.com", AuthProvider: domain.AuthProviderLocal, PasskeyHandle: "handle"}
+
+	mockRepo.On("FindByID", uint(7)).Return(user, nil)
+	mockRepo.On("FindByEmail", "alice@example.com").Return(user, nil)
+	mockRepo.On("FindByID", uint(8)).Return(passkeyOnly, nil)
+
+	// Act & Assert: неверный пароль
+	_, err = erasureService.RequestOwn(7, &domain.EraseAccountRequest{Password: "wrong-Passw0rd!"}, time.Now(), domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrWrongCurrentPassword)
+
+	// Свежий токен не заменяет пароль, если пароль есть
+	_, err = erasureService.RequestOwn(7, &domain.EraseAccountRequest{}, time.Now(), domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrWrongCurrentPassword)
+
+	// Аккаунт только с passkey: вход час назад - нужно войти заново
+	_, err = erasureService.RequestOwn(8, &domain.EraseAccountRequest{}, time.Now().Add(-time.Hour), domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrReauthenticationRequired)
+
+	mockErasures.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+// TestErasure_ImmediateAndDuplicate - администратор стирает сразу, второй запрос отклоняется
+func TestErasure_ImmediateAndDuplicate(t *testing.T) {
+	// Arrange
+	hasher := password.NewHasher(fastArgon2())
+	hash, err := hasher.Hash("old-Passw0rd!")
+	require.NoError(t, err)
+	mockRepo := new(MockUserRepository)
+	mockErasures := new(MockErasureRepository)
+	mockAudit := new(MockAuditService)
+	authService := service.NewAuthService(mockRepo, &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"},
+		service.WithPasswordHasher(hasher),
+	)
+	cfg := &config.Config{
+		ErasureCoolingOffDays: 14,
+		DataExportDir:         t.TempDir(),
+	}
+	erasureService := service.NewErasureService(mockErasures, mockRepo, authService, nil, mockAudit, cfg)
+	user := &domain.User{ID: 7, Email: "alice@example.com", Name: "Alice", Password: hash, Role: "user",
+		AuthProvider: domain.AuthProviderLocal}
+	mockRepo.On("FindByID", uint(7)).Return(user, nil)
+	mockErasures.On("FindPending", uint(7)).Return(nil, repository.ErrErasureRecordNotFound).Once()
+	mockErasures.On("Create", mock.AnythingOfType("*domain.UserErasure")).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.UserErasure).ID = 5
+	}).Return(nil)
+	mockErasures.On("Erase", mock.AnythingOfType("*domain.UserErasure"), mock.Anything).Run(func(args mock.Arguments) {
+		erasure := args.Get(0).(*domain.UserErasure)
+		now := args.Get(1).(time.Time)
+		erasure.Status = domain.ErasureStatusCompleted
+		erasure.CompletedAt = &now
+	}).Return(&repository.ErasedFiles{}, nil)
+	mockAudit.On("Record", mock.Anything).Return()
+
+	// Act
+	erasure, err := erasureService.Request(7, 1, &domain.CreateErasureRequest{Reason: "DPO-42", Immediate: true}, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErasureStatusCompleted, erasure.Status)
+	mockAudit.AssertCalled(t, "Record", mock.MatchedBy(func(e *domain.AuditEvent) bool {
+		return e.Action == domain.AuditActionUserErased && *e.TargetID == 7 && e.Details == "запрос: 5"
+	}))
+
+	// Act & Assert: уже есть ожидающий запрос
+	mockErasures.On("FindPending", uint(7)).Return(&domain.UserErasure{ID: 6, Status: domain.ErasureStatusPending}, nil)
+	_, err = erasureService.Request(7, 1, &domain.CreateErasureRequest{Reason: "DPO-43"}, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrErasurePending)
+}
+
+// TestErasure_Cancel - отмена в период ожидания
+func TestErasure_Cancel(t *testing.T) {
+	// Arrange
+	hasher := password.NewHasher(fastArgon2())
+	hash, err := hasher.Hash("old-Passw0rd!")
+	require.NoError(t, err)
+	mockRepo := new(MockUserRepository)
+	mockErasures := new(MockErasureRepository)
+	mockAudit := new(MockAuditService)
+	authService := service.NewAuthService(mockRepo, &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"},
+		service.WithPasswordHasher(hasher),
+	)
+	cfg := &config.Config{
+		ErasureCoolingOffDays: 14,
+		DataExportDir:         t.TempDir(),
+	}
+	erasureService := service.NewErasureService(mockErasures, mockRepo, authService, nil, mockAudit, cfg)
+	user := &domain.User{ID: 7, Email: "alice@example.com", Name: "Alice", Password: hash, Role: "user",
+		AuthProvider: domain.AuthProviderLocal}
+	pending := &domain.UserErasure{ID: 5, UserID: 7, Status: domain.ErasureStatusPending}
+
+	mockRepo.On("FindByID", uint(7)).Return(user, nil)
+	mockErasures.On("FindPending", uint(7)).Return(pending, nil)
+	mockErasures.On("Update", pending).Return(nil)
+	mockAudit.On("Record", mock.Anything).Return()
+
+	// Act
+	cancelled, err := erasureService.Cancel(7, 7, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErasureStatusCancelled, cancelled.Status)
+	require.NotNil(t, cancelled.CancelledByID)
+	assert.Equal(t, uint(7), *cancelled.CancelledByID)
+	assert.NotNil(t, cancelled.CancelledAt)
+}
+
+// TestErasure_ProcessDueRemovesArchives - архивы выгрузки удаляются вместе с данными
+func TestErasure_ProcessDueRemovesArchives(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	authService := service.NewAuthService(mockRepo, &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"})
+	cfg := &config.Config{DataExportDir: t.TempDir()}
+	mockErasures := new(MockErasureRepository)
+	erasureService := service.NewErasureService(mockErasures, mockRepo, authService, nil, nil, cfg)
+
+	path := filepath.Join(cfg.DataExportDir, "export-3.zip")
+	require.NoError(t, os.WriteFile(path, []byte("zip"), 0o600))
+
+	now := time.Now()
+	mockErasures.On("FindDue", now).Return([]domain.UserErasure{
+		{ID: 5, UserID: 7, Status: domain.ErasureStatusPending},
+		{ID: 6, UserID: 8, Status: domain.ErasureStatusPending},
+	}, nil)
+	mockErasures.On("Erase", mock.MatchedBy(func(e *domain.UserErasure) bool { return e.ID == 5 }), now).
+		Return(&repository.ErasedFiles{Exports: []string{"export-3.zip", "export-4.json"}}, nil)
+	mockErasures.On("Erase", mock.MatchedBy(func(e *domain.UserErasure) bool { return e.ID == 6 }), now).
+		Return(nil, repository.ErrErasureRecordNotFound)
+
+	// Act
+	count, err := erasureService.ProcessDue(now)
+
+	// Assert: отменённый в последний момент запрос не считается
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoFileExists(t, path)
+}
@@ -0,0 +1,135 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/handler"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// ТЕСТЫ ОПТИМИСТИЧНОЙ БЛОКИРОВКИ
+// ================================================================
+
+// versionedUser - пользователь версии 5
+func versionedUser() *domain.User {
+	return &domain.User{ID: 7, Name: "Alice", Email: "alice@example.com", Role: "user", Version: 5}
+}
+
+// TestUserService_UpdateChecksVersion - If-Match проверяется до записи, гонка - в UPDATE
+func TestUserService_UpdateChecksVersion(t *testing.T) {
+	replacement := func(ifMatch domain.VersionCondition) *domain.UpdateUserRequest {
+		return &domain.UpdateUserRequest{Name: "Alice L.", Email: "alice@example.com", Role: "user", IfMatch: ifMatch}
+	}
+
+	// Устаревшая версия - в БД ничего не пишется
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("FindByID", uint(7)).Return(versionedUser(), nil)
+	_, err := service.NewUserService(mockRepo).UpdateUser(7, replacement(domain.VersionCondition{4}), 7, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrUserVersionMismatch)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+
+	// Ни один ETag не разобран (например, только слабые) - не подходит ни одна версия
+	_, err = service.NewUserService(mockRepo).UpdateUser(7, replacement(domain.VersionCondition{}), 7, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrUserVersionMismatch)
+
+	// Версия совпала, но запись изменили между чтением и UPDATE
+	mockRepo = new(MockUserRepository)
+	mockRepo.On("FindByID", uint(7)).Return(versionedUser(), nil)
+	mockRepo.On("Update", mock.Anything).Return(repository.ErrUserVersionConflict)
+	_, err = service.NewUserService(mockRepo).UpdateUser(7, replacement(domain.VersionCondition{3, 5}), 7, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrUserVersionMismatch)
+}
+
+// TestUserService_DeleteChecksVersion - с If-Match удаляется только прочитанная версия
+func TestUserService_DeleteChecksVersion(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("FindByID", uint(7)).Return(versionedUser(), nil)
+	mockRepo.On("Delete", uint(7), uint(5)).Return(nil)
+	mockRepo.On("Delete", uint(7), uint(0)).Return(nil)
+	userService := service.NewUserService(mockRepo)
+
+	assert.ErrorIs(t, userService.DeleteUser(7, domain.VersionCondition{6}, 1, domain.ClientInfo{}), service.ErrUserVersionMismatch)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+
+	require.NoError(t, userService.DeleteUser(7, domain.VersionCondition{5}, 1, domain.ClientInfo{}))
+	mockRepo.AssertCalled(t, "Delete", uint(7), uint(5))
+
+	// Без If-Match версия в DELETE не проверяется
+	require.NoError(t, userService.DeleteUser(7, nil, 1, domain.ClientInfo{}))
+	mockRepo.AssertCalled(t, "Delete", uint(7), uint(0))
+}
+
+// TestUserHandler_ConditionalRequests - ETag, If-None-Match, If-Match и REQUIRE_IF_MATCH
+func TestUserHandler_ConditionalRequests(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	request := func(method, body string, requireIfMatch bool, headers map[string]string) *httptest.ResponseRecorder {
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByID", uint(7)).Return(versionedUser(), nil)
+		mockRepo.On("Update", mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(0).(*domain.User).Version++ // как versioned UPDATE в БД
+		}).Return(nil)
+		userHandler := handler.NewUserHandler(service.NewUserService(mockRepo), nil)
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", uint(7))
+			c.Set("userRole", "user")
+		})
+		ifMatch := middleware.RequireIfMatch(requireIfMatch)
+		router.GET("/users/:id", userHandler.GetByID)
+		router.PUT("/users/:id", ifMatch, userHandler.Update)
+		router.PATCH("/users/:id", ifMatch, userHandler.Patch)
+
+		req := httptest.NewRequest(method, "/users/7", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	put := `{"name": "Alice L.", "email": "alice@example.com", "role": "user"}`
+
+	// Act & Assert: чтение
+	rec := request(http.MethodGet, "", false, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"5"`, rec.Header().Get("ETag"))
+	assert.Equal(t, "private, no-cache", rec.Header().Get("Cache-Control"))
+
+	rec = request(http.MethodGet, "", false, map[string]string{"If-None-Match": `"4", W/"5"`})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "", false, map[string]string{"If-None-Match": `"4"`}).Code)
+
+	// Изменение: версия совпала - новый ETag, устарела - 412
+	rec = request(http.MethodPut, put, false, map[string]string{"If-Match": `"5"`})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"6"`, rec.Header().Get("ETag"))
+	assert.Equal(t, http.StatusPreconditionFailed, request(http.MethodPut, put, false, map[string]string{"If-Match": `"4"`}).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, request(http.MethodPut, put, false, map[string]string{"If-Match": `W/"5"`}).Code,
+		"If-Match сравнивает строго")
+	assert.Equal(t, http.StatusOK, request(http.MethodPut, put, false, map[string]string{"If-Match": "*"}).Code)
+
+	// PATCH - те же правила
+	headers := map[string]string{"If-Match": `"4"`, "Content-Type": domain.PatchFormatMerge}
+	assert.Equal(t, http.StatusPreconditionFailed, request(http.MethodPatch, `{"name": "Alice L."}`, false, headers).Code)
+
+	// REQUIRE_IF_MATCH: без заголовка - 428, без настройки - изменение как раньше
+	assert.Equal(t, http.StatusPreconditionRequired, request(http.MethodPut, put, true, nil).Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodPut, put, true, map[string]string{"If-Match": `"5"`}).Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodPut, put, false, nil).Code)
+}
@@ -0,0 +1,247 @@
+package unit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/fieldcrypt"
+	"advanced-user-api/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+)
+
+// ================================================================
+// ВСПОМОГАТЕЛЬНЫЕ ФУНКЦИИ
+// ================================================================
+
+// randomKey - случайный ключ 32 байта в base64
+func randomKey(t *testing.T) string {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// writeKeyFile - файл ключей во временном каталоге
+func writeKeyFile(t *testing.T, current int, keys map[string]string, indexKey string) string {
+	data, err := json.Marshal(map[string]interface{}{
+		"current":         current,
+		"keys":            keys,
+		"blind_index_key": indexKey,
+	})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// newTestEncryptor - Encryptor с ключами keys и текущей версией current
+func newTestEncryptor(t *testing.T, current int, keys map[string]string, indexKey string, fields ...string) *fieldcrypt.Encryptor {
+	provider, err := fieldcrypt.NewFileKeyProvider(writeKeyFile(t, current, keys, indexKey))
+	require.NoError(t, err)
+	enc, err := fieldcrypt.NewEncryptor(provider, fields)
+	require.NoError(t, err)
+	return enc
+}
+
+// ================================================================
+// ТЕСТЫ FIELDCRYPT
+// ================================================================
+
+// TestFieldCrypt_RoundTrip - шифрование со случайным DEK, расшифровка, открытый текст
+func TestFieldCrypt_RoundTrip(t *testing.T) {
+	// Arrange
+	enc := newTestEncryptor(t, 1, map[string]string{"1": randomKey(t)}, randomKey(t), "email", "name")
+
+	// Act
+	first, err := enc.Encrypt("email", "alice@example.com")
+	require.NoError(t, err)
+	second, err := enc.Encrypt("email", "alice@example.com")
+	require.NoError(t, err)
+
+	// Assert
+	assert.True(t, strings.HasPrefix(first, "enc:v1:1:"))
+	assert.NotContains(t, first, "alice")
+	assert.NotEqual(t, first, second, "каждое значение шифруется своим ключом данных")
+
+	plain, err := enc.Decrypt("email", first)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", plain)
+
+	// Записи до включения шифрования читаются как есть
+	plain, err = enc.Decrypt("email", "legacy@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy@example.com", plain)
+
+	empty, err := enc.Encrypt("name", "")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+// TestFieldCrypt_RejectsTamperedOrMovedValue - шифртекст привязан к колонке
+func TestFieldCrypt_RejectsTamperedOrMovedValue(t *testing.T) {
+	// Arrange
+	enc := newTestEncryptor(t, 1, map[string]string{"1": randomKey(t)}, randomKey(t), "email", "name")
+	stored, err := enc.Encrypt("email", "alice@example.com")
+	require.NoError(t, err)
+
+	// Act & Assert: email, подставленный в колонку name
+	_, err = enc.Decrypt("name", stored)
+	assert.ErrorIs(t, err, fieldcrypt.ErrMalformedValue)
+
+	// Изменённый шифртекст
+	tampered := stored[:len(stored)-2] + "AA"
+	if tampered == stored {
+		tampered = stored[:len(stored)-2] + "BB"
+	}
+	_, err = enc.Decrypt("email", tampered)
+	assert.Error(t, err)
+
+	_, err = enc.Decrypt("email", "enc:v1:garbage")
+	assert.ErrorIs(t, err, fieldcrypt.ErrMalformedValue)
+}
+
+// TestFieldCrypt_KeyRotation - старые значения читаются, но помечаются для перешифрования
+func TestFieldCrypt_KeyRotation(t *testing.T) {
+	// Arrange
+	keys := map[string]string{"1": randomKey(t), "2": randomKey(t)}
+	indexKey := randomKey(t)
+	before := newTestEncryptor(t, 1, keys, indexKey, "email")
+	after := newTestEncryptor(t, 2, keys, indexKey, "email")
+
+	old, err := before.Encrypt("email", "alice@example.com")
+	require.NoError(t, err)
+
+	// Act
+	plain, err := after.Decrypt("email", old)
+	require.NoError(t, err)
+	rotated, err := after.Encrypt("email", plain)
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "alice@example.com", plain)
+	assert.True(t, after.NeedsRotation("email", old))
+	assert.True(t, after.NeedsRotation("email", "alice@example.com"), "открытый текст шифруемой колонки")
+	assert.False(t, after.NeedsRotation("email", rotated))
+	assert.True(t, after.NeedsRotation("name", old), "колонку исключили из списка")
+	assert.True(t, strings.HasPrefix(rotated, after.CurrentPrefix()))
+
+	// Версию, удалённую из файла ключей, прочитать нельзя
+	onlyNew := newTestEncryptor(t, 2, map[string]string{"2": keys["2"]}, indexKey, "email")
+	_, err = onlyNew.Decrypt("email", old)
+	assert.ErrorIs(t, err, fieldcrypt.ErrUnknownKeyVersion)
+}
+
+// TestFieldCrypt_BlindIndex - детерминирован, зависит от ключа и не зависит от версии KEK
+func TestFieldCrypt_BlindIndex(t *testing.T) {
+	// Arrange
+	keys := map[string]string{"1": randomKey(t), "2": randomKey(t)}
+	indexKey := randomKey(t)
+	enc := newTestEncryptor(t, 1, keys, indexKey, "email")
+	rotated := newTestEncryptor(t, 2, keys, indexKey, "email")
+	otherKey := newTestEncryptor(t, 1, keys, randomKey(t), "email")
+
+	// Act
+	index := enc.BlindIndex("alice@example.com")
+
+	// Assert
+	assert.Len(t, index, 64)
+	assert.Equal(t, index, rotated.BlindIndex("alice@example.com"))
+	assert.NotEqual(t, index, enc.BlindIndex("bob@example.com"))
+	assert.NotEqual(t, index, otherKey.BlindIndex("alice@example.com"))
+	assert.Empty(t, enc.BlindIndex(""))
+}
+
+// TestFieldCrypt_InvalidKeyFile - файл без текущего ключа или с коротким ключом
+func TestFieldCrypt_InvalidKeyFile(t *testing.T) {
+	_, err := fieldcrypt.NewFileKeyProvider(writeKeyFile(t, 2, map[string]string{"1": randomKey(t)}, randomKey(t)))
+	assert.ErrorIs(t, err, fieldcrypt.ErrInvalidKeyFile)
+
+	_, err = fieldcrypt.NewFileKeyProvider(writeKeyFile(t, 1, map[string]string{"1": "c2hvcnQ="}, randomKey(t)))
+	assert.ErrorIs(t, err, fieldcrypt.ErrInvalidKeyFile)
+
+	_, err = fieldcrypt.NewFileKeyProvider(writeKeyFile(t, 1, map[string]string{"1": randomKey(t)}, ""))
+	assert.ErrorIs(t, err, fieldcrypt.ErrInvalidKeyFile)
+}
+
+// TestFieldCrypt_SerializerEncryptsConfiguredFields - тег serializer:encrypted на domain.User
+func TestFieldCrypt_SerializerEncryptsConfiguredFields(t *testing.T) {
+	// Arrange
+	userSchema, err := schema.Parse(&domain.User{}, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+	emailField := userSchema.LookUpField("email")
+	nameField := userSchema.LookUpField("name")
+	require.NotNil(t, emailField.Serializer)
+	require.NotNil(t, nameField.Serializer)
+	require.NotNil(t, userSchema.LookUpField("profile_phone").Serializer, "телефон из профиля")
+
+	ctx := context.Background()
+	serializer := fieldcrypt.Serializer{}
+	fieldcrypt.SetActive(newTestEncryptor(t, 1, map[string]string{"1": randomKey(t)}, randomKey(t), "email"))
+	t.Cleanup(func() { fieldcrypt.SetActive(nil) })
+
+	// Act: запись
+	storedEmail, err := serializer.Value(ctx, emailField, reflect.Value{}, "alice@example.com")
+	require.NoError(t, err)
+	storedName, err := serializer.Value(ctx, nameField, reflect.Value{}, "Alice")
+	require.NoError(t, err)
+
+	// Assert: шифруется только email
+	assert.True(t, fieldcrypt.IsEncrypted(storedEmail.(string)))
+	assert.Equal(t, "Alice", storedName)
+
+	// Act: чтение
+	var user domain.User
+	require.NoError(t, serializer.Scan(ctx, emailField, reflect.ValueOf(&user), storedEmail))
+	require.NoError(t, serializer.Scan(ctx, nameField, reflect.ValueOf(&user), []byte("Alice")))
+	assert.Equal(t, "alice@example.com", user.Email)
+	assert.Equal(t, "Alice", user.Name)
+
+	// Без ключей зашифрованное значение не читается
+	fieldcrypt.SetActive(nil)
+	assert.ErrorIs(t, serializer.Scan(ctx, emailField, reflect.ValueOf(&user), storedEmail), fieldcrypt.ErrNoKeys)
+}
+
+// ================================================================
+// ПЕРЕШИФРОВАНИЕ
+// ================================================================
+
+// MockFieldEncryptionRepository - мок перешифрования
+type MockFieldEncryptionRepository struct {
+	mock.Mock
+}
+
+func (m *MockFieldEncryptionRepository) ReencryptUsers(limit int) (int, error) {
+	args := m.Called(limit)
+	return args.Int(0), args.Error(1)
+}
+
+// TestFieldEncryption_ReencryptProcessesAllBatches - порции до первой неполной
+func TestFieldEncryption_ReencryptProcessesAllBatches(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockFieldEncryptionRepository)
+	mockRepo.On("ReencryptUsers", 200).Return(200, nil).Twice()
+	mockRepo.On("ReencryptUsers", 200).Return(13, nil).Once()
+	encryptionService := service.NewFieldEncryptionService(mockRepo)
+
+	// Act
+	count, err := encryptionService.Reencrypt()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 413, count)
+	mockRepo.AssertNumberOfCalls(t, "ReencryptUsers", 3)
+}
@@ -0,0 +1,268 @@
+package unit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/pkg/jwt"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// MOCK GROUP REPOSITORY
+// ================================================================
+
+// MockGroupRepository - мок репозитория групп
+type MockGroupRepository struct {
+	mock.Mock
+	TenantID uint // организация последнего ForTenant
+}
+
+func (m *MockGroupRepository) Create(group *domain.Group) error {
+	return m.Called(group).Error(0)
+}
+
+func (m *MockGroupRepository) FindByID(id uint) (*domain.Group, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Group), args.Error(1)
+}
+
+func (m *MockGroupRepository) FindByName(name string) (*domain.Group, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Group), args.Error(1)
+}
+
+func (m *MockGroupRepository) FindAll() ([]domain.Group, error) {
+	args := m.Called()
+	return args.Get(0).([]domain.Group), args.Error(1)
+}
+
+func (m *MockGroupRepository) Update(group *domain.Group) error {
+	return m.Called(group).Error(0)
+}
+
+func (m *MockGroupRepository) Delete(id uint) error {
+	return m.Called(id).Error(0)
+}
+
+func (m *MockGroupRepository) SubtreeIDs(id uint) ([]uint, error) {
+	args := m.Called(id)
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockGroupRepository) AddMember(groupID, userID uint) error {
+	return m.Called(groupID, userID).Error(0)
+}
+
+func (m *MockGroupRepository) RemoveMember(groupID, userID uint) error {
+	return m.Called(groupID, userID).Error(0)
+}
+
+func (m *MockGroupRepository) ListMembers(groupID uint) ([]domain.User, error) {
+	args := m.Called(groupID)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockGroupRepository) AddRole(groupID uint, role string) error {
+	return m.Called(groupID, role).Error(0)
+}
+
+func (m *MockGroupRepository) RemoveRole(groupID uint, role string) error {
+	return m.Called(groupID, role).Error(0)
+}
+
+func (m *MockGroupRepository) RolesForUser(userID uint) ([]string, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// ForTenant - тот же мок, запоминает организацию
+func (m *MockGroupRepository) ForTenant(orgID uint) repository.GroupRepository {
+	m.TenantID = orgID
+	return m
+}
+
+// MockRoleProvider - мок источника ролей групп для middleware
+type MockRoleProvider struct {
+	mock.Mock
+}
+
+func (m *MockRoleProvider) InheritedRoles(userID, orgID uint) ([]string, error) {
+	args := m.Called(userID, orgID)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// ================================================================
+// ТЕСТЫ GROUPS
+// ================================================================
+
+// TestGroupService_PreventsCycles - группу нельзя вложить в саму себя или в своего потомка
+func TestGroupService_PreventsCycles(t *testing.T) {
+	// Arrange: engineering (1) → backend (2) → payments (3)
+	mockRepo := new(MockGroupRepository)
+	groupService := service.NewGroupService(mockRepo, new(MockUserRepository), nil)
+
+	root := uint(1)
+	backend := uint(2)
+	mockRepo.On("FindByID", uint(1)).Return(&domain.Group{ID: 1, Name: "engineering"}, nil)
+	mockRepo.On("FindByID", uint(2)).Return(&domain.Group{ID: 2, Name: "backend", ParentID: &root}, nil)
+	mockRepo.On("FindByID", uint(3)).Return(&domain.Group{ID: 3, Name: "payments", ParentID: &backend}, nil)
+	mockRepo.On("FindByID", mock.Anything).Return(nil, repository.ErrGroupRecordNotFound)
+	mockRepo.On("SubtreeIDs", uint(1)).Return([]uint{1, 2, 3}, nil)
+	mockRepo.On("SubtreeIDs", uint(3)).Return([]uint{3}, nil)
+	mockRepo.On("Update", mock.Anything).Return(nil)
+
+	parent := func(id uint) *domain.UpdateGroupRequest {
+		return &domain.UpdateGroupRequest{ParentID: &id}
+	}
+
+	// Act & Assert
+	_, err := groupService.Update(1, parent(3))
+	assert.ErrorIs(t, err, service.ErrGroupCycle, "корень нельзя вложить во внука")
+
+	_, err = groupService.Update(1, parent(1))
+	assert.ErrorIs(t, err, service.ErrGroupCycle, "группу нельзя вложить в саму себя")
+
+	_, err = groupService.Update(3, parent(42))
+	assert.ErrorIs(t, err, service.ErrGroupNotFound)
+
+	group, err := groupService.Update(3, parent(1))
+	require.NoError(t, err)
+	require.NotNil(t, group.ParentID)
+	assert.Equal(t, uint(1), *group.ParentID)
+
+	group, err = groupService.Update(3, parent(0))
+	require.NoError(t, err)
+	assert.Nil(t, group.ParentID, "0 - группа верхнего уровня")
+	mockRepo.AssertNumberOfCalls(t, "Update", 2)
+}
+
+// TestGroupService_GrantRole - имя роли проверяется, выдача пишется в журнал
+func TestGroupService_GrantRole(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockGroupRepository)
+	mockAudit := new(MockAuditService)
+	groupService := service.NewGroupService(mockRepo, new(MockUserRepository), mockAudit)
+
+	mockRepo.On("FindByID", uint(4)).Return(&domain.Group{ID: 4, Name: "support", Roles: []string{"support"}}, nil)
+	mockRepo.On("AddRole", uint(4), "support").Return(nil)
+	mockRepo.On("RemoveRole", uint(4), "admin").Return(repository.ErrGroupRecordNotFound)
+	mockAudit.On("Record", mock.MatchedBy(func(e *domain.AuditEvent) bool {
+		return e.Action == domain.AuditActionGroupRoleGranted && e.Details == "group 4 support: role support" &&
+			e.ActorID != nil && *e.ActorID == 1
+	})).Return()
+
+	// Act & Assert
+	for _, role := range []string{"", "Admin", "a", "role with spaces", "1admin"} {
+		_, err := groupService.GrantRole(4, role, 1, domain.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidGroupRole, role)
+	}
+
+	group, err := groupService.GrantRole(4, "support", 1, domain.ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support"}, group.Roles)
+
+	_, err = groupService.RevokeRole(4, "admin", 1, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrGroupRoleNotFound)
+
+	mockRepo.AssertNumberOfCalls(t, "AddRole", 1)
+	mockAudit.AssertNumberOfCalls(t, "Record", 1)
+}
+
+// TestGroupService_MembersOfTenant - в группу добавляются только пользователи её организации
+func TestGroupService_MembersOfTenant(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockGroupRepository)
+	mockUsers := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	groupService := service.NewGroupService(mockRepo, mockUsers, mockAudit).ForTenant(2)
+
+	mockRepo.On("FindByID", uint(5)).Return(&domain.Group{ID: 5, OrganizationID: 2, Name: "ops"}, nil)
+	mockUsers.On("FindByID", uint(7)).Return(&domain.User{ID: 7}, nil)
+	mockUsers.On("FindByID", uint(8)).Return(nil, errors.New("пользователь не найден"))
+	mockRepo.On("AddMember", uint(5), uint(7)).Return(nil)
+	mockRepo.On("RemoveMember", uint(5), uint(8)).Return(repository.ErrGroupRecordNotFound)
+	mockAudit.On("Record", mock.MatchedBy(func(e *domain.AuditEvent) bool {
+		return e.Action == domain.AuditActionGroupMemberAdded && e.TargetID != nil && *e.TargetID == 7
+	})).Return()
+
+	// Act & Assert
+	assert.Equal(t, uint(2), mockRepo.TenantID)
+	assert.Equal(t, uint(2), mockUsers.TenantID)
+
+	require.NoError(t, groupService.AddMember(5, 7, 1, domain.ClientInfo{}))
+
+	err := groupService.AddMember(5, 8, 1, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrGroupUserNotFound, "пользователь другой организации")
+
+	err = groupService.RemoveMember(5, 8, 1, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrGroupMemberNotFound)
+
+	mockRepo.AssertNumberOfCalls(t, "AddMember", 1)
+	mockAudit.AssertNumberOfCalls(t, "Record", 1)
+}
+
+// TestRequireRole_InheritedFromGroups - роль группы проходит RequireRole наравне с собственной
+func TestRequireRole_InheritedFromGroups(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	provider := new(MockRoleProvider)
+	provider.On("InheritedRoles", uint(7), uint(0)).Return([]string{"admin", "support"}, nil)
+	provider.On("InheritedRoles", uint(8), uint(0)).Return([]string{"support"}, nil)
+	provider.On("InheritedRoles", uint(9), uint(0)).Return([]string(nil), errors.New("db down"))
+	provider.On("InheritedRoles", uint(10), uint(0)).Return([]string{}, nil)
+
+	router := gin.New()
+	router.Use(middleware.InheritedRoles(provider))
+	router.GET("/admin",
+		middleware.AuthMiddleware(cfg),
+		middleware.RequireRole("support"),
+		middleware.RequireRole("admin"),
+		func(c *gin.Context) { c.Status(http.StatusOK) },
+	)
+	router.GET("/direct",
+		middleware.AuthMiddleware(cfg),
+		middleware.RequireRole("admin"),
+		func(c *gin.Context) { c.Status(http.StatusOK) },
+	)
+
+	request := func(path string, userID uint, role string) int {
+		token, err := jwt.GenerateTokenFromClaims(jwt.Claims{UserID: userID, Role: role}, "test-secret", time.Hour)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Act & Assert
+	assert.Equal(t, http.StatusOK, request("/admin", 7, "user"), "admin и support от групп")
+	assert.Equal(t, http.StatusForbidden, request("/admin", 8, "user"), "только support от группы")
+	assert.Equal(t, http.StatusForbidden, request("/admin", 9, "user"), "ошибка провайдера не расширяет доступ")
+	assert.Equal(t, http.StatusForbidden, request("/admin", 10, "admin"), "собственный admin, но нет support")
+	assert.Equal(t, http.StatusOK, request("/direct", 11, "admin"), "собственной роли достаточно")
+
+	// Роли загружаются не больше одного раза на запрос и только если собственной роли не хватило
+	provider.AssertNumberOfCalls(t, "InheritedRoles", 4)
+	provider.AssertNotCalled(t, "InheritedRoles", uint(11), mock.Anything)
+}
@@ -0,0 +1,354 @@
+package unit
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/handler"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/pkg/jwt"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// ТЕСТЫ IDEMPOTENCY-KEY
+// ================================================================
+
+// idempotencyRouter - POST /items с Idempotency middleware и хранилищем в памяти
+// handle вызывается на каждое выполнение запроса (не на повтор)
+func idempotencyRouter(handle func(c *gin.Context)) *gin.Engine {
+	return idempotencyRouterWithConfig(&config.Config{JWTSecret: "test-secret", IdempotencyTTL: "1h"}, handle)
+}
+
+// idempotencyRouterWithConfig - то же с заданной конфигурацией
+func idempotencyRouterWithConfig(cfg *config.Config, handle func(c *gin.Context)) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	store := service.NewIdempotencyService(repository.NewMemoryIdempotencyRepository(), cfg)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Header("X-Request-ID", "outer") // выставлен до middleware - не сохраняется
+		c.Next()
+	})
+	router.Use(middleware.Idempotency(store, cfg))
+	router.POST("/items", handle)
+	router.GET("/items", handle)
+	return router
+}
+
+// idempotencyRequest - запрос с ключом и токеном (пустые - без заголовка)
+func idempotencyRequest(router *gin.Engine, method, key, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestIdempotency_ReplaysFirstResponse - повтор получает первый ответ, handler не выполняется
+func TestIdempotency_ReplaysFirstResponse(t *testing.T) {
+	// Arrange
+	var calls int32
+	router := idempotencyRouter(func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.Header("Location", "/items/1")
+		c.JSON(http.StatusCreated, gin.H{"call": n})
+	})
+	body := `{"email": "alice@example.com"}`
+
+	// Act
+	first := idempotencyRequest(router, http.MethodPost, "key-1", "", body)
+	retry := idempotencyRequest(router, http.MethodPost, "key-1", "", body)
+
+	// Assert
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	require.Equal(t, http.StatusCreated, retry.Code)
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "/items/1", retry.Header().Get("Location"))
+	assert.Equal(t, "application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+	assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+	assert.Empty(t, first.Header().Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, []string{"outer"}, retry.Header().Values("X-Request-ID"))
+
+	// Тот же ключ с другим телом - ошибка клиента
+	assert.Equal(t, http.StatusUnprocessableEntity, idempotencyRequest(router, http.MethodPost, "key-1", "", `{"email": "bob@example.com"}`).Code)
+
+	// Без ключа, с другим ключом и не POST - выполняется каждый раз
+	idempotencyRequest(router, http.MethodPost, "", "", body)
+	idempotencyRequest(router, http.MethodPost, "key-2", "", body)
+	idempotencyRequest(router, http.MethodGet, "key-1", "", "")
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+
+	// Недопустимый ключ
+	assert.Equal(t, http.StatusBadRequest, idempotencyRequest(router, http.MethodPost, strings.Repeat("k", 256), "", body).Code)
+}
+
+// TestIdempotency_KeyScopedByPrincipal - ключ одного пользователя не отдаёт ответ другому
+func TestIdempotency_KeyScopedByPrincipal(t *testing.T) {
+	// Arrange
+	var calls int32
+	router := idempotencyRouter(func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	token := func(userID uint) string {
+		token, err := jwt.GenerateTokenFromClaims(jwt.Claims{UserID: userID, Role: "user"}, "test-secret", time.Hour)
+		require.NoError(t, err)
+		return token
+	}
+	alice, bob := token(1), token(2)
+
+	// Act
+	idempotencyRequest(router, http.MethodPost, "shared", alice, `{}`)
+	idempotencyRequest(router, http.MethodPost, "shared", bob, `{}`)
+	idempotencyRequest(router, http.MethodPost, "shared", "", `{}`)
+	idempotencyRequest(router, http.MethodPost, "shared", token(1), `{}`) // новый токен того же пользователя - повтор
+
+	// Assert
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+// TestIdempotency_InFlightAndFailures - 409 во время выполнения, 5xx не сохраняется
+func TestIdempotency_InFlightAndFailures(t *testing.T) {
+	// Arrange
+	var calls int32
+	entered, finish := make(chan struct{}), make(chan struct{})
+	router := idempotencyRouter(func(c *gin.Context) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			close(entered)
+			<-finish
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "temporary"})
+		default:
+			c.JSON(http.StatusCreated, gin.H{})
+		}
+	})
+
+	// Act & Assert: первый запрос ещё выполняется
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- idempotencyRequest(router, http.MethodPost, "key", "", `{}`) }()
+	<-entered
+	rec := idempotencyRequest(router, http.MethodPost, "key", "", `{}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusUnprocessableEntity, idempotencyRequest(router, http.MethodPost, "key", "", `{"a": 1}`).Code)
+
+	// 500 не сохраняется - повтор выполняется заново, его ответ уже сохраняется
+	close(finish)
+	assert.Equal(t, http.StatusInternalServerError, (<-done).Code)
+	assert.Equal(t, http.StatusCreated, idempotencyRequest(router, http.MethodPost, "key", "", `{}`).Code)
+	assert.Equal(t, http.StatusCreated, idempotencyRequest(router, http.MethodPost, "key", "", `{}`).Code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+// TestIdempotency_BodyLimits - длинное тело не буферизуется, но сравнивается по хешу,
+// недочитанное тело и длинный ответ не сохраняются
+func TestIdempotency_BodyLimits(t *testing.T) {
+	// Arrange
+	var calls int32
+	router := idempotencyRouterWithConfig(&config.Config{JWTSecret: "test-secret", IdempotencyTTL: "1h", IdempotencyMaxBodySize: 64}, func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		if c.GetHeader("X-Reject") != "" {
+			// Свой лимит handler: тело не дочитано
+			c.String(http.StatusRequestEntityTooLarge, "")
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		require.NoError(t, err)
+		if len(body) > 64 {
+			c.String(http.StatusCreated, "%d", len(body))
+			return
+		}
+		c.String(http.StatusCreated, strings.Repeat(string(body), 2))
+	})
+	rejected := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+		req.Header.Set("X-Reject", "1")
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act & Assert: тело больше лимита доходит до handler целиком, повтор - по хешу тела
+	upload := strings.Repeat("c", 1000)
+	assert.Equal(t, "1000", idempotencyRequest(router, http.MethodPost, "upload", "", upload).Body.String())
+	retry := idempotencyRequest(router, http.MethodPost, "upload", "", upload)
+	assert.Equal(t, "1000", retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Другое длинное тело с тем же ключом - ошибка клиента
+	assert.Equal(t, http.StatusUnprocessableEntity, idempotencyRequest(router, http.MethodPost, "upload", "", strings.Repeat("d", 1000)).Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Handler отверг тело, не дочитав - ответ не сохраняется
+	rejected("reject", strings.Repeat("e", 1000))
+	assert.Empty(t, rejected("reject", strings.Repeat("e", 1000)).Header().Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// Ответ (2 × 40 байт) больше лимита - отдаётся, но не сохраняется
+	body := strings.Repeat("b", 40)
+	assert.Equal(t, strings.Repeat(body, 2), idempotencyRequest(router, http.MethodPost, "long", "", body).Body.String())
+	retry = idempotencyRequest(router, http.MethodPost, "long", "", body)
+	assert.Empty(t, retry.Header().Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+}
+
+// TestIdempotency_RegisterRetry - повтор регистрации получает 201 с новым токеном,
+// пользователь создаётся один раз
+func TestIdempotency_RegisterRetry(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h", IdempotencyTTL: "1h"}
+	mockRepo := new(MockUserRepository)
+	authHandler := handler.NewAuthHandler(service.NewAuthService(mockRepo, cfg), nil)
+
+	router := gin.New()
+	router.Use(middleware.Idempotency(service.NewIdempotencyService(repository.NewMemoryIdempotencyRepository(), cfg), cfg))
+	router.POST("/auth/register", authHandler.Register)
+
+	created := &domain.User{}
+	mockRepo.On("FindByEmail", "alice@example.com").Return(nil, repository.ErrUserNotFound).Once()
+	mockRepo.On("Create", mock.AnythingOfType("*domain.User")).Run(func(args mock.Arguments) {
+		user := args.Get(0).(*domain.User)
+		user.ID = 42
+		*created = *user
+	}).Return(nil).Once()
+	mockRepo.On("FindByID", uint(42)).Return(created, nil)
+
+	register := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(`{"email": "alice@example.com", "name": "Alice", "password": "Str0ng!Passw0rd"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.IdempotencyKeyHeader, "signup-1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act
+	first := register()
+	retry := register()
+
+	// Assert
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+	require.Equal(t, http.StatusCreated, retry.Code, retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, "no-store", retry.Header().Get("Cache-Control"))
+
+	var firstBody, retryBody domain.AuthResponse
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &firstBody))
+	require.NoError(t, json.Unmarshal(retry.Body.Bytes(), &retryBody))
+	assert.Equal(t, uint(42), retryBody.User.ID)
+	assert.Equal(t, firstBody.User.Email, retryBody.User.Email)
+	assert.NotEmpty(t, retryBody.Token)
+
+	mockRepo.AssertNumberOfCalls(t, "Create", 1)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestIdempotencyService_LockLease - занятый ключ освобождается через IDEMPOTENCY_LOCK_TTL,
+// сохранённый ответ живёт IDEMPOTENCY_TTL
+func TestIdempotencyService_LockLease(t *testing.T) {
+	// Arrange
+	store := service.NewIdempotencyService(repository.NewMemoryIdempotencyRepository(), &config.Config{
+		IdempotencyTTL:     "1h",
+		IdempotencyLockTTL: "10ms",
+	})
+
+	// Act & Assert: запрос "упал" без Complete и Release
+	record, err := store.Begin("k", "f")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+	_, err = store.Begin("k", "f")
+	assert.ErrorIs(t, err, domain.ErrIdempotencyInProgress)
+
+	time.Sleep(20 * time.Millisecond)
+	record, err = store.Begin("k", "f")
+	require.NoError(t, err)
+	assert.Nil(t, record, "после срока аренды ключ свободен")
+
+	// Ответ сохранён - живёт дольше аренды
+	require.NoError(t, store.Complete("k", "f", http.StatusCreated, nil, []byte(`{}`)))
+	time.Sleep(20 * time.Millisecond)
+	record, err = store.Begin("k", "f")
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, http.StatusCreated, record.Status)
+}
+
+// TestIdempotency_LongRequestKeepsLock - аренда продлевается, пока handler выполняется:
+// повтор долгого запроса после IDEMPOTENCY_LOCK_TTL получает 409, а не выполняется параллельно
+func TestIdempotency_LongRequestKeepsLock(t *testing.T) {
+	// Arrange
+	var calls int32
+	entered, finish := make(chan struct{}), make(chan struct{})
+	router := idempotencyRouterWithConfig(&config.Config{JWTSecret: "test-secret", IdempotencyTTL: "1h", IdempotencyLockTTL: "30ms"}, func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(entered)
+			<-finish
+		}
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	// Act: первый запрос выполняется дольше трёх сроков аренды
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- idempotencyRequest(router, http.MethodPost, "import", "", `{}`) }()
+	<-entered
+	time.Sleep(100 * time.Millisecond)
+	retry := idempotencyRequest(router, http.MethodPost, "import", "", `{}`)
+	close(finish)
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, retry.Code)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+// TestIdempotencyRepository_Expiry - устаревший ключ свободен, DeleteExpired его удаляет
+func TestIdempotencyRepository_Expiry(t *testing.T) {
+	// Arrange
+	repo := repository.NewMemoryIdempotencyRepository()
+	now := time.Now()
+	record := &domain.IdempotencyRecord{Key: "k", Fingerprint: "f1", ExpiresAt: now.Add(time.Hour)}
+
+	// Act & Assert
+	existing, err := repo.Acquire(record, now)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+	require.NoError(t, repo.Complete(&domain.IdempotencyRecord{Key: "k", Status: http.StatusCreated}))
+	require.NoError(t, repo.Release("k"), "ключ с ответом не освобождается")
+
+	existing, err = repo.Acquire(&domain.IdempotencyRecord{Key: "k", Fingerprint: "f2", ExpiresAt: now.Add(2 * time.Hour)}, now)
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	assert.Equal(t, "f1", existing.Fingerprint)
+	assert.True(t, existing.Completed())
+
+	count, err := repo.DeleteExpired(now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	existing, err = repo.Acquire(&domain.IdempotencyRecord{Key: "k", Fingerprint: "f2", ExpiresAt: now.Add(3 * time.Hour)}, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+}
@@ -0,0 +1,186 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/pkg/jwt"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// ТЕСТЫ IMPERSONATION
+// ================================================================
+
+// TestImpersonate_IssuesActorToken - токен пользователя с claim "act" и коротким сроком
+func TestImpersonate_IssuesActorToken(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	cfg := &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h", ImpersonationTTL: "10m"}
+
+	admin := &domain.User{ID: 1, Email: "support@example.com", Role: "admin"}
+	user := &domain.User{ID: 7, Email: "frank@example.com", Role: "user", TokenVersion: 3}
+	mockRepo.On("FindByID", admin.ID).Return(admin, nil)
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+
+	impersonationService := service.NewImpersonationService(mockRepo, service.NewTokenIssuer(cfg, nil), mockAudit, cfg)
+	mockAudit.On("Record", mock.MatchedBy(func(e *domain.AuditEvent) bool {
+		return e.Action == domain.AuditActionImpersonationStarted &&
+			*e.ActorID == admin.ID && *e.TargetID == user.ID
+	})).Return()
+
+	// Act
+	response, err := impersonationService.Impersonate(admin.ID, user.ID, domain.ClientInfo{IP: "192.0.2.1"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, admin.ID, response.ActorID)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), response.ExpiresAt, time.Minute)
+
+	claims, err := jwt.ValidateToken(response.Token, "test-secret")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.Equal(t, user.TokenVersion, claims.TokenVersion)
+	require.NotNil(t, claims.Actor)
+	assert.Equal(t, admin.ID, claims.Actor.UserID)
+	assert.Equal(t, admin.Email, claims.Actor.Email)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), claims.ExpiresAt.Time, time.Minute)
+	mockAudit.AssertExpectations(t)
+}
+
+// TestImpersonate_Forbidden - нельзя войти от имени администратора, себя или без роли admin
+func TestImpersonate_Forbidden(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	cfg := &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h", ImpersonationTTL: "10m"}
+
+	admin := &domain.User{ID: 1, Email: "support@example.com", Role: "admin"}
+	user := &domain.User{ID: 7, Email: "frank@example.com", Role: "user", TokenVersion: 3}
+	mockRepo.On("FindByID", admin.ID).Return(admin, nil)
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+
+	impersonationService := service.NewImpersonationService(mockRepo, service.NewTokenIssuer(cfg, nil), mockAudit, cfg)
+	otherAdmin := &domain.User{ID: 2, Email: "root@example.com", Role: "admin"}
+	mockRepo.On("FindByID", otherAdmin.ID).Return(otherAdmin, nil)
+	mockRepo.On("FindByID", uint(404)).Return(nil, assert.AnError)
+
+	// Act & Assert
+	_, err := impersonationService.Impersonate(admin.ID, otherAdmin.ID, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrImpersonationForbidden)
+
+	_, err = impersonationService.Impersonate(admin.ID, admin.ID, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrImpersonationForbidden)
+
+	_, err = impersonationService.Impersonate(user.ID, otherAdmin.ID, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrImpersonationForbidden, "обычный пользователь")
+
+	_, err = impersonationService.Impersonate(admin.ID, 404, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrImpersonationTargetNotFound)
+
+	mockAudit.AssertNotCalled(t, "Record", mock.Anything)
+}
+
+// TestImpersonate_GroupAdmins - роль admin от группы засчитывается, как в RequireRole
+func TestImpersonate_GroupAdmins(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	roles := new(MockRoleProvider)
+	cfg := &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h", ImpersonationTTL: "10m"}
+
+	groupAdmin := &domain.User{ID: 2, Email: "lead@example.com", Role: "user"}
+	user := &domain.User{ID: 7, Email: "frank@example.com", Role: "user"}
+	groupTarget := &domain.User{ID: 8, Email: "ops@example.com", Role: "user"}
+	unknown := &domain.User{ID: 9, Email: "db-down@example.com", Role: "user"}
+	for _, u := range []*domain.User{groupAdmin, user, groupTarget, unknown} {
+		mockRepo.On("FindByID", u.ID).Return(u, nil)
+	}
+	roles.On("InheritedRoles", groupAdmin.ID, uint(3)).Return([]string{"admin"}, nil)
+	roles.On("InheritedRoles", user.ID, uint(3)).Return([]string{"support"}, nil)
+	roles.On("InheritedRoles", groupTarget.ID, uint(3)).Return([]string{"admin"}, nil)
+	roles.On("InheritedRoles", unknown.ID, uint(3)).Return([]string(nil), assert.AnError)
+	mockAudit.On("Record", mock.Anything).Return()
+
+	impersonationService := service.NewImpersonationService(mockRepo, service.NewTokenIssuer(cfg, nil), mockAudit, cfg,
+		service.WithImpersonationRoles(roles),
+	).ForTenant(3)
+
+	// Act & Assert: администратор через группу может войти от имени пользователя
+	response, err := impersonationService.Impersonate(groupAdmin.ID, user.ID, domain.ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, groupAdmin.ID, response.ActorID)
+
+	// ...но не от имени администратора через группу
+	_, err = impersonationService.Impersonate(groupAdmin.ID, groupTarget.ID, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrImpersonationForbidden)
+
+	// Роли групп не загрузились - вход запрещён
+	_, err = impersonationService.Impersonate(groupAdmin.ID, unknown.ID, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrImpersonationForbidden)
+	_, err = impersonationService.Impersonate(unknown.ID, user.ID, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrImpersonationForbidden)
+
+	// Токен автора-администратора через группу принимается
+	authService := service.NewAuthService(mockRepo, cfg, service.WithRoleProvider(roles))
+	assert.NoError(t, authService.ValidateClaims(&jwt.Claims{UserID: user.ID, OrganizationID: 3, Actor: &jwt.Actor{UserID: groupAdmin.ID}}))
+	assert.ErrorIs(t, authService.ValidateClaims(&jwt.Claims{UserID: user.ID, OrganizationID: 3, Actor: &jwt.Actor{UserID: unknown.ID}}), service.ErrTokenRevoked)
+}
+
+// TestImpersonation_BlocksSensitiveRoutesAndAudits - чувствительные действия закрыты,
+// каждый запрос с токеном имперсонации пишется в журнал
+func TestImpersonation_BlocksSensitiveRoutesAndAudits(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	mockAudit := new(MockAuditService)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+
+	var recorded []*domain.AuditEvent
+	mockAudit.On("Record", mock.Anything).Run(func(args mock.Arguments) {
+		recorded = append(recorded, args.Get(0).(*domain.AuditEvent))
+	}).Return()
+
+	router := gin.New()
+	router.Use(middleware.ImpersonationAudit(mockAudit))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/me", middleware.AuthMiddleware(cfg), ok)
+	router.POST("/password/change", middleware.AuthMiddleware(cfg), middleware.BlockImpersonation(), ok)
+
+	request := func(method, path string, actor *jwt.Actor) int {
+		token, err := jwt.GenerateTokenFromClaims(jwt.Claims{UserID: 7, Actor: actor}, "test-secret", time.Hour)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	actor := &jwt.Actor{UserID: 1, Email: "support@example.com"}
+
+	// Act & Assert
+	// Собственный токен пользователя - ограничений и записей в журнале нет
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/password/change", nil))
+	assert.Empty(t, recorded)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/me", actor))
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "/password/change", actor))
+
+	require.Len(t, recorded, 2)
+	assert.Equal(t, domain.AuditActionImpersonatedRequest, recorded[0].Action)
+	assert.Equal(t, uint(1), *recorded[0].ActorID)
+	assert.Equal(t, uint(7), *recorded[0].TargetID)
+	assert.Equal(t, "GET /me → 200", recorded[0].Details)
+	assert.Equal(t, "POST /password/change → 403", recorded[1].Details)
+}
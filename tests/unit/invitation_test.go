@@ -0,0 +1,326 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/jwt"
+	"advanced-user-api/internal/pkg/mailer"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// MOCK INVITATION REPOSITORY
+// ================================================================
+
+// MockInvitationRepository - мок репозитория приглашений
+type MockInvitationRepository struct {
+	mock.Mock
+}
+
+func (m *MockInvitationRepository) Create(invitation *domain.Invitation) error {
+	return m.Called(invitation).Error(0)
+}
+
+func (m *MockInvitationRepository) Update(invitation *domain.Invitation) error {
+	return m.Called(invitation).Error(0)
+}
+
+func (m *MockInvitationRepository) FindByID(orgID, id uint) (*domain.Invitation, error) {
+	args := m.Called(orgID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Invitation), args.Error(1)
+}
+
+func (m *MockInvitationRepository) FindByHash(tokenHash string) (*domain.Invitation, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Invitation), args.Error(1)
+}
+
+func (m *MockInvitationRepository) FindPending(orgID uint, email string) (*domain.Invitation, error) {
+	args := m.Called(orgID, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Invitation), args.Error(1)
+}
+
+func (m *MockInvitationRepository) ListPending(orgID uint) ([]domain.Invitation, error) {
+	args := m.Called(orgID)
+	return args.Get(0).([]domain.Invitation), args.Error(1)
+}
+
+func (m *MockInvitationRepository) Respond(id uint, status string, userID *uint, at time.Time) (bool, error) {
+	args := m.Called(id, status, userID, at)
+	return args.Bool(0), args.Error(1)
+}
+
+// ================================================================
+// ТЕСТЫ INVITATIONS
+// ================================================================
+
+// TestInvitation_CreateAndPermissions - в БД хэш токена, в письме ссылка;
+// admin не приглашает владельцев, повторное приглашение - конфликт
+func TestInvitation_CreateAndPermissions(t *testing.T) {
+	// Arrange
+	mockInvitations := new(MockInvitationRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	mockRepo := new(MockUserRepository)
+	mockMailer := new(MockMailer)
+	cfg := &config.Config{
+		JWTSecret:     "test-secret",
+		JWTExpiration: "24h",
+		InvitationURL: "https://app.example.com/invitations/accept",
+		InvitationTTL: "72h",
+	}
+
+	// Организация 2: пользователь 1 - owner, 2 - admin
+	mockOrgs.On("FindMember", uint(2), uint(1)).Return(&domain.Membership{OrganizationID: 2, UserID: 1, Role: domain.OrgRoleOwner}, nil)
+	mockOrgs.On("FindMember", uint(2), uint(2)).Return(&domain.Membership{OrganizationID: 2, UserID: 2, Role: domain.OrgRoleAdmin}, nil)
+	mockOrgs.On("FindByID", uint(2)).Return(&domain.Organization{ID: 2, Slug: "acme", Name: "Acme"}, nil)
+
+	var sent mailer.Message
+	mockMailer.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(0).(mailer.Message)
+	}).Return(nil)
+
+	var stored *domain.Invitation
+	mockInvitations.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*domain.Invitation)
+		stored.ID = 9
+	}).Return(nil)
+
+	authService := service.NewAuthService(mockRepo, cfg)
+	invitationService := service.NewInvitationService(mockInvitations, mockOrgs, mockRepo, authService,
+		service.NewTokenIssuer(cfg, nil), mockMailer, nil, cfg)
+
+	mockRepo.On("FindByEmail", "new@example.com").Return(nil, nil)
+	mockInvitations.On("FindPending", uint(2), "new@example.com").Return(nil, repository.ErrOrganizationRecordNotFound).Once()
+
+	// Act
+	invitation, err := invitationService.Create(2, 2, &domain.CreateInvitationRequest{Email: "New@Example.com"}, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), mockRepo.TenantID, "проверка участников - в организации приглашения")
+	assert.Equal(t, "new@example.com", invitation.Email)
+	assert.Equal(t, domain.OrgRoleMember, invitation.Role)
+	assert.Equal(t, domain.InvitationStatusPending, invitation.Status)
+	assert.WithinDuration(t, time.Now().Add(72*time.Hour), invitation.ExpiresAt, time.Minute)
+
+	token := tokenFromEmail(t, sent.Body)
+	assert.Equal(t, "new@example.com", sent.To)
+	assert.Contains(t, sent.Subject, "Acme")
+	assert.NotContains(t, stored.TokenHash, token)
+
+	_, err = invitationService.Create(2, 2, &domain.CreateInvitationRequest{Email: "boss@example.com", Role: domain.OrgRoleOwner}, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrOrgPermissionDenied, "admin не может пригласить владельца")
+
+	mockInvitations.On("FindPending", uint(2), "new@example.com").Return(invitation, nil)
+	_, err = invitationService.Create(2, 1, &domain.CreateInvitationRequest{Email: "new@example.com"}, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrInvitationAlreadyPending)
+
+	mockOrgs.On("FindMember", uint(2), uint(3)).Return(nil, repository.ErrOrganizationRecordNotFound)
+	_, err = invitationService.Create(2, 3, &domain.CreateInvitationRequest{Email: "x@example.com"}, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrOrgPermissionDenied, "не участник")
+}
+
+// TestInvitation_AcceptWithRegistration - аккаунт создаётся в организации приглашения с его ролью
+func TestInvitation_AcceptWithRegistration(t *testing.T) {
+	// Arrange
+	mockInvitations := new(MockInvitationRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	mockRepo := new(MockUserRepository)
+	mockMailer := new(MockMailer)
+	cfg := &config.Config{
+		JWTSecret:     "test-secret",
+		JWTExpiration: "24h",
+		InvitationURL: "https://app.example.com/invitations/accept",
+		InvitationTTL: "72h",
+	}
+
+	// Организация 2: пользователь 1 - owner, 2 - admin
+	mockOrgs.On("FindMember", uint(2), uint(1)).Return(&domain.Membership{OrganizationID: 2, UserID: 1, Role: domain.OrgRoleOwner}, nil)
+	mockOrgs.On("FindMember", uint(2), uint(2)).Return(&domain.Membership{OrganizationID: 2, UserID: 2, Role: domain.OrgRoleAdmin}, nil)
+	mockOrgs.On("FindByID", uint(2)).Return(&domain.Organization{ID: 2, Slug: "acme", Name: "Acme"}, nil)
+
+	var sent mailer.Message
+	mockMailer.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(0).(mailer.Message)
+	}).Return(nil)
+
+	var stored *domain.Invitation
+	mockInvitations.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*domain.Invitation)
+		stored.ID = 9
+	}).Return(nil)
+
+	authService := service.NewAuthService(mockRepo, cfg)
+	invitationService := service.NewInvitationService(mockInvitations, mockOrgs, mockRepo, authService,
+		service.NewTokenIssuer(cfg, nil), mockMailer, nil, cfg)
+
+	mockRepo.On("FindByEmail", "lead@example.com").Return(nil, nil)
+	mockInvitations.On("FindPending", uint(2), "lead@example.com").Return(nil, repository.ErrOrganizationRecordNotFound)
+	_, err := invitationService.Create(2, 1, &domain.CreateInvitationRequest{Email: "lead@example.com", Role: domain.OrgRoleAdmin}, domain.ClientInfo{})
+	require.NoError(t, err)
+	token := tokenFromEmail(t, sent.Body)
+
+	mockInvitations.On("FindByHash", stored.TokenHash).Return(stored, nil)
+	mockRepo.On("Create", mock.AnythingOfType("*domain.User")).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.User).ID = 40
+	}).Return(nil)
+	mockOrgs.On("UpdateMemberRole", uint(2), uint(40), domain.OrgRoleAdmin).Return(nil)
+	mockInvitations.On("Respond", uint(9), domain.InvitationStatusAccepted, mock.Anything, mock.Anything).Return(true, nil)
+
+	// Act
+	response, err := invitationService.AcceptWithRegistration(&domain.AcceptInvitationRegisterRequest{
+		Token: token, Name: "Team Lead", Password: "Corr3ct-Horse-Battery",
+	}, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "lead@example.com", response.User.Email, "email из приглашения")
+
+	claims, err := jwt.ValidateToken(response.Token, "test-secret")
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), claims.OrganizationID)
+	mockOrgs.AssertCalled(t, "UpdateMemberRole", uint(2), uint(40), domain.OrgRoleAdmin)
+	mockInvitations.AssertCalled(t, "Respond", uint(9), domain.InvitationStatusAccepted, mock.Anything, mock.Anything)
+
+	// Неверный или просроченный токен - одна и та же ошибка
+	mockInvitations.On("FindByHash", mock.Anything).Return(nil, repository.ErrOrganizationRecordNotFound)
+	_, err = invitationService.AcceptWithRegistration(&domain.AcceptInvitationRegisterRequest{Token: "bogus", Name: "X", Password: "y"}, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrInvitationInvalid)
+
+	stored.ExpiresAt = time.Now().Add(-time.Minute)
+	err = invitationService.Decline(token, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrInvitationInvalid)
+}
+
+// TestInvitation_AcceptAsExistingUser - существующий аккаунт вступает в организацию,
+// только если email совпадает с приглашением
+func TestInvitation_AcceptAsExistingUser(t *testing.T) {
+	// Arrange
+	mockInvitations := new(MockInvitationRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	mockRepo := new(MockUserRepository)
+	mockMailer := new(MockMailer)
+	cfg := &config.Config{
+		JWTSecret:     "test-secret",
+		JWTExpiration: "24h",
+		InvitationURL: "https://app.example.com/invitations/accept",
+		InvitationTTL: "72h",
+	}
+
+	// Организация 2: пользователь 1 - owner, 2 - admin
+	mockOrgs.On("FindMember", uint(2), uint(1)).Return(&domain.Membership{OrganizationID: 2, UserID: 1, Role: domain.OrgRoleOwner}, nil)
+	mockOrgs.On("FindMember", uint(2), uint(2)).Return(&domain.Membership{OrganizationID: 2, UserID: 2, Role: domain.OrgRoleAdmin}, nil)
+	mockOrgs.On("FindByID", uint(2)).Return(&domain.Organization{ID: 2, Slug: "acme", Name: "Acme"}, nil)
+	mockMailer.On("Send", mock.Anything).Return(nil)
+
+	authService := service.NewAuthService(mockRepo, cfg)
+	invitationService := service.NewInvitationService(mockInvitations, mockOrgs, mockRepo, authService,
+		service.NewTokenIssuer(cfg, nil), mockMailer, nil, cfg)
+
+	token := "invitation-token"
+	invitation := &domain.Invitation{
+		ID: 9, OrganizationID: 2, Email: "carol@example.com", Role: domain.OrgRoleMember,
+		Status: domain.InvitationStatusPending, ExpiresAt: time.Now().Add(time.Hour),
+	}
+	carol := &domain.User{ID: 30, OrganizationID: 1, Email: "Carol@example.com"}
+	mallory := &domain.User{ID: 31, OrganizationID: 1, Email: "mallory@example.com"}
+
+	mockInvitations.On("FindByHash", mock.Anything).Return(invitation, nil)
+	mockRepo.On("FindByID", carol.ID).Return(carol, nil)
+	mockRepo.On("FindByID", mallory.ID).Return(mallory, nil)
+	mockRepo.On("FindByEmail", carol.Email).Return(nil, nil)
+	mockOrgs.On("FindMember", uint(2), carol.ID).Return(nil, repository.ErrOrganizationRecordNotFound)
+	mockOrgs.On("AddMember", mock.MatchedBy(func(member *domain.Membership) bool {
+		return member.OrganizationID == 2 && member.UserID == carol.ID && member.Role == domain.OrgRoleMember
+	})).Return(nil)
+	mockInvitations.On("Respond", uint(9), domain.InvitationStatusAccepted, mock.Anything, mock.Anything).Return(true, nil)
+
+	// Act & Assert
+	_, err := invitationService.AcceptAsUser(mallory.ID, token, domain.ClientInfo{OrganizationID: 1})
+	assert.ErrorIs(t, err, service.ErrInvitationEmailMismatch)
+	mockOrgs.AssertNotCalled(t, "AddMember", mock.Anything)
+
+	response, err := invitationService.AcceptAsUser(carol.ID, token, domain.ClientInfo{OrganizationID: 1})
+	require.NoError(t, err)
+
+	claims, err := jwt.ValidateToken(response.Token, "test-secret")
+	require.NoError(t, err)
+	assert.Equal(t, carol.ID, claims.UserID)
+	assert.Equal(t, uint(2), claims.OrganizationID, "токен для организации приглашения")
+	mockOrgs.AssertNumberOfCalls(t, "AddMember", 1)
+	mockInvitations.AssertCalled(t, "Respond", uint(9), domain.InvitationStatusAccepted, mock.Anything, mock.Anything)
+}
+
+// TestInvitation_ResendAndRevoke - повторная отправка меняет токен, отозванное не отправить
+func TestInvitation_ResendAndRevoke(t *testing.T) {
+	// Arrange
+	mockInvitations := new(MockInvitationRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	mockRepo := new(MockUserRepository)
+	mockMailer := new(MockMailer)
+	cfg := &config.Config{
+		JWTSecret:     "test-secret",
+		JWTExpiration: "24h",
+		InvitationURL: "https://app.example.com/invitations/accept",
+		InvitationTTL: "72h",
+	}
+
+	// Организация 2: пользователь 1 - owner, 2 - admin
+	mockOrgs.On("FindMember", uint(2), uint(1)).Return(&domain.Membership{OrganizationID: 2, UserID: 1, Role: domain.OrgRoleOwner}, nil)
+	mockOrgs.On("FindMember", uint(2), uint(2)).Return(&domain.Membership{OrganizationID: 2, UserID: 2, Role: domain.OrgRoleAdmin}, nil)
+	mockOrgs.On("FindByID", uint(2)).Return(&domain.Organization{ID: 2, Slug: "acme", Name: "Acme"}, nil)
+
+	var sent mailer.Message
+	mockMailer.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(0).(mailer.Message)
+	}).Return(nil)
+
+	authService := service.NewAuthService(mockRepo, cfg)
+	invitationService := service.NewInvitationService(mockInvitations, mockOrgs, mockRepo, authService,
+		service.NewTokenIssuer(cfg, nil), mockMailer, nil, cfg)
+
+	invitation := &domain.Invitation{
+		ID: 9, OrganizationID: 2, Email: "dan@example.com", Role: domain.OrgRoleMember,
+		TokenHash: "old-hash", Status: domain.InvitationStatusPending,
+		ExpiresAt: time.Now().Add(-time.Hour), SendCount: 1,
+	}
+	mockInvitations.On("FindByID", uint(2), uint(9)).Return(invitation, nil)
+	mockInvitations.On("Update", invitation).Return(nil)
+	mockInvitations.On("Respond", uint(9), domain.InvitationStatusRevoked, (*uint)(nil), mock.Anything).Return(true, nil).Run(func(mock.Arguments) {
+		invitation.Status = domain.InvitationStatusRevoked
+	})
+
+	// Act & Assert
+	resent, err := invitationService.Resend(2, 2, 9, domain.ClientInfo{})
+	require.NoError(t, err)
+	assert.NotEqual(t, "old-hash", resent.TokenHash)
+	assert.Equal(t, 2, resent.SendCount)
+	assert.True(t, resent.ExpiresAt.After(time.Now()), "срок продлён")
+	assert.NotEmpty(t, tokenFromEmail(t, sent.Body))
+
+	require.NoError(t, invitationService.Revoke(2, 1, 9, domain.ClientInfo{}))
+
+	_, err = invitationService.Resend(2, 1, 9, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrInvitationNotPending)
+
+	mockInvitations.On("FindByID", uint(2), uint(404)).Return(nil, repository.ErrOrganizationRecordNotFound)
+	err = invitationService.Revoke(2, 1, 404, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrInvitationNotFound)
+}
@@ -0,0 +1,361 @@
+package unit
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// FAKE LDAP SERVER - Минимальный LDAP сервер внутри теста
+// ================================================================
+// Понимает только то, что нужно для search-and-bind:
+// BindRequest, SearchRequest и UnbindRequest
+
+// fakeLDAPEntry - запись каталога
+type fakeLDAPEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// fakeLDAPServer - LDAP сервер на случайном порту 127.0.0.1
+type fakeLDAPServer struct {
+	listener net.Listener
+	entries  []fakeLDAPEntry
+
+	mu    sync.Mutex
+	binds []string // DN всех успешных bind (для проверок)
+}
+
+// startFakeLDAP - запускает сервер и останавливает его в конце теста
+func startFakeLDAP(t *testing.T, entries ...fakeLDAPEntry) *fakeLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &fakeLDAPServer{listener: listener, entries: entries}
+	go srv.serve()
+	t.Cleanup(func() { listener.Close() })
+
+	return srv
+}
+
+// URL - адрес сервера для config.Config
+func (s *fakeLDAPServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *fakeLDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return // Listener закрыт
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle - обрабатывает LDAP сообщения одного соединения
+func (s *fakeLDAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	bound := false
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		messageID := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case goldap.ApplicationBindRequest:
+			dn := op.Children[1].Value.(string)
+			pass := op.Children[2].Data.String()
+			code := uint16(goldap.LDAPResultInvalidCredentials)
+			if s.checkBind(dn, pass) {
+				code = goldap.LDAPResultSuccess
+				bound = true
+			}
+			s.write(conn, messageID, goldap.ApplicationBindResponse, code)
+
+		case goldap.ApplicationSearchRequest:
+			if !bound {
+				s.write(conn, messageID, goldap.ApplicationSearchResultDone, goldap.LDAPResultInsufficientAccessRights)
+				continue
+			}
+			filter, _ := goldap.DecompileFilter(op.Children[6])
+			for _, entry := range s.entries {
+				if entry.matches(filter) {
+					s.writeEntry(conn, messageID, entry)
+				}
+			}
+			s.write(conn, messageID, goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess)
+
+		case goldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+// boundDNs - копия списка успешных bind
+func (s *fakeLDAPServer) boundDNs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+// checkBind - сервисная учётная запись "cn=svc" или запись каталога
+func (s *fakeLDAPServer) checkBind(dn, pass string) bool {
+	ok := dn == "cn=svc,dc=example,dc=com" && pass == "svc-secret"
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.dn, dn) && entry.password == pass {
+			ok = true
+		}
+	}
+	if ok {
+		s.mu.Lock()
+		s.binds = append(s.binds, dn)
+		s.mu.Unlock()
+	}
+	return ok
+}
+
+// matches - достаточно для фильтра "(&(objectClass=person)(mail=...))"
+func (e fakeLDAPEntry) matches(filter string) bool {
+	for _, mail := range e.attrs["mail"] {
+		if strings.Contains(strings.ToLower(filter), "(mail="+strings.ToLower(mail)+")") {
+			return true
+		}
+	}
+	return false
+}
+
+// write - отправляет LDAPResult (BindResponse / SearchResultDone)
+func (s *fakeLDAPServer) write(conn net.Conn, messageID int64, tag ber.Tag, code uint16) {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "resultCode"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	s.send(conn, messageID, response)
+}
+
+// writeEntry - отправляет SearchResultEntry
+func (s *fakeLDAPServer) writeEntry(conn net.Conn, messageID int64, entry fakeLDAPEntry) {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "Entry")
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "objectName"))
+
+	attributes := ber.NewSequence("attributes")
+	for name, values := range entry.attrs {
+		attr := ber.NewSequence("attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, value := range values {
+			vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+		}
+		attr.AppendChild(vals)
+		attributes.AppendChild(attr)
+	}
+	response.AppendChild(attributes)
+
+	s.send(conn, messageID, response)
+}
+
+func (s *fakeLDAPServer) send(conn net.Conn, messageID int64, op *ber.Packet) {
+	envelope := ber.NewSequence("LDAPMessage")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "messageID"))
+	envelope.AppendChild(op)
+	conn.Write(envelope.Bytes())
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// aliceEntry - пользователь каталога, состоящий в группе администраторов
+func aliceEntry() fakeLDAPEntry {
+	return fakeLDAPEntry{
+		dn:       "uid=alice,ou=people,dc=example,dc=com",
+		password: "ad-password",
+		attrs: map[string][]string{
+			"mail":        {"alice@example.com"},
+			"displayName": {"Alice Directory"},
+			"memberOf": {
+				"cn=staff,ou=groups,dc=example,dc=com",
+				"CN=Admins, OU=Groups, DC=Example, DC=Com",
+			},
+		},
+	}
+}
+
+// ldapConfig - конфигурация с LDAP, указывающая на fake сервер
+func ldapConfig(srv *fakeLDAPServer) *config.Config {
+	return &config.Config{
+		JWTSecret:          "test-secret",
+		JWTExpiration:      "24h",
+		LDAPEnabled:        true,
+		LDAPURL:            srv.URL(),
+		LDAPBindDN:         "cn=svc,dc=example,dc=com",
+		LDAPBindPassword:   "svc-secret",
+		LDAPBaseDN:         "ou=people,dc=example,dc=com",
+		LDAPUserFilter:     "(&(objectClass=person)(mail=%s))",
+		LDAPEmailAttribute: "mail",
+		LDAPNameAttribute:  "displayName",
+		LDAPGroupAttribute: "memberOf",
+		LDAPGroupRoles:     "cn=admins,ou=groups,dc=example,dc=com=admin",
+		LDAPDefaultRole:    "user",
+		LDAPTimeout:        "2s",
+	}
+}
+
+// ================================================================
+// ТЕСТЫ LDAP VERIFIER
+// ================================================================
+
+// TestLDAPLogin_ProvisionsNewUser - первый вход создаёт локального пользователя
+func TestLDAPLogin_ProvisionsNewUser(t *testing.T) {
+	// Arrange
+	srv := startFakeLDAP(t, aliceEntry())
+	mockRepo := new(MockUserRepository)
+	authService := service.NewAuthService(mockRepo, ldapConfig(srv))
+
+	// Мок: локально пользователя нет
+	mockRepo.On("FindByEmail", "alice@example.com").Return(nil, repository.ErrUserNotFound)
+	mockRepo.On("Create", mock.AnythingOfType("*domain.User")).Return(nil)
+
+	// Act
+	response, err := authService.Login(&domain.LoginRequest{
+		Email:    "alice@example.com",
+		Password: "ad-password",
+	}, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	assert.Equal(t, "Alice Directory", response.User.Name)
+	assert.Equal(t, "admin", response.User.Role) // Группа Admins → admin
+	assert.Equal(t, domain.AuthProviderLDAP, response.User.AuthProvider)
+	assert.Empty(t, response.User.Password)
+
+	// Сервер видел bind сервисной учётки и затем bind пользователя
+	assert.Equal(t, []string{"cn=svc,dc=example,dc=com", aliceEntry().dn}, srv.boundDNs())
+	mockRepo.AssertExpectations(t)
+}
+
+// TestLDAPLogin_LookupErrorDoesNotProvision - ошибка БД при поиске не превращается в "новый пользователь"
+func TestLDAPLogin_LookupErrorDoesNotProvision(t *testing.T) {
+	// Arrange
+	srv := startFakeLDAP(t, aliceEntry())
+	mockRepo := new(MockUserRepository)
+	authService := service.NewAuthService(mockRepo, ldapConfig(srv))
+
+	// Мок: локальный источник пользователя не нашёл, а к моменту JIT БД стала недоступна
+	mockRepo.On("FindByEmail", "alice@example.com").Return(nil, repository.ErrUserNotFound).Once()
+	mockRepo.On("FindByEmail", "alice@example.com").Return(nil, errors.New("connection refused")).Once()
+
+	// Act
+	response, err := authService.Login(&domain.LoginRequest{
+		Email:    "alice@example.com",
+		Password: "ad-password",
+	}, domain.ClientInfo{})
+
+	// Assert: вход отклонён, дубликат аккаунта не создан
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	assert.Nil(t, response)
+	assert.Len(t, srv.boundDNs(), 2) // Каталог подтвердил пароль
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+// TestLDAPLogin_WrongPassword - неверный пароль в каталоге
+func TestLDAPLogin_WrongPassword(t *testing.T) {
+	// Arrange
+	srv := startFakeLDAP(t, aliceEntry())
+	mockRepo := new(MockUserRepository)
+	authService := service.NewAuthService(mockRepo, ldapConfig(srv))
+
+	mockRepo.On("FindByEmail", "alice@example.com").Return(nil, repository.ErrUserNotFound)
+
+	// Act
+	response, err := authService.Login(&domain.LoginRequest{
+		Email:    "alice@example.com",
+		Password: "wrong",
+	}, domain.ClientInfo{})
+
+	// Assert
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	assert.Nil(t, response)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+// TestLDAPLogin_SyncsExistingUser - повторный вход обновляет имя и роль
+func TestLDAPLogin_SyncsExistingUser(t *testing.T) {
+	// Arrange
+	srv := startFakeLDAP(t, aliceEntry())
+	mockRepo := new(MockUserRepository)
+	authService := service.NewAuthService(mockRepo, ldapConfig(srv))
+
+	existing := &domain.User{
+		ID:           7,
+		Email:        "alice@example.com",
+		Name:         "Old Name",
+		Role:         "user",
+		AuthProvider: domain.AuthProviderLDAP,
+		ExternalID:   aliceEntry().dn,
+	}
+	mockRepo.On("FindByEmail", "alice@example.com").Return(existing, nil)
+	mockRepo.On("Update", existing).Return(nil)
+
+	// Act
+	response, err := authService.Login(&domain.LoginRequest{
+		Email:    "alice@example.com",
+		Password: "ad-password",
+	}, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), response.User.ID)
+	assert.Equal(t, "Alice Directory", response.User.Name)
+	assert.Equal(t, "admin", response.User.Role)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestLDAPLogin_DoesNotTakeOverLocalAccount - локальный аккаунт проверяется только bcrypt
+func TestLDAPLogin_DoesNotTakeOverLocalAccount(t *testing.T) {
+	// Arrange
+	srv := startFakeLDAP(t, aliceEntry())
+	mockRepo := new(MockUserRepository)
+	authService := service.NewAuthService(mockRepo, ldapConfig(srv))
+
+	local := &domain.User{
+		ID:           1,
+		Email:        "alice@example.com",
+		Password:     "$2a$10$N9qo8uLOickgx2ZMRZoMye.6IrYtIB7LhGbp3bLMqGPHLLLpPPNnG",
+		AuthProvider: domain.AuthProviderLocal,
+	}
+	mockRepo.On("FindByEmail", "alice@example.com").Return(local, nil)
+
+	// Act: пароль из каталога не должен открыть локальный аккаунт
+	_, err := authService.Login(&domain.LoginRequest{
+		Email:    "alice@example.com",
+		Password: "ad-password",
+	}, domain.ClientInfo{})
+
+	// Assert
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	assert.Empty(t, srv.boundDNs()) // До LDAP дело не дошло
+}
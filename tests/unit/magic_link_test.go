@@ -0,0 +1,262 @@
+package unit
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/mailer"
+	"advanced-user-api/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// MOCK MAGIC LINK REPOSITORY
+// ================================================================
+
+// MockMagicLinkRepository - мок хранилища токенов magic link
+type MockMagicLinkRepository struct {
+	mock.Mock
+}
+
+func (m *MockMagicLinkRepository) Create(token *domain.MagicLinkToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockMagicLinkRepository) FindByHash(tokenHash string) (*domain.MagicLinkToken, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.MagicLinkToken), args.Error(1)
+}
+
+func (m *MockMagicLinkRepository) CountSince(email string, since time.Time) (int64, error) {
+	args := m.Called(email, since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockMagicLinkRepository) MarkUsed(id uint, usedAt time.Time) (bool, error) {
+	args := m.Called(id, usedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// tokenFromEmail - достаёт токен из ссылки в письме
+func tokenFromEmail(t *testing.T, body string) string {
+	link := regexp.MustCompile(`https://\S+`).FindString(body)
+	require.NotEmpty(t, link, "в письме нет ссылки")
+
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	return u.Query().Get("token")
+}
+
+// ================================================================
+// ТЕСТЫ MAGIC LINK
+// ================================================================
+
+// TestMagicLink_RequestAndRedeem - ссылка из письма даёт обычный AuthResponse
+func TestMagicLink_RequestAndRedeem(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	mockLinks := new(MockMagicLinkRepository)
+	mockMailer := new(MockMailer)
+	cfg := &config.Config{
+		JWTSecret:          "test-secret",
+		JWTExpiration:      "24h",
+		LoginMethods:       "password,magic_link",
+		MagicLinkURL:       "https://app.example.com/login/link",
+		MagicLinkTTL:       "15m",
+		MagicLinkRateLimit: 3,
+	}
+	authService := service.NewAuthService(mockRepo, cfg,
+		service.WithMagicLinkRepository(mockLinks),
+		service.WithMailer(mockMailer),
+	)
+	user := &domain.User{ID: 5, Email: "bob@example.com", Name: "Bob", Role: "user", AuthProvider: domain.AuthProviderLocal}
+	client := domain.ClientInfo{IP: "198.51.100.7", UserAgent: "Mozilla/5.0"}
+
+	var stored *domain.MagicLinkToken
+	var sent mailer.Message
+
+	mockLinks.On("CountSince", user.Email, mock.Anything).Return(int64(0), nil)
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+	mockLinks.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*domain.MagicLinkToken)
+		stored.ID = 11
+	}).Return(nil)
+	mockMailer.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(0).(mailer.Message)
+	}).Return(nil)
+
+	// Act 1: запрос ссылки
+	err := authService.RequestMagicLink(&domain.MagicLinkRequest{Email: user.Email, DeviceID: "dev-1"}, client)
+	require.NoError(t, err)
+
+	// В БД - только хеш, в письме - сам токен
+	token := tokenFromEmail(t, sent.Body)
+	require.NotEmpty(t, token)
+	assert.Equal(t, user.Email, sent.To)
+	assert.NotContains(t, stored.TokenHash, token)
+	assert.Equal(t, user.ID, *stored.UserID)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), stored.ExpiresAt, time.Minute)
+
+	// Act 2: вход по ссылке
+	mockLinks.On("FindByHash", stored.TokenHash).Return(stored, nil)
+	mockLinks.On("MarkUsed", stored.ID, mock.Anything).Return(true, nil)
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+
+	response, err := authService.RedeemMagicLink(&domain.MagicLinkVerifyRequest{Token: token, DeviceID: "dev-1"}, client)
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	assert.Equal(t, user, response.User)
+	mockLinks.AssertExpectations(t)
+}
+
+// TestMagicLink_RejectsOtherDeviceAndReuse - другое устройство и повторное использование
+func TestMagicLink_RejectsOtherDeviceAndReuse(t *testing.T) {
+	// Arrange: запрашиваем ссылку с устройства "dev-1"
+	mockRepo := new(MockUserRepository)
+	mockLinks := new(MockMagicLinkRepository)
+	mockMailer := new(MockMailer)
+	cfg := &config.Config{
+		JWTSecret:          "test-secret",
+		JWTExpiration:      "24h",
+		LoginMethods:       "password,magic_link",
+		MagicLinkURL:       "https://app.example.com/login/link",
+		MagicLinkTTL:       "15m",
+		MagicLinkRateLimit: 3,
+	}
+	authService := service.NewAuthService(mockRepo, cfg,
+		service.WithMagicLinkRepository(mockLinks),
+		service.WithMailer(mockMailer),
+	)
+	user := &domain.User{ID: 5, Email: "bob@example.com", Name: "Bob", Role: "user", AuthProvider: domain.AuthProviderLocal}
+	client := domain.ClientInfo{UserAgent: "Mozilla/5.0"}
+
+	var stored *domain.MagicLinkToken
+	var sent mailer.Message
+	mockLinks.On("CountSince", user.Email, mock.Anything).Return(int64(0), nil)
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+	mockLinks.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*domain.MagicLinkToken)
+		stored.ID = 12
+	}).Return(nil)
+	mockMailer.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(0).(mailer.Message)
+	}).Return(nil)
+	require.NoError(t, authService.RequestMagicLink(&domain.MagicLinkRequest{Email: user.Email, DeviceID: "dev-1"}, client))
+
+	token := tokenFromEmail(t, sent.Body)
+	mockLinks.On("FindByHash", stored.TokenHash).Return(stored, nil)
+
+	// Act & Assert: другой device_id
+	_, err := authService.RedeemMagicLink(&domain.MagicLinkVerifyRequest{Token: token, DeviceID: "dev-2"}, client)
+	assert.ErrorIs(t, err, service.ErrMagicLinkInvalid)
+
+	// Act & Assert: другой браузер
+	_, err = authService.RedeemMagicLink(&domain.MagicLinkVerifyRequest{Token: token, DeviceID: "dev-1"},
+		domain.ClientInfo{UserAgent: "curl/8.0"})
+	assert.ErrorIs(t, err, service.ErrMagicLinkInvalid)
+	mockLinks.AssertNotCalled(t, "MarkUsed", mock.Anything, mock.Anything)
+
+	// Act & Assert: ссылку уже использовал параллельный запрос
+	mockLinks.On("MarkUsed", stored.ID, mock.Anything).Return(false, nil)
+	_, err = authService.RedeemMagicLink(&domain.MagicLinkVerifyRequest{Token: token, DeviceID: "dev-1"}, client)
+	assert.ErrorIs(t, err, service.ErrMagicLinkInvalid)
+	mockRepo.AssertNotCalled(t, "FindByID", mock.Anything)
+}
+
+// TestMagicLink_UnknownEmail - ответ без ошибки, но письмо не отправляется
+func TestMagicLink_UnknownEmail(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	mockLinks := new(MockMagicLinkRepository)
+	mockMailer := new(MockMailer)
+	cfg := &config.Config{
+		JWTSecret:          "test-secret",
+		JWTExpiration:      "24h",
+		LoginMethods:       "password,magic_link",
+		MagicLinkURL:       "https://app.example.com/login/link",
+		MagicLinkTTL:       "15m",
+		MagicLinkRateLimit: 3,
+	}
+	authService := service.NewAuthService(mockRepo, cfg,
+		service.WithMagicLinkRepository(mockLinks),
+		service.WithMailer(mockMailer),
+	)
+	mockLinks.On("CountSince", "ghost@example.com", mock.Anything).Return(int64(0), nil)
+	mockRepo.On("FindByEmail", "ghost@example.com").Return(nil, nil)
+	mockLinks.On("Create", mock.MatchedBy(func(tk *domain.MagicLinkToken) bool {
+		return tk.UserID == nil
+	})).Return(nil)
+
+	// Act
+	err := authService.RequestMagicLink(&domain.MagicLinkRequest{Email: "ghost@example.com"}, domain.ClientInfo{})
+
+	// Assert
+	assert.NoError(t, err)
+	mockLinks.AssertExpectations(t)
+	mockMailer.AssertNotCalled(t, "Send", mock.Anything)
+}
+
+// TestMagicLink_RateLimited - лимит запросов на один email
+func TestMagicLink_RateLimited(t *testing.T) {
+	// Arrange
+	mockLinks := new(MockMagicLinkRepository)
+	mockMailer := new(MockMailer)
+	cfg := &config.Config{
+		JWTSecret:          "test-secret",
+		JWTExpiration:      "24h",
+		LoginMethods:       "password,magic_link",
+		MagicLinkURL:       "https://app.example.com/login/link",
+		MagicLinkTTL:       "15m",
+		MagicLinkRateLimit: 3,
+	}
+	authService := service.NewAuthService(new(MockUserRepository), cfg,
+		service.WithMagicLinkRepository(mockLinks),
+		service.WithMailer(mockMailer),
+	)
+	user := &domain.User{ID: 5, Email: "bob@example.com", Name: "Bob", Role: "user", AuthProvider: domain.AuthProviderLocal}
+	mockLinks.On("CountSince", user.Email, mock.Anything).Return(int64(3), nil)
+
+	// Act
+	err := authService.RequestMagicLink(&domain.MagicLinkRequest{Email: "Bob@Example.com"}, domain.ClientInfo{})
+
+	// Assert
+	assert.ErrorIs(t, err, service.ErrMagicLinkRateLimited)
+	mockLinks.AssertNotCalled(t, "Create", mock.Anything)
+	mockMailer.AssertNotCalled(t, "Send", mock.Anything)
+}
+
+// TestLoginMethods_MagicLinkOnly - при LOGIN_METHODS=magic_link вход по паролю отключён
+func TestLoginMethods_MagicLinkOnly(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	cfg := &config.Config{JWTSecret: "test-secret", LoginMethods: "magic_link"}
+	authService := service.NewAuthService(mockRepo, cfg)
+
+	// Act
+	_, err := authService.Login(&domain.LoginRequest{Email: "bob@example.com", Password: "password123"}, domain.ClientInfo{})
+
+	// Assert
+	assert.ErrorIs(t, err, service.ErrLoginMethodDisabled)
+	mockRepo.AssertNotCalled(t, "FindByEmail", mock.Anything)
+
+	// Без хранилища токенов magic link тоже недоступен
+	err = authService.RequestMagicLink(&domain.MagicLinkRequest{Email: "bob@example.com"}, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrLoginMethodDisabled)
+}
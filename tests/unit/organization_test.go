@@ -0,0 +1,245 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/pkg/jwt"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// MOCK ORGANIZATION REPOSITORY
+// ================================================================
+
+// MockOrganizationRepository - мок репозитория организаций
+type MockOrganizationRepository struct {
+	mock.Mock
+}
+
+func (m *MockOrganizationRepository) Create(org *domain.Organization, ownerID uint) error {
+	return m.Called(org, ownerID).Error(0)
+}
+
+func (m *MockOrganizationRepository) FindByID(id uint) (*domain.Organization, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Organization), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) FindBySlug(slug string) (*domain.Organization, error) {
+	args := m.Called(slug)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Organization), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) AddMember(member *domain.Membership) error {
+	return m.Called(member).Error(0)
+}
+
+func (m *MockOrganizationRepository) FindMember(orgID, userID uint) (*domain.Membership, error) {
+	args := m.Called(orgID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Membership), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) ListMembers(orgID uint) ([]domain.Membership, error) {
+	args := m.Called(orgID)
+	return args.Get(0).([]domain.Membership), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) UpdateMemberRole(orgID, userID uint, role string) error {
+	return m.Called(orgID, userID, role).Error(0)
+}
+
+func (m *MockOrganizationRepository) RemoveMember(orgID, userID uint) error {
+	return m.Called(orgID, userID).Error(0)
+}
+
+func (m *MockOrganizationRepository) CountMembersWithRole(orgID uint, role string) (int64, error) {
+	args := m.Called(orgID, role)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// ================================================================
+// ТЕСТЫ ORGANIZATIONS
+// ================================================================
+
+// tenantRouter - роутер с TenantMiddleware: организации default (1), acme (2), globex (3)
+// Ответ - ID организации запроса
+func tenantRouter(cfg *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	mockRepo := new(MockOrganizationRepository)
+	mockRepo.On("FindBySlug", "default").Return(&domain.Organization{ID: 1, Slug: "default"}, nil)
+	mockRepo.On("FindBySlug", "acme").Return(&domain.Organization{ID: 2, Slug: "acme"}, nil)
+	mockRepo.On("FindBySlug", "globex").Return(&domain.Organization{ID: 3, Slug: "globex"}, nil)
+	mockRepo.On("FindBySlug", mock.Anything).Return(nil, repository.ErrOrganizationRecordNotFound)
+
+	router := gin.New()
+	router.Use(middleware.TenantMiddleware(cfg, service.NewOrganizationService(mockRepo, nil)))
+	tenant := func(c *gin.Context) {
+		c.String(http.StatusOK, strconv.FormatUint(uint64(middleware.GetTenantIDFromContext(c)), 10))
+	}
+	router.GET("/public", tenant)
+	router.GET("/private", middleware.AuthMiddleware(cfg), tenant)
+	return router
+}
+
+// TestTenantMiddleware_Resolution - заголовок, затем поддомен, затем организация по умолчанию
+func TestTenantMiddleware_Resolution(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{TenantHeader: "X-Organization", TenantBaseDomain: "api.example.com", TenantDefault: "default"}
+	router := tenantRouter(cfg)
+
+	request := func(host, header string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "/public", nil)
+		req.Host = host
+		if header != "" {
+			req.Header.Set("X-Organization", header)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	// Act & Assert
+	code, body := request("localhost:8080", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "1", body, "организация по умолчанию")
+
+	_, body = request("acme.api.example.com:8080", "")
+	assert.Equal(t, "2", body, "поддомен")
+
+	_, body = request("acme.api.example.com", "globex")
+	assert.Equal(t, "3", body, "заголовок важнее поддомена")
+
+	_, body = request("a.b.api.example.com", "")
+	assert.Equal(t, "1", body, "вложенный поддомен не считается организацией")
+
+	code, _ = request("localhost", "initech")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+// TestTenantMiddleware_TokenOrganization - токен действует только в своей организации
+func TestTenantMiddleware_TokenOrganization(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{JWTSecret: "test-secret", TenantHeader: "X-Organization", TenantDefault: "default"}
+	router := tenantRouter(cfg)
+
+	token, err := jwt.GenerateTokenFromClaims(jwt.Claims{UserID: 7, OrganizationID: 2}, "test-secret", time.Hour)
+	require.NoError(t, err)
+
+	request := func(header string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "/private", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if header != "" {
+			req.Header.Set("X-Organization", header)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	// Act & Assert
+	code, body := request("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "2", body, "без явной организации используется организация токена")
+
+	code, body = request("acme")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "2", body)
+
+	code, _ = request("globex")
+	assert.Equal(t, http.StatusForbidden, code, "токен другой организации")
+}
+
+// TestOrganizationService_OwnerRules - admin не трогает владельцев, последнего владельца не убрать
+func TestOrganizationService_OwnerRules(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockOrganizationRepository)
+	mockAudit := new(MockAuditService)
+	orgService := service.NewOrganizationService(mockRepo, mockAudit)
+
+	owner := &domain.Membership{OrganizationID: 2, UserID: 1, Role: domain.OrgRoleOwner}
+	admin := &domain.Membership{OrganizationID: 2, UserID: 2, Role: domain.OrgRoleAdmin}
+	member := &domain.Membership{OrganizationID: 2, UserID: 3, Role: domain.OrgRoleMember}
+	for _, m := range []*domain.Membership{owner, admin, member} {
+		mockRepo.On("FindMember", uint(2), m.UserID).Return(m, nil)
+	}
+	mockRepo.On("FindMember", uint(2), mock.Anything).Return(nil, repository.ErrOrganizationRecordNotFound)
+	mockRepo.On("CountMembersWithRole", uint(2), domain.OrgRoleOwner).Return(int64(1), nil)
+	mockRepo.On("UpdateMemberRole", uint(2), member.UserID, domain.OrgRoleAdmin).Return(nil)
+	mockAudit.On("Record", mock.MatchedBy(func(e *domain.AuditEvent) bool {
+		return e.Action == domain.AuditActionOrgMemberRoleChanged &&
+			e.Changes["role"] == domain.AuditChange{Before: domain.OrgRoleMember, After: domain.OrgRoleAdmin}
+	})).Return()
+
+	// Act & Assert
+	_, err := orgService.UpdateMemberRole(2, admin.UserID, owner.UserID, domain.OrgRoleMember, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrOrgPermissionDenied, "admin не может понизить владельца")
+
+	_, err = orgService.UpdateMemberRole(2, admin.UserID, member.UserID, domain.OrgRoleOwner, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrOrgPermissionDenied, "admin не может назначить владельца")
+
+	_, err = orgService.UpdateMemberRole(2, member.UserID, admin.UserID, domain.OrgRoleMember, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrOrgPermissionDenied, "member не управляет участниками")
+
+	_, err = orgService.UpdateMemberRole(2, owner.UserID, owner.UserID, domain.OrgRoleAdmin, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrLastOwner)
+
+	err = orgService.RemoveMember(2, owner.UserID, owner.UserID, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrLastOwner, "последний владелец не может выйти")
+
+	_, err = orgService.UpdateMemberRole(2, owner.UserID, 404, domain.OrgRoleAdmin, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrMemberNotFound)
+
+	updated, err := orgService.UpdateMemberRole(2, admin.UserID, member.UserID, domain.OrgRoleAdmin, domain.ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrgRoleAdmin, updated.Role)
+
+	mockRepo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything)
+	mockAudit.AssertNumberOfCalls(t, "Record", 1)
+}
+
+// TestAuthService_ForTenant - сервис организации работает с её репозиторием
+// и выдаёт токен с claim "org"
+func TestAuthService_ForTenant(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	cfg := &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"}
+	authService := service.NewAuthService(mockRepo, cfg)
+
+	req := &domain.RegisterRequest{Email: "dave@example.com", Name: "Dave", Password: "password123"}
+	mockRepo.On("FindByEmail", req.Email).Return(nil, nil)
+	mockRepo.On("Create", mock.AnythingOfType("*domain.User")).Return(nil)
+
+	// Act
+	response, err := authService.ForTenant(2).Register(req, domain.ClientInfo{OrganizationID: 2})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), mockRepo.TenantID)
+
+	claims, err := jwt.ValidateToken(response.Token, "test-secret")
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), claims.OrganizationID)
+	mockRepo.AssertExpectations(t)
+}
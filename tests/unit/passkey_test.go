@@ -0,0 +1,451 @@
+package unit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/jwt"
+	"advanced-user-api/internal/service"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// MOCKS
+// ================================================================
+
+// MockCredentialRepository - мок хранилища passkeys
+type MockCredentialRepository struct {
+	mock.Mock
+}
+
+func (m *MockCredentialRepository) Create(credential *domain.WebAuthnCredential) error {
+	args := m.Called(credential)
+	return args.Error(0)
+}
+
+func (m *MockCredentialRepository) FindByID(id uint) (*domain.WebAuthnCredential, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.WebAuthnCredential), args.Error(1)
+}
+
+func (m *MockCredentialRepository) FindByCredentialID(credentialID []byte) (*domain.WebAuthnCredential, error) {
+	args := m.Called(credentialID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.WebAuthnCredential), args.Error(1)
+}
+
+func (m *MockCredentialRepository) FindByUserID(userID uint) ([]domain.WebAuthnCredential, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]domain.WebAuthnCredential), args.Error(1)
+}
+
+func (m *MockCredentialRepository) Update(credential *domain.WebAuthnCredential) error {
+	args := m.Called(credential)
+	return args.Error(0)
+}
+
+func (m *MockCredentialRepository) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// memoryChallengeRepository - хранилище challenge в памяти
+// Take удаляет запись, как и реализация на GORM
+type memoryChallengeRepository struct {
+	challenges map[string]*domain.WebAuthnChallenge
+}
+
+func (r *memoryChallengeRepository) Create(challenge *domain.WebAuthnChallenge) error {
+	r.challenges[challenge.ID] = challenge
+	return nil
+}
+
+func (r *memoryChallengeRepository) Take(id string) (*domain.WebAuthnChallenge, error) {
+	challenge, ok := r.challenges[id]
+	if !ok {
+		return nil, errors.New("challenge не найден")
+	}
+	delete(r.challenges, id)
+	return challenge, nil
+}
+
+// ================================================================
+// SOFTWARE AUTHENTICATOR - Программный аутентификатор для тестов
+// ================================================================
+// Формирует ответы так же, как браузер с платформенным аутентификатором:
+// authenticatorData, attestationObject (CBOR) и подпись ECDSA P-256
+
+const (
+	passkeyTestRPID   = "localhost"
+	passkeyTestOrigin = "http://localhost:3000"
+)
+
+// softAuthenticator - один ключ ES256
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	counter      uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+
+	return &softAuthenticator{key: key, credentialID: credentialID}
+}
+
+// create - ответ navigator.credentials.create() с аттестацией "none" или "packed"
+func (a *softAuthenticator) create(t *testing.T, options *protocol.CredentialCreation, format string) json.RawMessage {
+	clientData := a.clientData(t, protocol.CreateCeremony, options.Response.Challenge)
+
+	// Публичный ключ в формате COSE (EC2, ES256, P-256)
+	point, err := a.key.PublicKey.ECDH()
+	require.NoError(t, err)
+	raw := point.Bytes() // 0x04 | X | Y
+	coseKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: raw[1:33],
+		YCoord: raw[33:65],
+	})
+	require.NoError(t, err)
+
+	// authenticatorData: rpIdHash | flags (UP, UV, AT) | counter | AAGUID | len | credentialID | ключ
+	authData := a.authData(0x45)
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, coseKey...)
+
+	statement := map[string]interface{}{}
+	if format == "packed" {
+		// Self attestation: регистрация подписана самим ключом passkey
+		statement["alg"] = int64(webauthncose.AlgES256)
+		statement["sig"] = a.sign(t, authData, clientData)
+	}
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      format,
+		"attStmt":  statement,
+		"authData": authData,
+	})
+	require.NoError(t, err)
+
+	return a.credential(t, map[string]interface{}{
+		"clientDataJSON":    b64(clientData),
+		"attestationObject": b64(attestation),
+		"transports":        []string{"internal"},
+	})
+}
+
+// get - ответ navigator.credentials.get()
+func (a *softAuthenticator) get(t *testing.T, options *protocol.CredentialAssertion, userHandle []byte) json.RawMessage {
+	a.counter++
+	clientData := a.clientData(t, protocol.AssertCeremony, options.Response.Challenge)
+	authData := a.authData(0x05) // UP, UV
+
+	return a.credential(t, map[string]interface{}{
+		"clientDataJSON":    b64(clientData),
+		"authenticatorData": b64(authData),
+		"signature":         b64(a.sign(t, authData, clientData)),
+		"userHandle":        b64(userHandle),
+	})
+}
+
+func (a *softAuthenticator) authData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(passkeyTestRPID))
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.counter)
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony protocol.CeremonyType, challenge protocol.URLEncodedBase64) []byte {
+	data, err := json.Marshal(protocol.CollectedClientData{
+		Type:      ceremony,
+		Challenge: challenge.String(),
+		Origin:    passkeyTestOrigin,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+// sign - подпись authenticatorData || SHA-256(clientDataJSON)
+func (a *softAuthenticator) sign(t *testing.T, authData, clientData []byte) []byte {
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+	return sig
+}
+
+func (a *softAuthenticator) credential(t *testing.T, response map[string]interface{}) json.RawMessage {
+	data, err := json.Marshal(map[string]interface{}{
+		"id":       b64(a.credentialID),
+		"rawId":    b64(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// passkeyConfig - конфигурация relying party для тестов
+func passkeyConfig() *config.Config {
+	return &config.Config{
+		JWTSecret:           "test-secret",
+		JWTExpiration:       "24h",
+		WebAuthnRPID:        passkeyTestRPID,
+		WebAuthnRPName:      "Test",
+		WebAuthnRPOrigins:   passkeyTestOrigin,
+		WebAuthnAttestation: "none",
+		WebAuthnTimeout:     "5m",
+	}
+}
+
+// passkeySignup - регистрирует аккаунт без пароля и возвращает пользователя и его passkey
+func passkeySignup(t *testing.T, passkeyService service.PasskeyService, authenticator *softAuthenticator,
+	mockUsers *MockUserRepository, mockCredentials *MockCredentialRepository, mockAudit *MockAuditService) (*domain.User, *domain.WebAuthnCredential) {
+	var user *domain.User
+	var record *domain.WebAuthnCredential
+
+	mockUsers.On("FindByEmail", "carol@example.com").Return(nil, nil)
+	mockUsers.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		user = args.Get(0).(*domain.User)
+		user.ID = 9
+	}).Return(nil).Once()
+	mockCredentials.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		record = args.Get(0).(*domain.WebAuthnCredential)
+		record.ID = 21
+	}).Return(nil).Once()
+	mockAudit.On("Record", mock.Anything).Return()
+
+	begin, err := passkeyService.BeginSignup(&domain.PasskeySignupRequest{Email: "carol@example.com", Name: "Carol"})
+	require.NoError(t, err)
+
+	response, err := passkeyService.FinishSignup(&domain.PasskeyFinishRequest{
+		ChallengeID: begin.ChallengeID,
+		Name:        "Laptop",
+		Credential:  authenticator.create(t, begin.Options.(*protocol.CredentialCreation), "none"),
+	}, domain.ClientInfo{})
+	require.NoError(t, err)
+	require.NotEmpty(t, response.Token)
+
+	// Ключ и пользователь теперь "в БД"
+	mockUsers.On("FindByID", user.ID).Return(user, nil)
+	mockCredentials.On("FindByUserID", user.ID).Return([]domain.WebAuthnCredential{*record}, nil)
+	mockCredentials.On("FindByCredentialID", mock.Anything).Return(record, nil)
+	return user, record
+}
+
+// passkeyLogin - вход без email (discoverable credential)
+func passkeyLogin(t *testing.T, passkeyService service.PasskeyService, authenticator *softAuthenticator, user *domain.User) (*domain.AuthResponse, error) {
+	begin, err := passkeyService.BeginLogin(&domain.PasskeyLoginRequest{})
+	require.NoError(t, err)
+
+	handle, err := base64.RawURLEncoding.DecodeString(user.PasskeyHandle)
+	require.NoError(t, err)
+
+	return passkeyService.FinishLogin(&domain.PasskeyFinishRequest{
+		ChallengeID: begin.ChallengeID,
+		Credential:  authenticator.get(t, begin.Options.(*protocol.CredentialAssertion), handle),
+	}, domain.ClientInfo{})
+}
+
+// ================================================================
+// ТЕСТЫ PASSKEYS
+// ================================================================
+
+// TestPasskey_SignupAndLogin - аккаунт без пароля, затем вход по тому же ключу
+func TestPasskey_SignupAndLogin(t *testing.T) {
+	// Arrange: регистрация
+	cfg := passkeyConfig()
+	mockUsers := new(MockUserRepository)
+	mockCredentials := new(MockCredentialRepository)
+	mockAudit := new(MockAuditService)
+	authenticator := newSoftAuthenticator(t)
+	challenges := &memoryChallengeRepository{challenges: map[string]*domain.WebAuthnChallenge{}}
+	passkeyService, err := service.NewPasskeyService(mockUsers, mockCredentials, challenges, service.NewTokenIssuer(cfg, nil), mockAudit, cfg)
+	require.NoError(t, err)
+	user, record := passkeySignup(t, passkeyService, authenticator, mockUsers, mockCredentials, mockAudit)
+
+	assert.Empty(t, user.Password, "у аккаунта с passkey нет хеша пароля")
+	assert.Equal(t, domain.AuthProviderLocal, user.AuthProvider)
+	assert.Equal(t, authenticator.credentialID, record.CredentialID)
+	assert.Equal(t, "none", record.AttestationType)
+	assert.Equal(t, "Laptop", record.Name)
+
+	mockCredentials.On("Update", record).Return(nil)
+
+	// Act
+	response, err := passkeyLogin(t, passkeyService, authenticator, user)
+
+	// Assert
+	require.NoError(t, err)
+	claims, err := jwt.ValidateToken(response.Token, "test-secret")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.Equal(t, uint32(1), record.SignCount)
+	assert.NotNil(t, record.LastUsedAt)
+}
+
+// TestPasskey_BeginLoginDoesNotRevealEmail - для зарегистрированного и неизвестного
+// email ответ одинаковый: без allowCredentials, вход по любому ключу сайта
+func TestPasskey_BeginLoginDoesNotRevealEmail(t *testing.T) {
+	// Arrange
+	cfg := passkeyConfig()
+	mockUsers := new(MockUserRepository)
+	mockCredentials := new(MockCredentialRepository)
+	mockAudit := new(MockAuditService)
+	authenticator := newSoftAuthenticator(t)
+	challenges := &memoryChallengeRepository{challenges: map[string]*domain.WebAuthnChallenge{}}
+	passkeyService, err := service.NewPasskeyService(mockUsers, mockCredentials, challenges, service.NewTokenIssuer(cfg, nil), mockAudit, cfg)
+	require.NoError(t, err)
+	user, _ := passkeySignup(t, passkeyService, authenticator, mockUsers, mockCredentials, mockAudit)
+
+	// Act
+	known, err := passkeyService.BeginLogin(&domain.PasskeyLoginRequest{Email: user.Email})
+	require.NoError(t, err)
+	unknown, err := passkeyService.BeginLogin(&domain.PasskeyLoginRequest{Email: "nobody@example.com"})
+	require.NoError(t, err)
+
+	// Assert
+	for _, begin := range []*domain.PasskeyChallengeResponse{known, unknown} {
+		options := begin.Options.(*protocol.CredentialAssertion).Response
+		assert.Empty(t, options.AllowedCredentials)
+		assert.Equal(t, protocol.VerificationRequired, options.UserVerification)
+	}
+}
+
+// TestPasskey_RegisterPackedAttestation - добавление passkey к аккаунту с паролем
+func TestPasskey_RegisterPackedAttestation(t *testing.T) {
+	// Arrange
+	cfg := passkeyConfig()
+	mockUsers := new(MockUserRepository)
+	mockCredentials := new(MockCredentialRepository)
+	mockAudit := new(MockAuditService)
+	authenticator := newSoftAuthenticator(t)
+	challenges := &memoryChallengeRepository{challenges: map[string]*domain.WebAuthnChallenge{}}
+	passkeyService, err := service.NewPasskeyService(mockUsers, mockCredentials, challenges, service.NewTokenIssuer(cfg, nil), mockAudit, cfg)
+	require.NoError(t, err)
+	user := &domain.User{ID: 3, Email: "dave@example.com", Name: "Dave", Password: "hash", AuthProvider: domain.AuthProviderLocal}
+
+	var record *domain.WebAuthnCredential
+	mockUsers.On("FindByID", user.ID).Return(user, nil)
+	mockUsers.On("Update", user).Return(nil)
+	mockCredentials.On("FindByUserID", user.ID).Return([]domain.WebAuthnCredential{}, nil)
+	mockCredentials.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		record = args.Get(0).(*domain.WebAuthnCredential)
+	}).Return(nil)
+	mockAudit.On("Record", mock.MatchedBy(func(e *domain.AuditEvent) bool {
+		return e.Action == domain.AuditActionPasskeyAdded && *e.TargetID == user.ID
+	})).Return()
+
+	// Act
+	begin, err := passkeyService.BeginRegistration(user.ID)
+	require.NoError(t, err)
+	credential, err := passkeyService.FinishRegistration(user.ID, &domain.PasskeyFinishRequest{
+		ChallengeID: begin.ChallengeID,
+		Credential:  authenticator.create(t, begin.Options.(*protocol.CredentialCreation), "packed"),
+	}, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Same(t, record, credential)
+	assert.Equal(t, "packed", credential.AttestationType)
+	assert.NotEmpty(t, user.PasskeyHandle, "user handle создаётся при первой регистрации")
+
+	// Challenge одноразовый
+	_, err = passkeyService.FinishRegistration(user.ID, &domain.PasskeyFinishRequest{
+		ChallengeID: begin.ChallengeID,
+		Credential:  authenticator.create(t, begin.Options.(*protocol.CredentialCreation), "packed"),
+	}, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrPasskeyFailed)
+	mockAudit.AssertExpectations(t)
+}
+
+// TestPasskey_RejectsCounterRegression - счётчик подписей не вырос: вход отклоняется
+func TestPasskey_RejectsCounterRegression(t *testing.T) {
+	// Arrange: сохранённый счётчик больше, чем у этого экземпляра ключа
+	cfg := passkeyConfig()
+	mockUsers := new(MockUserRepository)
+	mockCredentials := new(MockCredentialRepository)
+	mockAudit := new(MockAuditService)
+	authenticator := newSoftAuthenticator(t)
+	challenges := &memoryChallengeRepository{challenges: map[string]*domain.WebAuthnChallenge{}}
+	passkeyService, err := service.NewPasskeyService(mockUsers, mockCredentials, challenges, service.NewTokenIssuer(cfg, nil), mockAudit, cfg)
+	require.NoError(t, err)
+	user, record := passkeySignup(t, passkeyService, authenticator, mockUsers, mockCredentials, mockAudit)
+	record.SignCount = 10
+	mockCredentials.On("FindByUserID", user.ID).Unset()
+	mockCredentials.On("FindByUserID", user.ID).Return([]domain.WebAuthnCredential{*record}, nil)
+	authenticator.counter = 4
+
+	// Act
+	response, err := passkeyLogin(t, passkeyService, authenticator, user)
+
+	// Assert
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, service.ErrPasskeyCloned)
+	assert.Equal(t, uint32(10), record.SignCount)
+	mockCredentials.AssertNotCalled(t, "Update", mock.Anything)
+	mockAudit.AssertCalled(t, "Record", mock.MatchedBy(func(e *domain.AuditEvent) bool {
+		return e.Action == domain.AuditActionPasskeyCloneDetected
+	}))
+}
+
+// TestPasskey_CannotDeleteLastPasskey - аккаунт без пароля не может остаться без способа входа
+func TestPasskey_CannotDeleteLastPasskey(t *testing.T) {
+	// Arrange
+	cfg := passkeyConfig()
+	mockUsers := new(MockUserRepository)
+	mockCredentials := new(MockCredentialRepository)
+	mockAudit := new(MockAuditService)
+	authenticator := newSoftAuthenticator(t)
+	challenges := &memoryChallengeRepository{challenges: map[string]*domain.WebAuthnChallenge{}}
+	passkeyService, err := service.NewPasskeyService(mockUsers, mockCredentials, challenges, service.NewTokenIssuer(cfg, nil), mockAudit, cfg)
+	require.NoError(t, err)
+	user, record := passkeySignup(t, passkeyService, authenticator, mockUsers, mockCredentials, mockAudit)
+	mockCredentials.On("FindByID", record.ID).Return(record, nil)
+
+	// Act & Assert: чужой passkey
+	err = passkeyService.Delete(user.ID+1, record.ID, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrPasskeyNotFound)
+
+	// Act & Assert: последний passkey
+	err = passkeyService.Delete(user.ID, record.ID, domain.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrLastPasskey)
+	mockCredentials.AssertNotCalled(t, "Delete", mock.Anything)
+}
@@ -0,0 +1,211 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/password"
+	"advanced-user-api/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ================================================================
+// ТЕСТЫ PASSWORD HASHER
+// ================================================================
+
+// fastArgon2 - лёгкие параметры argon2id, чтобы тесты были быстрыми
+func fastArgon2() password.Params {
+	return password.Params{
+		Algorithm:   password.AlgorithmArgon2id,
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+	}
+}
+
+// TestHasher_Argon2idRoundTrip - хеш в формате PHC и успешная проверка
+func TestHasher_Argon2idRoundTrip(t *testing.T) {
+	hasher := password.NewHasher(fastArgon2())
+
+	hash, err := hasher.Hash("correct horse battery staple")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	assert.True(t, hasher.Verify(hash, "correct horse battery staple"))
+	assert.False(t, hasher.Verify(hash, "wrong"))
+	assert.False(t, hasher.NeedsRehash(hash))
+}
+
+// TestHasher_LongPasswordsAreNotTruncated - argon2id учитывает весь пароль
+func TestHasher_LongPasswordsAreNotTruncated(t *testing.T) {
+	hasher := password.NewHasher(fastArgon2())
+	long := strings.Repeat("a", 100)
+
+	hash, err := hasher.Hash(long + "X")
+	require.NoError(t, err)
+
+	// bcrypt не заметил бы разницу после 72-го байта
+	assert.False(t, hasher.Verify(hash, long+"Y"))
+}
+
+// TestHasher_VerifiesLegacyBcrypt - старые bcrypt хеши проверяются и помечаются на пересчёт
+func TestHasher_VerifiesLegacyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	hasher := password.NewHasher(fastArgon2())
+
+	assert.True(t, hasher.Verify(string(legacy), "secret123"))
+	assert.True(t, hasher.NeedsRehash(string(legacy)))
+}
+
+// TestHasher_ParamsChangeRequiresRehash - изменение параметров argon2id
+func TestHasher_ParamsChangeRequiresRehash(t *testing.T) {
+	old := password.NewHasher(fastArgon2())
+	hash, err := old.Hash("secret123")
+	require.NoError(t, err)
+
+	stronger := fastArgon2()
+	stronger.Iterations = 2
+	hasher := password.NewHasher(stronger)
+
+	assert.True(t, hasher.Verify(hash, "secret123")) // Параметры берутся из хеша
+	assert.True(t, hasher.NeedsRehash(hash))
+}
+
+// TestHasher_Pepper - хеш с pepper нельзя проверить без pepper
+func TestHasher_Pepper(t *testing.T) {
+	params := fastArgon2()
+	params.Pepper = []byte("server-side-secret")
+	peppered := password.NewHasher(params)
+
+	hash, err := peppered.Hash("secret123")
+	require.NoError(t, err)
+	assert.Contains(t, hash, ",k=1$")
+	assert.True(t, peppered.Verify(hash, "secret123"))
+
+	// Без pepper (например, утекла только БД) пароль не подобрать
+	assert.False(t, password.NewHasher(fastArgon2()).Verify(hash, "secret123"))
+
+	// Хеш без pepper устарел, если pepper теперь настроен
+	plain, _ := password.NewHasher(fastArgon2()).Hash("secret123")
+	assert.True(t, peppered.Verify(plain, "secret123"))
+	assert.True(t, peppered.NeedsRehash(plain))
+}
+
+// TestHasher_BcryptWithPepper - bcrypt + pepper снимает ограничение 72 байта
+func TestHasher_BcryptWithPepper(t *testing.T) {
+	hasher := password.NewHasher(password.Params{
+		Algorithm:  password.AlgorithmBcrypt,
+		BcryptCost: bcrypt.MinCost,
+		Pepper:     []byte("pepper"),
+	})
+	long := strings.Repeat("b", 100)
+
+	hash, err := hasher.Hash(long + "1")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(hash, "$bcrypt-hmac$2a$04$"))
+	assert.True(t, hasher.Verify(hash, long+"1"))
+	assert.False(t, hasher.Verify(hash, long+"2"))
+	assert.False(t, hasher.NeedsRehash(hash))
+}
+
+// TestHasher_CheckFormat - готовые хеши из другой системы: argon2id PHC и bcrypt
+func TestHasher_CheckFormat(t *testing.T) {
+	hasher := password.NewHasher(fastArgon2())
+	argon, _ := hasher.Hash("secret123")
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+
+	params := fastArgon2()
+	params.Pepper = []byte("pepper")
+	peppered, _ := password.NewHasher(params).Hash("secret123")
+
+	assert.NoError(t, hasher.CheckFormat(argon))
+	assert.NoError(t, hasher.CheckFormat(string(legacy)))
+	assert.NoError(t, hasher.CheckFormat(strings.Replace(string(legacy), "$2a$", "$2y$", 1)))
+	assert.ErrorIs(t, hasher.CheckFormat(peppered), password.ErrUnknownFormat, "без pepper хеш не проверить")
+	assert.NoError(t, password.NewHasher(params).CheckFormat(peppered))
+	for _, invalid := range []string{"", "secret123", "$argon2id$v=19$m=0,t=1,p=1$c2FsdA$aGFzaA", "$2a$10$short", "$md5$abc"} {
+		assert.ErrorIs(t, hasher.CheckFormat(invalid), password.ErrUnknownFormat, invalid)
+	}
+}
+
+// TestHasher_Argon2ParamLimits - параметры хеша выше допустимых не проверяются и не импортируются
+func TestHasher_Argon2ParamLimits(t *testing.T) {
+	hasher := password.NewHasher(fastArgon2())
+	encoded := func(params string) string {
+		return "$argon2id$v=19$" + params + "$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA"
+	}
+
+	// Предел - значения по умолчанию × 4 (настроенные fastArgon2 меньше)
+	assert.NoError(t, hasher.CheckFormat(encoded("m=262144,t=12,p=8")))
+	for _, params := range []string{"m=262145,t=1,p=1", "m=4294967295,t=1,p=1", "m=1024,t=13,p=1", "m=1024,t=1,p=9"} {
+		assert.ErrorIs(t, hasher.CheckFormat(encoded(params)), password.ErrCostTooHigh, params)
+		assert.False(t, hasher.Verify(encoded(params), "secret123"), params)
+	}
+
+	// Значения больше типа параметра не обрезаются (4294967296 → 0, 257 → 1)
+	for _, params := range []string{"m=4294967296,t=1,p=1", "m=1024,t=4294967297,p=1", "m=1024,t=1,p=257"} {
+		assert.ErrorIs(t, hasher.CheckFormat(encoded(params)), password.ErrUnknownFormat, params)
+	}
+
+	// Более сильные настройки поднимают предел
+	strong := fastArgon2()
+	strong.Memory = 128 * 1024
+	assert.NoError(t, password.NewHasher(strong).CheckFormat(encoded("m=524288,t=1,p=1")))
+}
+
+// ================================================================
+// ТЕСТ ПЕРЕСЧЁТА ХЕША ПРИ ВХОДЕ
+// ================================================================
+
+// TestLogin_UpgradesLegacyBcryptHash - успешный вход переводит bcrypt хеш на argon2id
+func TestLogin_UpgradesLegacyBcryptHash(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	cfg := &config.Config{
+		JWTSecret:                 "test-secret",
+		JWTExpiration:             "24h",
+		PasswordAlgorithm:         "argon2id",
+		PasswordArgon2Memory:      1024,
+		PasswordArgon2Iterations:  1,
+		PasswordArgon2Parallelism: 1,
+	}
+	authService := service.NewAuthService(mockRepo, cfg)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	user := &domain.User{
+		ID:           1,
+		Email:        "test@example.com",
+		Password:     string(legacy),
+		Role:         "user",
+		AuthProvider: domain.AuthProviderLocal,
+	}
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+
+	// Мок: Update вызывается с уже пересчитанным хешем
+	mockRepo.On("Update", mock.MatchedBy(func(u *domain.User) bool {
+		return strings.HasPrefix(u.Password, "$argon2id$")
+	})).Return(nil)
+
+	// Act
+	response, err := authService.Login(&domain.LoginRequest{
+		Email:    user.Email,
+		Password: "password123",
+	}, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	assert.True(t, password.NewHasher(password.Params{}).Verify(user.Password, "password123"))
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,173 @@
+package unit
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/pkg/password"
+	"advanced-user-api/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// sha1Hex - SHA-1 пароля в верхнем регистре (как в Pwned Passwords)
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// violationCodes - коды нарушений из ошибки политики
+func violationCodes(t *testing.T, err error) []string {
+	var policyErr *password.PolicyError
+	require.True(t, errors.As(err, &policyErr), "ожидалась *password.PolicyError, получено %v", err)
+
+	codes := make([]string, 0, len(policyErr.Violations))
+	for _, v := range policyErr.Violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+// ================================================================
+// ТЕСТЫ STRENGTH
+// ================================================================
+
+// TestEstimateStrength - словарные и шаблонные пароли слабые, случайные - стойкие
+func TestEstimateStrength(t *testing.T) {
+	assert.Equal(t, 0, password.EstimateStrength("password").Score)
+	assert.Equal(t, 0, password.EstimateStrength("P@ssw0rd").Score)
+	assert.Equal(t, 0, password.EstimateStrength("123456789").Score)
+	assert.Equal(t, 0, password.EstimateStrength("aaaaaaaaaaaa").Score)
+	assert.LessOrEqual(t, password.EstimateStrength("qwertyuiop").Score, 1)
+	assert.LessOrEqual(t, password.EstimateStrength("johnsmith1", "john", "smith").Score, 1)
+
+	assert.Equal(t, 4, password.EstimateStrength("vT8#qLp2!zR4").Score)
+	assert.GreaterOrEqual(t, password.EstimateStrength("correct horse battery staple").Score, 3)
+}
+
+// ================================================================
+// ТЕСТЫ POLICY
+// ================================================================
+
+// TestPolicy_CollectsAllViolations - все нарушения возвращаются сразу
+func TestPolicy_CollectsAllViolations(t *testing.T) {
+	policy := password.NewPolicy(password.PolicyConfig{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		MinScore:      3,
+	}, nil)
+
+	err := policy.Check("alice", password.PolicyInput{Email: "alice@example.com", Name: "Alice"})
+
+	assert.ElementsMatch(t, []string{
+		password.ViolationTooShort,
+		password.ViolationNoUppercase,
+		password.ViolationNoDigit,
+		password.ViolationNoSymbol,
+		password.ViolationPersonalInfo,
+		password.ViolationTooWeak,
+	}, violationCodes(t, err))
+}
+
+// TestPolicy_PersonalInfoAndBannedWords - email, имя и запрещённые слова (с leet-заменами)
+func TestPolicy_PersonalInfoAndBannedWords(t *testing.T) {
+	policy := password.NewPolicy(password.PolicyConfig{BannedWords: []string{"acme"}}, nil)
+	input := password.PolicyInput{Email: "j.smith@corp.io", Name: "John Smith"}
+
+	assert.Contains(t, violationCodes(t, policy.Check("Sm1th-2024-xyz", input)), password.ViolationPersonalInfo)
+	assert.Contains(t, violationCodes(t, policy.Check("I-love-@cme-42", input)), password.ViolationBannedWord)
+	assert.NoError(t, policy.Check("vT8#qLp2!zR4", input))
+}
+
+// TestPolicy_MaxLength - слишком длинный пароль отклоняется
+func TestPolicy_MaxLength(t *testing.T) {
+	policy := password.NewPolicy(password.PolicyConfig{MaxLength: 16}, nil)
+
+	err := policy.Check(strings.Repeat("xY7!", 5), password.PolicyInput{})
+
+	assert.Equal(t, []string{password.ViolationTooLong}, violationCodes(t, err))
+}
+
+// TestPolicy_BreachedCorpusFile - база одним файлом "SHA1:COUNT"
+func TestPolicy_BreachedCorpusFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	content := "# топ утёкших паролей\n" +
+		sha1Hex("Summer2024!") + ":1532\n" +
+		strings.ToLower(sha1Hex("Tr0ub4dor&3")) + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	corpus, err := password.LoadBreachedCorpus(path)
+	require.NoError(t, err)
+
+	count, err := corpus.Count("Summer2024!")
+	require.NoError(t, err)
+	assert.Equal(t, 1532, count)
+
+	count, _ = corpus.Count("Tr0ub4dor&3")
+	assert.Equal(t, 1, count)
+
+	policy := password.NewPolicy(password.PolicyConfig{}, corpus)
+	assert.Equal(t, []string{password.ViolationBreached}, violationCodes(t, policy.Check("Tr0ub4dor&3", password.PolicyInput{})))
+	assert.NoError(t, policy.Check("vT8#qLp2!zR4", password.PolicyInput{}))
+}
+
+// TestPolicy_BreachedCorpusRangeDir - каталог range-файлов (k-anonymity, как в HIBP)
+func TestPolicy_BreachedCorpusRangeDir(t *testing.T) {
+	dir := t.TempDir()
+	hash := sha1Hex("Summer2024!")
+	rangeFile := "0000000000000000000000000000000000A:3\n" + hash[5:] + ":77\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(rangeFile), 0o600))
+
+	corpus, err := password.LoadBreachedCorpus(dir)
+	require.NoError(t, err)
+
+	count, err := corpus.Count("Summer2024!")
+	require.NoError(t, err)
+	assert.Equal(t, 77, count)
+
+	// Нет файла для prefix - пароль не встречался
+	count, err = corpus.Count("vT8#qLp2!zR4")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+// TestRegister_RejectsWeakPassword - регистрация применяет политику и не создаёт пользователя
+func TestRegister_RejectsWeakPassword(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	cfg := &config.Config{
+		JWTSecret:         "test-secret",
+		JWTExpiration:     "24h",
+		PasswordMinLength: 8,
+		PasswordMinScore:  2,
+	}
+	authService := service.NewAuthService(mockRepo, cfg)
+
+	mockRepo.On("FindByEmail", "bob@example.com").Return(nil, nil)
+
+	// Act
+	response, err := authService.Register(&domain.RegisterRequest{
+		Email:    "bob@example.com",
+		Name:     "Bob",
+		Password: "password",
+	}, domain.ClientInfo{})
+
+	// Assert
+	assert.Nil(t, response)
+	assert.Contains(t, violationCodes(t, err), password.ViolationTooWeak)
+	mockRepo.AssertNotCalled(t, "Create")
+}
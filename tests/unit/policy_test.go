@@ -0,0 +1,188 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/pkg/jwt"
+	"advanced-user-api/internal/pkg/policy"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// ТЕСТЫ POLICY ENGINE
+// ================================================================
+
+// examplePolicies - policies/example.yaml из корня репозитория
+const examplePolicies = "../../policies/example.yaml"
+
+// writePolicies - файл правил во временном каталоге
+func writePolicies(t *testing.T, dir, content string) string {
+	path := filepath.Join(dir, "policies.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// TestPolicyEngine_ExamplePolicies - решения по правилам из примера
+func TestPolicyEngine_ExamplePolicies(t *testing.T) {
+	// Arrange
+	engine, err := policy.NewEngine([]string{examplePolicies}, time.UTC)
+	require.NoError(t, err)
+
+	support := policy.Attributes{"id": uint(7), "role": "user", "roles": []interface{}{"user", "support"}, "impersonated": false}
+	target := policy.Attributes{"id": uint(9), "role": "user"}
+	admin := policy.Attributes{"id": uint(1), "role": "admin"}
+	wednesday := time.Date(2025, 10, 15, 10, 30, 0, 0, time.UTC)
+	saturday := time.Date(2025, 10, 18, 10, 30, 0, 0, time.UTC)
+
+	update := func(subject, resource policy.Attributes, at time.Time) policy.Decision {
+		return engine.Evaluate(policy.Request{
+			Subject: subject, Action: "users:update", ResourceType: "user", Resource: resource, Time: at,
+		})
+	}
+
+	// Act & Assert
+	decision := update(support, target, wednesday)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, "support-edit-business-hours", decision.PolicyID)
+
+	decision = update(support, target, saturday)
+	assert.False(t, decision.Allowed, "выходной")
+	assert.Equal(t, policy.EffectNotApplicable, decision.Effect)
+
+	decision = update(support, target, wednesday.Add(8*time.Hour))
+	assert.False(t, decision.Allowed, "18:30 - вне рабочего времени")
+
+	decision = update(support, admin, wednesday)
+	assert.False(t, decision.Allowed, "администратора поддержка не меняет")
+
+	decision = update(support, policy.Attributes{"id": 7, "role": "user"}, saturday)
+	assert.True(t, decision.Allowed, "свой профиль - всегда (uint и int равны)")
+	assert.Equal(t, "users-self-service", decision.PolicyID)
+
+	impersonating := policy.Attributes{"id": uint(9), "roles": []interface{}{"admin"}, "impersonated": true}
+	decision = engine.Evaluate(policy.Request{Subject: impersonating, Action: "users:delete", ResourceType: "user", Resource: target})
+	assert.False(t, decision.Allowed, "deny важнее allow")
+	assert.Equal(t, "no-delete-while-impersonating", decision.PolicyID)
+
+	// Explain - оценка каждого подходящего правила
+	decision = engine.Explain(policy.Request{Subject: support, Action: "users:update", ResourceType: "user", Resource: admin, Time: wednesday})
+	require.Len(t, decision.Trace, 3, "users-self-service, admins-manage-users, support-edit-business-hours")
+	for _, trace := range decision.Trace {
+		assert.False(t, trace.Matched)
+	}
+	assert.Contains(t, decision.Trace[2].Reason, "resource.role ne admin")
+}
+
+// TestPolicyEngine_InvalidPolicies - ошибки в файлах не загружаются
+func TestPolicyEngine_InvalidPolicies(t *testing.T) {
+	cases := map[string]string{
+		"неизвестный оператор": `policies: [{id: a, effect: allow, actions: ["*"], conditions: [{attribute: subject.id, operator: like, value: 1}]}]`,
+		"неверный effect":      `policies: [{id: a, effect: permit, actions: ["*"]}]`,
+		"без actions":          `policies: [{id: a, effect: allow}]`,
+		"повтор id":            `policies: [{id: a, effect: allow, actions: ["*"]}, {id: a, effect: deny, actions: ["*"]}]`,
+		"неверный атрибут":     `policies: [{id: a, effect: allow, actions: ["*"], conditions: [{attribute: user.id, operator: exists}]}]`,
+		"between без границ":   `policies: [{id: a, effect: allow, actions: ["*"], conditions: [{attribute: context.hour, operator: between, value: 9}]}]`,
+		"регулярное выражение": `policies: [{id: a, effect: allow, actions: ["*"], conditions: [{attribute: subject.email, operator: matches, value: "("}]}]`,
+		"не YAML": `policies: [`,
+	}
+
+	for name, content := range cases {
+		path := writePolicies(t, t.TempDir(), content)
+		_, err := policy.NewEngine([]string{path}, time.UTC)
+		assert.Error(t, err, name)
+	}
+}
+
+// TestPolicyEngine_HotReload - изменения файла подхватываются, ошибочный файл не ломает правила
+func TestPolicyEngine_HotReload(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	writePolicies(t, dir, `policies: [{id: first, effect: allow, actions: ["users:read"]}]`)
+
+	engine, err := policy.NewEngine([]string{dir}, time.UTC)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go engine.RunReload(ctx, 10*time.Millisecond)
+
+	read := policy.Request{Action: "users:read"}
+	require.True(t, engine.Evaluate(read).Allowed)
+
+	// Act: новая версия файла
+	writePolicies(t, dir, `{"policies": [{"id": "second", "effect": "allow", "actions": ["users:list"]}]}`)
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		policies := engine.Policies()
+		return len(policies) == 1 && policies[0].ID == "second"
+	}, time.Second, 10*time.Millisecond)
+	assert.False(t, engine.Evaluate(read).Allowed)
+
+	// Ошибка в файле - остаются прежние правила
+	writePolicies(t, dir, `policies: [{id: broken, effect: maybe, actions: ["*"]}]`)
+	time.Sleep(50 * time.Millisecond)
+	require.Len(t, engine.Policies(), 1)
+	assert.Equal(t, "second", engine.Policies()[0].ID)
+}
+
+// TestRequirePolicy_EnforceAndDryRun - middleware применяет решение, dry_run только пишет в журнал
+func TestRequirePolicy_EnforceAndDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := writePolicies(t, t.TempDir(), `
+policies:
+  - id: self-service
+    effect: allow
+    actions: ["users:update"]
+    resources: ["user"]
+    conditions:
+      - {attribute: resource.id, operator: eq, ref: subject.id}
+`)
+
+	request := func(mode string, targetID string) int {
+		// Arrange
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByID", uint(7)).Return(&domain.User{ID: 7, Email: "user@example.com", Role: "user"}, nil)
+		mockRepo.On("FindByID", uint(9)).Return(&domain.User{ID: 9, Email: "other@example.com", Role: "user"}, nil)
+
+		cfg := &config.Config{JWTSecret: "test-secret", PolicyFiles: path, PolicyMode: mode, PolicyDecisionLog: "off", PolicyTimezone: "UTC"}
+		authz, err := service.NewAuthorizationService(mockRepo, nil, cfg)
+		require.NoError(t, err)
+
+		router := gin.New()
+		router.PUT("/users/:id",
+			middleware.AuthMiddleware(cfg),
+			middleware.RequirePolicy(authz, "users:update", service.PolicyResourceUser, "id"),
+			func(c *gin.Context) { c.Status(http.StatusOK) },
+		)
+
+		token, err := jwt.GenerateTokenFromClaims(jwt.Claims{UserID: 7, Role: "user"}, "test-secret", time.Hour)
+		require.NoError(t, err)
+
+		// Act
+		req := httptest.NewRequest(http.MethodPut, "/users/"+targetID, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Assert
+	assert.Equal(t, http.StatusOK, request(service.PolicyModeEnforce, "7"), "свой профиль")
+	assert.Equal(t, http.StatusForbidden, request(service.PolicyModeEnforce, "9"), "чужой профиль")
+	assert.Equal(t, http.StatusOK, request(service.PolicyModeDryRun, "9"), "dry_run не запрещает")
+	assert.Equal(t, http.StatusBadRequest, request(service.PolicyModeEnforce, "abc"))
+}
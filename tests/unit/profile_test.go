@@ -0,0 +1,334 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/handler"
+	"advanced-user-api/internal/pkg/storage"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ================================================================
+// ВСПОМОГАТЕЛЬНЫЕ ФУНКЦИИ
+// ================================================================
+
+// pngImage - PNG width×height с полупрозрачной левой половиной
+func pngImage(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.NRGBA{R: 200, A: 0})
+			} else {
+				img.Set(x, y, color.NRGBA{B: 255, A: 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// avatarFiles - файлы аватара в каталоге хранилища
+func avatarFiles(t *testing.T, dir, avatarID string) []string {
+	entries, err := os.ReadDir(filepath.Join(dir, "avatars", avatarID))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// ================================================================
+// ТЕСТЫ ПРОФИЛЯ
+// ================================================================
+
+// TestProfile_UpdateRedactsSensitiveFields - телефон и "о себе" в журнал без значений
+func TestProfile_UpdateRedactsSensitiveFields(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	cfg := &config.Config{
+		AvatarDir:     dir,
+		AvatarURL:     "https://api.example.com/api/v1/avatars/",
+		AvatarMaxSize: 64 * 1024,
+	}
+	mockRepo := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	profiles := service.NewProfileService(mockRepo, storage.NewLocalStorage(dir), mockAudit, cfg)
+	user := &domain.User{ID: 7, Name: "Alice", Profile: domain.UserProfile{Timezone: "UTC"}}
+	mockRepo.On("FindByID", uint(7)).Return(user, nil)
+	mockRepo.On("Update", user).Return(nil)
+
+	var event *domain.AuditEvent
+	mockAudit.On("Record", mock.Anything).Run(func(args mock.Arguments) {
+		event = args.Get(0).(*domain.AuditEvent)
+	}).Return()
+
+	// Act
+	profile, err := profiles.Update(7, &domain.UpdateProfileRequest{
+		DisplayName: "  Ally ",
+		Phone:       "+79991234567",
+		Timezone:    "Europe/Moscow",
+	}, 7, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), profile.UserID)
+	assert.Equal(t, "Ally", profile.DisplayName)
+	assert.Equal(t, "+79991234567", user.Profile.Phone)
+	assert.Nil(t, profile.AvatarURLs)
+
+	require.NotNil(t, event)
+	assert.Equal(t, domain.AuditActionUserUpdated, event.Action)
+	assert.Equal(t, domain.AuditChange{Before: "UTC", After: "Europe/Moscow"}, event.Changes["timezone"])
+	assert.Equal(t, domain.AuditChange{Before: "", After: "(изменено)"}, event.Changes["phone"])
+	assert.NotContains(t, event.Changes, "bio")
+	assert.NotContains(t, event.Changes, "locale")
+}
+
+// TestProfile_UploadAvatarStoresThumbnails - перекодирование в JPEG всех размеров и замена старого аватара
+func TestProfile_UploadAvatarStoresThumbnails(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	cfg := &config.Config{
+		AvatarDir:     dir,
+		AvatarURL:     "https://api.example.com/api/v1/avatars/",
+		AvatarMaxSize: 64 * 1024,
+	}
+	mockRepo := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	profiles := service.NewProfileService(mockRepo, storage.NewLocalStorage(dir), mockAudit, cfg)
+	user := &domain.User{ID: 7}
+	mockRepo.On("FindByID", uint(7)).Return(user, nil)
+	mockRepo.On("Update", user).Return(nil)
+	mockAudit.On("Record", mock.Anything).Return()
+
+	first, err := profiles.UploadAvatar(7, bytes.NewReader(pngImage(t, 800, 400)), 7, domain.ClientInfo{})
+	require.NoError(t, err)
+	firstID := user.Profile.AvatarID
+
+	// Act
+	second, err := profiles.UploadAvatar(7, bytes.NewReader(pngImage(t, 100, 300)), 7, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, firstID, 32)
+	assert.NotEqual(t, firstID, user.Profile.AvatarID, "новая загрузка - новый адрес")
+	assert.Empty(t, avatarFiles(t, dir, firstID), "файлы прежнего аватара удалены")
+	assert.ElementsMatch(t, []string{"512.jpg", "128.jpg", "64.jpg"}, avatarFiles(t, dir, user.Profile.AvatarID))
+	assert.NotNil(t, second.AvatarUpdatedAt)
+	assert.Equal(t, "https://api.example.com/api/v1/avatars/"+firstID+"/128", first.AvatarURLs["128"])
+
+	// Квадрат из центра; маленькое изображение не увеличивается
+	expected := map[string]int{"512": 100, "128": 100, "64": 64}
+	for size, side := range expected {
+		object, err := profiles.OpenAvatar(user.Profile.AvatarID, size)
+		require.NoError(t, err)
+		img, err := jpeg.Decode(object.Body)
+		object.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, side, side), img.Bounds(), size)
+		assert.Equal(t, "image/jpeg", object.ContentType)
+	}
+}
+
+// TestProfile_UploadAvatarRejectsInvalidFiles - тип определяется по содержимому
+func TestProfile_UploadAvatarRejectsInvalidFiles(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	cfg := &config.Config{
+		AvatarDir:     dir,
+		AvatarURL:     "https://api.example.com/api/v1/avatars/",
+		AvatarMaxSize: 64 * 1024,
+	}
+	mockRepo := new(MockUserRepository)
+	profiles := service.NewProfileService(mockRepo, storage.NewLocalStorage(dir), new(MockAuditService), cfg)
+	mockRepo.On("FindByID", uint(7)).Return(&domain.User{ID: 7}, nil)
+	valid := pngImage(t, 64, 64)
+
+	cases := map[string]struct {
+		data []byte
+		err  error
+	}{
+		"html":      {[]byte("<html><script>alert(1)</script></html>"), service.ErrAvatarUnsupportedType},
+		"svg":       {[]byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), service.ErrAvatarUnsupportedType},
+		"truncated": {valid[:len(valid)/2], service.ErrAvatarInvalid},
+		"too large": {append(append([]byte{}, valid...), make([]byte, 64*1024)...), service.ErrAvatarTooLarge},
+	}
+
+	for name, tc := range cases {
+		// Act
+		_, err := profiles.UploadAvatar(7, bytes.NewReader(tc.data), 7, domain.ClientInfo{})
+
+		// Assert
+		assert.ErrorIs(t, err, tc.err, name)
+	}
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	_, err := os.Stat(filepath.Join(dir, "avatars"))
+	assert.True(t, os.IsNotExist(err), "отклонённые файлы не сохраняются")
+
+	// Неизвестный ID, размер или попытка выйти из каталога - 404 без обращения к диску
+	for _, params := range [][2]string{{"0123456789abcdef0123456789abcdef", "128"}, {"0123456789abcdef0123456789abcdef", "1024"}, {"..", "128"}} {
+		_, err := profiles.OpenAvatar(params[0], params[1])
+		assert.ErrorIs(t, err, service.ErrAvatarNotFound)
+	}
+}
+
+// TestLocalStorage_RejectsUnsafeKeys - ключ не может выйти за пределы каталога
+func TestLocalStorage_RejectsUnsafeKeys(t *testing.T) {
+	store := storage.NewLocalStorage(t.TempDir())
+	for _, key := range []string{"", "/etc/passwd", "../secret", "avatars/../../secret", "avatars//x", `avatars\x`} {
+		err := store.Put(context.Background(), key, strings.NewReader("x"), "text/plain")
+		assert.ErrorIs(t, err, storage.ErrInvalidKey, key)
+	}
+
+	require.NoError(t, store.Put(context.Background(), "a/b.txt", strings.NewReader("data"), "text/plain"))
+	_, err := store.Get(context.Background(), "a/missing.txt")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	require.NoError(t, store.Delete(context.Background(), "a/b.txt"))
+	require.NoError(t, store.Delete(context.Background(), "a/b.txt"), "повторное удаление - не ошибка")
+}
+
+// ================================================================
+// ТЕСТЫ HANDLER
+// ================================================================
+
+// TestProfileHandler_AvatarUploadAndCaching - multipart загрузка, 413, кеширование и 304
+func TestProfileHandler_AvatarUploadAndCaching(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	cfg := &config.Config{
+		AvatarDir:     dir,
+		AvatarURL:     "https://api.example.com/api/v1/avatars/",
+		AvatarMaxSize: 64 * 1024,
+	}
+	mockRepo := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	profiles := service.NewProfileService(mockRepo, storage.NewLocalStorage(dir), mockAudit, cfg)
+	user := &domain.User{ID: 7}
+	mockRepo.On("FindByID", uint(7)).Return(user, nil)
+	mockRepo.On("Update", user).Return(nil)
+	mockAudit.On("Record", mock.Anything).Return()
+	profileHandler := handler.NewProfileHandler(profiles)
+
+	router := gin.New()
+	me := router.Group("/users/me", func(c *gin.Context) { c.Set("userID", uint(7)) })
+	me.POST("/avatar", profileHandler.UploadAvatar)
+	me.PUT("/profile", profileHandler.UpdateOwn)
+	router.GET("/avatars/:id/:size", profileHandler.ServeAvatar)
+
+	upload := func(data []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("avatar", "me.png")
+		require.NoError(t, err)
+		_, err = part.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, form.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/users/me/avatar", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act & Assert: загрузка
+	rec := upload(pngImage(t, 300, 300))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var profile domain.ProfileResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &profile))
+	require.Contains(t, profile.AvatarURLs, "64")
+
+	// Файл больше AVATAR_MAX_SIZE и не изображение
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload(make([]byte, 200*1024)).Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, upload([]byte("GIF89 not really")).Code)
+
+	// Отдача с заголовками кеширования
+	path := strings.TrimPrefix(profile.AvatarURLs["64"], "https://api.example.com/api/v1")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Cache-Control"), "immutable")
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	// Валидация профиля
+	req = httptest.NewRequest(http.MethodPut, "/users/me/profile", strings.NewReader(`{"phone":"8 999 123","timezone":"Mars/Olympus"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// MockAvatarRemover - мок удаления файлов аватара
+type MockAvatarRemover struct {
+	mock.Mock
+}
+
+func (m *MockAvatarRemover) RemoveAvatarFiles(avatarID string) {
+	m.Called(avatarID)
+}
+
+// TestUserService_PurgeExpiredRemovesAvatars - файлы аватаров стираемых аккаунтов удаляются
+func TestUserService_PurgeExpiredRemovesAvatars(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	mockAvatars := new(MockAvatarRemover)
+	now := time.Now()
+	cutoff := now.Add(-7 * 24 * time.Hour)
+
+	mockRepo.On("FindDeleted").Return([]domain.User{
+		{ID: 1, Profile: domain.UserProfile{AvatarID: "recent"}, DeletedAt: gorm.DeletedAt{Time: now.Add(-time.Hour), Valid: true}},
+		{ID: 2, Profile: domain.UserProfile{AvatarID: "expired"}, DeletedAt: gorm.DeletedAt{Time: cutoff.Add(-time.Hour), Valid: true}},
+		{ID: 3, DeletedAt: gorm.DeletedAt{Time: cutoff.Add(-time.Hour), Valid: true}},
+	}, nil)
+	mockRepo.On("PurgeDeletedBefore", cutoff).Return(int64(2), nil, nil)
+	mockAvatars.On("RemoveAvatarFiles", "expired").Return()
+	userService := service.NewUserService(mockRepo,
+		service.WithDeletedUserPurge(7*24*time.Hour),
+		service.WithAvatarRemover(mockAvatars),
+	)
+
+	// Act
+	count, err := userService.PurgeExpired(now)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	mockAvatars.AssertNumberOfCalls(t, "RemoveAvatarFiles", 1)
+}
@@ -0,0 +1,226 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/middleware"
+	"advanced-user-api/internal/pkg/jwt"
+	"advanced-user-api/internal/pkg/mailer"
+	"advanced-user-api/internal/pkg/password"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// MOCK SESSION REPOSITORY
+// ================================================================
+
+// MockSessionRepository - мок хранилища сеансов
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) Create(session *domain.Session) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) FindByID(id string) (*domain.Session, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) FindActiveByUserID(userID uint, now time.Time) ([]domain.Session, error) {
+	args := m.Called(userID, now)
+	return args.Get(0).([]domain.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) Touch(id string, seenAt time.Time) error {
+	args := m.Called(id, seenAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) Revoke(id string, userID uint, at time.Time) (bool, error) {
+	args := m.Called(id, userID, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionRepository) RevokeAllExcept(userID uint, keepID string, at time.Time) (int64, error) {
+	args := m.Called(userID, keepID, at)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// ================================================================
+// ТЕСТЫ SESSIONS
+// ================================================================
+
+// TestTokenIssuer_OpensSession - каждый токен привязан к новому сеансу
+func TestTokenIssuer_OpensSession(t *testing.T) {
+	// Arrange
+	mockSessions := new(MockSessionRepository)
+	cfg := &config.Config{JWTSecret: "test-secret", JWTExpiration: "2h"}
+	issuer := service.NewTokenIssuer(cfg, mockSessions)
+	user := &domain.User{ID: 4, Email: "erin@example.com", Role: "user"}
+	client := domain.ClientInfo{
+		IP:        "192.0.2.10",
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36",
+	}
+
+	var session *domain.Session
+	mockSessions.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		session = args.Get(0).(*domain.Session)
+	}).Return(nil)
+
+	// Act
+	response, err := issuer.Issue(user, client)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, session.UserID)
+	assert.Equal(t, "Chrome on macOS", session.DeviceName)
+	assert.Equal(t, client.IP, session.IP)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), session.ExpiresAt, time.Minute)
+
+	claims, err := jwt.ValidateToken(response.Token, "test-secret")
+	require.NoError(t, err)
+	assert.NotEmpty(t, claims.SessionID)
+	assert.Equal(t, session.ID, claims.SessionID)
+
+	// Название из X-Device-Name важнее User-Agent
+	_, err = issuer.Issue(user, domain.ClientInfo{UserAgent: client.UserAgent, DeviceName: "Рабочий ноутбук"})
+	require.NoError(t, err)
+	assert.Equal(t, "Рабочий ноутбук", session.DeviceName)
+}
+
+// TestSessionService_ListFlagsCurrent - текущий сеанс помечен в списке
+func TestSessionService_ListFlagsCurrent(t *testing.T) {
+	// Arrange
+	mockSessions := new(MockSessionRepository)
+	sessionService := service.NewSessionService(mockSessions)
+	mockSessions.On("FindActiveByUserID", uint(4), mock.Anything).Return([]domain.Session{
+		{ID: "phone", UserID: 4},
+		{ID: "laptop", UserID: 4},
+	}, nil)
+
+	// Act
+	sessions, err := sessionService.List(4, "laptop")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+}
+
+// TestSessionService_RevokeOthers - текущий сеанс не завершается
+func TestSessionService_RevokeOthers(t *testing.T) {
+	// Arrange
+	mockSessions := new(MockSessionRepository)
+	sessionService := service.NewSessionService(mockSessions)
+	mockSessions.On("RevokeAllExcept", uint(4), "laptop", mock.Anything).Return(int64(2), nil)
+	mockSessions.On("Revoke", "tablet", uint(4), mock.Anything).Return(false, nil)
+
+	// Act
+	count, err := sessionService.RevokeOthers(4, "laptop")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Чужой или уже завершённый сеанс
+	err = sessionService.Revoke(4, "tablet")
+	assert.ErrorIs(t, err, service.ErrSessionNotFound)
+}
+
+// TestAuthMiddleware_RejectsRevokedSession - токен завершённого сеанса отклоняется
+func TestAuthMiddleware_RejectsRevokedSession(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	mockSessions := new(MockSessionRepository)
+	sessionService := service.NewSessionService(mockSessions)
+
+	revokedAt := time.Now().Add(-time.Minute)
+	expiresAt := time.Now().Add(time.Hour)
+	mockSessions.On("FindByID", "active").Return(&domain.Session{ID: "active", UserID: 4, LastSeenAt: time.Now(), ExpiresAt: expiresAt}, nil)
+	mockSessions.On("FindByID", "stale").Return(&domain.Session{ID: "stale", UserID: 4, LastSeenAt: time.Now().Add(-time.Hour), ExpiresAt: expiresAt}, nil)
+	mockSessions.On("FindByID", "revoked").Return(&domain.Session{ID: "revoked", UserID: 4, ExpiresAt: expiresAt, RevokedAt: &revokedAt}, nil)
+	mockSessions.On("Touch", "stale", mock.Anything).Return(nil)
+
+	var currentSession string
+	router := gin.New()
+	router.GET("/me", middleware.AuthMiddleware(&config.Config{JWTSecret: "test-secret"}, sessionService),
+		func(c *gin.Context) {
+			currentSession = middleware.GetSessionIDFromContext(c)
+			c.Status(http.StatusOK)
+		})
+
+	request := func(sessionID string) int {
+		token, err := jwt.GenerateTokenFromClaims(jwt.Claims{UserID: 4, SessionID: sessionID}, "test-secret", time.Hour)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Act & Assert
+	assert.Equal(t, http.StatusOK, request("active"))
+	assert.Equal(t, "active", currentSession)
+	mockSessions.AssertNotCalled(t, "Touch", "active", mock.Anything)
+
+	// last_seen_at обновляется не чаще раза в минуту
+	assert.Equal(t, http.StatusOK, request("stale"))
+	mockSessions.AssertCalled(t, "Touch", "stale", mock.Anything)
+
+	assert.Equal(t, http.StatusUnauthorized, request("revoked"))
+	assert.Equal(t, http.StatusUnauthorized, request(""), "токен без сеанса")
+}
+
+// TestChangePassword_RevokesSessions - смена пароля завершает все сеансы и открывает новый
+func TestChangePassword_RevokesSessions(t *testing.T) {
+	// Arrange
+	hasher := password.NewHasher(fastArgon2())
+	hash, err := hasher.Hash("old-Passw0rd!")
+	require.NoError(t, err)
+	mockRepo := new(MockUserRepository)
+	mockSessions := new(MockSessionRepository)
+	authService := service.NewAuthService(mockRepo, &config.Config{JWTSecret: "test-secret", JWTExpiration: "24h"},
+		service.WithPasswordHasher(hasher),
+		service.WithSessionRepository(mockSessions),
+		service.WithMailer(mailer.NewLogMailer()),
+	)
+	user := &domain.User{ID: 7, Email: "alice@example.com", Name: "Alice", Password: hash, Role: "user",
+		AuthProvider: domain.AuthProviderLocal, TokenVersion: 2}
+
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("Update", user).Return(nil)
+	mockSessions.On("RevokeAllExcept", user.ID, "", mock.Anything).Return(int64(3), nil)
+	mockSessions.On("Create", mock.Anything).Return(nil)
+
+	// Act
+	response, err := authService.ChangePassword(user.ID, &domain.ChangePasswordRequest{
+		CurrentPassword: "old-Passw0rd!",
+		NewPassword:     "vT8#qLp2!zR4",
+	}, domain.ClientInfo{UserAgent: "curl/8.0"})
+
+	// Assert
+	require.NoError(t, err)
+	claims, err := jwt.ValidateToken(response.Token, "test-secret")
+	require.NoError(t, err)
+	assert.NotEmpty(t, claims.SessionID, "новый токен получает новый сеанс")
+	mockSessions.AssertExpectations(t)
+}
@@ -0,0 +1,451 @@
+package unit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/handler"
+	"advanced-user-api/internal/pkg/parquet"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// exportedUser - пользователь с атрибутами для выгрузки
+func exportedUser(id uint, email string, attributes domain.UserAttributes) domain.User {
+	return domain.User{
+		ID:         id,
+		Email:      email,
+		Name:       "User " + email[:1],
+		Role:       "user",
+		Status:     domain.UserStatusActive,
+		Attributes: attributes,
+		CreatedAt:  time.Date(2025, 10, 18, 12, 0, int(id), 0, time.FixedZone("MSK", 3*3600)),
+	}
+}
+
+// thriftReader - чтение Thrift compact protocol: структура → map[ID поля]значение
+// Числа - int64, строки - string, списки - []interface{}, структуры - map[int16]interface{}
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	value, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return value
+}
+
+func (r *thriftReader) zigzag() int64 {
+	value := r.uvarint()
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	fields := map[int16]interface{}{}
+	var last int16
+	for {
+		header := r.data[r.pos]
+		r.pos++
+		if header == 0 {
+			return fields
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		fields[id] = r.readValue(header & 0x0F)
+		last = id
+	}
+}
+
+func (r *thriftReader) readValue(fieldType byte) interface{} {
+	switch fieldType {
+	case 1, 2:
+		return fieldType == 1
+	case 4, 5, 6:
+		return r.zigzag()
+	case 8:
+		size := int(r.uvarint())
+		r.pos += size
+		return string(r.data[r.pos-size : r.pos])
+	case 9:
+		header := r.data[r.pos]
+		r.pos++
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		items := make([]interface{}, size)
+		for i := range items {
+			items[i] = r.readValue(header & 0x0F)
+		}
+		return items
+	case 12:
+		return r.readStruct()
+	}
+	panic("неожиданный тип thrift")
+}
+
+// parquetFooter - FileMetaData файла (проверяет PAR1 в начале и в конце)
+func parquetFooter(t *testing.T, file []byte) map[int16]interface{} {
+	require.True(t, len(file) > 12)
+	require.Equal(t, "PAR1", string(file[:4]))
+	require.Equal(t, "PAR1", string(file[len(file)-4:]))
+	size := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	reader := &thriftReader{data: file[len(file)-8-size : len(file)-8]}
+	footer := reader.readStruct()
+	require.Equal(t, size, reader.pos, "FileMetaData занимает ровно указанную длину")
+	return footer
+}
+
+// parquetColumnNames - имена колонок схемы (без корня)
+func parquetColumnNames(footer map[int16]interface{}) []string {
+	var names []string
+	for _, element := range footer[2].([]interface{})[1:] {
+		names = append(names, element.(map[int16]interface{})[4].(string))
+	}
+	return names
+}
+
+// parquetPage - уровни определения и PLAIN значения страницы колонки column группы group
+func parquetPage(t *testing.T, file []byte, footer map[int16]interface{}, group, column int) ([]bool, []byte) {
+	rowGroup := footer[4].([]interface{})[group].(map[int16]interface{})
+	chunk := rowGroup[1].([]interface{})[column].(map[int16]interface{})
+	meta := chunk[3].(map[int16]interface{})
+
+	reader := &thriftReader{data: file, pos: int(meta[9].(int64))}
+	header := reader.readStruct()
+	numValues := int(header[5].(map[int16]interface{})[1].(int64))
+	body := file[reader.pos : reader.pos+int(header[3].(int64))]
+	require.Equal(t, meta[7].(int64), int64(reader.pos-int(meta[9].(int64))+len(body)), "размер колонки = заголовок + страница")
+
+	// Уровни: длина, заголовок bit-packed отрезка, биты
+	length := int(binary.LittleEndian.Uint32(body))
+	levels := &thriftReader{data: body[4 : 4+length]}
+	require.Equal(t, uint64((numValues+7)/8)<<1|1, levels.uvarint())
+	defined := make([]bool, numValues)
+	for i := range defined {
+		defined[i] = levels.data[levels.pos+i/8]&(1<<(i%8)) != 0
+	}
+	return defined, body[4+length:]
+}
+
+// ================================================================
+// ТЕСТЫ PARQUET WRITER
+// ================================================================
+
+// TestParquetWriter_RowGroupsAndValues - группы строк, null, метаданные и значения PLAIN
+func TestParquetWriter_RowGroupsAndValues(t *testing.T) {
+	// Arrange
+	var file bytes.Buffer
+	writer, err := parquet.NewWriter(&file, []parquet.Column{
+		{Name: "id", Type: parquet.Int64},
+		{Name: "email", Type: parquet.String},
+		{Name: "level", Type: parquet.Double},
+		{Name: "vip", Type: parquet.Boolean},
+		{Name: "created_at", Type: parquet.Timestamp},
+	}, 2)
+	require.NoError(t, err)
+	created := time.Date(2025, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	// Act
+	require.NoError(t, writer.Write([]interface{}{int64(1), "alice@example.com", 3.5, true, created}))
+	require.NoError(t, writer.Write([]interface{}{int64(2), nil, nil, false, created}))
+	require.NoError(t, writer.Write([]interface{}{int64(3), "carol@example.com", 1.0, nil, nil}))
+	assert.ErrorIs(t, writer.Write([]interface{}{"4", nil, nil, nil, nil}), parquet.ErrValueType)
+	assert.ErrorIs(t, writer.Write([]interface{}{int64(4)}), parquet.ErrRowLength)
+	require.NoError(t, writer.Close())
+	assert.ErrorIs(t, writer.Write([]interface{}{int64(5), nil, nil, nil, nil}), parquet.ErrClosed)
+
+	// Assert: метаданные
+	footer := parquetFooter(t, file.Bytes())
+	assert.Equal(t, int64(3), footer[3], "num_rows")
+	assert.Equal(t, []string{"id", "email", "level", "vip", "created_at"}, parquetColumnNames(footer))
+	assert.Len(t, footer[4], 2, "группы по 2 строки")
+	createdAt := footer[2].([]interface{})[5].(map[int16]interface{})
+	assert.Equal(t, int64(2), createdAt[1], "INT64")
+	assert.Equal(t, int64(9), createdAt[6], "TIMESTAMP_MILLIS")
+
+	// Assert: email первой группы - null во второй строке
+	defined, values := parquetPage(t, file.Bytes(), footer, 0, 1)
+	assert.Equal(t, []bool{true, false}, defined)
+	assert.Equal(t, uint32(len("alice@example.com")), binary.LittleEndian.Uint32(values))
+	assert.Equal(t, "alice@example.com", string(values[4:]))
+
+	// Assert: level второй группы, vip и created_at первой
+	defined, values = parquetPage(t, file.Bytes(), footer, 1, 2)
+	assert.Equal(t, []bool{true}, defined)
+	assert.Equal(t, 1.0, math.Float64frombits(binary.LittleEndian.Uint64(values)))
+	defined, values = parquetPage(t, file.Bytes(), footer, 0, 3)
+	assert.Equal(t, []bool{true, true}, defined)
+	assert.Equal(t, []byte{0b01}, values, "true, false по битам")
+	_, values = parquetPage(t, file.Bytes(), footer, 0, 4)
+	assert.Equal(t, created.UnixMilli(), int64(binary.LittleEndian.Uint64(values)))
+}
+
+// ================================================================
+// ТЕСТЫ ВЫГРУЗКИ ПОЛЬЗОВАТЕЛЕЙ
+// ================================================================
+
+// TestUserExport_StreamsCSVInBatches - пачки по ID, колонки атрибутов по схеме, журнал
+func TestUserExport_StreamsCSVInBatches(t *testing.T) {
+	// Arrange
+	mockUsers := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+	cfg := &config.Config{UserExportBatchSize: 2}
+	exportService := service.NewUserExportService(mockUsers, attributes, mockAudit, cfg).ForTenant(2)
+	filter := domain.UserFilter{GroupID: 3}
+	mockUsers.On("FindPage", filter, uint(0), 2).Return([]domain.User{
+		exportedUser(1, "alice@example.com", domain.UserAttributes{"department": "sales", "level": 3.0, "vip": true}),
+		exportedUser(2, "bob@example.com", domain.UserAttributes{"department": "support", "level": "high"}),
+	}, nil).Once()
+	mockUsers.On("FindPage", filter, uint(2), 2).Return([]domain.User{
+		exportedUser(5, "carol@example.com", nil),
+	}, nil).Once()
+	var event *domain.AuditEvent
+	mockAudit.On("Record", mock.Anything).Run(func(args mock.Arguments) {
+		event = args.Get(0).(*domain.AuditEvent)
+	})
+
+	// Act
+	export, err := exportService.Prepare(1, &domain.UserExportRequest{
+		Columns: "id, email,attr.department,attr.level,attr.vip,created_at,attributes",
+		Filter:  filter,
+	}, domain.ClientInfo{IP: "10.0.0.1"})
+	require.NoError(t, err)
+	var out bytes.Buffer
+	err = export.Stream(&out)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), mockUsers.TenantID)
+	assert.Equal(t, "id,email,attr.department,attr.level,attr.vip,created_at,attributes\n"+
+		`1,alice@example.com,sales,3,true,2025-10-18T09:00:01Z,"{""department"":""sales"",""level"":3,""vip"":true}"`+"\n"+
+		`2,bob@example.com,support,,,2025-10-18T09:00:02Z,"{""department"":""support"",""level"":""high""}"`+"\n"+
+		"5,carol@example.com,,,,2025-10-18T09:00:05Z,{}\n", out.String(), "значение не по типу схемы - пусто, время - UTC")
+	assert.Equal(t, 3, export.Rows)
+	assert.Equal(t, uint(5), export.Cursor)
+	mockUsers.AssertExpectations(t)
+
+	require.NotNil(t, event)
+	assert.Equal(t, domain.AuditActionUsersExported, event.Action)
+	assert.Equal(t, "10.0.0.1", event.IP)
+	assert.Contains(t, event.Details, "строк 3")
+}
+
+// TestUserExport_CSVEscapesFormulas - строки, похожие на формулы, экранируются ' (USER_EXPORT_CSV_RAW - нет)
+func TestUserExport_CSVEscapesFormulas(t *testing.T) {
+	export := func(raw bool) string {
+		mockUsers := new(MockUserRepository)
+		mockAttributes := new(MockAttributeRepository)
+		mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+		attributes := service.NewAttributeService(mockAttributes, nil)
+		mockAudit := new(MockAuditService)
+		mockAudit.On("Record", mock.Anything)
+		cfg := &config.Config{UserExportBatchSize: 10, UserExportCSVRaw: raw}
+		exportService := service.NewUserExportService(mockUsers, attributes, mockAudit, cfg).ForTenant(2)
+
+		users := []domain.User{
+			exportedUser(1, "alice@example.com", domain.UserAttributes{"department": "=HYPERLINK(\"http://evil\")", "level": -3.0}),
+			exportedUser(2, "bob@example.com", domain.UserAttributes{"department": "@SUM(A1)"}),
+			exportedUser(3, "carol@example.com", domain.UserAttributes{"department": "sales-east"}),
+		}
+		users[0].Name = "+1 (555) 010"
+		users[1].Name = "-2+3"
+		users[2].Name = "\tcmd"
+		mockUsers.On("FindPage", domain.UserFilter{}, uint(0), 10).Return(users, nil)
+
+		prepared, err := exportService.Prepare(1, &domain.UserExportRequest{Columns: "id,name,attr.department,attr.level"}, domain.ClientInfo{})
+		require.NoError(t, err)
+		var out bytes.Buffer
+		require.NoError(t, prepared.Stream(&out))
+		return out.String()
+	}
+
+	// Act & Assert: числа (-3) не экранируются, значение без префикса - как есть
+	assert.Equal(t, "id,name,attr.department,attr.level\n"+
+		`1,'+1 (555) 010,"'=HYPERLINK(""http://evil"")",-3`+"\n"+
+		"2,'-2+3,'@SUM(A1),\n"+
+		"3,'\tcmd,sales-east,\n", export(false))
+	assert.Equal(t, "id,name,attr.department,attr.level\n"+
+		`1,+1 (555) 010,"=HYPERLINK(""http://evil"")",-3`+"\n"+
+		"2,-2+3,@SUM(A1),\n"+
+		"3,\"\tcmd\",sales-east,\n", export(true))
+}
+
+// TestUserExport_NDJSONResumeAndFailure - продолжение с cursor, ошибка посреди выгрузки
+func TestUserExport_NDJSONResumeAndFailure(t *testing.T) {
+	// Arrange
+	mockUsers := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+	cfg := &config.Config{UserExportBatchSize: 2}
+	exportService := service.NewUserExportService(mockUsers, attributes, mockAudit, cfg).ForTenant(2)
+	mockUsers.On("FindPage", domain.UserFilter{}, uint(10), 2).Return([]domain.User{
+		exportedUser(11, "alice@example.com", domain.UserAttributes{"vip": false}),
+		exportedUser(12, "bob@example.com", nil),
+	}, nil)
+	mockUsers.On("FindPage", domain.UserFilter{}, uint(12), 2).Return([]domain.User(nil), errors.New("соединение с БД потеряно"))
+	var event *domain.AuditEvent
+	mockAudit.On("Record", mock.Anything).Run(func(args mock.Arguments) {
+		event = args.Get(0).(*domain.AuditEvent)
+	})
+
+	// Act
+	export, err := exportService.Prepare(1, &domain.UserExportRequest{
+		Format:  domain.UserExportFormatNDJSON,
+		Columns: "email,id,attr.vip",
+		Cursor:  10,
+	}, domain.ClientInfo{})
+	require.NoError(t, err)
+	var out bytes.Buffer
+	err = export.Stream(&out)
+
+	// Assert: первая пачка уже у клиента, продолжать - с 12
+	require.Error(t, err)
+	assert.Equal(t, `{"email":"alice@example.com","id":11,"attr.vip":false}`+"\n"+
+		`{"email":"bob@example.com","id":12,"attr.vip":null}`+"\n", out.String())
+	assert.Equal(t, 2, export.Rows)
+	assert.Equal(t, uint(12), export.Cursor)
+
+	require.NotNil(t, event)
+	assert.Contains(t, event.Details, "продолжение после 10")
+	assert.Contains(t, event.Details, "прервана")
+}
+
+// TestUserExport_ParquetAndColumns - Parquet с типами колонок; неизвестные колонки отклоняются
+func TestUserExport_ParquetAndColumns(t *testing.T) {
+	// Arrange
+	mockUsers := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+	cfg := &config.Config{UserExportBatchSize: 2}
+	exportService := service.NewUserExportService(mockUsers, attributes, mockAudit, cfg).ForTenant(2)
+	mockAudit.On("Record", mock.Anything)
+	mockUsers.On("FindPage", domain.UserFilter{}, uint(0), 2).Return([]domain.User{
+		exportedUser(1, "alice@example.com", domain.UserAttributes{"level": 2.5}),
+	}, nil)
+
+	// Act & Assert: колонки
+	for _, columns := range []string{"id,password", "id,id", "attr.unknown", "attr."} {
+		_, err := exportService.Prepare(1, &domain.UserExportRequest{Columns: columns}, domain.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrUserExportColumn, columns)
+	}
+
+	// Act & Assert: Parquet
+	export, err := exportService.Prepare(1, &domain.UserExportRequest{Format: domain.UserExportFormatParquet, Columns: "id,email,attr.level,attributes,password_changed_at"}, domain.ClientInfo{})
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, export.Stream(&out))
+	assert.Equal(t, 1, export.Rows)
+
+	footer := parquetFooter(t, out.Bytes())
+	assert.Equal(t, int64(1), footer[3])
+	assert.Equal(t, []string{"id", "email", "attr.level", "attributes", "password_changed_at"}, parquetColumnNames(footer))
+	level := footer[2].([]interface{})[3].(map[int16]interface{})
+	assert.Equal(t, int64(5), level[1], "число - DOUBLE")
+	_, values := parquetPage(t, out.Bytes(), footer, 0, 3)
+	assert.Equal(t, `{"level":2.5}`, string(values[4:]), "attributes - JSON строкой")
+	defined, _ := parquetPage(t, out.Bytes(), footer, 0, 4)
+	assert.Equal(t, []bool{false}, defined, "пароль не меняли - null")
+}
+
+// TestUserExportHandler_StreamsWithFiltersAndGzip - фильтры GET /users, gzip, трейлеры, ошибки
+func TestUserExportHandler_StreamsWithFiltersAndGzip(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	mockUsers := new(MockUserRepository)
+	mockAudit := new(MockAuditService)
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+	cfg := &config.Config{UserExportBatchSize: 10}
+	exportService := service.NewUserExportService(mockUsers, attributes, mockAudit, cfg).ForTenant(2)
+	mockAudit.On("Record", mock.Anything)
+	filter := domain.UserFilter{GroupID: 3, Attributes: domain.UserAttributes{"department": "sales"}}
+	mockUsers.On("FindPage", filter, uint(0), 10).Return([]domain.User{
+		exportedUser(1, "alice@example.com", nil),
+		exportedUser(4, "dave@example.com", nil),
+	}, nil)
+	mockUsers.On("FindPage", domain.UserFilter{}, uint(0), 10).Return([]domain.User(nil), errors.New("БД недоступна"))
+	exportHandler := handler.NewUserExportHandler(exportService, attributes)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Set("userRole", "admin")
+		c.Set("tenantID", uint(2))
+	})
+	router.GET("/admin/users/export", exportHandler.Export)
+
+	request := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act: сжатая выгрузка с фильтрами
+	rec := request("/admin/users/export?group=3&attr.department=sales&columns=id,email", "br, gzip")
+
+	// Assert
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), `filename="users.csv"`)
+	reader, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "id,email\n1,alice@example.com\n4,dave@example.com\n", string(body))
+
+	trailer := rec.Result().Trailer
+	assert.Equal(t, "4", trailer.Get("X-Export-Cursor"))
+	assert.Equal(t, "2", trailer.Get("X-Export-Rows"))
+	assert.Equal(t, "complete", trailer.Get("X-Export-Status"))
+
+	// Act & Assert: ошибки параметров - до начала ответа
+	assert.Equal(t, http.StatusBadRequest, request("/admin/users/export?columns=id,password", "").Code)
+	assert.Equal(t, http.StatusBadRequest, request("/admin/users/export?format=xlsx", "").Code)
+	assert.Equal(t, http.StatusBadRequest, request("/admin/users/export?group=abc", "").Code)
+	assert.Equal(t, http.StatusBadRequest, request("/admin/users/export?attr.level=high", "").Code)
+	assert.Equal(t, http.StatusBadRequest, request("/admin/users/export?columns=email&cursor=4", "").Code,
+		"без id продолжать выгрузку не с чего")
+
+	// Act & Assert: БД недоступна с первого запроса - обычный ответ 500 без сжатия
+	rec = request("/admin/users/export", "gzip;q=0, deflate")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.True(t, strings.Contains(rec.Body.String(), "ошибка выгрузки пользователей"))
+}
@@ -0,0 +1,550 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"advanced-user-api/internal/config"
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/handler"
+	"advanced-user-api/internal/pkg/password"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ================================================================
+// MOCK USER IMPORT REPOSITORY
+// ================================================================
+
+// MockUserImportRepository - мок задач импорта
+type MockUserImportRepository struct {
+	mock.Mock
+
+	// Progress - Processed задачи при каждом Update
+	Progress []int
+}
+
+func (m *MockUserImportRepository) Create(job *domain.UserImport) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockUserImportRepository) FindByID(id uint) (*domain.UserImport, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.UserImport), args.Error(1)
+}
+
+func (m *MockUserImportRepository) Update(job *domain.UserImport) error {
+	m.Progress = append(m.Progress, job.Processed)
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockUserImportRepository) ClaimPending(now, staleBefore time.Time) (*domain.UserImport, error) {
+	args := m.Called(now, staleBefore)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.UserImport), args.Error(1)
+}
+
+func (m *MockUserImportRepository) SaveBatch(orgID uint, users []*domain.User) error {
+	args := m.Called(orgID, users)
+	return args.Error(0)
+}
+
+// ================================================================
+// HELPERS
+// ================================================================
+
+// savedEmails - email пользователей пачки
+func savedEmails(users []*domain.User) []string {
+	emails := make([]string, 0, len(users))
+	for _, user := range users {
+		emails = append(emails, user.Email)
+	}
+	return emails
+}
+
+// rowErrors - номер строки → её ошибки одной строкой
+func rowErrors(job *domain.UserImport) map[int]string {
+	rows := map[int]string{}
+	for _, rowError := range job.Errors {
+		rows[rowError.Row] = strings.Join(rowError.Errors, "; ")
+	}
+	return rows
+}
+
+// ================================================================
+// ТЕСТЫ ИМПОРТА ПОЛЬЗОВАТЕЛЕЙ
+// ================================================================
+
+// TestUserImport_ValidatesRowsAndWritesBatches - ошибки по строкам, правильные строки - пачками
+func TestUserImport_ValidatesRowsAndWritesBatches(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		UserImportDir:       t.TempDir(),
+		UserImportMaxSize:   1024,
+		UserImportBatchSize: 2,
+	}
+	mockRepo := new(MockUserRepository)
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+	mockImports := new(MockUserImportRepository)
+	mockImports.On("Create", mock.AnythingOfType("*domain.UserImport")).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.UserImport).ID = 5
+	}).Return(nil)
+	mockImports.On("Update", mock.AnythingOfType("*domain.UserImport")).Return(nil)
+	importService := service.NewUserImportService(mockImports, mockRepo, attributes, nil, nil, cfg,
+		service.WithUserImportPasswordHasher(password.NewHasher(fastArgon2())),
+		service.WithUserImportPasswordPolicy(password.NewPolicy(password.PolicyConfig{}, nil)),
+	).ForTenant(2)
+
+	mockRepo.On("FindByEmail", mock.Anything).Return(nil, nil)
+	var batches [][]*domain.User
+	mockImports.On("SaveBatch", uint(2), mock.Anything).Run(func(args mock.Arguments) {
+		batches = append(batches, args.Get(1).([]*domain.User))
+	}).Return(nil)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	file := "email,name,password,password_hash,attr.department,attr.vip\n" +
+		"alice@example.com,Alice,vT8#qLp2!zR4,,sales,true\n" + // 2: создаётся
+		"bob@example.com,Bob,,\"" + string(legacy) + "\",support,false\n" + // 3: готовый хеш
+		"not-an-email,Carol,vT8#qLp2!zR4,,sales,true\n" + // 4
+		"ALICE@example.com,Alice Two,vT8#qLp2!zR4,,sales,true\n" + // 5: повтор строки 2
+		"dave@example.com,Dave,short,,sales,true\n" + // 6: политика паролей
+		"erin@example.com,Erin,,,sales,true\n" + // 7: без пароля и без invite
+		"frank@example.com,Frank,vT8#qLp2!zR4,,marketing,maybe\n" + // 8: атрибуты
+		"grace@example.com,Grace,vT8#qLp2!zR4,,support,false\n" // 9: создаётся
+
+	// Act
+	job, err := importService.Import(1, &domain.CreateUserImportRequest{Format: domain.UserImportFormatCSV}, strings.NewReader(file))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserImportStatusCompleted, job.Status)
+	assert.Equal(t, uint(2), job.OrganizationID)
+	assert.Equal(t, 8, job.Total)
+	assert.Equal(t, 8, job.Processed)
+	assert.Equal(t, 3, job.Created)
+	assert.Equal(t, 5, job.Failed)
+	assert.Subset(t, mockImports.Progress, []int{2, 4, 6, 8}, "прогресс сохраняется после каждой пачки")
+
+	require.Len(t, batches, 2)
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, savedEmails(batches[0]))
+	assert.Equal(t, []string{"grace@example.com"}, savedEmails(batches[1]))
+	alice, bob := batches[0][0], batches[0][1]
+	assert.True(t, strings.HasPrefix(alice.Password, "$argon2id$"))
+	assert.Equal(t, string(legacy), bob.Password, "готовый хеш переносится как есть")
+	assert.Equal(t, "user", alice.Role)
+	assert.Equal(t, domain.UserAttributes{"department": "sales", "vip": true}, alice.Attributes)
+
+	errors := rowErrors(job)
+	assert.Len(t, errors, 5)
+	assert.Contains(t, errors[4], "email: невалидный email")
+	assert.Contains(t, errors[5], "email: повторяет строку 2")
+	assert.Contains(t, errors[6], "password: ")
+	assert.Contains(t, errors[7], "password: укажите password или password_hash")
+	assert.Contains(t, errors[8], "attr.department: ")
+	assert.Contains(t, errors[8], "attr.vip: ")
+
+	entries, err := os.ReadDir(cfg.UserImportDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "файл удаляется после обработки")
+}
+
+// TestUserImport_UpsertInviteAndBatchFailure - обновление по email, приглашения,
+// ошибка пачки остаётся только у строки, которая её вызвала
+func TestUserImport_UpsertInviteAndBatchFailure(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		UserImportDir:       t.TempDir(),
+		UserImportMaxSize:   1024,
+		UserImportBatchSize: 10,
+	}
+	mockRepo := new(MockUserRepository)
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+
+	// Приглашения в организацию 2, где пользователь 1 - owner
+	mockInvitations := new(MockInvitationRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	mockMailer := new(MockMailer)
+	mockOrgs.On("FindMember", uint(2), uint(1)).Return(&domain.Membership{OrganizationID: 2, UserID: 1, Role: domain.OrgRoleOwner}, nil)
+	mockOrgs.On("FindByID", uint(2)).Return(&domain.Organization{ID: 2, Slug: "acme", Name: "Acme"}, nil)
+	mockInvitations.On("Create", mock.Anything).Return(nil)
+	mockMailer.On("Send", mock.Anything).Return(nil)
+	invitationCfg := &config.Config{
+		JWTSecret:     "test-secret",
+		JWTExpiration: "24h",
+		InvitationURL: "https://app.example.com/invitations/accept",
+		InvitationTTL: "72h",
+	}
+	invitations := service.NewInvitationService(mockInvitations, mockOrgs, mockRepo, service.NewAuthService(mockRepo, invitationCfg),
+		service.NewTokenIssuer(invitationCfg, nil), mockMailer, nil, invitationCfg)
+
+	mockImports := new(MockUserImportRepository)
+	mockImports.On("Create", mock.AnythingOfType("*domain.UserImport")).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.UserImport).ID = 5
+	}).Return(nil)
+	mockImports.On("Update", mock.AnythingOfType("*domain.UserImport")).Return(nil)
+	importService := service.NewUserImportService(mockImports, mockRepo, attributes, invitations, nil, cfg,
+		service.WithUserImportPasswordHasher(password.NewHasher(fastArgon2())),
+		service.WithUserImportPasswordPolicy(password.NewPolicy(password.PolicyConfig{}, nil)),
+	).ForTenant(2)
+
+	existing := &domain.User{ID: 4, Email: "bob@example.com", Name: "Bob", Role: "user", Password: "$argon2id$old", Version: 3,
+		Attributes: domain.UserAttributes{"department": "sales", "vip": false}}
+	mockRepo.On("FindByEmail", "bob@example.com").Return(existing, nil)
+	mockRepo.On("FindByEmail", mock.Anything).Return(nil, nil)
+	mockInvitations.On("FindPending", uint(2), "new@example.com").Return(nil, repository.ErrOrganizationRecordNotFound)
+
+	single := func(email string) interface{} {
+		return mock.MatchedBy(func(users []*domain.User) bool { return len(users) == 1 && users[0].Email == email })
+	}
+	mockImports.On("SaveBatch", uint(2), mock.MatchedBy(func(users []*domain.User) bool { return len(users) == 2 })).
+		Return(repository.ErrUserVersionConflict).Once()
+	mockImports.On("SaveBatch", uint(2), single("bob@example.com")).Return(nil)
+	mockImports.On("SaveBatch", uint(2), single("yan@example.com")).Return(assert.AnError)
+
+	file := `{"email": "bob@example.com", "name": "Robert", "role": "admin", "attributes": {"department": "support"}}
+{"email": "new@example.com", "name": "Newbie"}
+{"email": "xena@example.com", "name": "Xena", "role": "admin"}
+{"email": "yan@example.com", "name": "Yan", "password": "vT8#qLp2!zR4", "attributes": {"department": "sales", "vip": true}}
+
+{"email": "zoe@example.com", "nickname": "Zoe"}
+`
+
+	// Act
+	job, err := importService.Import(1, &domain.CreateUserImportRequest{
+		Format: domain.UserImportFormatNDJSON,
+		Upsert: true,
+		Invite: true,
+	}, strings.NewReader(file))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserImportStatusCompleted, job.Status)
+	assert.Equal(t, 5, job.Total, "пустая строка пропускается")
+	assert.Equal(t, 1, job.Updated)
+	assert.Equal(t, 1, job.Invited)
+	assert.Equal(t, 0, job.Created)
+	assert.Equal(t, 3, job.Failed)
+
+	assert.Equal(t, "Robert", existing.Name)
+	assert.Equal(t, "admin", existing.Role)
+	assert.Equal(t, []domain.UserImportRoleChange{{Row: 1, Email: "bob@example.com", From: "user", To: "admin"}}, job.RoleChanges)
+	assert.Equal(t, "$argon2id$old", existing.Password, "без пароля в строке пароль не меняется")
+	assert.Equal(t, domain.UserAttributes{"department": "support", "vip": false}, existing.Attributes)
+
+	mockInvitations.AssertCalled(t, "Create", mock.MatchedBy(func(invitation *domain.Invitation) bool {
+		return invitation.Email == "new@example.com" && invitation.OrganizationID == 2 &&
+			invitation.Role == domain.OrgRoleMember && invitation.InvitedByID == 1
+	}))
+
+	errors := rowErrors(job)
+	assert.Contains(t, errors[3], "role: ")
+	assert.Contains(t, errors[4], "не удалось сохранить пользователя")
+	assert.Contains(t, errors[6], "json: ")
+}
+
+// TestUserImport_NewPasswordRevokesTokens - новый пароль существующего пользователя
+// увеличивает TokenVersion и завершает сеансы, обновление без пароля - нет
+func TestUserImport_NewPasswordRevokesTokens(t *testing.T) {
+	// Arrange
+	sessions := new(MockSessionRepository)
+	cfg := &config.Config{
+		UserImportDir:       t.TempDir(),
+		UserImportMaxSize:   1024,
+		UserImportBatchSize: 10,
+	}
+	mockRepo := new(MockUserRepository)
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+	mockImports := new(MockUserImportRepository)
+	mockImports.On("Create", mock.AnythingOfType("*domain.UserImport")).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.UserImport).ID = 5
+	}).Return(nil)
+	mockImports.On("Update", mock.AnythingOfType("*domain.UserImport")).Return(nil)
+	importService := service.NewUserImportService(mockImports, mockRepo, attributes, nil, nil, cfg,
+		service.WithUserImportPasswordHasher(password.NewHasher(fastArgon2())),
+		service.WithUserImportPasswordPolicy(password.NewPolicy(password.PolicyConfig{}, nil)),
+		service.WithUserImportSessions(sessions),
+	).ForTenant(2)
+
+	bob := &domain.User{ID: 4, Email: "bob@example.com", Name: "Bob", Role: "user", TokenVersion: 2, Version: 1,
+		Attributes: domain.UserAttributes{"department": "sales", "vip": false}}
+	amy := &domain.User{ID: 5, Email: "amy@example.com", Name: "Amy", Role: "user", TokenVersion: 7, Version: 1,
+		Attributes: domain.UserAttributes{"department": "sales", "vip": false}}
+	mockRepo.On("FindByEmail", "bob@example.com").Return(bob, nil)
+	mockRepo.On("FindByEmail", "amy@example.com").Return(amy, nil)
+	mockImports.On("SaveBatch", uint(2), mock.Anything).Return(nil)
+	sessions.On("RevokeAllExcept", uint(4), "", mock.AnythingOfType("time.Time")).Return(int64(2), nil)
+
+	file := "email,name,password\n" +
+		"bob@example.com,Robert,vT8#qLp2!zR4\n" +
+		"amy@example.com,Amy,\n"
+
+	// Act
+	job, err := importService.Import(1, &domain.CreateUserImportRequest{
+		Format: domain.UserImportFormatCSV,
+		Upsert: true,
+	}, strings.NewReader(file))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 2, job.Updated)
+	assert.Equal(t, 3, bob.TokenVersion, "старые токены bob больше не принимаются")
+	assert.Equal(t, 7, amy.TokenVersion)
+	sessions.AssertExpectations(t)
+	sessions.AssertNotCalled(t, "RevokeAllExcept", uint(5), mock.Anything, mock.Anything)
+}
+
+// TestUserImport_RoleCheckedAndChangesReported - неизвестная роль - ошибка строки,
+// изменение роли существующего пользователя - отдельно в отчёте (и в dry-run)
+func TestUserImport_RoleCheckedAndChangesReported(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		UserImportDir:       t.TempDir(),
+		UserImportMaxSize:   1024,
+		UserImportBatchSize: 10,
+	}
+	mockRepo := new(MockUserRepository)
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+	mockImports := new(MockUserImportRepository)
+	mockImports.On("Create", mock.AnythingOfType("*domain.UserImport")).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.UserImport).ID = 5
+	}).Return(nil)
+	mockImports.On("Update", mock.AnythingOfType("*domain.UserImport")).Return(nil)
+	importService := service.NewUserImportService(mockImports, mockRepo, attributes, nil, nil, cfg,
+		service.WithUserImportPasswordHasher(password.NewHasher(fastArgon2())),
+		service.WithUserImportPasswordPolicy(password.NewPolicy(password.PolicyConfig{}, nil)),
+	).ForTenant(2)
+
+	mockRepo.On("FindByEmail", "bob@example.com").Return(&domain.User{ID: 4, Email: "bob@example.com", Role: "user", Version: 1,
+		Attributes: domain.UserAttributes{"department": "sales", "vip": false}}, nil)
+	mockRepo.On("FindByEmail", "amy@example.com").Return(&domain.User{ID: 5, Email: "amy@example.com", Role: "admin", Version: 1,
+		Attributes: domain.UserAttributes{"department": "sales", "vip": false}}, nil)
+	mockRepo.On("FindByEmail", mock.Anything).Return(nil, nil)
+
+	file := "email,name,role,attr.department\n" +
+		"bob@example.com,Bob,admin,sales\n" +
+		"amy@example.com,Amy,admin,sales\n" +
+		"new@example.com,Newbie,superuser,sales\n"
+
+	// Act
+	job, err := importService.Import(1, &domain.CreateUserImportRequest{
+		Format: domain.UserImportFormatCSV,
+		DryRun: true,
+		Upsert: true,
+	}, strings.NewReader(file))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 2, job.Updated)
+	assert.Equal(t, []domain.UserImportRoleChange{{Row: 2, Email: "bob@example.com", From: "user", To: "admin"}}, job.RoleChanges)
+	assert.Contains(t, rowErrors(job)[4], "role: допустимые значения: user, admin")
+}
+
+// TestUserImport_DryRun - проверка и счётчики без записи и писем
+func TestUserImport_DryRun(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		UserImportDir:       t.TempDir(),
+		UserImportMaxSize:   1024,
+		UserImportBatchSize: 10,
+	}
+	mockRepo := new(MockUserRepository)
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+
+	// Приглашения в организацию 2, где пользователь 1 - owner
+	mockInvitations := new(MockInvitationRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	mockMailer := new(MockMailer)
+	mockOrgs.On("FindMember", uint(2), uint(1)).Return(&domain.Membership{OrganizationID: 2, UserID: 1, Role: domain.OrgRoleOwner}, nil)
+	mockOrgs.On("FindByID", uint(2)).Return(&domain.Organization{ID: 2, Slug: "acme", Name: "Acme"}, nil)
+	mockInvitations.On("Create", mock.Anything).Return(nil)
+	mockMailer.On("Send", mock.Anything).Return(nil)
+	invitationCfg := &config.Config{
+		JWTSecret:     "test-secret",
+		JWTExpiration: "24h",
+		InvitationURL: "https://app.example.com/invitations/accept",
+		InvitationTTL: "72h",
+	}
+	invitations := service.NewInvitationService(mockInvitations, mockOrgs, mockRepo, service.NewAuthService(mockRepo, invitationCfg),
+		service.NewTokenIssuer(invitationCfg, nil), mockMailer, nil, invitationCfg)
+
+	mockImports := new(MockUserImportRepository)
+	mockImports.On("Create", mock.AnythingOfType("*domain.UserImport")).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.UserImport).ID = 5
+	}).Return(nil)
+	mockImports.On("Update", mock.AnythingOfType("*domain.UserImport")).Return(nil)
+	importService := service.NewUserImportService(mockImports, mockRepo, attributes, invitations, nil, cfg,
+		service.WithUserImportPasswordHasher(password.NewHasher(fastArgon2())),
+		service.WithUserImportPasswordPolicy(password.NewPolicy(password.PolicyConfig{}, nil)),
+	).ForTenant(2)
+
+	mockRepo.On("FindByEmail", "bob@example.com").Return(&domain.User{ID: 4, Email: "bob@example.com", Version: 1,
+		Attributes: domain.UserAttributes{"department": "sales", "vip": false}}, nil)
+	mockRepo.On("FindByEmail", mock.Anything).Return(nil, nil)
+
+	file := "email,name,password\n" +
+		"bob@example.com,Robert,\n" +
+		"new@example.com,Newbie,\n" +
+		"amy@example.com,Amy,vT8#qLp2!zR4\n"
+
+	// Act
+	job, err := importService.Import(1, &domain.CreateUserImportRequest{
+		Format: domain.UserImportFormatCSV,
+		DryRun: true,
+		Upsert: true,
+		Invite: true,
+	}, strings.NewReader(file))
+
+	// Assert: amy не проходит обязательные атрибуты схемы
+	require.NoError(t, err)
+	assert.True(t, job.DryRun)
+	assert.Equal(t, 1, job.Updated)
+	assert.Equal(t, 1, job.Invited)
+	assert.Equal(t, 0, job.Created)
+	assert.Contains(t, rowErrors(job)[4], "attr.department: ")
+	mockImports.AssertNotCalled(t, "SaveBatch", mock.Anything, mock.Anything)
+	mockInvitations.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+// TestUserImport_RequestAndWorker - файл проверяется при загрузке, задачу берёт обработчик
+func TestUserImport_RequestAndWorker(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		UserImportDir:       t.TempDir(),
+		UserImportMaxSize:   1024,
+		UserImportBatchSize: 10,
+	}
+	mockRepo := new(MockUserRepository)
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+	mockImports := new(MockUserImportRepository)
+	mockImports.On("Create", mock.AnythingOfType("*domain.UserImport")).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.UserImport).ID = 5
+	}).Return(nil)
+	mockImports.On("Update", mock.AnythingOfType("*domain.UserImport")).Return(nil)
+	importService := service.NewUserImportService(mockImports, mockRepo, attributes, nil, nil, cfg,
+		service.WithUserImportPasswordHasher(password.NewHasher(fastArgon2())),
+		service.WithUserImportPasswordPolicy(password.NewPolicy(password.PolicyConfig{}, nil)),
+	).ForTenant(2)
+
+	csvRequest := &domain.CreateUserImportRequest{Format: domain.UserImportFormatCSV}
+
+	// Act & Assert: недопустимый файл не сохраняется
+	_, err := importService.Request(1, &domain.CreateUserImportRequest{Format: "xml"}, strings.NewReader("<users/>"))
+	assert.ErrorIs(t, err, service.ErrUserImportFormat)
+	_, err = importService.Request(1, csvRequest, strings.NewReader(""))
+	assert.ErrorIs(t, err, service.ErrUserImportEmpty)
+	_, err = importService.Request(1, csvRequest, strings.NewReader(strings.Repeat("x", 1025)))
+	assert.ErrorIs(t, err, service.ErrUserImportTooLarge)
+
+	// Задача ждёт обработчика
+	job, err := importService.Request(1, csvRequest, strings.NewReader("email,name,phone\nalice@example.com,Alice,123\n"))
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserImportStatusPending, job.Status)
+	assert.Equal(t, uint(1), job.RequestedByID)
+
+	mockImports.On("ClaimPending", mock.Anything, mock.Anything).Return(job, nil).Once()
+	mockImports.On("ClaimPending", mock.Anything, mock.Anything).Return(nil, repository.ErrUserImportRecordNotFound)
+	count, err := importService.ProcessPending()
+
+	// Неизвестная колонка - ошибка всего файла
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, domain.UserImportStatusFailed, job.Status)
+	assert.Contains(t, job.Error, `неизвестная колонка "phone"`)
+	assert.NotNil(t, job.CompletedAt)
+}
+
+// TestUserImportHandler_RequestAndStatus - формат по Content-Type, 202, задачи чужой организации не видны
+func TestUserImportHandler_RequestAndStatus(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		UserImportDir:       t.TempDir(),
+		UserImportMaxSize:   1024,
+		UserImportBatchSize: 10,
+	}
+	mockRepo := new(MockUserRepository)
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+	mockImports := new(MockUserImportRepository)
+	mockImports.On("Create", mock.AnythingOfType("*domain.UserImport")).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.UserImport).ID = 5
+	}).Return(nil)
+	mockImports.On("Update", mock.AnythingOfType("*domain.UserImport")).Return(nil)
+	importService := service.NewUserImportService(mockImports, mockRepo, attributes, nil, nil, cfg,
+		service.WithUserImportPasswordHasher(password.NewHasher(fastArgon2())),
+		service.WithUserImportPasswordPolicy(password.NewPolicy(password.PolicyConfig{}, nil)),
+	).ForTenant(2)
+
+	mockImports.On("FindByID", uint(5)).Return(&domain.UserImport{ID: 5, OrganizationID: 2, Status: domain.UserImportStatusProcessing, Total: 10, Processed: 4}, nil)
+	mockImports.On("FindByID", uint(6)).Return(&domain.UserImport{ID: 6, OrganizationID: 3}, nil)
+	importHandler := handler.NewUserImportHandler(importService)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Set("tenantID", uint(3)) // ForTenant обработчика заменяет организацию 2
+	})
+	router.POST("/admin/users/import", importHandler.Request)
+	router.GET("/admin/imports/:id", importHandler.Get)
+
+	request := func(method, path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act & Assert
+	rec := request(http.MethodPost, "/admin/users/import?dry_run=true", "text/csv; charset=utf-8", "email,name\nalice@example.com,Alice\n")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"format":"csv"`)
+	assert.Contains(t, rec.Body.String(), `"dry_run":true`)
+	assert.Contains(t, rec.Body.String(), `"status":"pending"`)
+
+	rec = request(http.MethodPost, "/admin/users/import?format=ndjson", "application/octet-stream", `{"email": "alice@example.com", "name": "Alice"}`)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"format":"ndjson"`)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, request(http.MethodPost, "/admin/users/import", "application/json", "[]").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/admin/users/import?format=xml", "", "x").Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, request(http.MethodPost, "/admin/users/import", "text/csv", strings.Repeat("x", 2048)).Code)
+
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/admin/imports/5", "", "").Code, "задача другой организации")
+	rec = request(http.MethodGet, "/admin/imports/6", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"id":6`)
+}
@@ -0,0 +1,348 @@
+package unit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"advanced-user-api/internal/domain"
+	"advanced-user-api/internal/handler"
+	"advanced-user-api/internal/pkg/jsonpatch"
+	"advanced-user-api/internal/repository"
+	"advanced-user-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// ================================================================
+// ТЕСТЫ JSON PATCH
+// ================================================================
+
+// TestJSONPatch_Operations - операции RFC 6902 и пути RFC 6901
+func TestJSONPatch_Operations(t *testing.T) {
+	doc := `{"a": {"b~c": 1, "d/e": [1, 2]}, "f": "x"}`
+
+	cases := []struct {
+		name  string
+		patch string
+		want  string
+		err   error
+	}{
+		{"add в объект", `[{"op": "add", "path": "/g", "value": null}]`, `{"a": {"b~c": 1, "d/e": [1, 2]}, "f": "x", "g": null}`, nil},
+		{"add в массив и в конец", `[{"op": "add", "path": "/a/d~1e/0", "value": 0}, {"op": "add", "path": "/a/d~1e/-", "value": 3}]`, `{"a": {"b~c": 1, "d/e": [0, 1, 2, 3]}, "f": "x"}`, nil},
+		{"remove", `[{"op": "remove", "path": "/a/b~0c"}]`, `{"a": {"d/e": [1, 2]}, "f": "x"}`, nil},
+		{"replace", `[{"op": "replace", "path": "/f", "value": {"y": true}}]`, `{"a": {"b~c": 1, "d/e": [1, 2]}, "f": {"y": true}}`, nil},
+		{"move", `[{"op": "move", "from": "/f", "path": "/a/f"}]`, `{"a": {"b~c": 1, "d/e": [1, 2], "f": "x"}}`, nil},
+		{"copy", `[{"op": "copy", "from": "/a/d~1e", "path": "/h"}]`, `{"a": {"b~c": 1, "d/e": [1, 2]}, "f": "x", "h": [1, 2]}`, nil},
+		{"test 1 и 1.0 равны", `[{"op": "test", "path": "/a/b~0c", "value": 1.0}]`, doc, nil},
+		{"test не пройден", `[{"op": "test", "path": "/f", "value": "y"}, {"op": "remove", "path": "/f"}]`, "", jsonpatch.ErrTestFailed},
+		{"replace несуществующего", `[{"op": "replace", "path": "/z", "value": 1}]`, "", jsonpatch.ErrPathNotFound},
+		{"индекс за пределами", `[{"op": "add", "path": "/a/d~1e/3", "value": 1}]`, "", jsonpatch.ErrPathNotFound},
+		{"ведущий ноль", `[{"op": "remove", "path": "/a/d~1e/01"}]`, "", jsonpatch.ErrInvalidPatch},
+		{"move внутрь себя", `[{"op": "move", "from": "/a", "path": "/a/x"}]`, "", jsonpatch.ErrInvalidPatch},
+		{"нет value", `[{"op": "add", "path": "/g"}]`, "", jsonpatch.ErrInvalidPatch},
+		{"неизвестная операция", `[{"op": "merge", "path": "/g"}]`, "", jsonpatch.ErrInvalidPatch},
+		{"не массив", `{"op": "remove", "path": "/f"}`, "", jsonpatch.ErrInvalidPatch},
+	}
+	for _, tc := range cases {
+		result, err := jsonpatch.Apply([]byte(doc), []byte(tc.patch))
+		if tc.err != nil {
+			assert.ErrorIs(t, err, tc.err, tc.name)
+			continue
+		}
+		require.NoError(t, err, tc.name)
+		assert.JSONEq(t, tc.want, string(result), tc.name)
+	}
+}
+
+// TestJSONPatch_MergePatch - примеры из RFC 7396 (приложение A)
+func TestJSONPatch_MergePatch(t *testing.T) {
+	cases := [][3]string{
+		{`{"a": "b"}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "b"}`, `{"b": "c"}`, `{"a": "b", "b": "c"}`},
+		{`{"a": "b"}`, `{"a": null}`, `{}`},
+		{`{"a": [{"b": "c"}]}`, `{"a": [1]}`, `{"a": [1]}`},
+		{`{"a": {"b": "c"}}`, `{"a": {"b": "d", "c": null}}`, `{"a": {"b": "d"}}`},
+		{`["a", "b"]`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"e": null}`, `{"a": 1}`, `{"e": null, "a": 1}`},
+		{`{}`, `{"a": {"bb": {"ccc": null}}}`, `{"a": {"bb": {}}}`},
+	}
+	for _, tc := range cases {
+		result, err := jsonpatch.MergePatch([]byte(tc[0]), []byte(tc[1]))
+		require.NoError(t, err)
+		assert.JSONEq(t, tc[2], string(result), tc[1])
+	}
+
+	_, err := jsonpatch.MergePatch([]byte(`{}`), []byte(`{"a": `))
+	assert.ErrorIs(t, err, jsonpatch.ErrInvalidPatch)
+}
+
+// ================================================================
+// ТЕСТЫ PATCH / PUT /users/:id
+// ================================================================
+
+// TestUserService_PatchUser - патч применяется к видимому документу и проверяется как PUT
+func TestUserService_PatchUser(t *testing.T) {
+	patch := func(format, body string, actorID uint, admin bool) (*domain.User, error) {
+		mockAttributes := new(MockAttributeRepository)
+		mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+		attributes := service.NewAttributeService(mockAttributes, nil)
+		mockRepo := new(MockUserRepository)
+		alice := &domain.User{ID: 7, Name: "Alice", Email: "alice@example.com", Role: "user",
+			Attributes: domain.UserAttributes{"department": "sales", "cost_center": "CC-0001", "vip": true}}
+		mockRepo.On("FindByID", uint(7)).Return(alice, nil)
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		userService := service.NewUserService(mockRepo, service.WithUserAttributeService(attributes))
+		req := &domain.PatchUserRequest{Format: format, Patch: []byte(body), AsAdmin: admin}
+		return userService.PatchUser(7, req, actorID, domain.ClientInfo{})
+	}
+
+	// Merge Patch: null удаляет атрибут, невидимый vip не трогается
+	user, err := patch(domain.PatchFormatMerge, `{"name": "Alice L.", "attributes": {"cost_center": null}}`, 7, false)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice L.", user.Name)
+	assert.Equal(t, domain.UserAttributes{"department": "sales", "vip": true}, user.Attributes)
+
+	// JSON Patch с test: изменение, только если email не поменялся
+	user, err = patch(domain.PatchFormatJSON, `[
+		{"op": "test", "path": "/email", "value": "alice@example.com"},
+		{"op": "add", "path": "/attributes/level", "value": 4}
+	]`, 7, false)
+	require.NoError(t, err)
+	assert.Equal(t, float64(4), user.Attributes["level"])
+
+	_, err = patch(domain.PatchFormatJSON, `[{"op": "test", "path": "/email", "value": "old@example.com"}]`, 7, false)
+	assert.ErrorIs(t, err, service.ErrPatchTestFailed)
+
+	// Невидимые атрибуты в документе отсутствуют - путь к ним не найден
+	_, err = patch(domain.PatchFormatJSON, `[{"op": "remove", "path": "/attributes/vip"}]`, 7, false)
+	assert.ErrorIs(t, err, service.ErrInvalidPatch)
+
+	// Результат проверяется правилами PUT: имя нельзя очистить, лишние поля - ошибка
+	_, err = patch(domain.PatchFormatMerge, `{"name": null}`, 7, false)
+	assert.ErrorIs(t, err, service.ErrInvalidUserDocument)
+	_, err = patch(domain.PatchFormatMerge, `{"id": 8}`, 1, true)
+	assert.ErrorIs(t, err, service.ErrInvalidUserDocument)
+	_, err = patch(domain.PatchFormatMerge, `{"email": "not-an-email"}`, 7, false)
+	assert.ErrorIs(t, err, service.ErrInvalidUserDocument)
+
+	// Роль: не себе и только администратор
+	_, err = patch(domain.PatchFormatMerge, `{"role": "admin"}`, 7, false)
+	assert.ErrorIs(t, err, service.ErrRoleChangeForbidden)
+	_, err = patch(domain.PatchFormatMerge, `{"role": "admin"}`, 8, false)
+	assert.ErrorIs(t, err, service.ErrUserUpdateForbidden)
+	_, err = patch(domain.PatchFormatMerge, `{"role": "user"}`, 7, true)
+	require.NoError(t, err, "та же роль - не изменение")
+	user, err = patch(domain.PatchFormatMerge, `{"role": "admin"}`, 1, true)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", user.Role)
+
+	_, err = patch("application/json", `{"name": "Alice L."}`, 7, false)
+	assert.ErrorIs(t, err, service.ErrUnsupportedPatchFormat)
+}
+
+// TestUserService_UpdateReplacesDocument - PUT заменяет документ: пустые значения записываются
+func TestUserService_UpdateReplacesDocument(t *testing.T) {
+	// Arrange
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+	mockRepo := new(MockUserRepository)
+	alice := &domain.User{ID: 7, Name: "Alice", Email: "alice@example.com", Role: "user",
+		Attributes: domain.UserAttributes{"department": "sales", "cost_center": "CC-0001", "vip": true}}
+	mockRepo.On("FindByID", uint(7)).Return(alice, nil)
+	mockRepo.On("Update", mock.Anything).Return(nil)
+	userService := service.NewUserService(mockRepo, service.WithUserAttributeService(attributes))
+
+	// Act: атрибутов нет в запросе - видимые удаляются, vip (admin) остаётся
+	user, err := userService.UpdateUser(7, &domain.UpdateUserRequest{
+		Name: "Alice", Email: "alice@example.com", Role: "user",
+		Attributes: domain.UserAttributes{"department": "sales"},
+	}, 7, domain.ClientInfo{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Same(t, alice, user)
+	assert.Equal(t, domain.UserAttributes{"department": "sales", "vip": true}, user.Attributes)
+	mockRepo.AssertCalled(t, "Update", alice)
+}
+
+// TestUserService_NonOwnerUpdateForbidden - чужие email и имя меняет только администратор
+func TestUserService_NonOwnerUpdateForbidden(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	userService := service.NewUserService(mockRepo)
+	alice := &domain.User{ID: 7, Name: "Alice", Email: "alice@example.com", Role: "user"}
+	mockRepo.On("FindByID", uint(7)).Return(alice, nil)
+
+	// Act: пользователь 8 пытается сменить email Alice (PUT) и имя (PATCH)
+	_, putErr := userService.UpdateUser(7, &domain.UpdateUserRequest{
+		Name: "Alice", Email: "mallory@example.com", Role: "user",
+	}, 8, domain.ClientInfo{})
+	_, patchErr := userService.PatchUser(7, &domain.PatchUserRequest{
+		Format: domain.PatchFormatMerge, Patch: []byte(`{"name": "Mallory"}`),
+	}, 8, domain.ClientInfo{})
+
+	// Assert
+	assert.ErrorIs(t, putErr, service.ErrUserUpdateForbidden)
+	assert.ErrorIs(t, patchErr, service.ErrUserUpdateForbidden)
+	assert.Equal(t, "alice@example.com", alice.Email)
+	assert.Equal(t, "Alice", alice.Name)
+	mockRepo.AssertNotCalled(t, "FindByEmail", mock.Anything)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+// TestUserHandler_PatchStatuses - Content-Type, 409 на test, 403 на роль, PUT без полей
+func TestUserHandler_PatchStatuses(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	request := func(method, contentType, body string, role string) *httptest.ResponseRecorder {
+		mockAttributes := new(MockAttributeRepository)
+		mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+		attributes := service.NewAttributeService(mockAttributes, nil)
+		mockRepo := new(MockUserRepository)
+		alice := &domain.User{ID: 7, Name: "Alice", Email: "alice@example.com", Role: "user",
+			Attributes: domain.UserAttributes{"department": "sales", "cost_center": "CC-0001", "vip": true}}
+		mockRepo.On("FindByID", uint(7)).Return(alice, nil)
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		userService := service.NewUserService(mockRepo, service.WithUserAttributeService(attributes))
+		userHandler := handler.NewUserHandler(userService, attributes)
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", uint(7))
+			c.Set("userRole", role)
+		})
+		router.PUT("/users/:id", userHandler.Update)
+		router.PATCH("/users/:id", userHandler.Patch)
+
+		req := httptest.NewRequest(method, "/users/7", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act & Assert
+	rec := request(http.MethodPatch, domain.PatchFormatMerge+"; charset=utf-8", `{"name": "Alice L."}`, "user")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"Alice L."`)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, request(http.MethodPatch, "application/json", `{"name": "Alice L."}`, "user").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPatch, domain.PatchFormatJSON, `[{"op": "jump"}]`, "user").Code)
+	assert.Equal(t, http.StatusConflict, request(http.MethodPatch, domain.PatchFormatJSON,
+		`[{"op": "test", "path": "/name", "value": "Bob"}]`, "user").Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPatch, domain.PatchFormatMerge, `{"role": "user2"}`, "admin").Code,
+		"свою роль не меняет и администратор")
+
+	// PUT - полная замена: без email и role запрос невалиден
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPut, "application/json", `{"name": "Alice L."}`, "user").Code)
+}
+
+// TestUserHandler_EmailChangeImpersonated - от имени пользователя email не меняется
+func TestUserHandler_EmailChangeImpersonated(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	request := func(method, contentType, body string) *httptest.ResponseRecorder {
+		mockAttributes := new(MockAttributeRepository)
+		mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+		attributes := service.NewAttributeService(mockAttributes, nil)
+		mockRepo := new(MockUserRepository)
+		alice := &domain.User{ID: 7, Name: "Alice", Email: "alice@example.com", Role: "user",
+			Attributes: domain.UserAttributes{"department": "sales", "cost_center": "CC-0001", "vip": true}}
+		mockRepo.On("FindByID", uint(7)).Return(alice, nil)
+		mockRepo.On("Update", mock.Anything).Return(nil)
+		userService := service.NewUserService(mockRepo, service.WithUserAttributeService(attributes))
+		userHandler := handler.NewUserHandler(userService, attributes)
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", uint(7))
+			c.Set("userRole", "user")
+			c.Set("actorID", uint(1))
+		})
+		router.PUT("/users/:id", userHandler.Update)
+		router.PATCH("/users/:id", userHandler.Patch)
+
+		req := httptest.NewRequest(method, "/users/7", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act & Assert
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPatch, domain.PatchFormatMerge, `{"email": "mallory@example.com"}`).Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPut, "application/json",
+		`{"name": "Alice", "email": "mallory@example.com", "role": "user"}`).Code)
+
+	// Остальные поля администратор от имени пользователя менять может
+	assert.Equal(t, http.StatusOK, request(http.MethodPatch, domain.PatchFormatMerge, `{"name": "Alice L."}`).Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodPut, "application/json",
+		`{"name": "Alice", "email": "alice@example.com", "role": "user", "attributes": {"department": "sales"}}`).Code)
+}
+
+// TestUserHandler_EmailTakenAndBodyLimit - занятый email - 409 как при регистрации, длинное тело - 413
+func TestUserHandler_EmailTakenAndBodyLimit(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	request := func(userService service.UserService, method, contentType, body string) *httptest.ResponseRecorder {
+		mockAttributes := new(MockAttributeRepository)
+		mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+		attributes := service.NewAttributeService(mockAttributes, nil)
+		userHandler := handler.NewUserHandler(userService, attributes)
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", uint(7))
+			c.Set("userRole", "user")
+		})
+		router.PUT("/users/:id", userHandler.Update)
+		router.PATCH("/users/:id", userHandler.Patch)
+
+		req := httptest.NewRequest(method, "/users/7", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	mockAttributes := new(MockAttributeRepository)
+	mockAttributes.On("FindAll").Return(attributeSchema(), nil)
+	attributes := service.NewAttributeService(mockAttributes, nil)
+	mockRepo := new(MockUserRepository)
+	alice := &domain.User{ID: 7, Name: "Alice", Email: "alice@example.com", Role: "user",
+		Attributes: domain.UserAttributes{"department": "sales", "cost_center": "CC-0001", "vip": true}}
+	mockRepo.On("FindByID", uint(7)).Return(alice, nil)
+	mockRepo.On("Update", mock.Anything).Return(nil)
+	userService := service.NewUserService(mockRepo, service.WithUserAttributeService(attributes))
+	mockRepo.On("FindByEmail", "bob@example.com").Return(&domain.User{ID: 8, Email: "bob@example.com"}, nil)
+	mockRepo.On("FindByEmail", "alice.l@example.com").Return(nil, errors.New("пользователь с таким email не найден"))
+
+	// Act & Assert: email другого аккаунта
+	rec := request(userService, http.MethodPatch, domain.PatchFormatMerge, `{"email": "bob@example.com"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), service.ErrEmailAlreadyRegistered.Error())
+	assert.Equal(t, http.StatusConflict, request(userService, http.MethodPut, "application/json",
+		`{"name": "Alice", "email": "bob@example.com", "role": "user"}`).Code)
+
+	// Свободный email меняется
+	assert.Equal(t, http.StatusOK, request(userService, http.MethodPatch, domain.PatchFormatMerge, `{"email": "alice.l@example.com"}`).Code)
+
+	// Email заняли между проверкой и записью - уникальный индекс, тоже 409
+	racedRepo := new(MockUserRepository)
+	racedRepo.On("FindByID", uint(7)).Return(&domain.User{ID: 7, Name: "Alice", Email: "alice@example.com", Role: "user"}, nil)
+	racedRepo.On("FindByEmail", "carol@example.com").Return(nil, errors.New("пользователь с таким email не найден"))
+	racedRepo.On("Update", mock.Anything).Return(repository.ErrUserEmailTaken)
+	assert.Equal(t, http.StatusConflict, request(service.NewUserService(racedRepo), http.MethodPatch, domain.PatchFormatMerge,
+		`{"email": "carol@example.com"}`).Code)
+
+	// Тело больше 64 KB обрывается при чтении
+	large := `{"name": "` + strings.Repeat("a", 70*1024) + `"}`
+	assert.Equal(t, http.StatusRequestEntityTooLarge, request(userService, http.MethodPatch, domain.PatchFormatMerge, large).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, request(userService, http.MethodPut, "application/json", large).Code)
+}